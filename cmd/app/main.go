@@ -1,68 +1,172 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"net"
+	"log/slog"
 	"net/http"
 	"os"
+	"prodcrud/internal/config"
+	"prodcrud/internal/metrics"
+	"prodcrud/internal/repository/attribute"
+	"prodcrud/internal/repository/currency"
 	healthRepo "prodcrud/internal/repository/health"
+	"prodcrud/internal/repository/image"
+	"prodcrud/internal/repository/order"
 	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/promotion"
+	"prodcrud/internal/repository/purchase"
+	"prodcrud/internal/repository/reorder"
+	"prodcrud/internal/repository/tax"
 	"prodcrud/internal/rest"
+	adminHandler "prodcrud/internal/rest/handlers/admin"
+	attributeHandler "prodcrud/internal/rest/handlers/attribute"
 	healthHandler "prodcrud/internal/rest/handlers/health"
+	imageHandler "prodcrud/internal/rest/handlers/image"
+	orderHandler "prodcrud/internal/rest/handlers/order"
+	pricingHandler "prodcrud/internal/rest/handlers/pricing"
 	productHandler "prodcrud/internal/rest/handlers/product"
+	promotionHandler "prodcrud/internal/rest/handlers/promotion"
+	purchaseHandler "prodcrud/internal/rest/handlers/purchase"
+	reorderHandler "prodcrud/internal/rest/handlers/reorder"
+	taxHandler "prodcrud/internal/rest/handlers/tax"
+	"prodcrud/internal/tracing"
+	attributeService "prodcrud/internal/usecase/attribute"
 	healthService "prodcrud/internal/usecase/health"
+	imageService "prodcrud/internal/usecase/image"
+	orderService "prodcrud/internal/usecase/order"
+	pricingService "prodcrud/internal/usecase/pricing"
 	productService "prodcrud/internal/usecase/product"
+	promotionService "prodcrud/internal/usecase/promotion"
+	purchaseService "prodcrud/internal/usecase/purchase"
+	reorderService "prodcrud/internal/usecase/reorder"
+	taxService "prodcrud/internal/usecase/tax"
+	"prodcrud/migrations"
+	"prodcrud/pkg/blob"
+	"prodcrud/pkg/lifecycle"
+	"prodcrud/pkg/logging"
 	"prodcrud/pkg/migration"
-	"time"
+	"prodcrud/pkg/notify"
+	"strings"
 
 	"prodcrud/pkg/db"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/joho/godotenv"
 	"go.uber.org/dig"
 )
 
+const usage = `usage: app [command] [flags]
+
+commands:
+  serve [--no-migrate]    run the HTTP server (default)
+  migrate up              apply every pending migration
+  migrate down [N]        roll back N migrations (default 1)
+  migrate goto VERSION    migrate up or down to VERSION
+  migrate version         print the current schema version
+  migrate force VERSION   set the version after fixing a failed migration
+  migrate create NAME     add an empty up/down pair to -dir (default migrations)
+`
+
 func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+	command, args := splitCommand(os.Args[1:])
+	if len(command) == 0 {
+		command = []string{"serve"}
 	}
-	var (
-		port = os.Getenv("PORT")
-		host = os.Getenv("HOST")
-		dsn  = os.Getenv("DSN")
-		file = os.Getenv("FILE")
-	)
 
-	if err := migration.Migrate(file, dsn); err != nil {
-		log.Fatalf("Error running migration: %s", err.Error())
+	if command[0] != "serve" && command[0] != "migrate" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if len(command) >= 2 && command[0] == "migrate" && command[1] == "create" {
+		if err := createMigration(command[2:], args); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	if err := execute(host, port, dsn); err != nil {
-		log.Println(err)
-		os.Exit(1)
+	noMigrate := false
+	if command[0] == "serve" {
+		args, noMigrate = stripFlag(args, "no-migrate")
+	}
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if noMigrate {
+		cfg.Migrations.Auto = false
 	}
 
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(cfg.Log.Level))
+	levels, _ := logging.ParseLevels(cfg.Log.Packages)
+	logs := logging.New(os.Stdout, level, levels)
+	slog.SetDefault(logs.Logger())
+
+	switch command[0] {
+	case "serve":
+		slog.Info("configuration loaded", "config", cfg.String())
+		err = execute(cfg, logs)
+	case "migrate":
+		err = runMigrate(cfg, command[1:])
+	}
+	if err != nil {
+		slog.Error("service stopped", "command", strings.Join(command, " "), "error", err)
+		os.Exit(1)
+	}
 }
 
-func execute(host, port, dsn string) error {
+func execute(cfg *config.Config, logs *logging.Registry) error {
+	tracer, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName: cfg.Tracing.ServiceName,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to setup tracing: %w", err)
+	}
+
 	deps := []interface{}{
-		func() (*pgxpool.Pool, error) {
-			return db.NewDB(dsn)
-		},
 		gin.New,
-		healthRepo.NewRepo,
+		metrics.New,
+		func() *logging.Registry { return logs },
+		adminHandler.NewHandler,
+		func() healthService.Options {
+			return healthService.Options{Timeout: cfg.Health.CheckTimeout, CacheTTL: cfg.Health.CacheTTL}
+		},
 		healthService.NewService,
 		healthHandler.NewHandler,
+		func() rest.Options { return rest.Options{AdminToken: cfg.Admin.Token} },
 		rest.NewServer,
 		productHandler.NewHandler,
+		pricingHandler.NewHandler,
+		promotionHandler.NewHandler,
+		taxHandler.NewHandler,
+		attributeHandler.NewHandler,
+		imageHandler.NewHandler,
+		purchaseHandler.NewHandler,
+		orderHandler.NewHandler,
+		reorderHandler.NewHandler,
+		func() imageService.Options {
+			return imageService.Options{
+				MaxSize:       cfg.Images.MaxSize,
+				ThumbnailSize: cfg.Images.ThumbnailSize,
+				PublicURL:     cfg.Images.PublicURL,
+			}
+		},
+		func() (blob.BlobStore, error) { return newBlobStore(cfg) },
+		func() (notify.Notifier, error) { return newNotifier(cfg) },
 		func(server *rest.Server) *http.Server {
 			return &http.Server{
-				Addr:              net.JoinHostPort(host, port),
+				Addr:              cfg.Addr(),
 				Handler:           server,
-				ReadHeaderTimeout: 5 * time.Second,
+				ReadTimeout:       cfg.HTTP.ReadTimeout,
+				ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+				WriteTimeout:      cfg.HTTP.WriteTimeout,
+				IdleTimeout:       cfg.HTTP.IdleTimeout,
 			}
 		},
 	}
@@ -74,22 +178,294 @@ func execute(host, port, dsn string) error {
 		}
 	}
 
-	if err := container.Provide(product.NewRepo, dig.As(new(product.Repository))); err != nil {
-		return fmt.Errorf("failed to provide dependency: %w", err)
+	storage := cfg.Storage
+	if storage == config.StorageDB {
+		storage = db.DriverOf(cfg.DB.DSN)
+	}
+	if err := provideStorage(container, cfg, storage); err != nil {
+		return err
 	}
 
-	if err := container.Provide(productService.NewService, dig.As(new(productService.ServiceInterface))); err != nil {
-		return fmt.Errorf("failed to provide dependency: %w", err)
+	services := []interface{}{
+		productService.NewService, taxService.NewService, pricingService.NewService, promotionService.NewService,
+		attributeService.NewService, imageService.NewService, purchaseService.NewService, orderService.NewService,
+		reorderService.NewService,
+	}
+	for _, service := range services {
+		if err := container.Provide(service); err != nil {
+			return fmt.Errorf("failed to provide dependency: %w", err)
+		}
 	}
 
-	err := container.Invoke(func(server *rest.Server) {
+	// dig allows a single decorator per type, so the service layers are composed here
+	decorators := []interface{}{
+		func(next product.Repository, m *metrics.Metrics, images image.Repository, store blob.BlobStore) product.Repository {
+			return imageService.NewProductCleanup(metrics.NewRepository(next, m), images, store)
+		},
+		func(next productService.ServiceInterface, m *metrics.Metrics) productService.ServiceInterface {
+			return tracing.NewService(metrics.NewService(next, m))
+		},
+	}
+	for _, decorator := range decorators {
+		if err := container.Decorate(decorator); err != nil {
+			return fmt.Errorf("failed to decorate dependency: %w", err)
+		}
+	}
+
+	// the pool waits for the database to come up, so migrate only once it is ready
+	if cfg.Migrations.Auto {
+		switch storage {
+		case db.DriverPostgres:
+			err = container.Invoke(func(*db.DB) error {
+				migrator := migration.New(migrations.FS, cfg.DB.DSN)
+				migrator.LockTimeout = cfg.Migrations.LockTimeout
+				return migrator.Up(context.Background())
+			})
+		case db.DriverSQLite:
+			err = container.Invoke(func(conn *db.SQLite) error {
+				return migration.NewSQLite(migrations.SQLite, conn.DB).Up(context.Background())
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to run migration: %w", err)
+		}
+	}
+
+	err = container.Invoke(func(service *healthService.Service, repo *healthRepo.Repo) error {
+		if repo != nil {
+			latest, err := migration.LatestVersion(migrations.FS)
+			if err != nil {
+				return err //nolint:wrapcheck //wrapped below
+			}
+			service.Register(healthService.NewMigrationChecker(repo, latest), 0)
+		}
+		if storage == db.DriverSQLite {
+			if err := container.Invoke(func(conn *db.SQLite) {
+				service.Register(healthService.NewPingChecker("database", conn.PingContext), 0)
+			}); err != nil {
+				return err //nolint:wrapcheck //wrapped below
+			}
+		}
+		if cfg.Health.DiskPath != "" {
+			service.Register(healthService.NewDiskChecker(cfg.Health.DiskPath, uint64(cfg.Health.DiskMinFree)), 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register health checks: %w", err)
+	}
+
+	err = container.Invoke(func(server *rest.Server) {
 		server.Init()
 	})
 	if err != nil {
 		return fmt.Errorf("failed to init server: %w", err)
 	}
+
+	manager := lifecycle.New(cfg.ShutdownTimeout)
+	manager.Append(lifecycle.Hook{Name: "tracer provider", Stop: tracer.Shutdown})
+	switch storage {
+	case db.DriverPostgres:
+		err = container.Invoke(func(pool *db.DB, m *metrics.Metrics) {
+			m.RegisterPool(pool)
+			manager.Closer("postgres pool", pool.Close)
+			manager.Worker("replica monitor", pool.MonitorReplicas())
+		})
+	case db.DriverSQLite:
+		err = container.Invoke(func(conn *db.SQLite) {
+			manager.Closer("sqlite", conn.Close)
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to init database: %w", err)
+	}
 	//nolint:wrapcheck //dig.Invoke returns error
-	return container.Invoke(func(server *http.Server) error {
-		return server.ListenAndServe()
+	return container.Invoke(func(server *http.Server, m *metrics.Metrics, repo product.Repository,
+		reorders reorder.Repository, purchases purchaseService.ServiceInterface, notifier notify.Notifier) error {
+		manager.Worker("stock metrics", m.StockCollector(repo, cfg.Metrics.RefreshInterval, cfg.Metrics.LowStockThreshold))
+		if cfg.Purge.Retention > 0 {
+			manager.Worker("trash purge", productService.NewPurger(repo, cfg.Purge.Retention, cfg.Purge.Interval))
+		}
+		manager.Worker("price scheduler", pricingService.NewScheduler(repo, cfg.Pricing.ScheduleInterval))
+		if cfg.Reorder.Interval > 0 {
+			manager.Worker("reorder evaluator", reorderService.NewEvaluator(reorders, purchases, notifier,
+				cfg.Reorder.Interval, cfg.Reorder.AutoDraft))
+		}
+		manager.Server(server)
+		return manager.Run(context.Background())
 	})
 }
+
+// repositories lists the repository constructors of each storage, each
+// provided as the Repository interface of its package.
+var repositories = map[string][]repository{
+	config.StorageMemory: {
+		{newMemoryProducts, new(product.Repository)},
+		{currency.NewMemoryRepo, new(currency.Repository)},
+		{promotion.NewMemoryRepo, new(promotion.Repository)},
+		{tax.NewMemoryRepo, new(tax.Repository)},
+		{attribute.NewMemoryRepo, new(attribute.Repository)},
+		{image.NewMemoryRepo, new(image.Repository)},
+		{purchase.NewMemoryRepo, new(purchase.Repository)},
+		{order.NewMemoryRepo, new(order.Repository)},
+		{reorder.NewMemoryRepo, new(reorder.Repository)},
+	},
+	db.DriverSQLite: {
+		{product.NewSQLiteRepo, new(product.Repository)},
+		{currency.NewSQLiteRepo, new(currency.Repository)},
+		{promotion.NewSQLiteRepo, new(promotion.Repository)},
+		{tax.NewSQLiteRepo, new(tax.Repository)},
+		{attribute.NewSQLiteRepo, new(attribute.Repository)},
+		{image.NewSQLiteRepo, new(image.Repository)},
+		{purchase.NewSQLiteRepo, new(purchase.Repository)},
+		{order.NewSQLiteRepo, new(order.Repository)},
+		{reorder.NewSQLiteRepo, new(reorder.Repository)},
+	},
+	db.DriverPostgres: {
+		{product.NewRepo, new(product.Repository)},
+		{currency.NewRepo, new(currency.Repository)},
+		{promotion.NewRepo, new(promotion.Repository)},
+		{tax.NewRepo, new(tax.Repository)},
+		{attribute.NewRepo, new(attribute.Repository)},
+		{image.NewRepo, new(image.Repository)},
+		{purchase.NewRepo, new(purchase.Repository)},
+		{order.NewRepo, new(order.Repository)},
+		{reorder.NewRepo, new(reorder.Repository)},
+	},
+}
+
+// newBlobStore opens the store of the image files.
+func newBlobStore(cfg *config.Config) (blob.BlobStore, error) {
+	if cfg.Images.Store == "s3" {
+		store, err := blob.NewS3Store(blob.S3Config{
+			Endpoint:  cfg.Images.S3Endpoint,
+			Region:    cfg.Images.S3Region,
+			Bucket:    cfg.Images.S3Bucket,
+			AccessKey: cfg.Images.S3AccessKey,
+			SecretKey: cfg.Images.S3SecretKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open image store: %w", err)
+		}
+		return store, nil
+	}
+	store, err := blob.NewFileStore(cfg.Images.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image store: %w", err)
+	}
+	return store, nil
+}
+
+// newNotifier builds the destinations of the stock alerts.
+func newNotifier(cfg *config.Config) (notify.Notifier, error) {
+	var notifiers notify.Multi
+	for _, name := range cfg.Reorder.Notifiers {
+		switch name {
+		case "log":
+			notifiers = append(notifiers, notify.Log{})
+		case "webhook":
+			webhook, err := notify.NewWebhook(notify.WebhookConfig{
+				URL:    cfg.Reorder.WebhookURL,
+				Secret: cfg.Reorder.WebhookSecret,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to setup alert webhook: %w", err)
+			}
+			notifiers = append(notifiers, webhook)
+		case "smtp":
+			mailer, err := notify.NewSMTP(notify.SMTPConfig{
+				Addr:     cfg.Reorder.SMTPAddr,
+				From:     cfg.Reorder.SMTPFrom,
+				To:       cfg.Reorder.SMTPTo,
+				Username: cfg.Reorder.SMTPUsername,
+				Password: cfg.Reorder.SMTPPassword,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to setup alert emails: %w", err)
+			}
+			notifiers = append(notifiers, mailer)
+		}
+	}
+	return notifiers, nil
+}
+
+// newMemoryProducts keeps the products the memory stores point to from being
+// removed, as the foreign keys of the databases do.
+func newMemoryProducts(purchases purchase.Repository, orders order.Repository) *product.MemoryRepo {
+	var referrers []product.Referrer
+	for _, store := range []any{purchases, orders} {
+		if ref, ok := store.(product.Referrer); ok {
+			referrers = append(referrers, ref)
+		}
+	}
+	return product.NewMemoryRepo(referrers...)
+}
+
+type repository struct {
+	constructor interface{}
+	as          interface{}
+}
+
+// provideStorage registers the repositories of the given storage (memory, or
+// the database driver) and the connection they run on.
+func provideStorage(container *dig.Container, cfg *config.Config, storage string) error {
+	var deps []interface{}
+	switch storage {
+	case config.StorageMemory:
+		deps = []interface{}{
+			func() db.Transactor { return new(db.MemoryTx) },
+			func() *healthRepo.Repo { return nil },
+		}
+	case db.DriverSQLite:
+		deps = []interface{}{
+			func() (*db.SQLite, error) {
+				return db.OpenSQLite(context.Background(), db.Config{
+					DSN:          cfg.DB.DSN,
+					MaxConns:     cfg.DB.MaxConns,
+					ReadTimeout:  cfg.DB.ReadTimeout,
+					WriteTimeout: cfg.DB.WriteTimeout,
+				})
+			},
+			func(conn *db.SQLite) db.Transactor { return conn },
+			func() *healthRepo.Repo { return nil },
+		}
+	default:
+		deps = []interface{}{
+			func() (*db.DB, error) {
+				return db.NewDB(context.Background(), db.Config{
+					DSN:                   cfg.DB.DSN,
+					MaxConns:              cfg.DB.MaxConns,
+					MinConns:              cfg.DB.MinConns,
+					MaxConnLifetime:       cfg.DB.MaxConnLifetime,
+					MaxConnLifetimeJitter: cfg.DB.MaxConnLifetimeJitter,
+					MaxConnIdleTime:       cfg.DB.MaxConnIdleTime,
+					HealthCheckPeriod:     cfg.DB.HealthCheckPeriod,
+					StartupTimeout:        cfg.DB.StartupTimeout,
+					ConnectTimeout:        cfg.DB.ConnectTimeout,
+					ReadTimeout:           cfg.DB.ReadTimeout,
+					WriteTimeout:          cfg.DB.WriteTimeout,
+					ReadRetries:           cfg.DB.ReadRetries,
+					RetryBackoff:          cfg.DB.RetryBackoff,
+					ReplicaDSNs:           cfg.DB.ReplicaDSNs,
+					ReplicaMaxLag:         cfg.DB.ReplicaMaxLag,
+					ReplicaCheckPeriod:    cfg.DB.ReplicaCheckPeriod,
+					Tracer:                tracing.QueryTracer{},
+				})
+			},
+			func(pool *db.DB) db.Transactor { return pool },
+			healthRepo.NewRepo,
+		}
+		storage = db.DriverPostgres
+	}
+	for _, dep := range deps {
+		if err := container.Provide(dep); err != nil {
+			return fmt.Errorf("failed to provide dependencies: %w", err)
+		}
+	}
+	for _, repo := range repositories[storage] {
+		if err := container.Provide(repo.constructor, dig.As(repo.as)); err != nil {
+			return fmt.Errorf("failed to provide dependency: %w", err)
+		}
+	}
+	return nil
+}
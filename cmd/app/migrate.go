@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"prodcrud/internal/config"
+	"prodcrud/migrations"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/migration"
+	"strconv"
+	"strings"
+)
+
+// splitCommand separates the leading command words, such as "migrate goto 3",
+// from the flags that follow them.
+func splitCommand(args []string) (command, rest []string) {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			return args[:i], args[i:]
+		}
+	}
+	return args, nil
+}
+
+// stripFlag removes a boolean flag given as -name or --name and reports
+// whether it was present.
+func stripFlag(args []string, name string) ([]string, bool) {
+	out := make([]string, 0, len(args))
+	found := false
+	for _, arg := range args {
+		if arg == "-"+name || arg == "--"+name {
+			found = true
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out, found
+}
+
+func createMigration(names, args []string) error {
+	set := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := set.String("dir", "migrations", "directory holding the migrations")
+	if err := set.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	names = append(names, set.Args()...)
+	if len(names) != 1 {
+		return errors.New("usage: app migrate create NAME [-dir migrations]")
+	}
+	paths, err := migration.Create(*dir, names[0])
+	if err != nil {
+		return err //nolint:wrapcheck //already wrapped
+	}
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+	return nil
+}
+
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing migrate subcommand\n" + usage)
+	}
+	if cfg.Storage != config.StorageDB {
+		return fmt.Errorf("migrations need a database, STORAGE is %s", cfg.Storage)
+	}
+	ctx := context.Background()
+	migrator := migration.New(migrations.FS, cfg.DB.DSN)
+	migrator.LockTimeout = cfg.Migrations.LockTimeout
+	source := fs.FS(migrations.FS)
+	if db.DriverOf(cfg.DB.DSN) == db.DriverSQLite {
+		conn, err := db.OpenSQLite(ctx, db.Config{DSN: cfg.DB.DSN})
+		if err != nil {
+			return err //nolint:wrapcheck //already wrapped
+		}
+		defer conn.Close()
+		migrator = migration.NewSQLite(migrations.SQLite, conn.DB)
+		source = migrations.SQLite
+	}
+
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx) //nolint:wrapcheck //already wrapped
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		return migrator.Down(ctx, steps) //nolint:wrapcheck //already wrapped
+	case "goto":
+		if len(args) != 2 {
+			return errors.New("usage: app migrate goto VERSION")
+		}
+		version, err := strconv.ParseUint(args[1], 10, 0)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return migrator.Goto(ctx, uint(version)) //nolint:wrapcheck //already wrapped
+	case "force":
+		if len(args) != 2 {
+			return errors.New("usage: app migrate force VERSION")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return migrator.Force(ctx, version) //nolint:wrapcheck //already wrapped
+	case "version":
+		version, dirty, ok, err := migrator.Version(ctx)
+		if err != nil {
+			return err //nolint:wrapcheck //already wrapped
+		}
+		latest, err := migration.LatestVersion(source)
+		if err != nil {
+			return err //nolint:wrapcheck //already wrapped
+		}
+		switch {
+		case !ok:
+			fmt.Fprintf(os.Stdout, "no migrations applied, latest %d\n", latest)
+		case dirty:
+			fmt.Fprintf(os.Stdout, "version %d (dirty), latest %d\n", version, latest)
+		default:
+			fmt.Fprintf(os.Stdout, "version %d, latest %d\n", version, latest)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q\n%s", args[0], usage)
+	}
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the application configuration. Every leaf field is described by tags:
+// key is the dotted name used in config files and as a flag, env is the
+// environment variable, default is applied when no source sets the value and
+// secret redacts the value when the config is printed.
+type Config struct {
+	Storage         string        `key:"storage" env:"STORAGE" default:"db" usage:"product storage: db (postgres or sqlite, by dsn scheme) or memory"`
+	HTTP            HTTP          `key:"http"`
+	DB              DB            `key:"db"`
+	Migrations      Migrations    `key:"migrations"`
+	Metrics         Metrics       `key:"metrics"`
+	Tracing         Tracing       `key:"tracing"`
+	Log             Log           `key:"log"`
+	Health          Health        `key:"health"`
+	Admin           Admin         `key:"admin"`
+	Purge           Purge         `key:"purge"`
+	Pricing         Pricing       `key:"pricing"`
+	Images          Images        `key:"images"`
+	Reorder         Reorder       `key:"reorder"`
+	ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"15s" usage:"time allowed to drain connections on shutdown"`
+}
+
+const (
+	// StorageDB uses the database named by db.dsn, Postgres or SQLite.
+	StorageDB = "db"
+	// StorageMemory keeps products in process memory, for demos and tests.
+	StorageMemory = "memory"
+)
+
+type HTTP struct {
+	Host              string        `key:"host" env:"HOST" usage:"address to listen on"`
+	Port              string        `key:"port" env:"PORT" default:"8080" usage:"port to listen on"`
+	ReadTimeout       time.Duration `key:"read_timeout" env:"HTTP_READ_TIMEOUT" default:"15s" usage:"max duration for reading a request"`
+	ReadHeaderTimeout time.Duration `key:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" default:"5s" usage:"max duration for reading request headers"`
+	WriteTimeout      time.Duration `key:"write_timeout" env:"HTTP_WRITE_TIMEOUT" default:"30s" usage:"max duration for writing a response"`
+	IdleTimeout       time.Duration `key:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" default:"60s" usage:"max keep-alive idle duration"`
+}
+
+type DB struct {
+	DSN                   string        `key:"dsn" env:"DSN" secret:"url" usage:"postgres connection string"`
+	MaxConns              int32         `key:"max_conns" env:"DB_MAX_CONNS" default:"10" usage:"max open connections in the pool"`
+	MinConns              int32         `key:"min_conns" env:"DB_MIN_CONNS" default:"0" usage:"connections kept open when idle"`
+	MaxConnLifetime       time.Duration `key:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME" default:"1h" usage:"max lifetime of a connection"`
+	MaxConnIdleTime       time.Duration `key:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME" default:"30m" usage:"max idle time of a connection"`
+	MaxConnLifetimeJitter time.Duration `key:"max_conn_lifetime_jitter" env:"DB_MAX_CONN_LIFETIME_JITTER" default:"5m" usage:"random jitter added to the connection lifetime"`
+	HealthCheckPeriod     time.Duration `key:"health_check_period" env:"DB_HEALTH_CHECK_PERIOD" default:"1m" usage:"interval between idle connection health checks"`
+	StartupTimeout        time.Duration `key:"startup_timeout" env:"DB_STARTUP_TIMEOUT" default:"30s" usage:"how long to retry connecting on startup"`
+	ConnectTimeout        time.Duration `key:"connect_timeout" env:"DB_CONNECT_TIMEOUT" default:"5s" usage:"timeout of a single connection attempt"`
+	ReadTimeout           time.Duration `key:"read_timeout" env:"DB_READ_TIMEOUT" default:"5s" usage:"statement timeout for reads"`
+	WriteTimeout          time.Duration `key:"write_timeout" env:"DB_WRITE_TIMEOUT" default:"10s" usage:"statement timeout for writes"`
+	ReadRetries           int           `key:"read_retries" env:"DB_READ_RETRIES" default:"3" usage:"retries of reads failing with a transient error"`
+	RetryBackoff          time.Duration `key:"retry_backoff" env:"DB_RETRY_BACKOFF" default:"100ms" usage:"initial delay between retries"`
+	ReplicaDSNs           []string      `key:"replica_dsns" env:"DB_REPLICA_DSNS" secret:"url" usage:"comma separated read replica connection strings"`
+	ReplicaMaxLag         time.Duration `key:"replica_max_lag" env:"DB_REPLICA_MAX_LAG" default:"0s" usage:"eject replicas lagging more than this, 0 disables"`
+	ReplicaCheckPeriod    time.Duration `key:"replica_check_period" env:"DB_REPLICA_CHECK_PERIOD" default:"5s" usage:"interval between replica health checks"`
+}
+
+type Migrations struct {
+	Auto        bool          `key:"auto" env:"MIGRATIONS_AUTO" default:"true" usage:"apply pending migrations when serve starts, --no-migrate disables"`
+	LockTimeout time.Duration `key:"lock_timeout" env:"MIGRATIONS_LOCK_TIMEOUT" default:"1m" usage:"how long to wait for a concurrent migration"`
+}
+
+type Metrics struct {
+	RefreshInterval   time.Duration `key:"refresh_interval" env:"METRICS_REFRESH_INTERVAL" default:"30s" usage:"interval between business gauge refreshes"`
+	LowStockThreshold int           `key:"low_stock_threshold" env:"METRICS_LOW_STOCK_THRESHOLD" default:"10" usage:"quantity at or below which a product counts as low stock"`
+}
+
+type Tracing struct {
+	ServiceName string  `key:"service_name" env:"OTEL_SERVICE_NAME" default:"prodcrud" usage:"service name reported in traces"`
+	Exporter    string  `key:"exporter" env:"TRACING_EXPORTER" default:"none" usage:"trace exporter: none, otlp, stdout or file"`
+	Endpoint    string  `key:"endpoint" env:"TRACING_OTLP_ENDPOINT" default:"localhost:4318" usage:"OTLP/HTTP collector address"`
+	Insecure    bool    `key:"insecure" env:"TRACING_OTLP_INSECURE" default:"true" usage:"send OTLP over plain HTTP"`
+	File        string  `key:"file" env:"TRACING_FILE" default:"traces.json" usage:"destination of the file exporter"`
+	SampleRatio float64 `key:"sample_ratio" env:"TRACING_SAMPLE_RATIO" default:"1" usage:"fraction of new traces to sample"`
+}
+
+type Log struct {
+	Level    string   `key:"level" env:"LOG_LEVEL" default:"info" usage:"default log level: debug, info, warn or error"`
+	Packages []string `key:"packages" env:"LOG_PACKAGE_LEVELS" usage:"comma separated per-package levels, e.g. repository/product=debug"`
+}
+
+type Health struct {
+	CheckTimeout time.Duration `key:"check_timeout" env:"HEALTH_CHECK_TIMEOUT" default:"2s" usage:"timeout of a single readiness check"`
+	CacheTTL     time.Duration `key:"cache_ttl" env:"HEALTH_CACHE_TTL" default:"2s" usage:"how long a readiness report is reused"`
+	DiskPath     string        `key:"disk_path" env:"HEALTH_DISK_PATH" default:"/tmp" usage:"directory whose free space is checked, empty disables"`
+	DiskMinFree  int64         `key:"disk_min_free" env:"HEALTH_DISK_MIN_FREE" default:"104857600" usage:"minimum free bytes at disk_path"`
+}
+
+type Admin struct {
+	Token string `key:"token" env:"ADMIN_TOKEN" secret:"true" usage:"token expected in X-Admin-Token for admin operations, empty disables them"`
+}
+
+type Purge struct {
+	Retention time.Duration `key:"retention" env:"PURGE_RETENTION" default:"720h" usage:"how long deleted products stay restorable before they are purged, 0 disables"`
+	Interval  time.Duration `key:"interval" env:"PURGE_INTERVAL" default:"1h" usage:"interval between purge runs"`
+}
+
+type Pricing struct {
+	ScheduleInterval time.Duration `key:"schedule_interval" env:"PRICE_SCHEDULE_INTERVAL" default:"1m" usage:"max interval between checks for scheduled price changes"`
+}
+
+type Images struct {
+	Store         string `key:"store" env:"IMAGES_STORE" default:"file" usage:"where image files are kept: file or s3"`
+	Dir           string `key:"dir" env:"IMAGES_DIR" default:"data/images" usage:"directory of the file store"`
+	PublicURL     string `key:"public_url" env:"IMAGES_PUBLIC_URL" default:"/media" usage:"prefix of image URLs, /media serves the files from this service"`
+	MaxSize       int64  `key:"max_size" env:"IMAGES_MAX_SIZE" default:"10485760" usage:"largest upload in bytes"`
+	ThumbnailSize int    `key:"thumbnail_size" env:"IMAGES_THUMBNAIL_SIZE" default:"256" usage:"max width and height of thumbnails in pixels"`
+	S3Endpoint    string `key:"s3_endpoint" env:"IMAGES_S3_ENDPOINT" usage:"base URL of the S3 compatible store, buckets addressed in the path"`
+	S3Region      string `key:"s3_region" env:"IMAGES_S3_REGION" default:"us-east-1" usage:"region the requests are signed for"`
+	S3Bucket      string `key:"s3_bucket" env:"IMAGES_S3_BUCKET" usage:"bucket of the image files"`
+	S3AccessKey   string `key:"s3_access_key" env:"IMAGES_S3_ACCESS_KEY" secret:"true" usage:"access key of the S3 store"`
+	S3SecretKey   string `key:"s3_secret_key" env:"IMAGES_S3_SECRET_KEY" secret:"true" usage:"secret key of the S3 store"`
+}
+
+type Reorder struct {
+	Interval      time.Duration `key:"interval" env:"REORDER_INTERVAL" default:"5m" usage:"interval between evaluations of the reorder points, 0 disables"`
+	AutoDraft     bool          `key:"auto_draft" env:"REORDER_AUTO_DRAFT" default:"false" usage:"draft a purchase order from the supplier of the rule when an alert is raised"`
+	Notifiers     []string      `key:"notifiers" env:"REORDER_NOTIFIERS" default:"log" usage:"comma separated destinations of the stock alerts: log, webhook, smtp"`
+	WebhookURL    string        `key:"webhook_url" env:"REORDER_WEBHOOK_URL" secret:"true" usage:"URL the alerts are posted to as JSON"`
+	WebhookSecret string        `key:"webhook_secret" env:"REORDER_WEBHOOK_SECRET" secret:"true" usage:"key of the HMAC-SHA256 signature in X-Signature-256, empty sends unsigned"`
+	SMTPAddr      string        `key:"smtp_addr" env:"REORDER_SMTP_ADDR" usage:"host:port of the mail server"`
+	SMTPFrom      string        `key:"smtp_from" env:"REORDER_SMTP_FROM" usage:"sender address of the alert emails"`
+	SMTPTo        []string      `key:"smtp_to" env:"REORDER_SMTP_TO" usage:"comma separated recipients of the alert emails"`
+	SMTPUsername  string        `key:"smtp_username" env:"REORDER_SMTP_USERNAME" usage:"user to authenticate as, empty sends without auth"`
+	SMTPPassword  string        `key:"smtp_password" env:"REORDER_SMTP_PASSWORD" secret:"true" usage:"password of the mail server user"`
+}
+
+func (c *Config) Addr() string {
+	return net.JoinHostPort(c.HTTP.Host, c.HTTP.Port)
+}
+
+func (c *Config) validate() []FieldError {
+	var errs []FieldError
+	add := func(key, msg string) {
+		errs = append(errs, FieldError{Key: key, Message: msg})
+	}
+
+	if port, err := strconv.Atoi(c.HTTP.Port); err != nil || port < 1 || port > 65535 {
+		add("http.port", "must be a port number between 1 and 65535")
+	}
+	for key, d := range map[string]time.Duration{
+		"http.read_timeout":           c.HTTP.ReadTimeout,
+		"http.read_header_timeout":    c.HTTP.ReadHeaderTimeout,
+		"http.write_timeout":          c.HTTP.WriteTimeout,
+		"http.idle_timeout":           c.HTTP.IdleTimeout,
+		"db.max_conn_lifetime":        c.DB.MaxConnLifetime,
+		"db.max_conn_idle_time":       c.DB.MaxConnIdleTime,
+		"db.max_conn_lifetime_jitter": c.DB.MaxConnLifetimeJitter,
+		"db.health_check_period":      c.DB.HealthCheckPeriod,
+		"db.startup_timeout":          c.DB.StartupTimeout,
+		"db.connect_timeout":          c.DB.ConnectTimeout,
+		"db.read_timeout":             c.DB.ReadTimeout,
+		"db.write_timeout":            c.DB.WriteTimeout,
+		"db.retry_backoff":            c.DB.RetryBackoff,
+		"db.replica_max_lag":          c.DB.ReplicaMaxLag,
+		"db.replica_check_period":     c.DB.ReplicaCheckPeriod,
+		"health.check_timeout":        c.Health.CheckTimeout,
+		"health.cache_ttl":            c.Health.CacheTTL,
+		"purge.retention":             c.Purge.Retention,
+		"reorder.interval":            c.Reorder.Interval,
+	} {
+		if d < 0 {
+			add(key, "cannot be negative")
+		}
+	}
+	if c.ShutdownTimeout <= 0 {
+		add("shutdown_timeout", "must be positive")
+	}
+
+	switch c.Storage {
+	case StorageDB:
+		if c.DB.DSN == "" {
+			add("db.dsn", "is required")
+		} else if db.DriverOf(c.DB.DSN) == "" {
+			add("db.dsn", "must be a postgres:// or sqlite:// url")
+		}
+	case StorageMemory:
+	default:
+		add("storage", "must be one of db, memory")
+	}
+	for _, dsn := range c.DB.ReplicaDSNs {
+		if db.DriverOf(dsn) != db.DriverPostgres {
+			add("db.replica_dsns", "must be a list of postgres:// urls")
+			break
+		}
+	}
+	if len(c.DB.ReplicaDSNs) > 0 && db.DriverOf(c.DB.DSN) == db.DriverSQLite {
+		add("db.replica_dsns", "replicas need a postgres primary")
+	}
+	if c.DB.MaxConns < 1 {
+		add("db.max_conns", "must be at least 1")
+	}
+	if c.DB.MinConns < 0 || c.DB.MinConns > c.DB.MaxConns {
+		add("db.min_conns", "must be between 0 and db.max_conns")
+	}
+	if c.DB.ReadRetries < 0 {
+		add("db.read_retries", "cannot be negative")
+	}
+	if c.Metrics.RefreshInterval <= 0 {
+		add("metrics.refresh_interval", "must be positive")
+	}
+	if c.Metrics.LowStockThreshold < 0 {
+		add("metrics.low_stock_threshold", "cannot be negative")
+	}
+	switch c.Tracing.Exporter {
+	case "none", "otlp", "stdout", "file":
+	default:
+		add("tracing.exporter", "must be one of none, otlp, stdout, file")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio", "must be between 0 and 1")
+	}
+	if c.Health.DiskMinFree < 0 {
+		add("health.disk_min_free", "cannot be negative")
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		add("log.level", "must be one of debug, info, warn, error")
+	}
+	if _, err := logging.ParseLevels(c.Log.Packages); err != nil {
+		add("log.packages", err.Error())
+	}
+	if c.Purge.Retention > 0 && c.Purge.Interval <= 0 {
+		add("purge.interval", "must be positive")
+	}
+	if c.Pricing.ScheduleInterval <= 0 {
+		add("pricing.schedule_interval", "must be positive")
+	}
+	if c.Migrations.LockTimeout <= 0 {
+		add("migrations.lock_timeout", "must be positive")
+	}
+	switch c.Images.Store {
+	case "file":
+		if c.Images.Dir == "" {
+			add("images.dir", "is required by the file store")
+		}
+	case "s3":
+		for key, v := range map[string]string{
+			"images.s3_endpoint":   c.Images.S3Endpoint,
+			"images.s3_region":     c.Images.S3Region,
+			"images.s3_bucket":     c.Images.S3Bucket,
+			"images.s3_access_key": c.Images.S3AccessKey,
+			"images.s3_secret_key": c.Images.S3SecretKey,
+		} {
+			if v == "" {
+				add(key, "is required by the s3 store")
+			}
+		}
+	default:
+		add("images.store", "must be one of file, s3")
+	}
+	if c.Images.MaxSize < 1 {
+		add("images.max_size", "must be positive")
+	}
+	if c.Images.ThumbnailSize < 1 {
+		add("images.thumbnail_size", "must be positive")
+	}
+	for _, n := range c.Reorder.Notifiers {
+		switch n {
+		case "log":
+		case "webhook":
+			if c.Reorder.WebhookURL == "" {
+				add("reorder.webhook_url", "is required by the webhook notifier")
+			}
+		case "smtp":
+			for key, v := range map[string]string{
+				"reorder.smtp_addr": c.Reorder.SMTPAddr,
+				"reorder.smtp_from": c.Reorder.SMTPFrom,
+			} {
+				if v == "" {
+					add(key, "is required by the smtp notifier")
+				}
+			}
+			if len(c.Reorder.SMTPTo) == 0 {
+				add("reorder.smtp_to", "is required by the smtp notifier")
+			}
+		default:
+			add("reorder.notifiers", "must be a list of log, webhook, smtp")
+		}
+	}
+	return errs
+}
+
+// String prints every setting as key = value with secrets redacted.
+func (c *Config) String() string {
+	var b strings.Builder
+	for _, f := range fields(reflect.ValueOf(c).Elem(), "") {
+		value := format(f.value)
+		if f.value.Kind() == reflect.Slice {
+			items := strings.Split(value, ",")
+			for i := range items {
+				items[i] = redact(f.secret, items[i])
+			}
+			value = strings.Join(items, ",")
+		} else {
+			value = redact(f.secret, value)
+		}
+		fmt.Fprintf(&b, "%s = %s\n", f.key, value)
+	}
+	return b.String()
+}
+
+func redact(kind, value string) string {
+	switch {
+	case value == "" || kind == "":
+		return value
+	case kind == "url":
+		u, err := url.Parse(value)
+		if err != nil {
+			return "[REDACTED]"
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+		q := u.Query()
+		if q.Has("password") {
+			q.Set("password", "xxxxx")
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	default:
+		return "[REDACTED]"
+	}
+}
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError describes a single invalid setting.
+type FieldError struct {
+	Key     string
+	Env     string
+	Message string
+}
+
+func (e FieldError) String() string {
+	if e.Env == "" {
+		return fmt.Sprintf("%s: %s", e.Key, e.Message)
+	}
+	return fmt.Sprintf("%s (%s): %s", e.Key, e.Env, e.Message)
+}
+
+// ValidationError lists every invalid setting found while loading.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		lines = append(lines, "  "+f.String())
+	}
+	return "invalid configuration:\n" + strings.Join(lines, "\n")
+}
+
+type field struct {
+	key    string
+	env    string
+	def    string
+	usage  string
+	secret string
+	value  reflect.Value
+}
+
+// Load builds the configuration from, in increasing order of precedence:
+// defaults, a YAML or TOML file (-config flag or CONFIG_FILE), the environment
+// (a .env file, if present, only fills variables that are not already set) and
+// command line flags named after the dotted keys, e.g. -http.port.
+func Load(args []string) (*Config, error) {
+	var cfg Config
+	all := fields(reflect.ValueOf(&cfg).Elem(), "")
+
+	set := flag.NewFlagSet("app", flag.ContinueOnError)
+	configFile := set.String("config", "", "path to a YAML or TOML config file (env CONFIG_FILE)")
+	envFile := set.String("env-file", ".env", "path to an optional .env file")
+	flags := make(map[string]string)
+	for _, f := range all {
+		set.Func(f.key, f.usage, func(s string) error {
+			flags[f.key] = s
+			return nil
+		})
+	}
+	if err := set.Parse(args); err != nil {
+		return nil, fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	if err := godotenv.Load(*envFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load %s: %w", *envFile, err)
+	}
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
+
+	values := make(map[string]string, len(all))
+	for _, f := range all {
+		values[f.key] = f.def
+	}
+
+	var errs []FieldError
+	if *configFile != "" {
+		fileValues, err := readFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+		for key, v := range fileValues {
+			if _, ok := values[key]; !ok {
+				errs = append(errs, FieldError{Key: key, Message: "unknown key in " + *configFile})
+				continue
+			}
+			values[key] = v
+		}
+	}
+	for _, f := range all {
+		if v, ok := os.LookupEnv(f.env); ok && f.env != "" {
+			values[f.key] = v
+		}
+	}
+	for key, v := range flags {
+		values[key] = v
+	}
+
+	envs := make(map[string]string, len(all))
+	for _, f := range all {
+		envs[f.key] = f.env
+		if err := setValue(f.value, values[f.key]); err != nil {
+			errs = append(errs, FieldError{Key: f.key, Message: err.Error()})
+		}
+	}
+	invalid := make(map[string]bool, len(errs))
+	for _, e := range errs {
+		invalid[e.Key] = true
+	}
+	for _, e := range cfg.validate() {
+		if !invalid[e.Key] {
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) > 0 {
+		for i := range errs {
+			errs[i].Env = envs[errs[i].Key]
+		}
+		sortErrors(errs)
+		return nil, &ValidationError{Fields: errs}
+	}
+	return &cfg, nil
+}
+
+func fields(v reflect.Value, prefix string) []field {
+	var out []field
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		key := sf.Tag.Get("key")
+		if key == "" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		fv := v.Field(i)
+		if sf.Type.Kind() == reflect.Struct {
+			out = append(out, fields(fv, key)...)
+			continue
+		}
+		out = append(out, field{
+			key:    key,
+			env:    sf.Tag.Get("env"),
+			def:    sf.Tag.Get("default"),
+			usage:  sf.Tag.Get("usage"),
+			secret: sf.Tag.Get("secret"),
+			value:  fv,
+		})
+	}
+	return out
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+func setValue(v reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+	if v.Type() == durationType {
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	//nolint:exhaustive //only the kinds used by Config are supported
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		if s == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		if s == "" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func format(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range v.Len() {
+			items[i] = v.Index(i).String()
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := make(map[string]any)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	out := make(map[string]string)
+	flatten(raw, "", out)
+	return out, nil
+}
+
+func flatten(m map[string]any, prefix string, out map[string]string) {
+	for k, v := range m {
+		if prefix != "" {
+			k = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]any:
+			flatten(val, k, out)
+		case []any:
+			items := make([]string, len(val))
+			for i, item := range val {
+				items[i] = fmt.Sprint(item)
+			}
+			out[k] = strings.Join(items, ",")
+		case nil:
+			out[k] = ""
+		default:
+			out[k] = fmt.Sprint(val)
+		}
+	}
+}
+
+func sortErrors(errs []FieldError) {
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Key < errs[j].Key })
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"prodcrud/pkg/db"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolStats is the source of the connection pool metrics, implemented by *db.DB.
+type PoolStats interface {
+	Stats() db.Stats
+}
+
+type poolCollector struct {
+	src   PoolStats
+	descs map[string]*prometheus.Desc
+}
+
+var poolMetrics = []struct {
+	name  string
+	help  string
+	kind  prometheus.ValueType
+	value func(s db.Stats) float64
+}{
+	{"max_conns", "Maximum size of the pool.", prometheus.GaugeValue,
+		func(s db.Stats) float64 { return float64(s.MaxConns) }},
+	{"total_conns", "Connections currently open.", prometheus.GaugeValue,
+		func(s db.Stats) float64 { return float64(s.TotalConns) }},
+	{"acquired_conns", "Connections currently in use.", prometheus.GaugeValue,
+		func(s db.Stats) float64 { return float64(s.AcquiredConns) }},
+	{"idle_conns", "Idle connections.", prometheus.GaugeValue,
+		func(s db.Stats) float64 { return float64(s.IdleConns) }},
+	{"constructing_conns", "Connections being established.", prometheus.GaugeValue,
+		func(s db.Stats) float64 { return float64(s.ConstructingConns) }},
+	{"acquires_total", "Successful connection acquires.", prometheus.CounterValue,
+		func(s db.Stats) float64 { return float64(s.AcquireCount) }},
+	{"acquire_seconds_total", "Time spent acquiring connections.", prometheus.CounterValue,
+		func(s db.Stats) float64 { return s.AcquireDuration.Seconds() }},
+	{"empty_acquires_total", "Acquires that had to wait for a connection.", prometheus.CounterValue,
+		func(s db.Stats) float64 { return float64(s.EmptyAcquireCount) }},
+	{"canceled_acquires_total", "Acquires canceled by their context.", prometheus.CounterValue,
+		func(s db.Stats) float64 { return float64(s.CanceledAcquireCount) }},
+	{"new_conns_total", "Connections opened.", prometheus.CounterValue,
+		func(s db.Stats) float64 { return float64(s.NewConnsCount) }},
+	{"reads_total", "Read calls.", prometheus.CounterValue,
+		func(s db.Stats) float64 { return float64(s.Reads) }},
+	{"writes_total", "Write calls.", prometheus.CounterValue,
+		func(s db.Stats) float64 { return float64(s.Writes) }},
+	{"retries_total", "Read retries after transient errors.", prometheus.CounterValue,
+		func(s db.Stats) float64 { return float64(s.Retries) }},
+	{"timeouts_total", "Statements that hit their timeout.", prometheus.CounterValue,
+		func(s db.Stats) float64 { return float64(s.Timeouts) }},
+	{"replica_reads_total", "Reads served by a replica.", prometheus.CounterValue,
+		func(s db.Stats) float64 { return float64(s.ReplicaReads) }},
+	{"healthy_replicas", "Replicas currently in rotation.", prometheus.GaugeValue,
+		func(s db.Stats) float64 { return float64(s.HealthyReplicas) }},
+}
+
+// RegisterPool exports the pool statistics, read on every scrape.
+func (m *Metrics) RegisterPool(src PoolStats) {
+	c := &poolCollector{src: src, descs: make(map[string]*prometheus.Desc, len(poolMetrics))}
+	for _, pm := range poolMetrics {
+		c.descs[pm.name] = prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", pm.name), pm.help, nil, nil)
+	}
+	m.registry.MustRegister(c)
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.src.Stats()
+	for _, pm := range poolMetrics {
+		ch <- prometheus.MustNewConstMetric(c.descs[pm.name], pm.kind, pm.value(s))
+	}
+}
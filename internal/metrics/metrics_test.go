@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"prodcrud/internal/models"
+	productService "prodcrud/internal/usecase/product"
+	"prodcrud/pkg/db"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+	r := gin.New()
+	r.Use(m.Middleware())
+	r.GET("/products/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+
+	for _, path := range []string{"/products/1", "/products/2", "/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.InDelta(t, 2, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/products/:id", "404")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "unmatched", "404")), 0)
+}
+
+func TestRepository(t *testing.T) {
+	m := New()
+	next := new(productService.Mock)
+	next.On("GetProduct", mock.Anything, int64(1), false).Return(&models.Product{ID: 1}, nil).Once()
+	next.On("GetProduct", mock.Anything, int64(2), false).Return((*models.Product)(nil), errors.New("boom")).Once()
+	repo := NewRepository(next, m)
+
+	p, err := repo.GetProduct(context.Background(), 1, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), p.ID)
+	_, err = repo.GetProduct(context.Background(), 2, false)
+	assert.Error(t, err)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(m.queryDuration, "prodcrud_repository_query_duration_seconds"))
+	next.AssertExpectations(t)
+}
+
+func TestStockCollector(t *testing.T) {
+	m := New()
+	repo := new(productService.Mock)
+	repo.On("GetProductStats", mock.Anything, 5).Return(&models.ProductStats{
+		Active: 3, StockUnits: 42, LowStock: 1,
+	}, nil).Once()
+
+	m.StockCollector(repo, 0, 5).Refresh(context.Background())
+
+	assert.InDelta(t, 3, testutil.ToFloat64(m.activeProducts), 0)
+	assert.InDelta(t, 42, testutil.ToFloat64(m.stockUnits), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(m.lowStock), 0)
+}
+
+type fakePool db.Stats
+
+func (f fakePool) Stats() db.Stats { return db.Stats(f) }
+
+func TestRegisterPool(t *testing.T) {
+	m := New()
+	m.RegisterPool(fakePool{MaxConns: 10, AcquiredConns: 4, Retries: 7})
+
+	expected := `
+# HELP prodcrud_db_pool_acquired_conns Connections currently in use.
+# TYPE prodcrud_db_pool_acquired_conns gauge
+prodcrud_db_pool_acquired_conns 4
+# HELP prodcrud_db_pool_retries_total Read retries after transient errors.
+# TYPE prodcrud_db_pool_retries_total counter
+prodcrud_db_pool_retries_total 7
+`
+	err := testutil.GatherAndCompare(m.registry, strings.NewReader(expected),
+		"prodcrud_db_pool_acquired_conns", "prodcrud_db_pool_retries_total")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,244 @@
+package metrics
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	productService "prodcrud/internal/usecase/product"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"time"
+)
+
+const logPackage = "metrics"
+
+// Repository decorates a product.Repository with query duration metrics.
+type Repository struct {
+	next    product.Repository
+	metrics *Metrics
+}
+
+func NewRepository(next product.Repository, m *Metrics) product.Repository {
+	return &Repository{next: next, metrics: m}
+}
+
+func (r *Repository) CreateProduct(ctx context.Context, p *models.Product) (err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "CreateProduct", start, err) }(time.Now())
+	return r.next.CreateProduct(ctx, p)
+}
+
+func (r *Repository) GetProduct(ctx context.Context, id int64, includeDeleted bool) (_ *models.Product, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "GetProduct", start, err) }(time.Now())
+	return r.next.GetProduct(ctx, id, includeDeleted)
+}
+
+func (r *Repository) GetAllProducts(ctx context.Context, includeDeleted bool) (_ []*models.Product, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "GetAllProducts", start, err) }(time.Now())
+	return r.next.GetAllProducts(ctx, includeDeleted)
+}
+
+func (r *Repository) FindProducts(ctx context.Context, f models.ProductFilter) (_ []*models.Product, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "FindProducts", start, err) }(time.Now())
+	return r.next.FindProducts(ctx, f)
+}
+
+func (r *Repository) UpdateProduct(ctx context.Context, p *models.Product) (err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "UpdateProduct", start, err) }(time.Now())
+	return r.next.UpdateProduct(ctx, p)
+}
+
+func (r *Repository) AdjustQuantity(ctx context.Context, id int64, delta int) (_ int, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "AdjustQuantity", start, err) }(time.Now())
+	return r.next.AdjustQuantity(ctx, id, delta)
+}
+
+func (r *Repository) RestockQuantity(ctx context.Context, id int64, quantity int) (err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "RestockQuantity", start, err) }(time.Now())
+	return r.next.RestockQuantity(ctx, id, quantity)
+}
+
+func (r *Repository) DeleteProduct(ctx context.Context, id int64) (err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "DeleteProduct", start, err) }(time.Now())
+	return r.next.DeleteProduct(ctx, id)
+}
+
+func (r *Repository) RestoreProduct(ctx context.Context, id int64) (err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "RestoreProduct", start, err) }(time.Now())
+	return r.next.RestoreProduct(ctx, id)
+}
+
+func (r *Repository) GetProductStats(ctx context.Context, lowStock int) (_ *models.ProductStats, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "GetProductStats", start, err) }(time.Now())
+	return r.next.GetProductStats(ctx, lowStock)
+}
+
+func (r *Repository) GetDeletedProducts(ctx context.Context, before time.Time) (_ []*models.Product, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "GetDeletedProducts", start, err) }(time.Now())
+	return r.next.GetDeletedProducts(ctx, before)
+}
+
+func (r *Repository) HardDeleteProduct(ctx context.Context, id int64) (_ *models.PurgedProduct, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "HardDeleteProduct", start, err) }(time.Now())
+	return r.next.HardDeleteProduct(ctx, id)
+}
+
+func (r *Repository) PurgeProduct(ctx context.Context, id int64, before time.Time) (_ *models.PurgedProduct, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "PurgeProduct", start, err) }(time.Now())
+	return r.next.PurgeProduct(ctx, id, before)
+}
+
+func (r *Repository) GetProductPrices(ctx context.Context, id int64) (_ []money.Money, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "GetProductPrices", start, err) }(time.Now())
+	return r.next.GetProductPrices(ctx, id)
+}
+
+func (r *Repository) SetProductPrice(ctx context.Context, id int64, price money.Money) (err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "SetProductPrice", start, err) }(time.Now())
+	return r.next.SetProductPrice(ctx, id, price)
+}
+
+func (r *Repository) DeleteProductPrice(ctx context.Context, id int64, currency string) (err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "DeleteProductPrice", start, err) }(time.Now())
+	return r.next.DeleteProductPrice(ctx, id, currency)
+}
+
+func (r *Repository) GetPriceHistory(ctx context.Context, id int64) (_ []*models.PricePeriod, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "GetPriceHistory", start, err) }(time.Now())
+	return r.next.GetPriceHistory(ctx, id)
+}
+
+func (r *Repository) SchedulePrice(ctx context.Context, p *models.PricePeriod) (err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "SchedulePrice", start, err) }(time.Now())
+	return r.next.SchedulePrice(ctx, p)
+}
+
+func (r *Repository) ApplyScheduledPrices(ctx context.Context, now time.Time) (_ []*models.PricePeriod, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "ApplyScheduledPrices", start, err) }(time.Now())
+	return r.next.ApplyScheduledPrices(ctx, now)
+}
+
+func (r *Repository) NextPriceChange(ctx context.Context, after time.Time) (_ *time.Time, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "NextPriceChange", start, err) }(time.Now())
+	return r.next.NextPriceChange(ctx, after)
+}
+
+func (r *Repository) GetVariantAxes(ctx context.Context, id int64) (_ []models.VariantAxis, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "GetVariantAxes", start, err) }(time.Now())
+	return r.next.GetVariantAxes(ctx, id)
+}
+
+func (r *Repository) GetVariants(ctx context.Context, parentID int64, includeDeleted bool) (_ []*models.Product, err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "GetVariants", start, err) }(time.Now())
+	return r.next.GetVariants(ctx, parentID, includeDeleted)
+}
+
+func (r *Repository) CreateVariants(ctx context.Context, parentID int64, axes []models.VariantAxis, variants []*models.Product) (err error) {
+	defer func(start time.Time) { observe(r.metrics.queryDuration, "CreateVariants", start, err) }(time.Now())
+	return r.next.CreateVariants(ctx, parentID, axes, variants)
+}
+
+// Service decorates a product usecase with call duration metrics.
+type Service struct {
+	next    productService.ServiceInterface
+	metrics *Metrics
+}
+
+func NewService(next productService.ServiceInterface, m *Metrics) productService.ServiceInterface {
+	return &Service{next: next, metrics: m}
+}
+
+func (s *Service) CreateProduct(ctx context.Context, p *models.Product) (err error) {
+	defer func(start time.Time) { observe(s.metrics.serviceDuration, "CreateProduct", start, err) }(time.Now())
+	return s.next.CreateProduct(ctx, p)
+}
+
+func (s *Service) GetAllProducts(ctx context.Context, includeDeleted bool) (_ []*models.Product, err error) {
+	defer func(start time.Time) { observe(s.metrics.serviceDuration, "GetAllProducts", start, err) }(time.Now())
+	return s.next.GetAllProducts(ctx, includeDeleted)
+}
+
+func (s *Service) FindProducts(ctx context.Context, category string, attributes map[string]string, includeDeleted bool) (_ []*models.Product, err error) {
+	defer func(start time.Time) { observe(s.metrics.serviceDuration, "FindProducts", start, err) }(time.Now())
+	return s.next.FindProducts(ctx, category, attributes, includeDeleted)
+}
+
+func (s *Service) GetProduct(ctx context.Context, id int64, includeDeleted bool) (_ *models.Product, err error) {
+	defer func(start time.Time) { observe(s.metrics.serviceDuration, "GetProduct", start, err) }(time.Now())
+	return s.next.GetProduct(ctx, id, includeDeleted)
+}
+
+func (s *Service) UpdateProduct(ctx context.Context, p *models.Product) (err error) {
+	defer func(start time.Time) { observe(s.metrics.serviceDuration, "UpdateProduct", start, err) }(time.Now())
+	return s.next.UpdateProduct(ctx, p)
+}
+
+func (s *Service) DeleteProduct(ctx context.Context, id int64) (err error) {
+	defer func(start time.Time) { observe(s.metrics.serviceDuration, "DeleteProduct", start, err) }(time.Now())
+	return s.next.DeleteProduct(ctx, id)
+}
+
+func (s *Service) RestoreProduct(ctx context.Context, id int64) (err error) {
+	defer func(start time.Time) { observe(s.metrics.serviceDuration, "RestoreProduct", start, err) }(time.Now())
+	return s.next.RestoreProduct(ctx, id)
+}
+
+func (s *Service) HardDeleteProduct(ctx context.Context, id int64) (err error) {
+	defer func(start time.Time) { observe(s.metrics.serviceDuration, "HardDeleteProduct", start, err) }(time.Now())
+	return s.next.HardDeleteProduct(ctx, id)
+}
+
+func (s *Service) GetDeletedProducts(ctx context.Context) (_ []*models.Product, err error) {
+	defer func(start time.Time) { observe(s.metrics.serviceDuration, "GetDeletedProducts", start, err) }(time.Now())
+	return s.next.GetDeletedProducts(ctx)
+}
+
+func (s *Service) GetVariants(ctx context.Context, parentID int64) (_ []*models.Product, err error) {
+	defer func(start time.Time) { observe(s.metrics.serviceDuration, "GetVariants", start, err) }(time.Now())
+	return s.next.GetVariants(ctx, parentID)
+}
+
+func (s *Service) GenerateVariants(ctx context.Context, parentID int64, req models.VariantMatrixRequest) (_ *models.VariantMatrix, err error) {
+	defer func(start time.Time) { observe(s.metrics.serviceDuration, "GenerateVariants", start, err) }(time.Now())
+	return s.next.GenerateVariants(ctx, parentID, req)
+}
+
+func (s *Service) CreateVariant(ctx context.Context, parentID int64, req models.VariantRequest) (_ *models.Product, err error) {
+	defer func(start time.Time) { observe(s.metrics.serviceDuration, "CreateVariant", start, err) }(time.Now())
+	return s.next.CreateVariant(ctx, parentID, req)
+}
+
+// StockCollector periodically refreshes the business gauges from the repository.
+type StockCollector struct {
+	repo     product.Repository
+	metrics  *Metrics
+	interval time.Duration
+	lowStock int
+}
+
+func (m *Metrics) StockCollector(repo product.Repository, interval time.Duration, lowStock int) *StockCollector {
+	return &StockCollector{repo: repo, metrics: m, interval: interval, lowStock: lowStock}
+}
+
+func (c *StockCollector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		c.Refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *StockCollector) Refresh(ctx context.Context) {
+	st, err := c.repo.GetProductStats(ctx, c.lowStock)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to refresh product metrics", "error", err)
+		return
+	}
+	c.metrics.activeProducts.Set(float64(st.Active))
+	c.metrics.stockUnits.Set(float64(st.StockUnits))
+	c.metrics.lowStock.Set(float64(st.LowStock))
+}
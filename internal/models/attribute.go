@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// The attribute types. A product stores each attribute in the normal form
+// of its type: a trimmed string, a float64 number, one of the enum values,
+// a bool, a date as YYYY-MM-DD, or a unit value {"value": 13.3, "unit": "in"}.
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeEnum    = "enum"
+	AttributeBoolean = "boolean"
+	AttributeDate    = "date"
+	AttributeUnit    = "unit"
+)
+
+// AttributeDefinition is one attribute of the schema of a category.
+type AttributeDefinition struct {
+	Category string `json:"category" example:"laptops"`
+	Name     string `json:"name" example:"screen"`
+	Type     string `json:"type" enums:"string,number,enum,boolean,date,unit"`
+	// Required attributes must be set on every product of the category.
+	Required bool `json:"required"`
+	// Values lists the choices of an enum attribute.
+	Values []string `json:"values,omitempty" example:"ips,oled"`
+	// Units lists the units of measure a unit attribute accepts.
+	Units     []string  `json:"units,omitempty" example:"in,cm"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AttributeDefinitionRequest creates or replaces an attribute; the category
+// and the name come from the path.
+type AttributeDefinitionRequest struct {
+	Type     string   `json:"type" enums:"string,number,enum,boolean,date,unit"`
+	Required bool     `json:"required"`
+	Values   []string `json:"values,omitempty" example:"ips,oled"`
+	Units    []string `json:"units,omitempty" example:"in,cm"`
+}
+
+// ProductFilter narrows a product listing.
+type ProductFilter struct {
+	// Category is matched exactly, empty for any.
+	Category string
+	// Attributes holds normalised attribute values the products must
+	// contain; a unit value without a unit matches the value in any unit.
+	Attributes     map[string]any
+	IncludeDeleted bool
+}
+
+// HasAttributes reports whether the product attributes contain every value
+// of want, with the containment of a JSONB @>: maps match on their own
+// keys, other values on equality.
+func (p *Product) HasAttributes(want map[string]any) bool {
+	return containsAll(p.Attributes, want)
+}
+
+func containsAll(have, want map[string]any) bool {
+	for k, w := range want {
+		h, ok := have[k]
+		if !ok || !contains(h, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(have, want any) bool {
+	if w, ok := want.(map[string]any); ok {
+		h, ok := have.(map[string]any)
+		return ok && containsAll(h, w)
+	}
+	return have == want
+}
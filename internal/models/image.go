@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ProductImage is an uploaded image of a product with its thumbnail.
+type ProductImage struct {
+	CreatedAt time.Time `json:"created_at"`
+	// Key and ThumbnailKey locate the files in the blob store.
+	Key          string `json:"-"`
+	ThumbnailKey string `json:"-"`
+	// URL and ThumbnailURL are derived from the keys when the image is
+	// served.
+	URL          string `json:"url" example:"/media/products/1/5f2b9c.jpg"`
+	ThumbnailURL string `json:"thumbnail_url" example:"/media/products/1/5f2b9c_thumb.jpg"`
+	ContentType  string `json:"content_type" example:"image/jpeg"`
+	ID           int64  `json:"id"`
+	ProductID    int64  `json:"product_id"`
+	Size         int64  `json:"size" example:"183204"`
+	Width        int    `json:"width" example:"1600"`
+	Height       int    `json:"height" example:"1200"`
+	// Position orders the images of a product, from 1.
+	Position int `json:"position"`
+	// Primary marks the main image of the product, the first one uploaded
+	// unless another is chosen.
+	Primary bool `json:"primary"`
+}
+
+// ImageOrderRequest lists every image of a product in its new order.
+type ImageOrderRequest struct {
+	IDs []int64 `json:"ids" example:"3,1,2"`
+}
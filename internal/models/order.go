@@ -0,0 +1,59 @@
+package models
+
+import (
+	"prodcrud/pkg/money"
+	"time"
+)
+
+// The sales order statuses. An order is placed pending, allocated once its
+// units are taken from stock, then shipped; it can be cancelled until it is
+// shipped, and the allocated units go back to stock.
+const (
+	OrderPending   = "pending"
+	OrderAllocated = "allocated"
+	OrderShipped   = "shipped"
+	OrderCancelled = "cancelled"
+)
+
+// Order is a sales order of products.
+type Order struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status" enums:"pending,allocated,shipped,cancelled"`
+	// Currency is the currency of the unit prices and of the total.
+	Currency string       `json:"currency" example:"EUR"`
+	Lines    []*OrderLine `json:"lines"`
+	// Total sums the quantity times the unit price of every line.
+	Total       money.Money `json:"total"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	AllocatedAt *time.Time  `json:"allocated_at,omitempty"`
+	ShippedAt   *time.Time  `json:"shipped_at,omitempty"`
+	CancelledAt *time.Time  `json:"cancelled_at,omitempty"`
+}
+
+// OrderLine is the quantity of a product sold at the price it had when the
+// order was placed.
+type OrderLine struct {
+	ID        int64       `json:"id"`
+	ProductID int64       `json:"product_id"`
+	Quantity  int         `json:"quantity" example:"2"`
+	UnitPrice money.Money `json:"unit_price"`
+}
+
+// OrderRequest places a sales order.
+type OrderRequest struct {
+	// Currency defaults to the base currency of the first product.
+	Currency string             `json:"currency,omitempty" example:"EUR"`
+	Lines    []OrderLineRequest `json:"lines"`
+}
+
+// OrderLineRequest orders some units of an active product.
+type OrderLineRequest struct {
+	ProductID int64 `json:"product_id"`
+	Quantity  int   `json:"quantity" example:"2"`
+}
+
+// OrderFilter narrows an order listing; the zero value matches any.
+type OrderFilter struct {
+	Status string
+}
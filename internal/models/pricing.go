@@ -0,0 +1,82 @@
+package models
+
+import (
+	"prodcrud/pkg/money"
+	"time"
+)
+
+// ExchangeRate is the price of one major unit of Base in Quote.
+type ExchangeRate struct {
+	Base      string     `json:"base"`
+	Quote     string     `json:"quote"`
+	Rate      money.Rate `json:"rate"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+const (
+	// PriceSourceBase is the product's own price, in its currency.
+	PriceSourceBase = "base"
+	// PriceSourceList is a price set explicitly in the requested currency.
+	PriceSourceList = "list"
+	// PriceSourceConverted is the base price converted with an exchange rate.
+	PriceSourceConverted = "converted"
+)
+
+// PriceQuote is a product price in the requested currency.
+type PriceQuote struct {
+	ProductID int64       `json:"product_id"`
+	Price     money.Money `json:"price"`
+	BasePrice money.Money `json:"base_price"`
+	Source    string      `json:"source"`
+	// Rate is set for converted prices.
+	Rate *money.Rate `json:"rate,omitempty"`
+	// Tax is set when a jurisdiction is requested.
+	Tax *TaxBreakdown `json:"tax,omitempty"`
+}
+
+// PriceRequest sets a list price; the currency comes from the path.
+type PriceRequest struct {
+	Amount string `json:"amount" example:"12.34"`
+}
+
+// RateRequest sets an exchange rate; base and quote come from the path.
+type RateRequest struct {
+	Rate string `json:"rate" example:"12650.5"`
+}
+
+// PricePeriod is the base price of a product over [EffectiveFrom, EffectiveTo).
+// The periods of a product follow each other without gaps.
+type PricePeriod struct {
+	ID            int64       `json:"id"`
+	ProductID     int64       `json:"product_id"`
+	Price         money.Money `json:"price"`
+	EffectiveFrom time.Time   `json:"effective_from"`
+	// EffectiveTo is null for the last period, which lasts until further notice.
+	EffectiveTo *time.Time `json:"effective_to" extensions:"x-nullable"`
+}
+
+// InEffect reports whether the period covers the instant at.
+func (p *PricePeriod) InEffect(at time.Time) bool {
+	return !p.EffectiveFrom.After(at) && (p.EffectiveTo == nil || p.EffectiveTo.After(at))
+}
+
+// SchedulePriceRequest schedules a base price change.
+type SchedulePriceRequest struct {
+	Price         money.Money `json:"price"`
+	EffectiveFrom time.Time   `json:"effective_from" example:"2025-12-01T00:00:00Z"`
+	// EffectiveTo ends a temporary price, after which the price in effect
+	// before it resumes. Without it the price lasts until the next scheduled one.
+	EffectiveTo *time.Time `json:"effective_to,omitempty" example:"2025-12-08T00:00:00Z"`
+}
+
+// ProductPrices is the pricing of a product at an instant.
+type ProductPrices struct {
+	ProductID int64     `json:"product_id"`
+	At        time.Time `json:"at"`
+	// Price is the base price in effect at At.
+	Price money.Money `json:"price"`
+	// List holds the current list prices in other currencies.
+	List []money.Money `json:"list"`
+	// History holds every price period, past and scheduled, oldest first.
+	History []*PricePeriod `json:"history"`
+}
@@ -1,21 +1,88 @@
 package models
 
-import "time"
+import (
+	"prodcrud/pkg/money"
+	"time"
+)
 
 type Product struct {
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	DeletedAt   time.Time `json:"deleted_at"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Quantity    int       `json:"quantity"`
-	Price       int64     `json:"price"`
-	ID          int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is null while the product is active.
+	DeletedAt   *time.Time `json:"deleted_at" extensions:"x-nullable"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	// Category groups products for promotions, empty for none.
+	Category string `json:"category" example:"laptops"`
+	// TaxClass selects the tax rates that apply to the product.
+	TaxClass string `json:"tax_class" enums:"standard,reduced,exempt"`
+	// Attributes follow the attribute schema of the category.
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Quantity   int            `json:"quantity"`
+	// Price is the base price, in the product's own currency.
+	Price money.Money `json:"price"`
+	ID    int64       `json:"id"`
+	// Tax is the price broken down by the taxes of the requested
+	// jurisdiction, absent when none is requested.
+	Tax *TaxBreakdown `json:"tax,omitempty"`
+	// ParentID, SKU and Options are set on variants only: the product they
+	// vary and the value they take on each of its axes.
+	ParentID *int64            `json:"parent_id,omitempty"`
+	SKU      string            `json:"sku,omitempty" example:"P1-SILVER-16GB"`
+	Options  map[string]string `json:"options,omitempty"`
+	// VariantAxes and Variants are set on parents by GET /products/{id}.
+	VariantAxes []VariantAxis `json:"variant_axes,omitempty"`
+	Variants    []*Product    `json:"variants,omitempty"`
+	// Images are set by GET /products/{id}, the primary one first.
+	Images []*ProductImage `json:"images,omitempty"`
+}
+
+// IsVariant reports whether the product is a variant of another one.
+func (p *Product) IsVariant() bool {
+	return p.ParentID != nil
+}
+
+// IsDeleted reports whether the product is soft deleted.
+func (p *Product) IsDeleted() bool {
+	return p.DeletedAt != nil
 }
 
 type ProductResponse struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
-	Quantity    int    `json:"quantity"`
-	Price       int64  `json:"price"`
+	Category    string `json:"category,omitempty" example:"laptops"`
+	TaxClass    string `json:"tax_class,omitempty" enums:"standard,reduced,exempt"`
+	// Attributes replace all the attributes of the product on update.
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Quantity   int            `json:"quantity"`
+	Price      money.Money    `json:"price"`
+}
+
+// ProductStats aggregates the active catalog for monitoring.
+type ProductStats struct {
+	Active     int64 `json:"active"`
+	StockUnits int64 `json:"stock_units"`
+	LowStock   int64 `json:"low_stock"`
+}
+
+// PurgedProduct records a product removed permanently, by an admin or by the
+// retention job.
+type PurgedProduct struct {
+	ProductID int64      `json:"product_id"`
+	Name      string     `json:"name"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	PurgedAt  time.Time  `json:"purged_at"`
+	Reason    string     `json:"reason"`
+}
+
+const (
+	PurgeReasonHardDelete = "hard_delete"
+	PurgeReasonRetention  = "retention"
+)
+
+// PurgeResult is the outcome of one retention run. Skipped holds the ids of
+// products still referenced by other records.
+type PurgeResult struct {
+	Purged  []PurgedProduct `json:"purged"`
+	Skipped []int64         `json:"skipped"`
 }
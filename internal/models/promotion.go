@@ -0,0 +1,142 @@
+package models
+
+import (
+	"prodcrud/pkg/money"
+	"slices"
+	"time"
+)
+
+const (
+	// PromotionPercentage takes Percent off every unit.
+	PromotionPercentage = "percentage"
+	// PromotionFixedAmount takes Amount off every unit priced in its currency.
+	PromotionFixedAmount = "fixed_amount"
+	// PromotionBuyXGetY gives Get units free for every Buy units paid.
+	PromotionBuyXGetY = "buy_x_get_y"
+	// PromotionTiered takes the percent of the highest tier reached by the
+	// line quantity off every unit.
+	PromotionTiered = "tiered"
+)
+
+// Promotion is a discount rule applied to basket lines of the products and
+// categories it targets while its window [StartsAt, EndsAt) is open.
+type Promotion struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name" example:"Back to school"`
+	Kind string `json:"kind" enums:"percentage,fixed_amount,buy_x_get_y,tiered"`
+	// ProductIDs and Categories scope the promotion; a line matches if either does.
+	ProductIDs []int64  `json:"product_ids"`
+	Categories []string `json:"categories"`
+	// Percent is set for percentage promotions, e.g. "12.5".
+	Percent *money.Rate `json:"percent,omitempty"`
+	// Amount is set for fixed_amount promotions.
+	Amount *money.Money `json:"amount,omitempty"`
+	// Buy and Get are set for buy_x_get_y promotions.
+	Buy int `json:"buy,omitempty" example:"2"`
+	Get int `json:"get,omitempty" example:"1"`
+	// Tiers are set for tiered promotions, by growing MinQuantity.
+	Tiers []PromotionTier `json:"tiers,omitempty"`
+	// Priority orders promotions on a line, highest first.
+	Priority int `json:"priority"`
+	// Stackable promotions combine with each other; a line gets at most one
+	// promotion that is not.
+	Stackable bool      `json:"stackable"`
+	StartsAt  time.Time `json:"starts_at" example:"2025-08-15T00:00:00Z"`
+	// EndsAt is null for a promotion that runs until further notice.
+	EndsAt    *time.Time `json:"ends_at" extensions:"x-nullable"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// PromotionTier is a step of a tiered promotion.
+type PromotionTier struct {
+	MinQuantity int        `json:"min_quantity" example:"10"`
+	Percent     money.Rate `json:"percent"`
+}
+
+// InEffect reports whether the promotion window covers the instant at.
+func (p *Promotion) InEffect(at time.Time) bool {
+	return !p.StartsAt.After(at) && (p.EndsAt == nil || p.EndsAt.After(at))
+}
+
+// Targets reports whether the promotion applies to a product.
+func (p *Promotion) Targets(productID int64, category string) bool {
+	return slices.Contains(p.ProductIDs, productID) ||
+		(category != "" && slices.Contains(p.Categories, category))
+}
+
+// PromotionRequest creates or replaces a promotion.
+type PromotionRequest struct {
+	Name       string          `json:"name" example:"Back to school"`
+	Kind       string          `json:"kind" enums:"percentage,fixed_amount,buy_x_get_y,tiered"`
+	ProductIDs []int64         `json:"product_ids"`
+	Categories []string        `json:"categories" example:"laptops"`
+	Percent    *money.Rate     `json:"percent,omitempty"`
+	Amount     *money.Money    `json:"amount,omitempty"`
+	Buy        int             `json:"buy,omitempty" example:"2"`
+	Get        int             `json:"get,omitempty" example:"1"`
+	Tiers      []PromotionTier `json:"tiers,omitempty"`
+	Priority   int             `json:"priority"`
+	Stackable  bool            `json:"stackable"`
+	StartsAt   time.Time       `json:"starts_at" example:"2025-08-15T00:00:00Z"`
+	EndsAt     *time.Time      `json:"ends_at,omitempty" example:"2025-09-01T00:00:00Z"`
+}
+
+// QuoteRequest prices a basket.
+type QuoteRequest struct {
+	// Currency to price the basket in; empty prices it in the base currency
+	// of its products, which must then all share one.
+	Currency string `json:"currency,omitempty" example:"EUR"`
+	// Jurisdiction taxes every line total with the rates in effect now.
+	Jurisdiction string          `json:"jurisdiction,omitempty" example:"DE"`
+	Lines        []QuoteLineItem `json:"lines"`
+}
+
+// QuoteLineItem is a basket line.
+type QuoteLineItem struct {
+	ProductID int64 `json:"product_id" example:"1"`
+	Quantity  int   `json:"quantity" example:"3"`
+}
+
+// BasketQuote is a priced basket.
+type BasketQuote struct {
+	Currency string       `json:"currency"`
+	Lines    []*QuoteLine `json:"lines"`
+	Subtotal money.Money  `json:"subtotal"`
+	Discount money.Money  `json:"discount"`
+	Total    money.Money  `json:"total"`
+	// Tax sums the line taxes when a jurisdiction is requested.
+	Tax *BasketTax `json:"tax,omitempty"`
+}
+
+// BasketTax is the tax of a basket, the sum of the taxes of its lines, each
+// rounded on its own.
+type BasketTax struct {
+	Jurisdiction string      `json:"jurisdiction"`
+	Net          money.Money `json:"net"`
+	Tax          money.Money `json:"tax"`
+	Gross        money.Money `json:"gross"`
+}
+
+// QuoteLine is a priced basket line. Trace explains, in evaluation order,
+// why each promotion targeting the line was applied or skipped.
+type QuoteLine struct {
+	ProductID  int64              `json:"product_id"`
+	Quantity   int                `json:"quantity"`
+	UnitPrice  money.Money        `json:"unit_price"`
+	Subtotal   money.Money        `json:"subtotal"`
+	Discount   money.Money        `json:"discount"`
+	Total      money.Money        `json:"total"`
+	Promotions []AppliedPromotion `json:"promotions"`
+	Trace      []string           `json:"trace"`
+	// Tax splits Total when a jurisdiction is requested.
+	Tax *TaxBreakdown `json:"tax,omitempty"`
+}
+
+// AppliedPromotion is the discount a promotion gave a line.
+type AppliedPromotion struct {
+	PromotionID int64       `json:"promotion_id"`
+	Name        string      `json:"name"`
+	Kind        string      `json:"kind"`
+	Discount    money.Money `json:"discount"`
+}
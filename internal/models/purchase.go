@@ -0,0 +1,132 @@
+package models
+
+import (
+	"prodcrud/pkg/money"
+	"time"
+)
+
+// The purchase order statuses. An order is drafted, sent to its supplier,
+// then received in one or more receipts; it can be cancelled until it is
+// fully received.
+const (
+	PurchaseDraft             = "draft"
+	PurchaseSent              = "sent"
+	PurchasePartiallyReceived = "partially_received"
+	PurchaseReceived          = "received"
+	PurchaseCancelled         = "cancelled"
+)
+
+// Supplier is a company the stock is bought from.
+type Supplier struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name" example:"Acme Wholesale"`
+	Email string `json:"email,omitempty" example:"orders@acme.example"`
+	// Currency is the currency of the supplier costs and purchase orders.
+	Currency  string    `json:"currency" example:"EUR"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SupplierRequest creates or replaces a supplier.
+type SupplierRequest struct {
+	Name     string `json:"name" example:"Acme Wholesale"`
+	Email    string `json:"email,omitempty" example:"orders@acme.example"`
+	Currency string `json:"currency" example:"EUR"`
+}
+
+// SupplierProduct links a product to a supplier that sells it.
+type SupplierProduct struct {
+	SupplierID int64 `json:"supplier_id"`
+	ProductID  int64 `json:"product_id"`
+	// SKU is the reference of the product in the supplier catalog.
+	SKU string `json:"sku,omitempty" example:"ACME-1042"`
+	// Cost is the unit cost price, in the supplier currency.
+	Cost money.Money `json:"cost"`
+	// LeadTimeDays is the delay between sending an order and its delivery.
+	LeadTimeDays int       `json:"lead_time_days" example:"7"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SupplierProductRequest creates or replaces a supplier product link; the
+// supplier and the product come from the path.
+type SupplierProductRequest struct {
+	SKU          string      `json:"sku,omitempty" example:"ACME-1042"`
+	Cost         money.Money `json:"cost"`
+	LeadTimeDays int         `json:"lead_time_days" example:"7"`
+}
+
+// PurchaseOrder is an order of stock from a supplier.
+type PurchaseOrder struct {
+	ID         int64  `json:"id"`
+	SupplierID int64  `json:"supplier_id"`
+	Status     string `json:"status" enums:"draft,sent,partially_received,received,cancelled"`
+	// Currency is the supplier currency at the time of the order.
+	Currency string               `json:"currency" example:"EUR"`
+	Notes    string               `json:"notes,omitempty"`
+	Lines    []*PurchaseOrderLine `json:"lines"`
+	// Total sums the quantity times the cost of every line.
+	Total     money.Money `json:"total"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	SentAt    *time.Time  `json:"sent_at,omitempty"`
+	// ExpectedAt is SentAt plus the longest lead time of the lines.
+	ExpectedAt *time.Time `json:"expected_at,omitempty"`
+	ReceivedAt *time.Time `json:"received_at,omitempty"`
+}
+
+// PurchaseOrderLine is the quantity of a product ordered at a unit cost.
+type PurchaseOrderLine struct {
+	ID        int64       `json:"id"`
+	ProductID int64       `json:"product_id"`
+	Quantity  int         `json:"quantity" example:"50"`
+	Received  int         `json:"received_quantity" example:"20"`
+	Cost      money.Money `json:"cost"`
+}
+
+// Open reports whether the order can still be received or cancelled.
+func (o *PurchaseOrder) Open() bool {
+	return o.Status == PurchaseSent || o.Status == PurchasePartiallyReceived
+}
+
+// Line returns the line of the order by id, nil if it has none.
+func (o *PurchaseOrder) Line(id int64) *PurchaseOrderLine {
+	for _, l := range o.Lines {
+		if l.ID == id {
+			return l
+		}
+	}
+	return nil
+}
+
+// PurchaseOrderRequest creates a purchase order, or replaces the notes and
+// lines of a draft.
+type PurchaseOrderRequest struct {
+	SupplierID int64                      `json:"supplier_id"`
+	Notes      string                     `json:"notes,omitempty"`
+	Lines      []PurchaseOrderLineRequest `json:"lines"`
+}
+
+// PurchaseOrderLineRequest orders a product the supplier sells.
+type PurchaseOrderLineRequest struct {
+	ProductID int64 `json:"product_id"`
+	Quantity  int   `json:"quantity" example:"50"`
+	// Cost defaults to the cost of the supplier product link.
+	Cost *money.Money `json:"cost,omitempty"`
+}
+
+// PurchaseOrderFilter narrows a purchase order listing; zero values match any.
+type PurchaseOrderFilter struct {
+	SupplierID int64
+	Status     string
+}
+
+// ReceiptRequest records the delivery of some or all of the ordered units.
+type ReceiptRequest struct {
+	Lines []ReceiptLine `json:"lines"`
+}
+
+// ReceiptLine is a quantity received on a line of the order.
+type ReceiptLine struct {
+	LineID   int64 `json:"line_id"`
+	Quantity int   `json:"quantity" example:"20"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// ReorderRule sets when a product is low on stock and how much of it to
+// order then.
+type ReorderRule struct {
+	ProductID int64 `json:"product_id"`
+	// ReorderPoint is the quantity at or below which the product is low.
+	ReorderPoint int `json:"reorder_point" example:"5"`
+	// ReorderQuantity is the quantity of the purchase orders drafted for it.
+	ReorderQuantity int `json:"reorder_quantity" example:"50"`
+	// SupplierID is the supplier the purchase orders are drafted with, none
+	// drafts no order.
+	SupplierID *int64    `json:"supplier_id,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ReorderRuleRequest creates or replaces the reorder rule of the product in
+// the path.
+type ReorderRuleRequest struct {
+	ReorderPoint    int    `json:"reorder_point" example:"5"`
+	ReorderQuantity int    `json:"reorder_quantity" example:"50"`
+	SupplierID      *int64 `json:"supplier_id,omitempty"`
+}
+
+// LowStockProduct is an active product at or below its reorder point, with
+// the alert raised for it.
+type LowStockProduct struct {
+	ProductID       int64  `json:"product_id"`
+	Name            string `json:"name" example:"Ballpoint pen"`
+	Quantity        int    `json:"quantity" example:"3"`
+	ReorderPoint    int    `json:"reorder_point" example:"5"`
+	ReorderQuantity int    `json:"reorder_quantity" example:"50"`
+	SupplierID      *int64 `json:"supplier_id,omitempty"`
+	// FlaggedAt is when the alert was raised, nil until the next evaluation.
+	FlaggedAt *time.Time `json:"flagged_at,omitempty"`
+	// NotifiedAt is when the alert was delivered, nil until it is.
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+	// PurchaseOrderID is the draft ordered when the alert was raised.
+	PurchaseOrderID *int64 `json:"purchase_order_id,omitempty"`
+}
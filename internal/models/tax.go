@@ -0,0 +1,73 @@
+package models
+
+import (
+	"prodcrud/pkg/money"
+	"time"
+)
+
+const (
+	TaxClassStandard = "standard"
+	TaxClassReduced  = "reduced"
+	// TaxClassExempt is never taxed and needs no rate.
+	TaxClassExempt = "exempt"
+)
+
+// TaxJurisdiction is a region with its own tax rates, e.g. a country code
+// such as "DE" or a state such as "US-CA".
+type TaxJurisdiction struct {
+	Code string `json:"code" example:"DE"`
+	Name string `json:"name" example:"Germany"`
+	// PricesIncludeTax tells whether product prices are gross in this
+	// jurisdiction, so the tax is taken out of them instead of added.
+	PricesIncludeTax bool           `json:"prices_include_tax"`
+	Rounding         money.Rounding `json:"rounding" enums:"half_up,half_even,down,up"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+}
+
+// TaxJurisdictionRequest creates or replaces a jurisdiction; the code comes
+// from the path.
+type TaxJurisdictionRequest struct {
+	Name             string `json:"name" example:"Germany"`
+	PricesIncludeTax bool   `json:"prices_include_tax"`
+	// Rounding defaults to half_up.
+	Rounding string `json:"rounding,omitempty" enums:"half_up,half_even,down,up"`
+}
+
+// TaxRate is the percent charged on a tax class of a jurisdiction over
+// [ValidFrom, ValidTo).
+type TaxRate struct {
+	ID           int64      `json:"id"`
+	Jurisdiction string     `json:"jurisdiction"`
+	TaxClass     string     `json:"tax_class" enums:"standard,reduced"`
+	Rate         money.Rate `json:"rate"`
+	ValidFrom    time.Time  `json:"valid_from"`
+	// ValidTo is null for a rate in force until further notice.
+	ValidTo   *time.Time `json:"valid_to" extensions:"x-nullable"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// InEffect reports whether the rate applies at the instant at.
+func (r *TaxRate) InEffect(at time.Time) bool {
+	return !r.ValidFrom.After(at) && (r.ValidTo == nil || r.ValidTo.After(at))
+}
+
+// TaxRateRequest adds a rate to the jurisdiction in the path.
+type TaxRateRequest struct {
+	TaxClass  string     `json:"tax_class" enums:"standard,reduced"`
+	Rate      string     `json:"rate" example:"19"`
+	ValidFrom time.Time  `json:"valid_from" example:"2025-01-01T00:00:00Z"`
+	ValidTo   *time.Time `json:"valid_to,omitempty"`
+}
+
+// TaxBreakdown splits an amount into its net, tax and gross parts.
+type TaxBreakdown struct {
+	Jurisdiction string `json:"jurisdiction"`
+	TaxClass     string `json:"tax_class"`
+	// Rate is the percent applied, absent for exempt products.
+	Rate             *money.Rate    `json:"rate,omitempty"`
+	PricesIncludeTax bool           `json:"prices_include_tax"`
+	Rounding         money.Rounding `json:"rounding" enums:"half_up,half_even,down,up"`
+	Net              money.Money    `json:"net"`
+	Tax              money.Money    `json:"tax"`
+	Gross            money.Money    `json:"gross"`
+}
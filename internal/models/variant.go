@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/json"
+	"prodcrud/pkg/money"
+)
+
+// VariantAxis is an attribute a parent product varies by, with the values
+// its variants may take, e.g. color: silver, black.
+type VariantAxis struct {
+	Name   string   `json:"name" example:"color"`
+	Values []string `json:"values" example:"silver,black"`
+}
+
+// VariantKey returns the canonical form of a combination of axis values,
+// JSON with sorted keys, so two variants with the same options have the
+// same key.
+func VariantKey(options map[string]string) string {
+	b, _ := json.Marshal(options) //nolint:errchkjson //a string map always encodes
+	return string(b)
+}
+
+// VariantMatrixRequest generates a variant for every combination of the axis
+// values a parent does not have yet.
+type VariantMatrixRequest struct {
+	// Axes must keep the names and order of the parent axes once it has
+	// some; new values extend them.
+	Axes []VariantAxis `json:"axes"`
+	// SKUPrefix starts every generated SKU, P{id} by default.
+	SKUPrefix string `json:"sku_prefix,omitempty" example:"LAPTOP"`
+	// Price defaults to the parent price.
+	Price    *money.Money `json:"price,omitempty"`
+	Quantity int          `json:"quantity" example:"10"`
+}
+
+// VariantMatrix is the outcome of a generation.
+type VariantMatrix struct {
+	Axes    []VariantAxis `json:"axes"`
+	Created []*Product    `json:"created"`
+	// Existing counts the combinations the parent already had.
+	Existing int `json:"existing"`
+}
+
+// VariantRequest adds one variant to a parent. Options must name a value
+// of every parent axis.
+type VariantRequest struct {
+	SKU     string            `json:"sku" example:"LAPTOP-SILVER-32GB"`
+	Options map[string]string `json:"options"`
+	// Name defaults to the parent name followed by the option values.
+	Name string `json:"name,omitempty"`
+	// Price defaults to the parent price.
+	Price    *money.Money `json:"price,omitempty"`
+	Quantity int          `json:"quantity" example:"3"`
+}
@@ -0,0 +1,139 @@
+package attribute
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+)
+
+// Repository stores the attribute schemas of the categories.
+type Repository interface {
+	// SetDefinition creates or replaces the attribute d.Name of d.Category
+	// and sets d.UpdatedAt.
+	SetDefinition(ctx context.Context, d *models.AttributeDefinition) error
+	// GetDefinitions lists the attributes of a category by name, none for
+	// a category without a schema.
+	GetDefinitions(ctx context.Context, category string) ([]*models.AttributeDefinition, error)
+	DeleteDefinition(ctx context.Context, category, name string) error
+}
+
+// ErrNotFound is returned when the category has no such attribute.
+var ErrNotFound = errors.New("attribute not found")
+
+const columns = `category, name, type, required, "values"::text, units::text, updated_at`
+
+type Repo struct {
+	db *db.DB
+}
+
+func NewRepo(db *db.DB) *Repo {
+	return &Repo{db: db}
+}
+
+func (r *Repo) SetDefinition(ctx context.Context, d *models.AttributeDefinition) error {
+	values, units, err := encodeLists(d)
+	if err != nil {
+		return errors.New("failed to set attribute: " + err.Error())
+	}
+	err = r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRow(ctx, `
+	INSERT INTO category_attributes(category, name, type, required, "values", units) VALUES ($1, $2, $3, $4, $5::jsonb, $6::jsonb)
+	ON CONFLICT (category, name) DO UPDATE SET type = excluded.type, required = excluded.required,
+	    "values" = excluded."values", units = excluded.units, updated_at = now()
+	RETURNING updated_at`, d.Category, d.Name, d.Type, d.Required, values, units).Scan(&d.UpdatedAt)
+	})
+	if err != nil {
+		return errors.New("failed to set attribute: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) GetDefinitions(ctx context.Context, category string) ([]*models.AttributeDefinition, error) {
+	var defs []*models.AttributeDefinition
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		defs = nil
+		rows, err := q.Query(ctx, `SELECT `+columns+` FROM category_attributes WHERE category = $1 ORDER BY name`, category)
+		if err != nil {
+			return fmt.Errorf("failed to get attributes: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			d, err := scanDefinition(rows)
+			if err != nil {
+				return err
+			}
+			defs = append(defs, d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+func (r *Repo) DeleteDefinition(ctx context.Context, category, name string) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tag, err := r.db.Exec(ctx, `DELETE FROM category_attributes WHERE category = $1 AND name = $2`, category, name)
+		rows = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete attribute: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanDefinition reads a row of columns, both backends keep the lists as
+// JSON text.
+func scanDefinition(row rowScanner) (*models.AttributeDefinition, error) {
+	var (
+		d             models.AttributeDefinition
+		values, units string
+	)
+	if err := row.Scan(&d.Category, &d.Name, &d.Type, &d.Required, &values, &units, &d.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan attributes: %w", err)
+	}
+	if err := json.Unmarshal([]byte(values), &d.Values); err != nil {
+		return nil, fmt.Errorf("failed to decode attribute values: %w", err)
+	}
+	if err := json.Unmarshal([]byte(units), &d.Units); err != nil {
+		return nil, fmt.Errorf("failed to decode attribute units: %w", err)
+	}
+	if len(d.Values) == 0 {
+		d.Values = nil
+	}
+	if len(d.Units) == 0 {
+		d.Units = nil
+	}
+	return &d, nil
+}
+
+func encodeLists(d *models.AttributeDefinition) (values, units string, err error) {
+	encode := func(list []string) (string, error) {
+		if list == nil {
+			list = []string{}
+		}
+		b, err := json.Marshal(list)
+		return string(b), err
+	}
+	if values, err = encode(d.Values); err != nil {
+		return "", "", err
+	}
+	if units, err = encode(d.Units); err != nil {
+		return "", "", err
+	}
+	return values, units, nil
+}
@@ -0,0 +1,69 @@
+package attribute_test
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/attribute"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runContract checks the behaviour every attribute.Repository shares.
+func runContract(t *testing.T, newRepo func(t *testing.T) attribute.Repository) {
+	ctx := context.Background()
+
+	t.Run("set, list and replace definitions", func(t *testing.T) {
+		repo := newRepo(t)
+		screen := &models.AttributeDefinition{
+			Category: "laptops", Name: "screen", Type: models.AttributeUnit, Required: true, Units: []string{"in", "cm"},
+		}
+		require.NoError(t, repo.SetDefinition(ctx, screen))
+		assert.False(t, screen.UpdatedAt.IsZero())
+		require.NoError(t, repo.SetDefinition(ctx, &models.AttributeDefinition{
+			Category: "laptops", Name: "panel", Type: models.AttributeEnum, Values: []string{"ips", "oled"},
+		}))
+		require.NoError(t, repo.SetDefinition(ctx, &models.AttributeDefinition{
+			Category: "phones", Name: "dual_sim", Type: models.AttributeBoolean,
+		}))
+
+		defs, err := repo.GetDefinitions(ctx, "laptops")
+		require.NoError(t, err)
+		require.Len(t, defs, 2)
+		assert.Equal(t, "panel", defs[0].Name)
+		assert.Equal(t, []string{"ips", "oled"}, defs[0].Values)
+		assert.Nil(t, defs[0].Units)
+		assert.Equal(t, models.AttributeUnit, defs[1].Type)
+		assert.True(t, defs[1].Required)
+		assert.Equal(t, []string{"in", "cm"}, defs[1].Units)
+		assert.Nil(t, defs[1].Values)
+
+		screen.Type, screen.Required, screen.Units = models.AttributeNumber, false, nil
+		require.NoError(t, repo.SetDefinition(ctx, screen))
+		defs, err = repo.GetDefinitions(ctx, "laptops")
+		require.NoError(t, err)
+		require.Len(t, defs, 2)
+		assert.Equal(t, models.AttributeNumber, defs[1].Type)
+		assert.False(t, defs[1].Required)
+		assert.Nil(t, defs[1].Units)
+
+		defs, err = repo.GetDefinitions(ctx, "tablets")
+		require.NoError(t, err)
+		assert.Empty(t, defs)
+	})
+
+	t.Run("delete definitions", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.SetDefinition(ctx, &models.AttributeDefinition{
+			Category: "laptops", Name: "weight", Type: models.AttributeNumber,
+		}))
+		assert.ErrorIs(t, repo.DeleteDefinition(ctx, "phones", "weight"), attribute.ErrNotFound)
+		require.NoError(t, repo.DeleteDefinition(ctx, "laptops", "weight"))
+		assert.ErrorIs(t, repo.DeleteDefinition(ctx, "laptops", "weight"), attribute.ErrNotFound)
+
+		defs, err := repo.GetDefinitions(ctx, "laptops")
+		require.NoError(t, err)
+		assert.Empty(t, defs)
+	})
+}
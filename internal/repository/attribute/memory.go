@@ -0,0 +1,66 @@
+package attribute
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// MemoryRepo keeps the attribute schemas in memory, for tests and local
+// demos.
+type MemoryRepo struct {
+	mu   sync.RWMutex
+	defs map[string]map[string]*models.AttributeDefinition
+}
+
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{defs: make(map[string]map[string]*models.AttributeDefinition)}
+}
+
+// view returns a copy, so callers cannot mutate the stored definition.
+func view(d *models.AttributeDefinition) *models.AttributeDefinition {
+	cp := *d
+	cp.Values = slices.Clone(d.Values)
+	cp.Units = slices.Clone(d.Units)
+	return &cp
+}
+
+func (r *MemoryRepo) SetDefinition(_ context.Context, d *models.AttributeDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d.UpdatedAt = db.Now()
+	category, ok := r.defs[d.Category]
+	if !ok {
+		category = make(map[string]*models.AttributeDefinition)
+		r.defs[d.Category] = category
+	}
+	category[d.Name] = view(d)
+	return nil
+}
+
+func (r *MemoryRepo) GetDefinitions(_ context.Context, category string) ([]*models.AttributeDefinition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var defs []*models.AttributeDefinition
+	for _, d := range r.defs[category] {
+		defs = append(defs, view(d))
+	}
+	sort.Slice(defs, func(i, k int) bool { return defs[i].Name < defs[k].Name })
+	return defs, nil
+}
+
+func (r *MemoryRepo) DeleteDefinition(_ context.Context, category, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.defs[category][name]; !ok {
+		return ErrNotFound
+	}
+	delete(r.defs[category], name)
+	return nil
+}
@@ -0,0 +1,12 @@
+package attribute_test
+
+import (
+	"prodcrud/internal/repository/attribute"
+	"testing"
+)
+
+func TestMemoryRepo_Contract(t *testing.T) {
+	runContract(t, func(*testing.T) attribute.Repository {
+		return attribute.NewMemoryRepo()
+	})
+}
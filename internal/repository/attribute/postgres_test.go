@@ -0,0 +1,15 @@
+package attribute_test
+
+import (
+	"prodcrud/internal/repository/attribute"
+	"prodcrud/pkg/db/pgtest"
+	"testing"
+)
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+func TestRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) attribute.Repository {
+		return attribute.NewRepo(pgtest.New(t))
+	})
+}
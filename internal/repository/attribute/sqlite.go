@@ -0,0 +1,83 @@
+package attribute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+)
+
+// SQLiteRepo is the attribute store for SQLite, where the lists are JSON
+// text.
+type SQLiteRepo struct {
+	db *db.SQLite
+}
+
+func NewSQLiteRepo(db *db.SQLite) *SQLiteRepo {
+	return &SQLiteRepo{db: db}
+}
+
+const sqliteColumns = `category, name, type, required, "values", units, updated_at`
+
+func (r *SQLiteRepo) SetDefinition(ctx context.Context, d *models.AttributeDefinition) error {
+	values, units, err := encodeLists(d)
+	if err != nil {
+		return errors.New("failed to set attribute: " + err.Error())
+	}
+	err = r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `
+	INSERT INTO category_attributes(category, name, type, required, "values", units, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (category, name) DO UPDATE SET type = excluded.type, required = excluded.required,
+	    "values" = excluded."values", units = excluded.units, updated_at = excluded.updated_at
+	RETURNING updated_at`, d.Category, d.Name, d.Type, d.Required, values, units,
+			db.Now()).Scan(&d.UpdatedAt)
+	})
+	if err != nil {
+		return errors.New("failed to set attribute: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetDefinitions(ctx context.Context, category string) ([]*models.AttributeDefinition, error) {
+	var defs []*models.AttributeDefinition
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `SELECT `+sqliteColumns+` FROM category_attributes WHERE category = ? ORDER BY name`, category)
+		if err != nil {
+			return fmt.Errorf("failed to get attributes: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			d, err := scanDefinition(rows)
+			if err != nil {
+				return err
+			}
+			defs = append(defs, d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+func (r *SQLiteRepo) DeleteDefinition(ctx context.Context, category, name string) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		res, err := r.db.ExecContext(ctx, `DELETE FROM category_attributes WHERE category = ? AND name = ?`, category, name)
+		if err != nil {
+			return err
+		}
+		rows, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete attribute: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package attribute_test
+
+import (
+	"prodcrud/internal/repository/attribute"
+	"prodcrud/pkg/db/sqlitetest"
+	"testing"
+)
+
+func TestSQLiteRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) attribute.Repository {
+		return attribute.NewSQLiteRepo(sqlitetest.New(t))
+	})
+}
@@ -0,0 +1,64 @@
+package currency_test
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/currency"
+	"prodcrud/pkg/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runContract checks the behaviour every currency.Repository shares.
+func runContract(t *testing.T, newRepo func(t *testing.T) currency.Repository) {
+	ctx := context.Background()
+
+	t.Run("set and get", func(t *testing.T) {
+		repo := newRepo(t)
+		rate := &models.ExchangeRate{Base: "USD", Quote: "UZS", Rate: money.MustParseRate("12650.5")}
+		require.NoError(t, repo.SetRate(ctx, rate))
+		assert.False(t, rate.UpdatedAt.IsZero())
+
+		got, err := repo.GetRate(ctx, "USD", "UZS")
+		require.NoError(t, err)
+		assert.Equal(t, "12650.5", got.Rate.String())
+
+		_, err = repo.GetRate(ctx, "UZS", "USD")
+		assert.ErrorIs(t, err, currency.ErrNotFound, "rates are not inverted")
+	})
+
+	t.Run("replace keeps precision", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.SetRate(ctx, &models.ExchangeRate{Base: "EUR", Quote: "USD", Rate: money.MustParseRate("1.08")}))
+		require.NoError(t, repo.SetRate(ctx, &models.ExchangeRate{Base: "EUR", Quote: "USD", Rate: money.MustParseRate("1.0823456789")}))
+
+		got, err := repo.GetRate(ctx, "EUR", "USD")
+		require.NoError(t, err)
+		assert.Equal(t, "1.0823456789", got.Rate.String())
+	})
+
+	t.Run("list and delete", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, r := range []models.ExchangeRate{
+			{Base: "USD", Quote: "UZS", Rate: money.MustParseRate("12650")},
+			{Base: "EUR", Quote: "USD", Rate: money.MustParseRate("1.08")},
+			{Base: "USD", Quote: "EUR", Rate: money.MustParseRate("0.92")},
+		} {
+			require.NoError(t, repo.SetRate(ctx, &r))
+		}
+
+		rates, err := repo.GetRates(ctx)
+		require.NoError(t, err)
+		require.Len(t, rates, 3)
+		assert.Equal(t, []string{"EUR", "USD", "USD"}, []string{rates[0].Base, rates[1].Base, rates[2].Base})
+		assert.Equal(t, []string{"USD", "EUR", "UZS"}, []string{rates[0].Quote, rates[1].Quote, rates[2].Quote})
+
+		require.NoError(t, repo.DeleteRate(ctx, "USD", "EUR"))
+		assert.ErrorIs(t, repo.DeleteRate(ctx, "USD", "EUR"), currency.ErrNotFound)
+		rates, err = repo.GetRates(ctx)
+		require.NoError(t, err)
+		assert.Len(t, rates, 2)
+	})
+}
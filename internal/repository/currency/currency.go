@@ -0,0 +1,107 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Repository stores exchange rates locally, so converting prices never calls
+// an external service.
+type Repository interface {
+	// GetRate returns the rate of exactly base to quote, without inverting.
+	GetRate(ctx context.Context, base, quote string) (*models.ExchangeRate, error)
+	GetRates(ctx context.Context) ([]*models.ExchangeRate, error)
+	// SetRate adds or replaces the rate of r.Base to r.Quote and fills UpdatedAt.
+	SetRate(ctx context.Context, r *models.ExchangeRate) error
+	DeleteRate(ctx context.Context, base, quote string) error
+}
+
+const logPackage = "repository/currency"
+
+// ErrNotFound is returned when no rate is stored for the currency pair.
+var ErrNotFound = errors.New("exchange rate not found")
+
+type Repo struct {
+	db *db.DB
+}
+
+func NewRepo(db *db.DB) *Repo {
+	return &Repo{db: db}
+}
+
+func (r *Repo) GetRate(ctx context.Context, base, quote string) (*models.ExchangeRate, error) {
+	rate := models.ExchangeRate{Base: base, Quote: quote}
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		return q.QueryRow(ctx, `
+	SELECT rate::text, updated_at FROM exchange_rates WHERE base = $1 AND quote = $2`, base, quote).Scan(&rate.Rate, &rate.UpdatedAt)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("exchange rate not found", "base", base, "quote", quote)
+			return nil, ErrNotFound
+		}
+		return nil, errors.New("failed to get exchange rate: " + err.Error())
+	}
+	return &rate, nil
+}
+
+func (r *Repo) GetRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	var rates []*models.ExchangeRate
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		rates = nil
+		rows, err := q.Query(ctx, `
+	SELECT base, quote, rate::text, updated_at FROM exchange_rates ORDER BY base, quote`)
+		if err != nil {
+			return fmt.Errorf("failed to get exchange rates: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var rate models.ExchangeRate
+			if err := rows.Scan(&rate.Base, &rate.Quote, &rate.Rate, &rate.UpdatedAt); err != nil {
+				return fmt.Errorf("failed to scan exchange rates: %w", err)
+			}
+			rates = append(rates, &rate)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *Repo) SetRate(ctx context.Context, rate *models.ExchangeRate) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRow(ctx, `
+	INSERT INTO exchange_rates(base, quote, rate) VALUES ($1, $2, $3::numeric)
+	ON CONFLICT (base, quote) DO UPDATE SET rate = EXCLUDED.rate, updated_at = now()
+	RETURNING updated_at`, rate.Base, rate.Quote, rate.Rate.String()).Scan(&rate.UpdatedAt)
+	})
+	if err != nil {
+		return errors.New("failed to set exchange rate: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) DeleteRate(ctx context.Context, base, quote string) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tag, err := r.db.Exec(ctx, `DELETE FROM exchange_rates WHERE base = $1 AND quote = $2`, base, quote)
+		rows = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete exchange rate: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
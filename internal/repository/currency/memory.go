@@ -0,0 +1,68 @@
+package currency
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"sort"
+	"sync"
+)
+
+// MemoryRepo keeps exchange rates in memory, for tests and local demos.
+type MemoryRepo struct {
+	mu    sync.RWMutex
+	rates map[[2]string]models.ExchangeRate
+}
+
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{rates: make(map[[2]string]models.ExchangeRate)}
+}
+
+func (r *MemoryRepo) GetRate(_ context.Context, base, quote string) (*models.ExchangeRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rate, ok := r.rates[[2]string{base, quote}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &rate, nil
+}
+
+func (r *MemoryRepo) GetRates(_ context.Context) ([]*models.ExchangeRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var rates []*models.ExchangeRate
+	for _, rate := range r.rates {
+		rates = append(rates, &rate)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Base != rates[j].Base {
+			return rates[i].Base < rates[j].Base
+		}
+		return rates[i].Quote < rates[j].Quote
+	})
+	return rates, nil
+}
+
+func (r *MemoryRepo) SetRate(_ context.Context, rate *models.ExchangeRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rate.UpdatedAt = db.Now()
+	r.rates[[2]string{rate.Base, rate.Quote}] = *rate
+	return nil
+}
+
+func (r *MemoryRepo) DeleteRate(_ context.Context, base, quote string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := [2]string{base, quote}
+	if _, ok := r.rates[key]; !ok {
+		return ErrNotFound
+	}
+	delete(r.rates, key)
+	return nil
+}
@@ -0,0 +1,12 @@
+package currency_test
+
+import (
+	"prodcrud/internal/repository/currency"
+	"testing"
+)
+
+func TestMemoryRepo_Contract(t *testing.T) {
+	runContract(t, func(*testing.T) currency.Repository {
+		return currency.NewMemoryRepo()
+	})
+}
@@ -0,0 +1,15 @@
+package currency_test
+
+import (
+	"prodcrud/internal/repository/currency"
+	"prodcrud/pkg/db/pgtest"
+	"testing"
+)
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+func TestRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) currency.Repository {
+		return currency.NewRepo(pgtest.New(t))
+	})
+}
@@ -0,0 +1,97 @@
+package currency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+)
+
+// SQLiteRepo is the exchange rate store for SQLite, where rates are kept as
+// decimal text.
+type SQLiteRepo struct {
+	db *db.SQLite
+}
+
+func NewSQLiteRepo(db *db.SQLite) *SQLiteRepo {
+	return &SQLiteRepo{db: db}
+}
+
+func (r *SQLiteRepo) GetRate(ctx context.Context, base, quote string) (*models.ExchangeRate, error) {
+	rate := models.ExchangeRate{Base: base, Quote: quote}
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `
+	SELECT rate, updated_at FROM exchange_rates WHERE base = ? AND quote = ?`, base, quote).Scan(&rate.Rate, &rate.UpdatedAt)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("exchange rate not found", "base", base, "quote", quote)
+			return nil, ErrNotFound
+		}
+		return nil, errors.New("failed to get exchange rate: " + err.Error())
+	}
+	return &rate, nil
+}
+
+func (r *SQLiteRepo) GetRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	var rates []*models.ExchangeRate
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `
+	SELECT base, quote, rate, updated_at FROM exchange_rates ORDER BY base, quote`)
+		if err != nil {
+			return fmt.Errorf("failed to get exchange rates: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var rate models.ExchangeRate
+			if err := rows.Scan(&rate.Base, &rate.Quote, &rate.Rate, &rate.UpdatedAt); err != nil {
+				return fmt.Errorf("failed to scan exchange rates: %w", err)
+			}
+			rates = append(rates, &rate)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *SQLiteRepo) SetRate(ctx context.Context, rate *models.ExchangeRate) error {
+	ts := db.Now()
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		_, err := r.db.ExecContext(ctx, `
+	INSERT INTO exchange_rates(base, quote, rate, updated_at) VALUES (?, ?, ?, ?)
+	ON CONFLICT (base, quote) DO UPDATE SET rate = excluded.rate, updated_at = excluded.updated_at`,
+			rate.Base, rate.Quote, rate.Rate.String(), ts)
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to set exchange rate: " + err.Error())
+	}
+	rate.UpdatedAt = ts
+	return nil
+}
+
+func (r *SQLiteRepo) DeleteRate(ctx context.Context, base, quote string) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		res, err := r.db.ExecContext(ctx, `DELETE FROM exchange_rates WHERE base = ? AND quote = ?`, base, quote)
+		if err != nil {
+			return err
+		}
+		rows, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete exchange rate: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package currency_test
+
+import (
+	"prodcrud/internal/repository/currency"
+	"prodcrud/pkg/db/sqlitetest"
+	"testing"
+)
+
+func TestSQLiteRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) currency.Repository {
+		return currency.NewSQLiteRepo(sqlitetest.New(t))
+	})
+}
@@ -4,14 +4,14 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"prodcrud/pkg/db"
 )
 
 type Repo struct {
-	db *pgxpool.Pool
+	db *db.DB
 }
 
-func NewRepo(db *pgxpool.Pool) *Repo {
+func NewRepo(db *db.DB) *Repo {
 	return &Repo{db: db}
 }
 
@@ -21,3 +21,16 @@ func (r *Repo) Ping(ctx context.Context) error {
 	}
 	return nil
 }
+
+// MigrationVersion returns the schema version recorded by golang-migrate.
+func (r *Repo) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := r.db.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	return uint(version), dirty, nil
+}
@@ -0,0 +1,26 @@
+package health
+
+import (
+	"context"
+	"prodcrud/migrations"
+	"prodcrud/pkg/db/pgtest"
+	"prodcrud/pkg/migration"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+func TestRepo_MigrationVersion(t *testing.T) {
+	repo := NewRepo(pgtest.New(t))
+	latest, err := migration.LatestVersion(migrations.FS)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Ping(context.Background()))
+	version, dirty, err := repo.MigrationVersion(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+	assert.False(t, dirty)
+}
@@ -0,0 +1,137 @@
+package image_test
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/image"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runContract checks the behaviour every image.Repository shares. newRepo
+// returns the image repository with a product repository of the same
+// store, for the products the images belong to.
+func runContract(t *testing.T, newRepo func(t *testing.T) (image.Repository, product.Repository)) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (image.Repository, int64) {
+		repo, products := newRepo(t)
+		p := &models.Product{Name: "Laptop", Price: money.New(100000, "EUR"), Quantity: 1, Description: "14 inch"}
+		require.NoError(t, products.CreateProduct(ctx, p))
+		return repo, p.ID
+	}
+	add := func(t *testing.T, repo image.Repository, productID int64, name string, primary bool) *models.ProductImage {
+		t.Helper()
+		img := &models.ProductImage{
+			ProductID: productID, Key: "products/" + name + ".jpg", ThumbnailKey: "products/" + name + "_thumb.jpg",
+			ContentType: "image/jpeg", Size: 1024, Width: 800, Height: 600, Primary: primary,
+		}
+		require.NoError(t, repo.AddImage(ctx, img))
+		return img
+	}
+	ids := func(images []*models.ProductImage) (ids []int64, primary int64) {
+		for _, img := range images {
+			ids = append(ids, img.ID)
+			if img.Primary {
+				primary = img.ID
+			}
+		}
+		return ids, primary
+	}
+
+	t.Run("add and list", func(t *testing.T) {
+		repo, productID := setup(t)
+		first := add(t, repo, productID, "a", false)
+		assert.NotZero(t, first.ID)
+		assert.Equal(t, 1, first.Position)
+		assert.True(t, first.Primary, "the first image is the primary one")
+		assert.False(t, first.CreatedAt.IsZero())
+		second := add(t, repo, productID, "b", false)
+		assert.Equal(t, 2, second.Position)
+		assert.False(t, second.Primary)
+		third := add(t, repo, productID, "c", true)
+		assert.True(t, third.Primary)
+
+		images, err := repo.GetImages(ctx, productID)
+		require.NoError(t, err)
+		got, primary := ids(images)
+		assert.Equal(t, []int64{first.ID, second.ID, third.ID}, got)
+		assert.Equal(t, third.ID, primary, "a new primary image replaces the old one")
+		assert.Equal(t, *second, *images[1])
+
+		images, err = repo.GetImages(ctx, productID+1)
+		require.NoError(t, err)
+		assert.Empty(t, images)
+	})
+
+	t.Run("reorder", func(t *testing.T) {
+		repo, productID := setup(t)
+		a, b, c := add(t, repo, productID, "a", false), add(t, repo, productID, "b", false), add(t, repo, productID, "c", false)
+
+		require.NoError(t, repo.ReorderImages(ctx, productID, []int64{c.ID, a.ID, b.ID}))
+		images, err := repo.GetImages(ctx, productID)
+		require.NoError(t, err)
+		got, _ := ids(images)
+		assert.Equal(t, []int64{c.ID, a.ID, b.ID}, got)
+		assert.Equal(t, []int{1, 2, 3}, []int{images[0].Position, images[1].Position, images[2].Position})
+
+		for name, order := range map[string][]int64{
+			"missing":   {c.ID, a.ID},
+			"duplicate": {c.ID, a.ID, a.ID},
+			"unknown":   {c.ID, a.ID, b.ID + 100},
+		} {
+			assert.ErrorIs(t, repo.ReorderImages(ctx, productID, order), image.ErrInvalidOrder, name)
+		}
+		d := add(t, repo, productID, "d", false)
+		assert.Equal(t, 4, d.Position, "new images go last")
+	})
+
+	t.Run("primary", func(t *testing.T) {
+		repo, productID := setup(t)
+		a, b := add(t, repo, productID, "a", false), add(t, repo, productID, "b", false)
+
+		require.NoError(t, repo.SetPrimary(ctx, productID, b.ID))
+		images, err := repo.GetImages(ctx, productID)
+		require.NoError(t, err)
+		_, primary := ids(images)
+		assert.Equal(t, b.ID, primary)
+
+		assert.ErrorIs(t, repo.SetPrimary(ctx, productID, b.ID+100), image.ErrNotFound)
+		assert.ErrorIs(t, repo.SetPrimary(ctx, productID+1, a.ID), image.ErrNotFound)
+		images, err = repo.GetImages(ctx, productID)
+		require.NoError(t, err)
+		_, primary = ids(images)
+		assert.Equal(t, b.ID, primary, "a failed change keeps the primary image")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo, productID := setup(t)
+		a, b, c := add(t, repo, productID, "a", false), add(t, repo, productID, "b", false), add(t, repo, productID, "c", false)
+		require.NoError(t, repo.ReorderImages(ctx, productID, []int64{a.ID, c.ID, b.ID}))
+
+		deleted, err := repo.DeleteImage(ctx, productID, a.ID)
+		require.NoError(t, err)
+		assert.Equal(t, a.Key, deleted.Key)
+		assert.Equal(t, a.ThumbnailKey, deleted.ThumbnailKey)
+		images, err := repo.GetImages(ctx, productID)
+		require.NoError(t, err)
+		got, primary := ids(images)
+		assert.Equal(t, []int64{c.ID, b.ID}, got)
+		assert.Equal(t, c.ID, primary, "the first image left becomes the primary one")
+
+		_, err = repo.DeleteImage(ctx, productID, a.ID)
+		assert.ErrorIs(t, err, image.ErrNotFound)
+		_, err = repo.DeleteImage(ctx, productID+1, b.ID)
+		assert.ErrorIs(t, err, image.ErrNotFound)
+		_, err = repo.DeleteImage(ctx, productID, b.ID)
+		require.NoError(t, err)
+		images, err = repo.GetImages(ctx, productID)
+		require.NoError(t, err)
+		_, primary = ids(images)
+		assert.Equal(t, c.ID, primary)
+	})
+}
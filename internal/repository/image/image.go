@@ -0,0 +1,227 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Repository stores the image records of products; the files themselves
+// live in a blob store.
+type Repository interface {
+	// AddImage stores img after the other images of its product and sets
+	// its ID, Position and CreatedAt. It becomes the primary image when
+	// img.Primary is set or the product has no primary image yet.
+	AddImage(ctx context.Context, img *models.ProductImage) error
+	// GetImages lists the images of a product by position.
+	GetImages(ctx context.Context, productID int64) ([]*models.ProductImage, error)
+	// ReorderImages gives the images the positions of their ids in the list,
+	// which must hold every image of the product once.
+	ReorderImages(ctx context.Context, productID int64, ids []int64) error
+	// SetPrimary makes an image the primary image of its product.
+	SetPrimary(ctx context.Context, productID, id int64) error
+	// DeleteImage removes an image and returns it; when it was the primary
+	// one, the first image left takes its place.
+	DeleteImage(ctx context.Context, productID, id int64) (*models.ProductImage, error)
+}
+
+var (
+	// ErrNotFound is returned when the product has no such image.
+	ErrNotFound = errors.New("image not found")
+	// ErrProductNotFound is returned by AddImage for a missing product.
+	ErrProductNotFound = errors.New("product not found")
+	// ErrInvalidOrder is returned by ReorderImages for a list that is not a
+	// permutation of the images of the product.
+	ErrInvalidOrder = errors.New("the order must list every image of the product once")
+)
+
+const columns = `id, product_id, key, thumbnail_key, content_type, size, width, height, position, is_primary, created_at`
+
+type Repo struct {
+	db *db.DB
+}
+
+func NewRepo(db *db.DB) *Repo {
+	return &Repo{db: db}
+}
+
+func (r *Repo) AddImage(ctx context.Context, img *models.ProductImage) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+			// the product row lock serialises the positions of its images
+			var id int64
+			if err := tx.QueryRow(ctx, `SELECT id FROM products WHERE id = $1 FOR UPDATE`, img.ProductID).Scan(&id); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return ErrProductNotFound
+				}
+				return err
+			}
+			var hasPrimary bool
+			if err := tx.QueryRow(ctx, `
+	SELECT COALESCE(MAX(position), 0) + 1, COALESCE(bool_or(is_primary), false) FROM product_images WHERE product_id = $1`,
+				img.ProductID).Scan(&img.Position, &hasPrimary); err != nil {
+				return err
+			}
+			if img.Primary && hasPrimary {
+				if _, err := tx.Exec(ctx, `UPDATE product_images SET is_primary = false WHERE product_id = $1`, img.ProductID); err != nil {
+					return err
+				}
+			}
+			img.Primary = img.Primary || !hasPrimary
+			return tx.QueryRow(ctx, `
+	INSERT INTO product_images(product_id, key, thumbnail_key, content_type, size, width, height, position, is_primary)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at`,
+				img.ProductID, img.Key, img.ThumbnailKey, img.ContentType, img.Size, img.Width, img.Height, img.Position, img.Primary).
+				Scan(&img.ID, &img.CreatedAt)
+		})
+	})
+	if errors.Is(err, ErrProductNotFound) {
+		return err
+	}
+	if err != nil {
+		return errors.New("failed to add image: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) GetImages(ctx context.Context, productID int64) ([]*models.ProductImage, error) {
+	var images []*models.ProductImage
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		images = nil
+		rows, err := q.Query(ctx, `SELECT `+columns+` FROM product_images WHERE product_id = $1 ORDER BY position, id`, productID)
+		if err != nil {
+			return fmt.Errorf("failed to get images: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			img, err := scanImage(rows)
+			if err != nil {
+				return err
+			}
+			images = append(images, img)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (r *Repo) ReorderImages(ctx context.Context, productID int64, ids []int64) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+			rows, err := tx.Query(ctx, `SELECT id FROM product_images WHERE product_id = $1 FOR UPDATE`, productID)
+			if err != nil {
+				return err
+			}
+			current, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+			if err != nil {
+				return err
+			}
+			if !isPermutation(current, ids) {
+				return ErrInvalidOrder
+			}
+			_, err = tx.Exec(ctx, `
+	UPDATE product_images SET position = o.position
+	FROM unnest($2::bigint[]) WITH ORDINALITY AS o(id, position)
+	WHERE product_images.product_id = $1 AND product_images.id = o.id`, productID, ids)
+			return err
+		})
+	})
+	if errors.Is(err, ErrInvalidOrder) {
+		return err
+	}
+	if err != nil {
+		return errors.New("failed to reorder images: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) SetPrimary(ctx context.Context, productID, id int64) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, `
+	UPDATE product_images SET is_primary = false WHERE product_id = $1 AND is_primary AND id <> $2
+	    AND EXISTS (SELECT 1 FROM product_images WHERE product_id = $1 AND id = $2)`, productID, id); err != nil {
+				return err
+			}
+			tag, err := tx.Exec(ctx, `UPDATE product_images SET is_primary = true WHERE product_id = $1 AND id = $2`, productID, id)
+			rows = tag.RowsAffected()
+			return err
+		})
+	})
+	if err != nil {
+		return errors.New("failed to set primary image: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repo) DeleteImage(ctx context.Context, productID, id int64) (*models.ProductImage, error) {
+	var img *models.ProductImage
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+			var err error
+			img, err = scanImage(tx.QueryRow(ctx, `
+	DELETE FROM product_images WHERE product_id = $1 AND id = $2 RETURNING `+columns, productID, id))
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			if err != nil || !img.Primary {
+				return err
+			}
+			_, err = tx.Exec(ctx, `
+	UPDATE product_images SET is_primary = true
+	WHERE id = (SELECT id FROM product_images WHERE product_id = $1 ORDER BY position, id LIMIT 1)`, productID)
+			return err
+		})
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New("failed to delete image: " + err.Error())
+	}
+	return img, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanImage(row rowScanner) (*models.ProductImage, error) {
+	var img models.ProductImage
+	if err := row.Scan(&img.ID, &img.ProductID, &img.Key, &img.ThumbnailKey, &img.ContentType, &img.Size,
+		&img.Width, &img.Height, &img.Position, &img.Primary, &img.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan image: %w", err)
+	}
+	img.CreatedAt = img.CreatedAt.UTC()
+	return &img, nil
+}
+
+// isPermutation reports whether ids holds every id of current exactly once.
+func isPermutation(current, ids []int64) bool {
+	if len(current) != len(ids) {
+		return false
+	}
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range current {
+		seen[id] = false
+	}
+	for _, id := range ids {
+		if done, ok := seen[id]; !ok || done {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
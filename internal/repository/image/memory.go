@@ -0,0 +1,119 @@
+package image
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"sort"
+	"sync"
+)
+
+// MemoryRepo keeps image records in memory, for tests and local demos. It
+// does not know the products, so AddImage accepts any product id.
+type MemoryRepo struct {
+	mu     sync.RWMutex
+	images map[int64]*models.ProductImage
+	lastID int64
+}
+
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{images: make(map[int64]*models.ProductImage)}
+}
+
+// of returns the stored images of a product by position; the caller holds
+// the lock.
+func (r *MemoryRepo) of(productID int64) []*models.ProductImage {
+	var images []*models.ProductImage
+	for _, img := range r.images {
+		if img.ProductID == productID {
+			images = append(images, img)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Position != images[j].Position {
+			return images[i].Position < images[j].Position
+		}
+		return images[i].ID < images[j].ID
+	})
+	return images
+}
+
+func (r *MemoryRepo) AddImage(_ context.Context, img *models.ProductImage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hasPrimary := false
+	img.Position = 1
+	for _, other := range r.of(img.ProductID) {
+		img.Position = max(img.Position, other.Position+1)
+		if other.Primary && img.Primary {
+			other.Primary = false
+		}
+		hasPrimary = hasPrimary || other.Primary
+	}
+	img.Primary = img.Primary || !hasPrimary
+	r.lastID++
+	img.ID = r.lastID
+	img.CreatedAt = db.Now()
+	stored := *img
+	r.images[img.ID] = &stored
+	return nil
+}
+
+func (r *MemoryRepo) GetImages(_ context.Context, productID int64) ([]*models.ProductImage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var images []*models.ProductImage
+	for _, img := range r.of(productID) {
+		cp := *img
+		images = append(images, &cp)
+	}
+	return images, nil
+}
+
+func (r *MemoryRepo) ReorderImages(_ context.Context, productID int64, ids []int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	images := r.of(productID)
+	current := make([]int64, len(images))
+	for i, img := range images {
+		current[i] = img.ID
+	}
+	if !isPermutation(current, ids) {
+		return ErrInvalidOrder
+	}
+	for i, id := range ids {
+		r.images[id].Position = i + 1
+	}
+	return nil
+}
+
+func (r *MemoryRepo) SetPrimary(_ context.Context, productID, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if img, ok := r.images[id]; !ok || img.ProductID != productID {
+		return ErrNotFound
+	}
+	for _, img := range r.of(productID) {
+		img.Primary = img.ID == id
+	}
+	return nil
+}
+
+func (r *MemoryRepo) DeleteImage(_ context.Context, productID, id int64) (*models.ProductImage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	img, ok := r.images[id]
+	if !ok || img.ProductID != productID {
+		return nil, ErrNotFound
+	}
+	delete(r.images, id)
+	if left := r.of(productID); img.Primary && len(left) > 0 {
+		left[0].Primary = true
+	}
+	return img, nil
+}
@@ -0,0 +1,13 @@
+package image_test
+
+import (
+	"prodcrud/internal/repository/image"
+	"prodcrud/internal/repository/product"
+	"testing"
+)
+
+func TestMemoryRepo_Contract(t *testing.T) {
+	runContract(t, func(*testing.T) (image.Repository, product.Repository) {
+		return image.NewMemoryRepo(), product.NewMemoryRepo()
+	})
+}
@@ -0,0 +1,17 @@
+package image_test
+
+import (
+	"prodcrud/internal/repository/image"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/db/pgtest"
+	"testing"
+)
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+func TestRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) (image.Repository, product.Repository) {
+		conn := pgtest.New(t)
+		return image.NewRepo(conn), product.NewRepo(conn)
+	})
+}
@@ -0,0 +1,200 @@
+package image
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+)
+
+// SQLiteRepo is the image store for SQLite.
+type SQLiteRepo struct {
+	db *db.SQLite
+}
+
+func NewSQLiteRepo(db *db.SQLite) *SQLiteRepo {
+	return &SQLiteRepo{db: db}
+}
+
+func (r *SQLiteRepo) AddImage(ctx context.Context, img *models.ProductImage) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		var id int64
+		if err := tx.QueryRowContext(ctx, `SELECT id FROM products WHERE id = ?`, img.ProductID).Scan(&id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrProductNotFound
+			}
+			return err
+		}
+		var hasPrimary bool
+		if err := tx.QueryRowContext(ctx, `
+	SELECT COALESCE(MAX(position), 0) + 1, COALESCE(MAX(is_primary), false) FROM product_images WHERE product_id = ?`,
+			img.ProductID).Scan(&img.Position, &hasPrimary); err != nil {
+			return err
+		}
+		if img.Primary && hasPrimary {
+			if _, err := tx.ExecContext(ctx, `UPDATE product_images SET is_primary = false WHERE product_id = ?`, img.ProductID); err != nil {
+				return err
+			}
+		}
+		img.Primary = img.Primary || !hasPrimary
+		if err := tx.QueryRowContext(ctx, `
+	INSERT INTO product_images(product_id, key, thumbnail_key, content_type, size, width, height, position, is_primary, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, created_at`,
+			img.ProductID, img.Key, img.ThumbnailKey, img.ContentType, img.Size, img.Width, img.Height, img.Position, img.Primary,
+			db.Now()).Scan(&img.ID, &img.CreatedAt); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if errors.Is(err, ErrProductNotFound) {
+		return err
+	}
+	if err != nil {
+		return errors.New("failed to add image: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetImages(ctx context.Context, productID int64) ([]*models.ProductImage, error) {
+	var images []*models.ProductImage
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		images = nil
+		rows, err := r.db.QueryContext(ctx, `SELECT `+columns+` FROM product_images WHERE product_id = ? ORDER BY position, id`, productID)
+		if err != nil {
+			return fmt.Errorf("failed to get images: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			img, err := scanImage(rows)
+			if err != nil {
+				return err
+			}
+			images = append(images, img)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (r *SQLiteRepo) ReorderImages(ctx context.Context, productID int64, ids []int64) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		rows, err := tx.QueryContext(ctx, `SELECT id FROM product_images WHERE product_id = ?`, productID)
+		if err != nil {
+			return err
+		}
+		var current []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			current = append(current, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if !isPermutation(current, ids) {
+			return ErrInvalidOrder
+		}
+		for i, id := range ids {
+			if _, err := tx.ExecContext(ctx, `UPDATE product_images SET position = ? WHERE id = ?`, i+1, id); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+	if errors.Is(err, ErrInvalidOrder) {
+		return err
+	}
+	if err != nil {
+		return errors.New("failed to reorder images: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) SetPrimary(ctx context.Context, productID, id int64) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if _, err := tx.ExecContext(ctx, `
+	UPDATE product_images SET is_primary = false WHERE product_id = ? AND is_primary AND id <> ?
+	    AND EXISTS (SELECT 1 FROM product_images WHERE product_id = ? AND id = ?)`, productID, id, productID, id); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `UPDATE product_images SET is_primary = true WHERE product_id = ? AND id = ?`, productID, id)
+		if err != nil {
+			return err
+		}
+		if rows, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return errors.New("failed to set primary image: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) DeleteImage(ctx context.Context, productID, id int64) (*models.ProductImage, error) {
+	var img *models.ProductImage
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		img, err = scanImage(tx.QueryRowContext(ctx, `
+	DELETE FROM product_images WHERE product_id = ? AND id = ? RETURNING `+columns, productID, id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if img.Primary {
+			if _, err := tx.ExecContext(ctx, `
+	UPDATE product_images SET is_primary = true
+	WHERE id = (SELECT id FROM product_images WHERE product_id = ? ORDER BY position, id LIMIT 1)`, productID); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New("failed to delete image: " + err.Error())
+	}
+	return img, nil
+}
@@ -0,0 +1,25 @@
+package image_test
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/image"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/db/sqlitetest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) (image.Repository, product.Repository) {
+		conn := sqlitetest.New(t)
+		return image.NewSQLiteRepo(conn), product.NewSQLiteRepo(conn)
+	})
+}
+
+func TestSQLiteRepo_AddImage_missingProduct(t *testing.T) {
+	repo := image.NewSQLiteRepo(sqlitetest.New(t))
+	err := repo.AddImage(context.Background(), &models.ProductImage{ProductID: 99, Key: "a.jpg", ThumbnailKey: "a_thumb.jpg"})
+	assert.ErrorIs(t, err, image.ErrProductNotFound)
+}
@@ -0,0 +1,137 @@
+package order_test
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/order"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runContract checks the behaviour every order.Repository shares. newRepo
+// returns the order repository with a product repository of the same store,
+// for the products the orders sell.
+func runContract(t *testing.T, newRepo func(t *testing.T) (order.Repository, product.Repository)) {
+	ctx := context.Background()
+
+	newOrder := func(t *testing.T, repo order.Repository, products product.Repository, names ...string) *models.Order {
+		t.Helper()
+		o := &models.Order{Currency: "EUR"}
+		for i, name := range names {
+			p := &models.Product{Name: name, Price: money.New(2500, "EUR"), Quantity: 5, Description: name}
+			require.NoError(t, products.CreateProduct(ctx, p))
+			o.Lines = append(o.Lines, &models.OrderLine{
+				ProductID: p.ID, Quantity: i + 1, UnitPrice: money.New(2500, "EUR"),
+			})
+		}
+		require.NoError(t, repo.CreateOrder(ctx, o))
+		return o
+	}
+
+	t.Run("orders", func(t *testing.T) {
+		repo, products := newRepo(t)
+		o := newOrder(t, repo, products, "pen", "ink")
+		assert.Equal(t, models.OrderPending, o.Status)
+		assert.NotZero(t, o.Lines[0].ID)
+		assert.False(t, o.CreatedAt.IsZero())
+
+		got, err := repo.GetOrder(ctx, o.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.OrderPending, got.Status)
+		assert.Equal(t, "EUR", got.Currency)
+		require.Len(t, got.Lines, 2)
+		assert.Equal(t, &models.OrderLine{
+			ID: o.Lines[1].ID, ProductID: o.Lines[1].ProductID, Quantity: 2, UnitPrice: money.New(2500, "EUR"),
+		}, got.Lines[1])
+		assert.Nil(t, got.AllocatedAt)
+
+		second := newOrder(t, repo, products, "paper")
+		_, err = repo.CancelOrder(ctx, second.ID)
+		require.NoError(t, err)
+		third := newOrder(t, repo, products, "glue")
+
+		all, err := repo.GetOrders(ctx, models.OrderFilter{})
+		require.NoError(t, err)
+		require.Len(t, all, 3)
+		assert.Equal(t, third.ID, all[0].ID, "newest first")
+		assert.Len(t, all[2].Lines, 2)
+		pending, err := repo.GetOrders(ctx, models.OrderFilter{Status: models.OrderPending})
+		require.NoError(t, err)
+		require.Len(t, pending, 2)
+		assert.Equal(t, o.ID, pending[1].ID)
+		require.Len(t, pending[1].Lines, 2)
+		assert.Equal(t, "EUR", pending[1].Lines[0].UnitPrice.Currency)
+
+		_, err = repo.GetOrder(ctx, 999)
+		assert.ErrorIs(t, err, order.ErrOrderNotFound)
+	})
+
+	t.Run("transitions", func(t *testing.T) {
+		repo, products := newRepo(t)
+		o := newOrder(t, repo, products, "pen")
+
+		_, err := repo.ShipOrder(ctx, o.ID)
+		assert.ErrorIs(t, err, order.ErrInvalidStatus, "a pending order is allocated first")
+
+		allocated, err := repo.AllocateOrder(ctx, o.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.OrderAllocated, allocated.Status)
+		require.NotNil(t, allocated.AllocatedAt)
+		assert.Equal(t, *allocated.AllocatedAt, allocated.UpdatedAt)
+		_, err = repo.AllocateOrder(ctx, o.ID)
+		assert.ErrorIs(t, err, order.ErrInvalidStatus)
+
+		shipped, err := repo.ShipOrder(ctx, o.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.OrderShipped, shipped.Status)
+		assert.NotNil(t, shipped.ShippedAt)
+		got, err := repo.GetOrder(ctx, o.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.OrderShipped, got.Status)
+		assert.Equal(t, allocated.AllocatedAt.UTC(), got.AllocatedAt.UTC())
+		_, err = repo.CancelOrder(ctx, o.ID)
+		assert.ErrorIs(t, err, order.ErrInvalidStatus, "a shipped order stays shipped")
+
+		allocatedThenCancelled := newOrder(t, repo, products, "ink")
+		_, err = repo.AllocateOrder(ctx, allocatedThenCancelled.ID)
+		require.NoError(t, err)
+		cancelled, err := repo.CancelOrder(ctx, allocatedThenCancelled.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.OrderCancelled, cancelled.Status)
+		assert.NotNil(t, cancelled.AllocatedAt, "the stock of an allocated order goes back")
+		assert.NotNil(t, cancelled.CancelledAt)
+
+		pendingThenCancelled := newOrder(t, repo, products, "paper")
+		cancelled, err = repo.CancelOrder(ctx, pendingThenCancelled.ID)
+		require.NoError(t, err)
+		assert.Nil(t, cancelled.AllocatedAt)
+		_, err = repo.CancelOrder(ctx, pendingThenCancelled.ID)
+		assert.ErrorIs(t, err, order.ErrInvalidStatus)
+		_, err = repo.AllocateOrder(ctx, pendingThenCancelled.ID)
+		assert.ErrorIs(t, err, order.ErrInvalidStatus)
+		_, err = repo.CancelOrder(ctx, 999)
+		assert.ErrorIs(t, err, order.ErrOrderNotFound)
+	})
+	t.Run("products on orders", func(t *testing.T) {
+		repo, products := newRepo(t)
+		o := newOrder(t, repo, products, "pen")
+		pen := o.Lines[0].ProductID
+		_, err := repo.AllocateOrder(ctx, o.ID)
+		require.NoError(t, err)
+
+		_, err = products.HardDeleteProduct(ctx, pen)
+		assert.ErrorIs(t, err, product.ErrReferenced)
+		require.NoError(t, products.DeleteProduct(ctx, pen))
+		_, err = products.PurgeProduct(ctx, pen, time.Now().Add(time.Hour))
+		assert.ErrorIs(t, err, product.ErrReferenced, "the purge skips it")
+		got, err := repo.GetOrder(ctx, o.ID)
+		require.NoError(t, err)
+		require.Len(t, got.Lines, 1)
+		assert.Equal(t, pen, got.Lines[0].ProductID)
+	})
+}
@@ -0,0 +1,135 @@
+package order
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryRepo keeps sales orders in memory, for tests and local demos. A
+// transition applies as soon as it is valid, and is undone if the
+// db.MemoryTx.InTx call it runs in fails.
+type MemoryRepo struct {
+	mu          sync.RWMutex
+	orders      map[int64]*models.Order
+	lastOrderID int64
+	lastLineID  int64
+}
+
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{orders: make(map[int64]*models.Order)}
+}
+
+// ReferencesProduct reports whether an order has a line of the product, so
+// the product repository keeps it like the foreign key of order_lines does.
+func (r *MemoryRepo) ReferencesProduct(productID int64) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, o := range r.orders {
+		for _, l := range o.Lines {
+			if l.ProductID == productID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// viewOrder returns a deep copy, so callers cannot mutate the stored order.
+func viewOrder(o *models.Order) *models.Order {
+	cp := *o
+	cp.Lines = make([]*models.OrderLine, len(o.Lines))
+	for i, l := range o.Lines {
+		line := *l
+		cp.Lines[i] = &line
+	}
+	for _, t := range []**time.Time{&cp.AllocatedAt, &cp.ShippedAt, &cp.CancelledAt} {
+		if *t != nil {
+			v := **t
+			*t = &v
+		}
+	}
+	return &cp
+}
+
+func (r *MemoryRepo) CreateOrder(_ context.Context, o *models.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastOrderID++
+	o.ID = r.lastOrderID
+	o.Status = models.OrderPending
+	o.CreatedAt = db.Now()
+	o.UpdatedAt = o.CreatedAt
+	for _, l := range o.Lines {
+		r.lastLineID++
+		l.ID = r.lastLineID
+	}
+	r.orders[o.ID] = viewOrder(o)
+	return nil
+}
+
+func (r *MemoryRepo) GetOrder(_ context.Context, id int64) (*models.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	o, ok := r.orders[id]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	return viewOrder(o), nil
+}
+
+func (r *MemoryRepo) GetOrders(_ context.Context, f models.OrderFilter) ([]*models.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var orders []*models.Order
+	for _, o := range r.orders {
+		if f.Status == "" || o.Status == f.Status {
+			orders = append(orders, viewOrder(o))
+		}
+	}
+	slices.SortFunc(orders, func(a, b *models.Order) int { return int(b.ID - a.ID) })
+	return orders, nil
+}
+
+func (r *MemoryRepo) AllocateOrder(ctx context.Context, id int64) (*models.Order, error) {
+	return r.transition(ctx, id, models.OrderAllocated, "allocate")
+}
+
+func (r *MemoryRepo) ShipOrder(ctx context.Context, id int64) (*models.Order, error) {
+	return r.transition(ctx, id, models.OrderShipped, "ship")
+}
+
+func (r *MemoryRepo) CancelOrder(ctx context.Context, id int64) (*models.Order, error) {
+	return r.transition(ctx, id, models.OrderCancelled, "cancel")
+}
+
+// transition moves a copy of the order to status, stored only if it can.
+func (r *MemoryRepo) transition(ctx context.Context, id int64, status, action string) (*models.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.orders[id]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	o := viewOrder(stored)
+	if err := move(o, status, action, db.Now()); err != nil {
+		return nil, err
+	}
+	r.orders[id] = o
+	db.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.orders[id] == o {
+			r.orders[id] = stored
+		}
+	})
+	return viewOrder(o), nil
+}
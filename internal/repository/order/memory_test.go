@@ -0,0 +1,14 @@
+package order_test
+
+import (
+	"prodcrud/internal/repository/order"
+	"prodcrud/internal/repository/product"
+	"testing"
+)
+
+func TestMemoryRepo_Contract(t *testing.T) {
+	runContract(t, func(*testing.T) (order.Repository, product.Repository) {
+		repo := order.NewMemoryRepo()
+		return repo, product.NewMemoryRepo(repo)
+	})
+}
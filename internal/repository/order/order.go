@@ -0,0 +1,273 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Repository stores the sales orders. It does not touch the stock, which the
+// caller takes and returns in the same transaction.
+type Repository interface {
+	// CreateOrder stores a pending order with its lines and sets their IDs,
+	// the status and the timestamps.
+	CreateOrder(ctx context.Context, o *models.Order) error
+	GetOrder(ctx context.Context, id int64) (*models.Order, error)
+	// GetOrders lists the orders matching f, newest first.
+	GetOrders(ctx context.Context, f models.OrderFilter) ([]*models.Order, error)
+	// AllocateOrder marks a pending order as allocated, and joins the
+	// transaction of db.Transactor.InTx.
+	AllocateOrder(ctx context.Context, id int64) (*models.Order, error)
+	// ShipOrder marks an allocated order as shipped.
+	ShipOrder(ctx context.Context, id int64) (*models.Order, error)
+	// CancelOrder cancels a pending or allocated order, and joins the
+	// transaction of db.Transactor.InTx. The AllocatedAt of the cancelled
+	// order tells whether its units were taken from stock.
+	CancelOrder(ctx context.Context, id int64) (*models.Order, error)
+}
+
+const logPackage = "repository/order"
+
+var (
+	// ErrOrderNotFound is returned when the order does not exist.
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrInvalidStatus is returned when the status of the order does not
+	// allow the operation.
+	ErrInvalidStatus = errors.New("invalid order status")
+)
+
+const (
+	orderColumns = `id, status, currency, created_at, updated_at, allocated_at, shipped_at, cancelled_at`
+	lineColumns  = `id, order_id, product_id, quantity, unit_price`
+)
+
+// transitions lists, by status, the statuses an order can move to it from.
+var transitions = map[string][]string{
+	models.OrderAllocated: {models.OrderPending},
+	models.OrderShipped:   {models.OrderAllocated},
+	models.OrderCancelled: {models.OrderPending, models.OrderAllocated},
+}
+
+// move sets o to status at the given time, if its current status allows it.
+func move(o *models.Order, status, action string, at time.Time) error {
+	if !slices.Contains(transitions[status], o.Status) {
+		return fmt.Errorf("%w: cannot %s a %s order", ErrInvalidStatus, action, o.Status)
+	}
+	o.Status, o.UpdatedAt = status, at
+	switch status {
+	case models.OrderAllocated:
+		o.AllocatedAt = &at
+	case models.OrderShipped:
+		o.ShippedAt = &at
+	case models.OrderCancelled:
+		o.CancelledAt = &at
+	}
+	return nil
+}
+
+// isOrderError tells the errors returned as is from the ones to wrap.
+func isOrderError(err error) bool {
+	return errors.Is(err, ErrOrderNotFound) || errors.Is(err, ErrInvalidStatus)
+}
+
+// scanner is a row of either driver.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOrder(row scanner) (*models.Order, error) {
+	o := models.Order{Lines: []*models.OrderLine{}}
+	if err := row.Scan(&o.ID, &o.Status, &o.Currency, &o.CreatedAt, &o.UpdatedAt, &o.AllocatedAt, &o.ShippedAt,
+		&o.CancelledAt); err != nil {
+		return nil, err //nolint:wrapcheck //wrapped by the callers
+	}
+	return &o, nil
+}
+
+// scanLine returns a line with the id of its order, whose currency it takes.
+func scanLine(row scanner) (int64, *models.OrderLine, error) {
+	var (
+		orderID int64
+		l       models.OrderLine
+	)
+	if err := row.Scan(&l.ID, &orderID, &l.ProductID, &l.Quantity, &l.UnitPrice.Amount); err != nil {
+		return 0, nil, err //nolint:wrapcheck //wrapped by the callers
+	}
+	return orderID, &l, nil
+}
+
+// attachLine appends l to its order in orders, in the currency of the order.
+func attachLine(orders map[int64]*models.Order, orderID int64, l *models.OrderLine) {
+	if o, ok := orders[orderID]; ok {
+		l.UnitPrice.Currency = o.Currency
+		o.Lines = append(o.Lines, l)
+	}
+}
+
+type Repo struct {
+	db *db.DB
+}
+
+func NewRepo(db *db.DB) *Repo {
+	return &Repo{db: db}
+}
+
+func (r *Repo) CreateOrder(ctx context.Context, o *models.Order) error {
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		o.Status = models.OrderPending
+		if err := conn.QueryRow(ctx, `INSERT INTO orders(status, currency) VALUES ($1, $2)
+	RETURNING id, created_at, updated_at`, o.Status, o.Currency).Scan(&o.ID, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return err
+		}
+		for _, l := range o.Lines {
+			if err := conn.QueryRow(ctx, `
+	INSERT INTO order_lines(order_id, product_id, quantity, unit_price) VALUES ($1, $2, $3, $4) RETURNING id`,
+				o.ID, l.ProductID, l.Quantity, l.UnitPrice.Amount).Scan(&l.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.New("failed to create order: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) GetOrder(ctx context.Context, id int64) (*models.Order, error) {
+	var o *models.Order
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		var err error
+		o, err = getOrder(ctx, q, id, false)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrOrderNotFound) {
+			logging.For(ctx, logPackage).Debug("order not found", "id", id)
+			return nil, err
+		}
+		return nil, errors.New("failed to get order: " + err.Error())
+	}
+	return o, nil
+}
+
+// getOrder reads an order with its lines, locking it for update if asked.
+func getOrder(ctx context.Context, q db.Querier, id int64, forUpdate bool) (*models.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	o, err := scanOrder(q.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	orders := map[int64]*models.Order{o.ID: o}
+	if err := getLines(ctx, q, orders, `WHERE order_id = $1`, id); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// getLines attaches the lines matching where to their order in orders.
+func getLines(ctx context.Context, q db.Querier, orders map[int64]*models.Order, where string, args ...any) error {
+	rows, err := q.Query(ctx, `SELECT `+lineColumns+` FROM order_lines `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get order lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		orderID, l, err := scanLine(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan order lines: %w", err)
+		}
+		attachLine(orders, orderID, l)
+	}
+	return rows.Err()
+}
+
+func (r *Repo) GetOrders(ctx context.Context, f models.OrderFilter) ([]*models.Order, error) {
+	const where = `($1::text = '' OR status = $1)`
+	var orders []*models.Order
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		orders = nil
+		rows, err := q.Query(ctx, `SELECT `+orderColumns+` FROM orders WHERE `+where+` ORDER BY id DESC`, f.Status)
+		if err != nil {
+			return fmt.Errorf("failed to get orders: %w", err)
+		}
+		defer rows.Close()
+
+		byID := make(map[int64]*models.Order)
+		for rows.Next() {
+			o, err := scanOrder(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan orders: %w", err)
+			}
+			orders = append(orders, o)
+			byID[o.ID] = o
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+		return getLines(ctx, q, byID, `WHERE order_id IN (SELECT id FROM orders WHERE `+where+`)`, f.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *Repo) AllocateOrder(ctx context.Context, id int64) (*models.Order, error) {
+	return r.transition(ctx, id, models.OrderAllocated, "allocate")
+}
+
+func (r *Repo) ShipOrder(ctx context.Context, id int64) (*models.Order, error) {
+	return r.transition(ctx, id, models.OrderShipped, "ship")
+}
+
+func (r *Repo) CancelOrder(ctx context.Context, id int64) (*models.Order, error) {
+	return r.transition(ctx, id, models.OrderCancelled, "cancel")
+}
+
+// transition locks an order in a transaction, which it joins if ctx has one,
+// and moves it to status.
+func (r *Repo) transition(ctx context.Context, id int64, status, action string) (*models.Order, error) {
+	var o *models.Order
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		var err error
+		if o, err = getOrder(ctx, conn, id, true); err != nil {
+			return err
+		}
+		// the time of the transaction, as now() stores it in the timestamp columns
+		var at time.Time
+		if err := conn.QueryRow(ctx, `SELECT localtimestamp`).Scan(&at); err != nil {
+			return err
+		}
+		if err := move(o, status, action, at); err != nil {
+			return err
+		}
+		_, err = conn.Exec(ctx, `UPDATE orders SET status = $1, updated_at = $2, allocated_at = $3, shipped_at = $4,
+	    cancelled_at = $5 WHERE id = $6`, o.Status, o.UpdatedAt, o.AllocatedAt, o.ShippedAt, o.CancelledAt, id)
+		return err
+	})
+	if err != nil {
+		if isOrderError(err) {
+			return nil, err
+		}
+		return nil, errors.New("failed to " + action + " order: " + err.Error())
+	}
+	return o, nil
+}
@@ -0,0 +1,22 @@
+package order_test
+
+import (
+	"prodcrud/internal/repository/order"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/db/pgtest"
+	"testing"
+)
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+func TestRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) (order.Repository, product.Repository) {
+		pool := pgtest.New(t)
+		return order.NewRepo(pool), product.NewRepo(pool)
+	})
+}
+
+func TestRepo_AllocateOrder_sharedTransaction(t *testing.T) {
+	pool := pgtest.New(t)
+	checkSharedTransaction(t, pool, order.NewRepo(pool), product.NewRepo(pool))
+}
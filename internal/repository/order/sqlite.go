@@ -0,0 +1,173 @@
+package order
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+)
+
+// SQLiteRepo is the order store for SQLite.
+type SQLiteRepo struct {
+	db *db.SQLite
+}
+
+func NewSQLiteRepo(db *db.SQLite) *SQLiteRepo {
+	return &SQLiteRepo{db: db}
+}
+
+func (r *SQLiteRepo) CreateOrder(ctx context.Context, o *models.Order) error {
+	ts := db.Now()
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		o.Status = models.OrderPending
+		if err := conn.QueryRowContext(ctx, `INSERT INTO orders(status, currency, created_at, updated_at) VALUES (?, ?, ?, ?)
+	RETURNING id, created_at, updated_at`, o.Status, o.Currency, ts, ts).Scan(&o.ID, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return err
+		}
+		for _, l := range o.Lines {
+			if err := conn.QueryRowContext(ctx, `
+	INSERT INTO order_lines(order_id, product_id, quantity, unit_price) VALUES (?, ?, ?, ?) RETURNING id`,
+				o.ID, l.ProductID, l.Quantity, l.UnitPrice.Amount).Scan(&l.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.New("failed to create order: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetOrder(ctx context.Context, id int64) (*models.Order, error) {
+	var o *models.Order
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		var err error
+		o, err = sqliteGetOrder(ctx, r.db.Conn(ctx), id)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrOrderNotFound) {
+			logging.For(ctx, logPackage).Debug("order not found", "id", id)
+			return nil, err
+		}
+		return nil, errors.New("failed to get order: " + err.Error())
+	}
+	return o, nil
+}
+
+func sqliteGetOrder(ctx context.Context, conn db.SQLConn, id int64) (*models.Order, error) {
+	o, err := scanOrder(conn.QueryRowContext(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	orders := map[int64]*models.Order{o.ID: o}
+	if err := sqliteGetLines(ctx, conn, orders, `WHERE order_id = ?`, id); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func sqliteGetLines(ctx context.Context, conn db.SQLConn, orders map[int64]*models.Order, where string, args ...any) error {
+	rows, err := conn.QueryContext(ctx, `SELECT `+lineColumns+` FROM order_lines `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get order lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		orderID, l, err := scanLine(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan order lines: %w", err)
+		}
+		attachLine(orders, orderID, l)
+	}
+	return rows.Err()
+}
+
+func (r *SQLiteRepo) GetOrders(ctx context.Context, f models.OrderFilter) ([]*models.Order, error) {
+	const where = `(? = '' OR status = ?)`
+	args := []any{f.Status, f.Status}
+	var orders []*models.Order
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `SELECT `+orderColumns+` FROM orders WHERE `+where+` ORDER BY id DESC`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to get orders: %w", err)
+		}
+		defer rows.Close()
+
+		byID := make(map[int64]*models.Order)
+		for rows.Next() {
+			o, err := scanOrder(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan orders: %w", err)
+			}
+			orders = append(orders, o)
+			byID[o.ID] = o
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+		return sqliteGetLines(ctx, r.db, byID, `WHERE order_id IN (SELECT id FROM orders WHERE `+where+`)`, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *SQLiteRepo) AllocateOrder(ctx context.Context, id int64) (*models.Order, error) {
+	return r.transition(ctx, id, models.OrderAllocated, "allocate")
+}
+
+func (r *SQLiteRepo) ShipOrder(ctx context.Context, id int64) (*models.Order, error) {
+	return r.transition(ctx, id, models.OrderShipped, "ship")
+}
+
+func (r *SQLiteRepo) CancelOrder(ctx context.Context, id int64) (*models.Order, error) {
+	return r.transition(ctx, id, models.OrderCancelled, "cancel")
+}
+
+// transition touches the order first, so the transaction takes the write
+// lock before it reads, then moves it to status at the time of the change.
+func (r *SQLiteRepo) transition(ctx context.Context, id int64, status, action string) (*models.Order, error) {
+	var o *models.Order
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		res, err := conn.ExecContext(ctx, `UPDATE orders SET updated_at = ? WHERE id = ?`, db.Now(), id)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrOrderNotFound
+		}
+		if o, err = sqliteGetOrder(ctx, conn, id); err != nil {
+			return err
+		}
+		if err := move(o, status, action, o.UpdatedAt); err != nil {
+			return err
+		}
+		_, err = conn.ExecContext(ctx, `UPDATE orders SET status = ?, allocated_at = ?, shipped_at = ?, cancelled_at = ?
+	WHERE id = ?`, o.Status, o.AllocatedAt, o.ShippedAt, o.CancelledAt, id)
+		return err
+	})
+	if err != nil {
+		if isOrderError(err) {
+			return nil, err
+		}
+		return nil, errors.New("failed to " + action + " order: " + err.Error())
+	}
+	return o, nil
+}
@@ -0,0 +1,68 @@
+package order_test
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/order"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/db/sqlitetest"
+	"prodcrud/pkg/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) (order.Repository, product.Repository) {
+		conn := sqlitetest.New(t)
+		return order.NewSQLiteRepo(conn), product.NewSQLiteRepo(conn)
+	})
+}
+
+func TestSQLiteRepo_AllocateOrder_sharedTransaction(t *testing.T) {
+	conn := sqlitetest.New(t)
+	checkSharedTransaction(t, conn, order.NewSQLiteRepo(conn), product.NewSQLiteRepo(conn))
+}
+
+// checkSharedTransaction allocates an order and takes its units from stock in
+// one transaction, which rolls back both when the stock is short.
+func checkSharedTransaction(t *testing.T, tx db.Transactor, repo order.Repository, products product.Repository) {
+	ctx := context.Background()
+	p := &models.Product{Name: "pen", Price: money.New(250, "EUR"), Quantity: 3, Description: "blue"}
+	require.NoError(t, products.CreateProduct(ctx, p))
+	allocate := func(o *models.Order) error {
+		return tx.InTx(ctx, func(ctx context.Context) error {
+			if _, err := repo.AllocateOrder(ctx, o.ID); err != nil {
+				return err
+			}
+			_, err := products.AdjustQuantity(ctx, p.ID, -o.Lines[0].Quantity)
+			return err
+		})
+	}
+	newOrder := func(quantity int) *models.Order {
+		o := &models.Order{Currency: "EUR", Lines: []*models.OrderLine{
+			{ProductID: p.ID, Quantity: quantity, UnitPrice: money.New(250, "EUR")},
+		}}
+		require.NoError(t, repo.CreateOrder(ctx, o))
+		return o
+	}
+
+	short := newOrder(4)
+	require.ErrorIs(t, allocate(short), product.ErrInsufficientStock)
+	got, err := repo.GetOrder(ctx, short.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderPending, got.Status)
+	assert.Nil(t, got.AllocatedAt)
+
+	// the last units can be sold
+	all := newOrder(3)
+	require.NoError(t, allocate(all))
+	got, err = repo.GetOrder(ctx, all.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderAllocated, got.Status)
+	stored, err := products.GetProduct(ctx, p.ID, false)
+	require.NoError(t, err)
+	assert.Zero(t, stored.Quantity)
+}
@@ -0,0 +1,548 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"prodcrud/internal/models"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+)
+
+// MemoryRepo keeps products in memory. It follows the same semantics as the
+// Postgres Repo and is meant for tests and local demos; data is lost on exit.
+type MemoryRepo struct {
+	mu       sync.RWMutex
+	products map[int64]*models.Product
+	order    []int64
+	lastID   int64
+	purges   []models.PurgedProduct
+	prices   map[int64]map[string]money.Money
+	// history holds the price periods of each product, sorted by start
+	history      map[int64][]*models.PricePeriod
+	lastPeriodID int64
+	axes         map[int64][]models.VariantAxis
+	referrers    []Referrer
+}
+
+// Referrer is a store of records pointing to products, such as order lines.
+type Referrer interface {
+	// ReferencesProduct reports whether a record points to the product.
+	ReferencesProduct(productID int64) bool
+}
+
+// NewMemoryRepo refuses to remove a product the referrers point to, as the
+// foreign keys of a database do.
+func NewMemoryRepo(referrers ...Referrer) *MemoryRepo {
+	return &MemoryRepo{
+		products:  make(map[int64]*models.Product),
+		prices:    make(map[int64]map[string]money.Money),
+		history:   make(map[int64][]*models.PricePeriod),
+		axes:      make(map[int64][]models.VariantAxis),
+		referrers: referrers,
+	}
+}
+
+// view returns a copy, so callers cannot mutate the stored product.
+func view(p *models.Product) *models.Product {
+	cp := *p
+	if p.DeletedAt != nil {
+		deletedAt := *p.DeletedAt
+		cp.DeletedAt = &deletedAt
+	}
+	if p.ParentID != nil {
+		parentID := *p.ParentID
+		cp.ParentID = &parentID
+	}
+	cp.Options = maps.Clone(p.Options)
+	cp.Attributes = cloneAttributes(p.Attributes)
+	return &cp
+}
+
+// cloneAttributes copies attributes through JSON, as the SQL backends store
+// them, so nested unit values are not shared and numbers are float64.
+func cloneAttributes(attributes map[string]any) map[string]any {
+	if len(attributes) == 0 {
+		return nil
+	}
+	var cp map[string]any
+	_ = json.Unmarshal([]byte(encodeAttributes(attributes)), &cp)
+	return cp
+}
+
+func (r *MemoryRepo) CreateProduct(_ context.Context, p *models.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.insert(p, nil)
+	return nil
+}
+
+// insert stores p, as a variant of parentID when it is set, and opens the
+// period of its base price.
+func (r *MemoryRepo) insert(p *models.Product, parentID *int64) {
+	r.lastID++
+	p.ID = r.lastID
+	p.CreatedAt = db.Now()
+	p.UpdatedAt = p.CreatedAt
+	p.DeletedAt = nil
+
+	stored := *p
+	stored.ParentID, stored.SKU, stored.Options = nil, "", nil
+	if parentID != nil {
+		stored.ParentID, stored.SKU, stored.Options = parentID, p.SKU, maps.Clone(p.Options)
+	}
+	stored.VariantAxes, stored.Variants, stored.Tax = nil, nil, nil
+	stored.Attributes = cloneAttributes(p.Attributes)
+	r.products[p.ID] = &stored
+	r.order = append(r.order, p.ID)
+	r.schedule(&models.PricePeriod{ProductID: p.ID, Price: p.Price, EffectiveFrom: p.CreatedAt})
+}
+
+func (r *MemoryRepo) GetProduct(ctx context.Context, id int64, includeDeleted bool) (*models.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.products[id]
+	if !ok || (p.IsDeleted() && !includeDeleted) {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return nil, ErrNotFound
+	}
+	return view(p), nil
+}
+
+func (r *MemoryRepo) GetAllProducts(_ context.Context, includeDeleted bool) ([]*models.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var products []*models.Product
+	for _, id := range r.order {
+		if p := r.products[id]; includeDeleted || !p.IsDeleted() {
+			products = append(products, view(p))
+		}
+	}
+	return products, nil
+}
+
+func (r *MemoryRepo) FindProducts(_ context.Context, f models.ProductFilter) ([]*models.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var products []*models.Product
+	for _, id := range r.order {
+		p := r.products[id]
+		if (f.IncludeDeleted || !p.IsDeleted()) && (f.Category == "" || p.Category == f.Category) && p.HasAttributes(f.Attributes) {
+			products = append(products, view(p))
+		}
+	}
+	return products, nil
+}
+
+func (r *MemoryRepo) UpdateProduct(ctx context.Context, p *models.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.products[p.ID]
+	if !ok || stored.IsDeleted() {
+		logging.For(ctx, logPackage).Debug("product not found", "id", p.ID)
+		return ErrNotFound
+	}
+	changed := stored.Price != p.Price
+	stored.Name = p.Name
+	stored.Price = p.Price
+	stored.Quantity = p.Quantity
+	stored.Description = p.Description
+	stored.Category = p.Category
+	stored.TaxClass = p.TaxClass
+	stored.Attributes = cloneAttributes(p.Attributes)
+	stored.UpdatedAt = db.Now()
+	if changed {
+		r.schedule(&models.PricePeriod{ProductID: p.ID, Price: p.Price, EffectiveFrom: stored.UpdatedAt})
+	}
+	return nil
+}
+
+func (r *MemoryRepo) AdjustQuantity(ctx context.Context, id int64, delta int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.products[id]
+	if !ok || stored.IsDeleted() {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return 0, ErrNotFound
+	}
+	if stored.Quantity+delta < 0 {
+		return stored.Quantity, ErrInsufficientStock
+	}
+	updatedAt := stored.UpdatedAt
+	stored.Quantity += delta
+	stored.UpdatedAt = db.Now()
+	db.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		stored.Quantity -= delta
+		stored.UpdatedAt = updatedAt
+	})
+	return stored.Quantity, nil
+}
+
+func (r *MemoryRepo) RestockQuantity(ctx context.Context, id int64, quantity int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.products[id]
+	if !ok {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return ErrNotFound
+	}
+	updatedAt := stored.UpdatedAt
+	stored.Quantity += quantity
+	stored.UpdatedAt = db.Now()
+	db.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		stored.Quantity -= quantity
+		stored.UpdatedAt = updatedAt
+	})
+	return nil
+}
+
+func (r *MemoryRepo) DeleteProduct(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.products[id]
+	if !ok {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return ErrNotFound
+	}
+	if err := checkTransition(stored.IsDeleted(), true); err != nil {
+		return err
+	}
+	deletedAt := db.Now()
+	stored.DeletedAt = &deletedAt
+	return nil
+}
+
+func (r *MemoryRepo) RestoreProduct(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.products[id]
+	if !ok {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return ErrNotFound
+	}
+	if err := checkTransition(stored.IsDeleted(), false); err != nil {
+		return err
+	}
+	stored.DeletedAt = nil
+	return nil
+}
+
+func (r *MemoryRepo) GetProductStats(_ context.Context, lowStock int) (*models.ProductStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var st models.ProductStats
+	for _, p := range r.products {
+		if p.IsDeleted() {
+			continue
+		}
+		st.Active++
+		st.StockUnits += int64(p.Quantity)
+		if p.Quantity <= lowStock {
+			st.LowStock++
+		}
+	}
+	return &st, nil
+}
+
+func (r *MemoryRepo) GetDeletedProducts(_ context.Context, before time.Time) ([]*models.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var products []*models.Product
+	for _, id := range r.order {
+		if p := r.products[id]; p.IsDeleted() && p.DeletedAt.Before(before) {
+			products = append(products, view(p))
+		}
+	}
+	return products, nil
+}
+
+func (r *MemoryRepo) HardDeleteProduct(ctx context.Context, id int64) (*models.PurgedProduct, error) {
+	return r.remove(ctx, id, models.PurgeReasonHardDelete, func(*models.Product) bool { return true })
+}
+
+func (r *MemoryRepo) PurgeProduct(ctx context.Context, id int64, before time.Time) (*models.PurgedProduct, error) {
+	return r.remove(ctx, id, models.PurgeReasonRetention, func(p *models.Product) bool {
+		return p.IsDeleted() && p.DeletedAt.Before(before)
+	})
+}
+
+// remove deletes the product if match accepts it. It returns ErrReferenced
+// for a parent of variants, or a product one of the referrers points to.
+func (r *MemoryRepo) remove(ctx context.Context, id int64, reason string, match func(*models.Product) bool) (*models.PurgedProduct, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.products[id]
+	if !ok || !match(stored) {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return nil, ErrNotFound
+	}
+	for _, p := range r.products {
+		if p.ParentID != nil && *p.ParentID == id {
+			return nil, ErrReferenced
+		}
+	}
+	for _, ref := range r.referrers {
+		if ref.ReferencesProduct(id) {
+			return nil, ErrReferenced
+		}
+	}
+	purged := models.PurgedProduct{ProductID: id, Name: stored.Name, PurgedAt: db.Now(), Reason: reason}
+	purged.DeletedAt = view(stored).DeletedAt
+
+	delete(r.products, id)
+	delete(r.prices, id)
+	delete(r.history, id)
+	delete(r.axes, id)
+	for i, v := range r.order {
+		if v == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	r.purges = append(r.purges, purged)
+	return &purged, nil
+}
+
+func (r *MemoryRepo) GetProductPrices(_ context.Context, id int64) ([]money.Money, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var prices []money.Money
+	for _, m := range r.prices[id] {
+		prices = append(prices, m)
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Currency < prices[j].Currency })
+	return prices, nil
+}
+
+func (r *MemoryRepo) SetProductPrice(ctx context.Context, id int64, price money.Money) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.products[id]
+	if !ok || stored.IsDeleted() {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return ErrNotFound
+	}
+	if r.prices[id] == nil {
+		r.prices[id] = make(map[string]money.Money)
+	}
+	r.prices[id][price.Currency] = price
+	return nil
+}
+
+func (r *MemoryRepo) DeleteProductPrice(_ context.Context, id int64, currency string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.prices[id][currency]; !ok {
+		return ErrNotFound
+	}
+	delete(r.prices[id], currency)
+	return nil
+}
+
+func (r *MemoryRepo) GetPriceHistory(_ context.Context, id int64) ([]*models.PricePeriod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var periods []*models.PricePeriod
+	for _, p := range r.history[id] {
+		periods = append(periods, viewPeriod(p))
+	}
+	return periods, nil
+}
+
+func (r *MemoryRepo) SchedulePrice(ctx context.Context, p *models.PricePeriod) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.products[p.ProductID]
+	if !ok || stored.IsDeleted() {
+		logging.For(ctx, logPackage).Debug("product not found", "id", p.ProductID)
+		return ErrNotFound
+	}
+	r.schedule(p)
+	return nil
+}
+
+// schedule applies the plan of p to the periods of its product.
+func (r *MemoryRepo) schedule(p *models.PricePeriod) {
+	periods := r.history[p.ProductID]
+	plan := planPrice(viewPeriods(periods), p)
+
+	byID := make(map[int64]*models.PricePeriod, len(periods))
+	for _, q := range periods {
+		byID[q.ID] = q
+	}
+	for _, id := range plan.deletes {
+		delete(byID, id)
+	}
+	for _, u := range plan.updates {
+		byID[u.ID] = viewPeriod(u)
+	}
+	for _, in := range plan.inserts {
+		r.lastPeriodID++
+		in.ID = r.lastPeriodID
+		byID[in.ID] = viewPeriod(in)
+	}
+
+	periods = periods[:0]
+	for _, q := range byID {
+		periods = append(periods, q)
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].EffectiveFrom.Before(periods[j].EffectiveFrom) })
+	r.history[p.ProductID] = periods
+}
+
+func (r *MemoryRepo) ApplyScheduledPrices(_ context.Context, at time.Time) ([]*models.PricePeriod, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var applied []*models.PricePeriod
+	for _, id := range r.order {
+		p := r.products[id]
+		if p.IsDeleted() {
+			continue
+		}
+		for _, h := range r.history[id] {
+			if h.InEffect(at) && h.Price != p.Price {
+				p.Price = h.Price
+				p.UpdatedAt = db.Now()
+				applied = append(applied, viewPeriod(h))
+			}
+		}
+	}
+	return applied, nil
+}
+
+func (r *MemoryRepo) NextPriceChange(_ context.Context, after time.Time) (*time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var next *time.Time
+	for id, periods := range r.history {
+		if r.products[id].IsDeleted() {
+			continue
+		}
+		for _, h := range periods {
+			if h.EffectiveFrom.After(after) && (next == nil || h.EffectiveFrom.Before(*next)) {
+				from := h.EffectiveFrom
+				next = &from
+			}
+		}
+	}
+	return next, nil
+}
+
+func (r *MemoryRepo) GetVariantAxes(_ context.Context, id int64) ([]models.VariantAxis, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return cloneAxes(r.axes[id]), nil
+}
+
+func (r *MemoryRepo) GetVariants(_ context.Context, parentID int64, includeDeleted bool) ([]*models.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var variants []*models.Product
+	for _, id := range r.order {
+		if p := r.products[id]; p.ParentID != nil && *p.ParentID == parentID && (includeDeleted || !p.IsDeleted()) {
+			variants = append(variants, view(p))
+		}
+	}
+	return variants, nil
+}
+
+func (r *MemoryRepo) CreateVariants(ctx context.Context, parentID int64, axes []models.VariantAxis, variants []*models.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parent, ok := r.products[parentID]
+	if !ok || parent.IsDeleted() {
+		logging.For(ctx, logPackage).Debug("product not found", "id", parentID)
+		return ErrNotFound
+	}
+	// check every variant first, so that none is stored when one conflicts
+	keys := make(map[string]bool)
+	skus := make(map[string]bool)
+	for _, p := range r.products {
+		if p.ParentID != nil {
+			skus[p.SKU] = true
+			if *p.ParentID == parentID {
+				keys[models.VariantKey(p.Options)] = true
+			}
+		}
+	}
+	for _, v := range variants {
+		key := models.VariantKey(v.Options)
+		if keys[key] {
+			return ErrDuplicateVariant
+		}
+		keys[key] = true
+		if skus[v.SKU] {
+			return ErrDuplicateSKU
+		}
+		skus[v.SKU] = true
+	}
+
+	r.axes[parentID] = cloneAxes(axes)
+	for _, v := range variants {
+		stored, parent := parentID, parentID
+		r.insert(v, &stored)
+		v.ParentID = &parent
+	}
+	return nil
+}
+
+func cloneAxes(axes []models.VariantAxis) []models.VariantAxis {
+	if axes == nil {
+		return nil
+	}
+	out := make([]models.VariantAxis, len(axes))
+	for i, a := range axes {
+		out[i] = models.VariantAxis{Name: a.Name, Values: slices.Clone(a.Values)}
+	}
+	return out
+}
+
+// viewPeriod returns a copy, so callers cannot mutate the stored period.
+func viewPeriod(p *models.PricePeriod) *models.PricePeriod {
+	cp := *p
+	if p.EffectiveTo != nil {
+		to := *p.EffectiveTo
+		cp.EffectiveTo = &to
+	}
+	return &cp
+}
+
+func viewPeriods(periods []*models.PricePeriod) []*models.PricePeriod {
+	out := make([]*models.PricePeriod, len(periods))
+	for i, p := range periods {
+		out[i] = viewPeriod(p)
+	}
+	return out
+}
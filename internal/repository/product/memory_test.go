@@ -0,0 +1,13 @@
+package product_test
+
+import (
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/product/producttest"
+	"testing"
+)
+
+func TestMemoryRepo_Contract(t *testing.T) {
+	producttest.Run(t, func(*testing.T) product.Repository {
+		return product.NewMemoryRepo()
+	})
+}
@@ -0,0 +1,59 @@
+package product_test
+
+import (
+	"context"
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/product/producttest"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/db/pgtest"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+func TestRepo_Contract(t *testing.T) {
+	producttest.Run(t, func(t *testing.T) product.Repository {
+		return product.NewRepo(pgtest.New(t))
+	})
+}
+
+// TestRepo_sessionTimeZone asks for a session 14 hours ahead of UTC, whose
+// wall clock would put the timestamps written with now() in the future of
+// the UTC times they are compared with, and checks the pool keeps it in UTC.
+func TestRepo_sessionTimeZone(t *testing.T) {
+	ctx := context.Background()
+	pool, err := db.NewDB(ctx, db.Config{
+		DSN:            pgtest.New(t).Config().ConnConfig.ConnString() + "&timezone=Pacific/Kiritimati",
+		StartupTimeout: time.Minute,
+	})
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	repo := product.NewRepo(pool)
+
+	p := producttest.NewProduct("yuzu")
+	producttest.Create(t, repo, p)
+	assert.WithinDuration(t, time.Now(), p.CreatedAt, time.Minute)
+	p.Price = money.New(p.Price.Amount+100, p.Price.Currency)
+	require.NoError(t, repo.UpdateProduct(ctx, p))
+
+	next, err := repo.NextPriceChange(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Nil(t, next, "the new price is in effect already")
+	applied, err := repo.ApplyScheduledPrices(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+	history, err := repo.GetPriceHistory(ctx, p.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.WithinDuration(t, time.Now(), history[1].EffectiveFrom, time.Minute)
+
+	require.NoError(t, repo.DeleteProduct(ctx, p.ID))
+	deleted, err := repo.GetDeletedProducts(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, deleted, 1, "deleted a moment ago, not in 14 hours")
+}
@@ -2,109 +2,813 @@ package product
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"prodcrud/internal/models"
+	"time"
+
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type Repository interface {
 	CreateProduct(ctx context.Context, p *models.Product) error
-	GetProduct(ctx context.Context, id int64) (*models.Product, error)
-	GetAllProducts(ctx context.Context) ([]*models.Product, error)
+	// GetProduct returns ErrNotFound for a soft deleted product unless
+	// includeDeleted is set.
+	GetProduct(ctx context.Context, id int64, includeDeleted bool) (*models.Product, error)
+	GetAllProducts(ctx context.Context, includeDeleted bool) ([]*models.Product, error)
+	// FindProducts lists the products of f.Category, all categories if it is
+	// empty, whose attributes contain f.Attributes.
+	FindProducts(ctx context.Context, f models.ProductFilter) ([]*models.Product, error)
 	UpdateProduct(ctx context.Context, p *models.Product) error
+	// AdjustQuantity adds delta to the stock of an active product and returns
+	// the new quantity. It returns ErrInsufficientStock with the current
+	// quantity when the stock would go below zero. It joins the transaction of
+	// db.Transactor.InTx.
+	AdjustQuantity(ctx context.Context, id int64, delta int) (int, error)
+	// RestockQuantity returns quantity units to the stock of a product, active
+	// or deleted, such as those of a cancelled order. It joins the transaction
+	// of db.Transactor.InTx.
+	RestockQuantity(ctx context.Context, id int64, quantity int) error
+	// DeleteProduct soft deletes an active product, and returns
+	// ErrAlreadyDeleted if it is deleted already.
 	DeleteProduct(ctx context.Context, id int64) error
+	// RestoreProduct brings back a soft deleted product, and returns
+	// ErrNotDeleted if it is active.
 	RestoreProduct(ctx context.Context, id int64) error
+	GetProductStats(ctx context.Context, lowStock int) (*models.ProductStats, error)
+	// GetDeletedProducts lists the products soft deleted before the given time.
+	GetDeletedProducts(ctx context.Context, before time.Time) ([]*models.Product, error)
+	// HardDeleteProduct removes a product permanently, whatever its state.
+	HardDeleteProduct(ctx context.Context, id int64) (*models.PurgedProduct, error)
+	// PurgeProduct removes a product permanently if it was soft deleted
+	// before the given time, and returns ErrNotFound otherwise.
+	PurgeProduct(ctx context.Context, id int64, before time.Time) (*models.PurgedProduct, error)
+	// GetProductPrices lists the list prices set in other currencies, by currency.
+	GetProductPrices(ctx context.Context, id int64) ([]money.Money, error)
+	// SetProductPrice adds or replaces the list price in price.Currency of an
+	// active product.
+	SetProductPrice(ctx context.Context, id int64, price money.Money) error
+	// DeleteProductPrice removes a list price, ErrNotFound if it is not set.
+	DeleteProductPrice(ctx context.Context, id int64, currency string) error
+	// GetPriceHistory lists the base price periods of a product, oldest first.
+	GetPriceHistory(ctx context.Context, id int64) ([]*models.PricePeriod, error)
+	// SchedulePrice puts p in effect for an active product, reshaping the
+	// periods it overlaps, and sets p.ID and p.EffectiveTo.
+	SchedulePrice(ctx context.Context, p *models.PricePeriod) error
+	// ApplyScheduledPrices sets the price of every active product whose price
+	// differs from the period in effect at now, and returns those periods.
+	ApplyScheduledPrices(ctx context.Context, now time.Time) ([]*models.PricePeriod, error)
+	// NextPriceChange returns the start of the first period of an active
+	// product after the given time, nil if none is scheduled.
+	NextPriceChange(ctx context.Context, after time.Time) (*time.Time, error)
+	// GetVariantAxes returns the axes a parent varies by, nil if it has none.
+	GetVariantAxes(ctx context.Context, id int64) ([]models.VariantAxis, error)
+	// GetVariants lists the variants of a parent by id, the soft deleted ones
+	// only if includeDeleted is set.
+	GetVariants(ctx context.Context, parentID int64, includeDeleted bool) ([]*models.Product, error)
+	// CreateVariants replaces the axes of an active parent and adds the
+	// variants, all or none. Each variant needs its SKU and Options, and gets
+	// its ID, ParentID and timestamps. It returns ErrDuplicateVariant when the
+	// parent has a variant with the same options already, and ErrDuplicateSKU
+	// when a SKU is taken.
+	CreateVariants(ctx context.Context, parentID int64, axes []models.VariantAxis, variants []*models.Product) error
 }
 
+const logPackage = "repository/product"
+
+// ErrNotFound is returned by every implementation when the product does not
+// exist, or is soft deleted for operations that require an active product.
+var ErrNotFound = errors.New("product not found")
+
+// ErrAlreadyDeleted is returned when deleting a product that is soft deleted.
+var ErrAlreadyDeleted = errors.New("product is already deleted")
+
+// ErrNotDeleted is returned when restoring a product that is active.
+var ErrNotDeleted = errors.New("product is not deleted")
+
+// ErrReferenced is returned when a product cannot be removed permanently
+// because other records still point to it.
+var ErrReferenced = errors.New("product is referenced by other records")
+
+// ErrInsufficientStock is returned when taking more units than a product has
+// in stock.
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// ErrDuplicateVariant is returned when a parent already has a variant with
+// the same combination of options.
+var ErrDuplicateVariant = errors.New("variant with these options already exists")
+
+// ErrDuplicateSKU is returned when another variant has the SKU.
+var ErrDuplicateSKU = errors.New("sku is already taken")
+
+// productColumns are the columns read by scanProduct, from products p joined
+// with the variant link v of the product, if any.
+const productColumns = `p.id, p.name, p.price, p.currency, p.quantity, p.description, p.category, p.tax_class,
+	p.attributes::text, p.created_at, p.updated_at, p.deleted_at, v.parent_id, COALESCE(v.sku, ''), v.options::text
+	FROM products p LEFT JOIN product_variants v ON v.product_id = p.id`
+
 type Repo struct {
-	db *pgxpool.Pool
+	db *db.DB
 }
 
-func NewRepo(db *pgxpool.Pool) *Repo {
+func NewRepo(db *db.DB) *Repo {
 	return &Repo{db: db}
 }
 
 func (r *Repo) CreateProduct(ctx context.Context, p *models.Product) error {
-	_, err := r.db.Exec(ctx, `
-	INSERT INTO products(name, price, quantity, description)
-	VALUES ($1, $2, $3, $4)
-	RETURNING id`, p.Name, p.Price, p.Quantity, p.Description)
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+			return insertProduct(ctx, tx, p)
+		})
+	})
 	if err != nil {
 		return errors.New("Failed to insert the product: " + err.Error())
 	}
 	return nil
 }
 
-func (r *Repo) GetProduct(ctx context.Context, id int64) (*models.Product, error) {
-	var p models.Product
-	err := r.db.QueryRow(ctx, `
-	select id, name, price, quantity, description, created_at, updated_at from products where id = $1
-	`, id).Scan(&p.ID, &p.Name, &p.Price, &p.Quantity, &p.Description, &p.CreatedAt, &p.UpdatedAt)
+// insertProduct adds p with the price period its base price opens.
+func insertProduct(ctx context.Context, tx pgx.Tx, p *models.Product) error {
+	if err := tx.QueryRow(ctx, `
+	INSERT INTO products(name, price, currency, quantity, description, category, tax_class, attributes)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8::jsonb)
+	RETURNING id, created_at, updated_at`, p.Name, p.Price.Amount, p.Price.Currency, p.Quantity, p.Description, p.Category, p.TaxClass,
+		encodeAttributes(p.Attributes)).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `
+	INSERT INTO product_price_history(product_id, amount, currency, effective_from) VALUES ($1, $2, $3, $4)`,
+		p.ID, p.Price.Amount, p.Price.Currency, p.CreatedAt)
+	return err
+}
+
+func (r *Repo) GetProduct(ctx context.Context, id int64, includeDeleted bool) (*models.Product, error) {
+	var p *models.Product
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		var err error
+		p, err = scanProduct(q.QueryRow(ctx, `
+	SELECT `+productColumns+`
+	WHERE p.id = $1 and ($2 or p.deleted_at is null)`, id, includeDeleted))
+		return err
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("product not found")
+			logging.For(ctx, logPackage).Debug("product not found", "id", id)
+			return nil, ErrNotFound
 		}
 		return nil, errors.New("failed to get product: " + err.Error())
 	}
-	return &p, nil
+	return p, nil
 }
 
-func (r *Repo) GetAllProducts(ctx context.Context) ([]*models.Product, error) {
-	rows, err := r.db.Query(ctx, `
-	SELECT id, name, price, quantity, description, created_at, updated_at FROM products where deleted_at is null`)
+func (r *Repo) GetAllProducts(ctx context.Context, includeDeleted bool) ([]*models.Product, error) {
+	var products []*models.Product
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		products = nil
+		rows, err := q.Query(ctx, `
+	SELECT `+productColumns+`
+	WHERE $1 or p.deleted_at is null ORDER BY p.id`, includeDeleted)
+		if err != nil {
+			return fmt.Errorf("failed to get products: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scanProduct(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan products: %w", err)
+			}
+			products = append(products, p)
+		}
+		return rows.Err()
+	})
 	if err != nil {
-		return nil, errors.New("failed to get products: " + err.Error())
+		return nil, err
 	}
-	defer rows.Close()
+	return products, nil
+}
 
+// FindProducts matches the attributes with @>, served by the GIN index.
+func (r *Repo) FindProducts(ctx context.Context, f models.ProductFilter) ([]*models.Product, error) {
 	var products []*models.Product
-	for rows.Next() {
-		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Quantity, &p.Description, &p.CreatedAt, &p.UpdatedAt); err != nil {
-			return nil, errors.New("failed to scan products: " + err.Error())
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		products = nil
+		rows, err := q.Query(ctx, `
+	SELECT `+productColumns+`
+	WHERE ($1 or p.deleted_at is null) AND ($2 = '' OR p.category = $2) AND p.attributes @> $3::jsonb
+	ORDER BY p.id`, f.IncludeDeleted, f.Category, encodeAttributes(f.Attributes))
+		if err != nil {
+			return fmt.Errorf("failed to find products: %w", err)
 		}
-		products = append(products, &p)
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scanProduct(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan products: %w", err)
+			}
+			products = append(products, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 	return products, nil
 }
 
+// UpdateProduct also starts a new price period when the price changes, which
+// closes the one in effect.
 func (r *Repo) UpdateProduct(ctx context.Context, p *models.Product) error {
-	upd, err := r.db.Exec(ctx, `
-	UPDATE products SET name = $1, price = $2, quantity = $3, description = $4,updated_at = now() 
-	                WHERE id = $5 AND deleted_at is null`, p.Name, p.Price, p.Quantity, p.Description, p.ID)
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+			var old money.Money
+			if err := tx.QueryRow(ctx, `SELECT price, currency FROM products WHERE id = $1 AND deleted_at is null FOR UPDATE`, p.ID).
+				Scan(&old.Amount, &old.Currency); err != nil {
+				return err
+			}
+			var updatedAt time.Time
+			if err := tx.QueryRow(ctx, `
+	UPDATE products SET name = $1, price = $2, currency = $3, quantity = $4, description = $5, category = $6, tax_class = $7,
+	                attributes = $8::jsonb, updated_at = now()
+	                WHERE id = $9 RETURNING updated_at`, p.Name, p.Price.Amount, p.Price.Currency, p.Quantity, p.Description, p.Category, p.TaxClass,
+				encodeAttributes(p.Attributes), p.ID).
+				Scan(&updatedAt); err != nil {
+				return err
+			}
+			if old == p.Price {
+				return nil
+			}
+			return schedulePrice(ctx, tx, &models.PricePeriod{ProductID: p.ID, Price: p.Price, EffectiveFrom: updatedAt})
+		})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		logging.For(ctx, logPackage).Debug("product not found", "id", p.ID)
+		return ErrNotFound
+	}
 	if err != nil {
 		return errors.New("failed to update product: " + err.Error())
 	}
-	if upd.RowsAffected() == 0 {
-		return errors.New("product not found")
+	return nil
+}
+
+func (r *Repo) AdjustQuantity(ctx context.Context, id int64, delta int) (int, error) {
+	var quantity int
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		err := conn.QueryRow(ctx, `
+	UPDATE products SET quantity = quantity + $1, updated_at = now()
+	WHERE id = $2 AND deleted_at is null AND quantity + $1 >= 0
+	RETURNING quantity`, delta, id).Scan(&quantity)
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		// either the product is missing or its stock is short
+		if err := conn.QueryRow(ctx, `SELECT quantity FROM products WHERE id = $1 AND deleted_at is null`, id).
+			Scan(&quantity); err != nil {
+			return err
+		}
+		return ErrInsufficientStock
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return 0, ErrNotFound
+	}
+	if errors.Is(err, ErrInsufficientStock) {
+		return quantity, ErrInsufficientStock
+	}
+	if err != nil {
+		return 0, errors.New("failed to adjust product quantity: " + err.Error())
+	}
+	return quantity, nil
+}
+
+func (r *Repo) RestockQuantity(ctx context.Context, id int64, quantity int) error {
+	var tag pgconn.CommandTag
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		var err error
+		tag, err = r.db.Conn(ctx).Exec(ctx,
+			`UPDATE products SET quantity = quantity + $1, updated_at = now() WHERE id = $2`, quantity, id)
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to restock product: " + err.Error())
+	}
+	if tag.RowsAffected() == 0 {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return ErrNotFound
 	}
 	return nil
 }
 
 func (r *Repo) DeleteProduct(ctx context.Context, id int64) error {
-	dlt, err := r.db.Exec(ctx, `
-	UPDATE products SET deleted_at = now() WHERE id = $1
-	`, id)
-	if err != nil {
+	err := r.transition(ctx, id, true, `UPDATE products SET deleted_at = now() WHERE id = $1`)
+	if err != nil && !isLifecycleError(err) {
 		return errors.New("failed to delete product: " + err.Error())
 	}
-	if dlt.RowsAffected() == 0 {
-		return errors.New("product not found")
+	return err
+}
+
+func (r *Repo) RestoreProduct(ctx context.Context, id int64) error {
+	err := r.transition(ctx, id, false, `UPDATE products SET deleted_at = null WHERE id = $1`)
+	if err != nil && !isLifecycleError(err) {
+		return errors.New("failed to restore product: " + err.Error())
+	}
+	return err
+}
+
+// transition locks the row and checks that the product is in the state the
+// update leaves, active for delete and deleted for restore, before running it.
+func (r *Repo) transition(ctx context.Context, id int64, toDeleted bool, update string) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+			var deleted bool
+			if err := tx.QueryRow(ctx, `SELECT deleted_at IS NOT NULL FROM products WHERE id = $1 FOR UPDATE`, id).
+				Scan(&deleted); err != nil {
+				return err
+			}
+			if err := checkTransition(deleted, toDeleted); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, update, id)
+			return err
+		})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *Repo) GetProductStats(ctx context.Context, lowStock int) (*models.ProductStats, error) {
+	var st models.ProductStats
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		return q.QueryRow(ctx, `
+	SELECT count(*), COALESCE(sum(quantity), 0), count(*) FILTER (WHERE quantity <= $1)
+	FROM products WHERE deleted_at is null`, lowStock).Scan(&st.Active, &st.StockUnits, &st.LowStock)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product stats: %w", err)
+	}
+	return &st, nil
+}
+
+func (r *Repo) GetDeletedProducts(ctx context.Context, before time.Time) ([]*models.Product, error) {
+	var products []*models.Product
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		products = nil
+		rows, err := q.Query(ctx, `
+	SELECT `+productColumns+`
+	WHERE p.deleted_at < $1 ORDER BY p.id`, before)
+		if err != nil {
+			return fmt.Errorf("failed to get deleted products: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scanProduct(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan products: %w", err)
+			}
+			products = append(products, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *Repo) HardDeleteProduct(ctx context.Context, id int64) (*models.PurgedProduct, error) {
+	return r.remove(ctx, id, models.PurgeReasonHardDelete, `DELETE FROM products WHERE id = $1
+	RETURNING name, deleted_at`, id)
+}
+
+func (r *Repo) PurgeProduct(ctx context.Context, id int64, before time.Time) (*models.PurgedProduct, error) {
+	return r.remove(ctx, id, models.PurgeReasonRetention, `DELETE FROM products WHERE id = $1 AND deleted_at < $2
+	RETURNING name, deleted_at`, id, before)
+}
+
+// remove deletes a product with the given statement and records it in
+// product_purges in the same transaction.
+func (r *Repo) remove(ctx context.Context, id int64, reason, query string, args ...any) (*models.PurgedProduct, error) {
+	purged := models.PurgedProduct{ProductID: id, Reason: reason}
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+			if err := tx.QueryRow(ctx, query, args...).Scan(&purged.Name, &purged.DeletedAt); err != nil {
+				return err
+			}
+			return tx.QueryRow(ctx, `
+	INSERT INTO product_purges(product_id, name, deleted_at, reason) VALUES ($1, $2, $3, $4)
+	RETURNING purged_at`, id, purged.Name, purged.DeletedAt, reason).Scan(&purged.PurgedAt)
+		})
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			logging.For(ctx, logPackage).Debug("product not found", "id", id)
+			return nil, ErrNotFound
+		case errors.As(err, &pgErr) && pgErr.Code == "23503":
+			return nil, ErrReferenced
+		}
+		return nil, errors.New("failed to remove product: " + err.Error())
+	}
+	return &purged, nil
+}
+
+func (r *Repo) GetProductPrices(ctx context.Context, id int64) ([]money.Money, error) {
+	var prices []money.Money
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		prices = nil
+		rows, err := q.Query(ctx, `
+	SELECT amount, currency FROM product_prices WHERE product_id = $1 ORDER BY currency`, id)
+		if err != nil {
+			return fmt.Errorf("failed to get product prices: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var m money.Money
+			if err := rows.Scan(&m.Amount, &m.Currency); err != nil {
+				return fmt.Errorf("failed to scan product prices: %w", err)
+			}
+			prices = append(prices, m)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+func (r *Repo) SetProductPrice(ctx context.Context, id int64, price money.Money) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tag, err := r.db.Exec(ctx, `
+	INSERT INTO product_prices(product_id, currency, amount)
+	SELECT id, $2, $3 FROM products WHERE id = $1 AND deleted_at is null
+	ON CONFLICT (product_id, currency) DO UPDATE SET amount = EXCLUDED.amount, updated_at = now()`,
+			id, price.Currency, price.Amount)
+		rows = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to set product price: " + err.Error())
+	}
+	if rows == 0 {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return ErrNotFound
 	}
 	return nil
 }
 
-func (r *Repo) RestoreProduct(ctx context.Context, id int64) error {
-	restore, err := r.db.Exec(ctx, `
-	UPDATE products SET deleted_at = null WHERE id = $1
-	`, id)
+func (r *Repo) DeleteProductPrice(ctx context.Context, id int64, currency string) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tag, err := r.db.Exec(ctx, `DELETE FROM product_prices WHERE product_id = $1 AND currency = $2`, id, currency)
+		rows = tag.RowsAffected()
+		return err
+	})
 	if err != nil {
-		return errors.New("failed to restore product: " + err.Error())
+		return errors.New("failed to delete product price: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repo) GetPriceHistory(ctx context.Context, id int64) ([]*models.PricePeriod, error) {
+	var periods []*models.PricePeriod
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		var err error
+		periods, err = queryPeriods(ctx, q, `
+	SELECT id, product_id, amount, currency, effective_from, effective_to FROM product_price_history
+	WHERE product_id = $1 ORDER BY effective_from`, id)
+		return err
+	})
+	if err != nil {
+		return nil, errors.New("failed to get price history: " + err.Error())
+	}
+	return periods, nil
+}
+
+func (r *Repo) SchedulePrice(ctx context.Context, p *models.PricePeriod) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+			var id int64
+			if err := tx.QueryRow(ctx, `SELECT id FROM products WHERE id = $1 AND deleted_at is null FOR UPDATE`, p.ProductID).
+				Scan(&id); err != nil {
+				return err
+			}
+			return schedulePrice(ctx, tx, p)
+		})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		logging.For(ctx, logPackage).Debug("product not found", "id", p.ProductID)
+		return ErrNotFound
+	}
+	if err != nil {
+		return errors.New("failed to schedule price: " + err.Error())
+	}
+	return nil
+}
+
+// schedulePrice applies the plan of p to the periods of its product, whose
+// row the caller holds locked.
+func schedulePrice(ctx context.Context, tx pgx.Tx, p *models.PricePeriod) error {
+	periods, err := queryPeriods(ctx, tx, `
+	SELECT id, product_id, amount, currency, effective_from, effective_to FROM product_price_history
+	WHERE product_id = $1 AND (effective_to IS NULL OR effective_to > $2) ORDER BY effective_from`, p.ProductID, p.EffectiveFrom)
+	if err != nil {
+		return err
+	}
+	plan := planPrice(periods, p)
+	if len(plan.deletes) > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM product_price_history WHERE id = ANY($1)`, plan.deletes); err != nil {
+			return err
+		}
+	}
+	for _, u := range plan.updates {
+		if _, err := tx.Exec(ctx, `UPDATE product_price_history SET effective_from = $2, effective_to = $3 WHERE id = $1`,
+			u.ID, u.EffectiveFrom, u.EffectiveTo); err != nil {
+			return err
+		}
+	}
+	for _, in := range plan.inserts {
+		if err := tx.QueryRow(ctx, `
+	INSERT INTO product_price_history(product_id, amount, currency, effective_from, effective_to)
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+			in.ProductID, in.Price.Amount, in.Price.Currency, in.EffectiveFrom, in.EffectiveTo).Scan(&in.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repo) ApplyScheduledPrices(ctx context.Context, now time.Time) ([]*models.PricePeriod, error) {
+	var periods []*models.PricePeriod
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		var err error
+		periods, err = queryPeriods(ctx, r.db, `
+	UPDATE products p SET price = h.amount, currency = h.currency, updated_at = now()
+	FROM product_price_history h
+	WHERE h.product_id = p.id AND p.deleted_at is null
+	  AND h.effective_from <= $1 AND (h.effective_to IS NULL OR h.effective_to > $1)
+	  AND (p.price <> h.amount OR p.currency <> h.currency)
+	RETURNING h.id, h.product_id, h.amount, h.currency, h.effective_from, h.effective_to`, now.UTC())
+		return err
+	})
+	if err != nil {
+		return nil, errors.New("failed to apply scheduled prices: " + err.Error())
+	}
+	return periods, nil
+}
+
+func (r *Repo) NextPriceChange(ctx context.Context, after time.Time) (*time.Time, error) {
+	var next time.Time
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		return r.db.QueryRow(ctx, `
+	SELECT h.effective_from FROM product_price_history h JOIN products p ON p.id = h.product_id
+	WHERE p.deleted_at is null AND h.effective_from > $1 ORDER BY h.effective_from LIMIT 1`, after.UTC()).Scan(&next)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get next price change: " + err.Error())
+	}
+	return &next, nil
+}
+
+func (r *Repo) GetVariantAxes(ctx context.Context, id int64) ([]models.VariantAxis, error) {
+	var axes []models.VariantAxis
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		var raw string
+		if err := q.QueryRow(ctx, `SELECT axes::text FROM product_variant_axes WHERE product_id = $1`, id).Scan(&raw); err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(raw), &axes)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get variant axes: " + err.Error())
+	}
+	return axes, nil
+}
+
+func (r *Repo) GetVariants(ctx context.Context, parentID int64, includeDeleted bool) ([]*models.Product, error) {
+	var variants []*models.Product
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		variants = nil
+		rows, err := q.Query(ctx, `
+	SELECT `+productColumns+`
+	WHERE v.parent_id = $1 AND ($2 or p.deleted_at is null) ORDER BY p.id`, parentID, includeDeleted)
+		if err != nil {
+			return fmt.Errorf("failed to get variants: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scanProduct(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan variants: %w", err)
+			}
+			variants = append(variants, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+func (r *Repo) CreateVariants(ctx context.Context, parentID int64, axes []models.VariantAxis, variants []*models.Product) error {
+	rawAxes, err := json.Marshal(axes)
+	if err != nil {
+		return errors.New("failed to create variants: " + err.Error())
 	}
-	if restore.RowsAffected() == 0 {
-		return errors.New("product not found")
+	err = r.db.Write(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+			var id int64
+			if err := tx.QueryRow(ctx, `SELECT id FROM products WHERE id = $1 AND deleted_at is null FOR UPDATE`, parentID).
+				Scan(&id); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, `
+	INSERT INTO product_variant_axes(product_id, axes) VALUES ($1, $2::jsonb)
+	ON CONFLICT (product_id) DO UPDATE SET axes = EXCLUDED.axes`, parentID, string(rawAxes)); err != nil {
+				return err
+			}
+			for _, v := range variants {
+				if err := insertProduct(ctx, tx, v); err != nil {
+					return err
+				}
+				if _, err := tx.Exec(ctx, `
+	INSERT INTO product_variants(product_id, parent_id, sku, options) VALUES ($1, $2, $3, $4::jsonb)`,
+					v.ID, parentID, v.SKU, models.VariantKey(v.Options)); err != nil {
+					return err
+				}
+				parent := id
+				v.ParentID = &parent
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			logging.For(ctx, logPackage).Debug("product not found", "id", parentID)
+			return ErrNotFound
+		case errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "product_variants_sku_key":
+			return ErrDuplicateSKU
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return ErrDuplicateVariant
+		}
+		return errors.New("failed to create variants: " + err.Error())
 	}
 	return nil
 }
+
+// rowScanner is a result row of either SQL backend.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanProduct reads a row of productColumns, or of sqliteProductColumns.
+func scanProduct(row rowScanner) (*models.Product, error) {
+	var (
+		p          models.Product
+		attributes string
+		options    *string
+	)
+	if err := row.Scan(&p.ID, &p.Name, &p.Price.Amount, &p.Price.Currency, &p.Quantity, &p.Description, &p.Category,
+		&p.TaxClass, &attributes, &p.CreatedAt, &p.UpdatedAt, &p.DeletedAt, &p.ParentID, &p.SKU, &options); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(attributes), &p.Attributes); err != nil {
+		return nil, err
+	}
+	if len(p.Attributes) == 0 {
+		p.Attributes = nil
+	}
+	if options != nil {
+		if err := json.Unmarshal([]byte(*options), &p.Options); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+// encodeAttributes returns the JSON object stored in products.attributes.
+func encodeAttributes(attributes map[string]any) string {
+	if len(attributes) == 0 {
+		return "{}"
+	}
+	b, _ := json.Marshal(attributes) //nolint:errchkjson //normalised attributes are plain JSON values
+	return string(b)
+}
+
+func queryPeriods(ctx context.Context, q db.Querier, query string, args ...any) ([]*models.PricePeriod, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var periods []*models.PricePeriod
+	for rows.Next() {
+		var p models.PricePeriod
+		if err := rows.Scan(&p.ID, &p.ProductID, &p.Price.Amount, &p.Price.Currency, &p.EffectiveFrom, &p.EffectiveTo); err != nil {
+			return nil, err
+		}
+		periods = append(periods, &p)
+	}
+	return periods, rows.Err()
+}
+
+// pricePlan is the change to the periods of a product that puts a new one in
+// effect.
+type pricePlan struct {
+	deletes []int64
+	// updates are existing periods with moved bounds
+	updates []*models.PricePeriod
+	inserts []*models.PricePeriod
+}
+
+// planPrice puts p in effect over [p.EffectiveFrom, p.EffectiveTo) on top of
+// periods, sorted by start. The period covering the start is cut short, the
+// ones inside are dropped and the one covering the end resumes after it.
+// Without an end, p lasts until the next period starts.
+func planPrice(periods []*models.PricePeriod, p *models.PricePeriod) pricePlan {
+	from, to := p.EffectiveFrom, p.EffectiveTo
+	if to == nil {
+		for _, q := range periods {
+			if q.EffectiveFrom.After(from) {
+				next := q.EffectiveFrom
+				to = &next
+				break
+			}
+		}
+	}
+	covers := func(q *models.PricePeriod, t time.Time) bool {
+		return q.EffectiveTo == nil || q.EffectiveTo.After(t)
+	}
+
+	var plan pricePlan
+	for _, q := range periods {
+		switch {
+		case !covers(q, from), to != nil && !q.EffectiveFrom.Before(*to):
+			// entirely before or after p
+		case q.EffectiveFrom.Before(from):
+			if to != nil && covers(q, *to) {
+				plan.inserts = append(plan.inserts, &models.PricePeriod{
+					ProductID: q.ProductID, Price: q.Price, EffectiveFrom: *to, EffectiveTo: q.EffectiveTo,
+				})
+			}
+			cut := *q
+			end := from
+			cut.EffectiveTo = &end
+			plan.updates = append(plan.updates, &cut)
+		case to != nil && covers(q, *to):
+			moved := *q
+			moved.EffectiveFrom = *to
+			plan.updates = append(plan.updates, &moved)
+		default:
+			plan.deletes = append(plan.deletes, q.ID)
+		}
+	}
+	p.EffectiveTo = to
+	plan.inserts = append(plan.inserts, p)
+	return plan
+}
+
+// checkTransition reports whether a product in the deleted state may move to
+// toDeleted.
+func checkTransition(deleted, toDeleted bool) error {
+	switch {
+	case deleted && toDeleted:
+		return ErrAlreadyDeleted
+	case !deleted && !toDeleted:
+		return ErrNotDeleted
+	}
+	return nil
+}
+
+func isLifecycleError(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrAlreadyDeleted) || errors.Is(err, ErrNotDeleted)
+}
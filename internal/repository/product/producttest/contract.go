@@ -0,0 +1,684 @@
+// Package producttest holds the behaviour every product.Repository
+// implementation must share, so the backends can be checked against each other.
+package producttest
+
+import (
+	"context"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/money"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns an empty repository for a single test.
+type Factory func(t *testing.T) product.Repository
+
+// NewProduct returns a valid product; fields can be overridden by the caller.
+func NewProduct(name string) *models.Product {
+	return &models.Product{
+		Name:        name,
+		Price:       money.New(1000, "USD"),
+		Quantity:    10,
+		Description: name + " description",
+		TaxClass:    models.TaxClassStandard,
+	}
+}
+
+// laptop returns a product of the laptops category with a panel, a screen
+// size and a touch flag.
+func laptop(name, panel string, screen float64, unit string, touch bool) *models.Product {
+	p := NewProduct(name)
+	p.Category = "laptops"
+	p.Attributes = map[string]any{
+		"panel":  panel,
+		"screen": map[string]any{"value": screen, "unit": unit},
+		"touch":  touch,
+	}
+	return p
+}
+
+// NewVariant returns a valid variant on a single color axis.
+func NewVariant(name, sku, color string) *models.Product {
+	p := NewProduct(name)
+	p.SKU = sku
+	p.Options = map[string]string{"color": color}
+	return p
+}
+
+// Create stores the given products and fails the test on error.
+func Create(t *testing.T, repo product.Repository, products ...*models.Product) {
+	t.Helper()
+	for _, p := range products {
+		require.NoError(t, repo.CreateProduct(context.Background(), p))
+	}
+}
+
+// Seed stores n products named item-1..item-n with growing quantities and
+// returns them in creation order.
+func Seed(t *testing.T, repo product.Repository, n int) []*models.Product {
+	t.Helper()
+	products := make([]*models.Product, n)
+	for i := range products {
+		p := NewProduct(fmt.Sprintf("item-%d", i+1))
+		p.Quantity = i + 1
+		products[i] = p
+	}
+	Create(t, repo, products...)
+	return products
+}
+
+// Run executes the repository contract against fresh repositories made by newRepo.
+func Run(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+		p := NewProduct("apple")
+		p.Category, p.TaxClass = "fruit", models.TaxClassReduced
+		Create(t, repo, p)
+		assert.NotZero(t, p.ID)
+		assert.False(t, p.CreatedAt.IsZero())
+
+		got, err := repo.GetProduct(ctx, p.ID, false)
+		require.NoError(t, err)
+		assert.Equal(t, p.ID, got.ID)
+		assert.Equal(t, "apple", got.Name)
+		assert.Equal(t, money.New(1000, "USD"), got.Price)
+		assert.Equal(t, 10, got.Quantity)
+		assert.Equal(t, "apple description", got.Description)
+		assert.Equal(t, "fruit", got.Category)
+		assert.Equal(t, models.TaxClassReduced, got.TaxClass)
+		assert.True(t, got.CreatedAt.Equal(p.CreatedAt))
+		assert.Nil(t, got.DeletedAt)
+	})
+
+	t.Run("get missing", func(t *testing.T) {
+		repo := newRepo(t)
+		_, err := repo.GetProduct(ctx, 424242, false)
+		assert.ErrorIs(t, err, product.ErrNotFound)
+	})
+
+	t.Run("list skips deleted and keeps id order", func(t *testing.T) {
+		repo := newRepo(t)
+		a, b, c := NewProduct("a"), NewProduct("b"), NewProduct("c")
+		Create(t, repo, a, b, c)
+		require.NoError(t, repo.DeleteProduct(ctx, b.ID))
+
+		list, err := repo.GetAllProducts(ctx, false)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, a.ID, list[0].ID)
+		assert.Equal(t, c.ID, list[1].ID)
+	})
+
+	t.Run("list empty", func(t *testing.T) {
+		repo := newRepo(t)
+		list, err := repo.GetAllProducts(ctx, false)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		repo := newRepo(t)
+		p := NewProduct("pear")
+		Create(t, repo, p)
+
+		p.Name, p.Price, p.Quantity, p.Description, p.Category = "green pear", money.New(1500000, "UZS"), 3, "ripe", "fruit"
+		p.TaxClass = models.TaxClassExempt
+		require.NoError(t, repo.UpdateProduct(ctx, p))
+
+		got, err := repo.GetProduct(ctx, p.ID, false)
+		require.NoError(t, err)
+		assert.Equal(t, "green pear", got.Name)
+		assert.Equal(t, money.New(1500000, "UZS"), got.Price)
+		assert.Equal(t, 3, got.Quantity)
+		assert.Equal(t, "ripe", got.Description)
+		assert.Equal(t, "fruit", got.Category)
+		assert.Equal(t, models.TaxClassExempt, got.TaxClass)
+		assert.False(t, got.UpdatedAt.Before(got.CreatedAt))
+	})
+
+	t.Run("update missing or deleted", func(t *testing.T) {
+		repo := newRepo(t)
+		p := NewProduct("plum")
+		Create(t, repo, p)
+		require.NoError(t, repo.DeleteProduct(ctx, p.ID))
+
+		assert.ErrorIs(t, repo.UpdateProduct(ctx, p), product.ErrNotFound)
+		p.ID = 424242
+		assert.ErrorIs(t, repo.UpdateProduct(ctx, p), product.ErrNotFound)
+	})
+
+	t.Run("adjust quantity", func(t *testing.T) {
+		repo := newRepo(t)
+		p := NewProduct("quince")
+		Create(t, repo, p)
+
+		quantity, err := repo.AdjustQuantity(ctx, p.ID, 5)
+		require.NoError(t, err)
+		assert.Equal(t, 15, quantity)
+		quantity, err = repo.AdjustQuantity(ctx, p.ID, -3)
+		require.NoError(t, err)
+		assert.Equal(t, 12, quantity)
+		quantity, err = repo.AdjustQuantity(ctx, p.ID, -13)
+		assert.ErrorIs(t, err, product.ErrInsufficientStock)
+		assert.Equal(t, 12, quantity, "the stock is left as is")
+		quantity, err = repo.AdjustQuantity(ctx, p.ID, -12)
+		require.NoError(t, err, "the last units can go")
+		assert.Zero(t, quantity)
+		got, err := repo.GetProduct(ctx, p.ID, false)
+		require.NoError(t, err)
+		assert.Zero(t, got.Quantity)
+
+		require.NoError(t, repo.DeleteProduct(ctx, p.ID))
+		_, err = repo.AdjustQuantity(ctx, p.ID, 1)
+		assert.ErrorIs(t, err, product.ErrNotFound)
+		_, err = repo.AdjustQuantity(ctx, 424242, 1)
+		assert.ErrorIs(t, err, product.ErrNotFound)
+
+		require.NoError(t, repo.RestockQuantity(ctx, p.ID, 4), "a deleted product takes its units back")
+		require.NoError(t, repo.RestoreProduct(ctx, p.ID))
+		got, err = repo.GetProduct(ctx, p.ID, false)
+		require.NoError(t, err)
+		assert.Equal(t, 4, got.Quantity)
+		assert.ErrorIs(t, repo.RestockQuantity(ctx, 424242, 1), product.ErrNotFound)
+	})
+
+	t.Run("delete and restore", func(t *testing.T) {
+		repo := newRepo(t)
+		p := NewProduct("kiwi")
+		Create(t, repo, p)
+
+		require.NoError(t, repo.DeleteProduct(ctx, p.ID))
+		list, err := repo.GetAllProducts(ctx, false)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+		_, err = repo.GetProduct(ctx, p.ID, false)
+		assert.ErrorIs(t, err, product.ErrNotFound)
+
+		got, err := repo.GetProduct(ctx, p.ID, true)
+		require.NoError(t, err)
+		require.NotNil(t, got.DeletedAt)
+		assert.False(t, got.DeletedAt.Before(got.CreatedAt))
+		list, err = repo.GetAllProducts(ctx, true)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.True(t, list[0].IsDeleted())
+
+		require.NoError(t, repo.RestoreProduct(ctx, p.ID))
+		list, err = repo.GetAllProducts(ctx, false)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, p.ID, list[0].ID)
+		assert.Nil(t, list[0].DeletedAt)
+	})
+
+	t.Run("delete and restore check state", func(t *testing.T) {
+		repo := newRepo(t)
+		p := NewProduct("lime")
+		Create(t, repo, p)
+
+		assert.ErrorIs(t, repo.RestoreProduct(ctx, p.ID), product.ErrNotDeleted)
+		require.NoError(t, repo.DeleteProduct(ctx, p.ID))
+		got, err := repo.GetProduct(ctx, p.ID, true)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, repo.DeleteProduct(ctx, p.ID), product.ErrAlreadyDeleted)
+		again, err := repo.GetProduct(ctx, p.ID, true)
+		require.NoError(t, err)
+		assert.True(t, again.DeletedAt.Equal(*got.DeletedAt), "a repeated delete keeps the original time")
+	})
+
+	t.Run("delete and restore missing", func(t *testing.T) {
+		repo := newRepo(t)
+		assert.ErrorIs(t, repo.DeleteProduct(ctx, 424242), product.ErrNotFound)
+		assert.ErrorIs(t, repo.RestoreProduct(ctx, 424242), product.ErrNotFound)
+	})
+
+	t.Run("trash lists deleted before cutoff", func(t *testing.T) {
+		repo := newRepo(t)
+		kept, gone := NewProduct("kept"), NewProduct("gone")
+		Create(t, repo, kept, gone)
+		require.NoError(t, repo.DeleteProduct(ctx, gone.ID))
+
+		trash, err := repo.GetDeletedProducts(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, trash, 1)
+		assert.Equal(t, gone.ID, trash[0].ID)
+		assert.True(t, trash[0].IsDeleted())
+
+		trash, err = repo.GetDeletedProducts(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, trash)
+	})
+
+	t.Run("hard delete", func(t *testing.T) {
+		repo := newRepo(t)
+		p := NewProduct("fig")
+		Create(t, repo, p)
+
+		purged, err := repo.HardDeleteProduct(ctx, p.ID)
+		require.NoError(t, err)
+		assert.Equal(t, p.ID, purged.ProductID)
+		assert.Equal(t, "fig", purged.Name)
+		assert.Nil(t, purged.DeletedAt)
+		assert.Equal(t, models.PurgeReasonHardDelete, purged.Reason)
+
+		_, err = repo.GetProduct(ctx, p.ID, false)
+		assert.ErrorIs(t, err, product.ErrNotFound)
+		assert.ErrorIs(t, repo.RestoreProduct(ctx, p.ID), product.ErrNotFound)
+		_, err = repo.HardDeleteProduct(ctx, p.ID)
+		assert.ErrorIs(t, err, product.ErrNotFound)
+	})
+
+	t.Run("purge respects cutoff", func(t *testing.T) {
+		repo := newRepo(t)
+		active, gone := NewProduct("active"), NewProduct("gone")
+		Create(t, repo, active, gone)
+		require.NoError(t, repo.DeleteProduct(ctx, gone.ID))
+
+		_, err := repo.PurgeProduct(ctx, gone.ID, time.Now().Add(-time.Hour))
+		assert.ErrorIs(t, err, product.ErrNotFound, "deleted after the cutoff")
+		_, err = repo.PurgeProduct(ctx, active.ID, time.Now().Add(time.Minute))
+		assert.ErrorIs(t, err, product.ErrNotFound, "not deleted")
+
+		purged, err := repo.PurgeProduct(ctx, gone.ID, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, models.PurgeReasonRetention, purged.Reason)
+		require.NotNil(t, purged.DeletedAt)
+		assert.ErrorIs(t, repo.RestoreProduct(ctx, gone.ID), product.ErrNotFound)
+
+		list, err := repo.GetAllProducts(ctx, false)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, active.ID, list[0].ID)
+	})
+
+	t.Run("large prices", func(t *testing.T) {
+		repo := newRepo(t)
+		p := NewProduct("yacht")
+		p.Price = money.New(5_000_000_000_00, "UZS")
+		Create(t, repo, p)
+
+		got, err := repo.GetProduct(ctx, p.ID, false)
+		require.NoError(t, err)
+		assert.Equal(t, p.Price, got.Price)
+	})
+
+	t.Run("list prices", func(t *testing.T) {
+		repo := newRepo(t)
+		p := NewProduct("tea")
+		Create(t, repo, p)
+
+		require.NoError(t, repo.SetProductPrice(ctx, p.ID, money.New(1500000, "UZS")))
+		require.NoError(t, repo.SetProductPrice(ctx, p.ID, money.New(900, "EUR")))
+		require.NoError(t, repo.SetProductPrice(ctx, p.ID, money.New(950, "EUR")))
+		prices, err := repo.GetProductPrices(ctx, p.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []money.Money{money.New(950, "EUR"), money.New(1500000, "UZS")}, prices)
+
+		require.NoError(t, repo.DeleteProductPrice(ctx, p.ID, "EUR"))
+		assert.ErrorIs(t, repo.DeleteProductPrice(ctx, p.ID, "EUR"), product.ErrNotFound)
+		prices, err = repo.GetProductPrices(ctx, p.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []money.Money{money.New(1500000, "UZS")}, prices)
+
+		assert.ErrorIs(t, repo.SetProductPrice(ctx, 424242, money.New(1, "EUR")), product.ErrNotFound)
+		require.NoError(t, repo.DeleteProduct(ctx, p.ID))
+		assert.ErrorIs(t, repo.SetProductPrice(ctx, p.ID, money.New(1, "EUR")), product.ErrNotFound)
+
+		_, err = repo.HardDeleteProduct(ctx, p.ID)
+		require.NoError(t, err)
+		prices, err = repo.GetProductPrices(ctx, p.ID)
+		require.NoError(t, err)
+		assert.Empty(t, prices, "prices go with the product")
+	})
+
+	t.Run("price history", func(t *testing.T) {
+		repo := newRepo(t)
+		p := NewProduct("tea")
+		Create(t, repo, p)
+		day := time.Now().UTC().Truncate(time.Second).Add(24 * time.Hour)
+		schedule := func(amount int64, from time.Duration, to *time.Duration) {
+			t.Helper()
+			period := &models.PricePeriod{ProductID: p.ID, Price: money.New(amount, "USD"), EffectiveFrom: day.Add(from)}
+			if to != nil {
+				end := day.Add(*to)
+				period.EffectiveTo = &end
+			}
+			require.NoError(t, repo.SchedulePrice(ctx, period))
+			assert.NotZero(t, period.ID)
+		}
+		hours := func(h int) *time.Duration {
+			d := time.Duration(h) * time.Hour
+			return &d
+		}
+
+		schedule(1200, 0, nil)
+		schedule(900, 48*time.Hour, hours(72))
+		assert.Equal(t, []string{"10.00 USD [-, 0s)", "12.00 USD [0s, 48h0m0s)", "9.00 USD [48h0m0s, 72h0m0s)", "12.00 USD [72h0m0s, -)"},
+			periods(t, repo, p.ID, day))
+
+		// an open price lasts until the next scheduled one
+		schedule(1100, 12*time.Hour, nil)
+		assert.Equal(t, []string{"10.00 USD [-, 0s)", "12.00 USD [0s, 12h0m0s)", "11.00 USD [12h0m0s, 48h0m0s)", "9.00 USD [48h0m0s, 72h0m0s)", "12.00 USD [72h0m0s, -)"},
+			periods(t, repo, p.ID, day))
+
+		// a range replaces what it covers and the price before its end resumes
+		schedule(700, 6*time.Hour, hours(73))
+		assert.Equal(t, []string{"10.00 USD [-, 0s)", "12.00 USD [0s, 6h0m0s)", "7.00 USD [6h0m0s, 73h0m0s)", "12.00 USD [73h0m0s, -)"},
+			periods(t, repo, p.ID, day))
+
+		next, err := repo.NextPriceChange(ctx, day.Add(-time.Hour))
+		require.NoError(t, err)
+		require.NotNil(t, next)
+		assert.True(t, day.Equal(*next))
+		next, err = repo.NextPriceChange(ctx, day.Add(6*time.Hour))
+		require.NoError(t, err)
+		require.NotNil(t, next)
+		assert.True(t, day.Add(73*time.Hour).Equal(*next))
+		next, err = repo.NextPriceChange(ctx, day.Add(73*time.Hour))
+		require.NoError(t, err)
+		assert.Nil(t, next)
+
+		applied, err := repo.ApplyScheduledPrices(ctx, day.Add(7*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, money.New(700, "USD"), applied[0].Price)
+		got, err := repo.GetProduct(ctx, p.ID, false)
+		require.NoError(t, err)
+		assert.Equal(t, money.New(700, "USD"), got.Price)
+		applied, err = repo.ApplyScheduledPrices(ctx, day.Add(8*time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, applied, "already applied")
+
+		assert.ErrorIs(t, repo.SchedulePrice(ctx, &models.PricePeriod{ProductID: 424242, Price: money.New(1, "USD"), EffectiveFrom: day}),
+			product.ErrNotFound)
+		require.NoError(t, repo.DeleteProduct(ctx, p.ID))
+		assert.ErrorIs(t, repo.SchedulePrice(ctx, &models.PricePeriod{ProductID: p.ID, Price: money.New(1, "USD"), EffectiveFrom: day}),
+			product.ErrNotFound)
+		applied, err = repo.ApplyScheduledPrices(ctx, day.Add(80*time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, applied, "deleted products keep their price")
+		next, err = repo.NextPriceChange(ctx, day)
+		require.NoError(t, err)
+		assert.Nil(t, next, "deleted products have no pending changes")
+
+		_, err = repo.HardDeleteProduct(ctx, p.ID)
+		require.NoError(t, err)
+		history, err := repo.GetPriceHistory(ctx, p.ID)
+		require.NoError(t, err)
+		assert.Empty(t, history, "history goes with the product")
+	})
+
+	t.Run("update closes price period", func(t *testing.T) {
+		repo := newRepo(t)
+		p := NewProduct("tea")
+		Create(t, repo, p)
+
+		p.Quantity = 3
+		require.NoError(t, repo.UpdateProduct(ctx, p))
+		history, err := repo.GetPriceHistory(ctx, p.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 1, "same price, same period")
+		assert.Equal(t, p.Price, history[0].Price)
+		assert.Nil(t, history[0].EffectiveTo)
+
+		p.Price = money.New(1500, "EUR")
+		require.NoError(t, repo.UpdateProduct(ctx, p))
+		got, err := repo.GetProduct(ctx, p.ID, false)
+		require.NoError(t, err)
+		history, err = repo.GetPriceHistory(ctx, p.ID)
+		require.NoError(t, err)
+		last := history[len(history)-1]
+		assert.Equal(t, money.New(1500, "EUR"), last.Price)
+		assert.True(t, got.UpdatedAt.Equal(last.EffectiveFrom), "the new period starts with the update")
+		assert.Nil(t, last.EffectiveTo)
+		for i := 1; i < len(history); i++ {
+			require.NotNil(t, history[i-1].EffectiveTo)
+			assert.True(t, history[i-1].EffectiveTo.Equal(history[i].EffectiveFrom), "periods follow each other")
+		}
+		applied, err := repo.ApplyScheduledPrices(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Empty(t, applied, "the update is the price in effect")
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		repo := newRepo(t)
+		low, high, gone := NewProduct("low"), NewProduct("high"), NewProduct("gone")
+		low.Quantity, high.Quantity, gone.Quantity = 2, 50, 1
+		Create(t, repo, low, high, gone)
+		require.NoError(t, repo.DeleteProduct(ctx, gone.ID))
+
+		st, err := repo.GetProductStats(ctx, 5)
+		require.NoError(t, err)
+		assert.Equal(t, models.ProductStats{Active: 2, StockUnits: 52, LowStock: 1}, *st)
+	})
+
+	t.Run("variants", func(t *testing.T) {
+		repo := newRepo(t)
+		parent := NewProduct("laptop")
+		Create(t, repo, parent)
+		axes := []models.VariantAxis{{Name: "color", Values: []string{"silver", "black"}}}
+		silver, black := NewVariant("laptop silver", "L-SILVER", "silver"), NewVariant("laptop black", "L-BLACK", "black")
+		black.Price = money.New(1100, "USD")
+		require.NoError(t, repo.CreateVariants(ctx, parent.ID, axes, []*models.Product{silver, black}))
+		require.NotNil(t, silver.ParentID)
+		assert.Equal(t, parent.ID, *silver.ParentID)
+		assert.NotZero(t, black.ID)
+
+		got, err := repo.GetVariantAxes(ctx, parent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, axes, got)
+		none, err := repo.GetVariantAxes(ctx, silver.ID)
+		require.NoError(t, err)
+		assert.Nil(t, none)
+
+		v, err := repo.GetProduct(ctx, black.ID, false)
+		require.NoError(t, err)
+		require.NotNil(t, v.ParentID)
+		assert.Equal(t, parent.ID, *v.ParentID)
+		assert.Equal(t, "L-BLACK", v.SKU)
+		assert.Equal(t, map[string]string{"color": "black"}, v.Options)
+		assert.Equal(t, money.New(1100, "USD"), v.Price)
+		assert.Len(t, periods(t, repo, black.ID, black.CreatedAt), 1, "a variant opens its price period")
+		p, err := repo.GetProduct(ctx, parent.ID, false)
+		require.NoError(t, err)
+		assert.False(t, p.IsVariant())
+
+		require.NoError(t, repo.DeleteProduct(ctx, silver.ID))
+		variants, err := repo.GetVariants(ctx, parent.ID, false)
+		require.NoError(t, err)
+		require.Len(t, variants, 1, "deleted variants are not listed")
+		assert.Equal(t, black.ID, variants[0].ID)
+		assert.Equal(t, "L-BLACK", variants[0].SKU)
+		variants, err = repo.GetVariants(ctx, parent.ID, true)
+		require.NoError(t, err)
+		require.Len(t, variants, 2)
+		assert.Equal(t, silver.ID, variants[0].ID)
+
+		_, err = repo.HardDeleteProduct(ctx, parent.ID)
+		assert.ErrorIs(t, err, product.ErrReferenced, "a parent keeps its variants")
+		_, err = repo.HardDeleteProduct(ctx, silver.ID)
+		assert.NoError(t, err, "a variant can be removed")
+	})
+
+	t.Run("variants are created all or none", func(t *testing.T) {
+		repo := newRepo(t)
+		parent, other := NewProduct("phone"), NewProduct("tablet")
+		Create(t, repo, parent, other)
+		axes := []models.VariantAxis{{Name: "color", Values: []string{"red", "blue"}}}
+		require.NoError(t, repo.CreateVariants(ctx, parent.ID, axes, []*models.Product{NewVariant("phone red", "P-RED", "red")}))
+
+		err := repo.CreateVariants(ctx, parent.ID, axes, []*models.Product{
+			NewVariant("phone blue", "P-BLUE", "blue"), NewVariant("phone red", "P-RED-2", "red"),
+		})
+		assert.ErrorIs(t, err, product.ErrDuplicateVariant)
+		err = repo.CreateVariants(ctx, other.ID, axes, []*models.Product{NewVariant("tablet red", "P-RED", "red")})
+		assert.ErrorIs(t, err, product.ErrDuplicateSKU, "skus are unique across parents")
+		err = repo.CreateVariants(ctx, other.ID, axes, []*models.Product{NewVariant("tablet red", "T-RED", "red")})
+		assert.NoError(t, err, "the same options are fine on another parent")
+
+		variants, err := repo.GetVariants(ctx, parent.ID, false)
+		require.NoError(t, err)
+		assert.Len(t, variants, 1)
+		list, err := repo.GetAllProducts(ctx, false)
+		require.NoError(t, err)
+		assert.Len(t, list, 4, "the failed batches left no product behind")
+
+		require.NoError(t, repo.DeleteProduct(ctx, other.ID))
+		err = repo.CreateVariants(ctx, other.ID, axes, []*models.Product{NewVariant("tablet blue", "T-BLUE", "blue")})
+		assert.ErrorIs(t, err, product.ErrNotFound)
+		err = repo.CreateVariants(ctx, 9999, axes, nil)
+		assert.ErrorIs(t, err, product.ErrNotFound)
+	})
+
+	t.Run("attributes", func(t *testing.T) {
+		repo := newRepo(t)
+		oled := laptop("oled", "oled", 13.3, "in", true)
+		ips := laptop("ips", "ips", 15.6, "in", false)
+		metric := laptop("metric", "oled", 33.8, "cm", true)
+		plain := NewProduct("plain")
+		plain.Category = "laptops"
+		phone := NewProduct("phone")
+		phone.Category, phone.Attributes = "phones", map[string]any{"panel": "oled"}
+		Create(t, repo, oled, ips, metric, plain, phone)
+
+		got, err := repo.GetProduct(ctx, oled.ID, false)
+		require.NoError(t, err)
+		assert.Equal(t, oled.Attributes, got.Attributes)
+		got, err = repo.GetProduct(ctx, plain.ID, false)
+		require.NoError(t, err)
+		assert.Nil(t, got.Attributes)
+
+		find := func(f models.ProductFilter) []string {
+			t.Helper()
+			list, err := repo.FindProducts(ctx, f)
+			require.NoError(t, err)
+			names := []string{}
+			for _, p := range list {
+				names = append(names, p.Name)
+			}
+			return names
+		}
+		assert.Equal(t, []string{"oled", "ips", "metric", "plain"}, find(models.ProductFilter{Category: "laptops"}))
+		assert.Equal(t, []string{"oled", "metric", "phone"}, find(models.ProductFilter{Attributes: map[string]any{"panel": "oled"}}))
+		assert.Equal(t, []string{"oled", "metric"}, find(models.ProductFilter{
+			Category: "laptops", Attributes: map[string]any{"panel": "oled", "touch": true},
+		}))
+		assert.Equal(t, []string{"oled"}, find(models.ProductFilter{
+			Category: "laptops", Attributes: map[string]any{"screen": map[string]any{"value": 13.3, "unit": "in"}},
+		}))
+		assert.Equal(t, []string{"ips"}, find(models.ProductFilter{
+			Category: "laptops", Attributes: map[string]any{"screen": map[string]any{"value": 15.6}},
+		}), "a value without a unit matches in any unit")
+		assert.Empty(t, find(models.ProductFilter{Category: "laptops", Attributes: map[string]any{"panel": "tn"}}))
+
+		ips.Attributes = map[string]any{"panel": "oled"}
+		require.NoError(t, repo.UpdateProduct(ctx, ips))
+		require.NoError(t, repo.DeleteProduct(ctx, metric.ID))
+		assert.Equal(t, []string{"oled", "ips"}, find(models.ProductFilter{Category: "laptops", Attributes: map[string]any{"panel": "oled"}}))
+		assert.Equal(t, []string{"oled", "ips", "metric"}, find(models.ProductFilter{
+			Category: "laptops", Attributes: map[string]any{"panel": "oled"}, IncludeDeleted: true,
+		}))
+		got, err = repo.GetProduct(ctx, ips.ID, false)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"panel": "oled"}, got.Attributes)
+	})
+
+	t.Run("concurrent writers", func(t *testing.T) {
+		repo := newRepo(t)
+		const writers = 20
+		ids := make([]int64, writers)
+		var wg sync.WaitGroup
+		for i := range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p := NewProduct(fmt.Sprintf("item-%d", i))
+				if assert.NoError(t, repo.CreateProduct(ctx, p)) {
+					ids[i] = p.ID
+				}
+			}()
+		}
+		wg.Wait()
+
+		seen := make(map[int64]bool, writers)
+		for _, id := range ids {
+			assert.False(t, seen[id], "duplicate id %d", id)
+			seen[id] = true
+		}
+		list, err := repo.GetAllProducts(ctx, false)
+		require.NoError(t, err)
+		assert.Len(t, list, writers)
+
+		// updates read before they write in one transaction, started together to
+		// contend for the write lock
+		const rounds = 5
+		shared := ids[0]
+		race := func(fns ...func()) {
+			start := make(chan struct{})
+			for _, fn := range fns {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					fn()
+				}()
+			}
+			close(start)
+			wg.Wait()
+		}
+		for round := range rounds {
+			var updates, adjustments []func()
+			for _, p := range list {
+				adjustments = append(adjustments, func() {
+					_, err := repo.AdjustQuantity(ctx, shared, 1)
+					assert.NoError(t, err)
+				})
+				if p.ID == shared {
+					continue
+				}
+				p.Description = fmt.Sprintf("round %d", round)
+				updates = append(updates, func() { assert.NoError(t, repo.UpdateProduct(ctx, p)) })
+			}
+			race(updates...)
+			race(adjustments...)
+		}
+
+		list, err = repo.GetAllProducts(ctx, false)
+		require.NoError(t, err)
+		for _, p := range list {
+			if p.ID == shared {
+				assert.Equal(t, NewProduct("").Quantity+rounds*writers, p.Quantity, "no adjustment is lost")
+			} else {
+				assert.Equal(t, fmt.Sprintf("round %d", rounds-1), p.Description, p.Name)
+			}
+		}
+	})
+}
+
+// periods formats the price history of a product as "price [from, to)" with
+// the bounds relative to base; "-" stands for a bound before base or none.
+func periods(t *testing.T, repo product.Repository, id int64, base time.Time) []string {
+	t.Helper()
+	history, err := repo.GetPriceHistory(context.Background(), id)
+	require.NoError(t, err)
+	bound := func(at *time.Time) string {
+		if at == nil || at.Before(base) {
+			return "-"
+		}
+		return at.Sub(base).String()
+	}
+	out := make([]string, len(history))
+	for i, h := range history {
+		out[i] = fmt.Sprintf("%s [%s, %s)", h.Price, bound(&h.EffectiveFrom), bound(h.EffectiveTo))
+	}
+	return out
+}
@@ -0,0 +1,636 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"strings"
+	"time"
+
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+)
+
+// sqliteProductColumns are productColumns in the SQLite dialect, where
+// options is stored as text already.
+const sqliteProductColumns = `p.id, p.name, p.price, p.currency, p.quantity, p.description, p.category, p.tax_class,
+	p.attributes, p.created_at, p.updated_at, p.deleted_at, v.parent_id, COALESCE(v.sku, ''), v.options
+	FROM products p LEFT JOIN product_variants v ON v.product_id = p.id`
+
+// SQLiteRepo stores products in SQLite, for single node deployments.
+type SQLiteRepo struct {
+	db *db.SQLite
+}
+
+func NewSQLiteRepo(db *db.SQLite) *SQLiteRepo {
+	return &SQLiteRepo{db: db}
+}
+
+func (r *SQLiteRepo) CreateProduct(ctx context.Context, p *models.Product) error {
+	err := r.tx(ctx, func(tx *sql.Tx) error {
+		return sqliteInsertProduct(ctx, tx, p, db.Now())
+	})
+	if err != nil {
+		return errors.New("Failed to insert the product: " + err.Error())
+	}
+	return nil
+}
+
+// sqliteInsertProduct adds p with the price period its base price opens.
+func sqliteInsertProduct(ctx context.Context, tx *sql.Tx, p *models.Product, ts time.Time) error {
+	if err := tx.QueryRowContext(ctx, `
+	INSERT INTO products(name, price, currency, quantity, description, category, tax_class, attributes, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	RETURNING id, created_at, updated_at`, p.Name, p.Price.Amount, p.Price.Currency, p.Quantity, p.Description, p.Category, p.TaxClass,
+		encodeAttributes(p.Attributes), ts, ts).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+	INSERT INTO product_price_history(product_id, amount, currency, effective_from) VALUES (?, ?, ?, ?)`,
+		p.ID, p.Price.Amount, p.Price.Currency, ts)
+	return err
+}
+
+func (r *SQLiteRepo) GetProduct(ctx context.Context, id int64, includeDeleted bool) (*models.Product, error) {
+	var p *models.Product
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		var err error
+		p, err = scanProduct(r.db.QueryRowContext(ctx, `
+	SELECT `+sqliteProductColumns+`
+	WHERE p.id = ? and (? or p.deleted_at is null)`, id, includeDeleted))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("product not found", "id", id)
+			return nil, ErrNotFound
+		}
+		return nil, errors.New("failed to get product: " + err.Error())
+	}
+	return p, nil
+}
+
+func (r *SQLiteRepo) GetAllProducts(ctx context.Context, includeDeleted bool) ([]*models.Product, error) {
+	var products []*models.Product
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `
+	SELECT `+sqliteProductColumns+`
+	WHERE ? or p.deleted_at is null ORDER BY p.id`, includeDeleted)
+		if err != nil {
+			return fmt.Errorf("failed to get products: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scanProduct(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan products: %w", err)
+			}
+			products = append(products, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// FindProducts filters the category in SQL and the attributes in Go, SQLite
+// has no JSON containment.
+func (r *SQLiteRepo) FindProducts(ctx context.Context, f models.ProductFilter) ([]*models.Product, error) {
+	var products []*models.Product
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `
+	SELECT `+sqliteProductColumns+`
+	WHERE (? or p.deleted_at is null) AND (? = '' OR p.category = ?) ORDER BY p.id`, f.IncludeDeleted, f.Category, f.Category)
+		if err != nil {
+			return fmt.Errorf("failed to find products: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scanProduct(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan products: %w", err)
+			}
+			if p.HasAttributes(f.Attributes) {
+				products = append(products, p)
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// exec runs a write statement and returns ErrNotFound when no row matched.
+func (r *SQLiteRepo) exec(ctx context.Context, id int64, query string, args ...any) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		res, err := r.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		rows, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateProduct also starts a new price period when the price changes, which
+// closes the one in effect.
+func (r *SQLiteRepo) UpdateProduct(ctx context.Context, p *models.Product) error {
+	err := r.tx(ctx, func(tx *sql.Tx) error {
+		var old money.Money
+		if err := tx.QueryRowContext(ctx, `SELECT price, currency FROM products WHERE id = ? AND deleted_at is null`, p.ID).
+			Scan(&old.Amount, &old.Currency); err != nil {
+			return err
+		}
+		ts := db.Now()
+		if _, err := tx.ExecContext(ctx, `
+	UPDATE products SET name = ?, price = ?, currency = ?, quantity = ?, description = ?, category = ?, tax_class = ?,
+	                attributes = ?, updated_at = ?
+	                WHERE id = ?`, p.Name, p.Price.Amount, p.Price.Currency, p.Quantity, p.Description, p.Category, p.TaxClass,
+			encodeAttributes(p.Attributes), ts, p.ID); err != nil {
+			return err
+		}
+		if old == p.Price {
+			return nil
+		}
+		return sqliteSchedulePrice(ctx, tx, &models.PricePeriod{ProductID: p.ID, Price: p.Price, EffectiveFrom: ts})
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		logging.For(ctx, logPackage).Debug("product not found", "id", p.ID)
+		return ErrNotFound
+	}
+	if err != nil {
+		return errors.New("failed to update product: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) AdjustQuantity(ctx context.Context, id int64, delta int) (int, error) {
+	var quantity int
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		err := conn.QueryRowContext(ctx, `
+	UPDATE products SET quantity = quantity + ?, updated_at = ?
+	WHERE id = ? AND deleted_at is null AND quantity + ? >= 0
+	RETURNING quantity`, delta, db.Now(), id, delta).Scan(&quantity)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		// either the product is missing or its stock is short
+		if err := conn.QueryRowContext(ctx, `SELECT quantity FROM products WHERE id = ? AND deleted_at is null`, id).
+			Scan(&quantity); err != nil {
+			return err
+		}
+		return ErrInsufficientStock
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return 0, ErrNotFound
+	}
+	if errors.Is(err, ErrInsufficientStock) {
+		return quantity, ErrInsufficientStock
+	}
+	if err != nil {
+		return 0, errors.New("failed to adjust product quantity: " + err.Error())
+	}
+	return quantity, nil
+}
+
+func (r *SQLiteRepo) RestockQuantity(ctx context.Context, id int64, quantity int) error {
+	var res sql.Result
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = r.db.Conn(ctx).ExecContext(ctx,
+			`UPDATE products SET quantity = quantity + ?, updated_at = ? WHERE id = ?`, quantity, db.Now(), id)
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to restock product: " + err.Error())
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		logging.For(ctx, logPackage).Debug("product not found", "id", id)
+		return ErrNotFound
+	}
+	return nil
+}
+
+// tx runs fn in a write transaction, committed when fn succeeds.
+func (r *SQLiteRepo) tx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return r.db.Write(ctx, func(ctx context.Context) error {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+func (r *SQLiteRepo) DeleteProduct(ctx context.Context, id int64) error {
+	err := r.transition(ctx, id, true, `UPDATE products SET deleted_at = ? WHERE id = ? AND deleted_at is null`, db.Now(), id)
+	if err != nil && !isLifecycleError(err) {
+		return errors.New("failed to delete product: " + err.Error())
+	}
+	return err
+}
+
+func (r *SQLiteRepo) RestoreProduct(ctx context.Context, id int64) error {
+	err := r.transition(ctx, id, false, `UPDATE products SET deleted_at = null WHERE id = ? AND deleted_at is not null`, id)
+	if err != nil && !isLifecycleError(err) {
+		return errors.New("failed to restore product: " + err.Error())
+	}
+	return err
+}
+
+// transition runs an update guarded by the current state. When it matches no
+// row, the product is looked up to tell a missing product from one already in
+// the target state.
+func (r *SQLiteRepo) transition(ctx context.Context, id int64, toDeleted bool, query string, args ...any) error {
+	err := r.exec(ctx, id, query, args...)
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	var deleted bool
+	err = r.db.Read(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `SELECT deleted_at is not null FROM products WHERE id = ?`, id).Scan(&deleted)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkTransition(deleted, toDeleted); err != nil {
+		return err
+	}
+	// the state changed between the update and the lookup
+	return ErrNotFound
+}
+
+func (r *SQLiteRepo) GetProductStats(ctx context.Context, lowStock int) (*models.ProductStats, error) {
+	var st models.ProductStats
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `
+	SELECT count(*), COALESCE(sum(quantity), 0), count(*) FILTER (WHERE quantity <= ?)
+	FROM products WHERE deleted_at is null`, lowStock).Scan(&st.Active, &st.StockUnits, &st.LowStock)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product stats: %w", err)
+	}
+	return &st, nil
+}
+
+func (r *SQLiteRepo) GetDeletedProducts(ctx context.Context, before time.Time) ([]*models.Product, error) {
+	var products []*models.Product
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `
+	SELECT `+sqliteProductColumns+`
+	WHERE p.deleted_at < ? ORDER BY p.id`, before.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to get deleted products: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scanProduct(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan products: %w", err)
+			}
+			products = append(products, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *SQLiteRepo) HardDeleteProduct(ctx context.Context, id int64) (*models.PurgedProduct, error) {
+	return r.remove(ctx, id, models.PurgeReasonHardDelete, `DELETE FROM products WHERE id = ?
+	RETURNING name, deleted_at`, id)
+}
+
+func (r *SQLiteRepo) PurgeProduct(ctx context.Context, id int64, before time.Time) (*models.PurgedProduct, error) {
+	return r.remove(ctx, id, models.PurgeReasonRetention, `DELETE FROM products WHERE id = ? AND deleted_at < ?
+	RETURNING name, deleted_at`, id, before.UTC())
+}
+
+func (r *SQLiteRepo) remove(ctx context.Context, id int64, reason, query string, args ...any) (*models.PurgedProduct, error) {
+	purged := models.PurgedProduct{ProductID: id, Reason: reason, PurgedAt: db.Now()}
+	err := r.tx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&purged.Name, &purged.DeletedAt); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+	INSERT INTO product_purges(product_id, name, deleted_at, purged_at, reason) VALUES (?, ?, ?, ?, ?)`,
+			id, purged.Name, purged.DeletedAt, purged.PurgedAt, reason)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			logging.For(ctx, logPackage).Debug("product not found", "id", id)
+			return nil, ErrNotFound
+		case strings.Contains(err.Error(), "FOREIGN KEY constraint failed"):
+			return nil, ErrReferenced
+		}
+		return nil, errors.New("failed to remove product: " + err.Error())
+	}
+	return &purged, nil
+}
+
+func (r *SQLiteRepo) GetProductPrices(ctx context.Context, id int64) ([]money.Money, error) {
+	var prices []money.Money
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `
+	SELECT amount, currency FROM product_prices WHERE product_id = ? ORDER BY currency`, id)
+		if err != nil {
+			return fmt.Errorf("failed to get product prices: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var m money.Money
+			if err := rows.Scan(&m.Amount, &m.Currency); err != nil {
+				return fmt.Errorf("failed to scan product prices: %w", err)
+			}
+			prices = append(prices, m)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+func (r *SQLiteRepo) SetProductPrice(ctx context.Context, id int64, price money.Money) error {
+	ts := db.Now()
+	err := r.exec(ctx, id, `
+	INSERT INTO product_prices(product_id, currency, amount, updated_at)
+	SELECT id, ?, ?, ? FROM products WHERE id = ? AND deleted_at is null
+	ON CONFLICT (product_id, currency) DO UPDATE SET amount = excluded.amount, updated_at = excluded.updated_at`,
+		price.Currency, price.Amount, ts, id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return errors.New("failed to set product price: " + err.Error())
+	}
+	return err
+}
+
+func (r *SQLiteRepo) DeleteProductPrice(ctx context.Context, id int64, currency string) error {
+	err := r.exec(ctx, id, `DELETE FROM product_prices WHERE product_id = ? AND currency = ?`, id, currency)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return errors.New("failed to delete product price: " + err.Error())
+	}
+	return err
+}
+
+func (r *SQLiteRepo) GetPriceHistory(ctx context.Context, id int64) ([]*models.PricePeriod, error) {
+	var periods []*models.PricePeriod
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		var err error
+		periods, err = sqliteQueryPeriods(ctx, r.db.DB, `
+	SELECT id, product_id, amount, currency, effective_from, effective_to FROM product_price_history
+	WHERE product_id = ? ORDER BY effective_from`, id)
+		return err
+	})
+	if err != nil {
+		return nil, errors.New("failed to get price history: " + err.Error())
+	}
+	return periods, nil
+}
+
+func (r *SQLiteRepo) SchedulePrice(ctx context.Context, p *models.PricePeriod) error {
+	err := r.tx(ctx, func(tx *sql.Tx) error {
+		var id int64
+		if err := tx.QueryRowContext(ctx, `SELECT id FROM products WHERE id = ? AND deleted_at is null`, p.ProductID).
+			Scan(&id); err != nil {
+			return err
+		}
+		return sqliteSchedulePrice(ctx, tx, p)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		logging.For(ctx, logPackage).Debug("product not found", "id", p.ProductID)
+		return ErrNotFound
+	}
+	if err != nil {
+		return errors.New("failed to schedule price: " + err.Error())
+	}
+	return nil
+}
+
+// sqliteSchedulePrice applies the plan of p to the periods of its product.
+func sqliteSchedulePrice(ctx context.Context, tx *sql.Tx, p *models.PricePeriod) error {
+	periods, err := sqliteQueryPeriods(ctx, tx, `
+	SELECT id, product_id, amount, currency, effective_from, effective_to FROM product_price_history
+	WHERE product_id = ? AND (effective_to IS NULL OR effective_to > ?) ORDER BY effective_from`, p.ProductID, p.EffectiveFrom.UTC())
+	if err != nil {
+		return err
+	}
+	plan := planPrice(periods, p)
+	for _, id := range plan.deletes {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM product_price_history WHERE id = ?`, id); err != nil {
+			return err
+		}
+	}
+	for _, u := range plan.updates {
+		if _, err := tx.ExecContext(ctx, `UPDATE product_price_history SET effective_from = ?, effective_to = ? WHERE id = ?`,
+			u.EffectiveFrom.UTC(), utcOrNil(u.EffectiveTo), u.ID); err != nil {
+			return err
+		}
+	}
+	for _, in := range plan.inserts {
+		if err := tx.QueryRowContext(ctx, `
+	INSERT INTO product_price_history(product_id, amount, currency, effective_from, effective_to)
+	VALUES (?, ?, ?, ?, ?) RETURNING id`,
+			in.ProductID, in.Price.Amount, in.Price.Currency, in.EffectiveFrom.UTC(), utcOrNil(in.EffectiveTo)).Scan(&in.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) ApplyScheduledPrices(ctx context.Context, now time.Time) ([]*models.PricePeriod, error) {
+	var periods []*models.PricePeriod
+	err := r.tx(ctx, func(tx *sql.Tx) error {
+		var err error
+		periods, err = sqliteQueryPeriods(ctx, tx, `
+	SELECT h.id, h.product_id, h.amount, h.currency, h.effective_from, h.effective_to
+	FROM product_price_history h JOIN products p ON p.id = h.product_id
+	WHERE p.deleted_at is null
+	  AND h.effective_from <= ? AND (h.effective_to IS NULL OR h.effective_to > ?)
+	  AND (p.price <> h.amount OR p.currency <> h.currency)`, now.UTC(), now.UTC())
+		if err != nil {
+			return err
+		}
+		for _, h := range periods {
+			if _, err := tx.ExecContext(ctx, `UPDATE products SET price = ?, currency = ?, updated_at = ? WHERE id = ?`,
+				h.Price.Amount, h.Price.Currency, now.UTC(), h.ProductID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("failed to apply scheduled prices: " + err.Error())
+	}
+	return periods, nil
+}
+
+func (r *SQLiteRepo) NextPriceChange(ctx context.Context, after time.Time) (*time.Time, error) {
+	var next time.Time
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `
+	SELECT h.effective_from FROM product_price_history h JOIN products p ON p.id = h.product_id
+	WHERE p.deleted_at is null AND h.effective_from > ? ORDER BY h.effective_from LIMIT 1`, after.UTC()).Scan(&next)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get next price change: " + err.Error())
+	}
+	return &next, nil
+}
+
+func (r *SQLiteRepo) GetVariantAxes(ctx context.Context, id int64) ([]models.VariantAxis, error) {
+	var axes []models.VariantAxis
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		var raw string
+		if err := r.db.QueryRowContext(ctx, `SELECT axes FROM product_variant_axes WHERE product_id = ?`, id).Scan(&raw); err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(raw), &axes)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to get variant axes: " + err.Error())
+	}
+	return axes, nil
+}
+
+func (r *SQLiteRepo) GetVariants(ctx context.Context, parentID int64, includeDeleted bool) ([]*models.Product, error) {
+	var variants []*models.Product
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `
+	SELECT `+sqliteProductColumns+`
+	WHERE v.parent_id = ? AND (? or p.deleted_at is null) ORDER BY p.id`, parentID, includeDeleted)
+		if err != nil {
+			return fmt.Errorf("failed to get variants: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scanProduct(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan variants: %w", err)
+			}
+			variants = append(variants, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+func (r *SQLiteRepo) CreateVariants(ctx context.Context, parentID int64, axes []models.VariantAxis, variants []*models.Product) error {
+	rawAxes, err := json.Marshal(axes)
+	if err != nil {
+		return errors.New("failed to create variants: " + err.Error())
+	}
+	err = r.tx(ctx, func(tx *sql.Tx) error {
+		var id int64
+		if err := tx.QueryRowContext(ctx, `SELECT id FROM products WHERE id = ? AND deleted_at is null`, parentID).
+			Scan(&id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+	INSERT INTO product_variant_axes(product_id, axes) VALUES (?, ?)
+	ON CONFLICT (product_id) DO UPDATE SET axes = excluded.axes`, parentID, string(rawAxes)); err != nil {
+			return err
+		}
+		ts := db.Now()
+		for _, v := range variants {
+			if err := sqliteInsertProduct(ctx, tx, v, ts); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `
+	INSERT INTO product_variants(product_id, parent_id, sku, options) VALUES (?, ?, ?, ?)`,
+				v.ID, parentID, v.SKU, models.VariantKey(v.Options)); err != nil {
+				return err
+			}
+			parent := id
+			v.ParentID = &parent
+		}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			logging.For(ctx, logPackage).Debug("product not found", "id", parentID)
+			return ErrNotFound
+		case strings.Contains(err.Error(), "UNIQUE constraint failed: product_variants.sku"):
+			return ErrDuplicateSKU
+		case strings.Contains(err.Error(), "UNIQUE constraint failed: product_variants."):
+			return ErrDuplicateVariant
+		}
+		return errors.New("failed to create variants: " + err.Error())
+	}
+	return nil
+}
+
+type sqliteQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func sqliteQueryPeriods(ctx context.Context, q sqliteQuerier, query string, args ...any) ([]*models.PricePeriod, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var periods []*models.PricePeriod
+	for rows.Next() {
+		var p models.PricePeriod
+		if err := rows.Scan(&p.ID, &p.ProductID, &p.Price.Amount, &p.Price.Currency, &p.EffectiveFrom, &p.EffectiveTo); err != nil {
+			return nil, err
+		}
+		periods = append(periods, &p)
+	}
+	return periods, rows.Err()
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
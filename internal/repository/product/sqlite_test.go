@@ -0,0 +1,26 @@
+package product_test
+
+import (
+	"context"
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/product/producttest"
+	"prodcrud/pkg/db/sqlitetest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteRepo_Contract(t *testing.T) {
+	producttest.Run(t, func(t *testing.T) product.Repository {
+		return product.NewSQLiteRepo(sqlitetest.NewFile(t))
+	})
+}
+
+func TestSQLiteRepo_InMemory(t *testing.T) {
+	ctx := context.Background()
+	repo := product.NewSQLiteRepo(sqlitetest.New(t))
+	p := producttest.NewProduct("apple")
+	producttest.Create(t, repo, p)
+	_, err := repo.GetProduct(ctx, p.ID, false)
+	require.NoError(t, err)
+}
@@ -0,0 +1,141 @@
+package promotion_test
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/promotion"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, 8, 15, 0, 0, 0, 0, time.UTC)
+
+func percentage(name string, percent string, priority int) *models.Promotion {
+	p := money.MustParseRate(percent)
+	return &models.Promotion{
+		Name: name, Kind: models.PromotionPercentage, Categories: []string{"laptops"},
+		Percent: &p, Priority: priority, StartsAt: start,
+	}
+}
+
+// runContract checks the behaviour every promotion.Repository shares.
+func runContract(t *testing.T, newRepo func(t *testing.T) promotion.Repository) {
+	ctx := context.Background()
+
+	t.Run("create and get keeps every rule", func(t *testing.T) {
+		repo := newRepo(t)
+		end := start.AddDate(0, 0, 14)
+		amount := money.New(500, "USD")
+		rules := []*models.Promotion{
+			percentage("back to school", "12.5", 1),
+			{
+				Name: "five off", Kind: models.PromotionFixedAmount, ProductIDs: []int64{1, 2},
+				Amount: &amount, StartsAt: start, EndsAt: &end, Stackable: true,
+			},
+			{Name: "2+1", Kind: models.PromotionBuyXGetY, ProductIDs: []int64{3}, Buy: 2, Get: 1, StartsAt: start},
+			{
+				Name: "bulk", Kind: models.PromotionTiered, Categories: []string{"cables"}, StartsAt: start,
+				Tiers: []models.PromotionTier{
+					{MinQuantity: 10, Percent: money.MustParseRate("5")},
+					{MinQuantity: 50, Percent: money.MustParseRate("10")},
+				},
+			},
+		}
+		for _, p := range rules {
+			require.NoError(t, repo.CreatePromotion(ctx, p))
+			assert.NotZero(t, p.ID)
+			assert.False(t, p.CreatedAt.IsZero())
+		}
+
+		got, err := repo.GetPromotion(ctx, rules[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, "12.5", got.Percent.String())
+		assert.Equal(t, []string{"laptops"}, got.Categories)
+		assert.Empty(t, got.ProductIDs)
+		assert.Nil(t, got.Amount)
+		assert.True(t, got.StartsAt.Equal(start))
+		assert.Nil(t, got.EndsAt)
+
+		got, err = repo.GetPromotion(ctx, rules[1].ID)
+		require.NoError(t, err)
+		assert.Equal(t, &amount, got.Amount)
+		assert.Equal(t, []int64{1, 2}, got.ProductIDs)
+		assert.True(t, got.Stackable)
+		require.NotNil(t, got.EndsAt)
+		assert.True(t, got.EndsAt.Equal(end))
+		assert.Nil(t, got.Percent)
+
+		got, err = repo.GetPromotion(ctx, rules[2].ID)
+		require.NoError(t, err)
+		assert.Equal(t, [2]int{2, 1}, [2]int{got.Buy, got.Get})
+
+		got, err = repo.GetPromotion(ctx, rules[3].ID)
+		require.NoError(t, err)
+		require.Len(t, got.Tiers, 2)
+		assert.Equal(t, 50, got.Tiers[1].MinQuantity)
+		assert.Equal(t, "10", got.Tiers[1].Percent.String())
+		assert.Zero(t, got.Buy)
+
+		_, err = repo.GetPromotion(ctx, 424242)
+		assert.ErrorIs(t, err, promotion.ErrNotFound)
+	})
+
+	t.Run("active by priority", func(t *testing.T) {
+		repo := newRepo(t)
+		low, high, tie := percentage("low", "5", 1), percentage("high", "10", 5), percentage("tie", "15", 1)
+		ended := percentage("ended", "50", 9)
+		end := start.AddDate(0, 0, 1)
+		ended.EndsAt = &end
+		future := percentage("future", "50", 9)
+		future.StartsAt = start.AddDate(0, 1, 0)
+		for _, p := range []*models.Promotion{low, high, tie, ended, future} {
+			require.NoError(t, repo.CreatePromotion(ctx, p))
+		}
+
+		active, err := repo.GetActivePromotions(ctx, start.AddDate(0, 0, 2))
+		require.NoError(t, err)
+		var names []string
+		for _, p := range active {
+			names = append(names, p.Name)
+		}
+		assert.Equal(t, []string{"high", "low", "tie"}, names)
+
+		active, err = repo.GetActivePromotions(ctx, end)
+		require.NoError(t, err)
+		assert.Len(t, active, 3, "ends_at is exclusive")
+
+		all, err := repo.GetPromotions(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 5)
+		assert.Equal(t, low.ID, all[0].ID)
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		repo := newRepo(t)
+		p := percentage("sale", "10", 0)
+		require.NoError(t, repo.CreatePromotion(ctx, p))
+
+		p.Name, p.Kind, p.Percent, p.Buy, p.Get = "3 for 2", models.PromotionBuyXGetY, nil, 2, 1
+		require.NoError(t, repo.UpdatePromotion(ctx, p))
+		assert.False(t, p.UpdatedAt.Before(p.CreatedAt))
+
+		got, err := repo.GetPromotion(ctx, p.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "3 for 2", got.Name)
+		assert.Nil(t, got.Percent)
+		assert.Equal(t, 2, got.Buy)
+
+		missing := percentage("missing", "10", 0)
+		missing.ID = 424242
+		assert.ErrorIs(t, repo.UpdatePromotion(ctx, missing), promotion.ErrNotFound)
+
+		require.NoError(t, repo.DeletePromotion(ctx, p.ID))
+		assert.ErrorIs(t, repo.DeletePromotion(ctx, p.ID), promotion.ErrNotFound)
+		_, err = repo.GetPromotion(ctx, p.ID)
+		assert.ErrorIs(t, err, promotion.ErrNotFound)
+	})
+}
@@ -0,0 +1,123 @@
+package promotion
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryRepo keeps promotions in memory, for tests and local demos.
+type MemoryRepo struct {
+	mu         sync.RWMutex
+	promotions map[int64]*models.Promotion
+	lastID     int64
+}
+
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{promotions: make(map[int64]*models.Promotion)}
+}
+
+// view returns a copy, so callers cannot mutate the stored promotion.
+func view(p *models.Promotion) *models.Promotion {
+	cp := *p
+	cp.ProductIDs = append([]int64{}, p.ProductIDs...)
+	cp.Categories = append([]string{}, p.Categories...)
+	cp.Tiers = slices.Clone(p.Tiers)
+	if p.Percent != nil {
+		percent := *p.Percent
+		cp.Percent = &percent
+	}
+	if p.Amount != nil {
+		amount := *p.Amount
+		cp.Amount = &amount
+	}
+	if p.EndsAt != nil {
+		endsAt := p.EndsAt.UTC()
+		cp.EndsAt = &endsAt
+	}
+	cp.StartsAt = p.StartsAt.UTC()
+	return &cp
+}
+
+func (r *MemoryRepo) CreatePromotion(_ context.Context, p *models.Promotion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	p.ID = r.lastID
+	p.CreatedAt = db.Now()
+	p.UpdatedAt = p.CreatedAt
+	r.promotions[p.ID] = view(p)
+	return nil
+}
+
+func (r *MemoryRepo) GetPromotion(_ context.Context, id int64) (*models.Promotion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.promotions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return view(p), nil
+}
+
+func (r *MemoryRepo) GetPromotions(_ context.Context) ([]*models.Promotion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var promotions []*models.Promotion
+	for _, p := range r.promotions {
+		promotions = append(promotions, view(p))
+	}
+	sort.Slice(promotions, func(i, j int) bool { return promotions[i].ID < promotions[j].ID })
+	return promotions, nil
+}
+
+func (r *MemoryRepo) GetActivePromotions(_ context.Context, at time.Time) ([]*models.Promotion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var promotions []*models.Promotion
+	for _, p := range r.promotions {
+		if p.InEffect(at) {
+			promotions = append(promotions, view(p))
+		}
+	}
+	sort.Slice(promotions, func(i, j int) bool {
+		if promotions[i].Priority != promotions[j].Priority {
+			return promotions[i].Priority > promotions[j].Priority
+		}
+		return promotions[i].ID < promotions[j].ID
+	})
+	return promotions, nil
+}
+
+func (r *MemoryRepo) UpdatePromotion(_ context.Context, p *models.Promotion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.promotions[p.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	p.CreatedAt = stored.CreatedAt
+	p.UpdatedAt = db.Now()
+	r.promotions[p.ID] = view(p)
+	return nil
+}
+
+func (r *MemoryRepo) DeletePromotion(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.promotions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.promotions, id)
+	return nil
+}
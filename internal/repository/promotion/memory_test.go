@@ -0,0 +1,12 @@
+package promotion_test
+
+import (
+	"prodcrud/internal/repository/promotion"
+	"testing"
+)
+
+func TestMemoryRepo_Contract(t *testing.T) {
+	runContract(t, func(*testing.T) promotion.Repository {
+		return promotion.NewMemoryRepo()
+	})
+}
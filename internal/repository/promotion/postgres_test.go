@@ -0,0 +1,15 @@
+package promotion_test
+
+import (
+	"prodcrud/internal/repository/promotion"
+	"prodcrud/pkg/db/pgtest"
+	"testing"
+)
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+func TestRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) promotion.Repository {
+		return promotion.NewRepo(pgtest.New(t))
+	})
+}
@@ -0,0 +1,284 @@
+package promotion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Repository stores promotions. Scopes and tiers are kept as JSON, the
+// engine matches them against basket lines in memory.
+type Repository interface {
+	// CreatePromotion stores p and sets its ID, CreatedAt and UpdatedAt.
+	CreatePromotion(ctx context.Context, p *models.Promotion) error
+	GetPromotion(ctx context.Context, id int64) (*models.Promotion, error)
+	// GetPromotions lists every promotion, past and future, by id.
+	GetPromotions(ctx context.Context) ([]*models.Promotion, error)
+	// GetActivePromotions lists the promotions in effect at the given time,
+	// highest priority first, then by id.
+	GetActivePromotions(ctx context.Context, at time.Time) ([]*models.Promotion, error)
+	// UpdatePromotion replaces the promotion p.ID and sets p.UpdatedAt.
+	UpdatePromotion(ctx context.Context, p *models.Promotion) error
+	DeletePromotion(ctx context.Context, id int64) error
+}
+
+const logPackage = "repository/promotion"
+
+// ErrNotFound is returned when the promotion does not exist.
+var ErrNotFound = errors.New("promotion not found")
+
+const columns = `id, name, kind, product_ids::text, categories::text, percent::text, amount, currency, buy, get,
+	tiers::text, priority, stackable, starts_at, ends_at, created_at, updated_at`
+
+type Repo struct {
+	db *db.DB
+}
+
+func NewRepo(db *db.DB) *Repo {
+	return &Repo{db: db}
+}
+
+func (r *Repo) CreatePromotion(ctx context.Context, p *models.Promotion) error {
+	row, err := encode(p)
+	if err != nil {
+		return errors.New("failed to insert the promotion: " + err.Error())
+	}
+	err = r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRow(ctx, `
+	INSERT INTO promotions(name, kind, product_ids, categories, percent, amount, currency, buy, get, tiers, priority, stackable, starts_at, ends_at)
+	VALUES ($1, $2, $3::jsonb, $4::jsonb, $5::numeric, $6, $7, $8, $9, $10::jsonb, $11, $12, $13, $14)
+	RETURNING id, created_at, updated_at`, row.args()...).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	})
+	if err != nil {
+		return errors.New("failed to insert the promotion: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) GetPromotion(ctx context.Context, id int64) (*models.Promotion, error) {
+	var p *models.Promotion
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		var err error
+		p, err = scan(q.QueryRow(ctx, `SELECT `+columns+` FROM promotions WHERE id = $1`, id))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("promotion not found", "id", id)
+			return nil, ErrNotFound
+		}
+		return nil, errors.New("failed to get promotion: " + err.Error())
+	}
+	return p, nil
+}
+
+func (r *Repo) GetPromotions(ctx context.Context) ([]*models.Promotion, error) {
+	return r.query(ctx, `SELECT `+columns+` FROM promotions ORDER BY id`)
+}
+
+func (r *Repo) GetActivePromotions(ctx context.Context, at time.Time) ([]*models.Promotion, error) {
+	return r.query(ctx, `
+	SELECT `+columns+` FROM promotions
+	WHERE starts_at <= $1 AND (ends_at IS NULL OR ends_at > $1)
+	ORDER BY priority DESC, id`, at)
+}
+
+func (r *Repo) query(ctx context.Context, query string, args ...any) ([]*models.Promotion, error) {
+	var promotions []*models.Promotion
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		promotions = nil
+		rows, err := q.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to get promotions: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scan(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan promotions: %w", err)
+			}
+			promotions = append(promotions, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+func (r *Repo) UpdatePromotion(ctx context.Context, p *models.Promotion) error {
+	row, err := encode(p)
+	if err != nil {
+		return errors.New("failed to update promotion: " + err.Error())
+	}
+	err = r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRow(ctx, `
+	UPDATE promotions SET name = $1, kind = $2, product_ids = $3::jsonb, categories = $4::jsonb, percent = $5::numeric,
+	    amount = $6, currency = $7, buy = $8, get = $9, tiers = $10::jsonb, priority = $11, stackable = $12,
+	    starts_at = $13, ends_at = $14, updated_at = now()
+	WHERE id = $15 RETURNING created_at, updated_at`, append(row.args(), p.ID)...).Scan(&p.CreatedAt, &p.UpdatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		logging.For(ctx, logPackage).Debug("promotion not found", "id", p.ID)
+		return ErrNotFound
+	}
+	if err != nil {
+		return errors.New("failed to update promotion: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) DeletePromotion(ctx context.Context, id int64) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tag, err := r.db.Exec(ctx, `DELETE FROM promotions WHERE id = $1`, id)
+		rows = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete promotion: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// record is a promotion in its column form, shared by the SQL backends.
+type record struct {
+	p          *models.Promotion
+	productIDs string
+	categories string
+	tiers      string
+	percent    *string
+	amount     *int64
+	currency   *string
+}
+
+func encode(p *models.Promotion) (*record, error) {
+	rec := record{p: p}
+	var err error
+	if rec.productIDs, err = marshal(p.ProductIDs); err != nil {
+		return nil, err
+	}
+	if rec.categories, err = marshal(p.Categories); err != nil {
+		return nil, err
+	}
+	if rec.tiers, err = marshal(p.Tiers); err != nil {
+		return nil, err
+	}
+	if p.Percent != nil {
+		s := p.Percent.String()
+		rec.percent = &s
+	}
+	if p.Amount != nil {
+		rec.amount, rec.currency = &p.Amount.Amount, &p.Amount.Currency
+	}
+	return &rec, nil
+}
+
+// args returns the insert arguments, in the column order of columns
+// without id and the timestamps.
+func (rec *record) args() []any {
+	p := rec.p
+	return []any{p.Name, p.Kind, rec.productIDs, rec.categories, rec.percent, rec.amount, rec.currency,
+		nullIfZero(p.Buy), nullIfZero(p.Get), rec.tiers, p.Priority, p.Stackable, p.StartsAt.UTC(), utcOrNil(p.EndsAt)}
+}
+
+// dest returns the scan destinations, in the order of columns.
+func (rec *record) dest() []any {
+	p := rec.p
+	return []any{&p.ID, &p.Name, &p.Kind, &rec.productIDs, &rec.categories, &rec.percent, &rec.amount, &rec.currency,
+		&nullInt{&p.Buy}, &nullInt{&p.Get}, &rec.tiers, &p.Priority, &p.Stackable, &p.StartsAt, &p.EndsAt, &p.CreatedAt, &p.UpdatedAt}
+}
+
+// decode fills the promotion from the JSON and nullable columns.
+func (rec *record) decode() error {
+	p := rec.p
+	if err := json.Unmarshal([]byte(rec.productIDs), &p.ProductIDs); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(rec.categories), &p.Categories); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(rec.tiers), &p.Tiers); err != nil {
+		return err
+	}
+	if len(p.Tiers) == 0 {
+		p.Tiers = nil
+	}
+	if rec.percent != nil {
+		percent, err := money.ParseRate(*rec.percent)
+		if err != nil {
+			return err
+		}
+		p.Percent = &percent
+	}
+	if rec.amount != nil && rec.currency != nil {
+		p.Amount = &money.Money{Amount: *rec.amount, Currency: *rec.currency}
+	}
+	return nil
+}
+
+func scan(row pgx.Row) (*models.Promotion, error) {
+	rec := record{p: &models.Promotion{}}
+	if err := row.Scan(rec.dest()...); err != nil {
+		return nil, err
+	}
+	if err := rec.decode(); err != nil {
+		return nil, err
+	}
+	return rec.p, nil
+}
+
+// marshal encodes a scope or tier list, an empty one as [].
+func marshal[T any](list []T) (string, error) {
+	if list == nil {
+		list = []T{}
+	}
+	b, err := json.Marshal(list)
+	return string(b), err
+}
+
+func nullIfZero(n int) *int {
+	if n == 0 {
+		return nil
+	}
+	return &n
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// nullInt scans a nullable integer column into an int, NULL as 0.
+type nullInt struct {
+	n *int
+}
+
+func (n *nullInt) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*n.n = 0
+	case int64:
+		*n.n = int(v)
+	case int32:
+		*n.n = int(v)
+	default:
+		return fmt.Errorf("cannot scan %T into an int", src)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package promotion
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"time"
+)
+
+// SQLiteRepo is the promotion store for SQLite, where scopes and tiers are
+// JSON text and percents decimal text.
+type SQLiteRepo struct {
+	db *db.SQLite
+}
+
+func NewSQLiteRepo(db *db.SQLite) *SQLiteRepo {
+	return &SQLiteRepo{db: db}
+}
+
+const sqliteColumns = `id, name, kind, product_ids, categories, percent, amount, currency, buy, get,
+	tiers, priority, stackable, starts_at, ends_at, created_at, updated_at`
+
+func (r *SQLiteRepo) CreatePromotion(ctx context.Context, p *models.Promotion) error {
+	row, err := encode(p)
+	if err != nil {
+		return errors.New("failed to insert the promotion: " + err.Error())
+	}
+	ts := db.Now()
+	err = r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `
+	INSERT INTO promotions(name, kind, product_ids, categories, percent, amount, currency, buy, get, tiers, priority, stackable, starts_at, ends_at, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	RETURNING id, created_at, updated_at`, append(row.args(), ts, ts)...).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	})
+	if err != nil {
+		return errors.New("failed to insert the promotion: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetPromotion(ctx context.Context, id int64) (*models.Promotion, error) {
+	var p *models.Promotion
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		var err error
+		p, err = sqliteScan(r.db.QueryRowContext(ctx, `SELECT `+sqliteColumns+` FROM promotions WHERE id = ?`, id))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("promotion not found", "id", id)
+			return nil, ErrNotFound
+		}
+		return nil, errors.New("failed to get promotion: " + err.Error())
+	}
+	return p, nil
+}
+
+func (r *SQLiteRepo) GetPromotions(ctx context.Context) ([]*models.Promotion, error) {
+	return r.query(ctx, `SELECT `+sqliteColumns+` FROM promotions ORDER BY id`)
+}
+
+func (r *SQLiteRepo) GetActivePromotions(ctx context.Context, at time.Time) ([]*models.Promotion, error) {
+	at = at.UTC()
+	return r.query(ctx, `
+	SELECT `+sqliteColumns+` FROM promotions
+	WHERE starts_at <= ? AND (ends_at IS NULL OR ends_at > ?)
+	ORDER BY priority DESC, id`, at, at)
+}
+
+func (r *SQLiteRepo) query(ctx context.Context, query string, args ...any) ([]*models.Promotion, error) {
+	var promotions []*models.Promotion
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to get promotions: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := sqliteScan(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan promotions: %w", err)
+			}
+			promotions = append(promotions, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+func (r *SQLiteRepo) UpdatePromotion(ctx context.Context, p *models.Promotion) error {
+	row, err := encode(p)
+	if err != nil {
+		return errors.New("failed to update promotion: " + err.Error())
+	}
+	ts := db.Now()
+	err = r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `
+	UPDATE promotions SET name = ?, kind = ?, product_ids = ?, categories = ?, percent = ?,
+	    amount = ?, currency = ?, buy = ?, get = ?, tiers = ?, priority = ?, stackable = ?,
+	    starts_at = ?, ends_at = ?, updated_at = ?
+	WHERE id = ? RETURNING created_at, updated_at`, append(row.args(), ts, p.ID)...).Scan(&p.CreatedAt, &p.UpdatedAt)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		logging.For(ctx, logPackage).Debug("promotion not found", "id", p.ID)
+		return ErrNotFound
+	}
+	if err != nil {
+		return errors.New("failed to update promotion: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) DeletePromotion(ctx context.Context, id int64) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		res, err := r.db.ExecContext(ctx, `DELETE FROM promotions WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		rows, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete promotion: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func sqliteScan(row interface{ Scan(dest ...any) error }) (*models.Promotion, error) {
+	rec := record{p: &models.Promotion{}}
+	if err := row.Scan(rec.dest()...); err != nil {
+		return nil, err
+	}
+	if err := rec.decode(); err != nil {
+		return nil, err
+	}
+	return rec.p, nil
+}
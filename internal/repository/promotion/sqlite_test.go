@@ -0,0 +1,13 @@
+package promotion_test
+
+import (
+	"prodcrud/internal/repository/promotion"
+	"prodcrud/pkg/db/sqlitetest"
+	"testing"
+)
+
+func TestSQLiteRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) promotion.Repository {
+		return promotion.NewSQLiteRepo(sqlitetest.New(t))
+	})
+}
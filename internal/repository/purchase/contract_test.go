@@ -0,0 +1,255 @@
+package purchase_test
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/purchase"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runContract checks the behaviour every purchase.Repository shares. newRepo
+// returns the purchase repository with a product repository of the same
+// store, for the products the suppliers sell.
+func runContract(t *testing.T, newRepo func(t *testing.T) (purchase.Repository, product.Repository)) {
+	ctx := context.Background()
+
+	newProduct := func(t *testing.T, products product.Repository, name string) int64 {
+		t.Helper()
+		p := &models.Product{Name: name, Price: money.New(2500, "EUR"), Quantity: 1, Description: name}
+		require.NoError(t, products.CreateProduct(ctx, p))
+		return p.ID
+	}
+	newSupplier := func(t *testing.T, repo purchase.Repository) *models.Supplier {
+		t.Helper()
+		s := &models.Supplier{Name: "Acme", Email: "orders@acme.example", Currency: "EUR"}
+		require.NoError(t, repo.CreateSupplier(ctx, s))
+		return s
+	}
+	newOrder := func(t *testing.T, repo purchase.Repository, supplierID int64, productIDs ...int64) *models.PurchaseOrder {
+		t.Helper()
+		o := &models.PurchaseOrder{SupplierID: supplierID, Currency: "EUR", Notes: "restock"}
+		for i, id := range productIDs {
+			o.Lines = append(o.Lines, &models.PurchaseOrderLine{
+				ProductID: id, Quantity: 10 * (i + 1), Cost: money.New(1200, "EUR"),
+			})
+		}
+		require.NoError(t, repo.CreateOrder(ctx, o))
+		return o
+	}
+
+	t.Run("suppliers", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		s := newSupplier(t, repo)
+		assert.NotZero(t, s.ID)
+		assert.False(t, s.CreatedAt.IsZero())
+		other := &models.Supplier{Name: "Globex", Currency: "USD"}
+		require.NoError(t, repo.CreateSupplier(ctx, other))
+
+		s.Name, s.Currency = "Acme Wholesale", "USD"
+		require.NoError(t, repo.UpdateSupplier(ctx, s))
+		got, err := repo.GetSupplier(ctx, s.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Acme Wholesale", got.Name)
+		assert.Equal(t, "orders@acme.example", got.Email)
+		assert.Equal(t, "USD", got.Currency)
+		assert.False(t, got.UpdatedAt.Before(got.CreatedAt))
+
+		all, err := repo.GetSuppliers(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, s.ID, all[0].ID)
+
+		require.NoError(t, repo.DeleteSupplier(ctx, other.ID))
+		_, err = repo.GetSupplier(ctx, other.ID)
+		assert.ErrorIs(t, err, purchase.ErrSupplierNotFound)
+		assert.ErrorIs(t, repo.DeleteSupplier(ctx, other.ID), purchase.ErrSupplierNotFound)
+		assert.ErrorIs(t, repo.UpdateSupplier(ctx, other), purchase.ErrSupplierNotFound)
+	})
+
+	t.Run("supplier products", func(t *testing.T) {
+		repo, products := newRepo(t)
+		s := newSupplier(t, repo)
+		pen, ink := newProduct(t, products, "pen"), newProduct(t, products, "ink")
+
+		link := &models.SupplierProduct{SupplierID: s.ID, ProductID: ink, SKU: "ACME-INK", Cost: money.New(300, "EUR"), LeadTimeDays: 5}
+		require.NoError(t, repo.SetSupplierProduct(ctx, link))
+		assert.False(t, link.UpdatedAt.IsZero())
+		require.NoError(t, repo.SetSupplierProduct(ctx, &models.SupplierProduct{
+			SupplierID: s.ID, ProductID: pen, Cost: money.New(100, "EUR"), LeadTimeDays: 2,
+		}))
+		link.Cost, link.LeadTimeDays = money.New(350, "EUR"), 7
+		require.NoError(t, repo.SetSupplierProduct(ctx, link))
+
+		links, err := repo.GetSupplierProducts(ctx, s.ID)
+		require.NoError(t, err)
+		require.Len(t, links, 2)
+		assert.Equal(t, pen, links[0].ProductID)
+		assert.Equal(t, "ACME-INK", links[1].SKU)
+		assert.Equal(t, money.New(350, "EUR"), links[1].Cost)
+		assert.Equal(t, 7, links[1].LeadTimeDays)
+
+		require.NoError(t, repo.DeleteSupplierProduct(ctx, s.ID, pen))
+		assert.ErrorIs(t, repo.DeleteSupplierProduct(ctx, s.ID, pen), purchase.ErrSupplierProductNotFound)
+		link.SupplierID = 999
+		assert.ErrorIs(t, repo.SetSupplierProduct(ctx, link), purchase.ErrSupplierNotFound)
+
+		// the links go with the supplier
+		require.NoError(t, repo.DeleteSupplier(ctx, s.ID))
+		links, err = repo.GetSupplierProducts(ctx, s.ID)
+		require.NoError(t, err)
+		assert.Empty(t, links)
+	})
+
+	t.Run("orders", func(t *testing.T) {
+		repo, products := newRepo(t)
+		s := newSupplier(t, repo)
+		pen, ink := newProduct(t, products, "pen"), newProduct(t, products, "ink")
+
+		o := newOrder(t, repo, s.ID, pen, ink)
+		assert.Equal(t, models.PurchaseDraft, o.Status)
+		assert.NotZero(t, o.Lines[0].ID)
+		got, err := repo.GetOrder(ctx, o.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "restock", got.Notes)
+		assert.Equal(t, "EUR", got.Currency)
+		require.Len(t, got.Lines, 2)
+		assert.Equal(t, &models.PurchaseOrderLine{
+			ID: o.Lines[1].ID, ProductID: ink, Quantity: 20, Cost: money.New(1200, "EUR"),
+		}, got.Lines[1])
+		assert.Nil(t, got.SentAt)
+
+		o.Notes = "restock, urgent"
+		o.Lines = []*models.PurchaseOrderLine{{ProductID: ink, Quantity: 5, Cost: money.New(1100, "EUR")}}
+		require.NoError(t, repo.UpdateOrder(ctx, o))
+		got, err = repo.GetOrder(ctx, o.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "restock, urgent", got.Notes)
+		require.Len(t, got.Lines, 1)
+		assert.Equal(t, 5, got.Lines[0].Quantity)
+		assert.Equal(t, o.Lines[0].ID, got.Lines[0].ID)
+
+		second := newOrder(t, repo, s.ID, pen)
+		other := &models.Supplier{Name: "Globex", Currency: "EUR"}
+		require.NoError(t, repo.CreateSupplier(ctx, other))
+		third := newOrder(t, repo, other.ID, pen)
+		_, err = repo.CancelOrder(ctx, second.ID)
+		require.NoError(t, err)
+
+		all, err := repo.GetOrders(ctx, models.PurchaseOrderFilter{})
+		require.NoError(t, err)
+		require.Len(t, all, 3)
+		assert.Equal(t, third.ID, all[0].ID, "newest first")
+		assert.Len(t, all[2].Lines, 1)
+		bySupplier, err := repo.GetOrders(ctx, models.PurchaseOrderFilter{SupplierID: s.ID})
+		require.NoError(t, err)
+		assert.Len(t, bySupplier, 2)
+		drafts, err := repo.GetOrders(ctx, models.PurchaseOrderFilter{SupplierID: s.ID, Status: models.PurchaseDraft})
+		require.NoError(t, err)
+		require.Len(t, drafts, 1)
+		assert.Equal(t, o.ID, drafts[0].ID)
+		require.Len(t, drafts[0].Lines, 1)
+
+		_, err = repo.GetOrder(ctx, 999)
+		assert.ErrorIs(t, err, purchase.ErrOrderNotFound)
+		assert.ErrorIs(t, repo.DeleteSupplier(ctx, s.ID), purchase.ErrSupplierInUse)
+	})
+
+	t.Run("send, receive and cancel", func(t *testing.T) {
+		repo, products := newRepo(t)
+		s := newSupplier(t, repo)
+		pen, ink := newProduct(t, products, "pen"), newProduct(t, products, "ink")
+		o := newOrder(t, repo, s.ID, pen, ink)
+		penLine, inkLine := o.Lines[0].ID, o.Lines[1].ID
+
+		_, err := repo.ReceiveOrder(ctx, o.ID, []models.ReceiptLine{{LineID: penLine, Quantity: 1}})
+		assert.ErrorIs(t, err, purchase.ErrInvalidStatus, "a draft is not received")
+
+		sent, err := repo.SendOrder(ctx, o.ID, 7)
+		require.NoError(t, err)
+		assert.Equal(t, models.PurchaseSent, sent.Status)
+		require.NotNil(t, sent.SentAt)
+		require.NotNil(t, sent.ExpectedAt)
+		assert.Equal(t, 7*24*time.Hour, sent.ExpectedAt.Sub(*sent.SentAt))
+		_, err = repo.SendOrder(ctx, o.ID, 7)
+		assert.ErrorIs(t, err, purchase.ErrInvalidStatus)
+		assert.ErrorIs(t, repo.UpdateOrder(ctx, o), purchase.ErrInvalidStatus, "only drafts are edited")
+
+		got, err := repo.ReceiveOrder(ctx, o.ID, []models.ReceiptLine{{LineID: penLine, Quantity: 10}, {LineID: inkLine, Quantity: 5}})
+		require.NoError(t, err)
+		assert.Equal(t, models.PurchasePartiallyReceived, got.Status)
+		assert.Equal(t, 10, got.Line(penLine).Received)
+		assert.Equal(t, 5, got.Line(inkLine).Received)
+		assert.Nil(t, got.ReceivedAt)
+
+		for name, lines := range map[string][]models.ReceiptLine{
+			"complete line": {{LineID: penLine, Quantity: 1}},
+			"too many":      {{LineID: inkLine, Quantity: 16}},
+			"twice":         {{LineID: inkLine, Quantity: 10}, {LineID: inkLine, Quantity: 10}},
+			"no quantity":   {{LineID: inkLine}},
+			"other line":    {{LineID: 999, Quantity: 1}},
+		} {
+			_, err := repo.ReceiveOrder(ctx, o.ID, lines)
+			assert.ErrorIs(t, err, purchase.ErrInvalidReceipt, name)
+		}
+		got, err = repo.GetOrder(ctx, o.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 5, got.Line(inkLine).Received, "a failed receipt changes nothing")
+
+		got, err = repo.ReceiveOrder(ctx, o.ID, []models.ReceiptLine{{LineID: inkLine, Quantity: 15}})
+		require.NoError(t, err)
+		assert.Equal(t, models.PurchaseReceived, got.Status)
+		assert.NotNil(t, got.ReceivedAt)
+		got, err = repo.GetOrder(ctx, o.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.PurchaseReceived, got.Status)
+		assert.Equal(t, 20, got.Line(inkLine).Received)
+		_, err = repo.CancelOrder(ctx, o.ID)
+		assert.ErrorIs(t, err, purchase.ErrInvalidStatus, "a received order stays received")
+
+		partial := newOrder(t, repo, s.ID, pen)
+		_, err = repo.SendOrder(ctx, partial.ID, 0)
+		require.NoError(t, err)
+		_, err = repo.ReceiveOrder(ctx, partial.ID, []models.ReceiptLine{{LineID: partial.Lines[0].ID, Quantity: 4}})
+		require.NoError(t, err)
+		cancelled, err := repo.CancelOrder(ctx, partial.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.PurchaseCancelled, cancelled.Status)
+		assert.Equal(t, 4, cancelled.Lines[0].Received)
+		_, err = repo.CancelOrder(ctx, partial.ID)
+		assert.ErrorIs(t, err, purchase.ErrInvalidStatus)
+		_, err = repo.CancelOrder(ctx, 999)
+		assert.ErrorIs(t, err, purchase.ErrOrderNotFound)
+	})
+	t.Run("products on orders", func(t *testing.T) {
+		repo, products := newRepo(t)
+		s := newSupplier(t, repo)
+		pen, ink := newProduct(t, products, "pen"), newProduct(t, products, "ink")
+		require.NoError(t, repo.SetSupplierProduct(ctx, &models.SupplierProduct{
+			SupplierID: s.ID, ProductID: ink, Cost: money.New(1200, "EUR"), LeadTimeDays: 2,
+		}))
+		o := newOrder(t, repo, s.ID, pen)
+		_, err := repo.SendOrder(ctx, o.ID, 0)
+		require.NoError(t, err)
+		_, err = repo.ReceiveOrder(ctx, o.ID, []models.ReceiptLine{{LineID: o.Lines[0].ID, Quantity: 4}})
+		require.NoError(t, err)
+
+		_, err = products.HardDeleteProduct(ctx, pen)
+		assert.ErrorIs(t, err, product.ErrReferenced)
+		require.NoError(t, products.DeleteProduct(ctx, pen))
+		_, err = products.PurgeProduct(ctx, pen, time.Now().Add(time.Hour))
+		assert.ErrorIs(t, err, product.ErrReferenced, "the purge skips it")
+		got, err := repo.GetOrder(ctx, o.ID)
+		require.NoError(t, err)
+		require.Len(t, got.Lines, 1)
+		assert.Equal(t, 4, got.Lines[0].Received)
+
+		_, err = products.HardDeleteProduct(ctx, ink)
+		assert.NoError(t, err, "a product only in the catalog of a supplier can be removed")
+	})
+}
@@ -0,0 +1,293 @@
+package purchase
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryRepo keeps suppliers and purchase orders in memory, for tests and
+// local demos. A transition applies as soon as it is valid, and is undone if
+// the db.MemoryTx.InTx call it runs in fails.
+type MemoryRepo struct {
+	mu             sync.RWMutex
+	suppliers      map[int64]*models.Supplier
+	links          map[[2]int64]*models.SupplierProduct
+	orders         map[int64]*models.PurchaseOrder
+	lastSupplierID int64
+	lastOrderID    int64
+	lastLineID     int64
+}
+
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{
+		suppliers: make(map[int64]*models.Supplier),
+		links:     make(map[[2]int64]*models.SupplierProduct),
+		orders:    make(map[int64]*models.PurchaseOrder),
+	}
+}
+
+// ReferencesProduct reports whether a purchase order has a line of the
+// product, so the product repository keeps it like the foreign key of
+// purchase_order_lines does.
+func (r *MemoryRepo) ReferencesProduct(productID int64) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, o := range r.orders {
+		for _, l := range o.Lines {
+			if l.ProductID == productID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// viewOrder returns a deep copy, so callers cannot mutate the stored order.
+func viewOrder(o *models.PurchaseOrder) *models.PurchaseOrder {
+	cp := *o
+	cp.Lines = make([]*models.PurchaseOrderLine, len(o.Lines))
+	for i, l := range o.Lines {
+		line := *l
+		cp.Lines[i] = &line
+	}
+	for _, t := range []**time.Time{&cp.SentAt, &cp.ExpectedAt, &cp.ReceivedAt} {
+		if *t != nil {
+			v := **t
+			*t = &v
+		}
+	}
+	return &cp
+}
+
+func (r *MemoryRepo) CreateSupplier(_ context.Context, s *models.Supplier) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastSupplierID++
+	s.ID = r.lastSupplierID
+	s.CreatedAt = db.Now()
+	s.UpdatedAt = s.CreatedAt
+	stored := *s
+	r.suppliers[s.ID] = &stored
+	return nil
+}
+
+func (r *MemoryRepo) GetSupplier(_ context.Context, id int64) (*models.Supplier, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.suppliers[id]
+	if !ok {
+		return nil, ErrSupplierNotFound
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (r *MemoryRepo) GetSuppliers(_ context.Context) ([]*models.Supplier, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var suppliers []*models.Supplier
+	for _, s := range r.suppliers {
+		cp := *s
+		suppliers = append(suppliers, &cp)
+	}
+	sort.Slice(suppliers, func(i, k int) bool { return suppliers[i].ID < suppliers[k].ID })
+	return suppliers, nil
+}
+
+func (r *MemoryRepo) UpdateSupplier(_ context.Context, s *models.Supplier) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.suppliers[s.ID]
+	if !ok {
+		return ErrSupplierNotFound
+	}
+	stored.Name, stored.Email, stored.Currency = s.Name, s.Email, s.Currency
+	stored.UpdatedAt = db.Now()
+	s.CreatedAt, s.UpdatedAt = stored.CreatedAt, stored.UpdatedAt
+	return nil
+}
+
+func (r *MemoryRepo) DeleteSupplier(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.suppliers[id]; !ok {
+		return ErrSupplierNotFound
+	}
+	for _, o := range r.orders {
+		if o.SupplierID == id {
+			return ErrSupplierInUse
+		}
+	}
+	delete(r.suppliers, id)
+	for key := range r.links {
+		if key[0] == id {
+			delete(r.links, key)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepo) SetSupplierProduct(_ context.Context, sp *models.SupplierProduct) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.suppliers[sp.SupplierID]; !ok {
+		return ErrSupplierNotFound
+	}
+	sp.UpdatedAt = db.Now()
+	stored := *sp
+	r.links[[2]int64{sp.SupplierID, sp.ProductID}] = &stored
+	return nil
+}
+
+func (r *MemoryRepo) GetSupplierProducts(_ context.Context, supplierID int64) ([]*models.SupplierProduct, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var links []*models.SupplierProduct
+	for key, sp := range r.links {
+		if key[0] == supplierID {
+			cp := *sp
+			links = append(links, &cp)
+		}
+	}
+	sort.Slice(links, func(i, k int) bool { return links[i].ProductID < links[k].ProductID })
+	return links, nil
+}
+
+func (r *MemoryRepo) DeleteSupplierProduct(_ context.Context, supplierID, productID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := [2]int64{supplierID, productID}
+	if _, ok := r.links[key]; !ok {
+		return ErrSupplierProductNotFound
+	}
+	delete(r.links, key)
+	return nil
+}
+
+func (r *MemoryRepo) CreateOrder(_ context.Context, o *models.PurchaseOrder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastOrderID++
+	o.ID = r.lastOrderID
+	o.Status = models.PurchaseDraft
+	o.CreatedAt = db.Now()
+	o.UpdatedAt = o.CreatedAt
+	r.numberLines(o)
+	r.orders[o.ID] = viewOrder(o)
+	return nil
+}
+
+func (r *MemoryRepo) numberLines(o *models.PurchaseOrder) {
+	for _, l := range o.Lines {
+		r.lastLineID++
+		l.ID = r.lastLineID
+		l.Received = 0
+	}
+}
+
+func (r *MemoryRepo) GetOrder(_ context.Context, id int64) (*models.PurchaseOrder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	o, ok := r.orders[id]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	return viewOrder(o), nil
+}
+
+func (r *MemoryRepo) GetOrders(_ context.Context, f models.PurchaseOrderFilter) ([]*models.PurchaseOrder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var orders []*models.PurchaseOrder
+	for _, o := range r.orders {
+		if (f.SupplierID == 0 || o.SupplierID == f.SupplierID) && (f.Status == "" || o.Status == f.Status) {
+			orders = append(orders, viewOrder(o))
+		}
+	}
+	slices.SortFunc(orders, func(a, b *models.PurchaseOrder) int { return int(b.ID - a.ID) })
+	return orders, nil
+}
+
+func (r *MemoryRepo) UpdateOrder(ctx context.Context, o *models.PurchaseOrder) error {
+	_, err := r.transition(ctx, o.ID, func(stored *models.PurchaseOrder, at time.Time) error {
+		if err := checkDraft(stored, "edit"); err != nil {
+			return err
+		}
+		r.numberLines(o)
+		stored.Notes = o.Notes
+		stored.Lines = viewOrder(o).Lines
+		stored.UpdatedAt = at
+		o.SupplierID, o.Status, o.Currency = stored.SupplierID, stored.Status, stored.Currency
+		o.CreatedAt, o.UpdatedAt = stored.CreatedAt, stored.UpdatedAt
+		return nil
+	})
+	return err
+}
+
+func (r *MemoryRepo) SendOrder(ctx context.Context, id int64, leadTimeDays int) (*models.PurchaseOrder, error) {
+	return r.transition(ctx, id, func(o *models.PurchaseOrder, at time.Time) error {
+		if err := checkDraft(o, "send"); err != nil {
+			return err
+		}
+		expectedAt := at.AddDate(0, 0, leadTimeDays)
+		o.Status, o.SentAt, o.ExpectedAt, o.UpdatedAt = models.PurchaseSent, &at, &expectedAt, at
+		return nil
+	})
+}
+
+func (r *MemoryRepo) CancelOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error) {
+	return r.transition(ctx, id, func(o *models.PurchaseOrder, at time.Time) error {
+		if err := checkCancel(o); err != nil {
+			return err
+		}
+		o.Status, o.UpdatedAt = models.PurchaseCancelled, at
+		return nil
+	})
+}
+
+func (r *MemoryRepo) ReceiveOrder(ctx context.Context, id int64, lines []models.ReceiptLine) (*models.PurchaseOrder, error) {
+	return r.transition(ctx, id, func(o *models.PurchaseOrder, at time.Time) error {
+		return receive(o, lines, at)
+	})
+}
+
+// transition lets fn change a copy of the order, stored only if fn succeeds.
+func (r *MemoryRepo) transition(ctx context.Context, id int64, fn func(o *models.PurchaseOrder, at time.Time) error) (*models.PurchaseOrder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.orders[id]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	o := viewOrder(stored)
+	if err := fn(o, db.Now()); err != nil {
+		return nil, err
+	}
+	r.orders[id] = o
+	db.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.orders[id] == o {
+			r.orders[id] = stored
+		}
+	})
+	return viewOrder(o), nil
+}
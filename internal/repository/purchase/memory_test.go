@@ -0,0 +1,14 @@
+package purchase_test
+
+import (
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/purchase"
+	"testing"
+)
+
+func TestMemoryRepo_Contract(t *testing.T) {
+	runContract(t, func(*testing.T) (purchase.Repository, product.Repository) {
+		repo := purchase.NewMemoryRepo()
+		return repo, product.NewMemoryRepo(repo)
+	})
+}
@@ -0,0 +1,22 @@
+package purchase_test
+
+import (
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/purchase"
+	"prodcrud/pkg/db/pgtest"
+	"testing"
+)
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+func TestRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) (purchase.Repository, product.Repository) {
+		pool := pgtest.New(t)
+		return purchase.NewRepo(pool), product.NewRepo(pool)
+	})
+}
+
+func TestRepo_ReceiveOrder_sharedTransaction(t *testing.T) {
+	pool := pgtest.New(t)
+	checkSharedTransaction(t, pool, purchase.NewRepo(pool), product.NewRepo(pool))
+}
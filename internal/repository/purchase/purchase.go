@@ -0,0 +1,558 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Repository stores the suppliers, the products they sell and the purchase
+// orders placed with them.
+type Repository interface {
+	// CreateSupplier stores s and sets its ID and timestamps.
+	CreateSupplier(ctx context.Context, s *models.Supplier) error
+	GetSupplier(ctx context.Context, id int64) (*models.Supplier, error)
+	// GetSuppliers lists every supplier by id.
+	GetSuppliers(ctx context.Context) ([]*models.Supplier, error)
+	// UpdateSupplier replaces the name, email and currency of a supplier and
+	// sets s.UpdatedAt.
+	UpdateSupplier(ctx context.Context, s *models.Supplier) error
+	// DeleteSupplier deletes a supplier with its product links, and returns
+	// ErrSupplierInUse if it has purchase orders.
+	DeleteSupplier(ctx context.Context, id int64) error
+
+	// SetSupplierProduct creates or replaces the link of sp.ProductID to
+	// sp.SupplierID and sets sp.UpdatedAt.
+	SetSupplierProduct(ctx context.Context, sp *models.SupplierProduct) error
+	// GetSupplierProducts lists the products of a supplier by product id.
+	GetSupplierProducts(ctx context.Context, supplierID int64) ([]*models.SupplierProduct, error)
+	DeleteSupplierProduct(ctx context.Context, supplierID, productID int64) error
+
+	// CreateOrder stores a draft with its lines and sets their IDs, the
+	// status and the timestamps.
+	CreateOrder(ctx context.Context, o *models.PurchaseOrder) error
+	GetOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error)
+	// GetOrders lists the orders matching f, newest first.
+	GetOrders(ctx context.Context, f models.PurchaseOrderFilter) ([]*models.PurchaseOrder, error)
+	// UpdateOrder replaces the notes and lines of a draft, which get new IDs,
+	// and returns ErrInvalidStatus if the order is no draft.
+	UpdateOrder(ctx context.Context, o *models.PurchaseOrder) error
+	// SendOrder marks a draft as sent now and expected leadTimeDays later.
+	SendOrder(ctx context.Context, id int64, leadTimeDays int) (*models.PurchaseOrder, error)
+	// CancelOrder cancels an order that is not received or cancelled yet.
+	CancelOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error)
+	// ReceiveOrder adds the receipt lines to the received quantities of a
+	// sent or partially received order, which becomes received once every
+	// line is complete. It returns ErrInvalidReceipt for a line the order does
+	// not have or more units than are left, and joins the transaction of
+	// db.Transactor.InTx.
+	ReceiveOrder(ctx context.Context, id int64, lines []models.ReceiptLine) (*models.PurchaseOrder, error)
+}
+
+const logPackage = "repository/purchase"
+
+var (
+	// ErrSupplierNotFound is returned when the supplier does not exist.
+	ErrSupplierNotFound = errors.New("supplier not found")
+	// ErrSupplierInUse is returned when deleting a supplier with orders.
+	ErrSupplierInUse = errors.New("supplier has purchase orders")
+	// ErrSupplierProductNotFound is returned when the supplier does not sell
+	// the product.
+	ErrSupplierProductNotFound = errors.New("supplier product not found")
+	// ErrProductNotFound is returned when linking a product that does not exist.
+	ErrProductNotFound = errors.New("product not found")
+	// ErrOrderNotFound is returned when the purchase order does not exist.
+	ErrOrderNotFound = errors.New("purchase order not found")
+	// ErrInvalidStatus is returned when the status of the order does not
+	// allow the operation.
+	ErrInvalidStatus = errors.New("invalid purchase order status")
+	// ErrInvalidReceipt wraps every error of a receipt that does not match
+	// the order.
+	ErrInvalidReceipt = errors.New("invalid receipt")
+)
+
+const (
+	supplierColumns = `id, name, email, currency, created_at, updated_at`
+	linkColumns     = `supplier_id, product_id, sku, cost, currency, lead_time_days, updated_at`
+	orderColumns    = `id, supplier_id, status, currency, notes, created_at, updated_at, sent_at, expected_at, received_at`
+	lineColumns     = `id, order_id, product_id, quantity, received_quantity, cost`
+)
+
+// scanner is a row of either driver.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSupplier(row scanner) (*models.Supplier, error) {
+	var s models.Supplier
+	if err := row.Scan(&s.ID, &s.Name, &s.Email, &s.Currency, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err //nolint:wrapcheck //wrapped by the callers
+	}
+	return &s, nil
+}
+
+func scanLink(row scanner) (*models.SupplierProduct, error) {
+	var sp models.SupplierProduct
+	if err := row.Scan(&sp.SupplierID, &sp.ProductID, &sp.SKU, &sp.Cost.Amount, &sp.Cost.Currency, &sp.LeadTimeDays,
+		&sp.UpdatedAt); err != nil {
+		return nil, err //nolint:wrapcheck //wrapped by the callers
+	}
+	return &sp, nil
+}
+
+func scanOrder(row scanner) (*models.PurchaseOrder, error) {
+	o := models.PurchaseOrder{Lines: []*models.PurchaseOrderLine{}}
+	if err := row.Scan(&o.ID, &o.SupplierID, &o.Status, &o.Currency, &o.Notes, &o.CreatedAt, &o.UpdatedAt,
+		&o.SentAt, &o.ExpectedAt, &o.ReceivedAt); err != nil {
+		return nil, err //nolint:wrapcheck //wrapped by the callers
+	}
+	return &o, nil
+}
+
+// scanLine returns a line with the id of its order, whose currency it takes.
+func scanLine(row scanner) (int64, *models.PurchaseOrderLine, error) {
+	var (
+		orderID int64
+		l       models.PurchaseOrderLine
+	)
+	if err := row.Scan(&l.ID, &orderID, &l.ProductID, &l.Quantity, &l.Received, &l.Cost.Amount); err != nil {
+		return 0, nil, err //nolint:wrapcheck //wrapped by the callers
+	}
+	return orderID, &l, nil
+}
+
+// attachLine appends l to its order in orders, in the currency of the order.
+func attachLine(orders map[int64]*models.PurchaseOrder, orderID int64, l *models.PurchaseOrderLine) {
+	if o, ok := orders[orderID]; ok {
+		l.Cost.Currency = o.Currency
+		o.Lines = append(o.Lines, l)
+	}
+}
+
+// receive applies the receipt lines to o at the given time.
+func receive(o *models.PurchaseOrder, lines []models.ReceiptLine, at time.Time) error {
+	if !o.Open() {
+		return fmt.Errorf("%w: cannot receive a %s order", ErrInvalidStatus, o.Status)
+	}
+	for _, r := range lines {
+		l := o.Line(r.LineID)
+		if l == nil {
+			return fmt.Errorf("%w: the order has no line %d", ErrInvalidReceipt, r.LineID)
+		}
+		if left := l.Quantity - l.Received; r.Quantity <= 0 || r.Quantity > left {
+			return fmt.Errorf("%w: line %d has %d units left to receive", ErrInvalidReceipt, l.ID, left)
+		}
+		l.Received += r.Quantity
+	}
+	o.Status = models.PurchaseReceived
+	for _, l := range o.Lines {
+		if l.Received < l.Quantity {
+			o.Status = models.PurchasePartiallyReceived
+		}
+	}
+	o.UpdatedAt = at
+	if o.Status == models.PurchaseReceived {
+		o.ReceivedAt = &at
+	}
+	return nil
+}
+
+// checkCancel tells whether the order can still be cancelled.
+func checkCancel(o *models.PurchaseOrder) error {
+	if o.Status != models.PurchaseDraft && !o.Open() {
+		return fmt.Errorf("%w: cannot cancel a %s order", ErrInvalidStatus, o.Status)
+	}
+	return nil
+}
+
+func checkDraft(o *models.PurchaseOrder, action string) error {
+	if o.Status != models.PurchaseDraft {
+		return fmt.Errorf("%w: cannot %s a %s order", ErrInvalidStatus, action, o.Status)
+	}
+	return nil
+}
+
+// isOrderError tells the errors returned as is from the ones to wrap.
+func isOrderError(err error) bool {
+	return errors.Is(err, ErrOrderNotFound) || errors.Is(err, ErrInvalidStatus) || errors.Is(err, ErrInvalidReceipt)
+}
+
+type Repo struct {
+	db *db.DB
+}
+
+func NewRepo(db *db.DB) *Repo {
+	return &Repo{db: db}
+}
+
+func (r *Repo) CreateSupplier(ctx context.Context, s *models.Supplier) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRow(ctx, `
+	INSERT INTO suppliers(name, email, currency) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`,
+			s.Name, s.Email, s.Currency).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+	})
+	if err != nil {
+		return errors.New("failed to create supplier: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) GetSupplier(ctx context.Context, id int64) (*models.Supplier, error) {
+	var s *models.Supplier
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		var err error
+		s, err = scanSupplier(q.QueryRow(ctx, `SELECT `+supplierColumns+` FROM suppliers WHERE id = $1`, id))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("supplier not found", "id", id)
+			return nil, ErrSupplierNotFound
+		}
+		return nil, errors.New("failed to get supplier: " + err.Error())
+	}
+	return s, nil
+}
+
+func (r *Repo) GetSuppliers(ctx context.Context) ([]*models.Supplier, error) {
+	var suppliers []*models.Supplier
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		suppliers = nil
+		rows, err := q.Query(ctx, `SELECT `+supplierColumns+` FROM suppliers ORDER BY id`)
+		if err != nil {
+			return fmt.Errorf("failed to get suppliers: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			s, err := scanSupplier(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan suppliers: %w", err)
+			}
+			suppliers = append(suppliers, s)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return suppliers, nil
+}
+
+func (r *Repo) UpdateSupplier(ctx context.Context, s *models.Supplier) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRow(ctx, `
+	UPDATE suppliers SET name = $1, email = $2, currency = $3, updated_at = now() WHERE id = $4
+	RETURNING created_at, updated_at`, s.Name, s.Email, s.Currency, s.ID).Scan(&s.CreatedAt, &s.UpdatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		logging.For(ctx, logPackage).Debug("supplier not found", "id", s.ID)
+		return ErrSupplierNotFound
+	}
+	if err != nil {
+		return errors.New("failed to update supplier: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) DeleteSupplier(ctx context.Context, id int64) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tag, err := r.db.Exec(ctx, `DELETE FROM suppliers WHERE id = $1`, id)
+		rows = tag.RowsAffected()
+		return err
+	})
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == "23503":
+		return ErrSupplierInUse
+	case err != nil:
+		return errors.New("failed to delete supplier: " + err.Error())
+	case rows == 0:
+		return ErrSupplierNotFound
+	}
+	return nil
+}
+
+func (r *Repo) SetSupplierProduct(ctx context.Context, sp *models.SupplierProduct) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRow(ctx, `
+	INSERT INTO supplier_products(supplier_id, product_id, sku, cost, currency, lead_time_days)
+	SELECT id, $2, $3, $4, $5, $6 FROM suppliers WHERE id = $1
+	ON CONFLICT (supplier_id, product_id) DO UPDATE SET sku = excluded.sku, cost = excluded.cost,
+	    currency = excluded.currency, lead_time_days = excluded.lead_time_days, updated_at = now()
+	RETURNING updated_at`, sp.SupplierID, sp.ProductID, sp.SKU, sp.Cost.Amount, sp.Cost.Currency, sp.LeadTimeDays).
+			Scan(&sp.UpdatedAt)
+	})
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return ErrSupplierNotFound
+	case errors.As(err, &pgErr) && pgErr.Code == "23503":
+		return ErrProductNotFound
+	case err != nil:
+		return errors.New("failed to set supplier product: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) GetSupplierProducts(ctx context.Context, supplierID int64) ([]*models.SupplierProduct, error) {
+	var links []*models.SupplierProduct
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		links = nil
+		rows, err := q.Query(ctx, `SELECT `+linkColumns+` FROM supplier_products WHERE supplier_id = $1 ORDER BY product_id`,
+			supplierID)
+		if err != nil {
+			return fmt.Errorf("failed to get supplier products: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			sp, err := scanLink(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan supplier products: %w", err)
+			}
+			links = append(links, sp)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *Repo) DeleteSupplierProduct(ctx context.Context, supplierID, productID int64) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tag, err := r.db.Exec(ctx, `DELETE FROM supplier_products WHERE supplier_id = $1 AND product_id = $2`,
+			supplierID, productID)
+		rows = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete supplier product: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrSupplierProductNotFound
+	}
+	return nil
+}
+
+func (r *Repo) CreateOrder(ctx context.Context, o *models.PurchaseOrder) error {
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		o.Status = models.PurchaseDraft
+		if err := conn.QueryRow(ctx, `
+	INSERT INTO purchase_orders(supplier_id, status, currency, notes) VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, updated_at`, o.SupplierID, o.Status, o.Currency, o.Notes).
+			Scan(&o.ID, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return err
+		}
+		return insertLines(ctx, conn, o)
+	})
+	if err != nil {
+		return errors.New("failed to create purchase order: " + err.Error())
+	}
+	return nil
+}
+
+func insertLines(ctx context.Context, conn db.Conn, o *models.PurchaseOrder) error {
+	for _, l := range o.Lines {
+		if err := conn.QueryRow(ctx, `
+	INSERT INTO purchase_order_lines(order_id, product_id, quantity, cost) VALUES ($1, $2, $3, $4) RETURNING id`,
+			o.ID, l.ProductID, l.Quantity, l.Cost.Amount).Scan(&l.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repo) GetOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error) {
+	var o *models.PurchaseOrder
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		var err error
+		o, err = getOrder(ctx, q, id, false)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrOrderNotFound) {
+			logging.For(ctx, logPackage).Debug("purchase order not found", "id", id)
+			return nil, err
+		}
+		return nil, errors.New("failed to get purchase order: " + err.Error())
+	}
+	return o, nil
+}
+
+// getOrder reads an order with its lines, locking it for update if asked.
+func getOrder(ctx context.Context, q db.Querier, id int64, forUpdate bool) (*models.PurchaseOrder, error) {
+	query := `SELECT ` + orderColumns + ` FROM purchase_orders WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	o, err := scanOrder(q.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	orders := map[int64]*models.PurchaseOrder{o.ID: o}
+	if err := getLines(ctx, q, orders, `WHERE order_id = $1`, id); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// getLines attaches the lines matching where to their order in orders.
+func getLines(ctx context.Context, q db.Querier, orders map[int64]*models.PurchaseOrder, where string, args ...any) error {
+	rows, err := q.Query(ctx, `SELECT `+lineColumns+` FROM purchase_order_lines `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get purchase order lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		orderID, l, err := scanLine(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan purchase order lines: %w", err)
+		}
+		attachLine(orders, orderID, l)
+	}
+	return rows.Err()
+}
+
+func (r *Repo) GetOrders(ctx context.Context, f models.PurchaseOrderFilter) ([]*models.PurchaseOrder, error) {
+	const where = `($1::bigint = 0 OR supplier_id = $1) AND ($2::text = '' OR status = $2)`
+	var orders []*models.PurchaseOrder
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		orders = nil
+		rows, err := q.Query(ctx, `SELECT `+orderColumns+` FROM purchase_orders WHERE `+where+` ORDER BY id DESC`,
+			f.SupplierID, f.Status)
+		if err != nil {
+			return fmt.Errorf("failed to get purchase orders: %w", err)
+		}
+		defer rows.Close()
+
+		byID := make(map[int64]*models.PurchaseOrder)
+		for rows.Next() {
+			o, err := scanOrder(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan purchase orders: %w", err)
+			}
+			orders = append(orders, o)
+			byID[o.ID] = o
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+		return getLines(ctx, q, byID, `WHERE order_id IN (SELECT id FROM purchase_orders WHERE `+where+`)`,
+			f.SupplierID, f.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *Repo) UpdateOrder(ctx context.Context, o *models.PurchaseOrder) error {
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		stored, err := getOrder(ctx, conn, o.ID, true)
+		if err != nil {
+			return err
+		}
+		if err := checkDraft(stored, "edit"); err != nil {
+			return err
+		}
+		if err := conn.QueryRow(ctx, `UPDATE purchase_orders SET notes = $1, updated_at = now() WHERE id = $2
+	RETURNING supplier_id, status, currency, created_at, updated_at`, o.Notes, o.ID).
+			Scan(&o.SupplierID, &o.Status, &o.Currency, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return err
+		}
+		if _, err := conn.Exec(ctx, `DELETE FROM purchase_order_lines WHERE order_id = $1`, o.ID); err != nil {
+			return err
+		}
+		return insertLines(ctx, conn, o)
+	})
+	if err != nil && !isOrderError(err) {
+		return errors.New("failed to update purchase order: " + err.Error())
+	}
+	return err
+}
+
+func (r *Repo) SendOrder(ctx context.Context, id int64, leadTimeDays int) (*models.PurchaseOrder, error) {
+	return r.transition(ctx, id, "send", func(ctx context.Context, conn db.Conn, o *models.PurchaseOrder) error {
+		if err := checkDraft(o, "send"); err != nil {
+			return err
+		}
+		return conn.QueryRow(ctx, `
+	UPDATE purchase_orders SET status = $1, sent_at = now(), expected_at = now() + make_interval(days => $2),
+	    updated_at = now() WHERE id = $3
+	RETURNING status, sent_at, expected_at, updated_at`, models.PurchaseSent, leadTimeDays, id).
+			Scan(&o.Status, &o.SentAt, &o.ExpectedAt, &o.UpdatedAt)
+	})
+}
+
+func (r *Repo) CancelOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error) {
+	return r.transition(ctx, id, "cancel", func(ctx context.Context, conn db.Conn, o *models.PurchaseOrder) error {
+		if err := checkCancel(o); err != nil {
+			return err
+		}
+		return conn.QueryRow(ctx, `UPDATE purchase_orders SET status = $1, updated_at = now() WHERE id = $2
+	RETURNING status, updated_at`, models.PurchaseCancelled, id).Scan(&o.Status, &o.UpdatedAt)
+	})
+}
+
+func (r *Repo) ReceiveOrder(ctx context.Context, id int64, lines []models.ReceiptLine) (*models.PurchaseOrder, error) {
+	return r.transition(ctx, id, "receive", func(ctx context.Context, conn db.Conn, o *models.PurchaseOrder) error {
+		// the time of the transaction, as now() stores it in the timestamp columns
+		var at time.Time
+		if err := conn.QueryRow(ctx, `SELECT localtimestamp`).Scan(&at); err != nil {
+			return err
+		}
+		if err := receive(o, lines, at); err != nil {
+			return err
+		}
+		for _, rl := range lines {
+			if _, err := conn.Exec(ctx, `UPDATE purchase_order_lines SET received_quantity = received_quantity + $1
+	WHERE id = $2`, rl.Quantity, rl.LineID); err != nil {
+				return err
+			}
+		}
+		_, err := conn.Exec(ctx, `UPDATE purchase_orders SET status = $1, updated_at = $2, received_at = $3 WHERE id = $4`,
+			o.Status, o.UpdatedAt, o.ReceivedAt, id)
+		return err
+	})
+}
+
+// transition locks an order in a transaction, which it joins if ctx has one,
+// and lets fn change it.
+func (r *Repo) transition(ctx context.Context, id int64, action string,
+	fn func(ctx context.Context, conn db.Conn, o *models.PurchaseOrder) error) (*models.PurchaseOrder, error) {
+	var o *models.PurchaseOrder
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		var err error
+		if o, err = getOrder(ctx, conn, id, true); err != nil {
+			return err
+		}
+		return fn(ctx, conn, o)
+	})
+	if err != nil {
+		if isOrderError(err) {
+			return nil, err
+		}
+		return nil, errors.New("failed to " + action + " purchase order: " + err.Error())
+	}
+	return o, nil
+}
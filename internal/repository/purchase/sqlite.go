@@ -0,0 +1,383 @@
+package purchase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"strings"
+)
+
+// SQLiteRepo is the purchasing store for SQLite.
+type SQLiteRepo struct {
+	db *db.SQLite
+}
+
+func NewSQLiteRepo(db *db.SQLite) *SQLiteRepo {
+	return &SQLiteRepo{db: db}
+}
+
+func (r *SQLiteRepo) CreateSupplier(ctx context.Context, s *models.Supplier) error {
+	ts := db.Now()
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `
+	INSERT INTO suppliers(name, email, currency, created_at, updated_at) VALUES (?, ?, ?, ?, ?)
+	RETURNING id, created_at, updated_at`, s.Name, s.Email, s.Currency, ts, ts).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+	})
+	if err != nil {
+		return errors.New("failed to create supplier: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetSupplier(ctx context.Context, id int64) (*models.Supplier, error) {
+	var s *models.Supplier
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		var err error
+		s, err = scanSupplier(r.db.QueryRowContext(ctx, `SELECT `+supplierColumns+` FROM suppliers WHERE id = ?`, id))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("supplier not found", "id", id)
+			return nil, ErrSupplierNotFound
+		}
+		return nil, errors.New("failed to get supplier: " + err.Error())
+	}
+	return s, nil
+}
+
+func (r *SQLiteRepo) GetSuppliers(ctx context.Context) ([]*models.Supplier, error) {
+	var suppliers []*models.Supplier
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `SELECT `+supplierColumns+` FROM suppliers ORDER BY id`)
+		if err != nil {
+			return fmt.Errorf("failed to get suppliers: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			s, err := scanSupplier(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan suppliers: %w", err)
+			}
+			suppliers = append(suppliers, s)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return suppliers, nil
+}
+
+func (r *SQLiteRepo) UpdateSupplier(ctx context.Context, s *models.Supplier) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `
+	UPDATE suppliers SET name = ?, email = ?, currency = ?, updated_at = ? WHERE id = ?
+	RETURNING created_at, updated_at`, s.Name, s.Email, s.Currency, db.Now(), s.ID).Scan(&s.CreatedAt, &s.UpdatedAt)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		logging.For(ctx, logPackage).Debug("supplier not found", "id", s.ID)
+		return ErrSupplierNotFound
+	}
+	if err != nil {
+		return errors.New("failed to update supplier: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) DeleteSupplier(ctx context.Context, id int64) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		res, err := r.db.ExecContext(ctx, `DELETE FROM suppliers WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		rows, err = res.RowsAffected()
+		return err
+	})
+	switch {
+	case err != nil && strings.Contains(err.Error(), "FOREIGN KEY constraint failed"):
+		return ErrSupplierInUse
+	case err != nil:
+		return errors.New("failed to delete supplier: " + err.Error())
+	case rows == 0:
+		return ErrSupplierNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) SetSupplierProduct(ctx context.Context, sp *models.SupplierProduct) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `
+	INSERT INTO supplier_products(supplier_id, product_id, sku, cost, currency, lead_time_days, updated_at)
+	SELECT id, ?, ?, ?, ?, ?, ? FROM suppliers WHERE id = ?
+	ON CONFLICT (supplier_id, product_id) DO UPDATE SET sku = excluded.sku, cost = excluded.cost,
+	    currency = excluded.currency, lead_time_days = excluded.lead_time_days, updated_at = excluded.updated_at
+	RETURNING updated_at`, sp.ProductID, sp.SKU, sp.Cost.Amount, sp.Cost.Currency, sp.LeadTimeDays, db.Now(), sp.SupplierID).
+			Scan(&sp.UpdatedAt)
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrSupplierNotFound
+	case err != nil && strings.Contains(err.Error(), "FOREIGN KEY constraint failed"):
+		return ErrProductNotFound
+	case err != nil:
+		return errors.New("failed to set supplier product: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetSupplierProducts(ctx context.Context, supplierID int64) ([]*models.SupplierProduct, error) {
+	var links []*models.SupplierProduct
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `SELECT `+linkColumns+` FROM supplier_products WHERE supplier_id = ? ORDER BY product_id`,
+			supplierID)
+		if err != nil {
+			return fmt.Errorf("failed to get supplier products: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			sp, err := scanLink(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan supplier products: %w", err)
+			}
+			links = append(links, sp)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *SQLiteRepo) DeleteSupplierProduct(ctx context.Context, supplierID, productID int64) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		res, err := r.db.ExecContext(ctx, `DELETE FROM supplier_products WHERE supplier_id = ? AND product_id = ?`,
+			supplierID, productID)
+		if err != nil {
+			return err
+		}
+		rows, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete supplier product: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrSupplierProductNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) CreateOrder(ctx context.Context, o *models.PurchaseOrder) error {
+	ts := db.Now()
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		o.Status = models.PurchaseDraft
+		if err := conn.QueryRowContext(ctx, `
+	INSERT INTO purchase_orders(supplier_id, status, currency, notes, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+	RETURNING id, created_at, updated_at`, o.SupplierID, o.Status, o.Currency, o.Notes, ts, ts).
+			Scan(&o.ID, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return err
+		}
+		return sqliteInsertLines(ctx, conn, o)
+	})
+	if err != nil {
+		return errors.New("failed to create purchase order: " + err.Error())
+	}
+	return nil
+}
+
+func sqliteInsertLines(ctx context.Context, conn db.SQLConn, o *models.PurchaseOrder) error {
+	for _, l := range o.Lines {
+		if err := conn.QueryRowContext(ctx, `
+	INSERT INTO purchase_order_lines(order_id, product_id, quantity, cost) VALUES (?, ?, ?, ?) RETURNING id`,
+			o.ID, l.ProductID, l.Quantity, l.Cost.Amount).Scan(&l.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error) {
+	var o *models.PurchaseOrder
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		var err error
+		o, err = sqliteGetOrder(ctx, r.db.Conn(ctx), id)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrOrderNotFound) {
+			logging.For(ctx, logPackage).Debug("purchase order not found", "id", id)
+			return nil, err
+		}
+		return nil, errors.New("failed to get purchase order: " + err.Error())
+	}
+	return o, nil
+}
+
+func sqliteGetOrder(ctx context.Context, conn db.SQLConn, id int64) (*models.PurchaseOrder, error) {
+	o, err := scanOrder(conn.QueryRowContext(ctx, `SELECT `+orderColumns+` FROM purchase_orders WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	orders := map[int64]*models.PurchaseOrder{o.ID: o}
+	if err := sqliteGetLines(ctx, conn, orders, `WHERE order_id = ?`, id); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func sqliteGetLines(ctx context.Context, conn db.SQLConn, orders map[int64]*models.PurchaseOrder, where string, args ...any) error {
+	rows, err := conn.QueryContext(ctx, `SELECT `+lineColumns+` FROM purchase_order_lines `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get purchase order lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		orderID, l, err := scanLine(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan purchase order lines: %w", err)
+		}
+		attachLine(orders, orderID, l)
+	}
+	return rows.Err()
+}
+
+func (r *SQLiteRepo) GetOrders(ctx context.Context, f models.PurchaseOrderFilter) ([]*models.PurchaseOrder, error) {
+	const where = `(? = 0 OR supplier_id = ?) AND (? = '' OR status = ?)`
+	args := []any{f.SupplierID, f.SupplierID, f.Status, f.Status}
+	var orders []*models.PurchaseOrder
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `SELECT `+orderColumns+` FROM purchase_orders WHERE `+where+` ORDER BY id DESC`,
+			args...)
+		if err != nil {
+			return fmt.Errorf("failed to get purchase orders: %w", err)
+		}
+		defer rows.Close()
+
+		byID := make(map[int64]*models.PurchaseOrder)
+		for rows.Next() {
+			o, err := scanOrder(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan purchase orders: %w", err)
+			}
+			orders = append(orders, o)
+			byID[o.ID] = o
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+		return sqliteGetLines(ctx, r.db, byID, `WHERE order_id IN (SELECT id FROM purchase_orders WHERE `+where+`)`, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *SQLiteRepo) UpdateOrder(ctx context.Context, o *models.PurchaseOrder) error {
+	_, err := r.transition(ctx, o.ID, "update", func(ctx context.Context, conn db.SQLConn, stored *models.PurchaseOrder) error {
+		if err := checkDraft(stored, "edit"); err != nil {
+			return err
+		}
+		if err := conn.QueryRowContext(ctx, `UPDATE purchase_orders SET notes = ?, updated_at = ? WHERE id = ?
+	RETURNING supplier_id, status, currency, created_at, updated_at`, o.Notes, stored.UpdatedAt, o.ID).
+			Scan(&o.SupplierID, &o.Status, &o.Currency, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, `DELETE FROM purchase_order_lines WHERE order_id = ?`, o.ID); err != nil {
+			return err
+		}
+		return sqliteInsertLines(ctx, conn, o)
+	})
+	return err
+}
+
+func (r *SQLiteRepo) SendOrder(ctx context.Context, id int64, leadTimeDays int) (*models.PurchaseOrder, error) {
+	return r.transition(ctx, id, "send", func(ctx context.Context, conn db.SQLConn, o *models.PurchaseOrder) error {
+		if err := checkDraft(o, "send"); err != nil {
+			return err
+		}
+		sentAt := o.UpdatedAt
+		expectedAt := sentAt.AddDate(0, 0, leadTimeDays)
+		o.Status, o.SentAt, o.ExpectedAt = models.PurchaseSent, &sentAt, &expectedAt
+		_, err := conn.ExecContext(ctx, `UPDATE purchase_orders SET status = ?, sent_at = ?, expected_at = ? WHERE id = ?`,
+			o.Status, sentAt, expectedAt, id)
+		return err
+	})
+}
+
+func (r *SQLiteRepo) CancelOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error) {
+	return r.transition(ctx, id, "cancel", func(ctx context.Context, conn db.SQLConn, o *models.PurchaseOrder) error {
+		if err := checkCancel(o); err != nil {
+			return err
+		}
+		o.Status = models.PurchaseCancelled
+		_, err := conn.ExecContext(ctx, `UPDATE purchase_orders SET status = ? WHERE id = ?`, o.Status, id)
+		return err
+	})
+}
+
+func (r *SQLiteRepo) ReceiveOrder(ctx context.Context, id int64, lines []models.ReceiptLine) (*models.PurchaseOrder, error) {
+	return r.transition(ctx, id, "receive", func(ctx context.Context, conn db.SQLConn, o *models.PurchaseOrder) error {
+		if err := receive(o, lines, o.UpdatedAt); err != nil {
+			return err
+		}
+		for _, rl := range lines {
+			if _, err := conn.ExecContext(ctx, `UPDATE purchase_order_lines SET received_quantity = received_quantity + ?
+	WHERE id = ?`, rl.Quantity, rl.LineID); err != nil {
+				return err
+			}
+		}
+		_, err := conn.ExecContext(ctx, `UPDATE purchase_orders SET status = ?, received_at = ? WHERE id = ?`,
+			o.Status, o.ReceivedAt, id)
+		return err
+	})
+}
+
+// transition touches the order first, so the transaction takes the write
+// lock before it reads, then lets fn change the order whose UpdatedAt is the
+// time of the change.
+func (r *SQLiteRepo) transition(ctx context.Context, id int64, action string,
+	fn func(ctx context.Context, conn db.SQLConn, o *models.PurchaseOrder) error) (*models.PurchaseOrder, error) {
+	var o *models.PurchaseOrder
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		res, err := conn.ExecContext(ctx, `UPDATE purchase_orders SET updated_at = ? WHERE id = ?`, db.Now(), id)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrOrderNotFound
+		}
+		if o, err = sqliteGetOrder(ctx, conn, id); err != nil {
+			return err
+		}
+		return fn(ctx, conn, o)
+	})
+	if err != nil {
+		if isOrderError(err) {
+			return nil, err
+		}
+		return nil, errors.New("failed to " + action + " purchase order: " + err.Error())
+	}
+	return o, nil
+}
@@ -0,0 +1,78 @@
+package purchase_test
+
+import (
+	"context"
+	"errors"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/purchase"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/db/sqlitetest"
+	"prodcrud/pkg/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) (purchase.Repository, product.Repository) {
+		conn := sqlitetest.New(t)
+		return purchase.NewSQLiteRepo(conn), product.NewSQLiteRepo(conn)
+	})
+}
+
+func TestSQLiteRepo_ReceiveOrder_sharedTransaction(t *testing.T) {
+	conn := sqlitetest.New(t)
+	checkSharedTransaction(t, conn, purchase.NewSQLiteRepo(conn), product.NewSQLiteRepo(conn))
+}
+
+// checkSharedTransaction receives an order and adds the stock in one
+// transaction, which rolls back both when the stock update fails.
+func checkSharedTransaction(t *testing.T, tx db.Transactor, repo purchase.Repository, products product.Repository) {
+	ctx := context.Background()
+	p := &models.Product{Name: "pen", Price: money.New(250, "EUR"), Quantity: 1, Description: "blue"}
+	require.NoError(t, products.CreateProduct(ctx, p))
+	s := &models.Supplier{Name: "Acme", Currency: "EUR"}
+	require.NoError(t, repo.CreateSupplier(ctx, s))
+	o := &models.PurchaseOrder{SupplierID: s.ID, Currency: "EUR", Lines: []*models.PurchaseOrderLine{
+		{ProductID: p.ID, Quantity: 10, Cost: money.New(100, "EUR")},
+	}}
+	require.NoError(t, repo.CreateOrder(ctx, o))
+	_, err := repo.SendOrder(ctx, o.ID, 3)
+	require.NoError(t, err)
+	receipt := []models.ReceiptLine{{LineID: o.Lines[0].ID, Quantity: 4}}
+
+	failed := errors.New("failed")
+	err = tx.InTx(ctx, func(ctx context.Context) error {
+		if _, err := repo.ReceiveOrder(ctx, o.ID, receipt); err != nil {
+			return err
+		}
+		if _, err := products.AdjustQuantity(ctx, p.ID, 4); err != nil {
+			return err
+		}
+		return failed
+	})
+	require.ErrorIs(t, err, failed)
+	got, err := repo.GetOrder(ctx, o.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PurchaseSent, got.Status)
+	assert.Zero(t, got.Lines[0].Received)
+	stored, err := products.GetProduct(ctx, p.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.Quantity)
+
+	require.NoError(t, tx.InTx(ctx, func(ctx context.Context) error {
+		if _, err := repo.ReceiveOrder(ctx, o.ID, receipt); err != nil {
+			return err
+		}
+		_, err := products.AdjustQuantity(ctx, p.ID, 4)
+		return err
+	}))
+	got, err = repo.GetOrder(ctx, o.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PurchasePartiallyReceived, got.Status)
+	stored, err = products.GetProduct(ctx, p.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, 5, stored.Quantity)
+}
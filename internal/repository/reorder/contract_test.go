@@ -0,0 +1,150 @@
+package reorder_test
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/purchase"
+	"prodcrud/internal/repository/reorder"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runContract checks the behaviour every reorder.Repository shares. newRepo
+// returns the reorder repository with the product repository of the same
+// store, whose stock it reads, and the purchase repository the drafts of
+// the alerts are stored in.
+func runContract(t *testing.T,
+	newRepo func(t *testing.T) (reorder.Repository, product.Repository, purchase.Repository)) {
+	ctx := context.Background()
+
+	newProduct := func(t *testing.T, products product.Repository, name string, quantity int) int64 {
+		t.Helper()
+		p := &models.Product{Name: name, Price: money.New(250, "EUR"), Quantity: quantity, Description: name}
+		require.NoError(t, products.CreateProduct(ctx, p))
+		return p.ID
+	}
+	setStock := func(t *testing.T, products product.Repository, id int64, quantity int) {
+		t.Helper()
+		p, err := products.GetProduct(ctx, id, false)
+		require.NoError(t, err)
+		_, err = products.AdjustQuantity(ctx, id, quantity-p.Quantity)
+		require.NoError(t, err)
+	}
+
+	t.Run("rules", func(t *testing.T) {
+		repo, products, _ := newRepo(t)
+		pen := newProduct(t, products, "pen", 10)
+
+		_, err := repo.GetRule(ctx, pen)
+		assert.ErrorIs(t, err, reorder.ErrRuleNotFound)
+		rule := &models.ReorderRule{ProductID: pen, ReorderPoint: 5, ReorderQuantity: 50}
+		require.NoError(t, repo.SetRule(ctx, rule))
+		assert.False(t, rule.UpdatedAt.IsZero())
+		got, err := repo.GetRule(ctx, pen)
+		require.NoError(t, err)
+		assert.Equal(t, rule, got)
+
+		time.Sleep(time.Millisecond)
+		replaced := &models.ReorderRule{ProductID: pen, ReorderPoint: 0, ReorderQuantity: 20}
+		require.NoError(t, repo.SetRule(ctx, replaced))
+		assert.True(t, replaced.UpdatedAt.After(rule.UpdatedAt))
+		got, err = repo.GetRule(ctx, pen)
+		require.NoError(t, err)
+		assert.Equal(t, replaced, got)
+
+		assert.ErrorIs(t, repo.SetRule(ctx, &models.ReorderRule{ProductID: 99, ReorderQuantity: 1}),
+			reorder.ErrProductNotFound)
+		require.NoError(t, products.DeleteProduct(ctx, pen))
+		assert.ErrorIs(t, repo.SetRule(ctx, replaced), reorder.ErrProductNotFound, "the product is soft deleted")
+
+		require.NoError(t, repo.DeleteRule(ctx, pen))
+		assert.ErrorIs(t, repo.DeleteRule(ctx, pen), reorder.ErrRuleNotFound)
+		_, err = repo.GetRule(ctx, pen)
+		assert.ErrorIs(t, err, reorder.ErrRuleNotFound)
+	})
+
+	t.Run("alerts", func(t *testing.T) {
+		repo, products, purchases := newRepo(t)
+		pen := newProduct(t, products, "pen", 10)
+		ink := newProduct(t, products, "ink", 2)
+		newProduct(t, products, "cap", 1)
+		for _, rule := range []*models.ReorderRule{
+			{ProductID: pen, ReorderPoint: 5, ReorderQuantity: 50},
+			{ProductID: ink, ReorderPoint: 2, ReorderQuantity: 10},
+		} {
+			require.NoError(t, repo.SetRule(ctx, rule))
+		}
+
+		low, err := repo.GetLowStock(ctx)
+		require.NoError(t, err)
+		require.Len(t, low, 1, "at or below the point, and cap has no rule")
+		assert.Equal(t, &models.LowStockProduct{
+			ProductID: ink, Name: "ink", Quantity: 2, ReorderPoint: 2, ReorderQuantity: 10,
+		}, low[0])
+
+		setStock(t, products, pen, 4)
+		flagged, err := repo.FlagAlerts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{pen, ink}, flagged)
+		flagged, err = repo.FlagAlerts(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, flagged, "an alert is raised once")
+
+		s := &models.Supplier{Name: "Acme", Currency: "EUR"}
+		require.NoError(t, purchases.CreateSupplier(ctx, s))
+		draft := &models.PurchaseOrder{SupplierID: s.ID, Currency: "EUR", Lines: []*models.PurchaseOrderLine{
+			{ProductID: pen, Quantity: 50, Cost: money.New(100, "EUR")},
+		}}
+		require.NoError(t, purchases.CreateOrder(ctx, draft))
+		require.NoError(t, repo.SetAlertOrder(ctx, pen, draft.ID))
+		require.NoError(t, repo.MarkNotified(ctx, []int64{pen}))
+		require.NoError(t, repo.MarkNotified(ctx, nil))
+		low, err = repo.GetLowStock(ctx)
+		require.NoError(t, err)
+		require.Len(t, low, 2)
+		assert.Equal(t, pen, low[0].ProductID)
+		assert.Equal(t, 4, low[0].Quantity)
+		require.NotNil(t, low[0].FlaggedAt)
+		require.NotNil(t, low[0].NotifiedAt)
+		assert.False(t, low[0].NotifiedAt.Before(*low[0].FlaggedAt))
+		assert.Equal(t, draft.ID, *low[0].PurchaseOrderID)
+		assert.NotNil(t, low[1].FlaggedAt)
+		assert.Nil(t, low[1].NotifiedAt)
+		assert.Nil(t, low[1].PurchaseOrderID)
+
+		resolved, err := repo.ResolveAlerts(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, resolved)
+		setStock(t, products, pen, 6)
+		require.NoError(t, repo.DeleteRule(ctx, ink))
+		resolved, err = repo.ResolveAlerts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{pen, ink}, resolved, "restocked above the point, and without a rule")
+		low, err = repo.GetLowStock(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, low)
+
+		setStock(t, products, pen, 0)
+		flagged, err = repo.FlagAlerts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{pen}, flagged, "a new shortage raises a new alert")
+		low, err = repo.GetLowStock(ctx)
+		require.NoError(t, err)
+		require.Len(t, low, 1)
+		assert.Nil(t, low[0].NotifiedAt)
+		assert.Nil(t, low[0].PurchaseOrderID)
+
+		require.NoError(t, products.DeleteProduct(ctx, pen))
+		low, err = repo.GetLowStock(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, low, "soft deleted products are left out")
+		resolved, err = repo.ResolveAlerts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{pen}, resolved)
+	})
+}
@@ -0,0 +1,184 @@
+package reorder
+
+import (
+	"context"
+	"errors"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/db"
+	"slices"
+	"sync"
+)
+
+// MemoryRepo keeps reorder rules and alerts in memory, for tests and local
+// demos. It reads the stock from the product repository of the same store,
+// and does not check that the supplier of a rule exists.
+type MemoryRepo struct {
+	products product.Repository
+
+	mu     sync.RWMutex
+	rules  map[int64]*models.ReorderRule
+	alerts map[int64]*models.LowStockProduct
+}
+
+func NewMemoryRepo(products product.Repository) *MemoryRepo {
+	return &MemoryRepo{
+		products: products,
+		rules:    make(map[int64]*models.ReorderRule),
+		alerts:   make(map[int64]*models.LowStockProduct),
+	}
+}
+
+func (r *MemoryRepo) SetRule(ctx context.Context, rule *models.ReorderRule) error {
+	if _, err := r.products.GetProduct(ctx, rule.ProductID, false); err != nil {
+		if errors.Is(err, product.ErrNotFound) {
+			return ErrProductNotFound
+		}
+		return err //nolint:wrapcheck //wrapped by the product repository
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rule.UpdatedAt = db.Now()
+	cp := *rule
+	r.rules[rule.ProductID] = &cp
+	return nil
+}
+
+func (r *MemoryRepo) GetRule(_ context.Context, productID int64) (*models.ReorderRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rule, ok := r.rules[productID]
+	if !ok {
+		return nil, ErrRuleNotFound
+	}
+	cp := *rule
+	return &cp, nil
+}
+
+func (r *MemoryRepo) DeleteRule(_ context.Context, productID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rules[productID]; !ok {
+		return ErrRuleNotFound
+	}
+	delete(r.rules, productID)
+	return nil
+}
+
+// lowStock lists the products at or below the point of their rule, without
+// their alert.
+func (r *MemoryRepo) lowStock(ctx context.Context) ([]*models.LowStockProduct, error) {
+	r.mu.RLock()
+	rules := make([]models.ReorderRule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		rules = append(rules, *rule)
+	}
+	r.mu.RUnlock()
+	slices.SortFunc(rules, func(a, b models.ReorderRule) int { return int(a.ProductID - b.ProductID) })
+
+	var low []*models.LowStockProduct
+	for _, rule := range rules {
+		p, err := r.products.GetProduct(ctx, rule.ProductID, false)
+		if errors.Is(err, product.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err //nolint:wrapcheck //wrapped by the product repository
+		}
+		if p.Quantity <= rule.ReorderPoint {
+			low = append(low, &models.LowStockProduct{
+				ProductID: p.ID, Name: p.Name, Quantity: p.Quantity, ReorderPoint: rule.ReorderPoint,
+				ReorderQuantity: rule.ReorderQuantity, SupplierID: rule.SupplierID,
+			})
+		}
+	}
+	return low, nil
+}
+
+func (r *MemoryRepo) GetLowStock(ctx context.Context) ([]*models.LowStockProduct, error) {
+	low, err := r.lowStock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range low {
+		if a, ok := r.alerts[p.ProductID]; ok {
+			p.FlaggedAt, p.NotifiedAt, p.PurchaseOrderID = copyOf(a.FlaggedAt), copyOf(a.NotifiedAt),
+				copyOf(a.PurchaseOrderID)
+		}
+	}
+	return low, nil
+}
+
+func copyOf[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	cp := *v
+	return &cp
+}
+
+func (r *MemoryRepo) FlagAlerts(ctx context.Context) ([]int64, error) {
+	low, err := r.lowStock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []int64
+	ts := db.Now()
+	for _, p := range low {
+		if _, ok := r.alerts[p.ProductID]; !ok {
+			r.alerts[p.ProductID] = &models.LowStockProduct{ProductID: p.ProductID, FlaggedAt: &ts}
+			ids = append(ids, p.ProductID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *MemoryRepo) ResolveAlerts(ctx context.Context) ([]int64, error) {
+	low, err := r.lowStock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []int64
+	for id := range r.alerts {
+		if !slices.ContainsFunc(low, func(p *models.LowStockProduct) bool { return p.ProductID == id }) {
+			delete(r.alerts, id)
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (r *MemoryRepo) SetAlertOrder(_ context.Context, productID, orderID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if a, ok := r.alerts[productID]; ok {
+		a.PurchaseOrderID = &orderID
+	}
+	return nil
+}
+
+func (r *MemoryRepo) MarkNotified(_ context.Context, productIDs []int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ts := db.Now()
+	for _, id := range productIDs {
+		if a, ok := r.alerts[id]; ok {
+			a.NotifiedAt = &ts
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package reorder_test
+
+import (
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/purchase"
+	"prodcrud/internal/repository/reorder"
+	"testing"
+)
+
+func TestMemoryRepo_Contract(t *testing.T) {
+	runContract(t, func(*testing.T) (reorder.Repository, product.Repository, purchase.Repository) {
+		products := product.NewMemoryRepo()
+		return reorder.NewMemoryRepo(products), products, purchase.NewMemoryRepo()
+	})
+}
@@ -0,0 +1,23 @@
+package reorder_test
+
+import (
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/purchase"
+	"prodcrud/internal/repository/reorder"
+	"prodcrud/pkg/db/pgtest"
+	"testing"
+)
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+func TestRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) (reorder.Repository, product.Repository, purchase.Repository) {
+		pool := pgtest.New(t)
+		return reorder.NewRepo(pool), product.NewRepo(pool), purchase.NewRepo(pool)
+	})
+}
+
+func TestRepo_SetRule_supplier(t *testing.T) {
+	pool := pgtest.New(t)
+	checkSupplier(t, reorder.NewRepo(pool), product.NewRepo(pool), purchase.NewRepo(pool))
+}
@@ -0,0 +1,249 @@
+package reorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Repository stores the reorder rules of the products and the alerts raised
+// when a product falls to its reorder point. An alert lasts until the
+// product is restocked above the point, so that it is raised, and its
+// notification sent, once per shortage.
+type Repository interface {
+	// SetRule creates or replaces the rule of an active product and sets
+	// r.UpdatedAt. It returns ErrProductNotFound for a missing or soft
+	// deleted product, and ErrSupplierNotFound for a missing supplier.
+	SetRule(ctx context.Context, r *models.ReorderRule) error
+	GetRule(ctx context.Context, productID int64) (*models.ReorderRule, error)
+	// DeleteRule removes the rule of a product; ResolveAlerts clears its
+	// alert.
+	DeleteRule(ctx context.Context, productID int64) error
+	// GetLowStock lists the active products at or below their reorder point
+	// by id, with their alert if it is raised.
+	GetLowStock(ctx context.Context) ([]*models.LowStockProduct, error)
+	// FlagAlerts raises an alert, flagged now, for every product of
+	// GetLowStock without one, and returns their ids by id. Of concurrent
+	// callers, a single one raises a given alert.
+	FlagAlerts(ctx context.Context) ([]int64, error)
+	// ResolveAlerts clears the alerts of the products that are no longer low
+	// on stock, soft deleted ones and ones without a rule included, and
+	// returns their ids by id.
+	ResolveAlerts(ctx context.Context) ([]int64, error)
+	// SetAlertOrder records the purchase order drafted for the alert of a
+	// product.
+	SetAlertOrder(ctx context.Context, productID, orderID int64) error
+	// MarkNotified sets the delivery time of the alerts of the products to
+	// now.
+	MarkNotified(ctx context.Context, productIDs []int64) error
+}
+
+const logPackage = "repository/reorder"
+
+var (
+	// ErrRuleNotFound is returned when the product has no reorder rule.
+	ErrRuleNotFound = errors.New("reorder rule not found")
+	// ErrProductNotFound is returned when setting the rule of a product that
+	// does not exist or is soft deleted.
+	ErrProductNotFound = errors.New("product not found")
+	// ErrSupplierNotFound is returned when the supplier of a rule does not
+	// exist.
+	ErrSupplierNotFound = errors.New("supplier not found")
+)
+
+const (
+	ruleColumns = `product_id, reorder_point, reorder_quantity, supplier_id, updated_at`
+	// lowStockQuery selects the active products at or below their reorder
+	// point, the columns read by scanLowStock with the alert table a joined.
+	lowStockQuery = `
+	SELECT p.id, p.name, p.quantity, r.reorder_point, r.reorder_quantity, r.supplier_id,
+	    a.flagged_at, a.notified_at, a.purchase_order_id
+	FROM reorder_rules r JOIN products p ON p.id = r.product_id
+	LEFT JOIN stock_alerts a ON a.product_id = r.product_id
+	WHERE p.deleted_at IS NULL AND p.quantity <= r.reorder_point
+	ORDER BY p.id`
+	// lowStockCondition holds for the product of the alert table a when it is
+	// still low on stock.
+	lowStockCondition = `EXISTS (SELECT 1 FROM reorder_rules r JOIN products p ON p.id = r.product_id
+	    WHERE r.product_id = a.product_id AND p.deleted_at IS NULL AND p.quantity <= r.reorder_point)`
+)
+
+// scanner is a row of either driver.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRule(row scanner) (*models.ReorderRule, error) {
+	var r models.ReorderRule
+	if err := row.Scan(&r.ProductID, &r.ReorderPoint, &r.ReorderQuantity, &r.SupplierID, &r.UpdatedAt); err != nil {
+		return nil, err //nolint:wrapcheck //wrapped by the callers
+	}
+	return &r, nil
+}
+
+func scanLowStock(row scanner) (*models.LowStockProduct, error) {
+	var p models.LowStockProduct
+	if err := row.Scan(&p.ProductID, &p.Name, &p.Quantity, &p.ReorderPoint, &p.ReorderQuantity, &p.SupplierID,
+		&p.FlaggedAt, &p.NotifiedAt, &p.PurchaseOrderID); err != nil {
+		return nil, err //nolint:wrapcheck //wrapped by the callers
+	}
+	return &p, nil
+}
+
+type Repo struct {
+	db *db.DB
+}
+
+func NewRepo(db *db.DB) *Repo {
+	return &Repo{db: db}
+}
+
+func (r *Repo) SetRule(ctx context.Context, rule *models.ReorderRule) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRow(ctx, `
+	INSERT INTO reorder_rules(product_id, reorder_point, reorder_quantity, supplier_id)
+	SELECT id, $2, $3, $4 FROM products WHERE id = $1 AND deleted_at IS NULL
+	ON CONFLICT (product_id) DO UPDATE SET reorder_point = excluded.reorder_point,
+	    reorder_quantity = excluded.reorder_quantity, supplier_id = excluded.supplier_id, updated_at = now()
+	RETURNING updated_at`, rule.ProductID, rule.ReorderPoint, rule.ReorderQuantity, rule.SupplierID).
+			Scan(&rule.UpdatedAt)
+	})
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return ErrProductNotFound
+	case errors.As(err, &pgErr) && pgErr.Code == "23503":
+		return ErrSupplierNotFound
+	case err != nil:
+		return errors.New("failed to set reorder rule: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) GetRule(ctx context.Context, productID int64) (*models.ReorderRule, error) {
+	var rule *models.ReorderRule
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		var err error
+		rule, err = scanRule(q.QueryRow(ctx, `SELECT `+ruleColumns+` FROM reorder_rules WHERE product_id = $1`,
+			productID))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("reorder rule not found", "product_id", productID)
+			return nil, ErrRuleNotFound
+		}
+		return nil, errors.New("failed to get reorder rule: " + err.Error())
+	}
+	return rule, nil
+}
+
+func (r *Repo) DeleteRule(ctx context.Context, productID int64) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tag, err := r.db.Exec(ctx, `DELETE FROM reorder_rules WHERE product_id = $1`, productID)
+		rows = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete reorder rule: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+func (r *Repo) GetLowStock(ctx context.Context) ([]*models.LowStockProduct, error) {
+	var products []*models.LowStockProduct
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		products = nil
+		rows, err := q.Query(ctx, lowStockQuery)
+		if err != nil {
+			return fmt.Errorf("failed to get low stock products: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scanLowStock(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan low stock products: %w", err)
+			}
+			products = append(products, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *Repo) FlagAlerts(ctx context.Context) ([]int64, error) {
+	var ids []int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		rows, err := r.db.Query(ctx, `
+	INSERT INTO stock_alerts(product_id)
+	SELECT r.product_id FROM reorder_rules r JOIN products p ON p.id = r.product_id
+	WHERE p.deleted_at IS NULL AND p.quantity <= r.reorder_point
+	ON CONFLICT (product_id) DO NOTHING
+	RETURNING product_id`)
+		if err != nil {
+			return err
+		}
+		ids, err = pgx.CollectRows(rows, pgx.RowTo[int64])
+		return err
+	})
+	if err != nil {
+		return nil, errors.New("failed to flag stock alerts: " + err.Error())
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (r *Repo) ResolveAlerts(ctx context.Context) ([]int64, error) {
+	var ids []int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		rows, err := r.db.Query(ctx, `DELETE FROM stock_alerts a WHERE NOT `+lowStockCondition+` RETURNING a.product_id`)
+		if err != nil {
+			return err
+		}
+		ids, err = pgx.CollectRows(rows, pgx.RowTo[int64])
+		return err
+	})
+	if err != nil {
+		return nil, errors.New("failed to resolve stock alerts: " + err.Error())
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (r *Repo) SetAlertOrder(ctx context.Context, productID, orderID int64) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		_, err := r.db.Exec(ctx, `UPDATE stock_alerts SET purchase_order_id = $2 WHERE product_id = $1`, productID,
+			orderID)
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to set stock alert order: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) MarkNotified(ctx context.Context, productIDs []int64) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		_, err := r.db.Exec(ctx, `UPDATE stock_alerts SET notified_at = now() WHERE product_id = ANY($1)`, productIDs)
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to mark stock alerts notified: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package reorder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"slices"
+	"strings"
+)
+
+// SQLiteRepo is the reorder store for SQLite.
+type SQLiteRepo struct {
+	db *db.SQLite
+}
+
+func NewSQLiteRepo(db *db.SQLite) *SQLiteRepo {
+	return &SQLiteRepo{db: db}
+}
+
+func (r *SQLiteRepo) SetRule(ctx context.Context, rule *models.ReorderRule) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `
+	INSERT INTO reorder_rules(product_id, reorder_point, reorder_quantity, supplier_id, updated_at)
+	SELECT id, ?, ?, ?, ? FROM products WHERE id = ? AND deleted_at IS NULL
+	ON CONFLICT (product_id) DO UPDATE SET reorder_point = excluded.reorder_point,
+	    reorder_quantity = excluded.reorder_quantity, supplier_id = excluded.supplier_id, updated_at = excluded.updated_at
+	RETURNING updated_at`, rule.ReorderPoint, rule.ReorderQuantity, rule.SupplierID, db.Now(), rule.ProductID).
+			Scan(&rule.UpdatedAt)
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrProductNotFound
+	case err != nil && strings.Contains(err.Error(), "FOREIGN KEY constraint failed"):
+		return ErrSupplierNotFound
+	case err != nil:
+		return errors.New("failed to set reorder rule: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetRule(ctx context.Context, productID int64) (*models.ReorderRule, error) {
+	var rule *models.ReorderRule
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		var err error
+		rule, err = scanRule(r.db.QueryRowContext(ctx, `SELECT `+ruleColumns+` FROM reorder_rules WHERE product_id = ?`,
+			productID))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("reorder rule not found", "product_id", productID)
+			return nil, ErrRuleNotFound
+		}
+		return nil, errors.New("failed to get reorder rule: " + err.Error())
+	}
+	return rule, nil
+}
+
+func (r *SQLiteRepo) DeleteRule(ctx context.Context, productID int64) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		res, err := r.db.ExecContext(ctx, `DELETE FROM reorder_rules WHERE product_id = ?`, productID)
+		if err != nil {
+			return err
+		}
+		rows, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete reorder rule: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetLowStock(ctx context.Context) ([]*models.LowStockProduct, error) {
+	var products []*models.LowStockProduct
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, lowStockQuery)
+		if err != nil {
+			return fmt.Errorf("failed to get low stock products: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scanLowStock(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan low stock products: %w", err)
+			}
+			products = append(products, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// queryIDs collects the ids returned by a statement.
+func (r *SQLiteRepo) queryIDs(ctx context.Context, query string, args ...any) ([]int64, error) {
+	var ids []int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		ids = nil
+		rows, err := r.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return rows.Err()
+	})
+	slices.Sort(ids)
+	return ids, err
+}
+
+func (r *SQLiteRepo) FlagAlerts(ctx context.Context) ([]int64, error) {
+	ids, err := r.queryIDs(ctx, `
+	INSERT INTO stock_alerts(product_id, flagged_at)
+	SELECT r.product_id, ? FROM reorder_rules r JOIN products p ON p.id = r.product_id
+	WHERE p.deleted_at IS NULL AND p.quantity <= r.reorder_point
+	ON CONFLICT (product_id) DO NOTHING
+	RETURNING product_id`, db.Now())
+	if err != nil {
+		return nil, errors.New("failed to flag stock alerts: " + err.Error())
+	}
+	return ids, nil
+}
+
+func (r *SQLiteRepo) ResolveAlerts(ctx context.Context) ([]int64, error) {
+	ids, err := r.queryIDs(ctx, `DELETE FROM stock_alerts AS a WHERE NOT `+lowStockCondition+` RETURNING product_id`)
+	if err != nil {
+		return nil, errors.New("failed to resolve stock alerts: " + err.Error())
+	}
+	return ids, nil
+}
+
+func (r *SQLiteRepo) SetAlertOrder(ctx context.Context, productID, orderID int64) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		_, err := r.db.ExecContext(ctx, `UPDATE stock_alerts SET purchase_order_id = ? WHERE product_id = ?`, orderID,
+			productID)
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to set stock alert order: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) MarkNotified(ctx context.Context, productIDs []int64) error {
+	ts := db.Now()
+	err := r.db.InTx(ctx, func(ctx context.Context) error {
+		conn := r.db.Conn(ctx)
+		for _, id := range productIDs {
+			if _, err := conn.ExecContext(ctx, `UPDATE stock_alerts SET notified_at = ? WHERE product_id = ?`, ts,
+				id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.New("failed to mark stock alerts notified: " + err.Error())
+	}
+	return nil
+}
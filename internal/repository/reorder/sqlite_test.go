@@ -0,0 +1,51 @@
+package reorder_test
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/purchase"
+	"prodcrud/internal/repository/reorder"
+	"prodcrud/pkg/db/sqlitetest"
+	"prodcrud/pkg/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) (reorder.Repository, product.Repository, purchase.Repository) {
+		conn := sqlitetest.New(t)
+		return reorder.NewSQLiteRepo(conn), product.NewSQLiteRepo(conn), purchase.NewSQLiteRepo(conn)
+	})
+}
+
+func TestSQLiteRepo_SetRule_supplier(t *testing.T) {
+	conn := sqlitetest.New(t)
+	checkSupplier(t, reorder.NewSQLiteRepo(conn), product.NewSQLiteRepo(conn), purchase.NewSQLiteRepo(conn))
+}
+
+// checkSupplier sets the supplier of a rule, which must exist and is
+// dropped from the rule when it is deleted.
+func checkSupplier(t *testing.T, repo reorder.Repository, products product.Repository, suppliers purchase.Repository) {
+	ctx := context.Background()
+	p := &models.Product{Name: "pen", Price: money.New(250, "EUR"), Quantity: 1, Description: "blue"}
+	require.NoError(t, products.CreateProduct(ctx, p))
+	s := &models.Supplier{Name: "Acme", Currency: "EUR"}
+	require.NoError(t, suppliers.CreateSupplier(ctx, s))
+
+	missing := int64(99)
+	assert.ErrorIs(t, repo.SetRule(ctx, &models.ReorderRule{ProductID: p.ID, ReorderQuantity: 5, SupplierID: &missing}),
+		reorder.ErrSupplierNotFound)
+	require.NoError(t, repo.SetRule(ctx, &models.ReorderRule{ProductID: p.ID, ReorderQuantity: 5, SupplierID: &s.ID}))
+	rule, err := repo.GetRule(ctx, p.ID)
+	require.NoError(t, err)
+	require.NotNil(t, rule.SupplierID)
+	assert.Equal(t, s.ID, *rule.SupplierID)
+
+	require.NoError(t, suppliers.DeleteSupplier(ctx, s.ID))
+	rule, err = repo.GetRule(ctx, p.ID)
+	require.NoError(t, err)
+	assert.Nil(t, rule.SupplierID)
+}
@@ -0,0 +1,131 @@
+package tax_test
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/tax"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	jan  = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	july = time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func germany() *models.TaxJurisdiction {
+	return &models.TaxJurisdiction{Code: "DE", Name: "Germany", PricesIncludeTax: true, Rounding: money.RoundHalfUp}
+}
+
+func rate(class, percent string, from time.Time, to *time.Time) *models.TaxRate {
+	return &models.TaxRate{Jurisdiction: "DE", TaxClass: class, Rate: money.MustParseRate(percent), ValidFrom: from, ValidTo: to}
+}
+
+// runContract checks the behaviour every tax.Repository shares.
+func runContract(t *testing.T, newRepo func(t *testing.T) tax.Repository) {
+	ctx := context.Background()
+
+	t.Run("set, get and replace jurisdictions", func(t *testing.T) {
+		repo := newRepo(t)
+		de := germany()
+		require.NoError(t, repo.SetJurisdiction(ctx, de))
+		assert.False(t, de.UpdatedAt.IsZero())
+		require.NoError(t, repo.SetJurisdiction(ctx, &models.TaxJurisdiction{
+			Code: "US-CA", Name: "California", Rounding: money.RoundHalfEven,
+		}))
+
+		got, err := repo.GetJurisdiction(ctx, "DE")
+		require.NoError(t, err)
+		assert.Equal(t, "Germany", got.Name)
+		assert.True(t, got.PricesIncludeTax)
+		assert.Equal(t, money.RoundHalfUp, got.Rounding)
+
+		de.Name, de.Rounding = "Deutschland", money.RoundDown
+		require.NoError(t, repo.SetJurisdiction(ctx, de))
+		got, err = repo.GetJurisdiction(ctx, "DE")
+		require.NoError(t, err)
+		assert.Equal(t, "Deutschland", got.Name)
+		assert.Equal(t, money.RoundDown, got.Rounding)
+
+		all, err := repo.GetJurisdictions(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, "DE", all[0].Code)
+		assert.Equal(t, money.RoundHalfEven, all[1].Rounding)
+
+		_, err = repo.GetJurisdiction(ctx, "FR")
+		assert.ErrorIs(t, err, tax.ErrJurisdictionNotFound)
+	})
+
+	t.Run("rates by validity", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.SetJurisdiction(ctx, germany()))
+		old := rate(models.TaxClassStandard, "16", jan, &july)
+		current := rate(models.TaxClassStandard, "19", july, nil)
+		reduced := rate(models.TaxClassReduced, "7", jan, nil)
+		for _, r := range []*models.TaxRate{current, reduced, old} {
+			require.NoError(t, repo.AddRate(ctx, r))
+			assert.NotZero(t, r.ID)
+			assert.False(t, r.CreatedAt.IsZero())
+		}
+
+		got, err := repo.GetRate(ctx, "DE", models.TaxClassStandard, july.Add(-time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, "16", got.Rate.String())
+		require.NotNil(t, got.ValidTo)
+		assert.True(t, got.ValidTo.Equal(july))
+
+		got, err = repo.GetRate(ctx, "DE", models.TaxClassStandard, july)
+		require.NoError(t, err)
+		assert.Equal(t, current.ID, got.ID, "valid_to is exclusive")
+		assert.Nil(t, got.ValidTo)
+
+		_, err = repo.GetRate(ctx, "DE", models.TaxClassStandard, jan.Add(-time.Second))
+		assert.ErrorIs(t, err, tax.ErrRateNotFound)
+		_, err = repo.GetRate(ctx, "FR", models.TaxClassStandard, july)
+		assert.ErrorIs(t, err, tax.ErrRateNotFound)
+
+		rates, err := repo.GetRates(ctx, "DE")
+		require.NoError(t, err)
+		require.Len(t, rates, 3)
+		assert.Equal(t, []int64{reduced.ID, old.ID, current.ID}, []int64{rates[0].ID, rates[1].ID, rates[2].ID})
+		assert.Equal(t, "7", rates[0].Rate.String())
+	})
+
+	t.Run("rejects overlapping rates", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.SetJurisdiction(ctx, germany()))
+		require.NoError(t, repo.AddRate(ctx, rate(models.TaxClassStandard, "19", july, nil)))
+
+		assert.ErrorIs(t, repo.AddRate(ctx, rate(models.TaxClassStandard, "16", jan, nil)), tax.ErrRateOverlap)
+		aug := july.AddDate(0, 1, 0)
+		assert.ErrorIs(t, repo.AddRate(ctx, rate(models.TaxClassStandard, "16", jan, &aug)), tax.ErrRateOverlap)
+		assert.NoError(t, repo.AddRate(ctx, rate(models.TaxClassStandard, "16", jan, &july)), "adjacent rates do not overlap")
+		assert.NoError(t, repo.AddRate(ctx, rate(models.TaxClassReduced, "7", jan, nil)), "other classes do not overlap")
+
+		r := rate(models.TaxClassStandard, "19", jan, nil)
+		r.Jurisdiction = "FR"
+		assert.ErrorIs(t, repo.AddRate(ctx, r), tax.ErrJurisdictionNotFound)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		require.NoError(t, repo.SetJurisdiction(ctx, germany()))
+		r := rate(models.TaxClassStandard, "19", jan, nil)
+		require.NoError(t, repo.AddRate(ctx, r))
+		require.NoError(t, repo.DeleteRate(ctx, r.ID))
+		assert.ErrorIs(t, repo.DeleteRate(ctx, r.ID), tax.ErrRateNotFound)
+
+		require.NoError(t, repo.AddRate(ctx, rate(models.TaxClassStandard, "19", jan, nil)))
+		require.NoError(t, repo.DeleteJurisdiction(ctx, "DE"))
+		assert.ErrorIs(t, repo.DeleteJurisdiction(ctx, "DE"), tax.ErrJurisdictionNotFound)
+
+		rates, err := repo.GetRates(ctx, "DE")
+		require.NoError(t, err)
+		assert.Empty(t, rates, "rates go with their jurisdiction")
+	})
+}
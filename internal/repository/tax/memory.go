@@ -0,0 +1,151 @@
+package tax
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryRepo keeps tax jurisdictions and rates in memory, for tests and
+// local demos.
+type MemoryRepo struct {
+	mu            sync.RWMutex
+	jurisdictions map[string]*models.TaxJurisdiction
+	rates         map[int64]*models.TaxRate
+	lastID        int64
+}
+
+func NewMemoryRepo() *MemoryRepo {
+	return &MemoryRepo{
+		jurisdictions: make(map[string]*models.TaxJurisdiction),
+		rates:         make(map[int64]*models.TaxRate),
+	}
+}
+
+// viewRate returns a copy, so callers cannot mutate the stored rate.
+func viewRate(r *models.TaxRate) *models.TaxRate {
+	cp := *r
+	cp.ValidFrom = r.ValidFrom.UTC()
+	if r.ValidTo != nil {
+		validTo := r.ValidTo.UTC()
+		cp.ValidTo = &validTo
+	}
+	return &cp
+}
+
+func (r *MemoryRepo) SetJurisdiction(_ context.Context, j *models.TaxJurisdiction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	j.UpdatedAt = db.Now()
+	stored := *j
+	r.jurisdictions[j.Code] = &stored
+	return nil
+}
+
+func (r *MemoryRepo) GetJurisdiction(_ context.Context, code string) (*models.TaxJurisdiction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	j, ok := r.jurisdictions[code]
+	if !ok {
+		return nil, ErrJurisdictionNotFound
+	}
+	cp := *j
+	return &cp, nil
+}
+
+func (r *MemoryRepo) GetJurisdictions(_ context.Context) ([]*models.TaxJurisdiction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var jurisdictions []*models.TaxJurisdiction
+	for _, j := range r.jurisdictions {
+		cp := *j
+		jurisdictions = append(jurisdictions, &cp)
+	}
+	sort.Slice(jurisdictions, func(i, k int) bool { return jurisdictions[i].Code < jurisdictions[k].Code })
+	return jurisdictions, nil
+}
+
+func (r *MemoryRepo) DeleteJurisdiction(_ context.Context, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.jurisdictions[code]; !ok {
+		return ErrJurisdictionNotFound
+	}
+	delete(r.jurisdictions, code)
+	for id, rate := range r.rates {
+		if rate.Jurisdiction == code {
+			delete(r.rates, id)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepo) AddRate(_ context.Context, rate *models.TaxRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.jurisdictions[rate.Jurisdiction]; !ok {
+		return ErrJurisdictionNotFound
+	}
+	for _, other := range r.rates {
+		if other.Jurisdiction == rate.Jurisdiction && other.TaxClass == rate.TaxClass &&
+			(rate.ValidTo == nil || other.ValidFrom.Before(*rate.ValidTo)) &&
+			(other.ValidTo == nil || other.ValidTo.After(rate.ValidFrom)) {
+			return ErrRateOverlap
+		}
+	}
+	r.lastID++
+	rate.ID = r.lastID
+	rate.CreatedAt = db.Now()
+	r.rates[rate.ID] = viewRate(rate)
+	return nil
+}
+
+func (r *MemoryRepo) GetRates(_ context.Context, jurisdiction string) ([]*models.TaxRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var rates []*models.TaxRate
+	for _, rate := range r.rates {
+		if rate.Jurisdiction == jurisdiction {
+			rates = append(rates, viewRate(rate))
+		}
+	}
+	sort.Slice(rates, func(i, k int) bool {
+		if rates[i].TaxClass != rates[k].TaxClass {
+			return rates[i].TaxClass < rates[k].TaxClass
+		}
+		return rates[i].ValidFrom.Before(rates[k].ValidFrom)
+	})
+	return rates, nil
+}
+
+func (r *MemoryRepo) GetRate(_ context.Context, jurisdiction, class string, at time.Time) (*models.TaxRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, rate := range r.rates {
+		if rate.Jurisdiction == jurisdiction && rate.TaxClass == class && rate.InEffect(at) {
+			return viewRate(rate), nil
+		}
+	}
+	return nil, ErrRateNotFound
+}
+
+func (r *MemoryRepo) DeleteRate(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rates[id]; !ok {
+		return ErrRateNotFound
+	}
+	delete(r.rates, id)
+	return nil
+}
@@ -0,0 +1,12 @@
+package tax_test
+
+import (
+	"prodcrud/internal/repository/tax"
+	"testing"
+)
+
+func TestMemoryRepo_Contract(t *testing.T) {
+	runContract(t, func(*testing.T) tax.Repository {
+		return tax.NewMemoryRepo()
+	})
+}
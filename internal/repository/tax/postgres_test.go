@@ -0,0 +1,15 @@
+package tax_test
+
+import (
+	"prodcrud/internal/repository/tax"
+	"prodcrud/pkg/db/pgtest"
+	"testing"
+)
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+func TestRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) tax.Repository {
+		return tax.NewRepo(pgtest.New(t))
+	})
+}
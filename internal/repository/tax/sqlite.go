@@ -0,0 +1,205 @@
+package tax
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"time"
+)
+
+// SQLiteRepo is the tax store for SQLite, where rates are decimal text.
+type SQLiteRepo struct {
+	db *db.SQLite
+}
+
+func NewSQLiteRepo(db *db.SQLite) *SQLiteRepo {
+	return &SQLiteRepo{db: db}
+}
+
+const sqliteRateColumns = `id, jurisdiction, tax_class, rate, valid_from, valid_to, created_at`
+
+func (r *SQLiteRepo) SetJurisdiction(ctx context.Context, j *models.TaxJurisdiction) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `
+	INSERT INTO tax_jurisdictions(code, name, prices_include_tax, rounding, updated_at) VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT (code) DO UPDATE SET name = excluded.name, prices_include_tax = excluded.prices_include_tax,
+	    rounding = excluded.rounding, updated_at = excluded.updated_at
+	RETURNING updated_at`, j.Code, j.Name, j.PricesIncludeTax, j.Rounding, db.Now()).Scan(&j.UpdatedAt)
+	})
+	if err != nil {
+		return errors.New("failed to set tax jurisdiction: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetJurisdiction(ctx context.Context, code string) (*models.TaxJurisdiction, error) {
+	var j models.TaxJurisdiction
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx, `SELECT code, name, prices_include_tax, rounding, updated_at FROM tax_jurisdictions WHERE code = ?`,
+			code).Scan(&j.Code, &j.Name, &j.PricesIncludeTax, &j.Rounding, &j.UpdatedAt)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("tax jurisdiction not found", "code", code)
+			return nil, ErrJurisdictionNotFound
+		}
+		return nil, errors.New("failed to get tax jurisdiction: " + err.Error())
+	}
+	return &j, nil
+}
+
+func (r *SQLiteRepo) GetJurisdictions(ctx context.Context) ([]*models.TaxJurisdiction, error) {
+	var jurisdictions []*models.TaxJurisdiction
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `SELECT code, name, prices_include_tax, rounding, updated_at FROM tax_jurisdictions ORDER BY code`)
+		if err != nil {
+			return fmt.Errorf("failed to get tax jurisdictions: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var j models.TaxJurisdiction
+			if err := rows.Scan(&j.Code, &j.Name, &j.PricesIncludeTax, &j.Rounding, &j.UpdatedAt); err != nil {
+				return fmt.Errorf("failed to scan tax jurisdictions: %w", err)
+			}
+			jurisdictions = append(jurisdictions, &j)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jurisdictions, nil
+}
+
+func (r *SQLiteRepo) DeleteJurisdiction(ctx context.Context, code string) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		res, err := r.db.ExecContext(ctx, `DELETE FROM tax_jurisdictions WHERE code = ?`, code)
+		if err != nil {
+			return err
+		}
+		rows, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete tax jurisdiction: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrJurisdictionNotFound
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) AddRate(ctx context.Context, rate *models.TaxRate) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		var code string
+		if err := tx.QueryRowContext(ctx, `SELECT code FROM tax_jurisdictions WHERE code = ?`, rate.Jurisdiction).
+			Scan(&code); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrJurisdictionNotFound
+			}
+			return err
+		}
+		validTo := utcOrNil(rate.ValidTo)
+		var overlaps bool
+		if err := tx.QueryRowContext(ctx, `
+	SELECT EXISTS (SELECT 1 FROM tax_rates WHERE jurisdiction = ? AND tax_class = ?
+	    AND (? IS NULL OR valid_from < ?) AND (valid_to IS NULL OR valid_to > ?))`,
+			rate.Jurisdiction, rate.TaxClass, validTo, validTo, rate.ValidFrom.UTC()).Scan(&overlaps); err != nil {
+			return err
+		}
+		if overlaps {
+			return ErrRateOverlap
+		}
+		if err := tx.QueryRowContext(ctx, `
+	INSERT INTO tax_rates(jurisdiction, tax_class, rate, valid_from, valid_to, created_at) VALUES (?, ?, ?, ?, ?, ?)
+	RETURNING id, created_at`, rate.Jurisdiction, rate.TaxClass, rate.Rate.String(), rate.ValidFrom.UTC(), validTo, db.Now()).
+			Scan(&rate.ID, &rate.CreatedAt); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if errors.Is(err, ErrJurisdictionNotFound) || errors.Is(err, ErrRateOverlap) {
+		return err
+	}
+	if err != nil {
+		return errors.New("failed to add tax rate: " + err.Error())
+	}
+	return nil
+}
+
+func (r *SQLiteRepo) GetRates(ctx context.Context, jurisdiction string) ([]*models.TaxRate, error) {
+	var rates []*models.TaxRate
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		rows, err := r.db.QueryContext(ctx, `SELECT `+sqliteRateColumns+` FROM tax_rates WHERE jurisdiction = ? ORDER BY tax_class, valid_from`,
+			jurisdiction)
+		if err != nil {
+			return fmt.Errorf("failed to get tax rates: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			rate, err := scanRate(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan tax rates: %w", err)
+			}
+			rates = append(rates, rate)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *SQLiteRepo) GetRate(ctx context.Context, jurisdiction, class string, at time.Time) (*models.TaxRate, error) {
+	var rate *models.TaxRate
+	at = at.UTC()
+	err := r.db.Read(ctx, func(ctx context.Context) error {
+		var err error
+		rate, err = scanRate(r.db.QueryRowContext(ctx, `
+	SELECT `+sqliteRateColumns+` FROM tax_rates
+	WHERE jurisdiction = ? AND tax_class = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)`,
+			jurisdiction, class, at, at))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("tax rate not found", "jurisdiction", jurisdiction, "class", class, "at", at)
+			return nil, ErrRateNotFound
+		}
+		return nil, errors.New("failed to get tax rate: " + err.Error())
+	}
+	return rate, nil
+}
+
+func (r *SQLiteRepo) DeleteRate(ctx context.Context, id int64) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		res, err := r.db.ExecContext(ctx, `DELETE FROM tax_rates WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		rows, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete tax rate: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrRateNotFound
+	}
+	return nil
+}
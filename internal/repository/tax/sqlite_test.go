@@ -0,0 +1,13 @@
+package tax_test
+
+import (
+	"prodcrud/internal/repository/tax"
+	"prodcrud/pkg/db/sqlitetest"
+	"testing"
+)
+
+func TestSQLiteRepo_Contract(t *testing.T) {
+	runContract(t, func(t *testing.T) tax.Repository {
+		return tax.NewSQLiteRepo(sqlitetest.New(t))
+	})
+}
@@ -0,0 +1,252 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Repository stores tax jurisdictions and their rate tables.
+type Repository interface {
+	// SetJurisdiction creates or replaces the jurisdiction j.Code and sets
+	// j.UpdatedAt.
+	SetJurisdiction(ctx context.Context, j *models.TaxJurisdiction) error
+	GetJurisdiction(ctx context.Context, code string) (*models.TaxJurisdiction, error)
+	// GetJurisdictions lists every jurisdiction by code.
+	GetJurisdictions(ctx context.Context) ([]*models.TaxJurisdiction, error)
+	// DeleteJurisdiction deletes a jurisdiction with its rates.
+	DeleteJurisdiction(ctx context.Context, code string) error
+
+	// AddRate stores r and sets its ID and CreatedAt. It fails with
+	// ErrRateOverlap if another rate of the class is valid at any instant of
+	// [r.ValidFrom, r.ValidTo).
+	AddRate(ctx context.Context, r *models.TaxRate) error
+	// GetRates lists the rates of a jurisdiction by class, then validity.
+	GetRates(ctx context.Context, jurisdiction string) ([]*models.TaxRate, error)
+	// GetRate returns the rate of the class in effect at the given time.
+	GetRate(ctx context.Context, jurisdiction, class string, at time.Time) (*models.TaxRate, error)
+	DeleteRate(ctx context.Context, id int64) error
+}
+
+const logPackage = "repository/tax"
+
+var (
+	// ErrJurisdictionNotFound is returned when the jurisdiction does not exist.
+	ErrJurisdictionNotFound = errors.New("tax jurisdiction not found")
+	// ErrRateNotFound is returned when no rate matches.
+	ErrRateNotFound = errors.New("tax rate not found")
+	// ErrRateOverlap is returned when a new rate would overlap another rate
+	// of its class.
+	ErrRateOverlap = errors.New("tax rate overlaps another rate of its class")
+)
+
+const rateColumns = `id, jurisdiction, tax_class, rate::text, valid_from, valid_to, created_at`
+
+type Repo struct {
+	db *db.DB
+}
+
+func NewRepo(db *db.DB) *Repo {
+	return &Repo{db: db}
+}
+
+func (r *Repo) SetJurisdiction(ctx context.Context, j *models.TaxJurisdiction) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return r.db.QueryRow(ctx, `
+	INSERT INTO tax_jurisdictions(code, name, prices_include_tax, rounding) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (code) DO UPDATE SET name = excluded.name, prices_include_tax = excluded.prices_include_tax,
+	    rounding = excluded.rounding, updated_at = now()
+	RETURNING updated_at`, j.Code, j.Name, j.PricesIncludeTax, j.Rounding).Scan(&j.UpdatedAt)
+	})
+	if err != nil {
+		return errors.New("failed to set tax jurisdiction: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) GetJurisdiction(ctx context.Context, code string) (*models.TaxJurisdiction, error) {
+	var j models.TaxJurisdiction
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		return q.QueryRow(ctx, `SELECT code, name, prices_include_tax, rounding, updated_at FROM tax_jurisdictions WHERE code = $1`,
+			code).Scan(&j.Code, &j.Name, &j.PricesIncludeTax, &j.Rounding, &j.UpdatedAt)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("tax jurisdiction not found", "code", code)
+			return nil, ErrJurisdictionNotFound
+		}
+		return nil, errors.New("failed to get tax jurisdiction: " + err.Error())
+	}
+	return &j, nil
+}
+
+func (r *Repo) GetJurisdictions(ctx context.Context) ([]*models.TaxJurisdiction, error) {
+	var jurisdictions []*models.TaxJurisdiction
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		jurisdictions = nil
+		rows, err := q.Query(ctx, `SELECT code, name, prices_include_tax, rounding, updated_at FROM tax_jurisdictions ORDER BY code`)
+		if err != nil {
+			return fmt.Errorf("failed to get tax jurisdictions: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var j models.TaxJurisdiction
+			if err := rows.Scan(&j.Code, &j.Name, &j.PricesIncludeTax, &j.Rounding, &j.UpdatedAt); err != nil {
+				return fmt.Errorf("failed to scan tax jurisdictions: %w", err)
+			}
+			jurisdictions = append(jurisdictions, &j)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jurisdictions, nil
+}
+
+func (r *Repo) DeleteJurisdiction(ctx context.Context, code string) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tag, err := r.db.Exec(ctx, `DELETE FROM tax_jurisdictions WHERE code = $1`, code)
+		rows = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete tax jurisdiction: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrJurisdictionNotFound
+	}
+	return nil
+}
+
+func (r *Repo) AddRate(ctx context.Context, rate *models.TaxRate) error {
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+			// the jurisdiction row lock serialises the overlap check
+			var code string
+			if err := tx.QueryRow(ctx, `SELECT code FROM tax_jurisdictions WHERE code = $1 FOR UPDATE`, rate.Jurisdiction).
+				Scan(&code); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return ErrJurisdictionNotFound
+				}
+				return err
+			}
+			var overlaps bool
+			if err := tx.QueryRow(ctx, `
+	SELECT EXISTS (SELECT 1 FROM tax_rates WHERE jurisdiction = $1 AND tax_class = $2
+	    AND ($4::timestamp IS NULL OR valid_from < $4) AND (valid_to IS NULL OR valid_to > $3))`,
+				rate.Jurisdiction, rate.TaxClass, rate.ValidFrom.UTC(), utcOrNil(rate.ValidTo)).Scan(&overlaps); err != nil {
+				return err
+			}
+			if overlaps {
+				return ErrRateOverlap
+			}
+			return tx.QueryRow(ctx, `
+	INSERT INTO tax_rates(jurisdiction, tax_class, rate, valid_from, valid_to) VALUES ($1, $2, $3::numeric, $4, $5)
+	RETURNING id, created_at`, rate.Jurisdiction, rate.TaxClass, rate.Rate.String(), rate.ValidFrom.UTC(), utcOrNil(rate.ValidTo)).
+				Scan(&rate.ID, &rate.CreatedAt)
+		})
+	})
+	if errors.Is(err, ErrJurisdictionNotFound) || errors.Is(err, ErrRateOverlap) {
+		return err
+	}
+	if err != nil {
+		return errors.New("failed to add tax rate: " + err.Error())
+	}
+	return nil
+}
+
+func (r *Repo) GetRates(ctx context.Context, jurisdiction string) ([]*models.TaxRate, error) {
+	var rates []*models.TaxRate
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		rates = nil
+		rows, err := q.Query(ctx, `SELECT `+rateColumns+` FROM tax_rates WHERE jurisdiction = $1 ORDER BY tax_class, valid_from`,
+			jurisdiction)
+		if err != nil {
+			return fmt.Errorf("failed to get tax rates: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			rate, err := scanRate(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan tax rates: %w", err)
+			}
+			rates = append(rates, rate)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *Repo) GetRate(ctx context.Context, jurisdiction, class string, at time.Time) (*models.TaxRate, error) {
+	var rate *models.TaxRate
+	err := r.db.ReadReplica(ctx, func(ctx context.Context, q db.Querier) error {
+		var err error
+		rate, err = scanRate(q.QueryRow(ctx, `
+	SELECT `+rateColumns+` FROM tax_rates
+	WHERE jurisdiction = $1 AND tax_class = $2 AND valid_from <= $3 AND (valid_to IS NULL OR valid_to > $3)`,
+			jurisdiction, class, at.UTC()))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			logging.For(ctx, logPackage).Debug("tax rate not found", "jurisdiction", jurisdiction, "class", class, "at", at)
+			return nil, ErrRateNotFound
+		}
+		return nil, errors.New("failed to get tax rate: " + err.Error())
+	}
+	return rate, nil
+}
+
+func (r *Repo) DeleteRate(ctx context.Context, id int64) error {
+	var rows int64
+	err := r.db.Write(ctx, func(ctx context.Context) error {
+		tag, err := r.db.Exec(ctx, `DELETE FROM tax_rates WHERE id = $1`, id)
+		rows = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return errors.New("failed to delete tax rate: " + err.Error())
+	}
+	if rows == 0 {
+		return ErrRateNotFound
+	}
+	return nil
+}
+
+// scanRate scans a row of rateColumns, in either SQL backend.
+func scanRate(row interface{ Scan(dest ...any) error }) (*models.TaxRate, error) {
+	var (
+		rate    models.TaxRate
+		percent string
+	)
+	if err := row.Scan(&rate.ID, &rate.Jurisdiction, &rate.TaxClass, &percent, &rate.ValidFrom, &rate.ValidTo,
+		&rate.CreatedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if rate.Rate, err = money.ParseRate(percent); err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,431 @@
+package rest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"prodcrud/internal/metrics"
+	"prodcrud/internal/models"
+	attributeRepo "prodcrud/internal/repository/attribute"
+	currencyRepo "prodcrud/internal/repository/currency"
+	healthRepo "prodcrud/internal/repository/health"
+	imageRepo "prodcrud/internal/repository/image"
+	orderRepo "prodcrud/internal/repository/order"
+	productRepo "prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/product/producttest"
+	promotionRepo "prodcrud/internal/repository/promotion"
+	purchaseRepo "prodcrud/internal/repository/purchase"
+	reorderRepo "prodcrud/internal/repository/reorder"
+	taxRepo "prodcrud/internal/repository/tax"
+	"prodcrud/internal/rest"
+	"prodcrud/internal/rest/access"
+	"prodcrud/internal/rest/handlers/admin"
+	attributeHandler "prodcrud/internal/rest/handlers/attribute"
+	healthHandler "prodcrud/internal/rest/handlers/health"
+	imageHandler "prodcrud/internal/rest/handlers/image"
+	orderHandler "prodcrud/internal/rest/handlers/order"
+	pricingHandler "prodcrud/internal/rest/handlers/pricing"
+	productHandler "prodcrud/internal/rest/handlers/product"
+	promotionHandler "prodcrud/internal/rest/handlers/promotion"
+	purchaseHandler "prodcrud/internal/rest/handlers/purchase"
+	reorderHandler "prodcrud/internal/rest/handlers/reorder"
+	taxHandler "prodcrud/internal/rest/handlers/tax"
+	attributeService "prodcrud/internal/usecase/attribute"
+	healthService "prodcrud/internal/usecase/health"
+	imageService "prodcrud/internal/usecase/image"
+	orderService "prodcrud/internal/usecase/order"
+	pricingService "prodcrud/internal/usecase/pricing"
+	productService "prodcrud/internal/usecase/product"
+	promotionService "prodcrud/internal/usecase/promotion"
+	purchaseService "prodcrud/internal/usecase/purchase"
+	reorderService "prodcrud/internal/usecase/reorder"
+	taxService "prodcrud/internal/usecase/tax"
+	"prodcrud/pkg/blob"
+	"prodcrud/pkg/blob/s3test"
+	"prodcrud/pkg/db/pgtest"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) { pgtest.Main(m) }
+
+const adminToken = "e2e-admin-token"
+
+// newPostgresServer wires the real stack on a private Postgres schema.
+func newPostgresServer(t *testing.T) (*httptest.Server, productRepo.Repository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	pool := pgtest.New(t)
+
+	repo := productRepo.NewRepo(pool)
+	attributes := attributeRepo.NewRepo(pool)
+	taxes := taxService.NewService(taxRepo.NewRepo(pool))
+	prices := pricingService.NewService(repo, currencyRepo.NewRepo(pool), taxes)
+	store, err := blob.NewS3Store(s3test.New(t).Config())
+	require.NoError(t, err)
+	images := imageService.NewService(imageRepo.NewRepo(pool), repo, store, imageService.Options{
+		MaxSize: 1 << 20, ThumbnailSize: 32, PublicURL: "/media",
+	})
+	purchases := purchaseService.NewService(purchaseRepo.NewRepo(pool), repo, pool)
+	server := rest.NewServer(
+		gin.New(),
+		rest.Handlers{
+			Health:    healthHandler.NewHandler(healthService.NewService(healthRepo.NewRepo(pool), healthService.Options{})),
+			Product:   productHandler.NewHandler(productService.NewService(repo, attributes), taxes, images),
+			Pricing:   pricingHandler.NewHandler(prices),
+			Promotion: promotionHandler.NewHandler(promotionService.NewService(promotionRepo.NewRepo(pool), repo, prices, taxes)),
+			Tax:       taxHandler.NewHandler(taxes),
+			Attribute: attributeHandler.NewHandler(attributeService.NewService(attributes)),
+			Image:     imageHandler.NewHandler(images),
+			Purchase:  purchaseHandler.NewHandler(purchases),
+			Order:     orderHandler.NewHandler(orderService.NewService(orderRepo.NewRepo(pool), repo, prices, pool)),
+			Reorder:   reorderHandler.NewHandler(reorderService.NewService(reorderRepo.NewRepo(pool), purchases)),
+			Admin:     admin.NewHandler(logging.New(io.Discard, 0, nil)),
+		},
+		metrics.New(),
+		rest.Options{AdminToken: adminToken},
+	)
+	server.Init()
+
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	return ts, repo
+}
+
+func do(t *testing.T, method, url string, body any) (int, []byte) {
+	t.Helper()
+	return send(t, method, url, body, nil)
+}
+
+// doAdmin is do with the admin token set.
+func doAdmin(t *testing.T, method, url string, body any) (int, []byte) {
+	t.Helper()
+	return send(t, method, url, body, http.Header{access.Header: {adminToken}})
+}
+
+func send(t *testing.T, method, url string, body any, header http.Header) (int, []byte) {
+	t.Helper()
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, r)
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, data
+}
+
+func TestE2E_ProductLifecycle(t *testing.T) {
+	ts, repo := newPostgresServer(t)
+	seeded := producttest.Seed(t, repo, 2)
+
+	status, _ := do(t, http.MethodPost, ts.URL+"/products/", models.ProductResponse{
+		Name: "lamp", Price: money.New(2500, "USD"), Quantity: 4, Description: "desk lamp",
+	})
+	require.Equal(t, http.StatusOK, status)
+
+	status, body := do(t, http.MethodGet, ts.URL+"/products/", nil)
+	require.Equal(t, http.StatusOK, status)
+	var list []models.Product
+	require.NoError(t, json.Unmarshal(body, &list))
+	require.Len(t, list, 3)
+	assert.Equal(t, "lamp", list[2].Name)
+
+	status, _ = do(t, http.MethodPut, fmt.Sprintf("%s/products/%d", ts.URL, seeded[0].ID), map[string]any{"price": money.New(99, "USD")})
+	require.Equal(t, http.StatusOK, status)
+	status, body = do(t, http.MethodGet, fmt.Sprintf("%s/products/%d", ts.URL, seeded[0].ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	var got models.Product
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, money.New(99, "USD"), got.Price)
+	assert.Equal(t, models.TaxClassStandard, got.TaxClass)
+	status, _ = do(t, http.MethodGet, fmt.Sprintf("%s/products/%d?jurisdiction=DE", ts.URL, seeded[0].ID), nil)
+	assert.Equal(t, http.StatusNotFound, status, "no jurisdictions are set up")
+
+	status, _ = do(t, http.MethodPut, fmt.Sprintf("%s/products/%d/prices/EUR", ts.URL, seeded[0].ID),
+		models.PriceRequest{Amount: "0.95"})
+	require.Equal(t, http.StatusOK, status)
+	status, body = do(t, http.MethodGet, fmt.Sprintf("%s/products/%d/price?currency=EUR", ts.URL, seeded[0].ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	var quote models.PriceQuote
+	require.NoError(t, json.Unmarshal(body, &quote))
+	assert.Equal(t, money.New(95, "EUR"), quote.Price)
+	assert.Equal(t, models.PriceSourceList, quote.Source)
+	status, _ = do(t, http.MethodGet, fmt.Sprintf("%s/products/%d/price?currency=GBP", ts.URL, seeded[0].ID), nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+	status, body = do(t, http.MethodPost, ts.URL+"/pricing/quote", models.QuoteRequest{
+		Currency: "EUR", Lines: []models.QuoteLineItem{{ProductID: seeded[0].ID, Quantity: 3}},
+	})
+	require.Equal(t, http.StatusOK, status)
+	var basket models.BasketQuote
+	require.NoError(t, json.Unmarshal(body, &basket))
+	assert.Equal(t, money.New(285, "EUR"), basket.Total)
+
+	status, _ = do(t, http.MethodDelete, fmt.Sprintf("%s/products/%d", ts.URL, seeded[1].ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	status, body = do(t, http.MethodGet, ts.URL+"/products/", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.NoError(t, json.Unmarshal(body, &list))
+	assert.Len(t, list, 2)
+	status, _ = do(t, http.MethodGet, fmt.Sprintf("%s/products/%d", ts.URL, seeded[1].ID), nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = do(t, http.MethodDelete, fmt.Sprintf("%s/products/%d", ts.URL, seeded[1].ID), nil)
+	assert.Equal(t, http.StatusConflict, status)
+	status, _ = do(t, http.MethodPut, fmt.Sprintf("%s/products/%d/restore", ts.URL, seeded[0].ID), nil)
+	assert.Equal(t, http.StatusConflict, status)
+
+	status, _ = do(t, http.MethodGet, ts.URL+"/readyz", nil)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestE2E_Variants(t *testing.T) {
+	ts, repo := newPostgresServer(t)
+	parent := producttest.Seed(t, repo, 1)[0]
+	url := fmt.Sprintf("%s/products/%d/variants", ts.URL, parent.ID)
+
+	req := models.VariantMatrixRequest{
+		Axes:     []models.VariantAxis{{Name: "color", Values: []string{"silver", "black"}}, {Name: "ram", Values: []string{"16GB"}}},
+		Quantity: 3,
+	}
+	status, body := do(t, http.MethodPost, url+"/generate", req)
+	require.Equal(t, http.StatusCreated, status, string(body))
+	var matrix models.VariantMatrix
+	require.NoError(t, json.Unmarshal(body, &matrix))
+	assert.Len(t, matrix.Created, 2)
+	status, body = do(t, http.MethodPost, url+"/generate", req)
+	require.Equal(t, http.StatusCreated, status)
+	require.NoError(t, json.Unmarshal(body, &matrix))
+	assert.Empty(t, matrix.Created)
+	assert.Equal(t, 2, matrix.Existing)
+
+	status, _ = do(t, http.MethodPost, url, models.VariantRequest{
+		SKU: "OTHER", Options: map[string]string{"color": "silver", "ram": "16GB"}, Quantity: 1,
+	})
+	assert.Equal(t, http.StatusConflict, status, "the combination exists")
+
+	status, body = do(t, http.MethodGet, fmt.Sprintf("%s/products/%d", ts.URL, parent.ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	var got models.Product
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, matrix.Axes, got.VariantAxes)
+	require.Len(t, got.Variants, 2)
+	assert.Equal(t, map[string]string{"color": "silver", "ram": "16GB"}, got.Variants[0].Options)
+
+	status, _ = do(t, http.MethodDelete, fmt.Sprintf("%s/products/%d", ts.URL, got.Variants[0].ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	status, body = do(t, http.MethodGet, url, nil)
+	require.Equal(t, http.StatusOK, status)
+	var variants []models.Product
+	require.NoError(t, json.Unmarshal(body, &variants))
+	require.Len(t, variants, 1)
+	assert.Equal(t, fmt.Sprintf("P%d-BLACK-16GB", parent.ID), variants[0].SKU)
+}
+
+func upload(t *testing.T, url string, data []byte) (int, []byte) {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("image", "photo.png")
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	resp, err := http.Post(url, w.FormDataContentType(), &body)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	out, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, out
+}
+
+func TestE2E_ProductImages(t *testing.T) {
+	ts, repo := newPostgresServer(t)
+	p := producttest.Seed(t, repo, 1)[0]
+	url := fmt.Sprintf("%s/products/%d/images", ts.URL, p.ID)
+
+	var data bytes.Buffer
+	require.NoError(t, png.Encode(&data, image.NewGray(image.Rect(0, 0, 64, 48))))
+	var uploaded []models.ProductImage
+	for range 2 {
+		status, body := upload(t, url, data.Bytes())
+		require.Equal(t, http.StatusCreated, status, string(body))
+		var img models.ProductImage
+		require.NoError(t, json.Unmarshal(body, &img))
+		uploaded = append(uploaded, img)
+	}
+	status, _ := upload(t, url, []byte("not an image"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, status)
+
+	status, _ = do(t, http.MethodPut, fmt.Sprintf("%s/%d/primary", url, uploaded[1].ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	status, _ = do(t, http.MethodPut, url, models.ImageOrderRequest{IDs: []int64{uploaded[1].ID, uploaded[0].ID}})
+	require.Equal(t, http.StatusOK, status)
+
+	status, body := do(t, http.MethodGet, fmt.Sprintf("%s/products/%d", ts.URL, p.ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	var got models.Product
+	require.NoError(t, json.Unmarshal(body, &got))
+	require.Len(t, got.Images, 2)
+	assert.Equal(t, uploaded[1].ID, got.Images[0].ID)
+	assert.True(t, got.Images[0].Primary)
+	assert.Equal(t, [2]int{64, 48}, [2]int{got.Images[0].Width, got.Images[0].Height})
+
+	status, body = do(t, http.MethodGet, ts.URL+got.Images[0].URL, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, data.Bytes(), body)
+	status, body = do(t, http.MethodGet, ts.URL+got.Images[0].ThumbnailURL, nil)
+	require.Equal(t, http.StatusOK, status)
+	thumb, err := png.DecodeConfig(bytes.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, [2]int{32, 24}, [2]int{thumb.Width, thumb.Height})
+
+	status, _ = do(t, http.MethodDelete, fmt.Sprintf("%s/%d", url, uploaded[1].ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	status, _ = do(t, http.MethodGet, ts.URL+got.Images[0].URL, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestE2E_PurchaseOrderReceipt(t *testing.T) {
+	ts, repo := newPostgresServer(t)
+	p := producttest.Seed(t, repo, 1)[0]
+
+	status, body := doAdmin(t, http.MethodPost, ts.URL+"/suppliers/", models.SupplierRequest{Name: "Acme", Currency: "EUR"})
+	require.Equal(t, http.StatusCreated, status, string(body))
+	var supplier models.Supplier
+	require.NoError(t, json.Unmarshal(body, &supplier))
+	status, body = doAdmin(t, http.MethodPut, fmt.Sprintf("%s/suppliers/%d/products/%d", ts.URL, supplier.ID, p.ID),
+		models.SupplierProductRequest{Cost: money.New(150, "EUR"), LeadTimeDays: 3})
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	status, body = doAdmin(t, http.MethodPost, ts.URL+"/purchase-orders/", models.PurchaseOrderRequest{
+		SupplierID: supplier.ID, Lines: []models.PurchaseOrderLineRequest{{ProductID: p.ID, Quantity: 30}},
+	})
+	require.Equal(t, http.StatusCreated, status, string(body))
+	var o models.PurchaseOrder
+	require.NoError(t, json.Unmarshal(body, &o))
+	assert.Equal(t, money.New(4500, "EUR"), o.Total)
+	url := fmt.Sprintf("%s/purchase-orders/%d", ts.URL, o.ID)
+	status, _ = doAdmin(t, http.MethodPost, url+"/send", nil)
+	require.Equal(t, http.StatusOK, status)
+
+	receipt := models.ReceiptRequest{Lines: []models.ReceiptLine{{LineID: o.Lines[0].ID, Quantity: 10}}}
+	status, body = doAdmin(t, http.MethodPost, url+"/receive", receipt)
+	require.Equal(t, http.StatusOK, status, string(body))
+	require.NoError(t, json.Unmarshal(body, &o))
+	assert.Equal(t, models.PurchasePartiallyReceived, o.Status)
+	receipt.Lines[0].Quantity = 25
+	status, _ = doAdmin(t, http.MethodPost, url+"/receive", receipt)
+	assert.Equal(t, http.StatusBadRequest, status, "more than ordered")
+
+	status, body = do(t, http.MethodGet, fmt.Sprintf("%s/products/%d", ts.URL, p.ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	var got models.Product
+	require.NoError(t, json.Unmarshal(body, &got))
+	assert.Equal(t, p.Quantity+10, got.Quantity)
+	status, _ = do(t, http.MethodGet, url, nil)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestE2E_SalesOrderAllocation(t *testing.T) {
+	ts, repo := newPostgresServer(t)
+	p := producttest.Seed(t, repo, 1)[0]
+	place := func(quantity int) models.Order {
+		t.Helper()
+		status, body := do(t, http.MethodPost, ts.URL+"/orders/", models.OrderRequest{
+			Lines: []models.OrderLineRequest{{ProductID: p.ID, Quantity: quantity}},
+		})
+		require.Equal(t, http.StatusCreated, status, string(body))
+		var o models.Order
+		require.NoError(t, json.Unmarshal(body, &o))
+		return o
+	}
+	stock := func() int {
+		t.Helper()
+		status, body := do(t, http.MethodGet, fmt.Sprintf("%s/products/%d", ts.URL, p.ID), nil)
+		require.Equal(t, http.StatusOK, status)
+		var got models.Product
+		require.NoError(t, json.Unmarshal(body, &got))
+		return got.Quantity
+	}
+
+	all := place(p.Quantity)
+	assert.Equal(t, models.OrderPending, all.Status)
+	status, body := doAdmin(t, http.MethodPost, fmt.Sprintf("%s/orders/%d/allocate", ts.URL, all.ID), nil)
+	require.Equal(t, http.StatusOK, status, string(body))
+	assert.Zero(t, stock(), "the last unit can be sold")
+
+	more := place(1)
+	status, _ = doAdmin(t, http.MethodPost, fmt.Sprintf("%s/orders/%d/allocate", ts.URL, more.ID), nil)
+	assert.Equal(t, http.StatusConflict, status, "out of stock")
+	status, body = do(t, http.MethodGet, fmt.Sprintf("%s/orders/%d", ts.URL, more.ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	require.NoError(t, json.Unmarshal(body, &more))
+	assert.Equal(t, models.OrderPending, more.Status)
+
+	status, _ = doAdmin(t, http.MethodPost, fmt.Sprintf("%s/orders/%d/cancel", ts.URL, all.ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, p.Quantity, stock(), "the cancelled units go back to stock")
+	status, _ = do(t, http.MethodPost, fmt.Sprintf("%s/orders/%d/allocate", ts.URL, more.ID), nil)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestE2E_LowStock(t *testing.T) {
+	ts, repo := newPostgresServer(t)
+	p := producttest.Seed(t, repo, 1)[0]
+	rule := fmt.Sprintf("%s/products/%d/reorder", ts.URL, p.ID)
+	lowStock := func() []models.LowStockProduct {
+		t.Helper()
+		status, body := doAdmin(t, http.MethodGet, ts.URL+"/products/low-stock", nil)
+		require.Equal(t, http.StatusOK, status, string(body))
+		var low []models.LowStockProduct
+		require.NoError(t, json.Unmarshal(body, &low))
+		return low
+	}
+
+	status, body := doAdmin(t, http.MethodPut, rule, models.ReorderRuleRequest{ReorderPoint: p.Quantity - 1, ReorderQuantity: 20})
+	require.Equal(t, http.StatusOK, status, string(body))
+	assert.Empty(t, lowStock())
+
+	status, body = do(t, http.MethodPost, ts.URL+"/orders/", models.OrderRequest{
+		Lines: []models.OrderLineRequest{{ProductID: p.ID, Quantity: 1}},
+	})
+	require.Equal(t, http.StatusCreated, status, string(body))
+	var o models.Order
+	require.NoError(t, json.Unmarshal(body, &o))
+	status, _ = doAdmin(t, http.MethodPost, fmt.Sprintf("%s/orders/%d/allocate", ts.URL, o.ID), nil)
+	require.Equal(t, http.StatusOK, status)
+	low := lowStock()
+	require.Len(t, low, 1, "the sale takes the product down to its reorder point")
+	assert.Equal(t, p.ID, low[0].ProductID)
+	assert.Equal(t, p.Quantity-1, low[0].Quantity)
+
+	supplierID := int64(99)
+	status, _ = doAdmin(t, http.MethodPut, rule, models.ReorderRuleRequest{ReorderQuantity: 20, SupplierID: &supplierID})
+	assert.Equal(t, http.StatusBadRequest, status, "unknown supplier")
+	status, _ = doAdmin(t, http.MethodDelete, rule, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Empty(t, lowStock())
+	status, _ = do(t, http.MethodGet, ts.URL+"/products/low-stock", nil)
+	assert.Equal(t, http.StatusForbidden, status)
+}
@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+	"prodcrud/internal/rest/access"
+	"prodcrud/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	logs *logging.Registry
+}
+
+func NewHandler(logs *logging.Registry) *Handler {
+	return &Handler{logs: logs}
+}
+
+type LogLevelRequest struct {
+	Package string `json:"package"`
+	Level   string `json:"level" binding:"required"`
+}
+
+// GetLogLevels godoc
+//
+//	@Summary		Get log levels
+//	@Description	Get the default log level and every per-package override (admin only)
+//	@Tags			admin
+//
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		403				{object}	map[string]string
+//	@Success		200				{object}	map[string]string
+//	@Router			/admin/log-levels [get]
+func (h *Handler) GetLogLevels(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	c.JSON(http.StatusOK, h.logs.Levels())
+}
+
+// SetLogLevel godoc
+//
+//	@Summary		Change a log level
+//	@Description	Change the log level of a package (e.g. repository/product) at runtime, an empty package changes the default (admin only)
+//	@Tags			admin
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		LogLevelRequest	true	"Package and level (debug, info, warn, error)"
+//	@Param			X-Admin-Token	header		string			true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Success		200				{object}	map[string]string
+//	@Router			/admin/log-levels [put]
+func (h *Handler) SetLogLevel(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	var req LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid level, expected debug, info, warn or error"})
+		return
+	}
+	h.logs.SetLevel(req.Package, level)
+	logging.For(c, "rest").Info("log level changed", "target", req.Package, "level", level.String())
+	c.JSON(http.StatusOK, h.logs.Levels())
+}
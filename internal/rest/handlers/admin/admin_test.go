@@ -0,0 +1,19 @@
+package admin_test
+
+import (
+	"net/http"
+	"prodcrud/internal/rest/resttest"
+	"testing"
+)
+
+func TestHandler_Routes(t *testing.T) {
+	h := resttest.New(t, resttest.Services{})
+
+	h.Golden("levels", h.DoAdmin(http.MethodGet, "/admin/log-levels", nil))
+	h.Golden("levels_forbidden", h.Do(http.MethodGet, "/admin/log-levels", nil))
+	h.Golden("set_level", h.DoAdmin(http.MethodPut, "/admin/log-levels", map[string]string{"package": "repository/product", "level": "debug"}))
+	h.Golden("set_level_forbidden", h.Do(http.MethodPut, "/admin/log-levels", map[string]string{"package": "repository/product", "level": "debug"}))
+	h.Golden("set_level_invalid", h.DoAdmin(http.MethodPut, "/admin/log-levels", map[string]string{"level": "loud"}))
+	h.Golden("set_level_missing", h.DoAdmin(http.MethodPut, "/admin/log-levels", map[string]string{}))
+	h.AssertCovered("admin")
+}
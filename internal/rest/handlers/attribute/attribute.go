@@ -0,0 +1,121 @@
+package attribute
+
+import (
+	"errors"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/access"
+	"prodcrud/internal/usecase/attribute"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service attribute.ServiceInterface
+}
+
+func NewHandler(service attribute.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+// GetDefinitions godoc
+//
+//	@Summary		List the attributes of a category
+//	@Description	List the attribute schema of a category by name, empty for a category without one
+//	@Tags			attributes
+//
+//	@Produce		json
+//	@Param			category	path		string	true	"Category"
+//	@Failure		400			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Success		200			{object}	[]models.AttributeDefinition
+//	@Router			/categories/{category}/attributes [get]
+func (h *Handler) GetDefinitions(c *gin.Context) {
+	defs, err := h.service.GetDefinitions(c, c.Param("category"))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if defs == nil {
+		defs = []*models.AttributeDefinition{}
+	}
+	c.JSON(http.StatusOK, defs)
+}
+
+// SetDefinition godoc
+//
+//	@Summary		Set an attribute of a category
+//	@Description	Create or replace an attribute of a category (admin only). An enum lists its values, a unit attribute the units of measure it accepts. Products are checked against the schema when their attributes or category are set, so a change applies from then on
+//	@Tags			attributes
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			category		path		string								true	"Category"
+//	@Param			name			path		string								true	"Attribute name"
+//	@Param			request			body		models.AttributeDefinitionRequest	true	"Attribute"
+//	@Param			X-Admin-Token	header		string								true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.AttributeDefinition
+//	@Router			/categories/{category}/attributes/{name} [put]
+func (h *Handler) SetDefinition(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	var req models.AttributeDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	d, err := h.service.SetDefinition(c, c.Param("category"), c.Param("name"), req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// DeleteDefinition godoc
+//
+//	@Summary		Delete an attribute of a category
+//	@Description	Remove an attribute from the schema of a category (admin only). Products keep their value until their attributes are set again
+//	@Tags			attributes
+//
+//	@Produce		json
+//	@Param			category		path		string	true	"Category"
+//	@Param			name			path		string	true	"Attribute name"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	map[string]string
+//	@Router			/categories/{category}/attributes/{name} [delete]
+func (h *Handler) DeleteDefinition(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	if err := h.service.DeleteDefinition(c, c.Param("category"), c.Param("name")); err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "an attribute has been deleted"})
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, attribute.ErrInvalidDefinition):
+		return http.StatusBadRequest
+	case errors.Is(err, attribute.ErrNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
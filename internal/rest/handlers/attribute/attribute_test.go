@@ -0,0 +1,110 @@
+package attribute_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/resttest"
+	"prodcrud/internal/usecase/attribute"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	updated = time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	screen = &models.AttributeDefinition{
+		Category: "laptops", Name: "screen", Type: models.AttributeUnit, Required: true, Units: []string{"in", "cm"}, UpdatedAt: updated,
+	}
+	screenRequest = models.AttributeDefinitionRequest{Type: models.AttributeUnit, Required: true, Units: []string{"in", "cm"}}
+)
+
+func TestHandler_Routes(t *testing.T) {
+	errDB := errors.New("connection refused")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   any
+		admin  bool
+		setup  func(m *attribute.ServiceMock)
+	}{
+		{
+			name: "list", method: http.MethodGet, target: "/categories/laptops/attributes",
+			setup: func(m *attribute.ServiceMock) {
+				m.On("GetDefinitions", mock.Anything, "laptops").Return([]*models.AttributeDefinition{{
+					Category: "laptops", Name: "panel", Type: models.AttributeEnum, Values: []string{"ips", "oled"}, UpdatedAt: updated,
+				}, screen}, nil).Once()
+			},
+		},
+		{
+			name: "list_empty", method: http.MethodGet, target: "/categories/phones/attributes",
+			setup: func(m *attribute.ServiceMock) {
+				m.On("GetDefinitions", mock.Anything, "phones").Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "list_failed", method: http.MethodGet, target: "/categories/laptops/attributes",
+			setup: func(m *attribute.ServiceMock) {
+				m.On("GetDefinitions", mock.Anything, "laptops").Return(nil, errDB).Once()
+			},
+		},
+		{
+			name: "set", method: http.MethodPut, target: "/categories/laptops/attributes/screen", admin: true, body: screenRequest,
+			setup: func(m *attribute.ServiceMock) {
+				m.On("SetDefinition", mock.Anything, "laptops", "screen", screenRequest).Return(screen, nil).Once()
+			},
+		},
+		{
+			name: "set_forbidden", method: http.MethodPut, target: "/categories/laptops/attributes/screen", body: screenRequest,
+		},
+		{
+			name: "set_invalid_json", method: http.MethodPut, target: "/categories/laptops/attributes/screen", admin: true, body: "{",
+		},
+		{
+			name: "set_invalid", method: http.MethodPut, target: "/categories/laptops/attributes/screen", admin: true,
+			body: models.AttributeDefinitionRequest{Type: models.AttributeUnit},
+			setup: func(m *attribute.ServiceMock) {
+				m.On("SetDefinition", mock.Anything, "laptops", "screen", models.AttributeDefinitionRequest{Type: models.AttributeUnit}).
+					Return(nil, fmt.Errorf("%w: units must have 1 to 100 entries", attribute.ErrInvalidDefinition)).Once()
+			},
+		},
+		{
+			name: "delete", method: http.MethodDelete, target: "/categories/laptops/attributes/screen", admin: true,
+			setup: func(m *attribute.ServiceMock) {
+				m.On("DeleteDefinition", mock.Anything, "laptops", "screen").Return(nil).Once()
+			},
+		},
+		{
+			name: "delete_forbidden", method: http.MethodDelete, target: "/categories/laptops/attributes/screen",
+		},
+		{
+			name: "delete_not_found", method: http.MethodDelete, target: "/categories/laptops/attributes/color", admin: true,
+			setup: func(m *attribute.ServiceMock) {
+				m.On("DeleteDefinition", mock.Anything, "laptops", "color").Return(attribute.ErrNotFound).Once()
+			},
+		},
+	}
+
+	svc := new(attribute.ServiceMock)
+	h := resttest.New(t, resttest.Services{Attribute: svc})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(svc)
+			}
+			do := h.Do
+			if tt.admin {
+				do = h.DoAdmin
+			}
+			rec := do(tt.method, tt.target, tt.body)
+			h.Golden("attribute_"+tt.name, rec)
+		})
+	}
+	svc.AssertExpectations(t)
+	h.AssertCovered("attributes")
+}
@@ -1,7 +1,6 @@
 package health
 
 import (
-	"context"
 	"net/http"
 	"prodcrud/internal/usecase/health"
 
@@ -19,17 +18,48 @@ func NewHandler(service *health.Service) *Handler {
 // Check godoc
 //
 //	@Summary		Check service health
-//	@Description	Ping the database and cache to verify service health
+//	@Description	Run the readiness checks (database, migrations, disk) and report the overall state
 //	@Tags			health
 //	@Accept			json
 //	@Produce		json
-//	@Failure		500	{object} map[string]string
+//	@Failure		503	{object} map[string]string
 //	@Success		200	{object} map[string]string
 //	@Router			/products/health [get]
 func (h *Handler) HealthCheck(c *gin.Context) {
-	if err := h.service.Check(context.Background()); err != nil {
-		http.Error(c.Writer, "service not working", http.StatusInternalServerError)
+	if err := h.service.Check(c); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "service not working"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "service is working"})
 }
+
+// Livez godoc
+//
+//	@Summary		Liveness probe
+//	@Description	Report that the process is running, without checking dependencies
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	health.Report
+//	@Router			/livez [get]
+func (h *Handler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Live())
+}
+
+// Readyz godoc
+//
+//	@Summary		Readiness probe
+//	@Description	Check every dependency and report each component's status and latency
+//	@Tags			health
+//	@Produce		json
+//	@Failure		503	{object}	health.Report
+//	@Success		200	{object}	health.Report
+//	@Router			/readyz [get]
+func (h *Handler) Readyz(c *gin.Context) {
+	report := h.service.Ready(c)
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
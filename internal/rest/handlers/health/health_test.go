@@ -0,0 +1,27 @@
+package health_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"prodcrud/internal/rest/resttest"
+	"prodcrud/internal/usecase/health"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_Probes(t *testing.T) {
+	h := resttest.New(t, resttest.Services{})
+
+	for _, target := range []string{"/livez", "/readyz"} {
+		rec := h.Do(http.MethodGet, target, nil)
+		require.Equal(t, http.StatusOK, rec.Code, target)
+		var report health.Report
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, health.StatusOK, report.Status)
+	}
+	rec := h.Do(http.MethodGet, "/products/health", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	h.AssertCovered("health")
+}
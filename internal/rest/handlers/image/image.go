@@ -0,0 +1,243 @@
+package image
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/usecase/image"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FormField is the multipart field holding the uploaded image.
+const FormField = "image"
+
+type Handler struct {
+	service image.ServiceInterface
+}
+
+func NewHandler(service image.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+// UploadImage godoc
+//
+//	@Summary		Upload an image of a product
+//	@Description	Upload a JPEG, PNG or GIF image as the image field of a multipart form. The format is sniffed from the content, whatever the file name says; a thumbnail is generated and both are stored. The image goes after the others, and becomes the primary one when primary is set or the product has none yet
+//	@Tags			images
+//
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			id		path		int64	true	"Product ID"
+//	@Param			image	formData	file	true	"Image"
+//	@Param			primary	query		bool	false	"Make it the primary image"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		413		{object}	map[string]string
+//	@Failure		415		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Success		201		{object}	models.ProductImage
+//	@Router			/products/{id}/images [post]
+func (h *Handler) UploadImage(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid product id")
+	if !ok {
+		return
+	}
+	primary, err := strconv.ParseBool(c.DefaultQuery("primary", "false"))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "primary must be true or false"})
+		return
+	}
+	part, err := imagePart(c.Request)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer part.Close()
+	img, err := h.service.Upload(c, id, part, primary)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, img)
+}
+
+// imagePart streams the image field of a multipart request, so an upload is
+// never buffered whole before its size is checked.
+func imagePart(r *http.Request) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("the request must be a multipart form with an image field")
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("the form has no image field")
+		}
+		if err != nil {
+			return nil, errors.New("invalid multipart form: " + err.Error())
+		}
+		if part.FormName() == FormField {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// ReorderImages godoc
+//
+//	@Summary		Reorder the images of a product
+//	@Description	Order the images of a product as listed, which must hold each of them once
+//	@Tags			images
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int64						true	"Product ID"
+//	@Param			request	body		models.ImageOrderRequest	true	"Image IDs in order"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Success		200		{object}	[]models.ProductImage
+//	@Router			/products/{id}/images [put]
+func (h *Handler) ReorderImages(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid product id")
+	if !ok {
+		return
+	}
+	var req models.ImageOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	images, err := h.service.ReorderImages(c, id, req.IDs)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if images == nil {
+		images = []*models.ProductImage{}
+	}
+	c.JSON(http.StatusOK, images)
+}
+
+// SetPrimaryImage godoc
+//
+//	@Summary		Choose the primary image of a product
+//	@Description	Make an image the primary one of its product and list the images
+//	@Tags			images
+//
+//	@Produce		json
+//	@Param			id			path		int64	true	"Product ID"
+//	@Param			image_id	path		int64	true	"Image ID"
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Success		200			{object}	[]models.ProductImage
+//	@Router			/products/{id}/images/{image_id}/primary [put]
+func (h *Handler) SetPrimaryImage(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid product id")
+	if !ok {
+		return
+	}
+	imageID, ok := parseID(c, "image_id", "invalid image id")
+	if !ok {
+		return
+	}
+	images, err := h.service.SetPrimary(c, id, imageID)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, images)
+}
+
+// DeleteImage godoc
+//
+//	@Summary		Delete an image of a product
+//	@Description	Delete an image and its files; when it was the primary one, the first image left takes its place
+//	@Tags			images
+//
+//	@Produce		json
+//	@Param			id			path		int64	true	"Product ID"
+//	@Param			image_id	path		int64	true	"Image ID"
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Success		200			{object}	map[string]string
+//	@Router			/products/{id}/images/{image_id} [delete]
+func (h *Handler) DeleteImage(c *gin.Context) {
+	id, ok := parseID(c, "id", "invalid product id")
+	if !ok {
+		return
+	}
+	imageID, ok := parseID(c, "image_id", "invalid image id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteImage(c, id, imageID); err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "an image has been deleted"})
+}
+
+// GetMedia godoc
+//
+//	@Summary		Download an image file
+//	@Description	Serve an image or thumbnail of the blob store at its URL. Files never change once stored, so they may be cached for good
+//	@Tags			images
+//
+//	@Produce		image/jpeg,image/png,image/gif
+//	@Param			id		path		int64	true	"Product ID"
+//	@Param			file	path		string	true	"File name"
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Success		200		{file}		binary
+//	@Router			/media/products/{id}/{file} [get]
+func (h *Handler) GetMedia(c *gin.Context) {
+	r, info, err := h.service.Open(c, "products/"+c.Param("id")+"/"+c.Param("file"))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer r.Close()
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, r, nil)
+}
+
+func parseID(c *gin.Context, name, msg string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return 0, false
+	}
+	return id, true
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, image.ErrInvalidOrder):
+		return http.StatusBadRequest
+	case errors.Is(err, image.ErrProductNotFound), errors.Is(err, image.ErrImageNotFound),
+		errors.Is(err, image.ErrBlobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, image.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, image.ErrUnsupportedImage):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusInternalServerError
+	}
+}
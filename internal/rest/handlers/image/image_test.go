@@ -0,0 +1,165 @@
+package image_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/resttest"
+	"prodcrud/internal/usecase/image"
+	"prodcrud/pkg/blob"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var created = time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+func sample(id int64, position int, primary bool) *models.ProductImage {
+	name := fmt.Sprintf("/media/products/1/%06x", id)
+	return &models.ProductImage{
+		ID: id, ProductID: 1, Position: position, Primary: primary, ContentType: "image/jpeg",
+		Size: 183204, Width: 1600, Height: 1200, URL: name + ".jpg", ThumbnailURL: name + "_thumb.jpg", CreatedAt: created,
+	}
+}
+
+// readerOf matches the uploaded file by its content.
+func readerOf(want string) any {
+	return mock.MatchedBy(func(r io.Reader) bool {
+		data, err := io.ReadAll(r)
+		return err == nil && string(data) == want
+	})
+}
+
+func TestHandler_Routes(t *testing.T) {
+	errStore := errors.New("failed to upload image usc")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   any
+		// field, when set, sends body as the file of a multipart form
+		field string
+		setup func(m *image.ServiceMock)
+	}{
+		{
+			name: "upload", method: http.MethodPost, target: "/products/1/images?primary=true", field: "image", body: "jpeg-bytes",
+			setup: func(m *image.ServiceMock) {
+				m.On("Upload", mock.Anything, int64(1), readerOf("jpeg-bytes"), true).Return(sample(7, 3, true), nil).Once()
+			},
+		},
+		{
+			name: "upload_not_multipart", method: http.MethodPost, target: "/products/1/images", body: `{"image":"x"}`,
+		},
+		{
+			name: "upload_no_image_field", method: http.MethodPost, target: "/products/1/images", field: "file", body: "jpeg-bytes",
+		},
+		{
+			name: "upload_invalid_primary", method: http.MethodPost, target: "/products/1/images?primary=maybe", field: "image", body: "x",
+		},
+		{
+			name: "upload_unsupported", method: http.MethodPost, target: "/products/1/images", field: "image", body: "%PDF-1.4",
+			setup: func(m *image.ServiceMock) {
+				m.On("Upload", mock.Anything, int64(1), mock.Anything, false).
+					Return(nil, fmt.Errorf("%w: unsupported image format: application/pdf", image.ErrUnsupportedImage)).Once()
+			},
+		},
+		{
+			name: "upload_too_large", method: http.MethodPost, target: "/products/1/images", field: "image", body: "big",
+			setup: func(m *image.ServiceMock) {
+				m.On("Upload", mock.Anything, int64(1), mock.Anything, false).
+					Return(nil, fmt.Errorf("%w: the limit is 10485760 bytes", image.ErrTooLarge)).Once()
+			},
+		},
+		{
+			name: "upload_product_not_found", method: http.MethodPost, target: "/products/9/images", field: "image", body: "x",
+			setup: func(m *image.ServiceMock) {
+				m.On("Upload", mock.Anything, int64(9), mock.Anything, false).Return(nil, image.ErrProductNotFound).Once()
+			},
+		},
+		{
+			name: "upload_failed", method: http.MethodPost, target: "/products/1/images", field: "image", body: "x",
+			setup: func(m *image.ServiceMock) {
+				m.On("Upload", mock.Anything, int64(1), mock.Anything, false).Return(nil, errStore).Once()
+			},
+		},
+		{
+			name: "reorder", method: http.MethodPut, target: "/products/1/images", body: models.ImageOrderRequest{IDs: []int64{8, 7}},
+			setup: func(m *image.ServiceMock) {
+				m.On("ReorderImages", mock.Anything, int64(1), []int64{8, 7}).
+					Return([]*models.ProductImage{sample(8, 1, false), sample(7, 2, true)}, nil).Once()
+			},
+		},
+		{
+			name: "reorder_invalid", method: http.MethodPut, target: "/products/1/images", body: models.ImageOrderRequest{IDs: []int64{8}},
+			setup: func(m *image.ServiceMock) {
+				m.On("ReorderImages", mock.Anything, int64(1), []int64{8}).Return(nil, image.ErrInvalidOrder).Once()
+			},
+		},
+		{
+			name: "reorder_invalid_id", method: http.MethodPut, target: "/products/x/images", body: models.ImageOrderRequest{IDs: []int64{8}},
+		},
+		{
+			name: "primary", method: http.MethodPut, target: "/products/1/images/8/primary",
+			setup: func(m *image.ServiceMock) {
+				m.On("SetPrimary", mock.Anything, int64(1), int64(8)).
+					Return([]*models.ProductImage{sample(7, 1, false), sample(8, 2, true)}, nil).Once()
+			},
+		},
+		{
+			name: "primary_not_found", method: http.MethodPut, target: "/products/1/images/9/primary",
+			setup: func(m *image.ServiceMock) {
+				m.On("SetPrimary", mock.Anything, int64(1), int64(9)).Return(nil, image.ErrImageNotFound).Once()
+			},
+		},
+		{
+			name: "primary_invalid_id", method: http.MethodPut, target: "/products/1/images/x/primary",
+		},
+		{
+			name: "delete", method: http.MethodDelete, target: "/products/1/images/7",
+			setup: func(m *image.ServiceMock) {
+				m.On("DeleteImage", mock.Anything, int64(1), int64(7)).Return(nil).Once()
+			},
+		},
+		{
+			name: "delete_not_found", method: http.MethodDelete, target: "/products/1/images/9",
+			setup: func(m *image.ServiceMock) {
+				m.On("DeleteImage", mock.Anything, int64(1), int64(9)).Return(image.ErrImageNotFound).Once()
+			},
+		},
+		{
+			name: "media", method: http.MethodGet, target: "/media/products/1/000007.jpg",
+			setup: func(m *image.ServiceMock) {
+				m.On("Open", mock.Anything, "products/1/000007.jpg").
+					Return(io.NopCloser(strings.NewReader("jpeg-bytes")), blob.Info{Size: 10, ContentType: "image/jpeg"}, nil).Once()
+			},
+		},
+		{
+			name: "media_not_found", method: http.MethodGet, target: "/media/products/1/missing.jpg",
+			setup: func(m *image.ServiceMock) {
+				m.On("Open", mock.Anything, "products/1/missing.jpg").Return(nil, blob.Info{}, image.ErrBlobNotFound).Once()
+			},
+		},
+	}
+
+	svc := new(image.ServiceMock)
+	h := resttest.New(t, resttest.Services{Image: svc})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(svc)
+			}
+			if tt.field != "" {
+				h.Golden("image_"+tt.name, h.Upload(tt.target, tt.field, "photo.jpg", []byte(tt.body.(string))))
+				return
+			}
+			h.Golden("image_"+tt.name, h.Do(tt.method, tt.target, tt.body))
+		})
+	}
+	svc.AssertExpectations(t)
+	h.AssertCovered("images")
+}
@@ -0,0 +1,210 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/access"
+	"prodcrud/internal/usecase/order"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service order.ServiceInterface
+}
+
+func NewHandler(service order.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+// GetOrders godoc
+//
+//	@Summary		List orders
+//	@Description	List sales orders, newest first (admin only)
+//	@Tags			orders
+//
+//	@Produce		json
+//	@Param			status			query		string	false	"Only the orders in this status"	Enums(pending, allocated, shipped, cancelled)
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	[]models.Order
+//	@Router			/orders/ [get]
+func (h *Handler) GetOrders(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	orders, err := h.service.GetOrders(c, models.OrderFilter{Status: c.Query("status")})
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if orders == nil {
+		orders = []*models.Order{}
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+// GetOrder godoc
+//
+//	@Summary		Get an order
+//	@Description	Get a sales order by its ID, with its lines and status
+//	@Tags			orders
+//
+//	@Produce		json
+//	@Param			id	path		int64	true	"Order ID"
+//	@Failure		400	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Success		200	{object}	models.Order
+//	@Router			/orders/{id} [get]
+func (h *Handler) GetOrder(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	o, err := h.service.GetOrder(c, id)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+// CreateOrder godoc
+//
+//	@Summary		Place an order
+//	@Description	Place a pending order of active products. Each line is priced now in the order currency, which defaults to the base currency of the first product; the stock is taken only when the order is allocated
+//	@Tags			orders
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		models.OrderRequest	true	"Order"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Success		201		{object}	models.Order
+//	@Router			/orders/ [post]
+func (h *Handler) CreateOrder(c *gin.Context) {
+	var req models.OrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	o, err := h.service.CreateOrder(c, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, o)
+}
+
+// AllocateOrder godoc
+//
+//	@Summary		Allocate an order
+//	@Description	Take the units of a pending order from stock (admin only). Either every line is allocated or none is, and a product never goes below zero
+//	@Tags			orders
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Order ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.Order
+//	@Router			/orders/{id}/allocate [post]
+func (h *Handler) AllocateOrder(c *gin.Context) {
+	h.transition(c, h.service.AllocateOrder)
+}
+
+// ShipOrder godoc
+//
+//	@Summary		Ship an order
+//	@Description	Mark an allocated order as shipped (admin only)
+//	@Tags			orders
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Order ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.Order
+//	@Router			/orders/{id}/ship [post]
+func (h *Handler) ShipOrder(c *gin.Context) {
+	h.transition(c, h.service.ShipOrder)
+}
+
+// CancelOrder godoc
+//
+//	@Summary		Cancel an order
+//	@Description	Cancel an order until it is shipped (admin only). The units of an allocated order go back to stock, deleted products included
+//	@Tags			orders
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Order ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.Order
+//	@Router			/orders/{id}/cancel [post]
+func (h *Handler) CancelOrder(c *gin.Context) {
+	h.transition(c, h.service.CancelOrder)
+}
+
+func (h *Handler) transition(c *gin.Context, fn func(ctx context.Context, id int64) (*models.Order, error)) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	o, err := fn(c, id)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+func parseID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// errorStatus maps the order errors to their HTTP status.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, order.ErrInvalidOrder):
+		return http.StatusBadRequest
+	case errors.Is(err, order.ErrOrderNotFound), errors.Is(err, order.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, order.ErrInvalidStatus), errors.Is(err, order.ErrInsufficientStock):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,198 @@
+package order_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/resttest"
+	"prodcrud/internal/usecase/order"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	created   = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	allocated = time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	shipped   = time.Date(2025, 3, 2, 14, 0, 0, 0, time.UTC)
+
+	orderRequest = models.OrderRequest{Lines: []models.OrderLineRequest{
+		{ProductID: 3, Quantity: 2}, {ProductID: 4, Quantity: 1},
+	}}
+)
+
+func placed(status string) *models.Order {
+	o := &models.Order{
+		ID: 5, Status: status, Currency: "EUR",
+		Lines: []*models.OrderLine{
+			{ID: 7, ProductID: 3, Quantity: 2, UnitPrice: money.New(2500, "EUR")},
+			{ID: 8, ProductID: 4, Quantity: 1, UnitPrice: money.New(990, "EUR")},
+		},
+		Total: money.New(5990, "EUR"), CreatedAt: created, UpdatedAt: created,
+	}
+	switch status {
+	case models.OrderAllocated:
+		o.AllocatedAt, o.UpdatedAt = &allocated, allocated
+	case models.OrderShipped:
+		o.AllocatedAt, o.ShippedAt, o.UpdatedAt = &allocated, &shipped, shipped
+	case models.OrderCancelled:
+		o.AllocatedAt, o.CancelledAt, o.UpdatedAt = &allocated, &shipped, shipped
+	}
+	return o
+}
+
+func TestHandler_Routes(t *testing.T) {
+	errDB := errors.New("connection refused")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   any
+		admin  bool
+		setup  func(m *order.ServiceMock)
+	}{
+		{
+			name: "orders", method: http.MethodGet, target: "/orders/?status=allocated", admin: true,
+			setup: func(m *order.ServiceMock) {
+				m.On("GetOrders", mock.Anything, models.OrderFilter{Status: models.OrderAllocated}).
+					Return([]*models.Order{placed(models.OrderAllocated)}, nil).Once()
+			},
+		},
+		{
+			name: "orders_empty", method: http.MethodGet, target: "/orders/", admin: true,
+			setup: func(m *order.ServiceMock) {
+				m.On("GetOrders", mock.Anything, models.OrderFilter{}).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "orders_forbidden", method: http.MethodGet, target: "/orders/",
+		},
+		{
+			name: "orders_invalid_status", method: http.MethodGet, target: "/orders/?status=lost", admin: true,
+			setup: func(m *order.ServiceMock) {
+				m.On("GetOrders", mock.Anything, models.OrderFilter{Status: "lost"}).
+					Return(nil, fmt.Errorf("%w: unknown status %q", order.ErrInvalidOrder, "lost")).Once()
+			},
+		},
+		{
+			name: "orders_failed", method: http.MethodGet, target: "/orders/", admin: true,
+			setup: func(m *order.ServiceMock) {
+				m.On("GetOrders", mock.Anything, models.OrderFilter{}).Return(nil, errDB).Once()
+			},
+		},
+		{
+			name: "order", method: http.MethodGet, target: "/orders/5",
+			setup: func(m *order.ServiceMock) {
+				m.On("GetOrder", mock.Anything, int64(5)).Return(placed(models.OrderShipped), nil).Once()
+			},
+		},
+		{
+			name: "order_invalid_id", method: http.MethodGet, target: "/orders/abc",
+		},
+		{
+			name: "order_not_found", method: http.MethodGet, target: "/orders/9",
+			setup: func(m *order.ServiceMock) {
+				m.On("GetOrder", mock.Anything, int64(9)).Return(nil, order.ErrOrderNotFound).Once()
+			},
+		},
+		{
+			name: "create_order", method: http.MethodPost, target: "/orders/", body: orderRequest,
+			setup: func(m *order.ServiceMock) {
+				m.On("CreateOrder", mock.Anything, orderRequest).Return(placed(models.OrderPending), nil).Once()
+			},
+		},
+		{
+			name: "create_order_invalid_json", method: http.MethodPost, target: "/orders/", body: "{",
+		},
+		{
+			name: "create_order_invalid", method: http.MethodPost, target: "/orders/",
+			body: models.OrderRequest{Lines: []models.OrderLineRequest{{ProductID: 99, Quantity: 1}}},
+			setup: func(m *order.ServiceMock) {
+				m.On("CreateOrder", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: product 99 not found", order.ErrInvalidOrder)).Once()
+			},
+		},
+		{
+			name: "create_order_failed", method: http.MethodPost, target: "/orders/", body: orderRequest,
+			setup: func(m *order.ServiceMock) {
+				m.On("CreateOrder", mock.Anything, orderRequest).Return(nil, errors.New("failed to create order usc")).Once()
+			},
+		},
+		{
+			name: "allocate_order", method: http.MethodPost, target: "/orders/5/allocate", admin: true,
+			setup: func(m *order.ServiceMock) {
+				m.On("AllocateOrder", mock.Anything, int64(5)).Return(placed(models.OrderAllocated), nil).Once()
+			},
+		},
+		{
+			name: "allocate_order_forbidden", method: http.MethodPost, target: "/orders/5/allocate",
+		},
+		{
+			name: "allocate_order_invalid_id", method: http.MethodPost, target: "/orders/abc/allocate", admin: true,
+		},
+		{
+			name: "allocate_order_insufficient_stock", method: http.MethodPost, target: "/orders/5/allocate", admin: true,
+			setup: func(m *order.ServiceMock) {
+				m.On("AllocateOrder", mock.Anything, int64(5)).Return(nil,
+					fmt.Errorf("%w: product 4 has 0 of the 1 units ordered", order.ErrInsufficientStock)).Once()
+			},
+		},
+		{
+			name: "allocate_order_product_deleted", method: http.MethodPost, target: "/orders/5/allocate", admin: true,
+			setup: func(m *order.ServiceMock) {
+				m.On("AllocateOrder", mock.Anything, int64(5)).Return(nil,
+					fmt.Errorf("%w: product 4 is deleted", order.ErrProductNotFound)).Once()
+			},
+		},
+		{
+			name: "ship_order", method: http.MethodPost, target: "/orders/5/ship", admin: true,
+			setup: func(m *order.ServiceMock) {
+				m.On("ShipOrder", mock.Anything, int64(5)).Return(placed(models.OrderShipped), nil).Once()
+			},
+		},
+		{
+			name: "ship_order_pending", method: http.MethodPost, target: "/orders/5/ship", admin: true,
+			setup: func(m *order.ServiceMock) {
+				m.On("ShipOrder", mock.Anything, int64(5)).Return(nil,
+					fmt.Errorf("%w: cannot ship a pending order", order.ErrInvalidStatus)).Once()
+			},
+		},
+		{
+			name: "cancel_order", method: http.MethodPost, target: "/orders/5/cancel", admin: true,
+			setup: func(m *order.ServiceMock) {
+				m.On("CancelOrder", mock.Anything, int64(5)).Return(placed(models.OrderCancelled), nil).Once()
+			},
+		},
+		{
+			name: "cancel_order_forbidden", method: http.MethodPost, target: "/orders/5/cancel",
+		},
+		{
+			name: "cancel_order_not_found", method: http.MethodPost, target: "/orders/9/cancel", admin: true,
+			setup: func(m *order.ServiceMock) {
+				m.On("CancelOrder", mock.Anything, int64(9)).Return(nil, order.ErrOrderNotFound).Once()
+			},
+		},
+	}
+
+	svc := new(order.ServiceMock)
+	h := resttest.New(t, resttest.Services{Order: svc})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(svc)
+			}
+			do := h.Do
+			if tt.admin {
+				do = h.DoAdmin
+			}
+			rec := do(tt.method, tt.target, tt.body)
+			h.Golden("orders_"+tt.name, rec)
+		})
+	}
+	svc.AssertExpectations(t)
+	h.AssertCovered("orders")
+}
@@ -0,0 +1,320 @@
+package pricing
+
+import (
+	"errors"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/access"
+	"prodcrud/internal/usecase/pricing"
+	"prodcrud/pkg/money"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service pricing.ServiceInterface
+}
+
+func NewHandler(service pricing.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+// GetPrice godoc
+//
+//	@Summary		Get a product price
+//	@Description	Get the product price in a currency: a list price set in that currency, else the base price converted with the stored exchange rate. Without currency the base price is returned. A jurisdiction adds the net, tax and gross split of the price for the tax class of the product
+//	@Tags			pricing
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Product ID"
+//	@Param			currency		query		string	false	"ISO 4217 currency code"
+//	@Param			jurisdiction	query		string	false	"Tax jurisdiction code, e.g. DE"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		422				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.PriceQuote
+//	@Router			/products/{id}/price [get]
+func (h *Handler) GetPrice(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	quote, err := h.service.Quote(c, id, c.Query("currency"), c.Query("jurisdiction"))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
+
+// GetPrices godoc
+//
+//	@Summary		Get product prices
+//	@Description	Get the base price in effect at a time (now by default), the current list prices in other currencies and the price history with scheduled changes
+//	@Tags			pricing
+//
+//	@Produce		json
+//	@Param			id	path		int64	true	"Product ID"
+//	@Param			at	query		string	false	"RFC 3339 time, e.g. 2025-03-01T10:00:00Z"
+//	@Failure		400	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Success		200	{object}	models.ProductPrices
+//	@Router			/products/{id}/prices [get]
+func (h *Handler) GetPrices(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var at time.Time
+	if raw := c.Query("at"); raw != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339Nano, raw); err != nil {
+			_ = c.Error(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid at, want an RFC 3339 time"})
+			return
+		}
+	}
+	prices, err := h.service.GetProductPrices(c, id, at)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, prices)
+}
+
+// SchedulePrice godoc
+//
+//	@Summary		Schedule a price change
+//	@Description	Plan a base price from effective_from on. With effective_to the price is temporary and the one in effect before resumes after it; without, it lasts until the next scheduled change
+//	@Tags			pricing
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int64						true	"Product ID"
+//	@Param			request	body		models.SchedulePriceRequest	true	"Price and period"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Success		201		{object}	models.PricePeriod
+//	@Router			/products/{id}/prices [post]
+func (h *Handler) SchedulePrice(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req models.SchedulePriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	period, err := h.service.SchedulePrice(c, id, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, period)
+}
+
+// SetPrice godoc
+//
+//	@Summary		Set a product list price
+//	@Description	Set the product price in a currency other than its own, overriding conversion
+//	@Tags			pricing
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int64				true	"Product ID"
+//	@Param			currency	path		string				true	"ISO 4217 currency code"
+//	@Param			request		body		models.PriceRequest	true	"Amount in major units"
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Success		200			{object}	money.Money
+//	@Router			/products/{id}/prices/{currency} [put]
+func (h *Handler) SetPrice(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req models.PriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	price, err := money.Parse(req.Amount, c.Param("currency"))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.SetProductPrice(c, id, price); err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, price)
+}
+
+// DeletePrice godoc
+//
+//	@Summary		Delete a product list price
+//	@Description	Delete a list price, the product price in that currency falls back to conversion
+//	@Tags			pricing
+//
+//	@Produce		json
+//	@Param			id			path		int64	true	"Product ID"
+//	@Param			currency	path		string	true	"ISO 4217 currency code"
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Success		200			{object}	map[string]string
+//	@Router			/products/{id}/prices/{currency} [delete]
+func (h *Handler) DeletePrice(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteProductPrice(c, id, c.Param("currency")); err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "a price has been deleted"})
+}
+
+// GetRates godoc
+//
+//	@Summary		List exchange rates
+//	@Description	List the stored exchange rates, each the price of one base unit in the quote currency
+//	@Tags			pricing
+//
+//	@Produce		json
+//	@Failure		500	{object}	map[string]string
+//	@Success		200	{object}	[]models.ExchangeRate
+//	@Router			/exchange-rates/ [get]
+func (h *Handler) GetRates(c *gin.Context) {
+	rates, err := h.service.GetRates(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if rates == nil {
+		rates = []*models.ExchangeRate{}
+	}
+	c.JSON(http.StatusOK, rates)
+}
+
+// SetRate godoc
+//
+//	@Summary		Set an exchange rate
+//	@Description	Set the price of one base unit in the quote currency (admin only). The opposite direction is derived when it is not set
+//	@Tags			pricing
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			base			path		string				true	"Base currency code"
+//	@Param			quote			path		string				true	"Quote currency code"
+//	@Param			request			body		models.RateRequest	true	"Rate as a decimal string"
+//	@Param			X-Admin-Token	header		string				true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.ExchangeRate
+//	@Router			/exchange-rates/{base}/{quote} [put]
+func (h *Handler) SetRate(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	var req models.RateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rate, err := money.ParseRate(req.Rate)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	r, err := h.service.SetRate(c, c.Param("base"), c.Param("quote"), rate)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// DeleteRate godoc
+//
+//	@Summary		Delete an exchange rate
+//	@Description	Delete a stored exchange rate (admin only)
+//	@Tags			pricing
+//
+//	@Produce		json
+//	@Param			base			path		string	true	"Base currency code"
+//	@Param			quote			path		string	true	"Quote currency code"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	map[string]string
+//	@Router			/exchange-rates/{base}/{quote} [delete]
+func (h *Handler) DeleteRate(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	if err := h.service.DeleteRate(c, c.Param("base"), c.Param("quote")); err != nil {
+		_ = c.Error(err)
+		status := errorStatus(err)
+		if errors.Is(err, pricing.ErrRateNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "an exchange rate has been deleted"})
+}
+
+func parseID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// errorStatus maps the pricing errors to their HTTP status.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, pricing.ErrInvalidPrice):
+		return http.StatusBadRequest
+	case errors.Is(err, pricing.ErrProductNotFound),
+		errors.Is(err, pricing.ErrPriceNotFound),
+		errors.Is(err, pricing.ErrNoPriceAt),
+		errors.Is(err, pricing.ErrJurisdictionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, pricing.ErrRateNotFound),
+		errors.Is(err, pricing.ErrNoTaxRate):
+		// the request is fine, the price just cannot be derived yet
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
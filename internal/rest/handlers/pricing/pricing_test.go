@@ -0,0 +1,263 @@
+package pricing_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/resttest"
+	"prodcrud/internal/usecase/pricing"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	updated = time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	sale    = updated.AddDate(0, 0, 7)
+)
+
+func TestHandler_Routes(t *testing.T) {
+	errDB := errors.New("connection refused")
+	rate := money.MustParseRate("0.92")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   any
+		admin  bool
+		setup  func(m *pricing.ServiceMock)
+	}{
+		{
+			name: "quote_base", method: http.MethodGet, target: "/products/1/price",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("Quote", mock.Anything, int64(1), "", "").Return(&models.PriceQuote{
+					ProductID: 1, Price: money.New(1999, "USD"), BasePrice: money.New(1999, "USD"), Source: models.PriceSourceBase,
+				}, nil).Once()
+			},
+		},
+		{
+			name: "quote_converted", method: http.MethodGet, target: "/products/1/price?currency=EUR",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("Quote", mock.Anything, int64(1), "EUR", "").Return(&models.PriceQuote{
+					ProductID: 1, Price: money.New(1839, "EUR"), BasePrice: money.New(1999, "USD"),
+					Source: models.PriceSourceConverted, Rate: &rate,
+				}, nil).Once()
+			},
+		},
+		{
+			name: "quote_no_rate", method: http.MethodGet, target: "/products/1/price?currency=JPY",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("Quote", mock.Anything, int64(1), "JPY", "").
+					Return(nil, fmt.Errorf("%w: USD/JPY", pricing.ErrRateNotFound)).Once()
+			},
+		},
+		{
+			name: "quote_unknown_currency", method: http.MethodGet, target: "/products/1/price?currency=XXX",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("Quote", mock.Anything, int64(1), "XXX", "").
+					Return(nil, fmt.Errorf("%w: %w \"XXX\"", pricing.ErrInvalidPrice, money.ErrUnknownCurrency)).Once()
+			},
+		},
+		{
+			name: "quote_taxed", method: http.MethodGet, target: "/products/1/price?jurisdiction=US-CA",
+			setup: func(m *pricing.ServiceMock) {
+				taxRate := money.MustParseRate("7.25")
+				m.On("Quote", mock.Anything, int64(1), "", "US-CA").Return(&models.PriceQuote{
+					ProductID: 1, Price: money.New(1999, "USD"), BasePrice: money.New(1999, "USD"), Source: models.PriceSourceBase,
+					Tax: &models.TaxBreakdown{
+						Jurisdiction: "US-CA", TaxClass: models.TaxClassStandard, Rate: &taxRate, Rounding: money.RoundHalfUp,
+						Net: money.New(1999, "USD"), Tax: money.New(145, "USD"), Gross: money.New(2144, "USD"),
+					},
+				}, nil).Once()
+			},
+		},
+		{
+			name: "quote_no_tax_rate", method: http.MethodGet, target: "/products/1/price?jurisdiction=US-CA",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("Quote", mock.Anything, int64(1), "", "US-CA").
+					Return(nil, fmt.Errorf("%w: standard in US-CA", pricing.ErrNoTaxRate)).Once()
+			},
+		},
+		{
+			name: "quote_unknown_jurisdiction", method: http.MethodGet, target: "/products/1/price?jurisdiction=FR",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("Quote", mock.Anything, int64(1), "", "FR").Return(nil, pricing.ErrJurisdictionNotFound).Once()
+			},
+		},
+		{
+			name: "quote_not_found", method: http.MethodGet, target: "/products/9/price",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("Quote", mock.Anything, int64(9), "", "").Return(nil, pricing.ErrProductNotFound).Once()
+			},
+		},
+		{
+			name: "quote_invalid_id", method: http.MethodGet, target: "/products/abc/price",
+		},
+		{
+			name: "prices", method: http.MethodGet, target: "/products/1/prices",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("GetProductPrices", mock.Anything, int64(1), time.Time{}).Return(&models.ProductPrices{
+					ProductID: 1, At: updated, Price: money.New(1999, "USD"),
+					List: []money.Money{money.New(1500, "EUR"), money.New(250000, "UZS")},
+					History: []*models.PricePeriod{
+						{ID: 1, ProductID: 1, Price: money.New(2499, "USD"), EffectiveFrom: updated.AddDate(0, -1, 0), EffectiveTo: &updated},
+						{ID: 2, ProductID: 1, Price: money.New(1999, "USD"), EffectiveFrom: updated},
+					},
+				}, nil).Once()
+			},
+		},
+		{
+			name: "prices_at", method: http.MethodGet, target: "/products/1/prices?at=2025-02-01T00:00:00Z",
+			setup: func(m *pricing.ServiceMock) {
+				at := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+				m.On("GetProductPrices", mock.Anything, int64(1), at).Return(&models.ProductPrices{
+					ProductID: 1, At: at, Price: money.New(2499, "USD"),
+					List: []money.Money{}, History: []*models.PricePeriod{},
+				}, nil).Once()
+			},
+		},
+		{
+			name: "prices_invalid_at", method: http.MethodGet, target: "/products/1/prices?at=yesterday",
+		},
+		{
+			name: "prices_no_price_at", method: http.MethodGet, target: "/products/1/prices?at=2000-01-01T00:00:00Z",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("GetProductPrices", mock.Anything, int64(1), time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).
+					Return(nil, pricing.ErrNoPriceAt).Once()
+			},
+		},
+		{
+			name: "prices_failed", method: http.MethodGet, target: "/products/1/prices",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("GetProductPrices", mock.Anything, int64(1), time.Time{}).Return(nil, errDB).Once()
+			},
+		},
+		{
+			name: "schedule_price", method: http.MethodPost, target: "/products/1/prices",
+			body: models.SchedulePriceRequest{Price: money.New(1499, "USD"), EffectiveFrom: updated, EffectiveTo: &sale},
+			setup: func(m *pricing.ServiceMock) {
+				m.On("SchedulePrice", mock.Anything, int64(1), models.SchedulePriceRequest{
+					Price: money.New(1499, "USD"), EffectiveFrom: updated, EffectiveTo: &sale,
+				}).Return(&models.PricePeriod{
+					ID: 3, ProductID: 1, Price: money.New(1499, "USD"), EffectiveFrom: updated, EffectiveTo: &sale,
+				}, nil).Once()
+			},
+		},
+		{
+			name: "schedule_price_invalid_json", method: http.MethodPost, target: "/products/1/prices",
+			body: "{",
+		},
+		{
+			name: "schedule_price_in_past", method: http.MethodPost, target: "/products/1/prices",
+			body: models.SchedulePriceRequest{Price: money.New(1499, "USD"), EffectiveFrom: updated},
+			setup: func(m *pricing.ServiceMock) {
+				m.On("SchedulePrice", mock.Anything, int64(1), mock.Anything).
+					Return(nil, fmt.Errorf("%w: effective_from must be in the future, update the product to change the price now", pricing.ErrInvalidPrice)).Once()
+			},
+		},
+		{
+			name: "schedule_price_not_found", method: http.MethodPost, target: "/products/9/prices",
+			body: models.SchedulePriceRequest{Price: money.New(1499, "USD"), EffectiveFrom: updated},
+			setup: func(m *pricing.ServiceMock) {
+				m.On("SchedulePrice", mock.Anything, int64(9), mock.Anything).Return(nil, pricing.ErrProductNotFound).Once()
+			},
+		},
+		{
+			name: "set_price", method: http.MethodPut, target: "/products/1/prices/eur",
+			body: models.PriceRequest{Amount: "15.00"},
+			setup: func(m *pricing.ServiceMock) {
+				m.On("SetProductPrice", mock.Anything, int64(1), money.New(1500, "EUR")).Return(nil).Once()
+			},
+		},
+		{
+			name: "set_price_too_precise", method: http.MethodPut, target: "/products/1/prices/JPY",
+			body: models.PriceRequest{Amount: "1500.5"},
+		},
+		{
+			name: "set_price_not_found", method: http.MethodPut, target: "/products/9/prices/EUR",
+			body: models.PriceRequest{Amount: "15"},
+			setup: func(m *pricing.ServiceMock) {
+				m.On("SetProductPrice", mock.Anything, int64(9), money.New(1500, "EUR")).Return(pricing.ErrProductNotFound).Once()
+			},
+		},
+		{
+			name: "delete_price", method: http.MethodDelete, target: "/products/1/prices/EUR",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("DeleteProductPrice", mock.Anything, int64(1), "EUR").Return(nil).Once()
+			},
+		},
+		{
+			name: "delete_price_not_found", method: http.MethodDelete, target: "/products/1/prices/GBP",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("DeleteProductPrice", mock.Anything, int64(1), "GBP").Return(pricing.ErrPriceNotFound).Once()
+			},
+		},
+		{
+			name: "rates", method: http.MethodGet, target: "/exchange-rates/",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("GetRates", mock.Anything).Return([]*models.ExchangeRate{
+					{Base: "USD", Quote: "EUR", Rate: rate, UpdatedAt: updated},
+				}, nil).Once()
+			},
+		},
+		{
+			name: "rates_empty", method: http.MethodGet, target: "/exchange-rates/",
+			setup: func(m *pricing.ServiceMock) {
+				m.On("GetRates", mock.Anything).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "set_rate", method: http.MethodPut, target: "/exchange-rates/USD/EUR", admin: true,
+			body: models.RateRequest{Rate: "0.92"},
+			setup: func(m *pricing.ServiceMock) {
+				m.On("SetRate", mock.Anything, "USD", "EUR", rate).
+					Return(&models.ExchangeRate{Base: "USD", Quote: "EUR", Rate: rate, UpdatedAt: updated}, nil).Once()
+			},
+		},
+		{
+			name: "set_rate_forbidden", method: http.MethodPut, target: "/exchange-rates/USD/EUR",
+			body: models.RateRequest{Rate: "0.92"},
+		},
+		{
+			name: "set_rate_invalid", method: http.MethodPut, target: "/exchange-rates/USD/EUR", admin: true,
+			body: models.RateRequest{Rate: "-1"},
+		},
+		{
+			name: "delete_rate", method: http.MethodDelete, target: "/exchange-rates/USD/EUR", admin: true,
+			setup: func(m *pricing.ServiceMock) {
+				m.On("DeleteRate", mock.Anything, "USD", "EUR").Return(nil).Once()
+			},
+		},
+		{
+			name: "delete_rate_not_found", method: http.MethodDelete, target: "/exchange-rates/USD/GBP", admin: true,
+			setup: func(m *pricing.ServiceMock) {
+				m.On("DeleteRate", mock.Anything, "USD", "GBP").Return(pricing.ErrRateNotFound).Once()
+			},
+		},
+		{
+			name: "delete_rate_forbidden", method: http.MethodDelete, target: "/exchange-rates/USD/EUR",
+		},
+	}
+
+	svc := new(pricing.ServiceMock)
+	h := resttest.New(t, resttest.Services{Pricing: svc})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(svc)
+			}
+			do := h.Do
+			if tt.admin {
+				do = h.DoAdmin
+			}
+			rec := do(tt.method, tt.target, tt.body)
+			h.Golden("pricing_"+tt.name, rec)
+		})
+	}
+	svc.AssertExpectations(t)
+	h.AssertCovered("pricing")
+}
@@ -4,40 +4,48 @@ import (
 	"errors"
 	"net/http"
 	"prodcrud/internal/models"
+	"prodcrud/internal/rest/access"
+	"prodcrud/internal/usecase/image"
 	"prodcrud/internal/usecase/product"
+	"prodcrud/internal/usecase/tax"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
 	service product.ServiceInterface
+	taxes   tax.ServiceInterface
+	images  image.ServiceInterface
 }
 
-func NewHandler(service product.ServiceInterface) *Handler {
-	return &Handler{service: service}
+func NewHandler(service product.ServiceInterface, taxes tax.ServiceInterface, images image.ServiceInterface) *Handler {
+	return &Handler{service: service, taxes: taxes, images: images}
 }
 
 // CreateProduct godoc
 //
 //	@Summary		Create a new product
-//	@Description	Create a new product with the provided details
+//	@Description	Create a new product with the provided details. Attributes must follow the attribute schema of the category, with every required one set
 //	@Tags			products
 //
 //	@Accept			json
 //	@Produce		json
 //	@Param			request	body		models.ProductResponse	true	"Product details"
 //	@Failure		400		{object}	map[string]string
-//	@Failure		500		{object}	map[string]string
-//	@Success		201		{object}	map[string]string
+//	@Success		200		{object}	map[string]interface{}
 //	@Router			/products/ [post]
 func (h *Handler) CreateProduct(c *gin.Context) {
 	var p models.Product
 	if err := c.ShouldBindJSON(&p); err != nil {
+		_ = c.Error(err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	if err := h.service.CreateProduct(c, &p); err != nil {
+		_ = c.Error(err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -47,64 +55,132 @@ func (h *Handler) CreateProduct(c *gin.Context) {
 		"price":       p.Price,
 		"quantity":    p.Quantity,
 		"description": p.Description,
+		"category":    p.Category,
+		"tax_class":   p.TaxClass,
+		"attributes":  p.Attributes,
 	})
 }
 
 // GetProduct godoc
 //
 //	@Summary		Get a product by ID
-//	@Description	Get the details of a product by its ID
+//	@Description	Get the details of a product by its ID. A parent embeds its variant axes and active variants, a variant names its parent, SKU and options. Images lists the product images by position with their URLs. A jurisdiction adds the net, tax and gross split of its price for its tax class, with the rates in effect now
 //	@Tags			products
 //
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int64	true	"Product ID"
-//	@Failure		400	{object}	map[string]string
-//	@Failure		404	{object}	map[string]string
-//	@Failure		500	{object}	map[string]string
-//	@Success		200	{object}	models.Product
+//	@Param			id				path		int64	true	"Product ID"
+//	@Param			include_deleted	query		bool	false	"Also return a soft-deleted product (admin only)"
+//	@Param			jurisdiction	query		string	false	"Tax jurisdiction code, e.g. DE"
+//	@Param			X-Admin-Token	header		string	false	"Admin token, required for include_deleted"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		422				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.Product
 //	@Router			/products/{id} [get]
 func (h *Handler) GetProduct(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
+		_ = c.Error(err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
 		return
 	}
-	prod, err := h.service.GetProduct(c, id)
+	includeDeleted, ok := parseIncludeDeleted(c)
+	if !ok {
+		return
+	}
+	prod, err := h.service.GetProduct(c, id, includeDeleted)
 	if err != nil {
+		_ = c.Error(err)
 		if errors.Is(err, product.ErrProductNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if jurisdiction := c.Query("jurisdiction"); jurisdiction != "" {
+		if prod.Tax, err = h.taxes.Calculate(c, prod.Price, prod.TaxClass, jurisdiction, time.Time{}); err != nil {
+			_ = c.Error(err)
+			c.JSON(taxStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if prod.Images, err = h.images.GetImages(c, prod.ID); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, prod)
 }
 
 // GetAllProducts godoc
 //
 //	@Summary		Get all products
-//	@Description	Get a list of all products with optional filters
+//	@Description	Get a list of all products with optional filters. attr.{name}=value keeps the products of the category whose attribute has the value, e.g. attr.panel=oled or attr.screen=13.3 in; a unit value without a unit matches in any unit
 //	@Tags			products
 //
 //	@Accept			json
 //	@Produce		json
-//	@Failure		500	{object}	map[string]string
-//	@Success		200	{object}	[]models.Product
+//	@Param			category		query		string	false	"Category, required by attribute filters"
+//	@Param			attr.{name}		query		string	false	"Attribute value, one parameter per attribute"
+//	@Param			include_deleted	query		bool	false	"Also list soft-deleted products (admin only)"
+//	@Param			X-Admin-Token	header		string	false	"Admin token, required for include_deleted"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	[]models.Product
 //	@Router			/products/ [get]
 func (h *Handler) GetAllProducts(c *gin.Context) {
-	products, err := h.service.GetAllProducts(c)
+	includeDeleted, ok := parseIncludeDeleted(c)
+	if !ok {
+		return
+	}
+	var (
+		products []*models.Product
+		err      error
+	)
+	category, attributes := filterOf(c)
+	if category != "" || len(attributes) > 0 {
+		products, err = h.service.FindProducts(c, category, attributes, includeDeleted)
+	} else {
+		products, err = h.service.GetAllProducts(c, includeDeleted)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, product.ErrInvalidFilter) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
+	if products == nil {
+		products = []*models.Product{}
+	}
 	c.JSON(http.StatusOK, products)
 }
 
+// filterOf reads the category and the attr.{name} parameters of a listing.
+func filterOf(c *gin.Context) (string, map[string]string) {
+	var attributes map[string]string
+	for key, values := range c.Request.URL.Query() {
+		if name, ok := strings.CutPrefix(key, "attr."); ok && len(values) > 0 {
+			if attributes == nil {
+				attributes = make(map[string]string)
+			}
+			attributes[name] = values[0]
+		}
+	}
+	return c.Query("category"), attributes
+}
+
 // UpdateProduct godoc
 //
 //	@Summary		Update an existing product
-//	@Description	Update the details of an existing product by ID
+//	@Description	Update the details of an existing product by ID. Attributes, when given, replace all the attributes of the product; the attributes must follow the schema of a new category
 //	@Tags			products
 //
 //	@Accept			json
@@ -113,17 +189,18 @@ func (h *Handler) GetAllProducts(c *gin.Context) {
 //	@Param			request	body		models.ProductResponse	true	"Product details"
 //	@Failure		400		{object}	map[string]string
 //	@Failure		404		{object}	map[string]string
-//	@Failure		500		{object}	map[string]string
-//	@Success		200		{object}	models.Product
+//	@Success		200		{object}	map[string]string
 //	@Router			/products/{id} [put]
 func (h *Handler) UpdateProduct(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
+		_ = c.Error(err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
 	var p models.Product
 	if err := c.ShouldBindJSON(&p); err != nil {
+		_ = c.Error(err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -131,6 +208,11 @@ func (h *Handler) UpdateProduct(c *gin.Context) {
 	p.ID = id
 
 	if err := h.service.UpdateProduct(c, &p); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, product.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -140,51 +222,160 @@ func (h *Handler) UpdateProduct(c *gin.Context) {
 // DeleteProduct godoc
 //
 //	@Summary		Delete a product
-//	@Description	Delete an existing product by ID
+//	@Description	Soft delete an active product by ID, 409 if it is deleted already. With hard=true an admin removes it permanently
 //	@Tags			products
 //
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int64	true	"Product ID"
-//	@Failure		400	{object}	map[string]string
-//	@Failure		500	{object}	map[string]string
-//	@Success		204	{object}	map[string]string
+//	@Param			id				path		int64	true	"Product ID"
+//	@Param			hard			query		bool	false	"Remove permanently (admin only)"
+//	@Param			X-Admin-Token	header		string	false	"Admin token, required for hard=true"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	map[string]string
 //	@Router			/products/{id} [delete]
 func (h *Handler) DeleteProduct(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
+		_ = c.Error(err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
 		return
 	}
+	hard, err := strconv.ParseBool(c.DefaultQuery("hard", "false"))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid hard flag"})
+		return
+	}
+	if hard {
+		h.hardDelete(c, id)
+		return
+	}
 	if err := h.service.DeleteProduct(c, id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "a product has been deleted"})
 }
 
+func (h *Handler) hardDelete(c *gin.Context, id int64) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	if err := h.service.HardDeleteProduct(c, id); err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "a product has been deleted permanently"})
+}
+
+// GetDeletedProducts godoc
+//
+//	@Summary		List deleted products
+//	@Description	List soft-deleted products that can still be restored (admin only)
+//	@Tags			products
+//
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		403				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	[]models.Product
+//	@Router			/products/trash [get]
+func (h *Handler) GetDeletedProducts(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	products, err := h.service.GetDeletedProducts(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if products == nil {
+		products = []*models.Product{}
+	}
+	c.JSON(http.StatusOK, products)
+}
+
 // RestoreProduct godoc
 //
 //	@Summary		Restore a deleted product
-//	@Description	Restore a soft-deleted product by ID
+//	@Description	Restore a soft-deleted product by ID, 409 if it is active
 //	@Tags			products
 //
 //	@Accept			json
 //	@Produce		json
 //	@Param			id	path		int64	true	"Product ID"
 //	@Failure		400	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		409	{object}	map[string]string
 //	@Failure		500	{object}	map[string]string
 //	@Success		200	{object}	map[string]string
 //	@Router			/products/{id}/restore [put]
 func (h *Handler) RestoreProduct(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
+		_ = c.Error(err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
 		return
 	}
 	if err := h.service.RestoreProduct(c, id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "a product has been restored"})
 }
+
+// parseIncludeDeleted reads the include_deleted query flag, which only admins
+// may set. It writes the error response and returns false when the request
+// cannot go on.
+func parseIncludeDeleted(c *gin.Context) (bool, bool) {
+	includeDeleted, err := strconv.ParseBool(c.DefaultQuery("include_deleted", "false"))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid include_deleted flag"})
+		return false, false
+	}
+	if includeDeleted && !access.IsAdmin(c) {
+		access.Forbid(c)
+		return false, false
+	}
+	return includeDeleted, true
+}
+
+// errorStatus maps the product lifecycle errors to their HTTP status.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, product.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, product.ErrProductAlreadyDeleted),
+		errors.Is(err, product.ErrProductNotDeleted),
+		errors.Is(err, product.ErrProductReferenced):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// taxStatus maps the errors of taxing a product to their HTTP status.
+func taxStatus(err error) int {
+	switch {
+	case errors.Is(err, tax.ErrInvalidTax):
+		return http.StatusBadRequest
+	case errors.Is(err, tax.ErrJurisdictionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, tax.ErrNoRate):
+		// the product is fine, it just cannot be taxed there yet
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
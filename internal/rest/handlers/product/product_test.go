@@ -0,0 +1,461 @@
+package product_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/resttest"
+	"prodcrud/internal/usecase/image"
+	"prodcrud/internal/usecase/product"
+	"prodcrud/internal/usecase/tax"
+	"prodcrud/pkg/money"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var created = time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+func sample(id int64, name string) *models.Product {
+	return &models.Product{
+		ID:          id,
+		Name:        name,
+		Price:       money.New(1000, "USD"),
+		Quantity:    5,
+		Description: name + " description",
+		TaxClass:    models.TaxClassStandard,
+		CreatedAt:   created,
+		UpdatedAt:   created,
+	}
+}
+
+var colors = []models.VariantAxis{{Name: "color", Values: []string{"silver", "black"}}}
+
+func variant(id int64, color string) *models.Product {
+	p := sample(id, "laptop ("+color+")")
+	parentID := int64(1)
+	p.ParentID = &parentID
+	p.SKU = "P1-" + strings.ToUpper(color)
+	p.Options = map[string]string{"color": color}
+	return p
+}
+
+func TestHandler_Routes(t *testing.T) {
+	errDB := errors.New("connection refused")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   any
+		admin  bool
+		setup  func(m *product.ServiceMock)
+		taxes  func(m *tax.ServiceMock)
+		images func(m *image.ServiceMock)
+	}{
+		{
+			name: "create", method: http.MethodPost, target: "/products/",
+			body: models.ProductResponse{Name: "lamp", Price: money.New(2500, "USD"), Quantity: 4, Description: "desk lamp"},
+			setup: func(m *product.ServiceMock) {
+				m.On("CreateProduct", mock.Anything, mock.Anything).Return(nil).Once()
+			},
+		},
+		{
+			name: "create_invalid_json", method: http.MethodPost, target: "/products/", body: `{"name":`,
+		},
+		{
+			name: "create_invalid_price", method: http.MethodPost, target: "/products/",
+			body: `{"name":"lamp","price":{"amount":"25.001","currency":"USD"},"quantity":4}`,
+		},
+		{
+			name: "create_rejected", method: http.MethodPost, target: "/products/",
+			body: models.ProductResponse{Name: "lamp", Price: money.New(-1, "USD"), Quantity: 4, Description: "desk lamp"},
+			setup: func(m *product.ServiceMock) {
+				m.On("CreateProduct", mock.Anything, mock.Anything).
+					Return(errors.New("price cannot be negative or zero")).Once()
+			},
+		},
+		{
+			name: "list", method: http.MethodGet, target: "/products/",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetAllProducts", mock.Anything, false).
+					Return([]*models.Product{sample(1, "lamp"), sample(2, "desk")}, nil).Once()
+			},
+		},
+		{
+			name: "list_empty", method: http.MethodGet, target: "/products/",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetAllProducts", mock.Anything, false).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "list_failed", method: http.MethodGet, target: "/products/",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetAllProducts", mock.Anything, false).Return(nil, errDB).Once()
+			},
+		},
+		{
+			name: "list_filtered", method: http.MethodGet, target: "/products/?category=laptops&attr.panel=oled&attr.screen=13.3+in",
+			setup: func(m *product.ServiceMock) {
+				p := sample(4, "laptop")
+				p.Category = "laptops"
+				p.Attributes = map[string]any{"panel": "oled", "screen": map[string]any{"value": 13.3, "unit": "in"}}
+				m.On("FindProducts", mock.Anything, "laptops", map[string]string{"panel": "oled", "screen": "13.3 in"}, false).
+					Return([]*models.Product{p}, nil).Once()
+			},
+		},
+		{
+			name: "list_filtered_invalid", method: http.MethodGet, target: "/products/?attr.panel=oled",
+			setup: func(m *product.ServiceMock) {
+				m.On("FindProducts", mock.Anything, "", map[string]string{"panel": "oled"}, false).
+					Return(nil, fmt.Errorf("%w: filtering by attributes needs a category", product.ErrInvalidFilter)).Once()
+			},
+		},
+		{
+			name: "get", method: http.MethodGet, target: "/products/1",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetProduct", mock.Anything, int64(1), false).Return(sample(1, "lamp"), nil).Once()
+			},
+			images: func(m *image.ServiceMock) {
+				m.On("GetImages", mock.Anything, int64(1)).Return([]*models.ProductImage{
+					{
+						ID: 7, ProductID: 1, Position: 1, Primary: true, ContentType: "image/jpeg", Size: 183204, Width: 1600, Height: 1200,
+						URL: "/media/products/1/5f2b9c.jpg", ThumbnailURL: "/media/products/1/5f2b9c_thumb.jpg", CreatedAt: created,
+					},
+				}, nil).Once()
+			},
+		},
+		{
+			name: "get_images_failed", method: http.MethodGet, target: "/products/1",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetProduct", mock.Anything, int64(1), false).Return(sample(1, "lamp"), nil).Once()
+			},
+			images: func(m *image.ServiceMock) {
+				m.On("GetImages", mock.Anything, int64(1)).Return(nil, errors.New("failed to get images usc")).Once()
+			},
+		},
+		{
+			name: "get_taxed", method: http.MethodGet, target: "/products/1?jurisdiction=de",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetProduct", mock.Anything, int64(1), false).Return(sample(1, "lamp"), nil).Once()
+			},
+			taxes: func(m *tax.ServiceMock) {
+				rate := money.MustParseRate("19")
+				m.On("Calculate", mock.Anything, money.New(1000, "USD"), models.TaxClassStandard, "de", time.Time{}).
+					Return(&models.TaxBreakdown{
+						Jurisdiction: "DE", TaxClass: models.TaxClassStandard, Rate: &rate, PricesIncludeTax: true,
+						Rounding: money.RoundHalfUp, Net: money.New(840, "USD"), Tax: money.New(160, "USD"), Gross: money.New(1000, "USD"),
+					}, nil).Once()
+			},
+			images: func(m *image.ServiceMock) {
+				m.On("GetImages", mock.Anything, int64(1)).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "get_taxed_no_rate", method: http.MethodGet, target: "/products/1?jurisdiction=DE",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetProduct", mock.Anything, int64(1), false).Return(sample(1, "lamp"), nil).Once()
+			},
+			taxes: func(m *tax.ServiceMock) {
+				m.On("Calculate", mock.Anything, money.New(1000, "USD"), models.TaxClassStandard, "DE", time.Time{}).
+					Return(nil, fmt.Errorf("%w: standard in DE", tax.ErrNoRate)).Once()
+			},
+		},
+		{
+			name: "get_taxed_unknown_jurisdiction", method: http.MethodGet, target: "/products/1?jurisdiction=FR",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetProduct", mock.Anything, int64(1), false).Return(sample(1, "lamp"), nil).Once()
+			},
+			taxes: func(m *tax.ServiceMock) {
+				m.On("Calculate", mock.Anything, money.New(1000, "USD"), models.TaxClassStandard, "FR", time.Time{}).
+					Return(nil, tax.ErrJurisdictionNotFound).Once()
+			},
+		},
+		{
+			name: "get_include_deleted_forbidden", method: http.MethodGet, target: "/products/3?include_deleted=true",
+		},
+		{
+			name: "get_include_deleted_invalid", method: http.MethodGet, target: "/products/3?include_deleted=maybe",
+		},
+		{
+			name: "get_include_deleted", method: http.MethodGet, target: "/products/3?include_deleted=true", admin: true,
+			setup: func(m *product.ServiceMock) {
+				p := sample(3, "vase")
+				deleted := created.Add(time.Hour)
+				p.DeletedAt = &deleted
+				m.On("GetProduct", mock.Anything, int64(3), true).Return(p, nil).Once()
+			},
+			images: func(m *image.ServiceMock) {
+				m.On("GetImages", mock.Anything, int64(3)).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "list_include_deleted", method: http.MethodGet, target: "/products/?include_deleted=true", admin: true,
+			setup: func(m *product.ServiceMock) {
+				p := sample(3, "vase")
+				deleted := created.Add(time.Hour)
+				p.DeletedAt = &deleted
+				m.On("GetAllProducts", mock.Anything, true).Return([]*models.Product{sample(1, "lamp"), p}, nil).Once()
+			},
+		},
+		{
+			name: "list_include_deleted_forbidden", method: http.MethodGet, target: "/products/?include_deleted=true",
+		},
+		{
+			name: "get_invalid_id", method: http.MethodGet, target: "/products/abc",
+		},
+		{
+			name: "get_not_found", method: http.MethodGet, target: "/products/9",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetProduct", mock.Anything, int64(9), false).Return(nil, product.ErrProductNotFound).Once()
+			},
+		},
+		{
+			name: "get_failed", method: http.MethodGet, target: "/products/1",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetProduct", mock.Anything, int64(1), false).Return(nil, errDB).Once()
+			},
+		},
+		{
+			name: "update", method: http.MethodPut, target: "/products/1", body: map[string]any{"price": money.New(1200, "USD")},
+			setup: func(m *product.ServiceMock) {
+				m.On("UpdateProduct", mock.Anything, mock.MatchedBy(func(p *models.Product) bool {
+					return p.ID == 1 && p.Price == money.New(1200, "USD")
+				})).Return(nil).Once()
+			},
+		},
+		{
+			name: "update_invalid_id", method: http.MethodPut, target: "/products/abc", body: map[string]any{"price": money.New(1, "USD")},
+		},
+		{
+			name: "update_not_found", method: http.MethodPut, target: "/products/9", body: map[string]any{"price": money.New(1, "USD")},
+			setup: func(m *product.ServiceMock) {
+				m.On("UpdateProduct", mock.Anything, mock.Anything).Return(product.ErrProductNotFound).Once()
+			},
+		},
+		{
+			name: "delete", method: http.MethodDelete, target: "/products/1",
+			setup: func(m *product.ServiceMock) {
+				m.On("DeleteProduct", mock.Anything, int64(1)).Return(nil).Once()
+			},
+		},
+		{
+			name: "delete_invalid_id", method: http.MethodDelete, target: "/products/abc",
+		},
+		{
+			name: "delete_not_found", method: http.MethodDelete, target: "/products/9",
+			setup: func(m *product.ServiceMock) {
+				m.On("DeleteProduct", mock.Anything, int64(9)).Return(product.ErrProductNotFound).Once()
+			},
+		},
+		{
+			name: "delete_already_deleted", method: http.MethodDelete, target: "/products/3",
+			setup: func(m *product.ServiceMock) {
+				m.On("DeleteProduct", mock.Anything, int64(3)).Return(product.ErrProductAlreadyDeleted).Once()
+			},
+		},
+		{
+			name: "delete_failed", method: http.MethodDelete, target: "/products/1",
+			setup: func(m *product.ServiceMock) {
+				m.On("DeleteProduct", mock.Anything, int64(1)).Return(errors.New("failed to delete product usc")).Once()
+			},
+		},
+		{
+			name: "delete_hard_forbidden", method: http.MethodDelete, target: "/products/1?hard=true",
+		},
+		{
+			name: "delete_hard_invalid_flag", method: http.MethodDelete, target: "/products/1?hard=maybe", admin: true,
+		},
+		{
+			name: "delete_hard", method: http.MethodDelete, target: "/products/1?hard=true", admin: true,
+			setup: func(m *product.ServiceMock) {
+				m.On("HardDeleteProduct", mock.Anything, int64(1)).Return(nil).Once()
+			},
+		},
+		{
+			name: "delete_hard_not_found", method: http.MethodDelete, target: "/products/9?hard=true", admin: true,
+			setup: func(m *product.ServiceMock) {
+				m.On("HardDeleteProduct", mock.Anything, int64(9)).Return(product.ErrProductNotFound).Once()
+			},
+		},
+		{
+			name: "delete_hard_referenced", method: http.MethodDelete, target: "/products/2?hard=true", admin: true,
+			setup: func(m *product.ServiceMock) {
+				m.On("HardDeleteProduct", mock.Anything, int64(2)).Return(product.ErrProductReferenced).Once()
+			},
+		},
+		{
+			name: "trash_forbidden", method: http.MethodGet, target: "/products/trash",
+		},
+		{
+			name: "trash", method: http.MethodGet, target: "/products/trash", admin: true,
+			setup: func(m *product.ServiceMock) {
+				p := sample(3, "vase")
+				deleted := created.Add(time.Hour)
+				p.DeletedAt = &deleted
+				m.On("GetDeletedProducts", mock.Anything).Return([]*models.Product{p}, nil).Once()
+			},
+		},
+		{
+			name: "trash_empty", method: http.MethodGet, target: "/products/trash", admin: true,
+			setup: func(m *product.ServiceMock) {
+				m.On("GetDeletedProducts", mock.Anything).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "restore", method: http.MethodPut, target: "/products/1/restore",
+			setup: func(m *product.ServiceMock) {
+				m.On("RestoreProduct", mock.Anything, int64(1)).Return(nil).Once()
+			},
+		},
+		{
+			name: "restore_invalid_id", method: http.MethodPut, target: "/products/abc/restore",
+		},
+		{
+			name: "restore_not_found", method: http.MethodPut, target: "/products/9/restore",
+			setup: func(m *product.ServiceMock) {
+				m.On("RestoreProduct", mock.Anything, int64(9)).Return(product.ErrProductNotFound).Once()
+			},
+		},
+		{
+			name: "restore_not_deleted", method: http.MethodPut, target: "/products/1/restore",
+			setup: func(m *product.ServiceMock) {
+				m.On("RestoreProduct", mock.Anything, int64(1)).Return(product.ErrProductNotDeleted).Once()
+			},
+		},
+		{
+			name: "restore_failed", method: http.MethodPut, target: "/products/1/restore",
+			setup: func(m *product.ServiceMock) {
+				m.On("RestoreProduct", mock.Anything, int64(1)).Return(errors.New("failed to restore product usc")).Once()
+			},
+		},
+		{
+			name: "get_parent", method: http.MethodGet, target: "/products/1",
+			setup: func(m *product.ServiceMock) {
+				p := sample(1, "laptop")
+				p.VariantAxes = []models.VariantAxis{{Name: "color", Values: []string{"silver", "black"}}}
+				p.Variants = []*models.Product{variant(2, "silver"), variant(3, "black")}
+				m.On("GetProduct", mock.Anything, int64(1), false).Return(p, nil).Once()
+			},
+			images: func(m *image.ServiceMock) {
+				m.On("GetImages", mock.Anything, int64(1)).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "variants", method: http.MethodGet, target: "/products/1/variants",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetVariants", mock.Anything, int64(1)).
+					Return([]*models.Product{variant(2, "silver"), variant(3, "black")}, nil).Once()
+			},
+		},
+		{
+			name: "variants_empty", method: http.MethodGet, target: "/products/1/variants",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetVariants", mock.Anything, int64(1)).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "variants_invalid_id", method: http.MethodGet, target: "/products/abc/variants",
+		},
+		{
+			name: "variants_not_found", method: http.MethodGet, target: "/products/9/variants",
+			setup: func(m *product.ServiceMock) {
+				m.On("GetVariants", mock.Anything, int64(9)).Return(nil, product.ErrProductNotFound).Once()
+			},
+		},
+		{
+			name: "generate_variants", method: http.MethodPost, target: "/products/1/variants/generate",
+			body: models.VariantMatrixRequest{Axes: colors, Quantity: 5},
+			setup: func(m *product.ServiceMock) {
+				m.On("GenerateVariants", mock.Anything, int64(1), models.VariantMatrixRequest{Axes: colors, Quantity: 5}).
+					Return(&models.VariantMatrix{Axes: colors, Created: []*models.Product{variant(3, "black")}, Existing: 1}, nil).Once()
+			},
+		},
+		{
+			name: "generate_variants_invalid_json", method: http.MethodPost, target: "/products/1/variants/generate", body: `{"axes":`,
+		},
+		{
+			name: "generate_variants_invalid", method: http.MethodPost, target: "/products/1/variants/generate",
+			body: models.VariantMatrixRequest{Quantity: 5},
+			setup: func(m *product.ServiceMock) {
+				m.On("GenerateVariants", mock.Anything, int64(1), models.VariantMatrixRequest{Quantity: 5}).
+					Return(nil, fmt.Errorf("%w: axes are required", product.ErrInvalidVariant)).Once()
+			},
+		},
+		{
+			name: "generate_variants_conflict", method: http.MethodPost, target: "/products/1/variants/generate",
+			body: models.VariantMatrixRequest{Axes: colors, Quantity: 5},
+			setup: func(m *product.ServiceMock) {
+				m.On("GenerateVariants", mock.Anything, int64(1), models.VariantMatrixRequest{Axes: colors, Quantity: 5}).
+					Return(nil, product.ErrDuplicateSKU).Once()
+			},
+		},
+		{
+			name: "create_variant", method: http.MethodPost, target: "/products/1/variants",
+			body: models.VariantRequest{SKU: "P1-GOLD", Options: map[string]string{"color": "gold"}, Quantity: 2},
+			setup: func(m *product.ServiceMock) {
+				m.On("CreateVariant", mock.Anything, int64(1), mock.MatchedBy(func(req models.VariantRequest) bool {
+					return req.SKU == "P1-GOLD" && req.Options["color"] == "gold"
+				})).Return(variant(4, "gold"), nil).Once()
+			},
+		},
+		{
+			name: "create_variant_invalid_id", method: http.MethodPost, target: "/products/abc/variants", body: models.VariantRequest{},
+		},
+		{
+			name: "create_variant_duplicate", method: http.MethodPost, target: "/products/1/variants",
+			body: models.VariantRequest{SKU: "P1-SILVER-2", Options: map[string]string{"color": "silver"}, Quantity: 2},
+			setup: func(m *product.ServiceMock) {
+				m.On("CreateVariant", mock.Anything, int64(1), mock.Anything).Return(nil, product.ErrDuplicateVariant).Once()
+			},
+		},
+		{
+			name: "create_variant_of_variant", method: http.MethodPost, target: "/products/2/variants",
+			body: models.VariantRequest{SKU: "X", Options: map[string]string{"size": "s"}, Quantity: 2},
+			setup: func(m *product.ServiceMock) {
+				m.On("CreateVariant", mock.Anything, int64(2), mock.Anything).
+					Return(nil, fmt.Errorf("%w: product 2 is a variant itself", product.ErrInvalidVariant)).Once()
+			},
+		},
+		{
+			name: "create_variant_failed", method: http.MethodPost, target: "/products/1/variants",
+			body: models.VariantRequest{SKU: "P1-RED", Options: map[string]string{"color": "red"}, Quantity: 2},
+			setup: func(m *product.ServiceMock) {
+				m.On("CreateVariant", mock.Anything, int64(1), mock.Anything).
+					Return(nil, errors.New("failed to create variants usc")).Once()
+			},
+		},
+		{
+			name: "health", method: http.MethodGet, target: "/products/health",
+		},
+	}
+
+	svc, taxes, images := new(product.ServiceMock), new(tax.ServiceMock), new(image.ServiceMock)
+	h := resttest.New(t, resttest.Services{Product: svc, Tax: taxes, Image: images})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(svc)
+			}
+			if tt.taxes != nil {
+				tt.taxes(taxes)
+			}
+			if tt.images != nil {
+				tt.images(images)
+			}
+			do := h.Do
+			if tt.admin {
+				do = h.DoAdmin
+			}
+			rec := do(tt.method, tt.target, tt.body)
+			h.Golden("product_"+tt.name, rec)
+		})
+	}
+	svc.AssertExpectations(t)
+	taxes.AssertExpectations(t)
+	images.AssertExpectations(t)
+	h.AssertCovered("products")
+}
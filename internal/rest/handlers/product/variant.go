@@ -0,0 +1,138 @@
+package product
+
+import (
+	"errors"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/usecase/product"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetVariants godoc
+//
+//	@Summary		List the variants of a product
+//	@Description	List the active variants of a parent product by id
+//	@Tags			products
+//
+//	@Produce		json
+//	@Param			id	path		int64	true	"Parent product ID"
+//	@Failure		400	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Success		200	{object}	[]models.Product
+//	@Router			/products/{id}/variants [get]
+func (h *Handler) GetVariants(c *gin.Context) {
+	id, ok := parseProductID(c)
+	if !ok {
+		return
+	}
+	variants, err := h.service.GetVariants(c, id)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(variantStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if variants == nil {
+		variants = []*models.Product{}
+	}
+	c.JSON(http.StatusOK, variants)
+}
+
+// GenerateVariants godoc
+//
+//	@Summary		Generate the variant matrix of a product
+//	@Description	Create a variant for every combination of the axis values the product does not have yet. Each variant is a product of its own with a SKU, price and stock, named after the parent and its values. Once a product has axes, later generations must use the same axis names in the same order; new values extend them
+//	@Tags			products
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int64						true	"Parent product ID"
+//	@Param			request	body		models.VariantMatrixRequest	true	"Axes"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		409		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Success		201		{object}	models.VariantMatrix
+//	@Router			/products/{id}/variants/generate [post]
+func (h *Handler) GenerateVariants(c *gin.Context) {
+	id, ok := parseProductID(c)
+	if !ok {
+		return
+	}
+	var req models.VariantMatrixRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	matrix, err := h.service.GenerateVariants(c, id, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(variantStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, matrix)
+}
+
+// CreateVariant godoc
+//
+//	@Summary		Add a variant to a product
+//	@Description	Add a variant for one combination of axis values, 409 if the product has it already or the SKU is taken. The options must set every axis of the product; a first variant defines the axes
+//	@Tags			products
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int64					true	"Parent product ID"
+//	@Param			request	body		models.VariantRequest	true	"Variant"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		409		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Success		201		{object}	models.Product
+//	@Router			/products/{id}/variants [post]
+func (h *Handler) CreateVariant(c *gin.Context) {
+	id, ok := parseProductID(c)
+	if !ok {
+		return
+	}
+	var req models.VariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	v, err := h.service.CreateVariant(c, id, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(variantStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, v)
+}
+
+func parseProductID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// variantStatus maps the variant errors to their HTTP status.
+func variantStatus(err error) int {
+	switch {
+	case errors.Is(err, product.ErrInvalidVariant):
+		return http.StatusBadRequest
+	case errors.Is(err, product.ErrProductNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, product.ErrDuplicateVariant),
+		errors.Is(err, product.ErrDuplicateSKU):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
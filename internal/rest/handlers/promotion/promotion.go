@@ -0,0 +1,238 @@
+package promotion
+
+import (
+	"errors"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/access"
+	"prodcrud/internal/usecase/promotion"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service promotion.ServiceInterface
+}
+
+func NewHandler(service promotion.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+// GetPromotions godoc
+//
+//	@Summary		List promotions
+//	@Description	List every promotion, past, running and scheduled, by id
+//	@Tags			promotions
+//
+//	@Produce		json
+//	@Failure		500	{object}	map[string]string
+//	@Success		200	{object}	[]models.Promotion
+//	@Router			/promotions/ [get]
+func (h *Handler) GetPromotions(c *gin.Context) {
+	promotions, err := h.service.GetPromotions(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if promotions == nil {
+		promotions = []*models.Promotion{}
+	}
+	c.JSON(http.StatusOK, promotions)
+}
+
+// GetPromotion godoc
+//
+//	@Summary		Get a promotion
+//	@Description	Get a promotion by its ID
+//	@Tags			promotions
+//
+//	@Produce		json
+//	@Param			id	path		int64	true	"Promotion ID"
+//	@Failure		400	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Success		200	{object}	models.Promotion
+//	@Router			/promotions/{id} [get]
+func (h *Handler) GetPromotion(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	p, err := h.service.GetPromotion(c, id)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// CreatePromotion godoc
+//
+//	@Summary		Create a promotion
+//	@Description	Create a discount rule (admin only): percentage takes percent, fixed_amount takes amount per unit, buy_x_get_y takes buy and get, tiered takes tiers. It targets the listed products and categories between starts_at and ends_at
+//	@Tags			promotions
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		models.PromotionRequest	true	"Promotion"
+//	@Param			X-Admin-Token	header		string					true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		201				{object}	models.Promotion
+//	@Router			/promotions/ [post]
+func (h *Handler) CreatePromotion(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	var req models.PromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.service.CreatePromotion(c, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// UpdatePromotion godoc
+//
+//	@Summary		Replace a promotion
+//	@Description	Replace every field of a promotion (admin only)
+//	@Tags			promotions
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int64					true	"Promotion ID"
+//	@Param			request			body		models.PromotionRequest	true	"Promotion"
+//	@Param			X-Admin-Token	header		string					true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.Promotion
+//	@Router			/promotions/{id} [put]
+func (h *Handler) UpdatePromotion(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req models.PromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, err := h.service.UpdatePromotion(c, id, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// DeletePromotion godoc
+//
+//	@Summary		Delete a promotion
+//	@Description	Delete a promotion (admin only); baskets quoted afterwards no longer get it
+//	@Tags			promotions
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Promotion ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	map[string]string
+//	@Router			/promotions/{id} [delete]
+func (h *Handler) DeletePromotion(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeletePromotion(c, id); err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "a promotion has been deleted"})
+}
+
+// Quote godoc
+//
+//	@Summary		Quote a basket
+//	@Description	Price a basket of products with the promotions running now. Each line lists the promotions applied and a trace explaining why every promotion targeting it was applied or skipped. With a jurisdiction every line total is split into net, tax and gross, and the basket sums them
+//	@Tags			promotions
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		models.QuoteRequest	true	"Basket"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		422		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Success		200		{object}	models.BasketQuote
+//	@Router			/pricing/quote [post]
+func (h *Handler) Quote(c *gin.Context) {
+	var req models.QuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q, err := h.service.QuoteBasket(c, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, q)
+}
+
+func parseID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid promotion id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// errorStatus maps the promotion errors to their HTTP status.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, promotion.ErrInvalidPromotion),
+		errors.Is(err, promotion.ErrInvalidBasket):
+		return http.StatusBadRequest
+	case errors.Is(err, promotion.ErrPromotionNotFound),
+		errors.Is(err, promotion.ErrProductNotFound),
+		errors.Is(err, promotion.ErrJurisdictionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, promotion.ErrRateNotFound),
+		errors.Is(err, promotion.ErrNoTaxRate):
+		// the basket is fine, it just cannot be priced or taxed that way yet
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
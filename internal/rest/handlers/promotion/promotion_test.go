@@ -0,0 +1,232 @@
+package promotion_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/resttest"
+	"prodcrud/internal/usecase/promotion"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	start   = time.Date(2025, 8, 15, 0, 0, 0, 0, time.UTC)
+	end     = start.AddDate(0, 0, 14)
+	created = time.Date(2025, 8, 1, 9, 30, 0, 0, time.UTC)
+	percent = money.MustParseRate("12.5")
+
+	request = models.PromotionRequest{
+		Name: "Back to school", Kind: models.PromotionPercentage, Categories: []string{"laptops"},
+		Percent: &percent, Priority: 1, Stackable: true, StartsAt: start, EndsAt: &end,
+	}
+	stored = &models.Promotion{
+		ID: 1, Name: "Back to school", Kind: models.PromotionPercentage, ProductIDs: []int64{}, Categories: []string{"laptops"},
+		Percent: &percent, Priority: 1, Stackable: true, StartsAt: start, EndsAt: &end, CreatedAt: created, UpdatedAt: created,
+	}
+)
+
+func TestHandler_Routes(t *testing.T) {
+	errDB := errors.New("connection refused")
+	basket := models.QuoteRequest{Lines: []models.QuoteLineItem{{ProductID: 1, Quantity: 3}}}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   any
+		admin  bool
+		setup  func(m *promotion.ServiceMock)
+	}{
+		{
+			name: "list", method: http.MethodGet, target: "/promotions/",
+			setup: func(m *promotion.ServiceMock) {
+				m.On("GetPromotions", mock.Anything).Return([]*models.Promotion{stored, {
+					ID: 2, Name: "Bulk cables", Kind: models.PromotionTiered, ProductIDs: []int64{}, Categories: []string{"cables"},
+					Tiers:    []models.PromotionTier{{MinQuantity: 10, Percent: money.MustParseRate("5")}},
+					StartsAt: start, CreatedAt: created, UpdatedAt: created,
+				}}, nil).Once()
+			},
+		},
+		{
+			name: "list_empty", method: http.MethodGet, target: "/promotions/",
+			setup: func(m *promotion.ServiceMock) {
+				m.On("GetPromotions", mock.Anything).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "list_failed", method: http.MethodGet, target: "/promotions/",
+			setup: func(m *promotion.ServiceMock) {
+				m.On("GetPromotions", mock.Anything).Return(nil, errDB).Once()
+			},
+		},
+		{
+			name: "get", method: http.MethodGet, target: "/promotions/1",
+			setup: func(m *promotion.ServiceMock) {
+				m.On("GetPromotion", mock.Anything, int64(1)).Return(stored, nil).Once()
+			},
+		},
+		{
+			name: "get_not_found", method: http.MethodGet, target: "/promotions/9",
+			setup: func(m *promotion.ServiceMock) {
+				m.On("GetPromotion", mock.Anything, int64(9)).Return(nil, promotion.ErrPromotionNotFound).Once()
+			},
+		},
+		{
+			name: "get_invalid_id", method: http.MethodGet, target: "/promotions/abc",
+		},
+		{
+			name: "create", method: http.MethodPost, target: "/promotions/", admin: true, body: request,
+			setup: func(m *promotion.ServiceMock) {
+				m.On("CreatePromotion", mock.Anything, request).Return(stored, nil).Once()
+			},
+		},
+		{
+			name: "create_invalid", method: http.MethodPost, target: "/promotions/", admin: true,
+			body: models.PromotionRequest{Name: "x", Kind: models.PromotionBuyXGetY, ProductIDs: []int64{1}, Buy: 2, StartsAt: start},
+			setup: func(m *promotion.ServiceMock) {
+				m.On("CreatePromotion", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: buy and get must be positive", promotion.ErrInvalidPromotion)).Once()
+			},
+		},
+		{
+			name: "create_invalid_percent", method: http.MethodPost, target: "/promotions/", admin: true,
+			body: `{"name": "x", "kind": "percentage", "percent": "ten"}`,
+		},
+		{
+			name: "create_forbidden", method: http.MethodPost, target: "/promotions/", body: request,
+		},
+		{
+			name: "update", method: http.MethodPut, target: "/promotions/1", admin: true, body: request,
+			setup: func(m *promotion.ServiceMock) {
+				m.On("UpdatePromotion", mock.Anything, int64(1), request).Return(stored, nil).Once()
+			},
+		},
+		{
+			name: "update_not_found", method: http.MethodPut, target: "/promotions/9", admin: true, body: request,
+			setup: func(m *promotion.ServiceMock) {
+				m.On("UpdatePromotion", mock.Anything, int64(9), request).Return(nil, promotion.ErrPromotionNotFound).Once()
+			},
+		},
+		{
+			name: "update_forbidden", method: http.MethodPut, target: "/promotions/1", body: request,
+		},
+		{
+			name: "delete", method: http.MethodDelete, target: "/promotions/1", admin: true,
+			setup: func(m *promotion.ServiceMock) {
+				m.On("DeletePromotion", mock.Anything, int64(1)).Return(nil).Once()
+			},
+		},
+		{
+			name: "delete_not_found", method: http.MethodDelete, target: "/promotions/9", admin: true,
+			setup: func(m *promotion.ServiceMock) {
+				m.On("DeletePromotion", mock.Anything, int64(9)).Return(promotion.ErrPromotionNotFound).Once()
+			},
+		},
+		{
+			name: "delete_forbidden", method: http.MethodDelete, target: "/promotions/1",
+		},
+		{
+			name: "quote", method: http.MethodPost, target: "/pricing/quote", body: basket,
+			setup: func(m *promotion.ServiceMock) {
+				m.On("QuoteBasket", mock.Anything, basket).Return(&models.BasketQuote{
+					Currency: "USD",
+					Lines: []*models.QuoteLine{{
+						ProductID: 1, Quantity: 3, UnitPrice: money.New(2000, "USD"), Subtotal: money.New(6000, "USD"),
+						Discount: money.New(2000, "USD"), Total: money.New(4000, "USD"),
+						Promotions: []models.AppliedPromotion{{PromotionID: 5, Name: "Mice 3 for 2", Kind: models.PromotionBuyXGetY, Discount: money.New(2000, "USD")}},
+						Trace: []string{
+							`promotion 5 "Mice 3 for 2" (buy 2 get 1 free, priority 0) applied: -20.00 USD, line total 40.00 USD`,
+							`promotion 6 "Mice 5%" (5% off, priority -1) skipped: a promotion that does not stack was applied`,
+						},
+					}},
+					Subtotal: money.New(6000, "USD"), Discount: money.New(2000, "USD"), Total: money.New(4000, "USD"),
+				}, nil).Once()
+			},
+		},
+		{
+			name: "quote_invalid_json", method: http.MethodPost, target: "/pricing/quote", body: "{",
+		},
+		{
+			name: "quote_mixed_currencies", method: http.MethodPost, target: "/pricing/quote", body: basket,
+			setup: func(m *promotion.ServiceMock) {
+				m.On("QuoteBasket", mock.Anything, basket).
+					Return(nil, fmt.Errorf("%w: products are priced in USD and EUR, set a currency", promotion.ErrInvalidBasket)).Once()
+			},
+		},
+		{
+			name: "quote_product_not_found", method: http.MethodPost, target: "/pricing/quote", body: basket,
+			setup: func(m *promotion.ServiceMock) {
+				m.On("QuoteBasket", mock.Anything, basket).Return(nil, fmt.Errorf("%w: 1", promotion.ErrProductNotFound)).Once()
+			},
+		},
+		{
+			name: "quote_taxed", method: http.MethodPost, target: "/pricing/quote",
+			body: models.QuoteRequest{Jurisdiction: "US-CA", Lines: basket.Lines},
+			setup: func(m *promotion.ServiceMock) {
+				taxRate := money.MustParseRate("7.25")
+				m.On("QuoteBasket", mock.Anything, models.QuoteRequest{Jurisdiction: "US-CA", Lines: basket.Lines}).Return(&models.BasketQuote{
+					Currency: "USD",
+					Lines: []*models.QuoteLine{{
+						ProductID: 1, Quantity: 3, UnitPrice: money.New(2000, "USD"), Subtotal: money.New(6000, "USD"),
+						Discount: money.New(0, "USD"), Total: money.New(6000, "USD"),
+						Promotions: []models.AppliedPromotion{}, Trace: []string{},
+						Tax: &models.TaxBreakdown{
+							Jurisdiction: "US-CA", TaxClass: models.TaxClassStandard, Rate: &taxRate, Rounding: money.RoundHalfUp,
+							Net: money.New(6000, "USD"), Tax: money.New(435, "USD"), Gross: money.New(6435, "USD"),
+						},
+					}},
+					Subtotal: money.New(6000, "USD"), Discount: money.New(0, "USD"), Total: money.New(6000, "USD"),
+					Tax: &models.BasketTax{
+						Jurisdiction: "US-CA", Net: money.New(6000, "USD"), Tax: money.New(435, "USD"), Gross: money.New(6435, "USD"),
+					},
+				}, nil).Once()
+			},
+		},
+		{
+			name: "quote_no_tax_rate", method: http.MethodPost, target: "/pricing/quote",
+			body: models.QuoteRequest{Jurisdiction: "DE", Lines: basket.Lines},
+			setup: func(m *promotion.ServiceMock) {
+				m.On("QuoteBasket", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: reduced in DE", promotion.ErrNoTaxRate)).Once()
+			},
+		},
+		{
+			name: "quote_unknown_jurisdiction", method: http.MethodPost, target: "/pricing/quote",
+			body: models.QuoteRequest{Jurisdiction: "FR", Lines: basket.Lines},
+			setup: func(m *promotion.ServiceMock) {
+				m.On("QuoteBasket", mock.Anything, mock.Anything).Return(nil, promotion.ErrJurisdictionNotFound).Once()
+			},
+		},
+		{
+			name: "quote_no_rate", method: http.MethodPost, target: "/pricing/quote",
+			body: models.QuoteRequest{Currency: "JPY", Lines: basket.Lines},
+			setup: func(m *promotion.ServiceMock) {
+				m.On("QuoteBasket", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: USD/JPY", promotion.ErrRateNotFound)).Once()
+			},
+		},
+	}
+
+	svc := new(promotion.ServiceMock)
+	h := resttest.New(t, resttest.Services{Promotion: svc})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(svc)
+			}
+			do := h.Do
+			if tt.admin {
+				do = h.DoAdmin
+			}
+			rec := do(tt.method, tt.target, tt.body)
+			h.Golden("promotion_"+tt.name, rec)
+		})
+	}
+	svc.AssertExpectations(t)
+	h.AssertCovered("promotions")
+}
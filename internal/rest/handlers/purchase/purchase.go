@@ -0,0 +1,299 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/access"
+	"prodcrud/internal/usecase/purchase"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service purchase.ServiceInterface
+}
+
+func NewHandler(service purchase.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+// GetOrders godoc
+//
+//	@Summary		List purchase orders
+//	@Description	List purchase orders, newest first (admin only)
+//	@Tags			purchasing
+//
+//	@Produce		json
+//	@Param			supplier_id		query		int64	false	"Only the orders of this supplier"
+//	@Param			status			query		string	false	"Only the orders in this status"	Enums(draft, sent, partially_received, received, cancelled)
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	[]models.PurchaseOrder
+//	@Router			/purchase-orders/ [get]
+func (h *Handler) GetOrders(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	f := models.PurchaseOrderFilter{Status: c.Query("status")}
+	if raw := c.Query("supplier_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			_ = c.Error(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid supplier id"})
+			return
+		}
+		f.SupplierID = id
+	}
+	orders, err := h.service.GetOrders(c, f)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if orders == nil {
+		orders = []*models.PurchaseOrder{}
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+// GetOrder godoc
+//
+//	@Summary		Get a purchase order
+//	@Description	Get a purchase order by its ID, with its lines and the quantities received (admin only)
+//	@Tags			purchasing
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Purchase order ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.PurchaseOrder
+//	@Router			/purchase-orders/{id} [get]
+func (h *Handler) GetOrder(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c, "id", "invalid purchase order id")
+	if !ok {
+		return
+	}
+	o, err := h.service.GetOrder(c, id)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+// CreateOrder godoc
+//
+//	@Summary		Draft a purchase order
+//	@Description	Draft an order of products the supplier sells (admin only). A line costs the supplier cost unless it sets its own, in the supplier currency
+//	@Tags			purchasing
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		models.PurchaseOrderRequest	true	"Purchase order"
+//	@Param			X-Admin-Token	header		string						true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		201				{object}	models.PurchaseOrder
+//	@Router			/purchase-orders/ [post]
+func (h *Handler) CreateOrder(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	var req models.PurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	o, err := h.service.CreateOrder(c, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, o)
+}
+
+// UpdateOrder godoc
+//
+//	@Summary		Update a draft purchase order
+//	@Description	Replace the notes and lines of a draft (admin only). The supplier cannot change, and orders are no longer edited once sent
+//	@Tags			purchasing
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int64						true	"Purchase order ID"
+//	@Param			request			body		models.PurchaseOrderRequest	true	"Purchase order"
+//	@Param			X-Admin-Token	header		string						true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.PurchaseOrder
+//	@Router			/purchase-orders/{id} [put]
+func (h *Handler) UpdateOrder(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c, "id", "invalid purchase order id")
+	if !ok {
+		return
+	}
+	var req models.PurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	o, err := h.service.UpdateOrder(c, id, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+// SendOrder godoc
+//
+//	@Summary		Send a purchase order
+//	@Description	Mark a draft as sent to its supplier (admin only). It is expected after the longest lead time of its products
+//	@Tags			purchasing
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Purchase order ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.PurchaseOrder
+//	@Router			/purchase-orders/{id}/send [post]
+func (h *Handler) SendOrder(c *gin.Context) {
+	h.transition(c, h.service.SendOrder)
+}
+
+// CancelOrder godoc
+//
+//	@Summary		Cancel a purchase order
+//	@Description	Cancel an order until it is fully received (admin only). The units received already stay in stock
+//	@Tags			purchasing
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Purchase order ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.PurchaseOrder
+//	@Router			/purchase-orders/{id}/cancel [post]
+func (h *Handler) CancelOrder(c *gin.Context) {
+	h.transition(c, h.service.CancelOrder)
+}
+
+func (h *Handler) transition(c *gin.Context, fn func(ctx context.Context, id int64) (*models.PurchaseOrder, error)) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c, "id", "invalid purchase order id")
+	if !ok {
+		return
+	}
+	o, err := fn(c, id)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+// ReceiveOrder godoc
+//
+//	@Summary		Receive a purchase order
+//	@Description	Record the delivery of some or all of the ordered units of a sent order (admin only). The received units are added to the product stock in the same transaction, and the order becomes received once every line is complete
+//	@Tags			purchasing
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int64					true	"Purchase order ID"
+//	@Param			request			body		models.ReceiptRequest	true	"Received quantities by line"
+//	@Param			X-Admin-Token	header		string					true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.PurchaseOrder
+//	@Router			/purchase-orders/{id}/receive [post]
+func (h *Handler) ReceiveOrder(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c, "id", "invalid purchase order id")
+	if !ok {
+		return
+	}
+	var req models.ReceiptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	o, err := h.service.ReceiveOrder(c, id, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+func parseID(c *gin.Context, name, msg string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return 0, false
+	}
+	return id, true
+}
+
+// errorStatus maps the purchasing errors to their HTTP status.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, purchase.ErrInvalidSupplier), errors.Is(err, purchase.ErrInvalidOrder),
+		errors.Is(err, purchase.ErrInvalidReceipt):
+		return http.StatusBadRequest
+	case errors.Is(err, purchase.ErrSupplierNotFound), errors.Is(err, purchase.ErrSupplierProductNotFound),
+		errors.Is(err, purchase.ErrProductNotFound), errors.Is(err, purchase.ErrOrderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, purchase.ErrSupplierInUse), errors.Is(err, purchase.ErrInvalidStatus):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,412 @@
+package purchase_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/resttest"
+	"prodcrud/internal/usecase/purchase"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	created  = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	sent     = time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC)
+	expected = sent.AddDate(0, 0, 7)
+
+	acme = &models.Supplier{
+		ID: 1, Name: "Acme Wholesale", Email: "orders@acme.example", Currency: "EUR", CreatedAt: created, UpdatedAt: created,
+	}
+	supplierRequest = models.SupplierRequest{Name: "Acme Wholesale", Email: "orders@acme.example", Currency: "EUR"}
+	linkRequest     = models.SupplierProductRequest{SKU: "ACME-1042", Cost: money.New(1200, "EUR"), LeadTimeDays: 7}
+	orderRequest    = models.PurchaseOrderRequest{SupplierID: 1, Notes: "restock", Lines: []models.PurchaseOrderLineRequest{
+		{ProductID: 3, Quantity: 50}, {ProductID: 4, Quantity: 20},
+	}}
+	receipt = models.ReceiptRequest{Lines: []models.ReceiptLine{{LineID: 7, Quantity: 20}}}
+)
+
+func order(status string) *models.PurchaseOrder {
+	o := &models.PurchaseOrder{
+		ID: 5, SupplierID: 1, Status: status, Currency: "EUR", Notes: "restock",
+		Lines: []*models.PurchaseOrderLine{
+			{ID: 7, ProductID: 3, Quantity: 50, Cost: money.New(1200, "EUR")},
+			{ID: 8, ProductID: 4, Quantity: 20, Cost: money.New(450, "EUR")},
+		},
+		Total: money.New(69000, "EUR"), CreatedAt: created, UpdatedAt: created,
+	}
+	if status != models.PurchaseDraft {
+		o.SentAt, o.ExpectedAt, o.UpdatedAt = &sent, &expected, sent
+	}
+	return o
+}
+
+func TestHandler_Routes(t *testing.T) {
+	errDB := errors.New("connection refused")
+	partial := order(models.PurchasePartiallyReceived)
+	partial.Lines[0].Received = 20
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   any
+		admin  bool
+		setup  func(m *purchase.ServiceMock)
+	}{
+		{
+			name: "suppliers", method: http.MethodGet, target: "/suppliers/", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetSuppliers", mock.Anything).Return([]*models.Supplier{acme, {
+					ID: 2, Name: "Globex", Currency: "USD", CreatedAt: created, UpdatedAt: created,
+				}}, nil).Once()
+			},
+		},
+		{
+			name: "suppliers_empty", method: http.MethodGet, target: "/suppliers/", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetSuppliers", mock.Anything).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "suppliers_forbidden", method: http.MethodGet, target: "/suppliers/",
+		},
+		{
+			name: "suppliers_failed", method: http.MethodGet, target: "/suppliers/", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetSuppliers", mock.Anything).Return(nil, errDB).Once()
+			},
+		},
+		{
+			name: "supplier", method: http.MethodGet, target: "/suppliers/1", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetSupplier", mock.Anything, int64(1)).Return(acme, nil).Once()
+			},
+		},
+		{
+			name: "supplier_forbidden", method: http.MethodGet, target: "/suppliers/1",
+		},
+		{
+			name: "supplier_invalid_id", method: http.MethodGet, target: "/suppliers/abc", admin: true,
+		},
+		{
+			name: "supplier_not_found", method: http.MethodGet, target: "/suppliers/9", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetSupplier", mock.Anything, int64(9)).Return(nil, purchase.ErrSupplierNotFound).Once()
+			},
+		},
+		{
+			name: "create_supplier", method: http.MethodPost, target: "/suppliers/", admin: true, body: supplierRequest,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("CreateSupplier", mock.Anything, supplierRequest).Return(acme, nil).Once()
+			},
+		},
+		{
+			name: "create_supplier_forbidden", method: http.MethodPost, target: "/suppliers/", body: supplierRequest,
+		},
+		{
+			name: "create_supplier_invalid_json", method: http.MethodPost, target: "/suppliers/", admin: true, body: "{",
+		},
+		{
+			name: "create_supplier_invalid", method: http.MethodPost, target: "/suppliers/", admin: true,
+			body: models.SupplierRequest{Name: "Acme", Currency: "XXX"},
+			setup: func(m *purchase.ServiceMock) {
+				m.On("CreateSupplier", mock.Anything, models.SupplierRequest{Name: "Acme", Currency: "XXX"}).
+					Return(nil, fmt.Errorf("%w: %w", purchase.ErrInvalidSupplier, money.ErrUnknownCurrency)).Once()
+			},
+		},
+		{
+			name: "update_supplier", method: http.MethodPut, target: "/suppliers/1", admin: true, body: supplierRequest,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("UpdateSupplier", mock.Anything, int64(1), supplierRequest).Return(acme, nil).Once()
+			},
+		},
+		{
+			name: "update_supplier_forbidden", method: http.MethodPut, target: "/suppliers/1", body: supplierRequest,
+		},
+		{
+			name: "update_supplier_invalid_json", method: http.MethodPut, target: "/suppliers/1", admin: true, body: "{",
+		},
+		{
+			name: "update_supplier_invalid", method: http.MethodPut, target: "/suppliers/1", admin: true,
+			body: models.SupplierRequest{Name: "Acme", Currency: "USD"},
+			setup: func(m *purchase.ServiceMock) {
+				m.On("UpdateSupplier", mock.Anything, int64(1), mock.Anything).Return(nil,
+					fmt.Errorf("%w: the currency of a supplier with products cannot change", purchase.ErrInvalidSupplier)).Once()
+			},
+		},
+		{
+			name: "update_supplier_not_found", method: http.MethodPut, target: "/suppliers/9", admin: true, body: supplierRequest,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("UpdateSupplier", mock.Anything, int64(9), supplierRequest).Return(nil, purchase.ErrSupplierNotFound).Once()
+			},
+		},
+		{
+			name: "delete_supplier", method: http.MethodDelete, target: "/suppliers/2", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("DeleteSupplier", mock.Anything, int64(2)).Return(nil).Once()
+			},
+		},
+		{
+			name: "delete_supplier_forbidden", method: http.MethodDelete, target: "/suppliers/2",
+		},
+		{
+			name: "delete_supplier_in_use", method: http.MethodDelete, target: "/suppliers/1", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("DeleteSupplier", mock.Anything, int64(1)).Return(purchase.ErrSupplierInUse).Once()
+			},
+		},
+		{
+			name: "supplier_products", method: http.MethodGet, target: "/suppliers/1/products", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetSupplierProducts", mock.Anything, int64(1)).Return([]*models.SupplierProduct{
+					{SupplierID: 1, ProductID: 3, SKU: "ACME-1042", Cost: money.New(1200, "EUR"), LeadTimeDays: 7, UpdatedAt: created},
+					{SupplierID: 1, ProductID: 4, Cost: money.New(450, "EUR"), LeadTimeDays: 2, UpdatedAt: created},
+				}, nil).Once()
+			},
+		},
+		{
+			name: "supplier_products_empty", method: http.MethodGet, target: "/suppliers/2/products", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetSupplierProducts", mock.Anything, int64(2)).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "supplier_products_forbidden", method: http.MethodGet, target: "/suppliers/1/products",
+		},
+		{
+			name: "supplier_products_not_found", method: http.MethodGet, target: "/suppliers/9/products", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetSupplierProducts", mock.Anything, int64(9)).Return(nil, purchase.ErrSupplierNotFound).Once()
+			},
+		},
+		{
+			name: "set_supplier_product", method: http.MethodPut, target: "/suppliers/1/products/3", admin: true, body: linkRequest,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("SetSupplierProduct", mock.Anything, int64(1), int64(3), linkRequest).Return(&models.SupplierProduct{
+					SupplierID: 1, ProductID: 3, SKU: "ACME-1042", Cost: money.New(1200, "EUR"), LeadTimeDays: 7, UpdatedAt: created,
+				}, nil).Once()
+			},
+		},
+		{
+			name: "set_supplier_product_forbidden", method: http.MethodPut, target: "/suppliers/1/products/3", body: linkRequest,
+		},
+		{
+			name: "set_supplier_product_invalid_id", method: http.MethodPut, target: "/suppliers/1/products/abc", admin: true, body: linkRequest,
+		},
+		{
+			name: "set_supplier_product_invalid_json", method: http.MethodPut, target: "/suppliers/1/products/3", admin: true, body: "{",
+		},
+		{
+			name: "set_supplier_product_invalid", method: http.MethodPut, target: "/suppliers/1/products/3", admin: true,
+			body: models.SupplierProductRequest{Cost: money.New(1200, "USD")},
+			setup: func(m *purchase.ServiceMock) {
+				m.On("SetSupplierProduct", mock.Anything, int64(1), int64(3), mock.Anything).Return(nil,
+					fmt.Errorf("%w: cost must be in EUR, the currency of the supplier", purchase.ErrInvalidSupplier)).Once()
+			},
+		},
+		{
+			name: "set_supplier_product_not_found", method: http.MethodPut, target: "/suppliers/1/products/9", admin: true, body: linkRequest,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("SetSupplierProduct", mock.Anything, int64(1), int64(9), linkRequest).Return(nil, purchase.ErrProductNotFound).Once()
+			},
+		},
+		{
+			name: "delete_supplier_product", method: http.MethodDelete, target: "/suppliers/1/products/3", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("DeleteSupplierProduct", mock.Anything, int64(1), int64(3)).Return(nil).Once()
+			},
+		},
+		{
+			name: "delete_supplier_product_forbidden", method: http.MethodDelete, target: "/suppliers/1/products/3",
+		},
+		{
+			name: "delete_supplier_product_not_found", method: http.MethodDelete, target: "/suppliers/1/products/9", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("DeleteSupplierProduct", mock.Anything, int64(1), int64(9)).Return(purchase.ErrSupplierProductNotFound).Once()
+			},
+		},
+		{
+			name: "orders", method: http.MethodGet, target: "/purchase-orders/", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetOrders", mock.Anything, models.PurchaseOrderFilter{}).
+					Return([]*models.PurchaseOrder{partial, order(models.PurchaseDraft)}, nil).Once()
+			},
+		},
+		{
+			name: "orders_filtered", method: http.MethodGet, target: "/purchase-orders/?supplier_id=1&status=draft", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetOrders", mock.Anything, models.PurchaseOrderFilter{SupplierID: 1, Status: models.PurchaseDraft}).
+					Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "orders_forbidden", method: http.MethodGet, target: "/purchase-orders/",
+		},
+		{
+			name: "orders_invalid_supplier", method: http.MethodGet, target: "/purchase-orders/?supplier_id=acme", admin: true,
+		},
+		{
+			name: "orders_invalid_status", method: http.MethodGet, target: "/purchase-orders/?status=lost", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetOrders", mock.Anything, models.PurchaseOrderFilter{Status: "lost"}).
+					Return(nil, fmt.Errorf("%w: unknown status lost", purchase.ErrInvalidOrder)).Once()
+			},
+		},
+		{
+			name: "orders_failed", method: http.MethodGet, target: "/purchase-orders/", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetOrders", mock.Anything, models.PurchaseOrderFilter{}).Return(nil, errDB).Once()
+			},
+		},
+		{
+			name: "order", method: http.MethodGet, target: "/purchase-orders/5", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetOrder", mock.Anything, int64(5)).Return(partial, nil).Once()
+			},
+		},
+		{
+			name: "order_forbidden", method: http.MethodGet, target: "/purchase-orders/5",
+		},
+		{
+			name: "order_invalid_id", method: http.MethodGet, target: "/purchase-orders/abc", admin: true,
+		},
+		{
+			name: "order_not_found", method: http.MethodGet, target: "/purchase-orders/9", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("GetOrder", mock.Anything, int64(9)).Return(nil, purchase.ErrOrderNotFound).Once()
+			},
+		},
+		{
+			name: "create_order", method: http.MethodPost, target: "/purchase-orders/", admin: true, body: orderRequest,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("CreateOrder", mock.Anything, orderRequest).Return(order(models.PurchaseDraft), nil).Once()
+			},
+		},
+		{
+			name: "create_order_forbidden", method: http.MethodPost, target: "/purchase-orders/", body: orderRequest,
+		},
+		{
+			name: "create_order_invalid_json", method: http.MethodPost, target: "/purchase-orders/", admin: true, body: "{",
+		},
+		{
+			name: "create_order_invalid", method: http.MethodPost, target: "/purchase-orders/", admin: true,
+			body: models.PurchaseOrderRequest{SupplierID: 1, Lines: []models.PurchaseOrderLineRequest{{ProductID: 9, Quantity: 1}}},
+			setup: func(m *purchase.ServiceMock) {
+				m.On("CreateOrder", mock.Anything, mock.Anything).Return(nil,
+					fmt.Errorf("%w: the supplier does not sell product 9", purchase.ErrInvalidOrder)).Once()
+			},
+		},
+		{
+			name: "update_order", method: http.MethodPut, target: "/purchase-orders/5", admin: true, body: orderRequest,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("UpdateOrder", mock.Anything, int64(5), orderRequest).Return(order(models.PurchaseDraft), nil).Once()
+			},
+		},
+		{
+			name: "update_order_forbidden", method: http.MethodPut, target: "/purchase-orders/5", body: orderRequest,
+		},
+		{
+			name: "update_order_invalid_json", method: http.MethodPut, target: "/purchase-orders/5", admin: true, body: "{",
+		},
+		{
+			name: "update_order_sent", method: http.MethodPut, target: "/purchase-orders/5", admin: true, body: orderRequest,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("UpdateOrder", mock.Anything, int64(5), orderRequest).
+					Return(nil, fmt.Errorf("%w: cannot edit a sent order", purchase.ErrInvalidStatus)).Once()
+			},
+		},
+		{
+			name: "send_order", method: http.MethodPost, target: "/purchase-orders/5/send", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("SendOrder", mock.Anything, int64(5)).Return(order(models.PurchaseSent), nil).Once()
+			},
+		},
+		{
+			name: "send_order_forbidden", method: http.MethodPost, target: "/purchase-orders/5/send",
+		},
+		{
+			name: "send_order_invalid_id", method: http.MethodPost, target: "/purchase-orders/abc/send", admin: true,
+		},
+		{
+			name: "send_order_not_found", method: http.MethodPost, target: "/purchase-orders/9/send", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("SendOrder", mock.Anything, int64(9)).Return(nil, purchase.ErrOrderNotFound).Once()
+			},
+		},
+		{
+			name: "cancel_order", method: http.MethodPost, target: "/purchase-orders/5/cancel", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("CancelOrder", mock.Anything, int64(5)).Return(order(models.PurchaseCancelled), nil).Once()
+			},
+		},
+		{
+			name: "cancel_order_forbidden", method: http.MethodPost, target: "/purchase-orders/5/cancel",
+		},
+		{
+			name: "cancel_order_received", method: http.MethodPost, target: "/purchase-orders/5/cancel", admin: true,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("CancelOrder", mock.Anything, int64(5)).
+					Return(nil, fmt.Errorf("%w: cannot cancel a received order", purchase.ErrInvalidStatus)).Once()
+			},
+		},
+		{
+			name: "receive_order", method: http.MethodPost, target: "/purchase-orders/5/receive", admin: true, body: receipt,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("ReceiveOrder", mock.Anything, int64(5), receipt).Return(partial, nil).Once()
+			},
+		},
+		{
+			name: "receive_order_forbidden", method: http.MethodPost, target: "/purchase-orders/5/receive", body: receipt,
+		},
+		{
+			name: "receive_order_invalid_id", method: http.MethodPost, target: "/purchase-orders/abc/receive", admin: true, body: receipt,
+		},
+		{
+			name: "receive_order_invalid_json", method: http.MethodPost, target: "/purchase-orders/5/receive", admin: true, body: "{",
+		},
+		{
+			name: "receive_order_invalid", method: http.MethodPost, target: "/purchase-orders/5/receive", admin: true,
+			body: models.ReceiptRequest{Lines: []models.ReceiptLine{{LineID: 7, Quantity: 60}}},
+			setup: func(m *purchase.ServiceMock) {
+				m.On("ReceiveOrder", mock.Anything, int64(5), mock.Anything).
+					Return(nil, fmt.Errorf("%w: line 7 has 50 units left to receive", purchase.ErrInvalidReceipt)).Once()
+			},
+		},
+		{
+			name: "receive_order_draft", method: http.MethodPost, target: "/purchase-orders/5/receive", admin: true, body: receipt,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("ReceiveOrder", mock.Anything, int64(5), receipt).
+					Return(nil, fmt.Errorf("%w: cannot receive a draft order", purchase.ErrInvalidStatus)).Once()
+			},
+		},
+		{
+			name: "receive_order_failed", method: http.MethodPost, target: "/purchase-orders/5/receive", admin: true, body: receipt,
+			setup: func(m *purchase.ServiceMock) {
+				m.On("ReceiveOrder", mock.Anything, int64(5), receipt).Return(nil, errors.New("failed to receive purchase order usc")).Once()
+			},
+		},
+	}
+
+	svc := new(purchase.ServiceMock)
+	h := resttest.New(t, resttest.Services{Purchase: svc})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(svc)
+			}
+			do := h.Do
+			if tt.admin {
+				do = h.DoAdmin
+			}
+			rec := do(tt.method, tt.target, tt.body)
+			h.Golden("purchasing_"+tt.name, rec)
+		})
+	}
+	svc.AssertExpectations(t)
+	h.AssertCovered("purchasing")
+}
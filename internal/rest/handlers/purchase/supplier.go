@@ -0,0 +1,299 @@
+package purchase
+
+import (
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/access"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSuppliers godoc
+//
+//	@Summary		List suppliers
+//	@Description	List every supplier by id (admin only)
+//	@Tags			purchasing
+//
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		403				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	[]models.Supplier
+//	@Router			/suppliers/ [get]
+func (h *Handler) GetSuppliers(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	suppliers, err := h.service.GetSuppliers(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if suppliers == nil {
+		suppliers = []*models.Supplier{}
+	}
+	c.JSON(http.StatusOK, suppliers)
+}
+
+// GetSupplier godoc
+//
+//	@Summary		Get a supplier
+//	@Description	Get a supplier by its ID (admin only)
+//	@Tags			purchasing
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Supplier ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.Supplier
+//	@Router			/suppliers/{id} [get]
+func (h *Handler) GetSupplier(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c, "id", "invalid supplier id")
+	if !ok {
+		return
+	}
+	s, err := h.service.GetSupplier(c, id)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// CreateSupplier godoc
+//
+//	@Summary		Create a supplier
+//	@Description	Create a supplier (admin only). Its costs and purchase orders are in its currency
+//	@Tags			purchasing
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		models.SupplierRequest	true	"Supplier"
+//	@Param			X-Admin-Token	header		string					true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		201				{object}	models.Supplier
+//	@Router			/suppliers/ [post]
+func (h *Handler) CreateSupplier(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	var req models.SupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	s, err := h.service.CreateSupplier(c, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, s)
+}
+
+// UpdateSupplier godoc
+//
+//	@Summary		Update a supplier
+//	@Description	Replace a supplier (admin only). Its currency cannot change once it sells products
+//	@Tags			purchasing
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int64					true	"Supplier ID"
+//	@Param			request			body		models.SupplierRequest	true	"Supplier"
+//	@Param			X-Admin-Token	header		string					true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.Supplier
+//	@Router			/suppliers/{id} [put]
+func (h *Handler) UpdateSupplier(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c, "id", "invalid supplier id")
+	if !ok {
+		return
+	}
+	var req models.SupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	s, err := h.service.UpdateSupplier(c, id, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// DeleteSupplier godoc
+//
+//	@Summary		Delete a supplier
+//	@Description	Delete a supplier with the products it sells (admin only). A supplier with purchase orders is kept
+//	@Tags			purchasing
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Supplier ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	map[string]string
+//	@Router			/suppliers/{id} [delete]
+func (h *Handler) DeleteSupplier(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c, "id", "invalid supplier id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteSupplier(c, id); err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "a supplier has been deleted"})
+}
+
+// GetSupplierProducts godoc
+//
+//	@Summary		List supplier products
+//	@Description	List the products a supplier sells, with their cost and lead time, by product id (admin only)
+//	@Tags			purchasing
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Supplier ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	[]models.SupplierProduct
+//	@Router			/suppliers/{id}/products [get]
+func (h *Handler) GetSupplierProducts(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c, "id", "invalid supplier id")
+	if !ok {
+		return
+	}
+	links, err := h.service.GetSupplierProducts(c, id)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if links == nil {
+		links = []*models.SupplierProduct{}
+	}
+	c.JSON(http.StatusOK, links)
+}
+
+// SetSupplierProduct godoc
+//
+//	@Summary		Set a supplier product
+//	@Description	Create or replace the cost price and lead time of a product at a supplier (admin only). The cost is in the supplier currency
+//	@Tags			purchasing
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int64							true	"Supplier ID"
+//	@Param			product_id		path		int64							true	"Product ID"
+//	@Param			request			body		models.SupplierProductRequest	true	"Cost and lead time"
+//	@Param			X-Admin-Token	header		string							true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.SupplierProduct
+//	@Router			/suppliers/{id}/products/{product_id} [put]
+func (h *Handler) SetSupplierProduct(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c, "id", "invalid supplier id")
+	if !ok {
+		return
+	}
+	productID, ok := parseID(c, "product_id", "invalid product id")
+	if !ok {
+		return
+	}
+	var req models.SupplierProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sp, err := h.service.SetSupplierProduct(c, id, productID, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sp)
+}
+
+// DeleteSupplierProduct godoc
+//
+//	@Summary		Delete a supplier product
+//	@Description	Stop buying a product from a supplier (admin only). Its purchase orders are kept
+//	@Tags			purchasing
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Supplier ID"
+//	@Param			product_id		path		int64	true	"Product ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	map[string]string
+//	@Router			/suppliers/{id}/products/{product_id} [delete]
+func (h *Handler) DeleteSupplierProduct(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c, "id", "invalid supplier id")
+	if !ok {
+		return
+	}
+	productID, ok := parseID(c, "product_id", "invalid product id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteSupplierProduct(c, id, productID); err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "a supplier product has been deleted"})
+}
@@ -0,0 +1,177 @@
+package reorder
+
+import (
+	"errors"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/access"
+	"prodcrud/internal/usecase/reorder"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service reorder.ServiceInterface
+}
+
+func NewHandler(service reorder.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+// GetLowStock godoc
+//
+//	@Summary		List low stock products
+//	@Description	List the active products at or below their reorder point, by ID (admin only). An alert is raised for them on the next pass of the evaluator
+//	@Tags			reorder
+//
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		403				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	[]models.LowStockProduct
+//	@Router			/products/low-stock [get]
+func (h *Handler) GetLowStock(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	products, err := h.service.GetLowStock(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if products == nil {
+		products = []*models.LowStockProduct{}
+	}
+	c.JSON(http.StatusOK, products)
+}
+
+// GetRule godoc
+//
+//	@Summary		Get a reorder rule
+//	@Description	Get the reorder point and quantity of a product (admin only)
+//	@Tags			reorder
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Product ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.ReorderRule
+//	@Router			/products/{id}/reorder [get]
+func (h *Handler) GetRule(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	rule, err := h.service.GetRule(c, id)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// SetRule godoc
+//
+//	@Summary		Set a reorder rule
+//	@Description	Create or replace the reorder rule of a product (admin only). The product is low on stock at or below the reorder point; with automatic drafts, the reorder quantity is ordered from the supplier, which must sell the product
+//	@Tags			reorder
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int64						true	"Product ID"
+//	@Param			request			body		models.ReorderRuleRequest	true	"Reorder rule"
+//	@Param			X-Admin-Token	header		string						true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.ReorderRule
+//	@Router			/products/{id}/reorder [put]
+func (h *Handler) SetRule(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req models.ReorderRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule, err := h.service.SetRule(c, id, req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule godoc
+//
+//	@Summary		Delete a reorder rule
+//	@Description	Stop watching the stock of a product (admin only). Its alert is cleared on the next pass of the evaluator
+//	@Tags			reorder
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Product ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	map[string]string
+//	@Router			/products/{id}/reorder [delete]
+func (h *Handler) DeleteRule(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteRule(c, id); err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "a reorder rule has been deleted"})
+}
+
+func parseID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// errorStatus maps the reorder errors to their HTTP status.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, reorder.ErrInvalidRule):
+		return http.StatusBadRequest
+	case errors.Is(err, reorder.ErrRuleNotFound), errors.Is(err, reorder.ErrProductNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,144 @@
+package reorder_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/resttest"
+	"prodcrud/internal/usecase/reorder"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	flagged  = time.Date(2025, 3, 4, 8, 0, 0, 0, time.UTC)
+	notified = time.Date(2025, 3, 4, 8, 0, 2, 0, time.UTC)
+	updated  = time.Date(2025, 2, 20, 9, 30, 0, 0, time.UTC)
+
+	supplierID  = int64(2)
+	draftID     = int64(7)
+	ruleRequest = models.ReorderRuleRequest{ReorderPoint: 5, ReorderQuantity: 50, SupplierID: &supplierID}
+	rule        = &models.ReorderRule{
+		ProductID: 1, ReorderPoint: 5, ReorderQuantity: 50, SupplierID: &supplierID, UpdatedAt: updated,
+	}
+)
+
+func TestHandler_Routes(t *testing.T) {
+	errDB := errors.New("connection refused")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   any
+		admin  bool
+		setup  func(m *reorder.ServiceMock)
+	}{
+		{
+			name: "low_stock", method: http.MethodGet, target: "/products/low-stock", admin: true,
+			setup: func(m *reorder.ServiceMock) {
+				m.On("GetLowStock", mock.Anything).Return([]*models.LowStockProduct{
+					{
+						ProductID: 1, Name: "pen", Quantity: 3, ReorderPoint: 5, ReorderQuantity: 50, SupplierID: &supplierID,
+						FlaggedAt: &flagged, NotifiedAt: &notified, PurchaseOrderID: &draftID,
+					},
+					{ProductID: 4, Name: "ink", Quantity: 0, ReorderPoint: 0, ReorderQuantity: 10},
+				}, nil).Once()
+			},
+		},
+		{
+			name: "low_stock_empty", method: http.MethodGet, target: "/products/low-stock", admin: true,
+			setup: func(m *reorder.ServiceMock) {
+				m.On("GetLowStock", mock.Anything).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "low_stock_forbidden", method: http.MethodGet, target: "/products/low-stock",
+		},
+		{
+			name: "low_stock_failed", method: http.MethodGet, target: "/products/low-stock", admin: true,
+			setup: func(m *reorder.ServiceMock) {
+				m.On("GetLowStock", mock.Anything).Return(nil, errDB).Once()
+			},
+		},
+		{
+			name: "rule", method: http.MethodGet, target: "/products/1/reorder", admin: true,
+			setup: func(m *reorder.ServiceMock) {
+				m.On("GetRule", mock.Anything, int64(1)).Return(rule, nil).Once()
+			},
+		},
+		{
+			name: "rule_forbidden", method: http.MethodGet, target: "/products/1/reorder",
+		},
+		{
+			name: "rule_invalid_id", method: http.MethodGet, target: "/products/abc/reorder", admin: true,
+		},
+		{
+			name: "rule_not_found", method: http.MethodGet, target: "/products/9/reorder", admin: true,
+			setup: func(m *reorder.ServiceMock) {
+				m.On("GetRule", mock.Anything, int64(9)).Return(nil, reorder.ErrRuleNotFound).Once()
+			},
+		},
+		{
+			name: "set_rule", method: http.MethodPut, target: "/products/1/reorder", admin: true, body: ruleRequest,
+			setup: func(m *reorder.ServiceMock) {
+				m.On("SetRule", mock.Anything, int64(1), ruleRequest).Return(rule, nil).Once()
+			},
+		},
+		{
+			name: "set_rule_forbidden", method: http.MethodPut, target: "/products/1/reorder", body: ruleRequest,
+		},
+		{
+			name: "set_rule_invalid_json", method: http.MethodPut, target: "/products/1/reorder", admin: true, body: "{",
+		},
+		{
+			name: "set_rule_invalid", method: http.MethodPut, target: "/products/1/reorder", admin: true, body: ruleRequest,
+			setup: func(m *reorder.ServiceMock) {
+				m.On("SetRule", mock.Anything, int64(1), ruleRequest).
+					Return(nil, fmt.Errorf("%w: supplier 2 does not sell product 1", reorder.ErrInvalidRule)).Once()
+			},
+		},
+		{
+			name: "set_rule_not_found", method: http.MethodPut, target: "/products/9/reorder", admin: true, body: ruleRequest,
+			setup: func(m *reorder.ServiceMock) {
+				m.On("SetRule", mock.Anything, int64(9), ruleRequest).Return(nil, reorder.ErrProductNotFound).Once()
+			},
+		},
+		{
+			name: "delete_rule", method: http.MethodDelete, target: "/products/1/reorder", admin: true,
+			setup: func(m *reorder.ServiceMock) {
+				m.On("DeleteRule", mock.Anything, int64(1)).Return(nil).Once()
+			},
+		},
+		{
+			name: "delete_rule_forbidden", method: http.MethodDelete, target: "/products/1/reorder",
+		},
+		{
+			name: "delete_rule_not_found", method: http.MethodDelete, target: "/products/9/reorder", admin: true,
+			setup: func(m *reorder.ServiceMock) {
+				m.On("DeleteRule", mock.Anything, int64(9)).Return(reorder.ErrRuleNotFound).Once()
+			},
+		},
+	}
+
+	svc := new(reorder.ServiceMock)
+	h := resttest.New(t, resttest.Services{Reorder: svc})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(svc)
+			}
+			do := h.Do
+			if tt.admin {
+				do = h.DoAdmin
+			}
+			rec := do(tt.method, tt.target, tt.body)
+			h.Golden("reorder_"+tt.name, rec)
+		})
+	}
+	svc.AssertExpectations(t)
+	h.AssertCovered("reorder")
+}
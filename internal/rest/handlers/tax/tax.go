@@ -0,0 +1,220 @@
+package tax
+
+import (
+	"errors"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/access"
+	"prodcrud/internal/usecase/tax"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service tax.ServiceInterface
+}
+
+func NewHandler(service tax.ServiceInterface) *Handler {
+	return &Handler{service: service}
+}
+
+// GetJurisdictions godoc
+//
+//	@Summary		List tax jurisdictions
+//	@Description	List every tax jurisdiction by code
+//	@Tags			tax
+//
+//	@Produce		json
+//	@Failure		500	{object}	map[string]string
+//	@Success		200	{object}	[]models.TaxJurisdiction
+//	@Router			/tax/jurisdictions [get]
+func (h *Handler) GetJurisdictions(c *gin.Context) {
+	jurisdictions, err := h.service.GetJurisdictions(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if jurisdictions == nil {
+		jurisdictions = []*models.TaxJurisdiction{}
+	}
+	c.JSON(http.StatusOK, jurisdictions)
+}
+
+// SetJurisdiction godoc
+//
+//	@Summary		Set a tax jurisdiction
+//	@Description	Create or replace a tax jurisdiction (admin only). prices_include_tax tells whether product prices there are gross, rounding how the tax is brought to the cent
+//	@Tags			tax
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			code			path		string							true	"Jurisdiction code, e.g. DE or US-CA"
+//	@Param			request			body		models.TaxJurisdictionRequest	true	"Jurisdiction"
+//	@Param			X-Admin-Token	header		string							true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	models.TaxJurisdiction
+//	@Router			/tax/jurisdictions/{code} [put]
+func (h *Handler) SetJurisdiction(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	var req models.TaxJurisdictionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	j, err := h.service.SetJurisdiction(c, c.Param("code"), req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, j)
+}
+
+// DeleteJurisdiction godoc
+//
+//	@Summary		Delete a tax jurisdiction
+//	@Description	Delete a tax jurisdiction with its rate table (admin only)
+//	@Tags			tax
+//
+//	@Produce		json
+//	@Param			code			path		string	true	"Jurisdiction code"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	map[string]string
+//	@Router			/tax/jurisdictions/{code} [delete]
+func (h *Handler) DeleteJurisdiction(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	if err := h.service.DeleteJurisdiction(c, c.Param("code")); err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "a tax jurisdiction has been deleted"})
+}
+
+// GetRates godoc
+//
+//	@Summary		List tax rates
+//	@Description	List the rate table of a jurisdiction, past, current and future, by tax class and validity
+//	@Tags			tax
+//
+//	@Produce		json
+//	@Param			code	path		string	true	"Jurisdiction code"
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Success		200		{object}	[]models.TaxRate
+//	@Router			/tax/jurisdictions/{code}/rates [get]
+func (h *Handler) GetRates(c *gin.Context) {
+	rates, err := h.service.GetRates(c, c.Param("code"))
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if rates == nil {
+		rates = []*models.TaxRate{}
+	}
+	c.JSON(http.StatusOK, rates)
+}
+
+// AddRate godoc
+//
+//	@Summary		Add a tax rate
+//	@Description	Add the percent of a tax class valid from valid_from until valid_to, or until further notice (admin only). Rates of a class cannot overlap, so a rate change ends the current rate first
+//	@Tags			tax
+//
+//	@Accept			json
+//	@Produce		json
+//	@Param			code			path		string					true	"Jurisdiction code"
+//	@Param			request			body		models.TaxRateRequest	true	"Rate"
+//	@Param			X-Admin-Token	header		string					true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		201				{object}	models.TaxRate
+//	@Router			/tax/jurisdictions/{code}/rates [post]
+func (h *Handler) AddRate(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	var req models.TaxRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	r, err := h.service.AddRate(c, c.Param("code"), req)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, r)
+}
+
+// DeleteRate godoc
+//
+//	@Summary		Delete a tax rate
+//	@Description	Delete a tax rate (admin only)
+//	@Tags			tax
+//
+//	@Produce		json
+//	@Param			id				path		int64	true	"Tax rate ID"
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Failure		400				{object}	map[string]string
+//	@Failure		403				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Success		200				{object}	map[string]string
+//	@Router			/tax/rates/{id} [delete]
+func (h *Handler) DeleteRate(c *gin.Context) {
+	if !access.IsAdmin(c) {
+		access.Forbid(c)
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		_ = c.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tax rate id"})
+		return
+	}
+	if err := h.service.DeleteRate(c, id); err != nil {
+		_ = c.Error(err)
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "a tax rate has been deleted"})
+}
+
+// errorStatus maps the tax errors to their HTTP status.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, tax.ErrInvalidTax):
+		return http.StatusBadRequest
+	case errors.Is(err, tax.ErrJurisdictionNotFound),
+		errors.Is(err, tax.ErrRateNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, tax.ErrRateOverlap):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,191 @@
+package tax_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"prodcrud/internal/models"
+	"prodcrud/internal/rest/resttest"
+	"prodcrud/internal/usecase/tax"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	jan     = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	july    = time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	updated = time.Date(2024, 12, 20, 9, 30, 0, 0, time.UTC)
+
+	germany = &models.TaxJurisdiction{
+		Code: "DE", Name: "Germany", PricesIncludeTax: true, Rounding: money.RoundHalfUp, UpdatedAt: updated,
+	}
+	rateRequest = models.TaxRateRequest{TaxClass: models.TaxClassStandard, Rate: "19", ValidFrom: july}
+)
+
+func TestHandler_Routes(t *testing.T) {
+	errDB := errors.New("connection refused")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   any
+		admin  bool
+		setup  func(m *tax.ServiceMock)
+	}{
+		{
+			name: "jurisdictions", method: http.MethodGet, target: "/tax/jurisdictions",
+			setup: func(m *tax.ServiceMock) {
+				m.On("GetJurisdictions", mock.Anything).Return([]*models.TaxJurisdiction{germany, {
+					Code: "US-CA", Name: "California", Rounding: money.RoundHalfEven, UpdatedAt: updated,
+				}}, nil).Once()
+			},
+		},
+		{
+			name: "jurisdictions_empty", method: http.MethodGet, target: "/tax/jurisdictions",
+			setup: func(m *tax.ServiceMock) {
+				m.On("GetJurisdictions", mock.Anything).Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "jurisdictions_failed", method: http.MethodGet, target: "/tax/jurisdictions",
+			setup: func(m *tax.ServiceMock) {
+				m.On("GetJurisdictions", mock.Anything).Return(nil, errDB).Once()
+			},
+		},
+		{
+			name: "set_jurisdiction", method: http.MethodPut, target: "/tax/jurisdictions/de", admin: true,
+			body: models.TaxJurisdictionRequest{Name: "Germany", PricesIncludeTax: true},
+			setup: func(m *tax.ServiceMock) {
+				m.On("SetJurisdiction", mock.Anything, "de", models.TaxJurisdictionRequest{Name: "Germany", PricesIncludeTax: true}).
+					Return(germany, nil).Once()
+			},
+		},
+		{
+			name: "set_jurisdiction_forbidden", method: http.MethodPut, target: "/tax/jurisdictions/DE",
+			body: models.TaxJurisdictionRequest{Name: "Germany"},
+		},
+		{
+			name: "set_jurisdiction_invalid_json", method: http.MethodPut, target: "/tax/jurisdictions/DE", admin: true, body: "{",
+		},
+		{
+			name: "set_jurisdiction_invalid", method: http.MethodPut, target: "/tax/jurisdictions/DE", admin: true,
+			body: models.TaxJurisdictionRequest{Name: "Germany", Rounding: "bankers"},
+			setup: func(m *tax.ServiceMock) {
+				m.On("SetJurisdiction", mock.Anything, "DE", mock.Anything).
+					Return(nil, fmt.Errorf("%w: %w", tax.ErrInvalidTax, money.ErrInvalidRounding)).Once()
+			},
+		},
+		{
+			name: "delete_jurisdiction", method: http.MethodDelete, target: "/tax/jurisdictions/DE", admin: true,
+			setup: func(m *tax.ServiceMock) {
+				m.On("DeleteJurisdiction", mock.Anything, "DE").Return(nil).Once()
+			},
+		},
+		{
+			name: "delete_jurisdiction_forbidden", method: http.MethodDelete, target: "/tax/jurisdictions/DE",
+		},
+		{
+			name: "delete_jurisdiction_not_found", method: http.MethodDelete, target: "/tax/jurisdictions/FR", admin: true,
+			setup: func(m *tax.ServiceMock) {
+				m.On("DeleteJurisdiction", mock.Anything, "FR").Return(tax.ErrJurisdictionNotFound).Once()
+			},
+		},
+		{
+			name: "rates", method: http.MethodGet, target: "/tax/jurisdictions/DE/rates",
+			setup: func(m *tax.ServiceMock) {
+				m.On("GetRates", mock.Anything, "DE").Return([]*models.TaxRate{
+					{ID: 3, Jurisdiction: "DE", TaxClass: models.TaxClassReduced, Rate: money.MustParseRate("7"), ValidFrom: jan, CreatedAt: updated},
+					{ID: 1, Jurisdiction: "DE", TaxClass: models.TaxClassStandard, Rate: money.MustParseRate("16"), ValidFrom: jan, ValidTo: &july, CreatedAt: updated},
+					{ID: 2, Jurisdiction: "DE", TaxClass: models.TaxClassStandard, Rate: money.MustParseRate("19"), ValidFrom: july, CreatedAt: updated},
+				}, nil).Once()
+			},
+		},
+		{
+			name: "rates_empty", method: http.MethodGet, target: "/tax/jurisdictions/DE/rates",
+			setup: func(m *tax.ServiceMock) {
+				m.On("GetRates", mock.Anything, "DE").Return(nil, nil).Once()
+			},
+		},
+		{
+			name: "rates_not_found", method: http.MethodGet, target: "/tax/jurisdictions/FR/rates",
+			setup: func(m *tax.ServiceMock) {
+				m.On("GetRates", mock.Anything, "FR").Return(nil, tax.ErrJurisdictionNotFound).Once()
+			},
+		},
+		{
+			name: "add_rate", method: http.MethodPost, target: "/tax/jurisdictions/DE/rates", admin: true, body: rateRequest,
+			setup: func(m *tax.ServiceMock) {
+				m.On("AddRate", mock.Anything, "DE", rateRequest).Return(&models.TaxRate{
+					ID: 2, Jurisdiction: "DE", TaxClass: models.TaxClassStandard, Rate: money.MustParseRate("19"),
+					ValidFrom: july, CreatedAt: updated,
+				}, nil).Once()
+			},
+		},
+		{
+			name: "add_rate_forbidden", method: http.MethodPost, target: "/tax/jurisdictions/DE/rates", body: rateRequest,
+		},
+		{
+			name: "add_rate_invalid_json", method: http.MethodPost, target: "/tax/jurisdictions/DE/rates", admin: true, body: "{",
+		},
+		{
+			name: "add_rate_invalid", method: http.MethodPost, target: "/tax/jurisdictions/DE/rates", admin: true,
+			body: models.TaxRateRequest{TaxClass: models.TaxClassExempt, Rate: "1", ValidFrom: july},
+			setup: func(m *tax.ServiceMock) {
+				m.On("AddRate", mock.Anything, "DE", mock.Anything).
+					Return(nil, fmt.Errorf("%w: exempt products are never taxed and take no rate", tax.ErrInvalidTax)).Once()
+			},
+		},
+		{
+			name: "add_rate_overlap", method: http.MethodPost, target: "/tax/jurisdictions/DE/rates", admin: true, body: rateRequest,
+			setup: func(m *tax.ServiceMock) {
+				m.On("AddRate", mock.Anything, "DE", rateRequest).Return(nil, tax.ErrRateOverlap).Once()
+			},
+		},
+		{
+			name: "add_rate_not_found", method: http.MethodPost, target: "/tax/jurisdictions/FR/rates", admin: true, body: rateRequest,
+			setup: func(m *tax.ServiceMock) {
+				m.On("AddRate", mock.Anything, "FR", rateRequest).Return(nil, tax.ErrJurisdictionNotFound).Once()
+			},
+		},
+		{
+			name: "delete_rate", method: http.MethodDelete, target: "/tax/rates/2", admin: true,
+			setup: func(m *tax.ServiceMock) {
+				m.On("DeleteRate", mock.Anything, int64(2)).Return(nil).Once()
+			},
+		},
+		{
+			name: "delete_rate_forbidden", method: http.MethodDelete, target: "/tax/rates/2",
+		},
+		{
+			name: "delete_rate_invalid_id", method: http.MethodDelete, target: "/tax/rates/abc", admin: true,
+		},
+		{
+			name: "delete_rate_not_found", method: http.MethodDelete, target: "/tax/rates/9", admin: true,
+			setup: func(m *tax.ServiceMock) {
+				m.On("DeleteRate", mock.Anything, int64(9)).Return(tax.ErrRateNotFound).Once()
+			},
+		},
+	}
+
+	svc := new(tax.ServiceMock)
+	h := resttest.New(t, resttest.Services{Tax: svc})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(svc)
+			}
+			do := h.Do
+			if tt.admin {
+				do = h.DoAdmin
+			}
+			rec := do(tt.method, tt.target, tt.body)
+			h.Golden("tax_"+tt.name, rec)
+		})
+	}
+	svc.AssertExpectations(t)
+	h.AssertCovered("tax")
+}
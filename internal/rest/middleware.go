@@ -0,0 +1,98 @@
+package rest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	headerReadYourWrites = "X-Read-Your-Writes"
+	headerRequestID      = "X-Request-ID"
+	maxRequestIDLen      = 128
+)
+
+// readYourWrites sends the request's reads to the primary instead of a replica
+// when the client sets X-Read-Your-Writes, e.g. right after creating a product.
+func readYourWrites(c *gin.Context) {
+	if ok, _ := strconv.ParseBool(c.GetHeader(headerReadYourWrites)); ok {
+		c.Request = c.Request.WithContext(db.WithPrimary(c.Request.Context()))
+	}
+	c.Next()
+}
+
+// requestLogger accepts the client's X-Request-ID or generates one, echoes it in
+// the response, stores a logger carrying it (and the trace id) in the request
+// context and writes one access log line per request, including handler errors.
+func requestLogger(c *gin.Context) {
+	start := time.Now()
+	id := c.GetHeader(headerRequestID)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	c.Header(headerRequestID, id)
+
+	ctx := c.Request.Context()
+	logger := slog.Default().With("request_id", id)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		logger = logger.With("trace_id", sc.TraceID().String())
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", id))
+	}
+	c.Request = c.Request.WithContext(logging.WithLogger(ctx, logger))
+
+	c.Next()
+
+	status := c.Writer.Status()
+	level := slog.LevelInfo
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case status >= http.StatusBadRequest:
+		level = slog.LevelWarn
+	}
+	attrs := []any{
+		"method", c.Request.Method,
+		"route", c.FullPath(),
+		"path", c.Request.URL.Path,
+		"status", status,
+		"latency_ms", time.Since(start).Milliseconds(),
+		"client_ip", c.ClientIP(),
+	}
+	if len(c.Errors) > 0 {
+		attrs = append(attrs, "errors", c.Errors.Errors())
+	}
+	logging.For(c.Request.Context(), "rest").Log(c.Request.Context(), level, "request", attrs...)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// recovered logs a handler panic with the request logger and answers 500.
+func recovered(c *gin.Context, err any) {
+	logging.For(c.Request.Context(), "rest").Error("panic recovered", "panic", err)
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+}
@@ -2,26 +2,64 @@ package rest
 
 import (
 	"net/http"
+	"prodcrud/internal/metrics"
+	"prodcrud/internal/rest/access"
+	"prodcrud/internal/rest/handlers/admin"
+	"prodcrud/internal/rest/handlers/attribute"
 	"prodcrud/internal/rest/handlers/health"
+	"prodcrud/internal/rest/handlers/image"
+	"prodcrud/internal/rest/handlers/order"
+	"prodcrud/internal/rest/handlers/pricing"
 	"prodcrud/internal/rest/handlers/product"
+	"prodcrud/internal/rest/handlers/promotion"
+	"prodcrud/internal/rest/handlers/purchase"
+	"prodcrud/internal/rest/handlers/reorder"
+	"prodcrud/internal/rest/handlers/tax"
+	"prodcrud/internal/tracing"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	"github.com/swaggo/gin-swagger"
+	"go.uber.org/dig"
 	_ "prodcrud/docs"
 )
 
+// Options holds the server settings that are not handlers.
+type Options struct {
+	// AdminToken unlocks admin operations such as hard deletes, empty disables them.
+	AdminToken string
+}
+
+// Handlers are the route handlers of every domain, filled in by dig.
+type Handlers struct {
+	dig.In
+
+	Health    *health.Handler
+	Product   *product.Handler
+	Pricing   *pricing.Handler
+	Promotion *promotion.Handler
+	Tax       *tax.Handler
+	Attribute *attribute.Handler
+	Image     *image.Handler
+	Purchase  *purchase.Handler
+	Order     *order.Handler
+	Reorder   *reorder.Handler
+	Admin     *admin.Handler
+}
+
 type Server struct {
-	mux     *gin.Engine
-	health  *health.Handler
-	product *product.Handler
+	opts     Options
+	mux      *gin.Engine
+	handlers Handlers
+	metrics  *metrics.Metrics
 }
 
-func NewServer(mux *gin.Engine, healthHandler *health.Handler, productHandler *product.Handler) *Server {
+func NewServer(mux *gin.Engine, handlers Handlers, m *metrics.Metrics, opts Options) *Server {
 	return &Server{
-		mux:     mux,
-		health:  healthHandler,
-		product: productHandler,
+		opts:     opts,
+		mux:      mux,
+		handlers: handlers,
+		metrics:  m,
 	}
 }
 
@@ -35,19 +73,123 @@ func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 //
 // @BasePath		/products
 func (s *Server) Init() {
-	s.mux.Use(gin.Logger())
-	s.mux.Use(gin.Recovery())
+	// handlers pass *gin.Context as context.Context, let it see values set on the request
+	s.mux.ContextWithFallback = true
+	s.mux.Use(tracing.Middleware())
+	s.mux.Use(requestLogger)
+	s.mux.Use(gin.CustomRecovery(recovered))
+	s.mux.Use(s.metrics.Middleware())
+	s.mux.Use(readYourWrites)
+	s.mux.Use(access.Middleware(s.opts.AdminToken))
 
+	h := s.handlers
 	gr := s.mux.Group("/products")
 	{
-		gr.GET("/health", s.health.HealthCheck)
+		gr.GET("/health", h.Health.HealthCheck)
+
+		gr.GET("/", h.Product.GetAllProducts)
+		gr.GET("/trash", h.Product.GetDeletedProducts)
+		gr.GET("/low-stock", h.Reorder.GetLowStock)
+		gr.GET("/:id", h.Product.GetProduct)
+		gr.POST("/", h.Product.CreateProduct)
+		gr.PUT("/:id", h.Product.UpdateProduct)
+		gr.DELETE("/:id", h.Product.DeleteProduct)
+		gr.PUT("/:id/restore", h.Product.RestoreProduct)
+		gr.GET("/:id/variants", h.Product.GetVariants)
+		gr.POST("/:id/variants", h.Product.CreateVariant)
+		gr.POST("/:id/variants/generate", h.Product.GenerateVariants)
+
+		gr.POST("/:id/images", h.Image.UploadImage)
+		gr.PUT("/:id/images", h.Image.ReorderImages)
+		gr.PUT("/:id/images/:image_id/primary", h.Image.SetPrimaryImage)
+		gr.DELETE("/:id/images/:image_id", h.Image.DeleteImage)
+
+		gr.GET("/:id/reorder", h.Reorder.GetRule)
+		gr.PUT("/:id/reorder", h.Reorder.SetRule)
+		gr.DELETE("/:id/reorder", h.Reorder.DeleteRule)
+
+		gr.GET("/:id/price", h.Pricing.GetPrice)
+		gr.GET("/:id/prices", h.Pricing.GetPrices)
+		gr.POST("/:id/prices", h.Pricing.SchedulePrice)
+		gr.PUT("/:id/prices/:currency", h.Pricing.SetPrice)
+		gr.DELETE("/:id/prices/:currency", h.Pricing.DeletePrice)
+	}
+	s.mux.GET("/media/products/:id/:file", h.Image.GetMedia)
+	s.mux.GET("/livez", h.Health.Livez)
+	s.mux.GET("/readyz", h.Health.Readyz)
+
+	rates := s.mux.Group("/exchange-rates")
+	{
+		rates.GET("/", h.Pricing.GetRates)
+		rates.PUT("/:base/:quote", h.Pricing.SetRate)
+		rates.DELETE("/:base/:quote", h.Pricing.DeleteRate)
+	}
+
+	promotions := s.mux.Group("/promotions")
+	{
+		promotions.GET("/", h.Promotion.GetPromotions)
+		promotions.GET("/:id", h.Promotion.GetPromotion)
+		promotions.POST("/", h.Promotion.CreatePromotion)
+		promotions.PUT("/:id", h.Promotion.UpdatePromotion)
+		promotions.DELETE("/:id", h.Promotion.DeletePromotion)
+	}
+	s.mux.POST("/pricing/quote", h.Promotion.Quote)
+
+	taxes := s.mux.Group("/tax")
+	{
+		taxes.GET("/jurisdictions", h.Tax.GetJurisdictions)
+		taxes.PUT("/jurisdictions/:code", h.Tax.SetJurisdiction)
+		taxes.DELETE("/jurisdictions/:code", h.Tax.DeleteJurisdiction)
+		taxes.GET("/jurisdictions/:code/rates", h.Tax.GetRates)
+		taxes.POST("/jurisdictions/:code/rates", h.Tax.AddRate)
+		taxes.DELETE("/rates/:id", h.Tax.DeleteRate)
+	}
+
+	attributes := s.mux.Group("/categories/:category/attributes")
+	{
+		attributes.GET("", h.Attribute.GetDefinitions)
+		attributes.PUT("/:name", h.Attribute.SetDefinition)
+		attributes.DELETE("/:name", h.Attribute.DeleteDefinition)
+	}
+
+	suppliers := s.mux.Group("/suppliers")
+	{
+		suppliers.GET("/", h.Purchase.GetSuppliers)
+		suppliers.GET("/:id", h.Purchase.GetSupplier)
+		suppliers.POST("/", h.Purchase.CreateSupplier)
+		suppliers.PUT("/:id", h.Purchase.UpdateSupplier)
+		suppliers.DELETE("/:id", h.Purchase.DeleteSupplier)
+		suppliers.GET("/:id/products", h.Purchase.GetSupplierProducts)
+		suppliers.PUT("/:id/products/:product_id", h.Purchase.SetSupplierProduct)
+		suppliers.DELETE("/:id/products/:product_id", h.Purchase.DeleteSupplierProduct)
+	}
+
+	orders := s.mux.Group("/purchase-orders")
+	{
+		orders.GET("/", h.Purchase.GetOrders)
+		orders.GET("/:id", h.Purchase.GetOrder)
+		orders.POST("/", h.Purchase.CreateOrder)
+		orders.PUT("/:id", h.Purchase.UpdateOrder)
+		orders.POST("/:id/send", h.Purchase.SendOrder)
+		orders.POST("/:id/cancel", h.Purchase.CancelOrder)
+		orders.POST("/:id/receive", h.Purchase.ReceiveOrder)
+	}
 
-		gr.GET("/", s.product.GetAllProducts)
-		gr.GET("/:id", s.product.GetProduct)
-		gr.POST("/", s.product.CreateProduct)
-		gr.PUT("/:id", s.product.UpdateProduct)
-		gr.DELETE("/:id", s.product.DeleteProduct)
-		gr.PUT("/:id/restore", s.product.RestoreProduct)
+	sales := s.mux.Group("/orders")
+	{
+		sales.GET("/", h.Order.GetOrders)
+		sales.GET("/:id", h.Order.GetOrder)
+		sales.POST("/", h.Order.CreateOrder)
+		sales.POST("/:id/allocate", h.Order.AllocateOrder)
+		sales.POST("/:id/ship", h.Order.ShipOrder)
+		sales.POST("/:id/cancel", h.Order.CancelOrder)
+	}
+
+	adm := s.mux.Group("/admin")
+	{
+		adm.GET("/log-levels", h.Admin.GetLogLevels)
+		adm.PUT("/log-levels", h.Admin.SetLogLevel)
 	}
 	s.mux.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	s.mux.GET("/metrics", gin.WrapH(s.metrics.Handler()))
 }
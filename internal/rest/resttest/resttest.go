@@ -0,0 +1,266 @@
+// Package resttest builds the HTTP server around the given services and
+// checks responses against golden files and the generated swagger in docs.
+//
+// Golden files live in the testdata directory of the calling package; run
+// the tests with -update to rewrite them after an intended change.
+package resttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"prodcrud/internal/metrics"
+	"prodcrud/internal/rest"
+	"prodcrud/internal/rest/access"
+	adminHandler "prodcrud/internal/rest/handlers/admin"
+	attributeHandler "prodcrud/internal/rest/handlers/attribute"
+	healthHandler "prodcrud/internal/rest/handlers/health"
+	imageHandler "prodcrud/internal/rest/handlers/image"
+	orderHandler "prodcrud/internal/rest/handlers/order"
+	pricingHandler "prodcrud/internal/rest/handlers/pricing"
+	productHandler "prodcrud/internal/rest/handlers/product"
+	promotionHandler "prodcrud/internal/rest/handlers/promotion"
+	purchaseHandler "prodcrud/internal/rest/handlers/purchase"
+	reorderHandler "prodcrud/internal/rest/handlers/reorder"
+	taxHandler "prodcrud/internal/rest/handlers/tax"
+	attributeService "prodcrud/internal/usecase/attribute"
+	healthService "prodcrud/internal/usecase/health"
+	imageService "prodcrud/internal/usecase/image"
+	orderService "prodcrud/internal/usecase/order"
+	pricingService "prodcrud/internal/usecase/pricing"
+	productService "prodcrud/internal/usecase/product"
+	promotionService "prodcrud/internal/usecase/promotion"
+	purchaseService "prodcrud/internal/usecase/purchase"
+	reorderService "prodcrud/internal/usecase/reorder"
+	taxService "prodcrud/internal/usecase/tax"
+	"prodcrud/pkg/logging"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminToken is accepted as the admin token by the harness server.
+const AdminToken = "test-admin-token"
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// Harness serves requests through the full middleware chain and route table.
+type Harness struct {
+	t       *testing.T
+	handler http.Handler
+	spec    *spec
+	covered map[string]bool
+}
+
+// Services back the routes of the harness server. A nil service is replaced
+// by an empty mock, so a test only sets the ones it exercises.
+type Services struct {
+	Product   productService.ServiceInterface
+	Pricing   pricingService.ServiceInterface
+	Promotion promotionService.ServiceInterface
+	Tax       taxService.ServiceInterface
+	Attribute attributeService.ServiceInterface
+	Image     imageService.ServiceInterface
+	Purchase  purchaseService.ServiceInterface
+	Order     orderService.ServiceInterface
+	Reorder   reorderService.ServiceInterface
+}
+
+// New builds a server whose routes are backed by services. The health
+// service has no checks registered, so the probes always report ok.
+func New(t *testing.T, services Services) *Harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	if services.Product == nil {
+		services.Product = new(productService.ServiceMock)
+	}
+	if services.Pricing == nil {
+		services.Pricing = new(pricingService.ServiceMock)
+	}
+	if services.Promotion == nil {
+		services.Promotion = new(promotionService.ServiceMock)
+	}
+	if services.Tax == nil {
+		services.Tax = new(taxService.ServiceMock)
+	}
+	if services.Attribute == nil {
+		services.Attribute = new(attributeService.ServiceMock)
+	}
+	if services.Image == nil {
+		services.Image = new(imageService.ServiceMock)
+	}
+	if services.Purchase == nil {
+		services.Purchase = new(purchaseService.ServiceMock)
+	}
+	if services.Order == nil {
+		services.Order = new(orderService.ServiceMock)
+	}
+	if services.Reorder == nil {
+		services.Reorder = new(reorderService.ServiceMock)
+	}
+	server := rest.NewServer(
+		gin.New(),
+		rest.Handlers{
+			Health:    healthHandler.NewHandler(healthService.NewService(nil, healthService.Options{})),
+			Product:   productHandler.NewHandler(services.Product, services.Tax, services.Image),
+			Pricing:   pricingHandler.NewHandler(services.Pricing),
+			Promotion: promotionHandler.NewHandler(services.Promotion),
+			Tax:       taxHandler.NewHandler(services.Tax),
+			Attribute: attributeHandler.NewHandler(services.Attribute),
+			Image:     imageHandler.NewHandler(services.Image),
+			Purchase:  purchaseHandler.NewHandler(services.Purchase),
+			Order:     orderHandler.NewHandler(services.Order),
+			Reorder:   reorderHandler.NewHandler(services.Reorder),
+			Admin:     adminHandler.NewHandler(logging.New(io.Discard, slog.LevelInfo, nil)),
+		},
+		metrics.New(),
+		rest.Options{AdminToken: AdminToken},
+	)
+	server.Init()
+
+	return &Harness{t: t, handler: server, spec: loadSpec(t), covered: make(map[string]bool)}
+}
+
+// Do sends a request and checks the response against the swagger contract of
+// the matching operation. body is sent as is when it is a string, and as JSON
+// otherwise.
+func (h *Harness) Do(method, target string, body any) *httptest.ResponseRecorder {
+	h.t.Helper()
+	return h.do(method, target, body, nil)
+}
+
+// Upload sends a multipart/form-data request holding data as the file
+// field, with Do's checks.
+func (h *Harness) Upload(target, field, filename string, data []byte) *httptest.ResponseRecorder {
+	h.t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile(field, filename)
+	if err == nil {
+		_, err = part.Write(data)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		h.t.Fatalf("build multipart body: %v", err)
+	}
+	return h.do(http.MethodPost, target, body.String(), http.Header{"Content-Type": {w.FormDataContentType()}})
+}
+
+// DoAdmin is Do with the admin token set.
+func (h *Harness) DoAdmin(method, target string, body any) *httptest.ResponseRecorder {
+	h.t.Helper()
+	return h.do(method, target, body, http.Header{access.Header: {AdminToken}})
+}
+
+func (h *Harness) do(method, target string, body any, header http.Header) *httptest.ResponseRecorder {
+	h.t.Helper()
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		r = strings.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			h.t.Fatalf("marshal request body: %v", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, target, r)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if r != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.handler.ServeHTTP(rec, req)
+
+	path := strings.SplitN(req.URL.Path, "?", 2)[0]
+	op, route, err := h.spec.operation(method, path)
+	if err != nil {
+		h.t.Errorf("%s %s: %v", method, target, err)
+		return rec
+	}
+	h.covered[strings.ToUpper(method)+" "+route] = true
+	if err := h.spec.checkResponse(op, rec.Code, rec.Body.Bytes()); err != nil {
+		h.t.Errorf("%s %s: response does not match swagger: %v\n%s", method, target, err, rec.Body.String())
+	}
+	return rec
+}
+
+// Golden compares the status and the indented JSON body of rec with
+// testdata/name.golden.
+func (h *Harness) Golden(name string, rec *httptest.ResponseRecorder) {
+	h.t.Helper()
+	got := render(rec)
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			h.t.Fatalf("create testdata: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o600); err != nil {
+			h.t.Fatalf("write golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		h.t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		h.t.Errorf("response differs from %s (run with -update to accept)\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
+
+// AssertCovered fails for every documented operation tagged tag that no
+// request has exercised.
+func (h *Harness) AssertCovered(tag string) {
+	h.t.Helper()
+	var missing []string
+	for _, key := range h.spec.operations() {
+		method, route, _ := strings.Cut(key, " ")
+		if slices.Contains(h.spec.Paths[route][strings.ToLower(method)].Tags, tag) && !h.covered[key] {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		h.t.Errorf("documented routes without a test: %s", strings.Join(missing, ", "))
+	}
+}
+
+func render(rec *httptest.ResponseRecorder) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "HTTP %d\n\n", rec.Code)
+	var body bytes.Buffer
+	if err := json.Indent(&body, rec.Body.Bytes(), "", "  "); err != nil {
+		b.Write(rec.Body.Bytes())
+	} else {
+		b.Write(body.Bytes())
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,179 @@
+package resttest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"prodcrud/docs"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// schema is the subset of a swagger 2.0 schema produced by swag.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Nullable             bool               `json:"x-nullable"`
+}
+
+type response struct {
+	Schema *schema `json:"schema"`
+}
+
+type operation struct {
+	Tags      []string            `json:"tags"`
+	Responses map[string]response `json:"responses"`
+}
+
+type spec struct {
+	Paths       map[string]map[string]*operation `json:"paths"`
+	Definitions map[string]*schema               `json:"definitions"`
+
+	routes []route
+}
+
+type route struct {
+	template string
+	re       *regexp.Regexp
+}
+
+var param = regexp.MustCompile(`\{[^/]+\}`)
+
+func loadSpec(t *testing.T) *spec {
+	t.Helper()
+	var s spec
+	if err := json.Unmarshal([]byte(docs.SwaggerInfo.ReadDoc()), &s); err != nil {
+		t.Fatalf("parse swagger: %v", err)
+	}
+	for _, template := range sortedKeys(s.Paths) {
+		parts := param.Split(template, -1)
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		pattern := strings.Join(parts, `[^/]+`)
+		s.routes = append(s.routes, route{template: template, re: regexp.MustCompile("^" + pattern + "$")})
+	}
+	return &s
+}
+
+func (s *spec) operations() []string {
+	var out []string
+	for _, template := range sortedKeys(s.Paths) {
+		for _, method := range sortedKeys(s.Paths[template]) {
+			out = append(out, strings.ToUpper(method)+" "+template)
+		}
+	}
+	return out
+}
+
+func (s *spec) operation(method, path string) (*operation, string, error) {
+	for _, r := range s.routes {
+		if !r.re.MatchString(path) {
+			continue
+		}
+		if op, ok := s.Paths[r.template][strings.ToLower(method)]; ok {
+			return op, r.template, nil
+		}
+	}
+	return nil, "", errors.New("route is not documented in swagger")
+}
+
+func (s *spec) checkResponse(op *operation, status int, body []byte) error {
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		return fmt.Errorf("status %d is not documented", status)
+	}
+	if resp.Schema == nil || resp.Schema.Type == "file" || len(body) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("body is not JSON: %w", err)
+	}
+	return s.validate(resp.Schema, v, "$")
+}
+
+func (s *spec) validate(sc *schema, v any, at string) error {
+	if sc.Ref != "" {
+		def, ok := s.Definitions[strings.TrimPrefix(sc.Ref, "#/definitions/")]
+		if !ok {
+			return fmt.Errorf("%s: unknown definition %s", at, sc.Ref)
+		}
+		return s.validate(def, v, at)
+	}
+	if v == nil && sc.Nullable {
+		return nil
+	}
+
+	switch sc.Type {
+	case "":
+		return nil
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: want object, got %T", at, v)
+		}
+		for _, name := range sc.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required %q", at, name)
+			}
+		}
+		var extra *schema
+		if len(sc.AdditionalProperties) > 0 && string(sc.AdditionalProperties) != "true" {
+			extra = new(schema)
+			if err := json.Unmarshal(sc.AdditionalProperties, extra); err != nil {
+				return fmt.Errorf("%s: bad additionalProperties: %w", at, err)
+			}
+		}
+		for _, name := range sortedKeys(obj) {
+			prop, ok := sc.Properties[name]
+			switch {
+			case ok:
+			case extra != nil:
+				prop = extra
+			case len(sc.AdditionalProperties) > 0:
+				continue
+			default:
+				return fmt.Errorf("%s: undocumented property %q", at, name)
+			}
+			if err := s.validate(prop, obj[name], at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: want array, got %T", at, v)
+		}
+		for i, item := range arr {
+			if err := s.validate(sc.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: want string, got %T", at, v)
+		}
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s: want integer, got %v", at, v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: want number, got %T", at, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: want boolean, got %T", at, v)
+		}
+	}
+	return nil
+}
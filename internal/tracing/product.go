@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	productService "prodcrud/internal/usecase/product"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Service decorates a product usecase with a span per call.
+type Service struct {
+	next productService.ServiceInterface
+}
+
+func NewService(next productService.ServiceInterface) productService.ServiceInterface {
+	return &Service{next: next}
+}
+
+func start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "product.Service/"+method, trace.WithAttributes(attrs...))
+}
+
+func (s *Service) CreateProduct(ctx context.Context, p *models.Product) (err error) {
+	ctx, span := start(ctx, "CreateProduct")
+	defer func() { end(span, err) }()
+	return s.next.CreateProduct(ctx, p)
+}
+
+func (s *Service) GetAllProducts(ctx context.Context, includeDeleted bool) (_ []*models.Product, err error) {
+	ctx, span := start(ctx, "GetAllProducts")
+	defer func() { end(span, err) }()
+	return s.next.GetAllProducts(ctx, includeDeleted)
+}
+
+func (s *Service) FindProducts(ctx context.Context, category string, attributes map[string]string, includeDeleted bool) (_ []*models.Product, err error) {
+	ctx, span := start(ctx, "FindProducts", attribute.String("product.category", category), attribute.Int("product.attributes", len(attributes)))
+	defer func() { end(span, err) }()
+	return s.next.FindProducts(ctx, category, attributes, includeDeleted)
+}
+
+func (s *Service) GetProduct(ctx context.Context, id int64, includeDeleted bool) (_ *models.Product, err error) {
+	ctx, span := start(ctx, "GetProduct", attribute.Int64("product.id", id))
+	defer func() { end(span, err) }()
+	return s.next.GetProduct(ctx, id, includeDeleted)
+}
+
+func (s *Service) UpdateProduct(ctx context.Context, p *models.Product) (err error) {
+	ctx, span := start(ctx, "UpdateProduct", attribute.Int64("product.id", p.ID))
+	defer func() { end(span, err) }()
+	return s.next.UpdateProduct(ctx, p)
+}
+
+func (s *Service) DeleteProduct(ctx context.Context, id int64) (err error) {
+	ctx, span := start(ctx, "DeleteProduct", attribute.Int64("product.id", id))
+	defer func() { end(span, err) }()
+	return s.next.DeleteProduct(ctx, id)
+}
+
+func (s *Service) RestoreProduct(ctx context.Context, id int64) (err error) {
+	ctx, span := start(ctx, "RestoreProduct", attribute.Int64("product.id", id))
+	defer func() { end(span, err) }()
+	return s.next.RestoreProduct(ctx, id)
+}
+
+func (s *Service) HardDeleteProduct(ctx context.Context, id int64) (err error) {
+	ctx, span := start(ctx, "HardDeleteProduct", attribute.Int64("product.id", id))
+	defer func() { end(span, err) }()
+	return s.next.HardDeleteProduct(ctx, id)
+}
+
+func (s *Service) GetDeletedProducts(ctx context.Context) (_ []*models.Product, err error) {
+	ctx, span := start(ctx, "GetDeletedProducts")
+	defer func() { end(span, err) }()
+	return s.next.GetDeletedProducts(ctx)
+}
+
+func (s *Service) GetVariants(ctx context.Context, parentID int64) (_ []*models.Product, err error) {
+	ctx, span := start(ctx, "GetVariants", attribute.Int64("product.id", parentID))
+	defer func() { end(span, err) }()
+	return s.next.GetVariants(ctx, parentID)
+}
+
+func (s *Service) GenerateVariants(ctx context.Context, parentID int64, req models.VariantMatrixRequest) (_ *models.VariantMatrix, err error) {
+	ctx, span := start(ctx, "GenerateVariants", attribute.Int64("product.id", parentID), attribute.Int("variant.axes", len(req.Axes)))
+	defer func() { end(span, err) }()
+	return s.next.GenerateVariants(ctx, parentID, req)
+}
+
+func (s *Service) CreateVariant(ctx context.Context, parentID int64, req models.VariantRequest) (_ *models.Product, err error) {
+	ctx, span := start(ctx, "CreateVariant", attribute.Int64("product.id", parentID), attribute.String("variant.sku", req.SKU))
+	defer func() { end(span, err) }()
+	return s.next.CreateVariant(ctx, parentID, req)
+}
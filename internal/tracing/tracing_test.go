@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"prodcrud/internal/models"
+	attributeRepo "prodcrud/internal/repository/attribute"
+	productService "prodcrud/internal/usecase/product"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(newProvider(Config{ServiceName: "test", SampleRatio: 1}, sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	_, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	require.NoError(t, err)
+	return sr
+}
+
+func TestMiddleware_PropagatesTraceContext(t *testing.T) {
+	sr := record(t)
+	gin.SetMode(gin.TestMode)
+
+	repo := new(productService.Mock)
+	repo.On("GetProduct", mock.Anything, int64(7), false).Return(&models.Product{ID: 7}, nil).Once()
+	repo.On("GetVariantAxes", mock.Anything, int64(7)).Return(nil, nil).Once()
+	svc := NewService(productService.NewService(repo, attributeRepo.NewMemoryRepo()))
+
+	r := gin.New()
+	r.ContextWithFallback = true
+	r.Use(Middleware())
+	r.GET("/products/:id", func(c *gin.Context) {
+		_, err := svc.GetProduct(c, 7, false)
+		assert.NoError(t, err)
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/products/7", nil)
+	req.Header.Set("traceparent", traceparent)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	service, server := spans[0], spans[1]
+
+	assert.Equal(t, "GET /products/:id", server.Name())
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+
+	assert.Equal(t, "product.Service/GetProduct", service.Name())
+	assert.Equal(t, server.SpanContext().SpanID(), service.Parent().SpanID())
+	assert.Contains(t, w.Header().Get("traceparent"), "4bf92f3577b34da6a3ce929d0e0e4736")
+}
+
+func TestService_RecordsErrors(t *testing.T) {
+	sr := record(t)
+
+	repo := new(productService.Mock)
+	repo.On("DeleteProduct", mock.Anything, int64(1)).Return(assert.AnError).Once()
+	svc := NewService(productService.NewService(repo, attributeRepo.NewMemoryRepo()))
+
+	assert.Error(t, svc.DeleteProduct(context.Background(), 1))
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "Error", spans[0].Status().Code.String())
+}
+
+func TestSetup_FileExporter(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	path := filepath.Join(t.TempDir(), "traces.json")
+
+	p, err := Setup(context.Background(), Config{ServiceName: "test", Exporter: ExporterFile, File: path, SampleRatio: 1})
+	require.NoError(t, err)
+	_, span := tracer().Start(context.Background(), "exported")
+	span.End()
+	require.NoError(t, p.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"exported"`)
+}
@@ -0,0 +1,161 @@
+package attribute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/attribute"
+	"prodcrud/internal/usecase/product"
+	"prodcrud/pkg/logging"
+	"slices"
+	"strings"
+)
+
+// ServiceInterface manages the attribute schemas of the categories. Products
+// are checked against the schema when their attributes or category are set,
+// so a change applies to each product from then on.
+type ServiceInterface interface {
+	GetDefinitions(ctx context.Context, category string) ([]*models.AttributeDefinition, error)
+	// SetDefinition creates or replaces the attribute name of a category.
+	SetDefinition(ctx context.Context, category, name string, req models.AttributeDefinitionRequest) (*models.AttributeDefinition, error)
+	DeleteDefinition(ctx context.Context, category, name string) error
+}
+
+const logPackage = "usecase/attribute"
+
+const (
+	// MaxChoices bounds the values of an enum and the units of a unit
+	// attribute.
+	MaxChoices = 100
+	// MaxChoiceLen is the longest enum value, in bytes.
+	MaxChoiceLen = 64
+	// MaxUnitLen is the longest unit of measure, in bytes.
+	MaxUnitLen = 16
+)
+
+var (
+	ErrNotFound = attribute.ErrNotFound
+	// ErrInvalidDefinition wraps every validation error, which callers can
+	// show as is.
+	ErrInvalidDefinition = errors.New("invalid attribute")
+)
+
+type Service struct {
+	repo attribute.Repository
+}
+
+func NewService(repo attribute.Repository) ServiceInterface {
+	return &Service{repo: repo}
+}
+
+func (s *Service) GetDefinitions(ctx context.Context, category string) ([]*models.AttributeDefinition, error) {
+	category, err := normalizeCategory(category)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := s.repo.GetDefinitions(ctx, category)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get attributes", "category", category, "error", err)
+		return nil, errors.New("failed to get attributes usc")
+	}
+	return defs, nil
+}
+
+func (s *Service) SetDefinition(ctx context.Context, category, name string, req models.AttributeDefinitionRequest) (*models.AttributeDefinition, error) {
+	d, err := newDefinition(category, name, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetDefinition(ctx, d); err != nil {
+		logging.For(ctx, logPackage).Error("failed to set attribute", "category", d.Category, "name", d.Name, "error", err)
+		return nil, errors.New("failed to set attribute usc")
+	}
+	logging.For(ctx, logPackage).Info("attribute set", "category", d.Category, "name", d.Name, "type", d.Type,
+		"required", d.Required)
+	return d, nil
+}
+
+func (s *Service) DeleteDefinition(ctx context.Context, category, name string) error {
+	category, err := normalizeCategory(category)
+	if err != nil {
+		return err
+	}
+	if name, err = product.NormalizeAttributeName(name); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDefinition, err)
+	}
+	if err := s.repo.DeleteDefinition(ctx, category, name); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to delete attribute", "category", category, "name", name, "error", err)
+		return errors.New("failed to delete attribute usc")
+	}
+	logging.For(ctx, logPackage).Info("attribute deleted", "category", category, "name", name)
+	return nil
+}
+
+func normalizeCategory(category string) (string, error) {
+	category, err := product.NormalizeCategory(category)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidDefinition, err)
+	}
+	if category == "" {
+		return "", fmt.Errorf("%w: category is required", ErrInvalidDefinition)
+	}
+	return category, nil
+}
+
+func newDefinition(category, name string, req models.AttributeDefinitionRequest) (*models.AttributeDefinition, error) {
+	category, err := normalizeCategory(category)
+	if err != nil {
+		return nil, err
+	}
+	if name, err = product.NormalizeAttributeName(name); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDefinition, err)
+	}
+	d := &models.AttributeDefinition{Category: category, Name: name, Type: req.Type, Required: req.Required}
+	switch req.Type {
+	case models.AttributeString, models.AttributeNumber, models.AttributeBoolean, models.AttributeDate:
+	case models.AttributeEnum:
+		if d.Values, err = choices("values", req.Values, MaxChoiceLen); err != nil {
+			return nil, err
+		}
+	case models.AttributeUnit:
+		if d.Units, err = choices("units", req.Units, MaxUnitLen); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: type must be one of %s", ErrInvalidDefinition, strings.Join([]string{
+			models.AttributeString, models.AttributeNumber, models.AttributeEnum,
+			models.AttributeBoolean, models.AttributeDate, models.AttributeUnit,
+		}, ", "))
+	}
+	if req.Type != models.AttributeEnum && len(req.Values) > 0 {
+		return nil, fmt.Errorf("%w: only an enum has values", ErrInvalidDefinition)
+	}
+	if req.Type != models.AttributeUnit && len(req.Units) > 0 {
+		return nil, fmt.Errorf("%w: only a unit attribute has units", ErrInvalidDefinition)
+	}
+	return d, nil
+}
+
+// choices trims the values of an enum or the units of a unit attribute,
+// which must be distinct and one at least.
+func choices(field string, list []string, maxLen int) ([]string, error) {
+	if len(list) == 0 || len(list) > MaxChoices {
+		return nil, fmt.Errorf("%w: %s must have 1 to %d entries", ErrInvalidDefinition, field, MaxChoices)
+	}
+	trimmed := make([]string, 0, len(list))
+	for _, v := range list {
+		v = strings.TrimSpace(v)
+		if v == "" || len(v) > maxLen {
+			return nil, fmt.Errorf("%w: %s must have 1 to %d bytes each", ErrInvalidDefinition, field, maxLen)
+		}
+		if slices.Contains(trimmed, v) {
+			return nil, fmt.Errorf("%w: %s lists %q twice", ErrInvalidDefinition, field, v)
+		}
+		trimmed = append(trimmed, v)
+	}
+	return trimmed, nil
+}
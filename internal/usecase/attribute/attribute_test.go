@@ -0,0 +1,61 @@
+package attribute
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/attribute"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_SetDefinition(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(attribute.NewMemoryRepo())
+
+	d, err := s.SetDefinition(ctx, " Laptops", "Screen", models.AttributeDefinitionRequest{
+		Type: models.AttributeUnit, Required: true, Units: []string{" in", "cm"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "laptops", d.Category)
+	assert.Equal(t, "screen", d.Name)
+	assert.Equal(t, []string{"in", "cm"}, d.Units)
+
+	_, err = s.SetDefinition(ctx, "laptops", "panel", models.AttributeDefinitionRequest{
+		Type: models.AttributeEnum, Values: []string{"ips", "oled"},
+	})
+	require.NoError(t, err)
+	defs, err := s.GetDefinitions(ctx, "LAPTOPS")
+	require.NoError(t, err)
+	require.Len(t, defs, 2)
+	assert.Equal(t, "panel", defs[0].Name)
+
+	for name, tc := range map[string]struct {
+		category, name string
+		req            models.AttributeDefinitionRequest
+	}{
+		"no category":      {category: " ", name: "x", req: models.AttributeDefinitionRequest{Type: models.AttributeString}},
+		"bad name":         {category: "laptops", name: "2x", req: models.AttributeDefinitionRequest{Type: models.AttributeString}},
+		"bad type":         {category: "laptops", name: "x", req: models.AttributeDefinitionRequest{Type: "color"}},
+		"enum no values":   {category: "laptops", name: "x", req: models.AttributeDefinitionRequest{Type: models.AttributeEnum}},
+		"duplicate values": {category: "laptops", name: "x", req: models.AttributeDefinitionRequest{Type: models.AttributeEnum, Values: []string{"a", " a"}}},
+		"unit no units":    {category: "laptops", name: "x", req: models.AttributeDefinitionRequest{Type: models.AttributeUnit}},
+		"stray values":     {category: "laptops", name: "x", req: models.AttributeDefinitionRequest{Type: models.AttributeString, Values: []string{"a"}}},
+		"stray units":      {category: "laptops", name: "x", req: models.AttributeDefinitionRequest{Type: models.AttributeEnum, Values: []string{"a"}, Units: []string{"cm"}}},
+	} {
+		_, err := s.SetDefinition(ctx, tc.category, tc.name, tc.req)
+		assert.ErrorIs(t, err, ErrInvalidDefinition, name)
+	}
+}
+
+func TestService_DeleteDefinition(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(attribute.NewMemoryRepo())
+	_, err := s.SetDefinition(ctx, "laptops", "weight", models.AttributeDefinitionRequest{Type: models.AttributeNumber})
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteDefinition(ctx, "Laptops", "WEIGHT"))
+	assert.ErrorIs(t, s.DeleteDefinition(ctx, "laptops", "weight"), ErrNotFound)
+	assert.ErrorIs(t, s.DeleteDefinition(ctx, "", "weight"), ErrInvalidDefinition)
+}
@@ -0,0 +1,30 @@
+package attribute
+
+import (
+	"context"
+	"prodcrud/internal/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// ServiceMock stands in for the service in handler tests.
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (m *ServiceMock) GetDefinitions(ctx context.Context, category string) ([]*models.AttributeDefinition, error) {
+	args := m.Called(ctx, category)
+	defs, _ := args.Get(0).([]*models.AttributeDefinition)
+	return defs, args.Error(1)
+}
+
+func (m *ServiceMock) SetDefinition(ctx context.Context, category, name string, req models.AttributeDefinitionRequest) (*models.AttributeDefinition, error) {
+	args := m.Called(ctx, category, name, req)
+	d, _ := args.Get(0).(*models.AttributeDefinition)
+	return d, args.Error(1)
+}
+
+func (m *ServiceMock) DeleteDefinition(ctx context.Context, category, name string) error {
+	args := m.Called(ctx, category, name)
+	return args.Error(0)
+}
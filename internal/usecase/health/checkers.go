@@ -0,0 +1,97 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"prodcrud/internal/repository/health"
+	"syscall"
+)
+
+// DatabaseChecker pings the primary database.
+type DatabaseChecker struct {
+	repo *health.Repo
+}
+
+func NewDatabaseChecker(repo *health.Repo) *DatabaseChecker {
+	return &DatabaseChecker{repo: repo}
+}
+
+func (c *DatabaseChecker) Name() string { return "database" }
+
+func (c *DatabaseChecker) Check(ctx context.Context) error {
+	if err := c.repo.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping db: %w", err)
+	}
+	return nil
+}
+
+// PingChecker probes a dependency through its ping function, for backends
+// without a dedicated repository such as SQLite.
+type PingChecker struct {
+	name string
+	ping func(ctx context.Context) error
+}
+
+func NewPingChecker(name string, ping func(ctx context.Context) error) *PingChecker {
+	return &PingChecker{name: name, ping: ping}
+}
+
+func (c *PingChecker) Name() string { return c.name }
+
+func (c *PingChecker) Check(ctx context.Context) error {
+	if err := c.ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping %s: %w", c.name, err)
+	}
+	return nil
+}
+
+// MigrationChecker fails while the schema is dirty or behind the latest
+// migration shipped with the binary.
+type MigrationChecker struct {
+	repo     *health.Repo
+	expected uint
+}
+
+func NewMigrationChecker(repo *health.Repo, expected uint) *MigrationChecker {
+	return &MigrationChecker{repo: repo, expected: expected}
+}
+
+func (c *MigrationChecker) Name() string { return "migrations" }
+
+func (c *MigrationChecker) Check(ctx context.Context) error {
+	version, dirty, err := c.repo.MigrationVersion(ctx)
+	if err != nil {
+		return err //nolint:wrapcheck //already wrapped by the repository
+	}
+	if dirty {
+		return fmt.Errorf("schema version %d is dirty", version)
+	}
+	if version < c.expected {
+		return fmt.Errorf("schema version %d is behind %d", version, c.expected)
+	}
+	return nil
+}
+
+// DiskChecker fails when the filesystem holding Path has less than MinFree bytes available.
+type DiskChecker struct {
+	path    string
+	minFree uint64
+}
+
+func NewDiskChecker(path string, minFree uint64) *DiskChecker {
+	return &DiskChecker{path: path, minFree: minFree}
+}
+
+func (c *DiskChecker) Name() string { return "disk:" + c.path }
+
+func (c *DiskChecker) Check(context.Context) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(c.path, &st); err != nil {
+		return fmt.Errorf("failed to stat %s: %w", c.path, err)
+	}
+	free := st.Bavail * uint64(st.Bsize) //nolint:gosec //block size is positive
+	if free < c.minFree {
+		return fmt.Errorf("%d bytes free, need %d", free, c.minFree)
+	}
+	return nil
+}
@@ -2,21 +2,167 @@ package health
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"prodcrud/internal/repository/health"
+	"sync"
+	"time"
 )
 
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	defaultTimeout = 2 * time.Second
+)
+
+// Checker is a dependency probed by the readiness check.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type Options struct {
+	// Timeout bounds a single check unless the checker was registered with its own.
+	Timeout time.Duration
+	// CacheTTL is how long a readiness report is reused, so frequent probes do
+	// not hammer the database.
+	CacheTTL time.Duration
+}
+
+type ComponentStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status     string            `json:"status"`
+	CheckedAt  time.Time         `json:"checked_at"`
+	Uptime     string            `json:"uptime,omitempty"`
+	Components []ComponentStatus `json:"components,omitempty"`
+}
+
+type registered struct {
+	checker Checker
+	timeout time.Duration
+}
+
 type Service struct {
-	repo *health.Repo
+	opts    Options
+	started time.Time
+
+	mu       sync.Mutex
+	checkers []registered
+	cached   *Report
+}
+
+func NewService(repo *health.Repo, opts Options) *Service {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	s := &Service{opts: opts, started: time.Now()}
+	if repo != nil {
+		s.Register(NewDatabaseChecker(repo), 0)
+	}
+	return s
 }
 
-func NewService(repo *health.Repo) *Service {
-	return &Service{repo: repo}
+// Register adds a readiness check. A zero timeout uses Options.Timeout.
+func (s *Service) Register(c Checker, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = s.opts.Timeout
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkers = append(s.checkers, registered{checker: c, timeout: timeout})
+	s.cached = nil
 }
 
+// Check returns an error when any dependency is not ready.
 func (s *Service) Check(ctx context.Context) error {
-	if err := s.repo.Ping(ctx); err != nil {
-		return fmt.Errorf("failed to ping db: %w", err)
+	report := s.Ready(ctx)
+	if report.Status == StatusOK {
+		return nil
+	}
+	var errs []error
+	for _, c := range report.Components {
+		if c.Status != StatusOK {
+			errs = append(errs, fmt.Errorf("%s: %s", c.Name, c.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Live reports that the process is up. It never touches dependencies, so a
+// database outage does not get the pod restarted.
+func (s *Service) Live() Report {
+	return Report{
+		Status:    StatusOK,
+		CheckedAt: time.Now(),
+		Uptime:    time.Since(s.started).Round(time.Second).String(),
+	}
+}
+
+// Ready runs every registered check concurrently, each under its own timeout,
+// and caches the report for Options.CacheTTL. The report is shared by every
+// caller, so the checks do not stop when the request that ran them goes away.
+func (s *Service) Ready(ctx context.Context) Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cached != nil && time.Since(s.cached.CheckedAt) < s.opts.CacheTTL {
+		return *s.cached
+	}
+
+	report := Report{
+		Status:     StatusOK,
+		CheckedAt:  time.Now(),
+		Components: make([]ComponentStatus, len(s.checkers)),
+	}
+	ctx = context.WithoutCancel(ctx)
+	var wg sync.WaitGroup
+	for i, r := range s.checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Components[i] = run(ctx, r)
+		}()
+	}
+	wg.Wait()
+
+	for _, c := range report.Components {
+		if c.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	s.cached = &report
+	return report
+}
+
+func run(ctx context.Context, r registered) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() { errc <- r.checker.Check(ctx) }()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", r.timeout)
+	}
+
+	status := ComponentStatus{
+		Name:      r.checker.Name(),
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status = StatusFail
+		status.Error = err.Error()
 	}
-	return nil
+	return status
 }
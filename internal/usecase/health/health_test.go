@@ -0,0 +1,102 @@
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChecker struct {
+	name  string
+	err   error
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (f *fakeChecker) Name() string { return f.name }
+
+func (f *fakeChecker) Check(ctx context.Context) error {
+	f.calls.Add(1)
+	select {
+	case <-time.After(f.delay):
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestReady(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		s := NewService(nil, Options{})
+		s.Register(&fakeChecker{name: "a"}, 0)
+		s.Register(&fakeChecker{name: "b"}, 0)
+
+		report := s.Ready(context.Background())
+		assert.Equal(t, StatusOK, report.Status)
+		require.Len(t, report.Components, 2)
+		assert.Equal(t, "a", report.Components[0].Name)
+		assert.NoError(t, s.Check(context.Background()))
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		s := NewService(nil, Options{})
+		s.Register(&fakeChecker{name: "a"}, 0)
+		s.Register(&fakeChecker{name: "b", err: assert.AnError}, 0)
+
+		report := s.Ready(context.Background())
+		assert.Equal(t, StatusFail, report.Status)
+		assert.Equal(t, StatusOK, report.Components[0].Status)
+		assert.Equal(t, StatusFail, report.Components[1].Status)
+		assert.Equal(t, assert.AnError.Error(), report.Components[1].Error)
+		assert.ErrorContains(t, s.Check(context.Background()), "b: ")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		s := NewService(nil, Options{Timeout: time.Second})
+		s.Register(&fakeChecker{name: "slow", delay: time.Minute}, 20*time.Millisecond)
+
+		start := time.Now()
+		report := s.Ready(context.Background())
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, StatusFail, report.Status)
+		assert.Contains(t, report.Components[0].Error, "timed out")
+	})
+
+	t.Run("caller gone", func(t *testing.T) {
+		s := NewService(nil, Options{CacheTTL: time.Hour})
+		s.Register(&fakeChecker{name: "a", delay: 10 * time.Millisecond}, 0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.Equal(t, StatusOK, s.Ready(ctx).Status, "a cancelled probe does not cache a failure")
+		assert.Equal(t, StatusOK, s.Ready(context.Background()).Status)
+	})
+}
+
+func TestReady_Cache(t *testing.T) {
+	s := NewService(nil, Options{CacheTTL: time.Hour})
+	c := &fakeChecker{name: "a"}
+	s.Register(c, 0)
+
+	s.Ready(context.Background())
+	s.Ready(context.Background())
+	assert.Equal(t, int32(1), c.calls.Load())
+
+	s.Register(&fakeChecker{name: "b"}, 0)
+	report := s.Ready(context.Background())
+	assert.Len(t, report.Components, 2)
+	assert.Equal(t, int32(2), c.calls.Load())
+}
+
+func TestLive(t *testing.T) {
+	s := NewService(nil, Options{})
+	s.Register(&fakeChecker{name: "a", err: assert.AnError}, 0)
+
+	report := s.Live()
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Empty(t, report.Components)
+}
@@ -0,0 +1,61 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/image"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/blob"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"time"
+)
+
+// ProductCleanup decorates a product.Repository so that removing a product
+// permanently also deletes its image files. The database drops the image
+// records with the product, so they are listed before the removal and their
+// files deleted once it is done.
+type ProductCleanup struct {
+	product.Repository
+	images image.Repository
+	store  blob.BlobStore
+}
+
+func NewProductCleanup(next product.Repository, images image.Repository, store blob.BlobStore) product.Repository {
+	return &ProductCleanup{Repository: next, images: images, store: store}
+}
+
+func (c *ProductCleanup) HardDeleteProduct(ctx context.Context, id int64) (*models.PurgedProduct, error) {
+	return c.remove(ctx, id, func() (*models.PurgedProduct, error) {
+		return c.Repository.HardDeleteProduct(ctx, id)
+	})
+}
+
+func (c *ProductCleanup) PurgeProduct(ctx context.Context, id int64, before time.Time) (*models.PurgedProduct, error) {
+	return c.remove(ctx, id, func() (*models.PurgedProduct, error) {
+		return c.Repository.PurgeProduct(ctx, id, before)
+	})
+}
+
+// remove lists the images of the product on the primary, runs fn, and
+// deletes their files if fn removed the product. An image uploaded in
+// between is left orphaned.
+func (c *ProductCleanup) remove(ctx context.Context, id int64,
+	fn func() (*models.PurgedProduct, error)) (*models.PurgedProduct, error) {
+	images, err := c.images.GetImages(db.WithPrimary(ctx), id)
+	if err != nil {
+		return nil, errors.New("failed to get product images: " + err.Error())
+	}
+	purged, err := fn()
+	if err != nil {
+		return nil, err //nolint:wrapcheck //the errors of the repository are returned as is
+	}
+	for _, img := range images {
+		deleteFiles(ctx, c.store, img)
+	}
+	if len(images) > 0 {
+		logging.For(ctx, logPackage).Info("product images deleted", "product_id", id, "count", len(images))
+	}
+	return purged, nil
+}
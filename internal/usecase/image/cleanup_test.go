@@ -0,0 +1,69 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"prodcrud/internal/models"
+	imageRepo "prodcrud/internal/repository/image"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/blob"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pinned is a referrer holding on to some products, as order lines do.
+type pinned map[int64]bool
+
+func (p pinned) ReferencesProduct(productID int64) bool {
+	return p[productID]
+}
+
+func TestProductCleanup(t *testing.T) {
+	ctx := context.Background()
+	store, err := blob.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	images, refs := imageRepo.NewMemoryRepo(), pinned{}
+	products := NewProductCleanup(product.NewMemoryRepo(refs), images, store)
+	s := NewService(images, products, store, Options{MaxSize: 1 << 20, ThumbnailSize: 64, PublicURL: "/media"})
+
+	upload := func() (*models.ProductImage, []string) {
+		p := &models.Product{Name: "Laptop", Price: money.New(100000, "EUR"), Quantity: 1, Description: "14 inch"}
+		require.NoError(t, products.CreateProduct(ctx, p))
+		img, err := s.Upload(ctx, p.ID, bytes.NewReader(encode(t, 200, 100, false)), false)
+		require.NoError(t, err)
+		return img, []string{img.Key, img.ThumbnailKey}
+	}
+	exists := func(keys []string) bool {
+		for _, key := range keys {
+			if _, _, err := s.Open(ctx, key); err != nil {
+				assert.ErrorIs(t, err, ErrBlobNotFound)
+				return false
+			}
+		}
+		return true
+	}
+
+	hard, hardKeys := upload()
+	_, err = products.HardDeleteProduct(ctx, hard.ProductID)
+	require.NoError(t, err)
+	assert.False(t, exists(hardKeys), "the files go with a product deleted permanently")
+
+	purged, purgedKeys := upload()
+	require.NoError(t, products.DeleteProduct(ctx, purged.ProductID))
+	_, err = products.PurgeProduct(ctx, purged.ProductID, time.Now().Add(-time.Hour))
+	assert.ErrorIs(t, err, product.ErrNotFound)
+	assert.True(t, exists(purgedKeys), "the product is not purged yet")
+	_, err = products.PurgeProduct(ctx, purged.ProductID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, exists(purgedKeys), "the files go with a purged product")
+
+	kept, keptKeys := upload()
+	refs[kept.ProductID] = true
+	_, err = products.HardDeleteProduct(ctx, kept.ProductID)
+	assert.ErrorIs(t, err, product.ErrReferenced)
+	assert.True(t, exists(keptKeys), "the files stay with a product that is kept")
+}
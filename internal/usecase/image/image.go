@@ -0,0 +1,229 @@
+package image
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/image"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/blob"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/thumbnail"
+	"strings"
+)
+
+// ServiceInterface manages the images of products. The files live in a blob
+// store under keys of the form products/{id}/{random}.{ext}, and are served
+// from the public URL prefix of the options.
+type ServiceInterface interface {
+	// Upload reads an image, stores it with its thumbnail and adds it after
+	// the other images of an active product, as the primary one if primary
+	// is set or the product has none.
+	Upload(ctx context.Context, productID int64, r io.Reader, primary bool) (*models.ProductImage, error)
+	// GetImages lists the images of a product by position, with their URLs.
+	GetImages(ctx context.Context, productID int64) ([]*models.ProductImage, error)
+	// ReorderImages orders the images of a product as ids, which must list
+	// each of them once, and returns them.
+	ReorderImages(ctx context.Context, productID int64, ids []int64) ([]*models.ProductImage, error)
+	// SetPrimary makes an image the primary one and returns the images.
+	SetPrimary(ctx context.Context, productID, id int64) ([]*models.ProductImage, error)
+	// DeleteImage removes an image and its files.
+	DeleteImage(ctx context.Context, productID, id int64) error
+	// Open reads a stored image or thumbnail by key; the caller closes it.
+	Open(ctx context.Context, key string) (io.ReadCloser, blob.Info, error)
+}
+
+const logPackage = "usecase/image"
+
+// MaxPixels bounds the width times the height of an upload, checked before
+// it is decoded.
+const MaxPixels = 50_000_000
+
+var (
+	ErrImageNotFound   = image.ErrNotFound
+	ErrProductNotFound = product.ErrNotFound
+	ErrInvalidOrder    = image.ErrInvalidOrder
+	ErrBlobNotFound    = blob.ErrNotFound
+	// ErrTooLarge is returned for an upload over Options.MaxSize bytes or
+	// MaxPixels pixels.
+	ErrTooLarge = errors.New("image too large")
+	// ErrUnsupportedImage is returned for an upload that is not a JPEG, PNG
+	// or GIF image.
+	ErrUnsupportedImage = errors.New("unsupported image, upload a JPEG, PNG or GIF")
+)
+
+// extensions maps the content types of uploads and thumbnails to the
+// extension of their keys.
+var extensions = map[string]string{"image/jpeg": "jpg", "image/png": "png", "image/gif": "gif"}
+
+// Options configure the uploads.
+type Options struct {
+	// MaxSize is the largest upload, in bytes.
+	MaxSize int64
+	// ThumbnailSize bounds the width and the height of thumbnails.
+	ThumbnailSize int
+	// PublicURL prefixes the keys in the image URLs, such as /media or the
+	// address of a CDN in front of the bucket.
+	PublicURL string
+}
+
+type Service struct {
+	repo     image.Repository
+	products product.Repository
+	store    blob.BlobStore
+	opts     Options
+}
+
+func NewService(repo image.Repository, products product.Repository, store blob.BlobStore, opts Options) ServiceInterface {
+	opts.PublicURL = strings.TrimSuffix(opts.PublicURL, "/")
+	return &Service{repo: repo, products: products, store: store, opts: opts}
+}
+
+func (s *Service) Upload(ctx context.Context, productID int64, r io.Reader, primary bool) (*models.ProductImage, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.opts.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) > s.opts.MaxSize {
+		return nil, fmt.Errorf("%w: the limit is %d bytes", ErrTooLarge, s.opts.MaxSize)
+	}
+	img, err := thumbnail.Read(data, MaxPixels)
+	switch {
+	case errors.Is(err, thumbnail.ErrTooManyPixels):
+		return nil, fmt.Errorf("%w: %w", ErrTooLarge, err)
+	case err != nil:
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedImage, err)
+	}
+	if _, err := s.products.GetProduct(ctx, productID, false); err != nil {
+		if errors.Is(err, product.ErrNotFound) {
+			return nil, ErrProductNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to get product", "id", productID, "error", err)
+		return nil, errors.New("failed to upload image usc")
+	}
+	thumb, thumbType, err := img.Thumbnail(s.opts.ThumbnailSize)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to make thumbnail", "product_id", productID, "error", err)
+		return nil, errors.New("failed to upload image usc")
+	}
+
+	name := fmt.Sprintf("products/%d/%s", productID, randomName())
+	stored := &models.ProductImage{
+		ProductID:    productID,
+		Key:          name + "." + extensions[img.ContentType],
+		ThumbnailKey: name + "_thumb." + extensions[thumbType],
+		ContentType:  img.ContentType,
+		Size:         int64(len(data)),
+		Width:        img.Width,
+		Height:       img.Height,
+		Primary:      primary,
+	}
+	if err := s.store.Put(ctx, stored.Key, data, stored.ContentType); err != nil {
+		logging.For(ctx, logPackage).Error("failed to store image", "key", stored.Key, "error", err)
+		return nil, errors.New("failed to upload image usc")
+	}
+	if err := s.store.Put(ctx, stored.ThumbnailKey, thumb, thumbType); err != nil {
+		logging.For(ctx, logPackage).Error("failed to store thumbnail", "key", stored.ThumbnailKey, "error", err)
+		deleteFiles(ctx, s.store, stored)
+		return nil, errors.New("failed to upload image usc")
+	}
+	if err := s.repo.AddImage(ctx, stored); err != nil {
+		deleteFiles(ctx, s.store, stored)
+		if errors.Is(err, image.ErrProductNotFound) {
+			return nil, ErrProductNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to add image", "product_id", productID, "error", err)
+		return nil, errors.New("failed to upload image usc")
+	}
+	logging.For(ctx, logPackage).Info("image uploaded", "product_id", productID, "id", stored.ID, "key", stored.Key,
+		"size", stored.Size, "primary", stored.Primary)
+	return s.withURLs(stored), nil
+}
+
+func (s *Service) GetImages(ctx context.Context, productID int64) ([]*models.ProductImage, error) {
+	images, err := s.repo.GetImages(ctx, productID)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get images", "product_id", productID, "error", err)
+		return nil, errors.New("failed to get images usc")
+	}
+	for _, img := range images {
+		s.withURLs(img)
+	}
+	return images, nil
+}
+
+func (s *Service) ReorderImages(ctx context.Context, productID int64, ids []int64) ([]*models.ProductImage, error) {
+	if err := s.repo.ReorderImages(ctx, productID, ids); err != nil {
+		if errors.Is(err, ErrInvalidOrder) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to reorder images", "product_id", productID, "error", err)
+		return nil, errors.New("failed to reorder images usc")
+	}
+	logging.For(ctx, logPackage).Info("images reordered", "product_id", productID, "ids", ids)
+	return s.GetImages(ctx, productID)
+}
+
+func (s *Service) SetPrimary(ctx context.Context, productID, id int64) ([]*models.ProductImage, error) {
+	if err := s.repo.SetPrimary(ctx, productID, id); err != nil {
+		if errors.Is(err, ErrImageNotFound) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to set primary image", "product_id", productID, "id", id, "error", err)
+		return nil, errors.New("failed to set primary image usc")
+	}
+	logging.For(ctx, logPackage).Info("primary image set", "product_id", productID, "id", id)
+	return s.GetImages(ctx, productID)
+}
+
+func (s *Service) DeleteImage(ctx context.Context, productID, id int64) error {
+	img, err := s.repo.DeleteImage(ctx, productID, id)
+	if err != nil {
+		if errors.Is(err, ErrImageNotFound) {
+			return err
+		}
+		logging.For(ctx, logPackage).Error("failed to delete image", "product_id", productID, "id", id, "error", err)
+		return errors.New("failed to delete image usc")
+	}
+	deleteFiles(ctx, s.store, img)
+	logging.For(ctx, logPackage).Info("image deleted", "product_id", productID, "id", id)
+	return nil
+}
+
+func (s *Service) Open(ctx context.Context, key string) (io.ReadCloser, blob.Info, error) {
+	r, info, err := s.store.Get(ctx, key)
+	if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+		return nil, blob.Info{}, ErrBlobNotFound
+	}
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to open image", "key", key, "error", err)
+		return nil, blob.Info{}, errors.New("failed to open image usc")
+	}
+	return r, info, nil
+}
+
+// deleteFiles removes the files of an image; a failure only leaves them
+// orphaned, so it is logged.
+func deleteFiles(ctx context.Context, store blob.BlobStore, img *models.ProductImage) {
+	for _, key := range []string{img.Key, img.ThumbnailKey} {
+		if err := store.Delete(ctx, key); err != nil {
+			logging.For(ctx, logPackage).Warn("failed to delete image file", "key", key, "error", err)
+		}
+	}
+}
+
+func (s *Service) withURLs(img *models.ProductImage) *models.ProductImage {
+	img.URL = s.opts.PublicURL + "/" + img.Key
+	img.ThumbnailURL = s.opts.PublicURL + "/" + img.ThumbnailKey
+	return img
+}
+
+func randomName() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,159 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"prodcrud/internal/models"
+	imageRepo "prodcrud/internal/repository/image"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/blob"
+	"prodcrud/pkg/blob/s3test"
+	"prodcrud/pkg/money"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encode(t *testing.T, w, h int, jpg bool) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if jpg {
+		require.NoError(t, jpeg.Encode(&buf, img, nil))
+	} else {
+		require.NoError(t, png.Encode(&buf, img))
+	}
+	return buf.Bytes()
+}
+
+func newService(t *testing.T, store blob.BlobStore) (ServiceInterface, int64) {
+	t.Helper()
+	products := product.NewMemoryRepo()
+	p := &models.Product{Name: "Laptop", Price: money.New(100000, "EUR"), Quantity: 1, Description: "14 inch"}
+	require.NoError(t, products.CreateProduct(context.Background(), p))
+	return NewService(imageRepo.NewMemoryRepo(), products, store, Options{
+		MaxSize: 1 << 20, ThumbnailSize: 64, PublicURL: "/media/",
+	}), p.ID
+}
+
+func read(t *testing.T, s ServiceInterface, url string) ([]byte, blob.Info) {
+	t.Helper()
+	r, info, err := s.Open(context.Background(), strings.TrimPrefix(url, "/media/"))
+	require.NoError(t, err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return data, info
+}
+
+func TestService_Upload(t *testing.T) {
+	for name, newStore := range map[string]func(t *testing.T) blob.BlobStore{
+		"file": func(t *testing.T) blob.BlobStore {
+			store, err := blob.NewFileStore(t.TempDir())
+			require.NoError(t, err)
+			return store
+		},
+		"s3": func(t *testing.T) blob.BlobStore {
+			store, err := blob.NewS3Store(s3test.New(t).Config())
+			require.NoError(t, err)
+			return store
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, productID := newService(t, newStore(t))
+			data := encode(t, 200, 100, true)
+
+			img, err := s.Upload(ctx, productID, bytes.NewReader(data), false)
+			require.NoError(t, err)
+			assert.Equal(t, "image/jpeg", img.ContentType)
+			assert.Equal(t, int64(len(data)), img.Size)
+			assert.Equal(t, 200, img.Width)
+			assert.Equal(t, 100, img.Height)
+			assert.True(t, img.Primary)
+			assert.Regexp(t, `^/media/products/1/[0-9a-f]{24}\.jpg$`, img.URL)
+			assert.Equal(t, strings.TrimSuffix(img.URL, ".jpg")+"_thumb.jpg", img.ThumbnailURL)
+
+			stored, info := read(t, s, img.URL)
+			assert.Equal(t, data, stored)
+			assert.Equal(t, blob.Info{Size: int64(len(data)), ContentType: "image/jpeg"}, info)
+			thumb, info := read(t, s, img.ThumbnailURL)
+			assert.Equal(t, "image/jpeg", info.ContentType)
+			cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+			require.NoError(t, err)
+			assert.Equal(t, [2]int{64, 32}, [2]int{cfg.Width, cfg.Height})
+
+			second, err := s.Upload(ctx, productID, bytes.NewReader(encode(t, 10, 10, false)), true)
+			require.NoError(t, err)
+			assert.True(t, second.Primary)
+			assert.Regexp(t, `_thumb\.png$`, second.ThumbnailURL)
+			images, err := s.GetImages(ctx, productID)
+			require.NoError(t, err)
+			require.Len(t, images, 2)
+			assert.False(t, images[0].Primary)
+			assert.Equal(t, img.URL, images[0].URL)
+
+			require.NoError(t, s.DeleteImage(ctx, productID, img.ID))
+			_, _, err = s.Open(ctx, strings.TrimPrefix(img.URL, "/media/"))
+			assert.ErrorIs(t, err, ErrBlobNotFound, "deleting an image removes its files")
+			_, _, err = s.Open(ctx, strings.TrimPrefix(img.ThumbnailURL, "/media/"))
+			assert.ErrorIs(t, err, ErrBlobNotFound)
+		})
+	}
+}
+
+func TestService_Upload_invalid(t *testing.T) {
+	ctx := context.Background()
+	store, err := blob.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	s, productID := newService(t, store)
+
+	_, err = s.Upload(ctx, productID, strings.NewReader("GIF89a"), false)
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+	_, err = s.Upload(ctx, productID, strings.NewReader("<svg xmlns='http://www.w3.org/2000/svg'/>"), false)
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+	_, err = s.Upload(ctx, productID, bytes.NewReader(make([]byte, 1<<20+1)), false)
+	assert.ErrorIs(t, err, ErrTooLarge)
+	_, err = s.Upload(ctx, productID+1, bytes.NewReader(encode(t, 10, 10, false)), false)
+	assert.ErrorIs(t, err, ErrProductNotFound)
+	_, _, err = s.Open(ctx, "../etc/passwd")
+	assert.ErrorIs(t, err, ErrBlobNotFound)
+}
+
+func TestService_ReorderImages(t *testing.T) {
+	ctx := context.Background()
+	store, err := blob.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	s, productID := newService(t, store)
+	a, err := s.Upload(ctx, productID, bytes.NewReader(encode(t, 10, 10, false)), false)
+	require.NoError(t, err)
+	b, err := s.Upload(ctx, productID, bytes.NewReader(encode(t, 10, 10, false)), false)
+	require.NoError(t, err)
+
+	images, err := s.ReorderImages(ctx, productID, []int64{b.ID, a.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{b.ID, a.ID}, []int64{images[0].ID, images[1].ID})
+	assert.NotEmpty(t, images[0].URL)
+	_, err = s.ReorderImages(ctx, productID, []int64{b.ID})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+
+	images, err = s.SetPrimary(ctx, productID, b.ID)
+	require.NoError(t, err)
+	assert.True(t, images[0].Primary)
+	assert.False(t, images[1].Primary)
+	_, err = s.SetPrimary(ctx, productID, 99)
+	assert.ErrorIs(t, err, ErrImageNotFound)
+	assert.ErrorIs(t, s.DeleteImage(ctx, productID, 99), ErrImageNotFound)
+}
@@ -0,0 +1,51 @@
+package image
+
+import (
+	"context"
+	"io"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/blob"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// ServiceMock stands in for the service in handler tests.
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (m *ServiceMock) Upload(ctx context.Context, productID int64, r io.Reader, primary bool) (*models.ProductImage, error) {
+	args := m.Called(ctx, productID, r, primary)
+	img, _ := args.Get(0).(*models.ProductImage)
+	return img, args.Error(1)
+}
+
+func (m *ServiceMock) GetImages(ctx context.Context, productID int64) ([]*models.ProductImage, error) {
+	args := m.Called(ctx, productID)
+	images, _ := args.Get(0).([]*models.ProductImage)
+	return images, args.Error(1)
+}
+
+func (m *ServiceMock) ReorderImages(ctx context.Context, productID int64, ids []int64) ([]*models.ProductImage, error) {
+	args := m.Called(ctx, productID, ids)
+	images, _ := args.Get(0).([]*models.ProductImage)
+	return images, args.Error(1)
+}
+
+func (m *ServiceMock) SetPrimary(ctx context.Context, productID, id int64) ([]*models.ProductImage, error) {
+	args := m.Called(ctx, productID, id)
+	images, _ := args.Get(0).([]*models.ProductImage)
+	return images, args.Error(1)
+}
+
+func (m *ServiceMock) DeleteImage(ctx context.Context, productID, id int64) error {
+	args := m.Called(ctx, productID, id)
+	return args.Error(0)
+}
+
+func (m *ServiceMock) Open(ctx context.Context, key string) (io.ReadCloser, blob.Info, error) {
+	args := m.Called(ctx, key)
+	r, _ := args.Get(0).(io.ReadCloser)
+	info, _ := args.Get(1).(blob.Info)
+	return r, info, args.Error(2)
+}
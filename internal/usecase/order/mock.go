@@ -0,0 +1,49 @@
+package order
+
+import (
+	"context"
+	"prodcrud/internal/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// ServiceMock stands in for the service in handler tests.
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (m *ServiceMock) CreateOrder(ctx context.Context, req models.OrderRequest) (*models.Order, error) {
+	args := m.Called(ctx, req)
+	o, _ := args.Get(0).(*models.Order)
+	return o, args.Error(1)
+}
+
+func (m *ServiceMock) GetOrder(ctx context.Context, id int64) (*models.Order, error) {
+	args := m.Called(ctx, id)
+	o, _ := args.Get(0).(*models.Order)
+	return o, args.Error(1)
+}
+
+func (m *ServiceMock) GetOrders(ctx context.Context, f models.OrderFilter) ([]*models.Order, error) {
+	args := m.Called(ctx, f)
+	orders, _ := args.Get(0).([]*models.Order)
+	return orders, args.Error(1)
+}
+
+func (m *ServiceMock) AllocateOrder(ctx context.Context, id int64) (*models.Order, error) {
+	args := m.Called(ctx, id)
+	o, _ := args.Get(0).(*models.Order)
+	return o, args.Error(1)
+}
+
+func (m *ServiceMock) ShipOrder(ctx context.Context, id int64) (*models.Order, error) {
+	args := m.Called(ctx, id)
+	o, _ := args.Get(0).(*models.Order)
+	return o, args.Error(1)
+}
+
+func (m *ServiceMock) CancelOrder(ctx context.Context, id int64) (*models.Order, error) {
+	args := m.Called(ctx, id)
+	o, _ := args.Get(0).(*models.Order)
+	return o, args.Error(1)
+}
@@ -0,0 +1,271 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/order"
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/usecase/pricing"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"slices"
+)
+
+type ServiceInterface interface {
+	// CreateOrder places a pending order of active products, each line
+	// priced now in the order currency.
+	CreateOrder(ctx context.Context, req models.OrderRequest) (*models.Order, error)
+	GetOrder(ctx context.Context, id int64) (*models.Order, error)
+	GetOrders(ctx context.Context, f models.OrderFilter) ([]*models.Order, error)
+	// AllocateOrder takes the units of a pending order from stock, all of
+	// them or none, in one transaction with the status change.
+	AllocateOrder(ctx context.Context, id int64) (*models.Order, error)
+	// ShipOrder marks an allocated order as shipped.
+	ShipOrder(ctx context.Context, id int64) (*models.Order, error)
+	// CancelOrder cancels a pending or allocated order, and returns the units
+	// of an allocated one to stock in the same transaction, deleted products
+	// included.
+	CancelOrder(ctx context.Context, id int64) (*models.Order, error)
+}
+
+const logPackage = "usecase/order"
+
+// MaxLines is the most lines an order has.
+const MaxLines = 100
+
+var (
+	ErrOrderNotFound     = order.ErrOrderNotFound
+	ErrInvalidStatus     = order.ErrInvalidStatus
+	ErrInsufficientStock = product.ErrInsufficientStock
+	ErrProductNotFound   = product.ErrNotFound
+	// ErrInvalidOrder wraps every validation error of an order.
+	ErrInvalidOrder = errors.New("invalid order")
+)
+
+type Service struct {
+	repo     order.Repository
+	products product.Repository
+	prices   pricing.ServiceInterface
+	tx       db.Transactor
+}
+
+// NewService needs the product repository of the same store as repo, and its
+// transactor, so that status changes and stock updates commit together.
+func NewService(repo order.Repository, products product.Repository, prices pricing.ServiceInterface,
+	tx db.Transactor) ServiceInterface {
+	return &Service{repo: repo, products: products, prices: prices, tx: tx}
+}
+
+func invalidOrder(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalidOrder}, args...)...)
+}
+
+// orderOf validates req into an order priced in its currency, which defaults
+// to the base currency of the first product.
+func (s *Service) orderOf(ctx context.Context, req models.OrderRequest) (*models.Order, error) {
+	if len(req.Lines) == 0 || len(req.Lines) > MaxLines {
+		return nil, invalidOrder("an order must have 1 to %d lines", MaxLines)
+	}
+	o := &models.Order{}
+	if req.Currency != "" {
+		cur, err := money.LookupCurrency(req.Currency)
+		if err != nil {
+			return nil, invalidOrder("%s", err)
+		}
+		o.Currency = cur.Code
+	}
+	for _, l := range req.Lines {
+		if slices.ContainsFunc(o.Lines, func(other *models.OrderLine) bool { return other.ProductID == l.ProductID }) {
+			return nil, invalidOrder("product %d is ordered twice", l.ProductID)
+		}
+		if l.Quantity <= 0 {
+			return nil, invalidOrder("the quantity of product %d must be positive", l.ProductID)
+		}
+		q, err := s.prices.Quote(ctx, l.ProductID, o.Currency, "")
+		switch {
+		case errors.Is(err, pricing.ErrProductNotFound):
+			return nil, invalidOrder("product %d not found", l.ProductID)
+		case errors.Is(err, pricing.ErrRateNotFound), errors.Is(err, pricing.ErrInvalidPrice):
+			return nil, invalidOrder("product %d has no price in %s: %s", l.ProductID, o.Currency, err)
+		case err != nil:
+			return nil, err //nolint:wrapcheck //logged by the caller
+		}
+		if o.Currency == "" {
+			o.Currency = q.Price.Currency
+		}
+		o.Lines = append(o.Lines, &models.OrderLine{ProductID: l.ProductID, Quantity: l.Quantity, UnitPrice: q.Price})
+	}
+	if err := setTotal(o); err != nil {
+		return nil, invalidOrder("the total is out of range")
+	}
+	return o, nil
+}
+
+// setTotal sums the lines of o into its total.
+func setTotal(o *models.Order) error {
+	o.Total = money.New(0, o.Currency)
+	for _, l := range o.Lines {
+		amount, err := l.UnitPrice.Mul(int64(l.Quantity))
+		if err == nil {
+			o.Total, err = o.Total.Add(amount)
+		}
+		if err != nil {
+			return err //nolint:wrapcheck //wrapped by the callers
+		}
+	}
+	return nil
+}
+
+// withTotal sets the total of o, which orderOf keeps in range.
+func withTotal(o *models.Order) *models.Order {
+	_ = setTotal(o)
+	return o
+}
+
+func (s *Service) CreateOrder(ctx context.Context, req models.OrderRequest) (*models.Order, error) {
+	o, err := s.orderOf(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidOrder) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to price order", "error", err)
+		return nil, errors.New("failed to create order usc")
+	}
+	if err := s.repo.CreateOrder(ctx, o); err != nil {
+		logging.For(ctx, logPackage).Error("failed to create order", "error", err)
+		return nil, errors.New("failed to create order usc")
+	}
+	logging.For(ctx, logPackage).Info("order created", "id", o.ID, "lines", len(o.Lines), "total", o.Total.String())
+	return o, nil
+}
+
+func (s *Service) GetOrder(ctx context.Context, id int64) (*models.Order, error) {
+	o, err := s.repo.GetOrder(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrOrderNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to get order", "id", id, "error", err)
+		return nil, errors.New("failed to get order usc")
+	}
+	return withTotal(o), nil
+}
+
+func (s *Service) GetOrders(ctx context.Context, f models.OrderFilter) ([]*models.Order, error) {
+	switch f.Status {
+	case "", models.OrderPending, models.OrderAllocated, models.OrderShipped, models.OrderCancelled:
+	default:
+		return nil, invalidOrder("unknown status %q", f.Status)
+	}
+	orders, err := s.repo.GetOrders(ctx, f)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get orders", "error", err)
+		return nil, errors.New("failed to get orders usc")
+	}
+	for _, o := range orders {
+		withTotal(o)
+	}
+	return orders, nil
+}
+
+func (s *Service) AllocateOrder(ctx context.Context, id int64) (*models.Order, error) {
+	var o *models.Order
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if o, err = s.repo.AllocateOrder(ctx, id); err != nil {
+			return err
+		}
+		return s.takeStock(ctx, o)
+	})
+	if err != nil {
+		if isUserError(err) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to allocate order", "id", id, "error", err)
+		return nil, errors.New("failed to allocate order usc")
+	}
+	logging.For(ctx, logPackage).Info("order allocated", "id", id)
+	return withTotal(o), nil
+}
+
+func (s *Service) ShipOrder(ctx context.Context, id int64) (*models.Order, error) {
+	o, err := s.repo.ShipOrder(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrOrderNotFound) || errors.Is(err, ErrInvalidStatus) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to ship order", "id", id, "error", err)
+		return nil, errors.New("failed to ship order usc")
+	}
+	logging.For(ctx, logPackage).Info("order shipped", "id", id)
+	return withTotal(o), nil
+}
+
+func (s *Service) CancelOrder(ctx context.Context, id int64) (*models.Order, error) {
+	var o *models.Order
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if o, err = s.repo.CancelOrder(ctx, id); err != nil {
+			return err
+		}
+		if o.AllocatedAt == nil {
+			return nil
+		}
+		return s.restock(ctx, o)
+	})
+	if err != nil {
+		if isUserError(err) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to cancel order", "id", id, "error", err)
+		return nil, errors.New("failed to cancel order usc")
+	}
+	logging.For(ctx, logPackage).Info("order cancelled", "id", id, "restocked", o.AllocatedAt != nil)
+	return withTotal(o), nil
+}
+
+// byProduct sorts the lines of o by product id, the order the stock rows are
+// locked in, so that concurrent orders cannot deadlock.
+func byProduct(o *models.Order) []*models.OrderLine {
+	lines := slices.Clone(o.Lines)
+	slices.SortFunc(lines, func(a, b *models.OrderLine) int { return int(a.ProductID - b.ProductID) })
+	return lines
+}
+
+// takeStock takes the quantity of every line from the stock of its active
+// product.
+func (s *Service) takeStock(ctx context.Context, o *models.Order) error {
+	for _, l := range byProduct(o) {
+		left, err := s.products.AdjustQuantity(ctx, l.ProductID, -l.Quantity)
+		switch {
+		case errors.Is(err, product.ErrNotFound):
+			return fmt.Errorf("%w: product %d is deleted", ErrProductNotFound, l.ProductID)
+		case errors.Is(err, product.ErrInsufficientStock):
+			return fmt.Errorf("%w: product %d has %d of the %d units ordered", ErrInsufficientStock, l.ProductID, left,
+				l.Quantity)
+		case err != nil:
+			return err //nolint:wrapcheck //logged by the callers
+		}
+	}
+	return nil
+}
+
+// restock returns the quantity of every line to the stock of its product,
+// deleted or not, so that it is there if the product is restored.
+func (s *Service) restock(ctx context.Context, o *models.Order) error {
+	for _, l := range byProduct(o) {
+		if err := s.products.RestockQuantity(ctx, l.ProductID, l.Quantity); err != nil {
+			return err //nolint:wrapcheck //logged by the caller
+		}
+	}
+	return nil
+}
+
+// isUserError tells the errors returned as is from the ones to log.
+func isUserError(err error) bool {
+	return errors.Is(err, ErrOrderNotFound) || errors.Is(err, ErrInvalidStatus) ||
+		errors.Is(err, ErrInsufficientStock) || errors.Is(err, ErrProductNotFound)
+}
@@ -0,0 +1,173 @@
+package order
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/currency"
+	"prodcrud/internal/repository/order"
+	"prodcrud/internal/repository/product"
+	taxRepo "prodcrud/internal/repository/tax"
+	"prodcrud/internal/usecase/pricing"
+	"prodcrud/internal/usecase/tax"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/db/sqlitetest"
+	"prodcrud/pkg/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setup returns a service selling a pen at 3.00 with 5 in stock and ink at
+// 7.00 with 2, priced in USD at 1.25 per euro.
+func setup(t *testing.T, repo order.Repository, products product.Repository, rates currency.Repository,
+	tx db.Transactor) (s ServiceInterface, pen, ink int64) {
+	t.Helper()
+	ctx := context.Background()
+	for _, p := range []*models.Product{
+		{Name: "pen", Price: money.New(300, "EUR"), Quantity: 5, Description: "blue"},
+		{Name: "ink", Price: money.New(700, "EUR"), Quantity: 2, Description: "black"},
+	} {
+		require.NoError(t, products.CreateProduct(ctx, p))
+	}
+	prices := pricing.NewService(products, rates, tax.NewService(taxRepo.NewMemoryRepo()))
+	_, err := prices.SetRate(ctx, "EUR", "USD", money.MustParseRate("1.25"))
+	require.NoError(t, err)
+	return NewService(repo, products, prices, tx), 1, 2
+}
+
+func TestService_Orders(t *testing.T) {
+	ctx := context.Background()
+	products := product.NewMemoryRepo()
+	s, pen, ink := setup(t, order.NewMemoryRepo(), products, currency.NewMemoryRepo(), new(db.MemoryTx))
+	stock := func(id int64) int {
+		p, err := products.GetProduct(ctx, id, false)
+		require.NoError(t, err)
+		return p.Quantity
+	}
+
+	o, err := s.CreateOrder(ctx, models.OrderRequest{Lines: []models.OrderLineRequest{
+		{ProductID: pen, Quantity: 2}, {ProductID: ink, Quantity: 1},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderPending, o.Status)
+	assert.Equal(t, "EUR", o.Currency, "the base currency of the first product")
+	assert.Equal(t, money.New(300, "EUR"), o.Lines[0].UnitPrice)
+	assert.Equal(t, money.New(1300, "EUR"), o.Total)
+	assert.Equal(t, 5, stock(pen), "a pending order holds no stock")
+
+	usd, err := s.CreateOrder(ctx, models.OrderRequest{Currency: "usd", Lines: []models.OrderLineRequest{
+		{ProductID: pen, Quantity: 1},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, money.New(375, "USD"), usd.Total)
+
+	for name, req := range map[string]models.OrderRequest{
+		"no lines":         {},
+		"unknown product":  {Lines: []models.OrderLineRequest{{ProductID: 99, Quantity: 1}}},
+		"twice":            {Lines: []models.OrderLineRequest{{ProductID: pen, Quantity: 1}, {ProductID: pen, Quantity: 2}}},
+		"no quantity":      {Lines: []models.OrderLineRequest{{ProductID: pen}}},
+		"unknown currency": {Currency: "XXX", Lines: []models.OrderLineRequest{{ProductID: pen, Quantity: 1}}},
+		"no rate":          {Currency: "GBP", Lines: []models.OrderLineRequest{{ProductID: pen, Quantity: 1}}},
+	} {
+		_, err := s.CreateOrder(ctx, req)
+		assert.ErrorIs(t, err, ErrInvalidOrder, name)
+	}
+
+	_, err = s.ShipOrder(ctx, o.ID)
+	assert.ErrorIs(t, err, ErrInvalidStatus, "a pending order is allocated first")
+	o, err = s.AllocateOrder(ctx, o.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderAllocated, o.Status)
+	assert.Equal(t, money.New(1300, "EUR"), o.Total)
+	assert.Equal(t, 3, stock(pen))
+	assert.Equal(t, 1, stock(ink))
+
+	o, err = s.CancelOrder(ctx, o.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderCancelled, o.Status)
+	assert.Equal(t, 5, stock(pen), "the allocated units go back to stock")
+	assert.Equal(t, 2, stock(ink))
+
+	_, err = s.CancelOrder(ctx, usd.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 5, stock(pen), "a pending order returns nothing")
+
+	all, err := s.CreateOrder(ctx, models.OrderRequest{Lines: []models.OrderLineRequest{{ProductID: pen, Quantity: 5}}})
+	require.NoError(t, err)
+	_, err = s.AllocateOrder(ctx, all.ID)
+	require.NoError(t, err, "the last units can be sold")
+	assert.Zero(t, stock(pen))
+	shipped, err := s.ShipOrder(ctx, all.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderShipped, shipped.Status)
+	_, err = s.CancelOrder(ctx, all.ID)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+
+	_, err = s.GetOrders(ctx, models.OrderFilter{Status: "lost"})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+	cancelled, err := s.GetOrders(ctx, models.OrderFilter{Status: models.OrderCancelled})
+	require.NoError(t, err)
+	require.Len(t, cancelled, 2)
+	assert.Equal(t, money.New(375, "USD"), cancelled[0].Total)
+	_, err = s.GetOrder(ctx, 99)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+// stores returns a service on each store, with its product repository.
+func stores(t *testing.T) map[string]func(t *testing.T) (ServiceInterface, product.Repository, int64, int64) {
+	return map[string]func(t *testing.T) (ServiceInterface, product.Repository, int64, int64){
+		"memory": func(t *testing.T) (ServiceInterface, product.Repository, int64, int64) {
+			repo := order.NewMemoryRepo()
+			products := product.NewMemoryRepo(repo)
+			s, pen, ink := setup(t, repo, products, currency.NewMemoryRepo(), new(db.MemoryTx))
+			return s, products, pen, ink
+		},
+		"sqlite": func(t *testing.T) (ServiceInterface, product.Repository, int64, int64) {
+			conn := sqlitetest.New(t)
+			products := product.NewSQLiteRepo(conn)
+			s, pen, ink := setup(t, order.NewSQLiteRepo(conn), products, currency.NewSQLiteRepo(conn), conn)
+			return s, products, pen, ink
+		},
+	}
+}
+
+func TestService_AllocateOrder_rollsBack(t *testing.T) {
+	for name, open := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, products, pen, ink := open(t)
+			stock := func(id int64) int {
+				p, err := products.GetProduct(ctx, id, false)
+				require.NoError(t, err)
+				return p.Quantity
+			}
+
+			o, err := s.CreateOrder(ctx, models.OrderRequest{Lines: []models.OrderLineRequest{
+				{ProductID: pen, Quantity: 2}, {ProductID: ink, Quantity: 3},
+			}})
+			require.NoError(t, err)
+			_, err = s.AllocateOrder(ctx, o.ID)
+			assert.ErrorIs(t, err, ErrInsufficientStock)
+			assert.EqualError(t, err, "insufficient stock: product 2 has 2 of the 3 units ordered")
+			got, err := s.GetOrder(ctx, o.ID)
+			require.NoError(t, err)
+			assert.Equal(t, models.OrderPending, got.Status, "the allocation is rolled back")
+			assert.Equal(t, 5, stock(pen), "so is the stock of the pen")
+
+			o, err = s.CreateOrder(ctx, models.OrderRequest{Lines: []models.OrderLineRequest{
+				{ProductID: pen, Quantity: 2}, {ProductID: ink, Quantity: 2},
+			}})
+			require.NoError(t, err)
+			_, err = s.AllocateOrder(ctx, o.ID)
+			require.NoError(t, err)
+			require.NoError(t, products.DeleteProduct(ctx, ink))
+			o, err = s.CancelOrder(ctx, o.ID)
+			require.NoError(t, err, "a deleted product does not hold the order back")
+			assert.Equal(t, models.OrderCancelled, o.Status)
+			assert.Equal(t, 5, stock(pen))
+			require.NoError(t, products.RestoreProduct(ctx, ink))
+			assert.Equal(t, 2, stock(ink), "the deleted product got its units back")
+		})
+	}
+}
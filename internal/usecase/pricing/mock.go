@@ -0,0 +1,60 @@
+package pricing
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/money"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// ServiceMock stands in for the service in handler tests.
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (m *ServiceMock) Quote(ctx context.Context, productID int64, currency, jurisdiction string) (*models.PriceQuote, error) {
+	args := m.Called(ctx, productID, currency, jurisdiction)
+	q, _ := args.Get(0).(*models.PriceQuote)
+	return q, args.Error(1)
+}
+
+func (m *ServiceMock) GetProductPrices(ctx context.Context, productID int64, at time.Time) (*models.ProductPrices, error) {
+	args := m.Called(ctx, productID, at)
+	prices, _ := args.Get(0).(*models.ProductPrices)
+	return prices, args.Error(1)
+}
+
+func (m *ServiceMock) SchedulePrice(ctx context.Context, productID int64, req models.SchedulePriceRequest) (*models.PricePeriod, error) {
+	args := m.Called(ctx, productID, req)
+	period, _ := args.Get(0).(*models.PricePeriod)
+	return period, args.Error(1)
+}
+
+func (m *ServiceMock) SetProductPrice(ctx context.Context, productID int64, price money.Money) error {
+	args := m.Called(ctx, productID, price)
+	return args.Error(0)
+}
+
+func (m *ServiceMock) DeleteProductPrice(ctx context.Context, productID int64, currency string) error {
+	args := m.Called(ctx, productID, currency)
+	return args.Error(0)
+}
+
+func (m *ServiceMock) GetRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	args := m.Called(ctx)
+	rates, _ := args.Get(0).([]*models.ExchangeRate)
+	return rates, args.Error(1)
+}
+
+func (m *ServiceMock) SetRate(ctx context.Context, base, quote string, rate money.Rate) (*models.ExchangeRate, error) {
+	args := m.Called(ctx, base, quote, rate)
+	r, _ := args.Get(0).(*models.ExchangeRate)
+	return r, args.Error(1)
+}
+
+func (m *ServiceMock) DeleteRate(ctx context.Context, base, quote string) error {
+	args := m.Called(ctx, base, quote)
+	return args.Error(0)
+}
@@ -0,0 +1,300 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/currency"
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/usecase/tax"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"time"
+)
+
+type ServiceInterface interface {
+	// Quote returns the product price in the given currency: the base price,
+	// a list price set in that currency, or the base price converted with the
+	// stored exchange rate, in that order. An empty currency means the base.
+	// A jurisdiction adds the tax breakdown of the quoted price.
+	Quote(ctx context.Context, productID int64, currency, jurisdiction string) (*models.PriceQuote, error)
+	// GetProductPrices returns the base price in effect at the given time,
+	// now when it is zero, with the list prices and the whole price history.
+	GetProductPrices(ctx context.Context, productID int64, at time.Time) (*models.ProductPrices, error)
+	// SchedulePrice plans a base price change that starts in the future.
+	SchedulePrice(ctx context.Context, productID int64, req models.SchedulePriceRequest) (*models.PricePeriod, error)
+	SetProductPrice(ctx context.Context, productID int64, price money.Money) error
+	DeleteProductPrice(ctx context.Context, productID int64, currency string) error
+	GetRates(ctx context.Context) ([]*models.ExchangeRate, error)
+	SetRate(ctx context.Context, base, quote string, rate money.Rate) (*models.ExchangeRate, error)
+	DeleteRate(ctx context.Context, base, quote string) error
+}
+
+const logPackage = "usecase/pricing"
+
+var (
+	ErrProductNotFound = product.ErrNotFound
+	ErrRateNotFound    = currency.ErrNotFound
+	ErrPriceNotFound   = errors.New("price not found")
+	// ErrNoPriceAt is returned for instants before the first price of a product.
+	ErrNoPriceAt = errors.New("product has no price at that time")
+	// ErrInvalidPrice wraps every validation error, which callers can show as is.
+	ErrInvalidPrice         = errors.New("invalid price")
+	ErrJurisdictionNotFound = tax.ErrJurisdictionNotFound
+	ErrNoTaxRate            = tax.ErrNoRate
+)
+
+type Service struct {
+	products product.Repository
+	rates    currency.Repository
+	taxes    tax.ServiceInterface
+	now      func() time.Time
+}
+
+func NewService(products product.Repository, rates currency.Repository, taxes tax.ServiceInterface) ServiceInterface {
+	return &Service{products: products, rates: rates, taxes: taxes, now: time.Now}
+}
+
+func (s *Service) Quote(ctx context.Context, productID int64, code, jurisdiction string) (*models.PriceQuote, error) {
+	p, quote, err := s.quote(ctx, productID, code)
+	if err != nil || jurisdiction == "" {
+		return quote, err
+	}
+	if quote.Tax, err = s.taxes.Calculate(ctx, quote.Price, p.TaxClass, jurisdiction, s.now()); err != nil {
+		if errors.Is(err, tax.ErrInvalidTax) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+		}
+		return nil, err //nolint:wrapcheck //the tax errors are already user facing
+	}
+	return quote, nil
+}
+
+// quote prices the product in the currency code, without tax.
+func (s *Service) quote(ctx context.Context, productID int64, code string) (*models.Product, *models.PriceQuote, error) {
+	p, err := s.products.GetProduct(ctx, productID, false)
+	if err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			return nil, nil, ErrProductNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to get product", "id", productID, "error", err)
+		return nil, nil, errors.New("failed to quote price usc")
+	}
+	quote := &models.PriceQuote{ProductID: p.ID, Price: p.Price, BasePrice: p.Price, Source: models.PriceSourceBase}
+	if code == "" {
+		return p, quote, nil
+	}
+	cur, err := money.LookupCurrency(code)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	}
+	if cur.Code == p.Price.Currency {
+		return p, quote, nil
+	}
+
+	prices, err := s.products.GetProductPrices(ctx, productID)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get product prices", "id", productID, "error", err)
+		return nil, nil, errors.New("failed to quote price usc")
+	}
+	for _, price := range prices {
+		if price.Currency == cur.Code {
+			quote.Price, quote.Source = price, models.PriceSourceList
+			return p, quote, nil
+		}
+	}
+
+	rate, err := s.rate(ctx, p.Price.Currency, cur.Code)
+	if err != nil {
+		return nil, nil, err
+	}
+	converted, err := rate.Convert(p.Price, cur.Code)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	}
+	quote.Price, quote.Source, quote.Rate = converted, models.PriceSourceConverted, &rate
+	return p, quote, nil
+}
+
+// rate finds the base to quote rate, falling back to the inverse of a stored
+// quote to base rate.
+func (s *Service) rate(ctx context.Context, base, quote string) (money.Rate, error) {
+	r, err := s.rates.GetRate(ctx, base, quote)
+	if err == nil {
+		return r.Rate, nil
+	}
+	if !errors.Is(err, ErrRateNotFound) {
+		logging.For(ctx, logPackage).Error("failed to get exchange rate", "base", base, "quote", quote, "error", err)
+		return money.Rate{}, errors.New("failed to get exchange rate usc")
+	}
+	r, err = s.rates.GetRate(ctx, quote, base)
+	if err == nil {
+		return r.Rate.Inverse(), nil
+	}
+	if !errors.Is(err, ErrRateNotFound) {
+		logging.For(ctx, logPackage).Error("failed to get exchange rate", "base", quote, "quote", base, "error", err)
+		return money.Rate{}, errors.New("failed to get exchange rate usc")
+	}
+	return money.Rate{}, fmt.Errorf("%w: %s/%s", ErrRateNotFound, base, quote)
+}
+
+func (s *Service) GetProductPrices(ctx context.Context, productID int64, at time.Time) (*models.ProductPrices, error) {
+	if _, err := s.products.GetProduct(ctx, productID, false); err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			return nil, ErrProductNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to get product", "id", productID, "error", err)
+		return nil, errors.New("failed to get prices usc")
+	}
+	if at.IsZero() {
+		at = s.now()
+	}
+	prices := &models.ProductPrices{ProductID: productID, At: at.UTC()}
+
+	var err error
+	if prices.History, err = s.products.GetPriceHistory(ctx, productID); err != nil {
+		logging.For(ctx, logPackage).Error("failed to get price history", "id", productID, "error", err)
+		return nil, errors.New("failed to get prices usc")
+	}
+	found := false
+	for _, period := range prices.History {
+		if period.InEffect(at) {
+			prices.Price, found = period.Price, true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrNoPriceAt
+	}
+	if prices.List, err = s.products.GetProductPrices(ctx, productID); err != nil {
+		logging.For(ctx, logPackage).Error("failed to get product prices", "id", productID, "error", err)
+		return nil, errors.New("failed to get prices usc")
+	}
+	if prices.List == nil {
+		prices.List = []money.Money{}
+	}
+	return prices, nil
+}
+
+func (s *Service) SchedulePrice(ctx context.Context, productID int64, req models.SchedulePriceRequest) (*models.PricePeriod, error) {
+	if !req.Price.IsPositive() {
+		return nil, fmt.Errorf("%w: price cannot be negative or zero", ErrInvalidPrice)
+	}
+	if _, err := money.LookupCurrency(req.Price.Currency); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	}
+	// Postgres keeps microseconds, so the period reads back as it was planned
+	period := &models.PricePeriod{
+		ProductID:     productID,
+		Price:         req.Price,
+		EffectiveFrom: req.EffectiveFrom.UTC().Truncate(time.Microsecond),
+	}
+	if !period.EffectiveFrom.After(s.now()) {
+		return nil, fmt.Errorf("%w: effective_from must be in the future, update the product to change the price now", ErrInvalidPrice)
+	}
+	if req.EffectiveTo != nil {
+		to := req.EffectiveTo.UTC().Truncate(time.Microsecond)
+		if !to.After(period.EffectiveFrom) {
+			return nil, fmt.Errorf("%w: effective_to must be after effective_from", ErrInvalidPrice)
+		}
+		period.EffectiveTo = &to
+	}
+	if err := s.products.SchedulePrice(ctx, period); err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			return nil, ErrProductNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to schedule price", "id", productID, "error", err)
+		return nil, errors.New("failed to schedule price usc")
+	}
+	logging.For(ctx, logPackage).Info("price scheduled", "id", productID, "price", period.Price.String(),
+		"effective_from", period.EffectiveFrom, "effective_to", period.EffectiveTo)
+	return period, nil
+}
+
+func (s *Service) SetProductPrice(ctx context.Context, productID int64, price money.Money) error {
+	if !price.IsPositive() {
+		return fmt.Errorf("%w: price cannot be negative or zero", ErrInvalidPrice)
+	}
+	p, err := s.products.GetProduct(ctx, productID, false)
+	if err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			return ErrProductNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to get product", "id", productID, "error", err)
+		return errors.New("failed to set price usc")
+	}
+	if p.Price.Currency == price.Currency {
+		return fmt.Errorf("%w: %s is the product currency, update the product price instead", ErrInvalidPrice, price.Currency)
+	}
+	if err := s.products.SetProductPrice(ctx, productID, price); err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			return ErrProductNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to set product price", "id", productID, "error", err)
+		return errors.New("failed to set price usc")
+	}
+	return nil
+}
+
+func (s *Service) DeleteProductPrice(ctx context.Context, productID int64, code string) error {
+	if err := s.products.DeleteProductPrice(ctx, productID, code); err != nil {
+		if errors.Is(err, product.ErrNotFound) {
+			return ErrPriceNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to delete product price", "id", productID, "error", err)
+		return errors.New("failed to delete price usc")
+	}
+	return nil
+}
+
+func (s *Service) GetRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	rates, err := s.rates.GetRates(ctx)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get exchange rates", "error", err)
+		return nil, errors.New("failed to get exchange rates usc")
+	}
+	return rates, nil
+}
+
+func (s *Service) SetRate(ctx context.Context, base, quote string, rate money.Rate) (*models.ExchangeRate, error) {
+	b, err := money.LookupCurrency(base)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	}
+	q, err := money.LookupCurrency(quote)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	}
+	if b == q {
+		return nil, fmt.Errorf("%w: base and quote are the same currency", ErrInvalidPrice)
+	}
+	if rate.IsZero() {
+		return nil, fmt.Errorf("%w: rate is required", ErrInvalidPrice)
+	}
+	r := &models.ExchangeRate{Base: b.Code, Quote: q.Code, Rate: rate}
+	if err := s.rates.SetRate(ctx, r); err != nil {
+		logging.For(ctx, logPackage).Error("failed to set exchange rate", "base", b.Code, "quote", q.Code, "error", err)
+		return nil, errors.New("failed to set exchange rate usc")
+	}
+	logging.For(ctx, logPackage).Info("exchange rate set", "base", b.Code, "quote", q.Code, "rate", rate.String())
+	return r, nil
+}
+
+func (s *Service) DeleteRate(ctx context.Context, base, quote string) error {
+	b, err := money.LookupCurrency(base)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	}
+	q, err := money.LookupCurrency(quote)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPrice, err)
+	}
+	if err := s.rates.DeleteRate(ctx, b.Code, q.Code); err != nil {
+		if errors.Is(err, ErrRateNotFound) {
+			return ErrRateNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to delete exchange rate", "base", b.Code, "quote", q.Code, "error", err)
+		return errors.New("failed to delete exchange rate usc")
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package pricing
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/currency"
+	"prodcrud/internal/repository/product"
+	taxRepo "prodcrud/internal/repository/tax"
+	"prodcrud/internal/usecase/tax"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newService(t *testing.T) (ServiceInterface, *models.Product) {
+	t.Helper()
+	products := product.NewMemoryRepo()
+	p := &models.Product{Name: "Tea", Price: money.New(1999, "USD"), Quantity: 1, TaxClass: models.TaxClassStandard}
+	require.NoError(t, products.CreateProduct(context.Background(), p))
+	return NewService(products, currency.NewMemoryRepo(), tax.NewService(taxRepo.NewMemoryRepo())), p
+}
+
+func TestService_Quote(t *testing.T) {
+	ctx := context.Background()
+	s, p := newService(t)
+
+	q, err := s.Quote(ctx, p.ID, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.PriceSourceBase, q.Source)
+	assert.Equal(t, money.New(1999, "USD"), q.Price)
+
+	_, err = s.Quote(ctx, p.ID, "EUR", "")
+	assert.ErrorIs(t, err, ErrRateNotFound)
+
+	_, err = s.SetRate(ctx, "eur", "usd", money.MustParseRate("1.25"))
+	require.NoError(t, err)
+	q, err = s.Quote(ctx, p.ID, "EUR", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.PriceSourceConverted, q.Source)
+	// 19.99 USD * 0.8 = 15.992 EUR, through the inverse of the stored EUR/USD rate
+	assert.Equal(t, money.New(1599, "EUR"), q.Price)
+	assert.Equal(t, "0.8", q.Rate.String())
+
+	require.NoError(t, s.SetProductPrice(ctx, p.ID, money.New(1500, "EUR")))
+	q, err = s.Quote(ctx, p.ID, "eur", "")
+	require.NoError(t, err)
+	assert.Equal(t, models.PriceSourceList, q.Source)
+	assert.Equal(t, money.New(1500, "EUR"), q.Price)
+	assert.Nil(t, q.Rate)
+
+	_, err = s.Quote(ctx, p.ID, "XXX", "")
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+	_, err = s.Quote(ctx, p.ID+1, "EUR", "")
+	assert.ErrorIs(t, err, ErrProductNotFound)
+}
+
+func TestService_QuoteTax(t *testing.T) {
+	ctx := context.Background()
+	s, p := newService(t)
+	taxes := s.(*Service).taxes
+
+	_, err := s.Quote(ctx, p.ID, "", "us-ca")
+	assert.ErrorIs(t, err, ErrJurisdictionNotFound)
+	_, err = s.Quote(ctx, p.ID, "", "us/ca")
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+
+	_, err = taxes.SetJurisdiction(ctx, "US-CA", models.TaxJurisdictionRequest{Name: "California"})
+	require.NoError(t, err)
+	_, err = s.Quote(ctx, p.ID, "", "us-ca")
+	assert.ErrorIs(t, err, ErrNoTaxRate)
+
+	_, err = taxes.AddRate(ctx, "US-CA", models.TaxRateRequest{
+		TaxClass: models.TaxClassStandard, Rate: "7.25", ValidFrom: time.Now().AddDate(0, 0, -1),
+	})
+	require.NoError(t, err)
+	q, err := s.Quote(ctx, p.ID, "", "us-ca")
+	require.NoError(t, err)
+	require.NotNil(t, q.Tax)
+	// 19.99 * 7.25% = 1.449275, added on top of the net price
+	assert.Equal(t, money.New(145, "USD"), q.Tax.Tax)
+	assert.Equal(t, money.New(2144, "USD"), q.Tax.Gross)
+	assert.Equal(t, models.TaxClassStandard, q.Tax.TaxClass)
+
+	q, err = s.Quote(ctx, p.ID, "", "")
+	require.NoError(t, err)
+	assert.Nil(t, q.Tax)
+}
+
+func TestService_ProductPrices(t *testing.T) {
+	ctx := context.Background()
+	s, p := newService(t)
+
+	assert.ErrorIs(t, s.SetProductPrice(ctx, p.ID, money.New(1000, "USD")), ErrInvalidPrice, "product currency")
+	assert.ErrorIs(t, s.SetProductPrice(ctx, p.ID, money.New(0, "EUR")), ErrInvalidPrice)
+	assert.ErrorIs(t, s.SetProductPrice(ctx, p.ID+1, money.New(1000, "EUR")), ErrProductNotFound)
+
+	require.NoError(t, s.SetProductPrice(ctx, p.ID, money.New(1000, "EUR")))
+	prices, err := s.GetProductPrices(ctx, p.ID, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []money.Money{money.New(1000, "EUR")}, prices.List)
+	assert.Equal(t, money.New(1999, "USD"), prices.Price)
+
+	require.NoError(t, s.DeleteProductPrice(ctx, p.ID, "EUR"))
+	assert.ErrorIs(t, s.DeleteProductPrice(ctx, p.ID, "EUR"), ErrPriceNotFound)
+}
+
+func TestService_Rates(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newService(t)
+
+	_, err := s.SetRate(ctx, "USD", "USD", money.MustParseRate("1"))
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+	_, err = s.SetRate(ctx, "USD", "XXX", money.MustParseRate("1"))
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+	_, err = s.SetRate(ctx, "USD", "EUR", money.Rate{})
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+
+	r, err := s.SetRate(ctx, "usd", "uzs", money.MustParseRate("12650.5"))
+	require.NoError(t, err)
+	assert.Equal(t, "USD", r.Base)
+	rates, err := s.GetRates(ctx)
+	require.NoError(t, err)
+	require.Len(t, rates, 1)
+	assert.Equal(t, "UZS", rates[0].Quote)
+
+	require.NoError(t, s.DeleteRate(ctx, "USD", "UZS"))
+	assert.ErrorIs(t, s.DeleteRate(ctx, "USD", "UZS"), ErrRateNotFound)
+}
+
+func TestService_SchedulePrice(t *testing.T) {
+	ctx := context.Background()
+	s, p := newService(t)
+	svc := s.(*Service)
+	// a minute ahead, so the product price is already in effect
+	now := time.Now().UTC().Add(time.Minute)
+	svc.now = func() time.Time { return now }
+
+	sale := models.SchedulePriceRequest{Price: money.New(1499, "USD"), EffectiveFrom: now.Add(time.Hour)}
+	end := now.Add(2 * time.Hour)
+	sale.EffectiveTo = &end
+	period, err := s.SchedulePrice(ctx, p.ID, sale)
+	require.NoError(t, err)
+	assert.Equal(t, money.New(1499, "USD"), period.Price)
+
+	for name, at := range map[string]time.Time{"now": {}, "after the sale": now.Add(3 * time.Hour)} {
+		prices, err := s.GetProductPrices(ctx, p.ID, at)
+		require.NoError(t, err, name)
+		assert.Equal(t, money.New(1999, "USD"), prices.Price, name)
+		assert.Len(t, prices.History, 3, name)
+	}
+	prices, err := s.GetProductPrices(ctx, p.ID, now.Add(90*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, money.New(1499, "USD"), prices.Price)
+	_, err = s.GetProductPrices(ctx, p.ID, now.Add(-24*time.Hour))
+	assert.ErrorIs(t, err, ErrNoPriceAt)
+
+	_, err = s.SchedulePrice(ctx, p.ID, models.SchedulePriceRequest{Price: money.New(1, "USD"), EffectiveFrom: now})
+	assert.ErrorIs(t, err, ErrInvalidPrice, "not in the future")
+	_, err = s.SchedulePrice(ctx, p.ID, models.SchedulePriceRequest{Price: money.New(1, "USD"), EffectiveFrom: end, EffectiveTo: &end})
+	assert.ErrorIs(t, err, ErrInvalidPrice, "empty range")
+	_, err = s.SchedulePrice(ctx, p.ID, models.SchedulePriceRequest{Price: money.New(0, "USD"), EffectiveFrom: end})
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+	_, err = s.SchedulePrice(ctx, p.ID+1, models.SchedulePriceRequest{Price: money.New(1, "USD"), EffectiveFrom: end})
+	assert.ErrorIs(t, err, ErrProductNotFound)
+}
+
+func TestScheduler_Apply(t *testing.T) {
+	ctx := context.Background()
+	products := product.NewMemoryRepo()
+	p := &models.Product{Name: "Tea", Price: money.New(1999, "USD"), Quantity: 1, TaxClass: models.TaxClassStandard}
+	require.NoError(t, products.CreateProduct(ctx, p))
+	start := time.Now().UTC().Add(time.Hour)
+	require.NoError(t, products.SchedulePrice(ctx, &models.PricePeriod{ProductID: p.ID, Price: money.New(1499, "USD"), EffectiveFrom: start}))
+
+	scheduler := NewScheduler(products, time.Hour)
+	now := start.Add(-10 * time.Minute)
+	scheduler.now = func() time.Time { return now }
+	assert.Equal(t, 10*time.Minute, scheduler.wait(ctx), "wakes up when the price starts")
+	applied, err := scheduler.Apply(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	now = start
+	applied, err = scheduler.Apply(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	got, err := products.GetProduct(ctx, p.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, money.New(1499, "USD"), got.Price)
+	assert.Equal(t, time.Hour, scheduler.wait(ctx), "nothing left, falls back to the interval")
+}
@@ -0,0 +1,64 @@
+package pricing
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/logging"
+	"time"
+)
+
+// Scheduler puts scheduled prices in effect. It wakes up when the next
+// period starts, and at least every interval to notice newly scheduled ones.
+type Scheduler struct {
+	repo     product.Repository
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewScheduler(repo product.Repository, interval time.Duration) *Scheduler {
+	return &Scheduler{repo: repo, interval: interval, now: time.Now}
+}
+
+func (s *Scheduler) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+		if _, err := s.Apply(ctx); err != nil {
+			logging.For(ctx, logPackage).Error("failed to apply scheduled prices", "error", err)
+		}
+		timer.Reset(s.wait(ctx))
+	}
+}
+
+// Apply sets the prices in effect now and returns the periods it applied.
+func (s *Scheduler) Apply(ctx context.Context) ([]*models.PricePeriod, error) {
+	applied, err := s.repo.ApplyScheduledPrices(ctx, s.now())
+	if err != nil {
+		return nil, err //nolint:wrapcheck //already wrapped by the repository
+	}
+	logger := logging.For(ctx, logPackage)
+	for _, p := range applied {
+		logger.Info("scheduled price applied", "id", p.ProductID, "price", p.Price.String(), "effective_from", p.EffectiveFrom)
+	}
+	return applied, nil
+}
+
+// wait returns the time until the next price change, capped at the interval.
+func (s *Scheduler) wait(ctx context.Context) time.Duration {
+	now := s.now()
+	next, err := s.repo.NextPriceChange(ctx, now)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get next price change", "error", err)
+		return s.interval
+	}
+	if next != nil && next.Sub(now) < s.interval {
+		return max(next.Sub(now), 0)
+	}
+	return s.interval
+}
@@ -0,0 +1,253 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"prodcrud/internal/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxAttributeNameLen and MaxAttributeValueLen are in bytes.
+	MaxAttributeNameLen  = 32
+	MaxAttributeValueLen = 255
+)
+
+var (
+	// ErrInvalidAttributes wraps every error of attributes that do not
+	// follow the schema of their category.
+	ErrInvalidAttributes = errors.New("invalid attributes")
+	// ErrInvalidFilter wraps every error of a product listing filter.
+	ErrInvalidFilter = errors.New("invalid filter")
+)
+
+func invalidAttributes(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalidAttributes}, args...)...)
+}
+
+// NormalizeAttributeName trims and lower-cases an attribute name, which must
+// be a letter followed by letters, digits and underscores.
+func NormalizeAttributeName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || len(name) > MaxAttributeNameLen {
+		return "", fmt.Errorf("attribute name must have 1 to %d bytes", MaxAttributeNameLen)
+	}
+	for i, r := range name {
+		if !(r >= 'a' && r <= 'z' || i > 0 && (r >= '0' && r <= '9' || r == '_')) {
+			return "", fmt.Errorf("attribute name %q must start with a letter and hold letters, digits and underscores", name)
+		}
+	}
+	return name, nil
+}
+
+// FindProducts parses the filter values with the attribute types of the
+// category, which attribute filters require.
+func (s *Service) FindProducts(ctx context.Context, category string, attributes map[string]string, includeDeleted bool) ([]*models.Product, error) {
+	category, err := NormalizeCategory(category)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+	}
+	f := models.ProductFilter{Category: category, IncludeDeleted: includeDeleted}
+	if len(attributes) > 0 {
+		if category == "" {
+			return nil, fmt.Errorf("%w: filtering by attributes needs a category", ErrInvalidFilter)
+		}
+		defs, err := s.attributes.GetDefinitions(ctx, category)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get products usc: %w", err)
+		}
+		if f.Attributes, err = ParseAttributeFilter(defs, attributes); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+		}
+	}
+	products, err := s.repo.FindProducts(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products usc: %w", err)
+	}
+	return products, nil
+}
+
+// attributesOf validates raw against the schema of the category and returns
+// the attributes in their normal form.
+func (s *Service) attributesOf(ctx context.Context, category string, raw map[string]any) (map[string]any, error) {
+	if category == "" && len(raw) == 0 {
+		return nil, nil
+	}
+	defs, err := s.attributes.GetDefinitions(ctx, category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attributes usc: %w", err)
+	}
+	return NormalizeAttributes(defs, raw)
+}
+
+// NormalizeAttributes checks that raw sets only attributes of defs, every
+// required one, each with a value of its type, and returns them in their
+// normal form, nil if there are none.
+func NormalizeAttributes(defs []*models.AttributeDefinition, raw map[string]any) (map[string]any, error) {
+	attributes := make(map[string]any, len(raw))
+	for name, v := range raw {
+		key, err := NormalizeAttributeName(name)
+		if err != nil {
+			return nil, invalidAttributes("%s", err)
+		}
+		d := definition(defs, key)
+		if d == nil {
+			return nil, invalidAttributes("the category has no attribute %q", key)
+		}
+		if _, ok := attributes[key]; ok {
+			return nil, invalidAttributes("attribute %q is set twice", key)
+		}
+		if attributes[key], err = normalizeValue(d, v); err != nil {
+			return nil, invalidAttributes("%s: %s", key, err)
+		}
+	}
+	for _, d := range defs {
+		if _, ok := attributes[d.Name]; d.Required && !ok {
+			return nil, invalidAttributes("attribute %q is required", d.Name)
+		}
+	}
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+	return attributes, nil
+}
+
+func definition(defs []*models.AttributeDefinition, name string) *models.AttributeDefinition {
+	for _, d := range defs {
+		if d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// normalizeValue checks a decoded JSON value against the attribute type.
+func normalizeValue(d *models.AttributeDefinition, v any) (any, error) {
+	switch d.Type {
+	case models.AttributeString:
+		s, ok := v.(string)
+		if s = strings.TrimSpace(s); !ok || s == "" || len(s) > MaxAttributeValueLen {
+			return nil, fmt.Errorf("must be a string of 1 to %d bytes", MaxAttributeValueLen)
+		}
+		return s, nil
+	case models.AttributeNumber:
+		return number(v)
+	case models.AttributeEnum:
+		s, ok := v.(string)
+		if s = strings.TrimSpace(s); !ok || !slices.Contains(d.Values, s) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(d.Values, ", "))
+		}
+		return s, nil
+	case models.AttributeBoolean:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	case models.AttributeDate:
+		s, _ := v.(string)
+		t, err := time.Parse(time.DateOnly, strings.TrimSpace(s))
+		if err != nil {
+			return nil, errors.New("must be a date as YYYY-MM-DD")
+		}
+		return t.Format(time.DateOnly), nil
+	case models.AttributeUnit:
+		m, ok := v.(map[string]any)
+		if !ok || len(m) != 2 {
+			return nil, errors.New(`must be an object such as {"value": 13.3, "unit": "in"}`)
+		}
+		value, err := number(m["value"])
+		if err != nil {
+			return nil, fmt.Errorf("value %w", err)
+		}
+		unit, err := unitOf(d, m["unit"])
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"value": value, "unit": unit}, nil
+	default:
+		return nil, fmt.Errorf("unknown attribute type %q", d.Type)
+	}
+}
+
+func number(v any) (float64, error) {
+	f, ok := v.(float64)
+	if !ok || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, errors.New("must be a number")
+	}
+	return f, nil
+}
+
+func unitOf(d *models.AttributeDefinition, v any) (string, error) {
+	unit, ok := v.(string)
+	if unit = strings.TrimSpace(unit); !ok || !slices.Contains(d.Units, unit) {
+		return "", fmt.Errorf("unit must be one of %s", strings.Join(d.Units, ", "))
+	}
+	return unit, nil
+}
+
+// ParseAttributeFilter reads the query values of a listing filter, by
+// attribute name, into attributes in their normal form. A unit value reads
+// "13.3 in", or "13.3" to match the value in any unit.
+func ParseAttributeFilter(defs []*models.AttributeDefinition, query map[string]string) (map[string]any, error) {
+	raw := make(map[string]any, len(query))
+	for name, s := range query {
+		key, err := NormalizeAttributeName(name)
+		if err != nil {
+			return nil, err
+		}
+		d := definition(defs, key)
+		if d == nil {
+			return nil, fmt.Errorf("the category has no attribute %q", key)
+		}
+		if raw[key], err = parseValue(d, strings.TrimSpace(s)); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return raw, nil
+}
+
+func parseValue(d *models.AttributeDefinition, s string) (any, error) {
+	switch d.Type {
+	case models.AttributeNumber:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return number(f)
+	case models.AttributeBoolean:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	case models.AttributeUnit:
+		fields := strings.Fields(s)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, errors.New(`must read such as "13.3 in" or "13.3"`)
+		}
+		f, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, errors.New("value must be a number")
+		}
+		value, err := number(f)
+		if err != nil {
+			return nil, fmt.Errorf("value %w", err)
+		}
+		if len(fields) == 1 {
+			return map[string]any{"value": value}, nil
+		}
+		unit, err := unitOf(d, fields[1])
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"value": value, "unit": unit}, nil
+	default:
+		return normalizeValue(d, s)
+	}
+}
@@ -0,0 +1,143 @@
+package product
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/attribute"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// laptopSchema sets the attributes of the laptops category.
+func laptopSchema(t *testing.T) *attribute.MemoryRepo {
+	t.Helper()
+	repo := attribute.NewMemoryRepo()
+	for _, d := range []*models.AttributeDefinition{
+		{Category: "laptops", Name: "brand", Type: models.AttributeString, Required: true},
+		{Category: "laptops", Name: "cores", Type: models.AttributeNumber},
+		{Category: "laptops", Name: "panel", Type: models.AttributeEnum, Values: []string{"ips", "oled"}},
+		{Category: "laptops", Name: "touch", Type: models.AttributeBoolean},
+		{Category: "laptops", Name: "released", Type: models.AttributeDate},
+		{Category: "laptops", Name: "screen", Type: models.AttributeUnit, Units: []string{"in", "cm"}},
+	} {
+		require.NoError(t, repo.SetDefinition(context.Background(), d))
+	}
+	return repo
+}
+
+func laptopWith(attributes map[string]any) *models.Product {
+	return &models.Product{
+		Name: "Laptop", Price: money.New(100000, "EUR"), Quantity: 1, Description: "14 inch",
+		Category: "Laptops", Attributes: attributes,
+	}
+}
+
+func TestService_CreateProductAttributes(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(product.NewMemoryRepo(), laptopSchema(t))
+
+	p := laptopWith(map[string]any{
+		"Brand": " Acme ", "cores": 8.0, "panel": "oled", "touch": true, "released": "2025-03-01",
+		"screen": map[string]any{"value": 13.3, "unit": "in"},
+	})
+	require.NoError(t, service.CreateProduct(ctx, p))
+	got, err := service.GetProduct(ctx, p.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"brand": "Acme", "cores": 8.0, "panel": "oled", "touch": true, "released": "2025-03-01",
+		"screen": map[string]any{"value": 13.3, "unit": "in"},
+	}, got.Attributes)
+
+	for name, attributes := range map[string]map[string]any{
+		"missing required": {"panel": "ips"},
+		"unknown":          {"brand": "Acme", "color": "red"},
+		"bad number":       {"brand": "Acme", "cores": "eight"},
+		"bad enum":         {"brand": "Acme", "panel": "tn"},
+		"bad boolean":      {"brand": "Acme", "touch": "yes"},
+		"bad date":         {"brand": "Acme", "released": "01.03.2025"},
+		"bad unit":         {"brand": "Acme", "screen": map[string]any{"value": 13.3, "unit": "mm"}},
+		"unit not object":  {"brand": "Acme", "screen": 13.3},
+		"empty string":     {"brand": " "},
+	} {
+		err := service.CreateProduct(ctx, laptopWith(attributes))
+		assert.ErrorIs(t, err, ErrInvalidAttributes, name)
+	}
+
+	other := laptopWith(map[string]any{"panel": "oled"})
+	other.Category = "phones"
+	assert.ErrorIs(t, service.CreateProduct(ctx, other), ErrInvalidAttributes, "phones have no schema")
+	other.Category = ""
+	assert.ErrorIs(t, service.CreateProduct(ctx, other), ErrInvalidAttributes, "no category, no attributes")
+	other.Attributes = nil
+	require.NoError(t, service.CreateProduct(ctx, other))
+}
+
+func TestService_UpdateProductAttributes(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(product.NewMemoryRepo(), laptopSchema(t))
+	p := laptopWith(map[string]any{"brand": "Acme", "panel": "ips"})
+	require.NoError(t, service.CreateProduct(ctx, p))
+
+	require.NoError(t, service.UpdateProduct(ctx, &models.Product{ID: p.ID, Name: "Laptop 2"}))
+	got, err := service.GetProduct(ctx, p.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"brand": "Acme", "panel": "ips"}, got.Attributes, "attributes are kept")
+
+	require.NoError(t, service.UpdateProduct(ctx, &models.Product{ID: p.ID, Attributes: map[string]any{"brand": "Zeta"}}))
+	got, err = service.GetProduct(ctx, p.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"brand": "Zeta"}, got.Attributes, "attributes are replaced")
+
+	err = service.UpdateProduct(ctx, &models.Product{ID: p.ID, Attributes: map[string]any{"panel": "ips"}})
+	assert.ErrorIs(t, err, ErrInvalidAttributes)
+	err = service.UpdateProduct(ctx, &models.Product{ID: p.ID, Category: "phones"})
+	assert.ErrorIs(t, err, ErrInvalidAttributes, "the attributes must fit the new category")
+	require.NoError(t, service.UpdateProduct(ctx, &models.Product{ID: p.ID, Category: "phones", Attributes: map[string]any{}}))
+	got, err = service.GetProduct(ctx, p.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, "phones", got.Category)
+	assert.Nil(t, got.Attributes)
+}
+
+func TestService_FindProducts(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(product.NewMemoryRepo(), laptopSchema(t))
+	small := laptopWith(map[string]any{"brand": "Acme", "panel": "oled", "screen": map[string]any{"value": 13.3, "unit": "in"}})
+	large := laptopWith(map[string]any{"brand": "Acme", "panel": "ips", "screen": map[string]any{"value": 13.3, "unit": "cm"}})
+	require.NoError(t, service.CreateProduct(ctx, small))
+	require.NoError(t, service.CreateProduct(ctx, large))
+
+	find := func(category string, attributes map[string]string) []int64 {
+		t.Helper()
+		list, err := service.FindProducts(ctx, category, attributes, false)
+		require.NoError(t, err)
+		var ids []int64
+		for _, p := range list {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []int64{small.ID, large.ID}, find(" LAPTOPS", nil))
+	assert.Equal(t, []int64{small.ID}, find("laptops", map[string]string{"panel": "oled"}))
+	assert.Equal(t, []int64{small.ID}, find("laptops", map[string]string{"screen": "13.3 in"}))
+	assert.Equal(t, []int64{small.ID, large.ID}, find("laptops", map[string]string{"Screen": "13.3"}))
+	assert.Empty(t, find("laptops", map[string]string{"brand": "Zeta"}))
+
+	for name, tc := range map[string]struct {
+		category   string
+		attributes map[string]string
+	}{
+		"no category": {attributes: map[string]string{"panel": "oled"}},
+		"unknown":     {category: "laptops", attributes: map[string]string{"color": "red"}},
+		"bad number":  {category: "laptops", attributes: map[string]string{"cores": "many"}},
+		"bad unit":    {category: "laptops", attributes: map[string]string{"screen": "13.3 mm"}},
+		"bad enum":    {category: "laptops", attributes: map[string]string{"panel": "tn"}},
+	} {
+		_, err := service.FindProducts(ctx, tc.category, tc.attributes, false)
+		assert.ErrorIs(t, err, ErrInvalidFilter, name)
+	}
+}
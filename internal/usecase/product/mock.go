@@ -3,6 +3,8 @@ package product
 import (
 	"context"
 	"prodcrud/internal/models"
+	"prodcrud/pkg/money"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -16,13 +18,18 @@ func (m *Mock) CreateProduct(ctx context.Context, p *models.Product) error {
 	return args.Error(0)
 }
 
-func (m *Mock) GetAllProducts(ctx context.Context) ([]*models.Product, error) {
-	args := m.Called(ctx)
+func (m *Mock) GetAllProducts(ctx context.Context, includeDeleted bool) ([]*models.Product, error) {
+	args := m.Called(ctx, includeDeleted)
 	return args.Get(0).([]*models.Product), args.Error(1)
 }
 
-func (m *Mock) GetProduct(ctx context.Context, id int64) (*models.Product, error) {
-	args := m.Called(ctx, id)
+func (m *Mock) FindProducts(ctx context.Context, f models.ProductFilter) ([]*models.Product, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]*models.Product), args.Error(1)
+}
+
+func (m *Mock) GetProduct(ctx context.Context, id int64, includeDeleted bool) (*models.Product, error) {
+	args := m.Called(ctx, id, includeDeleted)
 	return args.Get(0).(*models.Product), args.Error(1)
 }
 
@@ -40,3 +47,163 @@ func (m *Mock) RestoreProduct(ctx context.Context, id int64) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
+
+func (m *Mock) GetProductStats(ctx context.Context, lowStock int) (*models.ProductStats, error) {
+	args := m.Called(ctx, lowStock)
+	return args.Get(0).(*models.ProductStats), args.Error(1)
+}
+
+func (m *Mock) GetDeletedProducts(ctx context.Context, before time.Time) ([]*models.Product, error) {
+	args := m.Called(ctx, before)
+	products, _ := args.Get(0).([]*models.Product)
+	return products, args.Error(1)
+}
+
+func (m *Mock) HardDeleteProduct(ctx context.Context, id int64) (*models.PurgedProduct, error) {
+	args := m.Called(ctx, id)
+	p, _ := args.Get(0).(*models.PurgedProduct)
+	return p, args.Error(1)
+}
+
+func (m *Mock) PurgeProduct(ctx context.Context, id int64, before time.Time) (*models.PurgedProduct, error) {
+	args := m.Called(ctx, id, before)
+	p, _ := args.Get(0).(*models.PurgedProduct)
+	return p, args.Error(1)
+}
+
+func (m *Mock) GetProductPrices(ctx context.Context, id int64) ([]money.Money, error) {
+	args := m.Called(ctx, id)
+	prices, _ := args.Get(0).([]money.Money)
+	return prices, args.Error(1)
+}
+
+func (m *Mock) SetProductPrice(ctx context.Context, id int64, price money.Money) error {
+	args := m.Called(ctx, id, price)
+	return args.Error(0)
+}
+
+func (m *Mock) DeleteProductPrice(ctx context.Context, id int64, currency string) error {
+	args := m.Called(ctx, id, currency)
+	return args.Error(0)
+}
+
+func (m *Mock) GetPriceHistory(ctx context.Context, id int64) ([]*models.PricePeriod, error) {
+	args := m.Called(ctx, id)
+	periods, _ := args.Get(0).([]*models.PricePeriod)
+	return periods, args.Error(1)
+}
+
+func (m *Mock) SchedulePrice(ctx context.Context, p *models.PricePeriod) error {
+	args := m.Called(ctx, p)
+	return args.Error(0)
+}
+
+func (m *Mock) ApplyScheduledPrices(ctx context.Context, now time.Time) ([]*models.PricePeriod, error) {
+	args := m.Called(ctx, now)
+	periods, _ := args.Get(0).([]*models.PricePeriod)
+	return periods, args.Error(1)
+}
+
+func (m *Mock) NextPriceChange(ctx context.Context, after time.Time) (*time.Time, error) {
+	args := m.Called(ctx, after)
+	next, _ := args.Get(0).(*time.Time)
+	return next, args.Error(1)
+}
+
+func (m *Mock) AdjustQuantity(ctx context.Context, id int64, delta int) (int, error) {
+	args := m.Called(ctx, id, delta)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *Mock) RestockQuantity(ctx context.Context, id int64, quantity int) error {
+	return m.Called(ctx, id, quantity).Error(0)
+}
+
+func (m *Mock) GetVariantAxes(ctx context.Context, id int64) ([]models.VariantAxis, error) {
+	args := m.Called(ctx, id)
+	axes, _ := args.Get(0).([]models.VariantAxis)
+	return axes, args.Error(1)
+}
+
+func (m *Mock) GetVariants(ctx context.Context, parentID int64, includeDeleted bool) ([]*models.Product, error) {
+	args := m.Called(ctx, parentID, includeDeleted)
+	variants, _ := args.Get(0).([]*models.Product)
+	return variants, args.Error(1)
+}
+
+func (m *Mock) CreateVariants(ctx context.Context, parentID int64, axes []models.VariantAxis, variants []*models.Product) error {
+	args := m.Called(ctx, parentID, axes, variants)
+	return args.Error(0)
+}
+
+// ServiceMock stands in for the service in handler tests.
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (m *ServiceMock) CreateProduct(ctx context.Context, p *models.Product) error {
+	args := m.Called(ctx, p)
+	return args.Error(0)
+}
+
+func (m *ServiceMock) GetAllProducts(ctx context.Context, includeDeleted bool) ([]*models.Product, error) {
+	args := m.Called(ctx, includeDeleted)
+	products, _ := args.Get(0).([]*models.Product)
+	return products, args.Error(1)
+}
+
+func (m *ServiceMock) FindProducts(ctx context.Context, category string, attributes map[string]string, includeDeleted bool) ([]*models.Product, error) {
+	args := m.Called(ctx, category, attributes, includeDeleted)
+	products, _ := args.Get(0).([]*models.Product)
+	return products, args.Error(1)
+}
+
+func (m *ServiceMock) GetProduct(ctx context.Context, id int64, includeDeleted bool) (*models.Product, error) {
+	args := m.Called(ctx, id, includeDeleted)
+	p, _ := args.Get(0).(*models.Product)
+	return p, args.Error(1)
+}
+
+func (m *ServiceMock) UpdateProduct(ctx context.Context, p *models.Product) error {
+	args := m.Called(ctx, p)
+	return args.Error(0)
+}
+
+func (m *ServiceMock) DeleteProduct(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *ServiceMock) RestoreProduct(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *ServiceMock) HardDeleteProduct(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *ServiceMock) GetDeletedProducts(ctx context.Context) ([]*models.Product, error) {
+	args := m.Called(ctx)
+	products, _ := args.Get(0).([]*models.Product)
+	return products, args.Error(1)
+}
+
+func (m *ServiceMock) GetVariants(ctx context.Context, parentID int64) ([]*models.Product, error) {
+	args := m.Called(ctx, parentID)
+	variants, _ := args.Get(0).([]*models.Product)
+	return variants, args.Error(1)
+}
+
+func (m *ServiceMock) GenerateVariants(ctx context.Context, parentID int64, req models.VariantMatrixRequest) (*models.VariantMatrix, error) {
+	args := m.Called(ctx, parentID, req)
+	matrix, _ := args.Get(0).(*models.VariantMatrix)
+	return matrix, args.Error(1)
+}
+
+func (m *ServiceMock) CreateVariant(ctx context.Context, parentID int64, req models.VariantRequest) (*models.Product, error) {
+	args := m.Called(ctx, parentID, req)
+	p, _ := args.Get(0).(*models.Product)
+	return p, args.Error(1)
+}
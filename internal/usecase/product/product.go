@@ -5,31 +5,54 @@ import (
 	"errors"
 	"fmt"
 	"prodcrud/internal/models"
+	"prodcrud/internal/repository/attribute"
 	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"strings"
+	"time"
 )
 
 type ServiceInterface interface {
 	CreateProduct(ctx context.Context, p *models.Product) error
-	GetAllProducts(ctx context.Context) ([]*models.Product, error)
-	GetProduct(ctx context.Context, id int64) (*models.Product, error)
+	GetAllProducts(ctx context.Context, includeDeleted bool) ([]*models.Product, error)
+	// FindProducts lists the products of a category, all categories if it
+	// is empty, filtered by attribute values given as query strings.
+	FindProducts(ctx context.Context, category string, attributes map[string]string, includeDeleted bool) ([]*models.Product, error)
+	GetProduct(ctx context.Context, id int64, includeDeleted bool) (*models.Product, error)
 	UpdateProduct(ctx context.Context, p *models.Product) error
 	DeleteProduct(ctx context.Context, id int64) error
 	RestoreProduct(ctx context.Context, id int64) error
+	HardDeleteProduct(ctx context.Context, id int64) error
+	GetDeletedProducts(ctx context.Context) ([]*models.Product, error)
+	// GetVariants lists the active variants of a parent.
+	GetVariants(ctx context.Context, parentID int64) ([]*models.Product, error)
+	// GenerateVariants creates a variant for every combination of the
+	// requested axis values the parent does not have yet.
+	GenerateVariants(ctx context.Context, parentID int64, req models.VariantMatrixRequest) (*models.VariantMatrix, error)
+	// CreateVariant adds a variant for one combination, extending the axes
+	// with new values.
+	CreateVariant(ctx context.Context, parentID int64, req models.VariantRequest) (*models.Product, error)
 }
 
+const logPackage = "usecase/product"
+
 type Service struct {
-	repo product.Repository
+	repo       product.Repository
+	attributes attribute.Repository
 }
 
-func NewService(repo product.Repository) ServiceInterface {
-	return &Service{repo: repo}
+func NewService(repo product.Repository, attributes attribute.Repository) ServiceInterface {
+	return &Service{repo: repo, attributes: attributes}
 }
 func (s *Service) CreateProduct(ctx context.Context, p *models.Product) error {
+	var err error
 	if p.Name == "" {
 		return errors.New("name is required")
 	}
-	if p.Price <= 0 {
-		return errors.New("price cannot be negative or zero")
+	if err := validatePrice(p.Price); err != nil {
+		return err
 	}
 	if p.Quantity <= 0 {
 		return errors.New("quantity cannot be negative or zero")
@@ -37,23 +60,57 @@ func (s *Service) CreateProduct(ctx context.Context, p *models.Product) error {
 	if p.Description == "" {
 		return errors.New("description is required")
 	}
+	if p.Category, err = NormalizeCategory(p.Category); err != nil {
+		return err
+	}
+	if p.TaxClass == "" {
+		p.TaxClass = models.TaxClassStandard
+	}
+	if err := ValidateTaxClass(p.TaxClass); err != nil {
+		return err
+	}
+	attributes, err := s.attributesOf(ctx, p.Category, p.Attributes)
+	if err != nil {
+		return err
+	}
+	p.Attributes = attributes
 
 	if err := s.repo.CreateProduct(ctx, p); err != nil {
+		logging.For(ctx, logPackage).Error("failed to create product", "error", err)
 		return errors.New("failed to create product usc")
 	}
 	return nil
 }
 
-func (s *Service) GetAllProducts(ctx context.Context) ([]*models.Product, error) {
-	products, err := s.repo.GetAllProducts(ctx)
+func (s *Service) GetAllProducts(ctx context.Context, includeDeleted bool) ([]*models.Product, error) {
+	products, err := s.repo.GetAllProducts(ctx, includeDeleted)
 	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get products", "error", err)
 		return nil, errors.New("failed to get products usc")
 	}
 	return products, nil
 }
 
-func (s *Service) GetProduct(ctx context.Context, id int64) (*models.Product, error) {
-	prod, err := s.repo.GetProduct(ctx, id)
+// GetProduct embeds the axes and active variants of a parent.
+func (s *Service) GetProduct(ctx context.Context, id int64, includeDeleted bool) (*models.Product, error) {
+	prod, err := s.get(ctx, id, includeDeleted)
+	if err != nil || prod.IsVariant() {
+		return prod, err
+	}
+	if prod.VariantAxes, err = s.repo.GetVariantAxes(ctx, id); err != nil {
+		return nil, fmt.Errorf("failed to get product usc: %w", err)
+	}
+	if prod.VariantAxes == nil {
+		return prod, nil
+	}
+	if prod.Variants, err = s.repo.GetVariants(ctx, id, false); err != nil {
+		return nil, fmt.Errorf("failed to get product usc: %w", err)
+	}
+	return prod, nil
+}
+
+func (s *Service) get(ctx context.Context, id int64, includeDeleted bool) (*models.Product, error) {
+	prod, err := s.repo.GetProduct(ctx, id, includeDeleted)
 	if err != nil {
 		if errors.Is(err, ErrProductNotFound) {
 			return nil, ErrProductNotFound
@@ -64,7 +121,8 @@ func (s *Service) GetProduct(ctx context.Context, id int64) (*models.Product, er
 }
 
 func (s *Service) UpdateProduct(ctx context.Context, p *models.Product) error {
-	upd, err := s.repo.GetProduct(ctx, p.ID)
+	// read-modify-write must see the latest row, not a lagging replica
+	upd, err := s.repo.GetProduct(db.WithPrimary(ctx), p.ID, false)
 	if err != nil {
 		return fmt.Errorf("failed to get product usc: %w", err)
 	}
@@ -72,7 +130,7 @@ func (s *Service) UpdateProduct(ctx context.Context, p *models.Product) error {
 	if p.Name != "" {
 		upd.Name = p.Name
 	}
-	if p.Price != 0 {
+	if !p.Price.IsZero() {
 		upd.Price = p.Price
 	}
 	if p.Quantity != 0 {
@@ -81,21 +139,44 @@ func (s *Service) UpdateProduct(ctx context.Context, p *models.Product) error {
 	if p.Description != "" {
 		upd.Description = p.Description
 	}
+	category := upd.Category
+	if p.Category != "" {
+		if upd.Category, err = NormalizeCategory(p.Category); err != nil {
+			return err
+		}
+	}
+	// new attributes replace the old ones, which must also fit a new category
+	if p.Attributes != nil || upd.Category != category {
+		if p.Attributes != nil {
+			upd.Attributes = p.Attributes
+		}
+		if upd.Attributes, err = s.attributesOf(ctx, upd.Category, upd.Attributes); err != nil {
+			return err
+		}
+	}
+	if p.TaxClass != "" {
+		if err := ValidateTaxClass(p.TaxClass); err != nil {
+			return err
+		}
+		upd.TaxClass = p.TaxClass
+	}
 
 	if upd.Name == "" {
 		return errors.New("name is required")
 	}
-	if upd.Price <= 0 {
-		return errors.New("price cannot be negative or zero")
+	if err := validatePrice(upd.Price); err != nil {
+		return err
 	}
-	if upd.Quantity <= 0 {
-		return errors.New("quantity cannot be negative or zero")
+	// orders can sell the last unit, and a sold out product stays editable
+	if upd.Quantity < 0 {
+		return errors.New("quantity cannot be negative")
 	}
 	if upd.Description == "" {
 		return errors.New("description is required")
 	}
 
 	if err := s.repo.UpdateProduct(ctx, upd); err != nil {
+		logging.For(ctx, logPackage).Error("failed to update product", "id", upd.ID, "error", err)
 		return errors.New("failed to update product usc")
 	}
 	return nil
@@ -103,6 +184,10 @@ func (s *Service) UpdateProduct(ctx context.Context, p *models.Product) error {
 
 func (s *Service) DeleteProduct(ctx context.Context, id int64) error {
 	if err := s.repo.DeleteProduct(ctx, id); err != nil {
+		if errors.Is(err, ErrProductNotFound) || errors.Is(err, ErrProductAlreadyDeleted) {
+			return err
+		}
+		logging.For(ctx, logPackage).Error("failed to delete product", "id", id, "error", err)
 		return errors.New("failed to delete product usc")
 	}
 	return nil
@@ -110,11 +195,73 @@ func (s *Service) DeleteProduct(ctx context.Context, id int64) error {
 
 func (s *Service) RestoreProduct(ctx context.Context, id int64) error {
 	if err := s.repo.RestoreProduct(ctx, id); err != nil {
+		if errors.Is(err, ErrProductNotFound) || errors.Is(err, ErrProductNotDeleted) {
+			return err
+		}
+		logging.For(ctx, logPackage).Error("failed to restore product", "id", id, "error", err)
 		return errors.New("failed to restore product usc")
 	}
 	return nil
 }
 
+func (s *Service) HardDeleteProduct(ctx context.Context, id int64) error {
+	purged, err := s.repo.HardDeleteProduct(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrProductNotFound) || errors.Is(err, ErrProductReferenced) {
+			return err
+		}
+		logging.For(ctx, logPackage).Error("failed to hard delete product", "id", id, "error", err)
+		return errors.New("failed to delete product usc")
+	}
+	logging.For(ctx, logPackage).Info("product deleted permanently", "id", id, "name", purged.Name)
+	return nil
+}
+
+func (s *Service) GetDeletedProducts(ctx context.Context) ([]*models.Product, error) {
+	products, err := s.repo.GetDeletedProducts(ctx, time.Now())
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get deleted products", "error", err)
+		return nil, errors.New("failed to get deleted products usc")
+	}
+	return products, nil
+}
+
+func validatePrice(price money.Money) error {
+	if !price.IsPositive() {
+		return errors.New("price cannot be negative or zero")
+	}
+	if _, err := money.LookupCurrency(price.Currency); err != nil {
+		return err //nolint:wrapcheck //the message names the currency
+	}
+	return nil
+}
+
+// MaxCategoryLen is the longest category name, in bytes.
+const MaxCategoryLen = 64
+
+// NormalizeCategory trims and lower-cases a category, so "Laptops " and
+// "laptops" name the same one.
+func NormalizeCategory(category string) (string, error) {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if len(category) > MaxCategoryLen {
+		return "", fmt.Errorf("category cannot be longer than %d bytes", MaxCategoryLen)
+	}
+	return category, nil
+}
+
+// ValidateTaxClass checks that class is one of the tax classes.
+func ValidateTaxClass(class string) error {
+	switch class {
+	case models.TaxClassStandard, models.TaxClassReduced, models.TaxClassExempt:
+		return nil
+	default:
+		return fmt.Errorf("tax_class must be one of %s, %s, %s", models.TaxClassStandard, models.TaxClassReduced, models.TaxClassExempt)
+	}
+}
+
 var (
-	ErrProductNotFound = errors.New("product not found")
+	ErrProductNotFound       = product.ErrNotFound
+	ErrProductAlreadyDeleted = product.ErrAlreadyDeleted
+	ErrProductNotDeleted     = product.ErrNotDeleted
+	ErrProductReferenced     = product.ErrReferenced
 )
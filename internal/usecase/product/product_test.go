@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"prodcrud/internal/models"
+	"prodcrud/internal/repository/attribute"
+	"prodcrud/pkg/money"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,11 +16,11 @@ import (
 func TestService_CreateProduct(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
 
 		p := &models.Product{
 			Name:        "Test Product",
-			Price:       1000,
+			Price:       money.New(1000, "USD"),
 			Quantity:    10,
 			Description: "Test Product Description",
 		}
@@ -31,10 +34,10 @@ func TestService_CreateProduct(t *testing.T) {
 	})
 	t.Run("failed", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
 		p := &models.Product{
 			Name:        "Test Product",
-			Price:       1000,
+			Price:       money.New(1000, "USD"),
 			Quantity:    10,
 			Description: "Test Product Description",
 		}
@@ -48,10 +51,10 @@ func TestService_CreateProduct(t *testing.T) {
 	})
 	t.Run("error with negative price", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
 		p := &models.Product{
 			Name:        "Test Product",
-			Price:       -1000,
+			Price:       money.New(-1000, "USD"),
 			Quantity:    10,
 			Description: "Test Product Description",
 		}
@@ -59,38 +62,75 @@ func TestService_CreateProduct(t *testing.T) {
 		assert.Error(t, err)
 		assert.EqualError(t, err, "price cannot be negative or zero")
 	})
+	t.Run("category is normalized", func(t *testing.T) {
+		mockRepo := new(Mock)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
+		p := &models.Product{
+			Name:        "Test Product",
+			Price:       money.New(1000, "USD"),
+			Quantity:    10,
+			Description: "Test Product Description",
+			Category:    " Laptops ",
+		}
+		mockRepo.On("CreateProduct", mock.Anything, p).Return(nil).Once()
+
+		assert.NoError(t, service.CreateProduct(context.Background(), p))
+		assert.Equal(t, "laptops", p.Category)
+
+		p.Category = strings.Repeat("x", MaxCategoryLen+1)
+		assert.EqualError(t, service.CreateProduct(context.Background(), p), "category cannot be longer than 64 bytes")
+		mockRepo.AssertExpectations(t)
+	})
+	t.Run("tax class defaults to standard", func(t *testing.T) {
+		mockRepo := new(Mock)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
+		p := &models.Product{
+			Name:        "Test Product",
+			Price:       money.New(1000, "USD"),
+			Quantity:    10,
+			Description: "Test Product Description",
+		}
+		mockRepo.On("CreateProduct", mock.Anything, p).Return(nil).Once()
+
+		assert.NoError(t, service.CreateProduct(context.Background(), p))
+		assert.Equal(t, models.TaxClassStandard, p.TaxClass)
+
+		p.TaxClass = "luxury"
+		assert.EqualError(t, service.CreateProduct(context.Background(), p), "tax_class must be one of standard, reduced, exempt")
+		mockRepo.AssertExpectations(t)
+	})
 }
 
 func TestService_GetAllProducts(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
-		mockRepo.On("GetAllProducts", mock.Anything).Return([]*models.Product{
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
+		mockRepo.On("GetAllProducts", mock.Anything, false).Return([]*models.Product{
 			{
 				ID:          1,
 				Name:        "Test Product",
-				Price:       1000,
+				Price:       money.New(1000, "USD"),
 				Quantity:    10,
 				Description: "Test Product Description",
 			},
 			{
 				ID:          2,
 				Name:        "Test Product 2",
-				Price:       1000,
+				Price:       money.New(1000, "USD"),
 				Quantity:    10,
 				Description: "Test Product Description 2",
 			},
 		}, nil).Once()
-		products, err := service.GetAllProducts(context.Background())
+		products, err := service.GetAllProducts(context.Background(), false)
 		assert.NoError(t, err)
 		assert.Len(t, products, 2)
 		mockRepo.AssertExpectations(t)
 	})
 	t.Run("failed", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
-		mockRepo.On("GetAllProducts", mock.Anything).Return([]*models.Product{}, errors.New("failed to get products usc")).Once()
-		products, err := service.GetAllProducts(context.Background())
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
+		mockRepo.On("GetAllProducts", mock.Anything, false).Return([]*models.Product{}, errors.New("failed to get products usc")).Once()
+		products, err := service.GetAllProducts(context.Background(), false)
 		assert.Error(t, err)
 		assert.Nil(t, products)
 	})
@@ -99,34 +139,35 @@ func TestService_GetAllProducts(t *testing.T) {
 func TestService_GetProduct(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
-		mockRepo.On("GetProduct", mock.Anything, int64(1)).Return(&models.Product{
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
+		mockRepo.On("GetProduct", mock.Anything, int64(1), false).Return(&models.Product{
 			ID:          1,
 			Name:        "Test Product",
-			Price:       1000,
+			Price:       money.New(1000, "USD"),
 			Quantity:    10,
 			Description: "Test Product Description",
 		}, nil).Once()
-		product, err := service.GetProduct(context.Background(), 1)
+		mockRepo.On("GetVariantAxes", mock.Anything, int64(1)).Return(nil, nil).Once()
+		product, err := service.GetProduct(context.Background(), 1, false)
 		assert.NoError(t, err)
 		assert.NotNil(t, product)
 		mockRepo.AssertExpectations(t)
 	})
 	t.Run("failed", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
-		mockRepo.On("GetProduct", mock.Anything, int64(1)).Return(
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
+		mockRepo.On("GetProduct", mock.Anything, int64(1), false).Return(
 			(*models.Product)(nil), errors.New("failed to get product usc")).Once()
-		product, err := service.GetProduct(context.Background(), 1)
+		product, err := service.GetProduct(context.Background(), 1, false)
 		assert.Error(t, err)
 		assert.Nil(t, product)
 	})
 	t.Run("not found", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
-		mockRepo.On("GetProduct", mock.Anything, int64(1)).Return(
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
+		mockRepo.On("GetProduct", mock.Anything, int64(1), false).Return(
 			(*models.Product)(nil), ErrProductNotFound).Once()
-		product, err := service.GetProduct(context.Background(), 1)
+		product, err := service.GetProduct(context.Background(), 1, false)
 		assert.Error(t, err)
 		assert.Nil(t, product)
 		assert.EqualError(t, err, "product not found")
@@ -137,23 +178,23 @@ func TestService_GetProduct(t *testing.T) {
 func TestService_UpdateProduct(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
 
 		existProd := &models.Product{
 			ID:          1,
 			Name:        "Test Product",
-			Price:       1000,
+			Price:       money.New(1000, "USD"),
 			Quantity:    10,
 			Description: "Test Product Description",
 		}
 		updatedProd := &models.Product{
 			ID:          1,
 			Name:        "Test Product Updated",
-			Price:       2000000,
+			Price:       money.New(2000000, "USD"),
 			Quantity:    20,
 			Description: "Test Product Description Updated",
 		}
-		mockRepo.On("GetProduct", mock.Anything, int64(1)).Return(existProd, nil).Once()
+		mockRepo.On("GetProduct", mock.Anything, int64(1), false).Return(existProd, nil).Once()
 		mockRepo.On("UpdateProduct", mock.Anything, updatedProd).Return(nil).Once()
 		err := service.UpdateProduct(context.Background(), updatedProd)
 		assert.NoError(t, err)
@@ -161,17 +202,49 @@ func TestService_UpdateProduct(t *testing.T) {
 		mockRepo.AssertNumberOfCalls(t, "UpdateProduct", 1)
 		mockRepo.AssertCalled(t, "UpdateProduct", mock.Anything, updatedProd)
 	})
+	t.Run("sold out", func(t *testing.T) {
+		mockRepo := new(Mock)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
+		existProd := &models.Product{
+			ID:          1,
+			Name:        "Test Product",
+			Price:       money.New(1000, "USD"),
+			Description: "Test Product Description",
+		}
+		mockRepo.On("GetProduct", mock.Anything, int64(1), false).Return(existProd, nil).Once()
+		mockRepo.On("UpdateProduct", mock.Anything, mock.Anything).Return(nil).Once()
+		err := service.UpdateProduct(context.Background(), &models.Product{ID: 1, Name: "Test Product Renamed"})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		assert.Zero(t, existProd.Quantity)
+		assert.Equal(t, "Test Product Renamed", existProd.Name)
+	})
+	t.Run("negative quantity", func(t *testing.T) {
+		mockRepo := new(Mock)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
+		existProd := &models.Product{
+			ID:          1,
+			Name:        "Test Product",
+			Price:       money.New(1000, "USD"),
+			Quantity:    10,
+			Description: "Test Product Description",
+		}
+		mockRepo.On("GetProduct", mock.Anything, int64(1), false).Return(existProd, nil).Once()
+		err := service.UpdateProduct(context.Background(), &models.Product{ID: 1, Quantity: -1})
+		assert.EqualError(t, err, "quantity cannot be negative")
+		mockRepo.AssertNumberOfCalls(t, "UpdateProduct", 0)
+	})
 	t.Run("failed", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
 		updatedProd := &models.Product{
 			ID:          1,
 			Name:        "Test Product Updated",
-			Price:       2000000,
+			Price:       money.New(2000000, "USD"),
 			Quantity:    20,
 			Description: "Test Product Description Updated",
 		}
-		mockRepo.On("GetProduct", mock.Anything, int64(1)).Return(
+		mockRepo.On("GetProduct", mock.Anything, int64(1), false).Return(
 			(*models.Product)(nil), errors.New("failed to get product usc")).Once()
 		err := service.UpdateProduct(context.Background(), updatedProd)
 		assert.Error(t, err)
@@ -181,22 +254,22 @@ func TestService_UpdateProduct(t *testing.T) {
 	})
 	t.Run("update failed", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
 		existProd := &models.Product{
 			ID:          1,
 			Name:        "Test Product",
-			Price:       1000,
+			Price:       money.New(1000, "USD"),
 			Quantity:    10,
 			Description: "Test Product Description",
 		}
 		updatedProd := &models.Product{
 			ID:          1,
 			Name:        "Test Product Updated",
-			Price:       2000000,
+			Price:       money.New(2000000, "USD"),
 			Quantity:    20,
 			Description: "Test Product Description Updated",
 		}
-		mockRepo.On("GetProduct", mock.Anything, int64(1)).Return(existProd, nil).Once()
+		mockRepo.On("GetProduct", mock.Anything, int64(1), false).Return(existProd, nil).Once()
 		mockRepo.On("UpdateProduct", mock.Anything, updatedProd).Return(
 			errors.New("failed to update product usc")).Once()
 		err := service.UpdateProduct(context.Background(), updatedProd)
@@ -210,7 +283,7 @@ func TestService_UpdateProduct(t *testing.T) {
 func TestService_DeleteProduct(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
 		mockRepo.On("DeleteProduct", mock.Anything, int64(1)).Return(nil).Once()
 		err := service.DeleteProduct(context.Background(), 1)
 		assert.NoError(t, err)
@@ -220,18 +293,25 @@ func TestService_DeleteProduct(t *testing.T) {
 	})
 	t.Run("failed", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
 		mockRepo.On("DeleteProduct", mock.Anything, int64(1)).Return(
 			errors.New("failed to delete product usc")).Once()
 		err := service.DeleteProduct(context.Background(), 1)
 		assert.Error(t, err)
 	})
+	t.Run("already deleted", func(t *testing.T) {
+		mockRepo := new(Mock)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
+		mockRepo.On("DeleteProduct", mock.Anything, int64(1)).Return(ErrProductAlreadyDeleted).Once()
+		err := service.DeleteProduct(context.Background(), 1)
+		assert.ErrorIs(t, err, ErrProductAlreadyDeleted)
+	})
 }
 
 func TestService_RestoreProduct(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
 		mockRepo.On("RestoreProduct", mock.Anything, int64(1)).Return(nil).Once()
 		err := service.RestoreProduct(context.Background(), 1)
 		assert.NoError(t, err)
@@ -241,10 +321,17 @@ func TestService_RestoreProduct(t *testing.T) {
 	})
 	t.Run("failed", func(t *testing.T) {
 		mockRepo := new(Mock)
-		service := NewService(mockRepo)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
 		mockRepo.On("RestoreProduct", mock.Anything, int64(1)).Return(
 			errors.New("failed to restore product usc")).Once()
 		err := service.RestoreProduct(context.Background(), 1)
 		assert.Error(t, err)
 	})
+	t.Run("not deleted", func(t *testing.T) {
+		mockRepo := new(Mock)
+		service := NewService(mockRepo, attribute.NewMemoryRepo())
+		mockRepo.On("RestoreProduct", mock.Anything, int64(1)).Return(ErrProductNotDeleted).Once()
+		err := service.RestoreProduct(context.Background(), 1)
+		assert.ErrorIs(t, err, ErrProductNotDeleted)
+	})
 }
@@ -0,0 +1,70 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/logging"
+	"time"
+)
+
+// Purger permanently removes products soft deleted longer than the retention
+// period. Products still referenced by other records are skipped and retried
+// on the next run.
+type Purger struct {
+	repo      product.Repository
+	retention time.Duration
+	interval  time.Duration
+	now       func() time.Time
+}
+
+func NewPurger(repo product.Repository, retention, interval time.Duration) *Purger {
+	return &Purger{repo: repo, retention: retention, interval: interval, now: time.Now}
+}
+
+func (p *Purger) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := p.Purge(ctx); err != nil {
+			logging.For(ctx, logPackage).Error("failed to purge deleted products", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge runs one retention pass.
+func (p *Purger) Purge(ctx context.Context) (*models.PurgeResult, error) {
+	cutoff := p.now().Add(-p.retention)
+	candidates, err := p.repo.GetDeletedProducts(ctx, cutoff)
+	if err != nil {
+		return nil, err //nolint:wrapcheck //already wrapped by the repository
+	}
+
+	logger := logging.For(ctx, logPackage)
+	result := &models.PurgeResult{}
+	for _, c := range candidates {
+		purged, err := p.repo.PurgeProduct(ctx, c.ID, cutoff)
+		switch {
+		case err == nil:
+			result.Purged = append(result.Purged, *purged)
+			logger.Info("product purged", "id", c.ID, "name", purged.Name, "deleted_at", purged.DeletedAt)
+		case errors.Is(err, product.ErrReferenced):
+			result.Skipped = append(result.Skipped, c.ID)
+			logger.Info("product purge skipped, still referenced", "id", c.ID)
+		case errors.Is(err, product.ErrNotFound):
+			// restored or removed since the listing
+		default:
+			return result, err //nolint:wrapcheck //already wrapped by the repository
+		}
+	}
+	if len(result.Purged) > 0 || len(result.Skipped) > 0 {
+		logger.Info("retention purge finished", "purged", len(result.Purged), "skipped", len(result.Skipped), "cutoff", cutoff)
+	}
+	return result, nil
+}
@@ -0,0 +1,47 @@
+package product
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurger_Purge(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cutoff := now.Add(-24 * time.Hour)
+
+	mockRepo := new(Mock)
+	purger := NewPurger(mockRepo, 24*time.Hour, time.Hour)
+	purger.now = func() time.Time { return now }
+
+	mockRepo.On("GetDeletedProducts", mock.Anything, cutoff).Return([]*models.Product{{ID: 1}, {ID: 2}, {ID: 3}}, nil).Once()
+	mockRepo.On("PurgeProduct", mock.Anything, int64(1), cutoff).
+		Return(&models.PurgedProduct{ProductID: 1, Name: "old", Reason: models.PurgeReasonRetention}, nil).Once()
+	mockRepo.On("PurgeProduct", mock.Anything, int64(2), cutoff).Return(nil, product.ErrReferenced).Once()
+	mockRepo.On("PurgeProduct", mock.Anything, int64(3), cutoff).Return(nil, product.ErrNotFound).Once()
+
+	result, err := purger.Purge(context.Background())
+	require.NoError(t, err)
+	require.Len(t, result.Purged, 1)
+	assert.Equal(t, int64(1), result.Purged[0].ProductID)
+	assert.Equal(t, []int64{2}, result.Skipped)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPurger_PurgeStopsOnError(t *testing.T) {
+	mockRepo := new(Mock)
+	purger := NewPurger(mockRepo, time.Hour, time.Hour)
+
+	mockRepo.On("GetDeletedProducts", mock.Anything, mock.Anything).Return([]*models.Product{{ID: 1}, {ID: 2}}, nil).Once()
+	mockRepo.On("PurgeProduct", mock.Anything, int64(1), mock.Anything).Return(nil, assert.AnError).Once()
+
+	_, err := purger.Purge(context.Background())
+	assert.ErrorIs(t, err, assert.AnError)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,388 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"slices"
+	"strings"
+)
+
+const (
+	// MaxVariantAxes bounds the axes a parent varies by.
+	MaxVariantAxes = 4
+	// MaxVariantMatrix bounds the combinations of one generation.
+	MaxVariantMatrix = 100
+	// MaxAxisNameLen and MaxAxisValueLen are in bytes.
+	MaxAxisNameLen  = 32
+	MaxAxisValueLen = 64
+	// MaxSKULen is the longest SKU, in bytes.
+	MaxSKULen = 64
+)
+
+var (
+	ErrDuplicateVariant = product.ErrDuplicateVariant
+	ErrDuplicateSKU     = product.ErrDuplicateSKU
+	// ErrInvalidVariant wraps every variant validation error.
+	ErrInvalidVariant = errors.New("invalid variant")
+)
+
+func invalidVariant(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalidVariant}, args...)...)
+}
+
+func (s *Service) GetVariants(ctx context.Context, parentID int64) ([]*models.Product, error) {
+	if _, err := s.get(ctx, parentID, false); err != nil {
+		return nil, err
+	}
+	variants, err := s.repo.GetVariants(ctx, parentID, false)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get variants", "id", parentID, "error", err)
+		return nil, errors.New("failed to get variants usc")
+	}
+	return variants, nil
+}
+
+func (s *Service) GenerateVariants(ctx context.Context, parentID int64, req models.VariantMatrixRequest) (*models.VariantMatrix, error) {
+	// the existing variants decide what is left to create, read them from the primary
+	ctx = db.WithPrimary(ctx)
+	parent, err := s.variantParent(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	axes, err := normalizeAxes(req.Axes)
+	if err != nil {
+		return nil, err
+	}
+	// checked after every axis, so that the product cannot overflow
+	combinations := 1
+	for _, a := range axes {
+		if combinations *= len(a.Values); combinations > MaxVariantMatrix {
+			return nil, invalidVariant("the axes make more than %d combinations", MaxVariantMatrix)
+		}
+	}
+	price := parent.Price
+	if req.Price != nil {
+		price = *req.Price
+	}
+	if err := validatePrice(price); err != nil {
+		return nil, invalidVariant("%w", err)
+	}
+	if req.Quantity <= 0 {
+		return nil, invalidVariant("quantity cannot be negative or zero")
+	}
+	prefix := strings.TrimSpace(req.SKUPrefix)
+	if prefix == "" {
+		prefix = fmt.Sprintf("P%d", parentID)
+	}
+
+	stored, existing, err := s.family(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergeAxes(stored, axes)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := &models.VariantMatrix{Axes: merged, Created: []*models.Product{}}
+	for _, values := range cartesian(axes) {
+		options := make(map[string]string, len(axes))
+		for i, a := range axes {
+			options[a.Name] = values[i]
+		}
+		if existing[models.VariantKey(options)] {
+			matrix.Existing++
+			continue
+		}
+		sku := skuOf(prefix, values)
+		if err := validateSKU(sku); err != nil {
+			return nil, err
+		}
+		matrix.Created = append(matrix.Created, newVariant(parent, variantName(parent.Name, values), sku, options, price, req.Quantity))
+	}
+
+	if err := s.createVariants(ctx, parentID, merged, matrix.Created); err != nil {
+		return nil, err
+	}
+	logging.For(ctx, logPackage).Info("variants generated", "id", parentID,
+		"created", len(matrix.Created), "existing", matrix.Existing)
+	return matrix, nil
+}
+
+func (s *Service) CreateVariant(ctx context.Context, parentID int64, req models.VariantRequest) (*models.Product, error) {
+	ctx = db.WithPrimary(ctx)
+	parent, err := s.variantParent(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	sku := strings.TrimSpace(req.SKU)
+	if sku == "" {
+		return nil, invalidVariant("sku is required")
+	}
+	if err := validateSKU(sku); err != nil {
+		return nil, err
+	}
+	price := parent.Price
+	if req.Price != nil {
+		price = *req.Price
+	}
+	if err := validatePrice(price); err != nil {
+		return nil, invalidVariant("%w", err)
+	}
+	if req.Quantity <= 0 {
+		return nil, invalidVariant("quantity cannot be negative or zero")
+	}
+	options, err := normalizeOptions(req.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, existing, err := s.family(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	// a first variant defines the axes, by name; later ones must set them all
+	names := make([]string, 0, len(options))
+	for _, a := range stored {
+		names = append(names, a.Name)
+	}
+	if len(stored) == 0 {
+		for name := range options {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+	if len(options) != len(names) {
+		return nil, invalidVariant("options must set exactly the axes %s", strings.Join(names, ", "))
+	}
+	axes := make([]models.VariantAxis, len(names))
+	values := make([]string, len(names))
+	for i, name := range names {
+		value, ok := options[name]
+		if !ok {
+			return nil, invalidVariant("options must set exactly the axes %s", strings.Join(names, ", "))
+		}
+		axes[i] = models.VariantAxis{Name: name, Values: []string{value}}
+		values[i] = value
+	}
+	if axes, err = normalizeAxes(axes); err != nil {
+		return nil, err
+	}
+	if existing[models.VariantKey(options)] {
+		return nil, ErrDuplicateVariant
+	}
+	merged, err := mergeAxes(stored, axes)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = variantName(parent.Name, values)
+	}
+	v := newVariant(parent, name, sku, options, price, req.Quantity)
+	if err := s.createVariants(ctx, parentID, merged, []*models.Product{v}); err != nil {
+		return nil, err
+	}
+	logging.For(ctx, logPackage).Info("variant created", "id", v.ID, "parent_id", parentID, "sku", v.SKU)
+	return v, nil
+}
+
+// variantParent returns the active product variants are added to, which
+// cannot be a variant itself.
+func (s *Service) variantParent(ctx context.Context, id int64) (*models.Product, error) {
+	parent, err := s.get(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if parent.IsVariant() {
+		return nil, invalidVariant("product %d is a variant itself", id)
+	}
+	return parent, nil
+}
+
+// family returns the axes of a parent and the keys of all its variants,
+// deleted ones included since they keep their combination.
+func (s *Service) family(ctx context.Context, parentID int64) ([]models.VariantAxis, map[string]bool, error) {
+	axes, err := s.repo.GetVariantAxes(ctx, parentID)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get variant axes", "id", parentID, "error", err)
+		return nil, nil, errors.New("failed to create variants usc")
+	}
+	variants, err := s.repo.GetVariants(ctx, parentID, true)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get variants", "id", parentID, "error", err)
+		return nil, nil, errors.New("failed to create variants usc")
+	}
+	keys := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		keys[models.VariantKey(v.Options)] = true
+	}
+	return axes, keys, nil
+}
+
+func (s *Service) createVariants(ctx context.Context, parentID int64, axes []models.VariantAxis, variants []*models.Product) error {
+	err := s.repo.CreateVariants(ctx, parentID, axes, variants)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrProductNotFound), errors.Is(err, ErrDuplicateVariant), errors.Is(err, ErrDuplicateSKU):
+		return err
+	}
+	logging.For(ctx, logPackage).Error("failed to create variants", "id", parentID, "error", err)
+	return errors.New("failed to create variants usc")
+}
+
+// normalizeAxes trims and lower-cases the axis names and trims the values,
+// so they can be compared with the stored ones.
+func normalizeAxes(axes []models.VariantAxis) ([]models.VariantAxis, error) {
+	if len(axes) == 0 {
+		return nil, invalidVariant("axes are required")
+	}
+	if len(axes) > MaxVariantAxes {
+		return nil, invalidVariant("at most %d axes are allowed", MaxVariantAxes)
+	}
+	out := make([]models.VariantAxis, len(axes))
+	for i, a := range axes {
+		name, err := normalizeAxisName(a.Name)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(out[:i], func(b models.VariantAxis) bool { return b.Name == name }) {
+			return nil, invalidVariant("axis %q is listed twice", name)
+		}
+		if len(a.Values) == 0 || len(a.Values) > MaxVariantMatrix {
+			return nil, invalidVariant("axis %q must have 1 to %d values", name, MaxVariantMatrix)
+		}
+		values := make([]string, 0, len(a.Values))
+		seen := make(map[string]bool, len(a.Values))
+		for _, v := range a.Values {
+			v = strings.TrimSpace(v)
+			if v == "" || len(v) > MaxAxisValueLen {
+				return nil, invalidVariant("values of axis %q must have 1 to %d bytes", name, MaxAxisValueLen)
+			}
+			if seen[v] {
+				return nil, invalidVariant("axis %q lists %q twice", name, v)
+			}
+			seen[v] = true
+			values = append(values, v)
+		}
+		out[i] = models.VariantAxis{Name: name, Values: values}
+	}
+	return out, nil
+}
+
+func normalizeAxisName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || len(name) > MaxAxisNameLen {
+		return "", invalidVariant("axis names must have 1 to %d bytes", MaxAxisNameLen)
+	}
+	return name, nil
+}
+
+// normalizeOptions normalizes the axis names and values of a combination.
+func normalizeOptions(options map[string]string) (map[string]string, error) {
+	if len(options) == 0 {
+		return nil, invalidVariant("options are required")
+	}
+	out := make(map[string]string, len(options))
+	for name, value := range options {
+		name, err := normalizeAxisName(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := out[name]; ok {
+			return nil, invalidVariant("axis %q is set twice", name)
+		}
+		out[name] = strings.TrimSpace(value)
+	}
+	return out, nil
+}
+
+// mergeAxes adds the values of axes missing from the stored ones. Once a
+// parent has axes, they keep their names and order.
+func mergeAxes(stored, axes []models.VariantAxis) ([]models.VariantAxis, error) {
+	if len(stored) == 0 {
+		return axes, nil
+	}
+	same := len(stored) == len(axes)
+	for i := 0; same && i < len(axes); i++ {
+		same = stored[i].Name == axes[i].Name
+	}
+	if !same {
+		names := make([]string, len(stored))
+		for i, a := range stored {
+			names[i] = a.Name
+		}
+		return nil, invalidVariant("the product varies by %s, in this order", strings.Join(names, ", "))
+	}
+	merged := make([]models.VariantAxis, len(stored))
+	for i, a := range stored {
+		values := slices.Clone(a.Values)
+		for _, v := range axes[i].Values {
+			if !slices.Contains(values, v) {
+				values = append(values, v)
+			}
+		}
+		merged[i] = models.VariantAxis{Name: a.Name, Values: values}
+	}
+	return merged, nil
+}
+
+// cartesian lists every combination of the axis values, the first axis
+// changing slowest.
+func cartesian(axes []models.VariantAxis) [][]string {
+	combinations := [][]string{{}}
+	for _, a := range axes {
+		next := make([][]string, 0, len(combinations)*len(a.Values))
+		for _, c := range combinations {
+			for _, v := range a.Values {
+				next = append(next, append(slices.Clone(c), v))
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// skuOf joins the prefix and the upper-cased values with dashes, e.g.
+// P1-SILVER-16GB.
+func skuOf(prefix string, values []string) string {
+	parts := []string{prefix}
+	for _, v := range values {
+		parts = append(parts, strings.Join(strings.Fields(strings.ToUpper(v)), "-"))
+	}
+	return strings.Join(parts, "-")
+}
+
+func validateSKU(sku string) error {
+	if len(sku) > MaxSKULen {
+		return invalidVariant("sku %q is longer than %d bytes", sku, MaxSKULen)
+	}
+	return nil
+}
+
+func variantName(parent string, values []string) string {
+	return parent + " (" + strings.Join(values, ", ") + ")"
+}
+
+// newVariant copies what a variant shares with its parent.
+func newVariant(parent *models.Product, name, sku string, options map[string]string, price money.Money, quantity int) *models.Product {
+	return &models.Product{
+		Name:        name,
+		Description: parent.Description,
+		Category:    parent.Category,
+		TaxClass:    parent.TaxClass,
+		Attributes:  parent.Attributes,
+		Price:       price,
+		Quantity:    quantity,
+		SKU:         sku,
+		Options:     options,
+	}
+}
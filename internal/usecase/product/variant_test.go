@@ -0,0 +1,172 @@
+package product
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/attribute"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/money"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLaptop(t *testing.T, service ServiceInterface) *models.Product {
+	t.Helper()
+	p := &models.Product{
+		Name: "Laptop", Price: money.New(100000, "EUR"), Quantity: 1, Description: "14 inch",
+		Category: "laptops", TaxClass: models.TaxClassReduced,
+	}
+	require.NoError(t, service.CreateProduct(context.Background(), p))
+	return p
+}
+
+func TestService_GenerateVariants(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(product.NewMemoryRepo(), attribute.NewMemoryRepo())
+	laptop := newLaptop(t, service)
+
+	matrix, err := service.GenerateVariants(ctx, laptop.ID, models.VariantMatrixRequest{
+		Axes: []models.VariantAxis{
+			{Name: " Color", Values: []string{"silver", "space gray"}},
+			{Name: "ram", Values: []string{"16GB", "32GB"}},
+		},
+		Quantity: 5,
+	})
+	require.NoError(t, err)
+	assert.Zero(t, matrix.Existing)
+	require.Len(t, matrix.Created, 4)
+	first := matrix.Created[0]
+	assert.Equal(t, "Laptop (silver, 16GB)", first.Name)
+	assert.Equal(t, "P1-SILVER-16GB", first.SKU)
+	assert.Equal(t, map[string]string{"color": "silver", "ram": "16GB"}, first.Options)
+	assert.Equal(t, laptop.Price, first.Price, "the price defaults to the parent one")
+	assert.Equal(t, "laptops", first.Category)
+	assert.Equal(t, models.TaxClassReduced, first.TaxClass)
+	assert.Equal(t, "P1-SPACE-GRAY-32GB", matrix.Created[3].SKU)
+
+	price := money.New(120000, "EUR")
+	matrix, err = service.GenerateVariants(ctx, laptop.ID, models.VariantMatrixRequest{
+		Axes: []models.VariantAxis{
+			{Name: "color", Values: []string{"silver", "gold"}},
+			{Name: "ram", Values: []string{"32GB"}},
+		},
+		SKUPrefix: "LT", Price: &price, Quantity: 2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, matrix.Existing, "silver 32GB exists already")
+	require.Len(t, matrix.Created, 1)
+	assert.Equal(t, "LT-GOLD-32GB", matrix.Created[0].SKU)
+	assert.Equal(t, price, matrix.Created[0].Price)
+	assert.Equal(t, []models.VariantAxis{
+		{Name: "color", Values: []string{"silver", "space gray", "gold"}},
+		{Name: "ram", Values: []string{"16GB", "32GB"}},
+	}, matrix.Axes)
+
+	got, err := service.GetProduct(ctx, laptop.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, matrix.Axes, got.VariantAxes)
+	assert.Len(t, got.Variants, 5)
+	variant, err := service.GetProduct(ctx, first.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, laptop.ID, *variant.ParentID)
+	assert.Empty(t, variant.Variants)
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, req := range map[string]models.VariantMatrixRequest{
+			"no axes":         {Quantity: 1},
+			"no values":       {Axes: []models.VariantAxis{{Name: "color"}}, Quantity: 1},
+			"duplicate value": {Axes: []models.VariantAxis{{Name: "color", Values: []string{"red", " red"}}}, Quantity: 1},
+			"duplicate axis":  {Axes: []models.VariantAxis{{Name: "color", Values: []string{"red"}}, {Name: "COLOR", Values: []string{"blue"}}}, Quantity: 1},
+			"other axes":      {Axes: []models.VariantAxis{{Name: "ram", Values: []string{"8GB"}}, {Name: "color", Values: []string{"red"}}}, Quantity: 1},
+			"no quantity":     {Axes: []models.VariantAxis{{Name: "color", Values: []string{"red"}}, {Name: "ram", Values: []string{"8GB"}}}},
+		} {
+			_, err := service.GenerateVariants(ctx, laptop.ID, req)
+			assert.ErrorIs(t, err, ErrInvalidVariant, name)
+		}
+		_, err := service.GenerateVariants(ctx, first.ID, models.VariantMatrixRequest{
+			Axes: []models.VariantAxis{{Name: "size", Values: []string{"s"}}}, Quantity: 1,
+		})
+		assert.ErrorIs(t, err, ErrInvalidVariant, "a variant cannot have variants")
+		_, err = service.GenerateVariants(ctx, 99, models.VariantMatrixRequest{
+			Axes: []models.VariantAxis{{Name: "size", Values: []string{"s"}}}, Quantity: 1,
+		})
+		assert.ErrorIs(t, err, ErrProductNotFound)
+	})
+
+	t.Run("too many combinations", func(t *testing.T) {
+		values := make([]string, 11)
+		for i := range values {
+			values[i] = string(rune('a' + i))
+		}
+		other := newLaptop(t, service)
+		_, err := service.GenerateVariants(ctx, other.ID, models.VariantMatrixRequest{
+			Axes:     []models.VariantAxis{{Name: "x", Values: values}, {Name: "y", Values: values}},
+			Quantity: 1,
+		})
+		assert.ErrorIs(t, err, ErrInvalidVariant)
+
+		// 65536^4 wraps an int to 0
+		values = make([]string, 1<<16)
+		for i := range values {
+			values[i] = strconv.Itoa(i)
+		}
+		axes := make([]models.VariantAxis, MaxVariantAxes)
+		for i := range axes {
+			axes[i] = models.VariantAxis{Name: string(rune('a' + i)), Values: values}
+		}
+		_, err = service.GenerateVariants(ctx, other.ID, models.VariantMatrixRequest{Axes: axes, Quantity: 1})
+		assert.ErrorIs(t, err, ErrInvalidVariant)
+	})
+}
+
+func TestService_CreateVariant(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(product.NewMemoryRepo(), attribute.NewMemoryRepo())
+	laptop := newLaptop(t, service)
+
+	v, err := service.CreateVariant(ctx, laptop.ID, models.VariantRequest{
+		SKU: "LT-RED-8", Options: map[string]string{"RAM": "8GB", "color": "red"}, Quantity: 3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Laptop (red, 8GB)", v.Name, "a first variant orders the axes by name")
+	assert.Equal(t, map[string]string{"color": "red", "ram": "8GB"}, v.Options)
+
+	v, err = service.CreateVariant(ctx, laptop.ID, models.VariantRequest{
+		SKU: "LT-BLUE-8", Name: "Blue laptop", Options: map[string]string{"ram": "8GB", "color": "blue"}, Quantity: 1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Blue laptop", v.Name)
+
+	_, err = service.CreateVariant(ctx, laptop.ID, models.VariantRequest{
+		SKU: "LT-RED-8-B", Options: map[string]string{"ram": "8GB", "color": " red"}, Quantity: 1,
+	})
+	assert.ErrorIs(t, err, ErrDuplicateVariant)
+	_, err = service.CreateVariant(ctx, laptop.ID, models.VariantRequest{
+		SKU: "LT-RED-8", Options: map[string]string{"ram": "16GB", "color": "red"}, Quantity: 1,
+	})
+	assert.ErrorIs(t, err, ErrDuplicateSKU)
+	_, err = service.CreateVariant(ctx, laptop.ID, models.VariantRequest{
+		SKU: "LT-RED", Options: map[string]string{"color": "red"}, Quantity: 1,
+	})
+	assert.ErrorIs(t, err, ErrInvalidVariant, "every axis must be set")
+	_, err = service.CreateVariant(ctx, laptop.ID, models.VariantRequest{
+		Options: map[string]string{"ram": "4GB", "color": "red"}, Quantity: 1,
+	})
+	assert.ErrorIs(t, err, ErrInvalidVariant, "the sku is required")
+
+	// a deleted variant keeps its combination
+	require.NoError(t, service.DeleteProduct(ctx, v.ID))
+	_, err = service.CreateVariant(ctx, laptop.ID, models.VariantRequest{
+		SKU: "LT-BLUE-8-B", Options: map[string]string{"ram": "8GB", "color": "blue"}, Quantity: 1,
+	})
+	assert.ErrorIs(t, err, ErrDuplicateVariant)
+
+	variants, err := service.GetVariants(ctx, laptop.ID)
+	require.NoError(t, err)
+	assert.Len(t, variants, 1)
+	_, err = service.GetVariants(ctx, 99)
+	assert.ErrorIs(t, err, ErrProductNotFound)
+}
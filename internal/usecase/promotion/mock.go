@@ -0,0 +1,47 @@
+package promotion
+
+import (
+	"context"
+	"prodcrud/internal/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// ServiceMock stands in for the service in handler tests.
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (m *ServiceMock) CreatePromotion(ctx context.Context, req models.PromotionRequest) (*models.Promotion, error) {
+	args := m.Called(ctx, req)
+	p, _ := args.Get(0).(*models.Promotion)
+	return p, args.Error(1)
+}
+
+func (m *ServiceMock) GetPromotion(ctx context.Context, id int64) (*models.Promotion, error) {
+	args := m.Called(ctx, id)
+	p, _ := args.Get(0).(*models.Promotion)
+	return p, args.Error(1)
+}
+
+func (m *ServiceMock) GetPromotions(ctx context.Context) ([]*models.Promotion, error) {
+	args := m.Called(ctx)
+	promotions, _ := args.Get(0).([]*models.Promotion)
+	return promotions, args.Error(1)
+}
+
+func (m *ServiceMock) UpdatePromotion(ctx context.Context, id int64, req models.PromotionRequest) (*models.Promotion, error) {
+	args := m.Called(ctx, id, req)
+	p, _ := args.Get(0).(*models.Promotion)
+	return p, args.Error(1)
+}
+
+func (m *ServiceMock) DeletePromotion(ctx context.Context, id int64) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *ServiceMock) QuoteBasket(ctx context.Context, req models.QuoteRequest) (*models.BasketQuote, error) {
+	args := m.Called(ctx, req)
+	q, _ := args.Get(0).(*models.BasketQuote)
+	return q, args.Error(1)
+}
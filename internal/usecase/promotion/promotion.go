@@ -0,0 +1,247 @@
+package promotion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/promotion"
+	"prodcrud/internal/usecase/pricing"
+	productService "prodcrud/internal/usecase/product"
+	"prodcrud/internal/usecase/tax"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"time"
+)
+
+type ServiceInterface interface {
+	CreatePromotion(ctx context.Context, req models.PromotionRequest) (*models.Promotion, error)
+	GetPromotion(ctx context.Context, id int64) (*models.Promotion, error)
+	GetPromotions(ctx context.Context) ([]*models.Promotion, error)
+	UpdatePromotion(ctx context.Context, id int64, req models.PromotionRequest) (*models.Promotion, error)
+	DeletePromotion(ctx context.Context, id int64) error
+	// QuoteBasket prices every line and applies the promotions in effect now,
+	// explaining each decision in the line trace. A jurisdiction then taxes
+	// every discounted line total.
+	QuoteBasket(ctx context.Context, req models.QuoteRequest) (*models.BasketQuote, error)
+}
+
+const logPackage = "usecase/promotion"
+
+// MaxBasketLines bounds the lines of a quoted basket.
+const MaxBasketLines = 100
+
+var (
+	ErrPromotionNotFound = promotion.ErrNotFound
+	ErrProductNotFound   = pricing.ErrProductNotFound
+	ErrRateNotFound      = pricing.ErrRateNotFound
+	// ErrJurisdictionNotFound and ErrNoTaxRate come from taxing a basket.
+	ErrJurisdictionNotFound = tax.ErrJurisdictionNotFound
+	ErrNoTaxRate            = tax.ErrNoRate
+	// ErrInvalidPromotion wraps every promotion validation error.
+	ErrInvalidPromotion = errors.New("invalid promotion")
+	// ErrInvalidBasket wraps every basket validation error.
+	ErrInvalidBasket = errors.New("invalid basket")
+)
+
+type Service struct {
+	promotions promotion.Repository
+	products   product.Repository
+	pricing    pricing.ServiceInterface
+	taxes      tax.ServiceInterface
+	now        func() time.Time
+}
+
+func NewService(promotions promotion.Repository, products product.Repository, prices pricing.ServiceInterface,
+	taxes tax.ServiceInterface) ServiceInterface {
+	return &Service{promotions: promotions, products: products, pricing: prices, taxes: taxes, now: time.Now}
+}
+
+func (s *Service) CreatePromotion(ctx context.Context, req models.PromotionRequest) (*models.Promotion, error) {
+	p, err := newPromotion(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.promotions.CreatePromotion(ctx, p); err != nil {
+		logging.For(ctx, logPackage).Error("failed to create promotion", "error", err)
+		return nil, errors.New("failed to create promotion usc")
+	}
+	logging.For(ctx, logPackage).Info("promotion created", "id", p.ID, "name", p.Name, "kind", p.Kind)
+	return p, nil
+}
+
+func (s *Service) GetPromotion(ctx context.Context, id int64) (*models.Promotion, error) {
+	p, err := s.promotions.GetPromotion(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrPromotionNotFound) {
+			return nil, ErrPromotionNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to get promotion", "id", id, "error", err)
+		return nil, errors.New("failed to get promotion usc")
+	}
+	return p, nil
+}
+
+func (s *Service) GetPromotions(ctx context.Context) ([]*models.Promotion, error) {
+	promotions, err := s.promotions.GetPromotions(ctx)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get promotions", "error", err)
+		return nil, errors.New("failed to get promotions usc")
+	}
+	return promotions, nil
+}
+
+func (s *Service) UpdatePromotion(ctx context.Context, id int64, req models.PromotionRequest) (*models.Promotion, error) {
+	p, err := newPromotion(req)
+	if err != nil {
+		return nil, err
+	}
+	p.ID = id
+	if err := s.promotions.UpdatePromotion(ctx, p); err != nil {
+		if errors.Is(err, ErrPromotionNotFound) {
+			return nil, ErrPromotionNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to update promotion", "id", id, "error", err)
+		return nil, errors.New("failed to update promotion usc")
+	}
+	logging.For(ctx, logPackage).Info("promotion updated", "id", p.ID, "name", p.Name, "kind", p.Kind)
+	return p, nil
+}
+
+func (s *Service) DeletePromotion(ctx context.Context, id int64) error {
+	if err := s.promotions.DeletePromotion(ctx, id); err != nil {
+		if errors.Is(err, ErrPromotionNotFound) {
+			return ErrPromotionNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to delete promotion", "id", id, "error", err)
+		return errors.New("failed to delete promotion usc")
+	}
+	logging.For(ctx, logPackage).Info("promotion deleted", "id", id)
+	return nil
+}
+
+var hundred = money.MustParseRate("100")
+
+// newPromotion validates req. Every kind requires its own parameters and
+// rejects those of the other kinds, so a stored rule is never ambiguous.
+func newPromotion(req models.PromotionRequest) (*models.Promotion, error) {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: "+format, append([]any{ErrInvalidPromotion}, args...)...)
+	}
+	if req.Name == "" {
+		return nil, invalid("name is required")
+	}
+	if len(req.ProductIDs) == 0 && len(req.Categories) == 0 {
+		return nil, invalid("product_ids or categories is required")
+	}
+	for _, id := range req.ProductIDs {
+		if id <= 0 {
+			return nil, invalid("product id %d is not positive", id)
+		}
+	}
+	categories := make([]string, 0, len(req.Categories))
+	for _, c := range req.Categories {
+		category, err := productService.NormalizeCategory(c)
+		if err != nil {
+			return nil, invalid("%w", err)
+		}
+		if category == "" {
+			return nil, invalid("categories cannot be empty")
+		}
+		categories = append(categories, category)
+	}
+	if req.StartsAt.IsZero() {
+		return nil, invalid("starts_at is required")
+	}
+	if req.EndsAt != nil && !req.EndsAt.After(req.StartsAt) {
+		return nil, invalid("ends_at must be after starts_at")
+	}
+
+	var (
+		hasPercent = req.Percent != nil
+		hasAmount  = req.Amount != nil
+		hasBuyGet  = req.Buy != 0 || req.Get != 0
+		hasTiers   = len(req.Tiers) > 0
+	)
+	switch req.Kind {
+	case models.PromotionPercentage:
+		if !hasPercent {
+			return nil, invalid("percent is required")
+		}
+		hasPercent = false
+		if err := validPercent(*req.Percent); err != nil {
+			return nil, invalid("%w", err)
+		}
+	case models.PromotionFixedAmount:
+		if !hasAmount {
+			return nil, invalid("amount is required")
+		}
+		hasAmount = false
+		if !req.Amount.IsPositive() {
+			return nil, invalid("amount must be positive")
+		}
+		cur, err := money.LookupCurrency(req.Amount.Currency)
+		if err != nil {
+			return nil, invalid("%w", err)
+		}
+		req.Amount = &money.Money{Amount: req.Amount.Amount, Currency: cur.Code}
+	case models.PromotionBuyXGetY:
+		if req.Buy <= 0 || req.Get <= 0 {
+			return nil, invalid("buy and get must be positive")
+		}
+		hasBuyGet = false
+	case models.PromotionTiered:
+		if !hasTiers {
+			return nil, invalid("tiers are required")
+		}
+		hasTiers = false
+		for i, tier := range req.Tiers {
+			if tier.MinQuantity <= 0 {
+				return nil, invalid("tier min_quantity must be positive")
+			}
+			if i > 0 && tier.MinQuantity <= req.Tiers[i-1].MinQuantity {
+				return nil, invalid("tiers must be sorted by growing min_quantity")
+			}
+			if err := validPercent(tier.Percent); err != nil {
+				return nil, invalid("tier %w", err)
+			}
+		}
+	default:
+		return nil, invalid("kind must be one of percentage, fixed_amount, buy_x_get_y, tiered")
+	}
+	if hasPercent || hasAmount || hasBuyGet || hasTiers {
+		return nil, invalid("only the parameters of a %s promotion can be set", req.Kind)
+	}
+
+	return &models.Promotion{
+		Name:       req.Name,
+		Kind:       req.Kind,
+		ProductIDs: append([]int64{}, req.ProductIDs...),
+		Categories: categories,
+		Percent:    req.Percent,
+		Amount:     req.Amount,
+		Buy:        req.Buy,
+		Get:        req.Get,
+		Tiers:      req.Tiers,
+		Priority:   req.Priority,
+		Stackable:  req.Stackable,
+		StartsAt:   req.StartsAt.UTC(),
+		EndsAt:     utc(req.EndsAt),
+	}, nil
+}
+
+func validPercent(p money.Rate) error {
+	if p.IsZero() || p.Cmp(hundred) > 0 {
+		return errors.New("percent must be above 0 and at most 100")
+	}
+	return nil
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
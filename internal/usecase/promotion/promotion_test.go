@@ -0,0 +1,241 @@
+package promotion
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/currency"
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/promotion"
+	taxRepo "prodcrud/internal/repository/tax"
+	"prodcrud/internal/usecase/pricing"
+	"prodcrud/internal/usecase/tax"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, 8, 15, 0, 0, 0, 0, time.UTC)
+
+func percent(s string) *money.Rate {
+	r := money.MustParseRate(s)
+	return &r
+}
+
+func amount(m money.Money) *money.Money {
+	return &m
+}
+
+func newService(t *testing.T) (*Service, *product.MemoryRepo) {
+	t.Helper()
+	products := product.NewMemoryRepo()
+	taxes := tax.NewService(taxRepo.NewMemoryRepo())
+	s := NewService(promotion.NewMemoryRepo(), products, pricing.NewService(products, currency.NewMemoryRepo(), taxes), taxes).(*Service)
+	s.now = func() time.Time { return start.AddDate(0, 0, 1) }
+	return s, products
+}
+
+func TestService_CreatePromotion(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newService(t)
+
+	p, err := s.CreatePromotion(ctx, models.PromotionRequest{
+		Name: "Bulk cables", Kind: models.PromotionTiered, Categories: []string{" Cables"}, StartsAt: start,
+		Tiers: []models.PromotionTier{{MinQuantity: 10, Percent: money.MustParseRate("5")}},
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, p.ID)
+	assert.Equal(t, []string{"cables"}, p.Categories)
+
+	p, err = s.CreatePromotion(ctx, models.PromotionRequest{
+		Name: "Five off", Kind: models.PromotionFixedAmount, ProductIDs: []int64{1}, StartsAt: start,
+		Amount: amount(money.New(500, "usd")),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "USD", p.Amount.Currency)
+
+	end := start.Add(-time.Hour)
+	for name, req := range map[string]models.PromotionRequest{
+		"no name":        {Kind: models.PromotionPercentage, ProductIDs: []int64{1}, Percent: percent("5"), StartsAt: start},
+		"no scope":       {Name: "x", Kind: models.PromotionPercentage, Percent: percent("5"), StartsAt: start},
+		"bad product":    {Name: "x", Kind: models.PromotionPercentage, ProductIDs: []int64{0}, Percent: percent("5"), StartsAt: start},
+		"no start":       {Name: "x", Kind: models.PromotionPercentage, ProductIDs: []int64{1}, Percent: percent("5")},
+		"ends first":     {Name: "x", Kind: models.PromotionPercentage, ProductIDs: []int64{1}, Percent: percent("5"), StartsAt: start, EndsAt: &end},
+		"unknown kind":   {Name: "x", Kind: "bogo", ProductIDs: []int64{1}, StartsAt: start},
+		"no percent":     {Name: "x", Kind: models.PromotionPercentage, ProductIDs: []int64{1}, StartsAt: start},
+		"over 100":       {Name: "x", Kind: models.PromotionPercentage, ProductIDs: []int64{1}, Percent: percent("100.5"), StartsAt: start},
+		"mixed params":   {Name: "x", Kind: models.PromotionPercentage, ProductIDs: []int64{1}, Percent: percent("5"), Buy: 2, StartsAt: start},
+		"no amount":      {Name: "x", Kind: models.PromotionFixedAmount, ProductIDs: []int64{1}, StartsAt: start},
+		"bad currency":   {Name: "x", Kind: models.PromotionFixedAmount, ProductIDs: []int64{1}, Amount: amount(money.New(1, "XXX")), StartsAt: start},
+		"no get":         {Name: "x", Kind: models.PromotionBuyXGetY, ProductIDs: []int64{1}, Buy: 2, StartsAt: start},
+		"no tiers":       {Name: "x", Kind: models.PromotionTiered, ProductIDs: []int64{1}, StartsAt: start},
+		"unsorted tiers": {Name: "x", Kind: models.PromotionTiered, ProductIDs: []int64{1}, StartsAt: start, Tiers: []models.PromotionTier{{MinQuantity: 5, Percent: money.MustParseRate("5")}, {MinQuantity: 5, Percent: money.MustParseRate("6")}}},
+		"tier percent":   {Name: "x", Kind: models.PromotionTiered, ProductIDs: []int64{1}, StartsAt: start, Tiers: []models.PromotionTier{{MinQuantity: 5}}},
+	} {
+		_, err := s.CreatePromotion(ctx, req)
+		assert.ErrorIs(t, err, ErrInvalidPromotion, name)
+	}
+
+	_, err = s.UpdatePromotion(ctx, 424242, models.PromotionRequest{
+		Name: "x", Kind: models.PromotionBuyXGetY, ProductIDs: []int64{1}, Buy: 2, Get: 1, StartsAt: start,
+	})
+	assert.ErrorIs(t, err, ErrPromotionNotFound)
+}
+
+func TestService_QuoteBasket(t *testing.T) {
+	ctx := context.Background()
+	s, products := newService(t)
+
+	laptop := &models.Product{Name: "Laptop", Price: money.New(100000, "USD"), Quantity: 5, Category: "laptops"}
+	cable := &models.Product{Name: "Cable", Price: money.New(500, "USD"), Quantity: 50, Category: "cables"}
+	mouse := &models.Product{Name: "Mouse", Price: money.New(2000, "USD"), Quantity: 9}
+	for _, p := range []*models.Product{laptop, cable, mouse} {
+		require.NoError(t, products.CreateProduct(ctx, p))
+	}
+	ended := start.Add(time.Hour)
+	for _, req := range []models.PromotionRequest{
+		{Name: "Laptops 10%", Kind: models.PromotionPercentage, Categories: []string{"laptops"}, Percent: percent("10"), Priority: 1, Stackable: true, StartsAt: start},
+		{Name: "Laptop 50 off", Kind: models.PromotionFixedAmount, ProductIDs: []int64{laptop.ID}, Amount: amount(money.New(5000, "USD")), Priority: 5, Stackable: true, StartsAt: start},
+		{Name: "Bulk cables", Kind: models.PromotionTiered, Categories: []string{"cables"}, Tiers: []models.PromotionTier{{MinQuantity: 10, Percent: money.MustParseRate("5")}, {MinQuantity: 50, Percent: money.MustParseRate("10")}}, StartsAt: start},
+		{Name: "Euro off", Kind: models.PromotionFixedAmount, ProductIDs: []int64{cable.ID}, Amount: amount(money.New(100, "EUR")), Priority: 2, Stackable: true, StartsAt: start},
+		{Name: "Mice 3 for 2", Kind: models.PromotionBuyXGetY, ProductIDs: []int64{mouse.ID}, Buy: 2, Get: 1, StartsAt: start},
+		{Name: "Mice 5%", Kind: models.PromotionPercentage, ProductIDs: []int64{mouse.ID}, Percent: percent("5"), Priority: -1, Stackable: true, StartsAt: start},
+		{Name: "Flash sale", Kind: models.PromotionPercentage, ProductIDs: []int64{mouse.ID}, Percent: percent("50"), Priority: 10, StartsAt: start, EndsAt: &ended},
+		{Name: "Next month", Kind: models.PromotionPercentage, Categories: []string{"cables"}, Percent: percent("20"), Priority: 3, StartsAt: start.AddDate(0, 1, 0)},
+	} {
+		_, err := s.CreatePromotion(ctx, req)
+		require.NoError(t, err, req.Name)
+	}
+
+	q, err := s.QuoteBasket(ctx, models.QuoteRequest{Lines: []models.QuoteLineItem{
+		{ProductID: laptop.ID, Quantity: 2},
+		{ProductID: cable.ID, Quantity: 12},
+		{ProductID: mouse.ID, Quantity: 3},
+	}})
+	require.NoError(t, err)
+	require.Len(t, q.Lines, 3)
+
+	// 2000.00 - 2 * 50.00, then 10% of 1900.00
+	l := q.Lines[0]
+	assert.Equal(t, money.New(200000, "USD"), l.Subtotal)
+	assert.Equal(t, money.New(29000, "USD"), l.Discount)
+	assert.Equal(t, money.New(171000, "USD"), l.Total)
+	require.Len(t, l.Promotions, 2)
+	assert.Equal(t, "Laptop 50 off", l.Promotions[0].Name)
+	assert.Equal(t, money.New(19000, "USD"), l.Promotions[1].Discount)
+
+	// the euro amount cannot apply to a dollar line, the 5% tier does
+	l = q.Lines[1]
+	assert.Equal(t, money.New(5700, "USD"), l.Total)
+	require.Len(t, l.Promotions, 1)
+	assert.Equal(t, "Bulk cables", l.Promotions[0].Name)
+	require.Len(t, l.Trace, 2)
+	assert.Contains(t, l.Trace[0], `"Euro off" (1.00 EUR off each unit, priority 2) skipped: amount is in EUR, the line in USD`)
+
+	// one mouse free, and the 3 for 2 does not stack with the 5%
+	l = q.Lines[2]
+	assert.Equal(t, money.New(4000, "USD"), l.Total)
+	require.Len(t, l.Trace, 2)
+	assert.Contains(t, l.Trace[0], `"Mice 3 for 2" (buy 2 get 1 free, priority 0) applied: -20.00 USD, line total 40.00 USD`)
+	assert.Contains(t, l.Trace[1], `"Mice 5%" (5% off, priority -1) skipped: a promotion that does not stack was applied`)
+
+	assert.Equal(t, "USD", q.Currency)
+	assert.Equal(t, money.New(212000, "USD"), q.Subtotal)
+	assert.Equal(t, money.New(31300, "USD"), q.Discount)
+	assert.Equal(t, money.New(180700, "USD"), q.Total)
+
+	q, err = s.QuoteBasket(ctx, models.QuoteRequest{Lines: []models.QuoteLineItem{{ProductID: cable.ID, Quantity: 1}}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`promotion 3 "Bulk cables" (tiered 10+: 5%, 50+: 10%, priority 0) skipped: needs 10 units for the first tier, the line has 1`}, q.Lines[0].Trace[1:])
+	assert.Empty(t, q.Lines[0].Promotions)
+	assert.Equal(t, money.New(500, "USD"), q.Total)
+}
+
+func TestService_QuoteBasketCurrencies(t *testing.T) {
+	ctx := context.Background()
+	s, products := newService(t)
+	tea := &models.Product{Name: "Tea", Price: money.New(1000, "USD"), Quantity: 5}
+	cheese := &models.Product{Name: "Cheese", Price: money.New(800, "EUR"), Quantity: 5}
+	require.NoError(t, products.CreateProduct(ctx, tea))
+	require.NoError(t, products.CreateProduct(ctx, cheese))
+	basket := []models.QuoteLineItem{{ProductID: tea.ID, Quantity: 1}, {ProductID: cheese.ID, Quantity: 2}}
+
+	_, err := s.QuoteBasket(ctx, models.QuoteRequest{Lines: basket})
+	assert.ErrorIs(t, err, ErrInvalidBasket, "mixed currencies")
+	_, err = s.QuoteBasket(ctx, models.QuoteRequest{Currency: "EUR", Lines: basket})
+	assert.ErrorIs(t, err, ErrRateNotFound)
+
+	_, err = s.pricing.SetRate(ctx, "USD", "EUR", money.MustParseRate("0.9"))
+	require.NoError(t, err)
+	q, err := s.QuoteBasket(ctx, models.QuoteRequest{Currency: "eur", Lines: basket})
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", q.Currency)
+	assert.Equal(t, money.New(900, "EUR"), q.Lines[0].UnitPrice)
+	assert.Equal(t, money.New(2500, "EUR"), q.Total)
+
+	for name, req := range map[string]models.QuoteRequest{
+		"empty":     {},
+		"quantity":  {Lines: []models.QuoteLineItem{{ProductID: tea.ID}}},
+		"duplicate": {Lines: []models.QuoteLineItem{{ProductID: tea.ID, Quantity: 1}, {ProductID: tea.ID, Quantity: 1}}},
+		"currency":  {Currency: "XXX", Lines: basket},
+	} {
+		_, err := s.QuoteBasket(ctx, req)
+		assert.ErrorIs(t, err, ErrInvalidBasket, name)
+	}
+	_, err = s.QuoteBasket(ctx, models.QuoteRequest{Lines: []models.QuoteLineItem{{ProductID: 424242, Quantity: 1}}})
+	assert.ErrorIs(t, err, ErrProductNotFound)
+}
+
+func TestService_QuoteBasketTax(t *testing.T) {
+	ctx := context.Background()
+	s, products := newService(t)
+	book := &models.Product{Name: "Book", Price: money.New(1999, "EUR"), Quantity: 5, TaxClass: models.TaxClassReduced}
+	lamp := &models.Product{Name: "Lamp", Price: money.New(5950, "EUR"), Quantity: 5, TaxClass: models.TaxClassStandard}
+	voucher := &models.Product{Name: "Voucher", Price: money.New(2500, "EUR"), Quantity: 5, TaxClass: models.TaxClassExempt}
+	for _, p := range []*models.Product{book, lamp, voucher} {
+		require.NoError(t, products.CreateProduct(ctx, p))
+	}
+	basket := []models.QuoteLineItem{{ProductID: book.ID, Quantity: 2}, {ProductID: lamp.ID, Quantity: 1}, {ProductID: voucher.ID, Quantity: 1}}
+
+	_, err := s.QuoteBasket(ctx, models.QuoteRequest{Jurisdiction: "de", Lines: basket})
+	assert.ErrorIs(t, err, ErrJurisdictionNotFound)
+	_, err = s.QuoteBasket(ctx, models.QuoteRequest{Jurisdiction: "d e", Lines: basket})
+	assert.ErrorIs(t, err, ErrInvalidBasket)
+
+	_, err = s.taxes.SetJurisdiction(ctx, "DE", models.TaxJurisdictionRequest{Name: "Germany", PricesIncludeTax: true})
+	require.NoError(t, err)
+	_, err = s.taxes.AddRate(ctx, "DE", models.TaxRateRequest{TaxClass: models.TaxClassStandard, Rate: "19", ValidFrom: start})
+	require.NoError(t, err)
+	_, err = s.QuoteBasket(ctx, models.QuoteRequest{Jurisdiction: "de", Lines: basket})
+	assert.ErrorIs(t, err, ErrNoTaxRate, "books need the reduced rate")
+
+	_, err = s.taxes.AddRate(ctx, "DE", models.TaxRateRequest{TaxClass: models.TaxClassReduced, Rate: "7", ValidFrom: start})
+	require.NoError(t, err)
+	_, err = s.CreatePromotion(ctx, models.PromotionRequest{
+		Name: "Lamp 10%", Kind: models.PromotionPercentage, ProductIDs: []int64{lamp.ID}, Percent: percent("10"), StartsAt: start,
+	})
+	require.NoError(t, err)
+
+	q, err := s.QuoteBasket(ctx, models.QuoteRequest{Jurisdiction: "de", Lines: basket})
+	require.NoError(t, err)
+	// 39.98 gross holds 7/107 of it as tax, 2.6155... rounded to 2.62
+	assert.Equal(t, money.New(262, "EUR"), q.Lines[0].Tax.Tax)
+	// the tax is taken out of the discounted total, 53.55 * 19/119 = 8.55
+	assert.Equal(t, money.New(5355, "EUR"), q.Lines[1].Tax.Gross)
+	assert.Equal(t, money.New(855, "EUR"), q.Lines[1].Tax.Tax)
+	assert.Equal(t, money.New(0, "EUR"), q.Lines[2].Tax.Tax)
+	assert.Nil(t, q.Lines[2].Tax.Rate)
+
+	require.NotNil(t, q.Tax)
+	assert.Equal(t, "DE", q.Tax.Jurisdiction)
+	assert.Equal(t, money.New(1117, "EUR"), q.Tax.Tax)
+	assert.Equal(t, q.Total, q.Tax.Gross)
+	assert.Equal(t, money.New(10736, "EUR"), q.Tax.Net)
+
+	q, err = s.QuoteBasket(ctx, models.QuoteRequest{Lines: basket})
+	require.NoError(t, err)
+	assert.Nil(t, q.Tax)
+	assert.Nil(t, q.Lines[0].Tax)
+}
@@ -0,0 +1,272 @@
+package promotion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/internal/usecase/pricing"
+	"prodcrud/internal/usecase/tax"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"strings"
+)
+
+func (s *Service) QuoteBasket(ctx context.Context, req models.QuoteRequest) (*models.BasketQuote, error) {
+	if err := validBasket(req); err != nil {
+		return nil, err
+	}
+	code := ""
+	if req.Currency != "" {
+		cur, err := money.LookupCurrency(req.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBasket, err)
+		}
+		code = cur.Code
+	}
+
+	now := s.now().UTC()
+	active, err := s.promotions.GetActivePromotions(ctx, now)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get active promotions", "error", err)
+		return nil, errors.New("failed to quote basket usc")
+	}
+
+	quote := &models.BasketQuote{Currency: code, Lines: make([]*models.QuoteLine, 0, len(req.Lines))}
+	for _, item := range req.Lines {
+		unit, p, err := s.unitPrice(ctx, item.ProductID, code)
+		if err != nil {
+			return nil, err
+		}
+		if quote.Currency == "" {
+			quote.Currency = unit.Currency
+		} else if unit.Currency != quote.Currency {
+			return nil, fmt.Errorf("%w: products are priced in %s and %s, set a currency", ErrInvalidBasket, quote.Currency, unit.Currency)
+		}
+		line, err := priceLine(item, unit, p.Category, active)
+		if err != nil {
+			logging.For(ctx, logPackage).Error("failed to price basket line", "id", item.ProductID, "error", err)
+			return nil, errors.New("failed to quote basket usc")
+		}
+		if req.Jurisdiction != "" {
+			if line.Tax, err = s.taxes.Calculate(ctx, line.Total, p.TaxClass, req.Jurisdiction, now); err != nil {
+				if errors.Is(err, tax.ErrInvalidTax) {
+					return nil, fmt.Errorf("%w: %w", ErrInvalidBasket, err)
+				}
+				return nil, err //nolint:wrapcheck //the tax errors are already user facing
+			}
+		}
+		quote.Lines = append(quote.Lines, line)
+	}
+
+	quote.Subtotal = money.Money{Currency: quote.Currency}
+	quote.Discount, quote.Total = quote.Subtotal, quote.Subtotal
+	for _, line := range quote.Lines {
+		if quote.Subtotal, err = quote.Subtotal.Add(line.Subtotal); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBasket, err)
+		}
+		if quote.Discount, err = quote.Discount.Add(line.Discount); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBasket, err)
+		}
+		if quote.Total, err = quote.Total.Add(line.Total); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBasket, err)
+		}
+	}
+	if req.Jurisdiction != "" {
+		if quote.Tax, err = sumTax(quote); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBasket, err)
+		}
+	}
+	return quote, nil
+}
+
+// sumTax adds up the tax breakdowns of the lines.
+func sumTax(quote *models.BasketQuote) (*models.BasketTax, error) {
+	zero := money.Money{Currency: quote.Currency}
+	sum := &models.BasketTax{Net: zero, Tax: zero, Gross: zero}
+	var err error
+	for _, line := range quote.Lines {
+		sum.Jurisdiction = line.Tax.Jurisdiction
+		if sum.Net, err = sum.Net.Add(line.Tax.Net); err != nil {
+			return nil, err
+		}
+		if sum.Tax, err = sum.Tax.Add(line.Tax.Tax); err != nil {
+			return nil, err
+		}
+		if sum.Gross, err = sum.Gross.Add(line.Tax.Gross); err != nil {
+			return nil, err
+		}
+	}
+	return sum, nil
+}
+
+func validBasket(req models.QuoteRequest) error {
+	if len(req.Lines) == 0 {
+		return fmt.Errorf("%w: lines are required", ErrInvalidBasket)
+	}
+	if len(req.Lines) > MaxBasketLines {
+		return fmt.Errorf("%w: at most %d lines", ErrInvalidBasket, MaxBasketLines)
+	}
+	seen := make(map[int64]bool, len(req.Lines))
+	for _, item := range req.Lines {
+		if item.Quantity <= 0 {
+			return fmt.Errorf("%w: quantity of product %d must be positive", ErrInvalidBasket, item.ProductID)
+		}
+		if seen[item.ProductID] {
+			return fmt.Errorf("%w: product %d is on several lines", ErrInvalidBasket, item.ProductID)
+		}
+		seen[item.ProductID] = true
+	}
+	return nil
+}
+
+// unitPrice returns the price of one unit in the currency code, the base
+// price when it is empty, and the product.
+func (s *Service) unitPrice(ctx context.Context, productID int64, code string) (money.Money, *models.Product, error) {
+	p, err := s.products.GetProduct(ctx, productID, false)
+	if err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			return money.Money{}, nil, fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+		}
+		logging.For(ctx, logPackage).Error("failed to get product", "id", productID, "error", err)
+		return money.Money{}, nil, errors.New("failed to quote basket usc")
+	}
+	if code == "" || code == p.Price.Currency {
+		return p.Price, p, nil
+	}
+	q, err := s.pricing.Quote(ctx, productID, code, "")
+	if err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			return money.Money{}, nil, fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+		}
+		if errors.Is(err, pricing.ErrInvalidPrice) {
+			return money.Money{}, nil, fmt.Errorf("%w: %w", ErrInvalidBasket, err)
+		}
+		return money.Money{}, nil, err //nolint:wrapcheck //the rate and internal errors of pricing are already user facing
+	}
+	return q.Price, p, nil
+}
+
+// priceLine applies the promotions targeting the line, highest priority
+// first, each to what is left of the line after the previous ones. A
+// promotion that is not stackable only applies to a line without
+// discounts, and then closes it to the others.
+func priceLine(item models.QuoteLineItem, unit money.Money, category string, active []*models.Promotion) (*models.QuoteLine, error) {
+	subtotal, err := unit.Mul(int64(item.Quantity))
+	if err != nil {
+		return nil, err
+	}
+	line := &models.QuoteLine{
+		ProductID:  item.ProductID,
+		Quantity:   item.Quantity,
+		UnitPrice:  unit,
+		Subtotal:   subtotal,
+		Promotions: []models.AppliedPromotion{},
+		Trace:      []string{},
+	}
+	left := subtotal
+	closed := false
+	for _, p := range active {
+		if !p.Targets(item.ProductID, category) {
+			continue
+		}
+		label := fmt.Sprintf("promotion %d %q (%s, priority %d)", p.ID, p.Name, describe(p), p.Priority)
+		skip := func(reason string) {
+			line.Trace = append(line.Trace, label+" skipped: "+reason)
+		}
+		switch {
+		case closed:
+			skip("a promotion that does not stack was applied")
+			continue
+		case !p.Stackable && len(line.Promotions) > 0:
+			skip("does not stack with the promotions applied")
+			continue
+		}
+
+		discount, reason, err := discountOf(p, item.Quantity, unit, left)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			skip(reason)
+			continue
+		}
+		if discount.Amount > left.Amount {
+			discount = left
+		}
+		if discount.Amount == 0 {
+			skip("nothing left to discount")
+			continue
+		}
+		if left, err = left.Sub(discount); err != nil {
+			return nil, err
+		}
+		line.Promotions = append(line.Promotions, models.AppliedPromotion{
+			PromotionID: p.ID, Name: p.Name, Kind: p.Kind, Discount: discount,
+		})
+		line.Trace = append(line.Trace, fmt.Sprintf("%s applied: -%s, line total %s", label, discount, left))
+		closed = !p.Stackable
+	}
+	line.Total = left
+	if line.Discount, err = subtotal.Sub(left); err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+// discountOf returns the discount p gives a line of quantity units at unit
+// with left still to pay, or the reason it gives none.
+func discountOf(p *models.Promotion, quantity int, unit, left money.Money) (money.Money, string, error) {
+	switch p.Kind {
+	case models.PromotionPercentage:
+		d, err := left.Percent(*p.Percent)
+		return d, "", err
+	case models.PromotionFixedAmount:
+		if p.Amount.Currency != unit.Currency {
+			return money.Money{}, fmt.Sprintf("amount is in %s, the line in %s", p.Amount.Currency, unit.Currency), nil
+		}
+		d, err := p.Amount.Mul(int64(quantity))
+		return d, "", err
+	case models.PromotionBuyXGetY:
+		free := quantity / (p.Buy + p.Get) * p.Get
+		if free == 0 {
+			return money.Money{}, fmt.Sprintf("needs %d units, the line has %d", p.Buy+p.Get, quantity), nil
+		}
+		d, err := unit.Mul(int64(free))
+		return d, "", err
+	case models.PromotionTiered:
+		var tier *models.PromotionTier
+		for i := range p.Tiers {
+			if quantity >= p.Tiers[i].MinQuantity {
+				tier = &p.Tiers[i]
+			}
+		}
+		if tier == nil {
+			return money.Money{}, fmt.Sprintf("needs %d units for the first tier, the line has %d", p.Tiers[0].MinQuantity, quantity), nil
+		}
+		d, err := left.Percent(tier.Percent)
+		return d, "", err
+	default:
+		return money.Money{}, "unknown kind " + p.Kind, nil
+	}
+}
+
+// describe summarizes the rule of p for the trace.
+func describe(p *models.Promotion) string {
+	switch p.Kind {
+	case models.PromotionPercentage:
+		return p.Percent.String() + "% off"
+	case models.PromotionFixedAmount:
+		return p.Amount.String() + " off each unit"
+	case models.PromotionBuyXGetY:
+		return fmt.Sprintf("buy %d get %d free", p.Buy, p.Get)
+	case models.PromotionTiered:
+		tiers := make([]string, len(p.Tiers))
+		for i, t := range p.Tiers {
+			tiers[i] = fmt.Sprintf("%d+: %s%%", t.MinQuantity, t.Percent)
+		}
+		return "tiered " + strings.Join(tiers, ", ")
+	default:
+		return p.Kind
+	}
+}
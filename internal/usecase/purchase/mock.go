@@ -0,0 +1,101 @@
+package purchase
+
+import (
+	"context"
+	"prodcrud/internal/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// ServiceMock stands in for the service in handler tests.
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (m *ServiceMock) CreateSupplier(ctx context.Context, req models.SupplierRequest) (*models.Supplier, error) {
+	args := m.Called(ctx, req)
+	s, _ := args.Get(0).(*models.Supplier)
+	return s, args.Error(1)
+}
+
+func (m *ServiceMock) GetSupplier(ctx context.Context, id int64) (*models.Supplier, error) {
+	args := m.Called(ctx, id)
+	s, _ := args.Get(0).(*models.Supplier)
+	return s, args.Error(1)
+}
+
+func (m *ServiceMock) GetSuppliers(ctx context.Context) ([]*models.Supplier, error) {
+	args := m.Called(ctx)
+	suppliers, _ := args.Get(0).([]*models.Supplier)
+	return suppliers, args.Error(1)
+}
+
+func (m *ServiceMock) UpdateSupplier(ctx context.Context, id int64, req models.SupplierRequest) (*models.Supplier, error) {
+	args := m.Called(ctx, id, req)
+	s, _ := args.Get(0).(*models.Supplier)
+	return s, args.Error(1)
+}
+
+func (m *ServiceMock) DeleteSupplier(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *ServiceMock) SetSupplierProduct(ctx context.Context, supplierID, productID int64, req models.SupplierProductRequest) (*models.SupplierProduct, error) {
+	args := m.Called(ctx, supplierID, productID, req)
+	sp, _ := args.Get(0).(*models.SupplierProduct)
+	return sp, args.Error(1)
+}
+
+func (m *ServiceMock) GetSupplierProducts(ctx context.Context, supplierID int64) ([]*models.SupplierProduct, error) {
+	args := m.Called(ctx, supplierID)
+	links, _ := args.Get(0).([]*models.SupplierProduct)
+	return links, args.Error(1)
+}
+
+func (m *ServiceMock) DeleteSupplierProduct(ctx context.Context, supplierID, productID int64) error {
+	args := m.Called(ctx, supplierID, productID)
+	return args.Error(0)
+}
+
+func (m *ServiceMock) CreateOrder(ctx context.Context, req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	args := m.Called(ctx, req)
+	o, _ := args.Get(0).(*models.PurchaseOrder)
+	return o, args.Error(1)
+}
+
+func (m *ServiceMock) GetOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error) {
+	args := m.Called(ctx, id)
+	o, _ := args.Get(0).(*models.PurchaseOrder)
+	return o, args.Error(1)
+}
+
+func (m *ServiceMock) GetOrders(ctx context.Context, f models.PurchaseOrderFilter) ([]*models.PurchaseOrder, error) {
+	args := m.Called(ctx, f)
+	orders, _ := args.Get(0).([]*models.PurchaseOrder)
+	return orders, args.Error(1)
+}
+
+func (m *ServiceMock) UpdateOrder(ctx context.Context, id int64, req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	args := m.Called(ctx, id, req)
+	o, _ := args.Get(0).(*models.PurchaseOrder)
+	return o, args.Error(1)
+}
+
+func (m *ServiceMock) SendOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error) {
+	args := m.Called(ctx, id)
+	o, _ := args.Get(0).(*models.PurchaseOrder)
+	return o, args.Error(1)
+}
+
+func (m *ServiceMock) CancelOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error) {
+	args := m.Called(ctx, id)
+	o, _ := args.Get(0).(*models.PurchaseOrder)
+	return o, args.Error(1)
+}
+
+func (m *ServiceMock) ReceiveOrder(ctx context.Context, id int64, req models.ReceiptRequest) (*models.PurchaseOrder, error) {
+	args := m.Called(ctx, id, req)
+	o, _ := args.Get(0).(*models.PurchaseOrder)
+	return o, args.Error(1)
+}
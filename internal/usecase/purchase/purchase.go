@@ -0,0 +1,329 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/purchase"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"slices"
+	"strings"
+)
+
+type ServiceInterface interface {
+	CreateSupplier(ctx context.Context, req models.SupplierRequest) (*models.Supplier, error)
+	GetSupplier(ctx context.Context, id int64) (*models.Supplier, error)
+	GetSuppliers(ctx context.Context) ([]*models.Supplier, error)
+	// UpdateSupplier replaces a supplier; its currency cannot change once it
+	// sells products.
+	UpdateSupplier(ctx context.Context, id int64, req models.SupplierRequest) (*models.Supplier, error)
+	// DeleteSupplier deletes a supplier without purchase orders, with the
+	// products it sells.
+	DeleteSupplier(ctx context.Context, id int64) error
+	// SetSupplierProduct creates or replaces the cost and lead time of an
+	// active product at a supplier, the cost in the supplier currency.
+	SetSupplierProduct(ctx context.Context, supplierID, productID int64, req models.SupplierProductRequest) (*models.SupplierProduct, error)
+	GetSupplierProducts(ctx context.Context, supplierID int64) ([]*models.SupplierProduct, error)
+	DeleteSupplierProduct(ctx context.Context, supplierID, productID int64) error
+
+	// CreateOrder drafts an order of products the supplier sells.
+	CreateOrder(ctx context.Context, req models.PurchaseOrderRequest) (*models.PurchaseOrder, error)
+	GetOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error)
+	GetOrders(ctx context.Context, f models.PurchaseOrderFilter) ([]*models.PurchaseOrder, error)
+	// UpdateOrder replaces the notes and lines of a draft of the same supplier.
+	UpdateOrder(ctx context.Context, id int64, req models.PurchaseOrderRequest) (*models.PurchaseOrder, error)
+	// SendOrder marks a draft as sent, expected after the longest lead time
+	// of its products.
+	SendOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error)
+	// CancelOrder cancels an order until it is fully received; the units
+	// received already stay in stock.
+	CancelOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error)
+	// ReceiveOrder records a delivery and adds the units to the stock of the
+	// products, in the same transaction.
+	ReceiveOrder(ctx context.Context, id int64, req models.ReceiptRequest) (*models.PurchaseOrder, error)
+}
+
+const logPackage = "usecase/purchase"
+
+const (
+	// MaxLines is the most lines an order has.
+	MaxLines = 100
+	// MaxNotesLen is in bytes.
+	MaxNotesLen = 2000
+)
+
+var (
+	ErrSupplierNotFound        = purchase.ErrSupplierNotFound
+	ErrSupplierInUse           = purchase.ErrSupplierInUse
+	ErrSupplierProductNotFound = purchase.ErrSupplierProductNotFound
+	ErrProductNotFound         = purchase.ErrProductNotFound
+	ErrOrderNotFound           = purchase.ErrOrderNotFound
+	ErrInvalidStatus           = purchase.ErrInvalidStatus
+	ErrInvalidReceipt          = purchase.ErrInvalidReceipt
+	// ErrInvalidSupplier wraps every validation error of a supplier or of a
+	// supplier product.
+	ErrInvalidSupplier = errors.New("invalid supplier")
+	// ErrInvalidOrder wraps every validation error of a purchase order.
+	ErrInvalidOrder = errors.New("invalid purchase order")
+)
+
+type Service struct {
+	repo     purchase.Repository
+	products product.Repository
+	tx       db.Transactor
+}
+
+// NewService needs the product repository of the same store as repo, and its
+// transactor, so that receipts and stock updates commit together.
+func NewService(repo purchase.Repository, products product.Repository, tx db.Transactor) ServiceInterface {
+	return &Service{repo: repo, products: products, tx: tx}
+}
+
+func invalidOrder(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalidOrder}, args...)...)
+}
+
+// orderOf validates req into an order in the supplier currency, with the
+// cost of each line defaulting to the one of the supplier product.
+func (s *Service) orderOf(ctx context.Context, req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	o := &models.PurchaseOrder{SupplierID: req.SupplierID, Notes: strings.TrimSpace(req.Notes)}
+	if len(o.Notes) > MaxNotesLen {
+		return nil, invalidOrder("notes must have at most %d bytes", MaxNotesLen)
+	}
+	if len(req.Lines) == 0 || len(req.Lines) > MaxLines {
+		return nil, invalidOrder("an order must have 1 to %d lines", MaxLines)
+	}
+	supplier, err := s.repo.GetSupplier(ctx, req.SupplierID)
+	if err != nil {
+		if errors.Is(err, ErrSupplierNotFound) {
+			return nil, invalidOrder("supplier %d not found", req.SupplierID)
+		}
+		return nil, err
+	}
+	o.Currency = supplier.Currency
+	links, err := s.repo.GetSupplierProducts(ctx, supplier.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	total := money.New(0, o.Currency)
+	for _, l := range req.Lines {
+		i := slices.IndexFunc(links, func(sp *models.SupplierProduct) bool { return sp.ProductID == l.ProductID })
+		if i < 0 {
+			return nil, invalidOrder("the supplier does not sell product %d", l.ProductID)
+		}
+		if slices.ContainsFunc(o.Lines, func(other *models.PurchaseOrderLine) bool { return other.ProductID == l.ProductID }) {
+			return nil, invalidOrder("product %d is ordered twice", l.ProductID)
+		}
+		if l.Quantity <= 0 {
+			return nil, invalidOrder("the quantity of product %d must be positive", l.ProductID)
+		}
+		line := &models.PurchaseOrderLine{ProductID: l.ProductID, Quantity: l.Quantity, Cost: links[i].Cost}
+		if l.Cost != nil {
+			if cur, err := money.LookupCurrency(l.Cost.Currency); err != nil || cur.Code != o.Currency || !l.Cost.IsPositive() {
+				return nil, invalidOrder("the cost of product %d must be positive in %s", l.ProductID, o.Currency)
+			}
+			line.Cost = money.New(l.Cost.Amount, o.Currency)
+		}
+		if line.Cost.Currency != o.Currency {
+			return nil, invalidOrder("the supplier cost of product %d is not in %s", l.ProductID, o.Currency)
+		}
+		amount, err := line.Cost.Mul(int64(line.Quantity))
+		if err == nil {
+			total, err = total.Add(amount)
+		}
+		if err != nil {
+			return nil, invalidOrder("the total is out of range")
+		}
+		o.Lines = append(o.Lines, line)
+	}
+	for _, l := range o.Lines {
+		if _, err := s.products.GetProduct(ctx, l.ProductID, false); err != nil {
+			if errors.Is(err, product.ErrNotFound) {
+				return nil, invalidOrder("product %d not found", l.ProductID)
+			}
+			return nil, err
+		}
+	}
+	o.Total = total
+	return o, nil
+}
+
+// withTotal sets the total of o, which orderOf keeps in range.
+func withTotal(o *models.PurchaseOrder) *models.PurchaseOrder {
+	o.Total = money.New(0, o.Currency)
+	for _, l := range o.Lines {
+		amount, _ := l.Cost.Mul(int64(l.Quantity))
+		o.Total, _ = o.Total.Add(amount)
+	}
+	return o
+}
+
+func (s *Service) CreateOrder(ctx context.Context, req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	o, err := s.orderOf(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidOrder) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to validate purchase order", "error", err)
+		return nil, errors.New("failed to create purchase order usc")
+	}
+	if err := s.repo.CreateOrder(ctx, o); err != nil {
+		logging.For(ctx, logPackage).Error("failed to create purchase order", "error", err)
+		return nil, errors.New("failed to create purchase order usc")
+	}
+	logging.For(ctx, logPackage).Info("purchase order created", "id", o.ID, "supplier_id", o.SupplierID,
+		"lines", len(o.Lines), "total", o.Total.String())
+	return o, nil
+}
+
+func (s *Service) GetOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error) {
+	o, err := s.repo.GetOrder(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrOrderNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to get purchase order", "id", id, "error", err)
+		return nil, errors.New("failed to get purchase order usc")
+	}
+	return withTotal(o), nil
+}
+
+func (s *Service) GetOrders(ctx context.Context, f models.PurchaseOrderFilter) ([]*models.PurchaseOrder, error) {
+	switch f.Status {
+	case "", models.PurchaseDraft, models.PurchaseSent, models.PurchasePartiallyReceived, models.PurchaseReceived,
+		models.PurchaseCancelled:
+	default:
+		return nil, invalidOrder("unknown status %q", f.Status)
+	}
+	orders, err := s.repo.GetOrders(ctx, f)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get purchase orders", "error", err)
+		return nil, errors.New("failed to get purchase orders usc")
+	}
+	for _, o := range orders {
+		withTotal(o)
+	}
+	return orders, nil
+}
+
+func (s *Service) UpdateOrder(ctx context.Context, id int64, req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	stored, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Status != models.PurchaseDraft {
+		return nil, fmt.Errorf("%w: cannot edit a %s order", ErrInvalidStatus, stored.Status)
+	}
+	if req.SupplierID == 0 {
+		req.SupplierID = stored.SupplierID
+	}
+	if req.SupplierID != stored.SupplierID {
+		return nil, invalidOrder("the supplier of an order cannot change")
+	}
+	o, err := s.orderOf(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidOrder) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to validate purchase order", "id", id, "error", err)
+		return nil, errors.New("failed to update purchase order usc")
+	}
+	o.ID = id
+	if err := s.repo.UpdateOrder(ctx, o); err != nil {
+		if errors.Is(err, ErrOrderNotFound) || errors.Is(err, ErrInvalidStatus) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to update purchase order", "id", id, "error", err)
+		return nil, errors.New("failed to update purchase order usc")
+	}
+	logging.For(ctx, logPackage).Info("purchase order updated", "id", id, "lines", len(o.Lines))
+	return o, nil
+}
+
+func (s *Service) SendOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error) {
+	o, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	links, err := s.repo.GetSupplierProducts(ctx, o.SupplierID)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get supplier products", "id", o.SupplierID, "error", err)
+		return nil, errors.New("failed to send purchase order usc")
+	}
+	leadTime := 0
+	for _, sp := range links {
+		if slices.ContainsFunc(o.Lines, func(l *models.PurchaseOrderLine) bool { return l.ProductID == sp.ProductID }) {
+			leadTime = max(leadTime, sp.LeadTimeDays)
+		}
+	}
+	if o, err = s.repo.SendOrder(ctx, id, leadTime); err != nil {
+		if errors.Is(err, ErrOrderNotFound) || errors.Is(err, ErrInvalidStatus) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to send purchase order", "id", id, "error", err)
+		return nil, errors.New("failed to send purchase order usc")
+	}
+	logging.For(ctx, logPackage).Info("purchase order sent", "id", id, "expected_at", o.ExpectedAt)
+	return withTotal(o), nil
+}
+
+func (s *Service) CancelOrder(ctx context.Context, id int64) (*models.PurchaseOrder, error) {
+	o, err := s.repo.CancelOrder(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrOrderNotFound) || errors.Is(err, ErrInvalidStatus) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to cancel purchase order", "id", id, "error", err)
+		return nil, errors.New("failed to cancel purchase order usc")
+	}
+	logging.For(ctx, logPackage).Info("purchase order cancelled", "id", id)
+	return withTotal(o), nil
+}
+
+func (s *Service) ReceiveOrder(ctx context.Context, id int64, req models.ReceiptRequest) (*models.PurchaseOrder, error) {
+	if len(req.Lines) == 0 || len(req.Lines) > MaxLines {
+		return nil, fmt.Errorf("%w: a receipt must have 1 to %d lines", ErrInvalidReceipt, MaxLines)
+	}
+	var o *models.PurchaseOrder
+	err := s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if o, err = s.repo.ReceiveOrder(ctx, id, req.Lines); err != nil {
+			return err
+		}
+		received := make(map[int64]int)
+		for _, rl := range req.Lines {
+			received[o.Line(rl.LineID).ProductID] += rl.Quantity
+		}
+		// the stock rows are locked by product id, so concurrent receipts
+		// cannot deadlock
+		ids := make([]int64, 0, len(received))
+		for id := range received {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		for _, productID := range ids {
+			if _, err := s.products.AdjustQuantity(ctx, productID, received[productID]); err != nil {
+				if errors.Is(err, product.ErrNotFound) {
+					return fmt.Errorf("%w: product %d is deleted", ErrProductNotFound, productID)
+				}
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrOrderNotFound) || errors.Is(err, ErrInvalidStatus) || errors.Is(err, ErrInvalidReceipt) ||
+			errors.Is(err, ErrProductNotFound) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to receive purchase order", "id", id, "error", err)
+		return nil, errors.New("failed to receive purchase order usc")
+	}
+	logging.For(ctx, logPackage).Info("purchase order received", "id", id, "status", o.Status)
+	return withTotal(o), nil
+}
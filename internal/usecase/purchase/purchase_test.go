@@ -0,0 +1,207 @@
+package purchase
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/internal/repository/purchase"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/db/sqlitetest"
+	"prodcrud/pkg/money"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func eur(amount int64) *money.Money {
+	m := money.New(amount, "EUR")
+	return &m
+}
+
+// setup returns a service with a supplier selling two products, a pen at 1.00
+// with a lead time of 3 days and ink at 2.50 with 10 days.
+func setup(t *testing.T, s ServiceInterface, products product.Repository) (supplier *models.Supplier, pen, ink int64) {
+	t.Helper()
+	ctx := context.Background()
+	supplier, err := s.CreateSupplier(ctx, models.SupplierRequest{Name: " Acme ", Email: "orders@acme.example", Currency: "eur"})
+	require.NoError(t, err)
+	for _, p := range []*models.Product{
+		{Name: "pen", Price: money.New(300, "EUR"), Quantity: 2, Description: "blue"},
+		{Name: "ink", Price: money.New(700, "EUR"), Quantity: 1, Description: "black"},
+	} {
+		require.NoError(t, products.CreateProduct(ctx, p))
+	}
+	pen, ink = 1, 2
+	_, err = s.SetSupplierProduct(ctx, supplier.ID, pen, models.SupplierProductRequest{Cost: *eur(100), LeadTimeDays: 3})
+	require.NoError(t, err)
+	_, err = s.SetSupplierProduct(ctx, supplier.ID, ink, models.SupplierProductRequest{SKU: "INK-1", Cost: *eur(250), LeadTimeDays: 10})
+	require.NoError(t, err)
+	return supplier, pen, ink
+}
+
+func TestService_Suppliers(t *testing.T) {
+	ctx := context.Background()
+	products := product.NewMemoryRepo()
+	s := NewService(purchase.NewMemoryRepo(), products, new(db.MemoryTx))
+	supplier, pen, _ := setup(t, s, products)
+	assert.Equal(t, "Acme", supplier.Name)
+	assert.Equal(t, "EUR", supplier.Currency)
+
+	for name, req := range map[string]models.SupplierRequest{
+		"no name":          {Currency: "EUR"},
+		"bad email":        {Name: "Acme", Email: "acme", Currency: "EUR"},
+		"unknown currency": {Name: "Acme", Currency: "XXX"},
+	} {
+		_, err := s.CreateSupplier(ctx, req)
+		assert.ErrorIs(t, err, ErrInvalidSupplier, name)
+	}
+	_, err := s.UpdateSupplier(ctx, supplier.ID, models.SupplierRequest{Name: "Acme", Currency: "USD"})
+	assert.ErrorIs(t, err, ErrInvalidSupplier, "the currency is fixed once it sells products")
+	updated, err := s.UpdateSupplier(ctx, supplier.ID, models.SupplierRequest{Name: "Acme Wholesale", Currency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Wholesale", updated.Name)
+
+	for name, req := range map[string]models.SupplierProductRequest{
+		"no cost":        {},
+		"other currency": {Cost: money.New(100, "USD")},
+		"long lead time": {Cost: *eur(100), LeadTimeDays: MaxLeadTimeDays + 1},
+	} {
+		_, err := s.SetSupplierProduct(ctx, supplier.ID, pen, req)
+		assert.ErrorIs(t, err, ErrInvalidSupplier, name)
+	}
+	_, err = s.SetSupplierProduct(ctx, supplier.ID, 99, models.SupplierProductRequest{Cost: *eur(100)})
+	assert.ErrorIs(t, err, ErrProductNotFound)
+	_, err = s.SetSupplierProduct(ctx, 99, pen, models.SupplierProductRequest{Cost: *eur(100)})
+	assert.ErrorIs(t, err, ErrSupplierNotFound)
+
+	links, err := s.GetSupplierProducts(ctx, supplier.ID)
+	require.NoError(t, err)
+	assert.Len(t, links, 2)
+	_, err = s.GetSupplierProducts(ctx, 99)
+	assert.ErrorIs(t, err, ErrSupplierNotFound)
+}
+
+func TestService_Orders(t *testing.T) {
+	ctx := context.Background()
+	products := product.NewMemoryRepo()
+	s := NewService(purchase.NewMemoryRepo(), products, new(db.MemoryTx))
+	supplier, pen, ink := setup(t, s, products)
+
+	o, err := s.CreateOrder(ctx, models.PurchaseOrderRequest{SupplierID: supplier.ID, Lines: []models.PurchaseOrderLineRequest{
+		{ProductID: pen, Quantity: 10},
+		{ProductID: ink, Quantity: 4, Cost: eur(200)},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, models.PurchaseDraft, o.Status)
+	assert.Equal(t, "EUR", o.Currency)
+	assert.Equal(t, money.New(100, "EUR"), o.Lines[0].Cost, "the cost defaults to the supplier one")
+	assert.Equal(t, money.New(1800, "EUR"), o.Total)
+
+	for name, req := range map[string]models.PurchaseOrderRequest{
+		"no lines":         {SupplierID: supplier.ID},
+		"unknown supplier": {SupplierID: 99, Lines: []models.PurchaseOrderLineRequest{{ProductID: pen, Quantity: 1}}},
+		"not sold":         {SupplierID: supplier.ID, Lines: []models.PurchaseOrderLineRequest{{ProductID: 99, Quantity: 1}}},
+		"twice":            {SupplierID: supplier.ID, Lines: []models.PurchaseOrderLineRequest{{ProductID: pen, Quantity: 1}, {ProductID: pen, Quantity: 2}}},
+		"no quantity":      {SupplierID: supplier.ID, Lines: []models.PurchaseOrderLineRequest{{ProductID: pen}}},
+		"other currency":   {SupplierID: supplier.ID, Lines: []models.PurchaseOrderLineRequest{{ProductID: pen, Quantity: 1, Cost: &money.Money{Amount: 1, Currency: "USD"}}}},
+	} {
+		_, err := s.CreateOrder(ctx, req)
+		assert.ErrorIs(t, err, ErrInvalidOrder, name)
+	}
+
+	o, err = s.UpdateOrder(ctx, o.ID, models.PurchaseOrderRequest{Notes: "urgent", Lines: []models.PurchaseOrderLineRequest{
+		{ProductID: pen, Quantity: 10},
+		{ProductID: ink, Quantity: 4},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, money.New(2000, "EUR"), o.Total)
+	penLine, inkLine := o.Lines[0].ID, o.Lines[1].ID
+
+	_, err = s.ReceiveOrder(ctx, o.ID, models.ReceiptRequest{Lines: []models.ReceiptLine{{LineID: penLine, Quantity: 1}}})
+	assert.ErrorIs(t, err, ErrInvalidStatus, "a draft is not received")
+
+	o, err = s.SendOrder(ctx, o.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PurchaseSent, o.Status)
+	assert.Equal(t, o.SentAt.AddDate(0, 0, 10), *o.ExpectedAt, "the longest lead time")
+	_, err = s.UpdateOrder(ctx, o.ID, models.PurchaseOrderRequest{Lines: []models.PurchaseOrderLineRequest{{ProductID: pen, Quantity: 1}}})
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+
+	o, err = s.ReceiveOrder(ctx, o.ID, models.ReceiptRequest{Lines: []models.ReceiptLine{{LineID: penLine, Quantity: 6}}})
+	require.NoError(t, err)
+	assert.Equal(t, models.PurchasePartiallyReceived, o.Status)
+	o, err = s.ReceiveOrder(ctx, o.ID, models.ReceiptRequest{Lines: []models.ReceiptLine{
+		{LineID: penLine, Quantity: 4}, {LineID: inkLine, Quantity: 4},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, models.PurchaseReceived, o.Status)
+	assert.Equal(t, money.New(2000, "EUR"), o.Total)
+
+	stock := func(id int64) int {
+		p, err := products.GetProduct(ctx, id, false)
+		require.NoError(t, err)
+		return p.Quantity
+	}
+	assert.Equal(t, 12, stock(pen))
+	assert.Equal(t, 5, stock(ink))
+
+	_, err = s.ReceiveOrder(ctx, o.ID, models.ReceiptRequest{})
+	assert.ErrorIs(t, err, ErrInvalidReceipt)
+	_, err = s.CancelOrder(ctx, o.ID)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+	_, err = s.GetOrders(ctx, models.PurchaseOrderFilter{Status: "lost"})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+	received, err := s.GetOrders(ctx, models.PurchaseOrderFilter{Status: models.PurchaseReceived})
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	assert.Equal(t, money.New(2000, "EUR"), received[0].Total)
+	assert.ErrorIs(t, s.DeleteSupplier(ctx, supplier.ID), ErrSupplierInUse)
+}
+
+// stores returns a service on each store, with its product repository.
+func stores(t *testing.T) map[string]func(t *testing.T) (ServiceInterface, product.Repository) {
+	return map[string]func(t *testing.T) (ServiceInterface, product.Repository){
+		"memory": func(t *testing.T) (ServiceInterface, product.Repository) {
+			repo := purchase.NewMemoryRepo()
+			products := product.NewMemoryRepo(repo)
+			return NewService(repo, products, new(db.MemoryTx)), products
+		},
+		"sqlite": func(t *testing.T) (ServiceInterface, product.Repository) {
+			conn := sqlitetest.New(t)
+			products := product.NewSQLiteRepo(conn)
+			return NewService(purchase.NewSQLiteRepo(conn), products, conn), products
+		},
+	}
+}
+
+func TestService_ReceiveOrder_rollsBack(t *testing.T) {
+	for name, open := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, products := open(t)
+			supplier, pen, ink := setup(t, s, products)
+
+			o, err := s.CreateOrder(ctx, models.PurchaseOrderRequest{SupplierID: supplier.ID, Lines: []models.PurchaseOrderLineRequest{
+				{ProductID: pen, Quantity: 10}, {ProductID: ink, Quantity: 4},
+			}})
+			require.NoError(t, err)
+			_, err = s.SendOrder(ctx, o.ID)
+			require.NoError(t, err)
+			require.NoError(t, products.DeleteProduct(ctx, ink))
+
+			_, err = s.ReceiveOrder(ctx, o.ID, models.ReceiptRequest{Lines: []models.ReceiptLine{
+				{LineID: o.Lines[0].ID, Quantity: 10}, {LineID: o.Lines[1].ID, Quantity: 4},
+			}})
+			assert.ErrorIs(t, err, ErrProductNotFound)
+
+			got, err := s.GetOrder(ctx, o.ID)
+			require.NoError(t, err)
+			assert.Equal(t, models.PurchaseSent, got.Status, "the receipt is rolled back")
+			assert.Zero(t, got.Lines[0].Received)
+			p, err := products.GetProduct(ctx, pen, false)
+			require.NoError(t, err)
+			assert.Equal(t, 2, p.Quantity, "so is the stock of the pen")
+		})
+	}
+}
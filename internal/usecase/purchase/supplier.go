@@ -0,0 +1,189 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"strings"
+)
+
+const (
+	// MaxNameLen, MaxEmailLen and MaxSKULen are in bytes.
+	MaxNameLen  = 255
+	MaxEmailLen = 255
+	MaxSKULen   = 64
+	// MaxLeadTimeDays is the longest lead time of a supplier product.
+	MaxLeadTimeDays = 365
+)
+
+func invalidSupplier(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalidSupplier}, args...)...)
+}
+
+// supplierOf validates req into a supplier.
+func supplierOf(req models.SupplierRequest) (*models.Supplier, error) {
+	s := &models.Supplier{Name: strings.TrimSpace(req.Name), Email: strings.TrimSpace(req.Email)}
+	if s.Name == "" || len(s.Name) > MaxNameLen {
+		return nil, invalidSupplier("name must have 1 to %d bytes", MaxNameLen)
+	}
+	if s.Email != "" {
+		if _, err := mail.ParseAddress(s.Email); err != nil || len(s.Email) > MaxEmailLen {
+			return nil, invalidSupplier("email must be an address of at most %d bytes", MaxEmailLen)
+		}
+	}
+	cur, err := money.LookupCurrency(req.Currency)
+	if err != nil {
+		return nil, invalidSupplier("%s", err)
+	}
+	s.Currency = cur.Code
+	return s, nil
+}
+
+func (s *Service) CreateSupplier(ctx context.Context, req models.SupplierRequest) (*models.Supplier, error) {
+	supplier, err := supplierOf(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateSupplier(ctx, supplier); err != nil {
+		logging.For(ctx, logPackage).Error("failed to create supplier", "error", err)
+		return nil, errors.New("failed to create supplier usc")
+	}
+	logging.For(ctx, logPackage).Info("supplier created", "id", supplier.ID, "currency", supplier.Currency)
+	return supplier, nil
+}
+
+func (s *Service) GetSupplier(ctx context.Context, id int64) (*models.Supplier, error) {
+	supplier, err := s.repo.GetSupplier(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrSupplierNotFound) {
+			return nil, ErrSupplierNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to get supplier", "id", id, "error", err)
+		return nil, errors.New("failed to get supplier usc")
+	}
+	return supplier, nil
+}
+
+func (s *Service) GetSuppliers(ctx context.Context) ([]*models.Supplier, error) {
+	suppliers, err := s.repo.GetSuppliers(ctx)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get suppliers", "error", err)
+		return nil, errors.New("failed to get suppliers usc")
+	}
+	return suppliers, nil
+}
+
+func (s *Service) UpdateSupplier(ctx context.Context, id int64, req models.SupplierRequest) (*models.Supplier, error) {
+	supplier, err := supplierOf(req)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.GetSupplier(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Currency != supplier.Currency {
+		links, err := s.repo.GetSupplierProducts(ctx, id)
+		if err != nil {
+			logging.For(ctx, logPackage).Error("failed to get supplier products", "id", id, "error", err)
+			return nil, errors.New("failed to update supplier usc")
+		}
+		if len(links) > 0 {
+			return nil, invalidSupplier("the currency of a supplier with products cannot change")
+		}
+	}
+	supplier.ID = id
+	if err := s.repo.UpdateSupplier(ctx, supplier); err != nil {
+		if errors.Is(err, ErrSupplierNotFound) {
+			return nil, ErrSupplierNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to update supplier", "id", id, "error", err)
+		return nil, errors.New("failed to update supplier usc")
+	}
+	logging.For(ctx, logPackage).Info("supplier updated", "id", id)
+	return supplier, nil
+}
+
+func (s *Service) DeleteSupplier(ctx context.Context, id int64) error {
+	if err := s.repo.DeleteSupplier(ctx, id); err != nil {
+		if errors.Is(err, ErrSupplierNotFound) || errors.Is(err, ErrSupplierInUse) {
+			return err
+		}
+		logging.For(ctx, logPackage).Error("failed to delete supplier", "id", id, "error", err)
+		return errors.New("failed to delete supplier usc")
+	}
+	logging.For(ctx, logPackage).Info("supplier deleted", "id", id)
+	return nil
+}
+
+func (s *Service) SetSupplierProduct(ctx context.Context, supplierID, productID int64, req models.SupplierProductRequest) (*models.SupplierProduct, error) {
+	sp := &models.SupplierProduct{
+		SupplierID: supplierID, ProductID: productID, SKU: strings.TrimSpace(req.SKU), Cost: req.Cost,
+		LeadTimeDays: req.LeadTimeDays,
+	}
+	if len(sp.SKU) > MaxSKULen {
+		return nil, invalidSupplier("sku must have at most %d bytes", MaxSKULen)
+	}
+	if !sp.Cost.IsPositive() {
+		return nil, invalidSupplier("cost must be positive")
+	}
+	if sp.LeadTimeDays < 0 || sp.LeadTimeDays > MaxLeadTimeDays {
+		return nil, invalidSupplier("lead time must be 0 to %d days", MaxLeadTimeDays)
+	}
+	supplier, err := s.GetSupplier(ctx, supplierID)
+	if err != nil {
+		return nil, err
+	}
+	if cur, err := money.LookupCurrency(sp.Cost.Currency); err != nil || cur.Code != supplier.Currency {
+		return nil, invalidSupplier("cost must be in %s, the currency of the supplier", supplier.Currency)
+	}
+	sp.Cost.Currency = supplier.Currency
+	if _, err := s.products.GetProduct(ctx, productID, false); err != nil {
+		if errors.Is(err, product.ErrNotFound) {
+			return nil, ErrProductNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to get product", "id", productID, "error", err)
+		return nil, errors.New("failed to set supplier product usc")
+	}
+	if err := s.repo.SetSupplierProduct(ctx, sp); err != nil {
+		if errors.Is(err, ErrSupplierNotFound) || errors.Is(err, ErrProductNotFound) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to set supplier product", "supplier_id", supplierID,
+			"product_id", productID, "error", err)
+		return nil, errors.New("failed to set supplier product usc")
+	}
+	logging.For(ctx, logPackage).Info("supplier product set", "supplier_id", supplierID, "product_id", productID,
+		"cost", sp.Cost.String(), "lead_time_days", sp.LeadTimeDays)
+	return sp, nil
+}
+
+func (s *Service) GetSupplierProducts(ctx context.Context, supplierID int64) ([]*models.SupplierProduct, error) {
+	if _, err := s.GetSupplier(ctx, supplierID); err != nil {
+		return nil, err
+	}
+	links, err := s.repo.GetSupplierProducts(ctx, supplierID)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get supplier products", "id", supplierID, "error", err)
+		return nil, errors.New("failed to get supplier products usc")
+	}
+	return links, nil
+}
+
+func (s *Service) DeleteSupplierProduct(ctx context.Context, supplierID, productID int64) error {
+	if err := s.repo.DeleteSupplierProduct(ctx, supplierID, productID); err != nil {
+		if errors.Is(err, ErrSupplierProductNotFound) {
+			return ErrSupplierProductNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to delete supplier product", "supplier_id", supplierID,
+			"product_id", productID, "error", err)
+		return errors.New("failed to delete supplier product usc")
+	}
+	logging.For(ctx, logPackage).Info("supplier product deleted", "supplier_id", supplierID, "product_id", productID)
+	return nil
+}
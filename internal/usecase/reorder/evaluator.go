@@ -0,0 +1,192 @@
+package reorder
+
+import (
+	"context"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/reorder"
+	"prodcrud/internal/usecase/purchase"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/notify"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Evaluation is the outcome of one pass of the evaluator.
+type Evaluation struct {
+	// Flagged lists the products whose alert was raised.
+	Flagged []int64
+	// Resolved lists the products whose alert was cleared.
+	Resolved []int64
+	// Drafted lists the purchase orders drafted for the flagged products.
+	Drafted []int64
+	// Notified lists the products whose alert was delivered.
+	Notified []int64
+}
+
+// Evaluator raises an alert when a product falls to its reorder point and
+// clears it once the product is restocked above it. The alerts are sent to
+// the notifier until it accepts them, so a failed delivery is retried on the
+// next pass. With autoDraft, the products of the new alerts are ordered
+// from the supplier of their rule; a draft that fails is logged and not
+// retried.
+type Evaluator struct {
+	repo      reorder.Repository
+	purchases purchase.ServiceInterface
+	notifier  notify.Notifier
+	interval  time.Duration
+	autoDraft bool
+}
+
+func NewEvaluator(repo reorder.Repository, purchases purchase.ServiceInterface, notifier notify.Notifier,
+	interval time.Duration, autoDraft bool) *Evaluator {
+	return &Evaluator{repo: repo, purchases: purchases, notifier: notifier, interval: interval, autoDraft: autoDraft}
+}
+
+func (e *Evaluator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := e.Evaluate(ctx); err != nil {
+			logging.For(ctx, logPackage).Error("failed to evaluate stock alerts", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Evaluate runs one pass.
+func (e *Evaluator) Evaluate(ctx context.Context) (*Evaluation, error) {
+	// the low stock list must see the alerts just flagged, read it from the primary
+	ctx = db.WithPrimary(ctx)
+	logger := logging.For(ctx, logPackage)
+	result := &Evaluation{}
+	var err error
+	if result.Resolved, err = e.repo.ResolveAlerts(ctx); err != nil {
+		return result, err //nolint:wrapcheck //already wrapped by the repository
+	}
+	for _, id := range result.Resolved {
+		logger.Info("stock alert resolved", "product_id", id)
+	}
+	if result.Flagged, err = e.repo.FlagAlerts(ctx); err != nil {
+		return result, err //nolint:wrapcheck //already wrapped by the repository
+	}
+	low, err := e.repo.GetLowStock(ctx)
+	if err != nil {
+		return result, err //nolint:wrapcheck //already wrapped by the repository
+	}
+	var flagged, pending []*models.LowStockProduct
+	for _, p := range low {
+		if slices.Contains(result.Flagged, p.ProductID) {
+			logger.Info("stock alert raised", "product_id", p.ProductID, "quantity", p.Quantity,
+				"reorder_point", p.ReorderPoint)
+			flagged = append(flagged, p)
+		}
+		if p.FlaggedAt != nil && p.NotifiedAt == nil {
+			pending = append(pending, p)
+		}
+	}
+	if e.autoDraft {
+		result.Drafted = e.draft(ctx, flagged)
+	}
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	if err := e.notifier.Notify(ctx, alertMessage(pending)); err != nil {
+		return result, fmt.Errorf("failed to notify stock alerts: %w", err)
+	}
+	ids := make([]int64, len(pending))
+	for i, p := range pending {
+		ids[i] = p.ProductID
+	}
+	if err := e.repo.MarkNotified(ctx, ids); err != nil {
+		return result, err //nolint:wrapcheck //already wrapped by the repository
+	}
+	result.Notified = ids
+	logger.Info("stock alerts notified", "products", len(ids))
+	return result, nil
+}
+
+// draft orders the reorder quantity of the products from the supplier of
+// their rule, one draft per supplier and MaxLines products, records the
+// drafts on the alerts and returns their ids.
+func (e *Evaluator) draft(ctx context.Context, products []*models.LowStockProduct) []int64 {
+	logger := logging.For(ctx, logPackage)
+	bySupplier := make(map[int64][]*models.LowStockProduct)
+	var suppliers []int64
+	for _, p := range products {
+		if p.SupplierID == nil {
+			logger.Info("no supplier to draft a purchase order from", "product_id", p.ProductID)
+			continue
+		}
+		if _, ok := bySupplier[*p.SupplierID]; !ok {
+			suppliers = append(suppliers, *p.SupplierID)
+		}
+		bySupplier[*p.SupplierID] = append(bySupplier[*p.SupplierID], p)
+	}
+	slices.Sort(suppliers)
+
+	var drafted []int64
+	for _, supplierID := range suppliers {
+		for group := range slices.Chunk(bySupplier[supplierID], purchase.MaxLines) {
+			if id, ok := e.draftOrder(ctx, supplierID, group); ok {
+				drafted = append(drafted, id)
+			}
+		}
+	}
+	return drafted
+}
+
+// draftOrder drafts one purchase order of the products from the supplier.
+func (e *Evaluator) draftOrder(ctx context.Context, supplierID int64, products []*models.LowStockProduct) (int64, bool) {
+	logger := logging.For(ctx, logPackage)
+	req := models.PurchaseOrderRequest{SupplierID: supplierID}
+	ids := make([]string, len(products))
+	for i, p := range products {
+		ids[i] = fmt.Sprintf("#%d", p.ProductID)
+		req.Lines = append(req.Lines, models.PurchaseOrderLineRequest{ProductID: p.ProductID, Quantity: p.ReorderQuantity})
+	}
+	req.Notes = "Drafted for the low stock alerts of products " + strings.Join(ids, ", ")
+	o, err := e.purchases.CreateOrder(ctx, req)
+	if err != nil {
+		logger.Error("failed to draft purchase order", "supplier_id", supplierID, "error", err)
+		return 0, false
+	}
+	for _, p := range products {
+		if err := e.repo.SetAlertOrder(ctx, p.ProductID, o.ID); err != nil {
+			logger.Error("failed to record drafted purchase order", "product_id", p.ProductID, "id", o.ID, "error", err)
+			continue
+		}
+		p.PurchaseOrderID = &o.ID
+	}
+	logger.Info("purchase order drafted", "id", o.ID, "supplier_id", supplierID, "lines", len(o.Lines))
+	return o.ID, true
+}
+
+// alertMessage lists the products of the alerts, with the product data for
+// webhooks.
+func alertMessage(products []*models.LowStockProduct) notify.Message {
+	m := notify.Message{Data: products}
+	if len(products) == 1 {
+		m.Subject = "1 product at or below its reorder point"
+	} else {
+		m.Subject = fmt.Sprintf("%d products at or below their reorder point", len(products))
+	}
+	var b strings.Builder
+	for _, p := range products {
+		fmt.Fprintf(&b, "%s (#%d): %d in stock, reorder point %d, reorder quantity %d", p.Name, p.ProductID,
+			p.Quantity, p.ReorderPoint, p.ReorderQuantity)
+		if p.PurchaseOrderID != nil {
+			fmt.Fprintf(&b, ", drafted in purchase order #%d", *p.PurchaseOrderID)
+		}
+		b.WriteString("\n")
+	}
+	m.Text = b.String()
+	return m
+}
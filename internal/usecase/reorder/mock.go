@@ -0,0 +1,36 @@
+package reorder
+
+import (
+	"context"
+	"prodcrud/internal/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// ServiceMock stands in for the service in handler tests.
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (m *ServiceMock) GetRule(ctx context.Context, productID int64) (*models.ReorderRule, error) {
+	args := m.Called(ctx, productID)
+	r, _ := args.Get(0).(*models.ReorderRule)
+	return r, args.Error(1)
+}
+
+func (m *ServiceMock) SetRule(ctx context.Context, productID int64, req models.ReorderRuleRequest) (*models.ReorderRule, error) {
+	args := m.Called(ctx, productID, req)
+	r, _ := args.Get(0).(*models.ReorderRule)
+	return r, args.Error(1)
+}
+
+func (m *ServiceMock) DeleteRule(ctx context.Context, productID int64) error {
+	args := m.Called(ctx, productID)
+	return args.Error(0)
+}
+
+func (m *ServiceMock) GetLowStock(ctx context.Context) ([]*models.LowStockProduct, error) {
+	args := m.Called(ctx)
+	products, _ := args.Get(0).([]*models.LowStockProduct)
+	return products, args.Error(1)
+}
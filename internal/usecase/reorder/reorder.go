@@ -0,0 +1,119 @@
+package reorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/reorder"
+	"prodcrud/internal/usecase/purchase"
+	"prodcrud/pkg/logging"
+	"slices"
+)
+
+type ServiceInterface interface {
+	GetRule(ctx context.Context, productID int64) (*models.ReorderRule, error)
+	// SetRule creates or replaces the reorder rule of an active product. Its
+	// supplier, if any, must sell the product.
+	SetRule(ctx context.Context, productID int64, req models.ReorderRuleRequest) (*models.ReorderRule, error)
+	DeleteRule(ctx context.Context, productID int64) error
+	// GetLowStock lists the active products at or below their reorder point,
+	// whether or not the evaluator has raised their alert yet.
+	GetLowStock(ctx context.Context) ([]*models.LowStockProduct, error)
+}
+
+const logPackage = "usecase/reorder"
+
+var (
+	ErrRuleNotFound    = reorder.ErrRuleNotFound
+	ErrProductNotFound = reorder.ErrProductNotFound
+	// ErrInvalidRule wraps every validation error of a reorder rule.
+	ErrInvalidRule = errors.New("invalid reorder rule")
+)
+
+type Service struct {
+	repo      reorder.Repository
+	purchases purchase.ServiceInterface
+}
+
+// NewService checks the suppliers of the rules with purchases.
+func NewService(repo reorder.Repository, purchases purchase.ServiceInterface) ServiceInterface {
+	return &Service{repo: repo, purchases: purchases}
+}
+
+func invalidRule(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalidRule}, args...)...)
+}
+
+func (s *Service) GetRule(ctx context.Context, productID int64) (*models.ReorderRule, error) {
+	rule, err := s.repo.GetRule(ctx, productID)
+	if err != nil {
+		if errors.Is(err, ErrRuleNotFound) {
+			return nil, ErrRuleNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to get reorder rule", "product_id", productID, "error", err)
+		return nil, errors.New("failed to get reorder rule usc")
+	}
+	return rule, nil
+}
+
+func (s *Service) SetRule(ctx context.Context, productID int64, req models.ReorderRuleRequest) (*models.ReorderRule, error) {
+	if req.ReorderPoint < 0 || req.ReorderPoint > math.MaxInt32 {
+		return nil, invalidRule("the reorder point must be between 0 and %d", math.MaxInt32)
+	}
+	if req.ReorderQuantity <= 0 || req.ReorderQuantity > math.MaxInt32 {
+		return nil, invalidRule("the reorder quantity must be between 1 and %d", math.MaxInt32)
+	}
+	if req.SupplierID != nil {
+		links, err := s.purchases.GetSupplierProducts(ctx, *req.SupplierID)
+		switch {
+		case errors.Is(err, purchase.ErrSupplierNotFound):
+			return nil, invalidRule("supplier %d not found", *req.SupplierID)
+		case err != nil:
+			return nil, errors.New("failed to set reorder rule usc")
+		}
+		if !slices.ContainsFunc(links, func(sp *models.SupplierProduct) bool { return sp.ProductID == productID }) {
+			return nil, invalidRule("supplier %d does not sell product %d", *req.SupplierID, productID)
+		}
+	}
+
+	rule := &models.ReorderRule{
+		ProductID: productID, ReorderPoint: req.ReorderPoint, ReorderQuantity: req.ReorderQuantity,
+		SupplierID: req.SupplierID,
+	}
+	if err := s.repo.SetRule(ctx, rule); err != nil {
+		switch {
+		case errors.Is(err, ErrProductNotFound):
+			return nil, ErrProductNotFound
+		case errors.Is(err, reorder.ErrSupplierNotFound):
+			return nil, invalidRule("supplier %d not found", *req.SupplierID)
+		}
+		logging.For(ctx, logPackage).Error("failed to set reorder rule", "product_id", productID, "error", err)
+		return nil, errors.New("failed to set reorder rule usc")
+	}
+	logging.For(ctx, logPackage).Info("reorder rule set", "product_id", productID, "reorder_point", rule.ReorderPoint,
+		"reorder_quantity", rule.ReorderQuantity)
+	return rule, nil
+}
+
+func (s *Service) DeleteRule(ctx context.Context, productID int64) error {
+	if err := s.repo.DeleteRule(ctx, productID); err != nil {
+		if errors.Is(err, ErrRuleNotFound) {
+			return ErrRuleNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to delete reorder rule", "product_id", productID, "error", err)
+		return errors.New("failed to delete reorder rule usc")
+	}
+	logging.For(ctx, logPackage).Info("reorder rule deleted", "product_id", productID)
+	return nil
+}
+
+func (s *Service) GetLowStock(ctx context.Context) ([]*models.LowStockProduct, error) {
+	products, err := s.repo.GetLowStock(ctx)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get low stock products", "error", err)
+		return nil, errors.New("failed to get low stock products usc")
+	}
+	return products, nil
+}
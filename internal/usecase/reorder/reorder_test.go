@@ -0,0 +1,181 @@
+package reorder
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/mail"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/product"
+	purchaseRepo "prodcrud/internal/repository/purchase"
+	"prodcrud/internal/repository/reorder"
+	"prodcrud/internal/usecase/purchase"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/money"
+	"prodcrud/pkg/notify"
+	"prodcrud/pkg/notify/smtptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setup stores a pen with 2 in stock and ink with 1, and a supplier selling
+// the pen at 1.00.
+func setup(t *testing.T) (repo reorder.Repository, products product.Repository, purchases purchase.ServiceInterface,
+	supplierID int64) {
+	t.Helper()
+	ctx := context.Background()
+	products = product.NewMemoryRepo()
+	for _, p := range []*models.Product{
+		{Name: "pen", Price: money.New(300, "EUR"), Quantity: 2, Description: "blue"},
+		{Name: "ink", Price: money.New(700, "EUR"), Quantity: 1, Description: "black"},
+	} {
+		require.NoError(t, products.CreateProduct(ctx, p))
+	}
+	purchases = purchase.NewService(purchaseRepo.NewMemoryRepo(), products, new(db.MemoryTx))
+	supplier, err := purchases.CreateSupplier(ctx, models.SupplierRequest{Name: "Acme", Currency: "EUR"})
+	require.NoError(t, err)
+	_, err = purchases.SetSupplierProduct(ctx, supplier.ID, 1, models.SupplierProductRequest{
+		Cost: money.New(100, "EUR"), LeadTimeDays: 3,
+	})
+	require.NoError(t, err)
+	return reorder.NewMemoryRepo(products), products, purchases, supplier.ID
+}
+
+func TestService_Rules(t *testing.T) {
+	ctx := context.Background()
+	repo, _, purchases, supplierID := setup(t)
+	s := NewService(repo, purchases)
+	const pen, ink = 1, 2
+
+	rule, err := s.SetRule(ctx, pen, models.ReorderRuleRequest{ReorderPoint: 5, ReorderQuantity: 50, SupplierID: &supplierID})
+	require.NoError(t, err)
+	assert.Equal(t, 5, rule.ReorderPoint)
+	got, err := s.GetRule(ctx, pen)
+	require.NoError(t, err)
+	assert.Equal(t, rule, got)
+	_, err = s.SetRule(ctx, ink, models.ReorderRuleRequest{ReorderPoint: 0, ReorderQuantity: 10})
+	require.NoError(t, err, "a point of zero flags the product once it is out of stock")
+
+	unknown := int64(9)
+	for name, req := range map[string]models.ReorderRuleRequest{
+		"negative point":   {ReorderPoint: -1, ReorderQuantity: 10},
+		"no quantity":      {ReorderPoint: 1},
+		"unknown supplier": {ReorderPoint: 1, ReorderQuantity: 10, SupplierID: &unknown},
+		"not sold":         {ReorderPoint: 1, ReorderQuantity: 10, SupplierID: &supplierID},
+	} {
+		_, err := s.SetRule(ctx, ink, req)
+		assert.ErrorIs(t, err, ErrInvalidRule, name)
+	}
+	_, err = s.SetRule(ctx, 99, models.ReorderRuleRequest{ReorderQuantity: 1})
+	assert.ErrorIs(t, err, ErrProductNotFound)
+
+	low, err := s.GetLowStock(ctx)
+	require.NoError(t, err)
+	require.Len(t, low, 1)
+	assert.Equal(t, "pen", low[0].Name)
+	assert.Nil(t, low[0].FlaggedAt, "no alert before the evaluator runs")
+
+	require.NoError(t, s.DeleteRule(ctx, pen))
+	assert.ErrorIs(t, s.DeleteRule(ctx, pen), ErrRuleNotFound)
+	_, err = s.GetRule(ctx, pen)
+	assert.ErrorIs(t, err, ErrRuleNotFound)
+}
+
+// recorder keeps the messages it is sent, and fails while err is set.
+type recorder struct {
+	messages []notify.Message
+	err      error
+}
+
+func (r *recorder) Notify(_ context.Context, m notify.Message) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.messages = append(r.messages, m)
+	return nil
+}
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	ctx := context.Background()
+	repo, products, purchases, supplierID := setup(t)
+	const pen, ink = 1, 2
+	for _, rule := range []*models.ReorderRule{
+		{ProductID: pen, ReorderPoint: 5, ReorderQuantity: 50, SupplierID: &supplierID},
+		{ProductID: ink, ReorderPoint: 1, ReorderQuantity: 10},
+	} {
+		require.NoError(t, repo.SetRule(ctx, rule))
+	}
+	notifier := &recorder{}
+	e := NewEvaluator(repo, purchases, notifier, 0, true)
+
+	res, err := e.Evaluate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &Evaluation{Flagged: []int64{pen, ink}, Drafted: []int64{1}, Notified: []int64{pen, ink}}, res)
+	require.Len(t, notifier.messages, 1)
+	assert.Equal(t, "2 products at or below their reorder point", notifier.messages[0].Subject)
+	assert.Equal(t, "pen (#1): 2 in stock, reorder point 5, reorder quantity 50, drafted in purchase order #1\n"+
+		"ink (#2): 1 in stock, reorder point 1, reorder quantity 10\n", notifier.messages[0].Text)
+	draft, err := purchases.GetOrder(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.PurchaseDraft, draft.Status)
+	assert.Equal(t, "Drafted for the low stock alerts of products #1", draft.Notes)
+	require.Len(t, draft.Lines, 1)
+	assert.Equal(t, 50, draft.Lines[0].Quantity)
+	assert.Equal(t, money.New(100, "EUR"), draft.Lines[0].Cost)
+
+	res, err = e.Evaluate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &Evaluation{}, res, "an alert is notified once")
+	assert.Len(t, notifier.messages, 1)
+
+	_, err = products.AdjustQuantity(ctx, ink, 5)
+	require.NoError(t, err)
+	res, err = e.Evaluate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ink}, res.Resolved)
+
+	_, err = products.AdjustQuantity(ctx, ink, -6)
+	require.NoError(t, err)
+	notifier.err = errors.New("connection refused")
+	res, err = e.Evaluate(ctx)
+	assert.ErrorIs(t, err, notifier.err)
+	assert.Equal(t, []int64{ink}, res.Flagged)
+	assert.Empty(t, res.Drafted, "ink has no supplier")
+	notifier.err = nil
+	res, err = e.Evaluate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ink}, res.Notified, "the failed delivery is retried")
+	require.Len(t, notifier.messages, 2)
+	assert.Equal(t, "1 product at or below its reorder point", notifier.messages[1].Subject)
+	low, ok := notifier.messages[1].Data.([]*models.LowStockProduct)
+	require.True(t, ok)
+	assert.Equal(t, 0, low[0].Quantity)
+}
+
+func TestEvaluator_Evaluate_noAutoDraft(t *testing.T) {
+	ctx := context.Background()
+	repo, _, purchases, supplierID := setup(t)
+	require.NoError(t, repo.SetRule(ctx, &models.ReorderRule{ProductID: 1, ReorderPoint: 5, ReorderQuantity: 50,
+		SupplierID: &supplierID}))
+	server := smtptest.New(t)
+	notifier, err := notify.NewSMTP(server.Config())
+	require.NoError(t, err)
+
+	res, err := NewEvaluator(repo, purchases, notifier, 0, false).Evaluate(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, res.Drafted)
+	orders, err := purchases.GetOrders(ctx, models.PurchaseOrderFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, orders)
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	email, err := mail.ReadMessage(strings.NewReader(messages[0].Data))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(email.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "1 product at or below its reorder point", subject)
+}
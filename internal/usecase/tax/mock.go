@@ -0,0 +1,59 @@
+package tax
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/pkg/money"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// ServiceMock stands in for the service in handler tests.
+type ServiceMock struct {
+	mock.Mock
+}
+
+func (m *ServiceMock) GetJurisdictions(ctx context.Context) ([]*models.TaxJurisdiction, error) {
+	args := m.Called(ctx)
+	jurisdictions, _ := args.Get(0).([]*models.TaxJurisdiction)
+	return jurisdictions, args.Error(1)
+}
+
+func (m *ServiceMock) GetJurisdiction(ctx context.Context, code string) (*models.TaxJurisdiction, error) {
+	args := m.Called(ctx, code)
+	j, _ := args.Get(0).(*models.TaxJurisdiction)
+	return j, args.Error(1)
+}
+
+func (m *ServiceMock) SetJurisdiction(ctx context.Context, code string, req models.TaxJurisdictionRequest) (*models.TaxJurisdiction, error) {
+	args := m.Called(ctx, code, req)
+	j, _ := args.Get(0).(*models.TaxJurisdiction)
+	return j, args.Error(1)
+}
+
+func (m *ServiceMock) DeleteJurisdiction(ctx context.Context, code string) error {
+	return m.Called(ctx, code).Error(0)
+}
+
+func (m *ServiceMock) GetRates(ctx context.Context, jurisdiction string) ([]*models.TaxRate, error) {
+	args := m.Called(ctx, jurisdiction)
+	rates, _ := args.Get(0).([]*models.TaxRate)
+	return rates, args.Error(1)
+}
+
+func (m *ServiceMock) AddRate(ctx context.Context, jurisdiction string, req models.TaxRateRequest) (*models.TaxRate, error) {
+	args := m.Called(ctx, jurisdiction, req)
+	r, _ := args.Get(0).(*models.TaxRate)
+	return r, args.Error(1)
+}
+
+func (m *ServiceMock) DeleteRate(ctx context.Context, id int64) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *ServiceMock) Calculate(ctx context.Context, price money.Money, class, jurisdiction string, at time.Time) (*models.TaxBreakdown, error) {
+	args := m.Called(ctx, price, class, jurisdiction, at)
+	b, _ := args.Get(0).(*models.TaxBreakdown)
+	return b, args.Error(1)
+}
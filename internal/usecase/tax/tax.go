@@ -0,0 +1,267 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/tax"
+	"prodcrud/pkg/logging"
+	"prodcrud/pkg/money"
+	"strings"
+	"time"
+)
+
+type ServiceInterface interface {
+	GetJurisdictions(ctx context.Context) ([]*models.TaxJurisdiction, error)
+	GetJurisdiction(ctx context.Context, code string) (*models.TaxJurisdiction, error)
+	// SetJurisdiction creates or replaces the jurisdiction code.
+	SetJurisdiction(ctx context.Context, code string, req models.TaxJurisdictionRequest) (*models.TaxJurisdiction, error)
+	// DeleteJurisdiction deletes a jurisdiction with its rate table.
+	DeleteJurisdiction(ctx context.Context, code string) error
+	GetRates(ctx context.Context, jurisdiction string) ([]*models.TaxRate, error)
+	AddRate(ctx context.Context, jurisdiction string, req models.TaxRateRequest) (*models.TaxRate, error)
+	DeleteRate(ctx context.Context, id int64) error
+	// Calculate splits price into net, tax and gross for a tax class of a
+	// jurisdiction, with the rate in effect at the given time, now when it is
+	// zero. The price is gross in jurisdictions whose prices include tax and
+	// net elsewhere.
+	Calculate(ctx context.Context, price money.Money, class, jurisdiction string, at time.Time) (*models.TaxBreakdown, error)
+}
+
+const logPackage = "usecase/tax"
+
+// MaxJurisdictionLen is the longest jurisdiction code, in bytes.
+const MaxJurisdictionLen = 16
+
+// RateScale is the number of decimal places a tax rate is stored with.
+const RateScale = 4
+
+var (
+	ErrJurisdictionNotFound = tax.ErrJurisdictionNotFound
+	ErrRateNotFound         = tax.ErrRateNotFound
+	ErrRateOverlap          = tax.ErrRateOverlap
+	// ErrNoRate is returned when a taxed class has no rate in effect, so the
+	// price cannot be taxed until one is added.
+	ErrNoRate = errors.New("no tax rate in effect for the tax class")
+	// ErrInvalidTax wraps every validation error, which callers can show as is.
+	ErrInvalidTax = errors.New("invalid tax")
+)
+
+type Service struct {
+	repo tax.Repository
+	now  func() time.Time
+}
+
+func NewService(repo tax.Repository) ServiceInterface {
+	return &Service{repo: repo, now: time.Now}
+}
+
+func (s *Service) GetJurisdictions(ctx context.Context) ([]*models.TaxJurisdiction, error) {
+	jurisdictions, err := s.repo.GetJurisdictions(ctx)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get tax jurisdictions", "error", err)
+		return nil, errors.New("failed to get tax jurisdictions usc")
+	}
+	return jurisdictions, nil
+}
+
+func (s *Service) GetJurisdiction(ctx context.Context, code string) (*models.TaxJurisdiction, error) {
+	code, err := NormalizeJurisdiction(code)
+	if err != nil {
+		return nil, err
+	}
+	j, err := s.repo.GetJurisdiction(ctx, code)
+	if err != nil {
+		if errors.Is(err, ErrJurisdictionNotFound) {
+			return nil, ErrJurisdictionNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to get tax jurisdiction", "code", code, "error", err)
+		return nil, errors.New("failed to get tax jurisdiction usc")
+	}
+	return j, nil
+}
+
+func (s *Service) SetJurisdiction(ctx context.Context, code string, req models.TaxJurisdictionRequest) (*models.TaxJurisdiction, error) {
+	code, err := NormalizeJurisdiction(code)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidTax)
+	}
+	rounding, err := money.ParseRounding(req.Rounding)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTax, err)
+	}
+	j := &models.TaxJurisdiction{Code: code, Name: name, PricesIncludeTax: req.PricesIncludeTax, Rounding: rounding}
+	if err := s.repo.SetJurisdiction(ctx, j); err != nil {
+		logging.For(ctx, logPackage).Error("failed to set tax jurisdiction", "code", code, "error", err)
+		return nil, errors.New("failed to set tax jurisdiction usc")
+	}
+	logging.For(ctx, logPackage).Info("tax jurisdiction set", "code", code, "prices_include_tax", j.PricesIncludeTax,
+		"rounding", j.Rounding)
+	return j, nil
+}
+
+func (s *Service) DeleteJurisdiction(ctx context.Context, code string) error {
+	code, err := NormalizeJurisdiction(code)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteJurisdiction(ctx, code); err != nil {
+		if errors.Is(err, ErrJurisdictionNotFound) {
+			return ErrJurisdictionNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to delete tax jurisdiction", "code", code, "error", err)
+		return errors.New("failed to delete tax jurisdiction usc")
+	}
+	logging.For(ctx, logPackage).Info("tax jurisdiction deleted", "code", code)
+	return nil
+}
+
+func (s *Service) GetRates(ctx context.Context, jurisdiction string) ([]*models.TaxRate, error) {
+	j, err := s.GetJurisdiction(ctx, jurisdiction)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := s.repo.GetRates(ctx, j.Code)
+	if err != nil {
+		logging.For(ctx, logPackage).Error("failed to get tax rates", "code", j.Code, "error", err)
+		return nil, errors.New("failed to get tax rates usc")
+	}
+	return rates, nil
+}
+
+var hundred = money.MustParseRate("100")
+
+func (s *Service) AddRate(ctx context.Context, jurisdiction string, req models.TaxRateRequest) (*models.TaxRate, error) {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: "+format, append([]any{ErrInvalidTax}, args...)...)
+	}
+	code, err := NormalizeJurisdiction(jurisdiction)
+	if err != nil {
+		return nil, err
+	}
+	switch req.TaxClass {
+	case models.TaxClassStandard, models.TaxClassReduced:
+	case models.TaxClassExempt:
+		return nil, invalid("exempt products are never taxed and take no rate")
+	default:
+		return nil, invalid("tax_class must be one of %s, %s", models.TaxClassStandard, models.TaxClassReduced)
+	}
+	rate, err := money.ParseRate(req.Rate)
+	if err != nil {
+		return nil, invalid("%w", err)
+	}
+	if rate.IsZero() || rate.Cmp(hundred) > 0 {
+		return nil, invalid("rate must be above 0 and at most 100")
+	}
+	if _, frac, _ := strings.Cut(rate.String(), "."); len(frac) > RateScale {
+		return nil, invalid("rate must have at most %d decimal places", RateScale)
+	}
+	if req.ValidFrom.IsZero() {
+		return nil, invalid("valid_from is required")
+	}
+	if req.ValidTo != nil && !req.ValidTo.After(req.ValidFrom) {
+		return nil, invalid("valid_to must be after valid_from")
+	}
+
+	r := &models.TaxRate{
+		Jurisdiction: code,
+		TaxClass:     req.TaxClass,
+		Rate:         rate,
+		ValidFrom:    req.ValidFrom.UTC(),
+		ValidTo:      utc(req.ValidTo),
+	}
+	if err := s.repo.AddRate(ctx, r); err != nil {
+		if errors.Is(err, ErrJurisdictionNotFound) || errors.Is(err, ErrRateOverlap) {
+			return nil, err
+		}
+		logging.For(ctx, logPackage).Error("failed to add tax rate", "code", code, "error", err)
+		return nil, errors.New("failed to add tax rate usc")
+	}
+	logging.For(ctx, logPackage).Info("tax rate added", "id", r.ID, "code", code, "class", r.TaxClass, "rate", r.Rate.String())
+	return r, nil
+}
+
+func (s *Service) DeleteRate(ctx context.Context, id int64) error {
+	if err := s.repo.DeleteRate(ctx, id); err != nil {
+		if errors.Is(err, ErrRateNotFound) {
+			return ErrRateNotFound
+		}
+		logging.For(ctx, logPackage).Error("failed to delete tax rate", "id", id, "error", err)
+		return errors.New("failed to delete tax rate usc")
+	}
+	logging.For(ctx, logPackage).Info("tax rate deleted", "id", id)
+	return nil
+}
+
+func (s *Service) Calculate(ctx context.Context, price money.Money, class, jurisdiction string, at time.Time) (*models.TaxBreakdown, error) {
+	j, err := s.GetJurisdiction(ctx, jurisdiction)
+	if err != nil {
+		return nil, err
+	}
+	if at.IsZero() {
+		at = s.now()
+	}
+	b := &models.TaxBreakdown{
+		Jurisdiction:     j.Code,
+		TaxClass:         class,
+		PricesIncludeTax: j.PricesIncludeTax,
+		Rounding:         j.Rounding,
+		Net:              price,
+		Tax:              money.New(0, price.Currency),
+		Gross:            price,
+	}
+	if class == models.TaxClassExempt {
+		return b, nil
+	}
+
+	rate, err := s.repo.GetRate(ctx, j.Code, class, at)
+	if err != nil {
+		if errors.Is(err, ErrRateNotFound) {
+			return nil, fmt.Errorf("%w: %s in %s", ErrNoRate, class, j.Code)
+		}
+		logging.For(ctx, logPackage).Error("failed to get tax rate", "code", j.Code, "class", class, "error", err)
+		return nil, errors.New("failed to calculate tax usc")
+	}
+	b.Rate = &rate.Rate
+	if j.PricesIncludeTax {
+		if b.Tax, err = price.IncludedPercent(rate.Rate, j.Rounding); err == nil {
+			b.Net, err = price.Sub(b.Tax)
+		}
+	} else {
+		if b.Tax, err = price.PercentRounded(rate.Rate, j.Rounding); err == nil {
+			b.Gross, err = price.Add(b.Tax)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTax, err)
+	}
+	return b, nil
+}
+
+// NormalizeJurisdiction trims and upper-cases a jurisdiction code, so "de"
+// and "DE" name the same one. Codes are letters, digits and dashes.
+func NormalizeJurisdiction(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" || len(code) > MaxJurisdictionLen {
+		return "", fmt.Errorf("%w: jurisdiction code must be 1 to %d characters", ErrInvalidTax, MaxJurisdictionLen)
+	}
+	for _, c := range code {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' {
+			return "", fmt.Errorf("%w: jurisdiction code %q can only hold letters, digits and dashes", ErrInvalidTax, code)
+		}
+	}
+	return code, nil
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
@@ -0,0 +1,152 @@
+package tax
+
+import (
+	"context"
+	"prodcrud/internal/models"
+	"prodcrud/internal/repository/tax"
+	"prodcrud/pkg/money"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	jan  = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	july = time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newService(t *testing.T) *Service {
+	t.Helper()
+	s := NewService(tax.NewMemoryRepo()).(*Service)
+	s.now = func() time.Time { return july }
+	return s
+}
+
+func TestService_SetJurisdiction(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+
+	j, err := s.SetJurisdiction(ctx, " us-ca", models.TaxJurisdictionRequest{Name: "California"})
+	require.NoError(t, err)
+	assert.Equal(t, "US-CA", j.Code)
+	assert.Equal(t, money.RoundHalfUp, j.Rounding)
+
+	got, err := s.GetJurisdiction(ctx, "us-ca")
+	require.NoError(t, err)
+	assert.Equal(t, "California", got.Name)
+
+	for name, tc := range map[string]struct {
+		code string
+		req  models.TaxJurisdictionRequest
+	}{
+		"no code":      {code: " ", req: models.TaxJurisdictionRequest{Name: "x"}},
+		"bad code":     {code: "de/by", req: models.TaxJurisdictionRequest{Name: "x"}},
+		"no name":      {code: "DE"},
+		"bad rounding": {code: "DE", req: models.TaxJurisdictionRequest{Name: "x", Rounding: "bankers"}},
+	} {
+		_, err := s.SetJurisdiction(ctx, tc.code, tc.req)
+		assert.ErrorIs(t, err, ErrInvalidTax, name)
+	}
+	_, err = s.GetJurisdiction(ctx, "DE")
+	assert.ErrorIs(t, err, ErrJurisdictionNotFound)
+}
+
+func TestService_AddRate(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+	_, err := s.SetJurisdiction(ctx, "DE", models.TaxJurisdictionRequest{Name: "Germany"})
+	require.NoError(t, err)
+
+	r, err := s.AddRate(ctx, "de", models.TaxRateRequest{TaxClass: models.TaxClassStandard, Rate: "19", ValidFrom: jan})
+	require.NoError(t, err)
+	assert.Equal(t, "DE", r.Jurisdiction)
+	assert.Equal(t, "19", r.Rate.String())
+
+	_, err = s.AddRate(ctx, "DE", models.TaxRateRequest{TaxClass: models.TaxClassStandard, Rate: "16", ValidFrom: july})
+	assert.ErrorIs(t, err, ErrRateOverlap)
+	_, err = s.AddRate(ctx, "FR", models.TaxRateRequest{TaxClass: models.TaxClassStandard, Rate: "20", ValidFrom: jan})
+	assert.ErrorIs(t, err, ErrJurisdictionNotFound)
+
+	before := jan.Add(-time.Hour)
+	for name, req := range map[string]models.TaxRateRequest{
+		"exempt":        {TaxClass: models.TaxClassExempt, Rate: "1", ValidFrom: jan},
+		"unknown class": {TaxClass: "luxury", Rate: "1", ValidFrom: jan},
+		"bad rate":      {TaxClass: models.TaxClassReduced, Rate: "seven", ValidFrom: jan},
+		"zero rate":     {TaxClass: models.TaxClassReduced, Rate: "0", ValidFrom: jan},
+		"over 100":      {TaxClass: models.TaxClassReduced, Rate: "100.5", ValidFrom: jan},
+		"too precise":   {TaxClass: models.TaxClassReduced, Rate: "7.12345", ValidFrom: jan},
+		"no valid_from": {TaxClass: models.TaxClassReduced, Rate: "7"},
+		"empty window":  {TaxClass: models.TaxClassReduced, Rate: "7", ValidFrom: jan, ValidTo: &before},
+	} {
+		_, err := s.AddRate(ctx, "DE", req)
+		assert.ErrorIs(t, err, ErrInvalidTax, name)
+	}
+
+	rates, err := s.GetRates(ctx, "DE")
+	require.NoError(t, err)
+	assert.Len(t, rates, 1)
+	_, err = s.GetRates(ctx, "FR")
+	assert.ErrorIs(t, err, ErrJurisdictionNotFound)
+
+	require.NoError(t, s.DeleteRate(ctx, r.ID))
+	assert.ErrorIs(t, s.DeleteRate(ctx, r.ID), ErrRateNotFound)
+}
+
+func TestService_Calculate(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t)
+	for code, req := range map[string]models.TaxJurisdictionRequest{
+		"DE":    {Name: "Germany", PricesIncludeTax: true},
+		"US-CA": {Name: "California", Rounding: "half_even"},
+		"US-NY": {Name: "New York", Rounding: "up"},
+	} {
+		_, err := s.SetJurisdiction(ctx, code, req)
+		require.NoError(t, err)
+	}
+	for _, r := range []struct {
+		code, class, rate string
+		from              time.Time
+		to                *time.Time
+	}{
+		{"DE", models.TaxClassStandard, "16", jan, &july},
+		{"DE", models.TaxClassStandard, "19", july, nil},
+		{"DE", models.TaxClassReduced, "7", jan, nil},
+		{"US-CA", models.TaxClassStandard, "7.25", jan, nil},
+		{"US-NY", models.TaxClassStandard, "8.875", jan, nil},
+	} {
+		_, err := s.AddRate(ctx, r.code, models.TaxRateRequest{TaxClass: r.class, Rate: r.rate, ValidFrom: r.from, ValidTo: r.to})
+		require.NoError(t, err)
+	}
+
+	for name, tc := range map[string]struct {
+		price        money.Money
+		class, code  string
+		at           time.Time
+		net, tax, gr string
+	}{
+		// 119.00 gross holds 19/119 of tax
+		"inclusive":          {money.New(11900, "EUR"), models.TaxClassStandard, "DE", time.Time{}, "100.00", "19.00", "119.00"},
+		"inclusive, earlier": {money.New(11600, "EUR"), models.TaxClassStandard, "DE", july.Add(-time.Second), "100.00", "16.00", "116.00"},
+		// 9.99 * 7/107 = 0.65355... rounds to 0.65
+		"inclusive, reduced": {money.New(999, "EUR"), models.TaxClassReduced, "DE", time.Time{}, "9.34", "0.65", "9.99"},
+		// 10.00 * 7.25% = 0.725, a tie that half_even takes to 0.72
+		"exclusive, half even": {money.New(1000, "USD"), models.TaxClassStandard, "US-CA", time.Time{}, "10.00", "0.72", "10.72"},
+		// 10.00 * 8.875% = 0.8875, which up takes to 0.89
+		"exclusive, up": {money.New(1000, "USD"), models.TaxClassStandard, "US-NY", time.Time{}, "10.00", "0.89", "10.89"},
+		"exempt":        {money.New(1000, "USD"), models.TaxClassExempt, "us-ca", time.Time{}, "10.00", "0.00", "10.00"},
+	} {
+		b, err := s.Calculate(ctx, tc.price, tc.class, tc.code, tc.at)
+		require.NoError(t, err, name)
+		assert.Equal(t, []string{tc.net, tc.tax, tc.gr}, []string{b.Net.Decimal(), b.Tax.Decimal(), b.Gross.Decimal()}, name)
+		assert.Equal(t, tc.class == models.TaxClassExempt, b.Rate == nil, name)
+	}
+
+	_, err := s.Calculate(ctx, money.New(1000, "USD"), models.TaxClassReduced, "US-CA", time.Time{})
+	assert.ErrorIs(t, err, ErrNoRate)
+	_, err = s.Calculate(ctx, money.New(1000, "USD"), models.TaxClassStandard, "FR", time.Time{})
+	assert.ErrorIs(t, err, ErrJurisdictionNotFound)
+	_, err = s.Calculate(ctx, money.New(1000, "EUR"), models.TaxClassStandard, "DE", jan.Add(-time.Second))
+	assert.ErrorIs(t, err, ErrNoRate)
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS products_deleted_at_idx;
+DROP TABLE IF EXISTS product_purges;
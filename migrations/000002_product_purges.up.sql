@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS product_purges(
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    deleted_at TIMESTAMP,
+    purged_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    reason VARCHAR(32) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS products_deleted_at_idx ON products (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS exchange_rates;
+DROP TABLE IF EXISTS product_prices;
+
+ALTER TABLE products DROP COLUMN IF EXISTS currency;
+ALTER TABLE products ALTER COLUMN price TYPE INTEGER;
@@ -0,0 +1,18 @@
+ALTER TABLE products ALTER COLUMN price TYPE BIGINT;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+
+CREATE TABLE IF NOT EXISTS product_prices(
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL check ( amount > 0 ),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (product_id, currency)
+);
+
+CREATE TABLE IF NOT EXISTS exchange_rates(
+    base VARCHAR(3) NOT NULL,
+    quote VARCHAR(3) NOT NULL,
+    rate NUMERIC(28, 10) NOT NULL check ( rate > 0 ),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (base, quote)
+);
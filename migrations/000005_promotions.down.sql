@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS promotions;
+
+DROP INDEX IF EXISTS products_category_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS category;
@@ -0,0 +1,25 @@
+ALTER TABLE products ADD COLUMN category VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS products_category_idx ON products (category);
+
+CREATE TABLE IF NOT EXISTS promotions(
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    product_ids JSONB NOT NULL DEFAULT '[]',
+    categories JSONB NOT NULL DEFAULT '[]',
+    percent NUMERIC(7, 4) check ( percent > 0 AND percent <= 100 ),
+    amount BIGINT check ( amount > 0 ),
+    currency VARCHAR(3),
+    buy INTEGER check ( buy > 0 ),
+    get INTEGER check ( get > 0 ),
+    tiers JSONB NOT NULL DEFAULT '[]',
+    priority INTEGER NOT NULL DEFAULT 0,
+    stackable BOOLEAN NOT NULL DEFAULT false,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP check ( ends_at > starts_at ),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS promotions_starts_at_idx ON promotions (starts_at);
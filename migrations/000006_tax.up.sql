@@ -0,0 +1,21 @@
+ALTER TABLE products ADD COLUMN tax_class VARCHAR(16) NOT NULL DEFAULT 'standard';
+
+CREATE TABLE IF NOT EXISTS tax_jurisdictions(
+    code VARCHAR(16) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    prices_include_tax BOOLEAN NOT NULL DEFAULT false,
+    rounding VARCHAR(16) NOT NULL DEFAULT 'half_up',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS tax_rates(
+    id BIGSERIAL PRIMARY KEY,
+    jurisdiction VARCHAR(16) NOT NULL REFERENCES tax_jurisdictions(code) ON DELETE CASCADE,
+    tax_class VARCHAR(16) NOT NULL,
+    rate NUMERIC(7, 4) NOT NULL check ( rate > 0 AND rate <= 100 ),
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP check ( valid_to > valid_from ),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS tax_rates_lookup_idx ON tax_rates (jurisdiction, tax_class, valid_from);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS product_variant_axes(
+    product_id INTEGER PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    axes JSONB NOT NULL
+);
+
+-- a variant is a product of its own, linked to its parent; the parent cannot
+-- be removed while it still has variants
+CREATE TABLE IF NOT EXISTS product_variants(
+    product_id INTEGER PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    parent_id INTEGER NOT NULL REFERENCES products(id),
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    options JSONB NOT NULL,
+    UNIQUE (parent_id, options)
+);
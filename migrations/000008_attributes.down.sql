@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS category_attributes;
+
+DROP INDEX IF EXISTS products_attributes_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS attributes;
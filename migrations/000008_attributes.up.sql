@@ -0,0 +1,17 @@
+ALTER TABLE products ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+
+-- jsonb_path_ops serves the @> containment of the attribute filters
+CREATE INDEX IF NOT EXISTS products_attributes_idx ON products USING GIN (attributes jsonb_path_ops);
+
+-- the attribute schema of a category; "values" lists the choices of an enum,
+-- units the units of measure a unit attribute accepts
+CREATE TABLE IF NOT EXISTS category_attributes(
+    category VARCHAR(64) NOT NULL,
+    name VARCHAR(32) NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT false,
+    "values" JSONB NOT NULL DEFAULT '[]',
+    units JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (category, name)
+);
@@ -0,0 +1,20 @@
+-- the image files live in the blob store under key and thumbnail_key; purging
+-- a product drops its rows but leaves the files behind
+CREATE TABLE IF NOT EXISTS product_images(
+    id BIGSERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL UNIQUE,
+    thumbnail_key VARCHAR(255) NOT NULL,
+    content_type VARCHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS product_images_product_idx ON product_images (product_id, position);
+
+-- at most one primary image per product
+CREATE UNIQUE INDEX IF NOT EXISTS product_images_primary_idx ON product_images (product_id) WHERE is_primary;
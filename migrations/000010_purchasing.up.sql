@@ -0,0 +1,51 @@
+CREATE TABLE IF NOT EXISTS suppliers(
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the products a supplier sells, at a unit cost in the currency of the supplier
+CREATE TABLE IF NOT EXISTS supplier_products(
+    supplier_id BIGINT NOT NULL REFERENCES suppliers(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(64) NOT NULL DEFAULT '',
+    cost BIGINT NOT NULL check ( cost > 0 ),
+    currency VARCHAR(3) NOT NULL,
+    lead_time_days INTEGER NOT NULL check ( lead_time_days >= 0 ),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (supplier_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS supplier_products_product_idx ON supplier_products (product_id);
+
+-- a supplier with purchase orders cannot be deleted
+CREATE TABLE IF NOT EXISTS purchase_orders(
+    id BIGSERIAL PRIMARY KEY,
+    supplier_id BIGINT NOT NULL REFERENCES suppliers(id),
+    status VARCHAR(32) NOT NULL DEFAULT 'draft',
+    currency VARCHAR(3) NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP,
+    expected_at TIMESTAMP,
+    received_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS purchase_orders_supplier_idx ON purchase_orders (supplier_id);
+CREATE INDEX IF NOT EXISTS purchase_orders_status_idx ON purchase_orders (status);
+
+-- a product on a purchase order cannot be removed permanently, its lines
+-- account for the units ordered and received
+CREATE TABLE IF NOT EXISTS purchase_order_lines(
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE RESTRICT,
+    quantity INTEGER NOT NULL check ( quantity > 0 ),
+    received_quantity INTEGER NOT NULL DEFAULT 0 check ( received_quantity >= 0 AND received_quantity <= quantity ),
+    cost BIGINT NOT NULL check ( cost > 0 ),
+    UNIQUE (order_id, product_id)
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS order_lines;
+DROP TABLE IF EXISTS orders;
+
+-- fails while a product is out of stock
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_quantity_check;
+ALTER TABLE products ADD CONSTRAINT products_quantity_check CHECK ( quantity > 0 );
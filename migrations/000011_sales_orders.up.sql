@@ -0,0 +1,29 @@
+-- selling the last unit leaves a product out of stock
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_quantity_check;
+ALTER TABLE products ADD CONSTRAINT products_quantity_check CHECK ( quantity >= 0 );
+
+CREATE TABLE IF NOT EXISTS orders(
+    id BIGSERIAL PRIMARY KEY,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    allocated_at TIMESTAMP,
+    shipped_at TIMESTAMP,
+    cancelled_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS orders_status_idx ON orders (status);
+
+-- a product on an order cannot be removed permanently, its lines hold what
+-- the order sold and takes back on cancellation
+CREATE TABLE IF NOT EXISTS order_lines(
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE RESTRICT,
+    quantity INTEGER NOT NULL check ( quantity > 0 ),
+    unit_price BIGINT NOT NULL check ( unit_price >= 0 ),
+    UNIQUE (order_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS order_lines_product_idx ON order_lines (product_id);
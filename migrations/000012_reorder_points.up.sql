@@ -0,0 +1,19 @@
+-- a product with a rule is low on stock at or below its reorder point;
+-- supplier_id is the supplier purchase orders are drafted with
+CREATE TABLE IF NOT EXISTS reorder_rules(
+    product_id INTEGER PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    reorder_point INTEGER NOT NULL check ( reorder_point >= 0 ),
+    reorder_quantity INTEGER NOT NULL check ( reorder_quantity > 0 ),
+    supplier_id BIGINT REFERENCES suppliers(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- an alert is raised once when a product falls to its reorder point and
+-- cleared when it is restocked above it; notified_at stays null until the
+-- alert is delivered
+CREATE TABLE IF NOT EXISTS stock_alerts(
+    product_id INTEGER PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    flagged_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    notified_at TIMESTAMP,
+    purchase_order_id BIGINT REFERENCES purchase_orders(id) ON DELETE SET NULL
+);
@@ -0,0 +1,27 @@
+// Package migrations embeds the SQL migrations into the binary.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// FS holds the Postgres NNNNNN_name.{up,down}.sql files of this directory.
+//
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// SQLite holds the migrations of the SQLite backend, kept in the sqlite
+// directory with the same numbering as the Postgres ones.
+var SQLite = mustSub(sqliteFS, "sqlite")
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
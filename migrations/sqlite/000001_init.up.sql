@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS products(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    price INTEGER NOT NULL check ( price > 0 ),
+    quantity INTEGER NOT NULL check ( quantity > 0 ),
+    description TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP
+);
@@ -0,0 +1,17 @@
+ALTER TABLE products ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+
+CREATE TABLE IF NOT EXISTS product_prices(
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    currency VARCHAR(3) NOT NULL,
+    amount INTEGER NOT NULL check ( amount > 0 ),
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (product_id, currency)
+);
+
+CREATE TABLE IF NOT EXISTS exchange_rates(
+    base VARCHAR(3) NOT NULL,
+    quote VARCHAR(3) NOT NULL,
+    rate TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (base, quote)
+);
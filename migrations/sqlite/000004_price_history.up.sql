@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS product_price_history(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    amount INTEGER NOT NULL check ( amount > 0 ),
+    currency VARCHAR(3) NOT NULL,
+    effective_from TIMESTAMP NOT NULL,
+    effective_to TIMESTAMP check ( effective_to > effective_from ),
+    UNIQUE (product_id, effective_from)
+);
+
+CREATE INDEX IF NOT EXISTS product_price_history_effective_from_idx ON product_price_history (effective_from);
+
+-- the current price of every product has been in effect since it was created
+INSERT INTO product_price_history(product_id, amount, currency, effective_from)
+SELECT id, price, currency, created_at FROM products;
@@ -0,0 +1,25 @@
+ALTER TABLE products ADD COLUMN category VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS products_category_idx ON products (category);
+
+CREATE TABLE IF NOT EXISTS promotions(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    product_ids TEXT NOT NULL DEFAULT '[]',
+    categories TEXT NOT NULL DEFAULT '[]',
+    percent TEXT,
+    amount INTEGER check ( amount > 0 ),
+    currency VARCHAR(3),
+    buy INTEGER check ( buy > 0 ),
+    get INTEGER check ( get > 0 ),
+    tiers TEXT NOT NULL DEFAULT '[]',
+    priority INTEGER NOT NULL DEFAULT 0,
+    stackable BOOLEAN NOT NULL DEFAULT false,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP check ( ends_at > starts_at ),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS promotions_starts_at_idx ON promotions (starts_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS tax_rates;
+DROP TABLE IF EXISTS tax_jurisdictions;
+
+ALTER TABLE products DROP COLUMN tax_class;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS product_variant_axes(
+    product_id INTEGER PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    axes TEXT NOT NULL
+);
+
+-- a variant is a product of its own, linked to its parent; the parent cannot
+-- be removed while it still has variants. options is canonical JSON, keys
+-- sorted, so equal combinations compare equal as text
+CREATE TABLE IF NOT EXISTS product_variants(
+    product_id INTEGER PRIMARY KEY REFERENCES products(id) ON DELETE CASCADE,
+    parent_id INTEGER NOT NULL REFERENCES products(id),
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    options TEXT NOT NULL,
+    UNIQUE (parent_id, options)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS category_attributes;
+
+ALTER TABLE products DROP COLUMN attributes;
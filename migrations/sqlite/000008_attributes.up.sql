@@ -0,0 +1,14 @@
+ALTER TABLE products ADD COLUMN attributes TEXT NOT NULL DEFAULT '{}';
+
+-- the attribute schema of a category; "values" lists the choices of an enum,
+-- units the units of measure a unit attribute accepts
+CREATE TABLE IF NOT EXISTS category_attributes(
+    category VARCHAR(64) NOT NULL,
+    name VARCHAR(32) NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT false,
+    "values" TEXT NOT NULL DEFAULT '[]',
+    units TEXT NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (category, name)
+);
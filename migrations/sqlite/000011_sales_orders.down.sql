@@ -0,0 +1,9 @@
+PRAGMA writable_schema = ON;
+UPDATE sqlite_schema SET sql = replace(sql, 'check ( quantity >= 0 )', 'check ( quantity > 0 )')
+WHERE type = 'table' AND name = 'products';
+PRAGMA writable_schema = RESET;
+-- fails while a product is out of stock, like on Postgres
+UPDATE products SET quantity = quantity WHERE quantity = 0;
+
+DROP TABLE IF EXISTS order_lines;
+DROP TABLE IF EXISTS orders;
@@ -0,0 +1,36 @@
+-- selling the last unit leaves a product out of stock. SQLite cannot alter a
+-- check constraint and rebuilding products would cascade to every table
+-- referencing it, so the constraint is loosened in place, which the SQLite
+-- documentation allows for changes that leave the stored rows valid. RESET
+-- reloads the schema of this connection, the tables below make the others
+-- reload it.
+PRAGMA writable_schema = ON;
+UPDATE sqlite_schema SET sql = replace(sql, 'check ( quantity > 0 )', 'check ( quantity >= 0 )')
+WHERE type = 'table' AND name = 'products';
+PRAGMA writable_schema = RESET;
+
+CREATE TABLE IF NOT EXISTS orders(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    allocated_at TIMESTAMP,
+    shipped_at TIMESTAMP,
+    cancelled_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS orders_status_idx ON orders (status);
+
+-- a product on an order cannot be removed permanently, its lines hold what
+-- the order sold and takes back on cancellation
+CREATE TABLE IF NOT EXISTS order_lines(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE RESTRICT,
+    quantity INTEGER NOT NULL check ( quantity > 0 ),
+    unit_price INTEGER NOT NULL check ( unit_price >= 0 ),
+    UNIQUE (order_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS order_lines_product_idx ON order_lines (product_id);
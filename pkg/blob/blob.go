@@ -0,0 +1,60 @@
+// Package blob stores binary objects by key, in a local directory or in an
+// S3 compatible object store.
+//
+// Keys are slash separated paths such as "products/1/ab12.jpg" made of
+// letters, digits, dots, dashes and underscores; no segment may be empty,
+// "." or "..", so a key never escapes the root of its store.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// MaxKeyLen is the longest key, in bytes.
+const MaxKeyLen = 1024
+
+var (
+	// ErrNotFound is returned by Get for a key without an object.
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidKey is returned for a key that does not follow the rules
+	// of the package.
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Info describes a stored object.
+type Info struct {
+	Size        int64
+	ContentType string
+}
+
+// BlobStore stores objects by key.
+type BlobStore interface {
+	// Put stores data under key, replacing the object stored there.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get opens the object stored under key; the caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	// Delete removes the object stored under key; a missing object is not
+	// an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey checks key against the rules of the package.
+func ValidKey(key string) error {
+	if key == "" || len(key) > MaxKeyLen {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+		for _, r := range segment {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+				return ErrInvalidKey
+			}
+		}
+	}
+	return nil
+}
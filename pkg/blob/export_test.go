@@ -0,0 +1,15 @@
+package blob
+
+import (
+	"net/http"
+	"time"
+)
+
+// Sign exposes the request signer to the external tests.
+func Sign(s *S3Store, req *http.Request, body []byte, amzDate string) {
+	now, err := time.Parse(amzDateFormat, amzDate)
+	if err != nil {
+		panic(err)
+	}
+	s.sign(req, body, now)
+}
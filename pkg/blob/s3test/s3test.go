@@ -0,0 +1,157 @@
+// Package s3test serves an in-memory stand-in of an S3 compatible store for
+// tests of code using blob.S3Store.
+//
+// The server keeps the objects of one bucket, addressed in the path. It
+// accepts PUT, GET and DELETE of objects signed with the access key of the
+// server: the Authorization header must carry a Signature Version 4
+// credential for the key, the region and the s3 service, sign the host and
+// the x-amz headers, and x-amz-content-sha256 must match the body.
+package s3test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"prodcrud/pkg/blob"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The credentials of the stand-in.
+const (
+	Region    = "us-east-1"
+	Bucket    = "test-bucket"
+	AccessKey = "test-access-key"
+	SecretKey = "test-secret-key"
+)
+
+type object struct {
+	data        []byte
+	contentType string
+}
+
+// Server is a running stand-in, closed when the test ends.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string]object
+}
+
+// New starts a stand-in for the test.
+func New(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{objects: make(map[string]object)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Config returns the configuration of a store backed by the server.
+func (s *Server) Config() blob.S3Config {
+	return blob.S3Config{
+		Endpoint:  s.URL,
+		Region:    Region,
+		Bucket:    Bucket,
+		AccessKey: AccessKey,
+		SecretKey: SecretKey,
+		Client:    s.Client(),
+	}
+}
+
+// Keys lists the keys of the stored objects in order.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		fail(w, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+	if code := checkSignature(r, body); code != "" {
+		fail(w, http.StatusForbidden, code)
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != Bucket {
+		fail(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[key] = object{data: body, contentType: r.Header.Get("Content-Type")}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		o, ok := s.objects[key]
+		if !ok {
+			fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if o.contentType != "" {
+			w.Header().Set("Content-Type", o.contentType)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(o.data)))
+		_, _ = w.Write(o.data)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// checkSignature returns the S3 error code of a request that is not signed
+// as the package documents, empty for a valid one.
+func checkSignature(r *http.Request, body []byte) string {
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return "AccessDenied"
+	}
+	fields := make(map[string]string)
+	for _, f := range strings.Split(auth, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(f), "=")
+		fields[k] = v
+	}
+	date := r.Header.Get("X-Amz-Date")
+	if len(date) < 8 || fields["Credential"] != fmt.Sprintf("%s/%s/%s/s3/aws4_request", AccessKey, date[:8], Region) {
+		return "InvalidAccessKeyId"
+	}
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	for _, h := range []string{"host", "x-amz-content-sha256", "x-amz-date"} {
+		if !slices.Contains(signed, h) {
+			return "AccessDenied"
+		}
+	}
+	if len(fields["Signature"]) != 64 {
+		return "SignatureDoesNotMatch"
+	}
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		return "XAmzContentSHA256Mismatch"
+	}
+	return ""
+}
+
+func fail(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code></Error>", code)
+}
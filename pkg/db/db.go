@@ -3,24 +3,153 @@ package db
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
+	"time"
 
+	"prodcrud/pkg/logging"
+
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func NewDB(dsn string) (*pgxpool.Pool, error) {
-	config, err := pgxpool.ParseConfig(dsn)
+const logPackage = "db"
+
+// Config holds the pool settings. Zero values keep the pgxpool defaults.
+type Config struct {
+	DSN                   string
+	MaxConns              int32
+	MinConns              int32
+	MaxConnLifetime       time.Duration
+	MaxConnLifetimeJitter time.Duration
+	MaxConnIdleTime       time.Duration
+	HealthCheckPeriod     time.Duration
+
+	// StartupTimeout bounds how long NewDB keeps retrying while the database is
+	// not reachable yet, ConnectTimeout bounds a single attempt.
+	StartupTimeout time.Duration
+	ConnectTimeout time.Duration
+
+	// ReadTimeout and WriteTimeout are the statement timeouts for the two query
+	// classes, see DB.Read and DB.Write.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// ReadRetries is the number of extra attempts for reads failing with a
+	// transient error, RetryBackoff the delay before the first one.
+	ReadRetries  int
+	RetryBackoff time.Duration
+
+	// ReplicaDSNs are optional read replicas used by DB.ReadReplica. A replica is
+	// ejected while unreachable or lagging more than ReplicaMaxLag (0 disables
+	// the lag check), and rechecked every ReplicaCheckPeriod.
+	ReplicaDSNs        []string
+	ReplicaMaxLag      time.Duration
+	ReplicaCheckPeriod time.Duration
+
+	// Tracer, if set, is notified of every query on the primary and replicas.
+	Tracer pgx.QueryTracer
+}
+
+// DB is a connection pool that applies per-class statement timeouts, retries
+// idempotent reads on transient errors and routes replica reads.
+type DB struct {
+	*pgxpool.Pool
+	cfg      Config
+	metrics  metrics
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+func NewDB(ctx context.Context, cfg Config) (*DB, error) {
+	d := &DB{cfg: cfg}
+	config, err := d.poolConfig(cfg.DSN)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse dsn: %w", err)
 	}
 
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pool: %w", err)
 	}
+	d.Pool = pool
+
+	if err := waitReady(ctx, pool, cfg); err != nil {
+		pool.Close()
+		return nil, err
+	}
 
-	if err := pool.Ping(context.Background()); err != nil {
-		return nil, fmt.Errorf("failed to ping pool: %w", err)
+	if err := d.connectReplicas(ctx); err != nil {
+		d.Close()
+		return nil, err
 	}
 
-	return pool, nil
+	return d, nil
+}
+
+// Close closes the primary and every replica pool.
+func (d *DB) Close() {
+	for _, r := range d.replicas {
+		r.pool.Close()
+	}
+	d.Pool.Close()
+}
+
+func (d *DB) poolConfig(dsn string) (*pgxpool.Config, error) {
+	cfg := d.cfg
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err //nolint:wrapcheck //wrapped by the callers
+	}
+	if cfg.MaxConns > 0 {
+		config.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		config.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnLifetimeJitter > 0 {
+		config.MaxConnLifetimeJitter = cfg.MaxConnLifetimeJitter
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	// the timestamp columns have no time zone, so now() must be the UTC time
+	// Go compares them with, whatever the server or the dsn default to
+	config.ConnConfig.RuntimeParams["timezone"] = "UTC"
+	if cfg.ConnectTimeout > 0 {
+		config.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+	if cfg.Tracer != nil {
+		config.ConnConfig.Tracer = cfg.Tracer
+	}
+	return config, nil
+}
+
+// waitReady pings the database with exponential backoff until it answers or
+// the startup deadline passes.
+func waitReady(ctx context.Context, pool *pgxpool.Pool, cfg Config) error {
+	if cfg.StartupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.StartupTimeout)
+		defer cancel()
+	}
+
+	backoff := newBackoff(cfg.RetryBackoff)
+	for attempt := 1; ; attempt++ {
+		err := pool.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		delay := backoff.next()
+		if cfg.StartupTimeout <= 0 || !sleep(ctx, delay) {
+			return fmt.Errorf("failed to ping pool after %d attempts: %w", attempt, err)
+		}
+		logging.For(ctx, logPackage).Warn("database is not ready, retrying", "attempt", attempt, "delay", delay,
+			"error", err)
+	}
 }
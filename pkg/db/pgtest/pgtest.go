@@ -0,0 +1,230 @@
+// Package pgtest provides throwaway Postgres databases for integration tests.
+//
+// The server is the one named by TEST_DSN, or else a temporary cluster started
+// with the initdb and pg_ctl binaries found in PG_BIN or on the PATH. Every
+// call to New gets its own schema with the migrations applied, so tests can
+// run in parallel and never see each other's rows. Without a server the tests
+// are skipped, or fail when PGTEST_REQUIRED is set, as in CI, so that they
+// cannot pass there without having run.
+//
+// Packages using New should stop the temporary cluster from TestMain:
+//
+//	func TestMain(m *testing.M) { pgtest.Main(m) }
+package pgtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"prodcrud/migrations"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/migration"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const startTimeout = 30 * time.Second
+
+var (
+	once    sync.Once
+	server  *cluster
+	baseDSN string
+	initErr error
+	skipMsg string
+)
+
+// Main runs the tests of a package and stops the temporary cluster, if one
+// was started, before exiting.
+func Main(m *testing.M) {
+	code := m.Run()
+	if server != nil {
+		server.stop()
+	}
+	os.Exit(code)
+}
+
+// DSN returns the connection string of the test server, skipping the test
+// when none is available, or failing it with PGTEST_REQUIRED.
+func DSN(t testing.TB) string {
+	t.Helper()
+	once.Do(setup)
+	if skipMsg != "" {
+		if os.Getenv("PGTEST_REQUIRED") != "" {
+			t.Fatalf("%s (PGTEST_REQUIRED is set)", skipMsg)
+		}
+		t.Skip(skipMsg)
+	}
+	if initErr != nil {
+		t.Fatalf("pgtest: %v", initErr)
+	}
+	return baseDSN
+}
+
+// New creates a schema private to the test, applies the migrations in it and
+// returns a pool whose search_path points there. The schema is dropped when
+// the test ends.
+func New(t testing.TB) *db.DB {
+	t.Helper()
+	return NewWithMigrations(t, migrations.FS)
+}
+
+// NewWithMigrations is New with another migration source.
+func NewWithMigrations(t testing.TB, source fs.FS) *db.DB {
+	t.Helper()
+	ctx := context.Background()
+	base := DSN(t)
+
+	schema := "test_" + randomSuffix()
+	admin, err := pgx.Connect(ctx, base)
+	if err != nil {
+		t.Fatalf("pgtest: connect: %v", err)
+	}
+	defer admin.Close(ctx)
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("pgtest: create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		conn, err := pgx.Connect(context.Background(), base)
+		if err != nil {
+			t.Logf("pgtest: drop schema %s: %v", schema, err)
+			return
+		}
+		defer conn.Close(context.Background())
+		if _, err := conn.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Logf("pgtest: drop schema %s: %v", schema, err)
+		}
+	})
+
+	dsn, err := withSearchPath(base, schema)
+	if err != nil {
+		t.Fatalf("pgtest: %v", err)
+	}
+	if err := migration.New(source, dsn).Up(ctx); err != nil {
+		t.Fatalf("pgtest: %v", err)
+	}
+
+	pool, err := db.NewDB(ctx, db.Config{DSN: dsn, MaxConns: 4, StartupTimeout: startTimeout})
+	if err != nil {
+		t.Fatalf("pgtest: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func setup() {
+	if dsn := os.Getenv("TEST_DSN"); dsn != "" {
+		baseDSN = dsn
+		return
+	}
+	bin, err := findBinaries()
+	if err != nil {
+		skipMsg = "pgtest: set TEST_DSN or install postgres (initdb, pg_ctl): " + err.Error()
+		return
+	}
+	server, initErr = start(bin)
+	if initErr == nil {
+		baseDSN = server.dsn
+	}
+}
+
+func findBinaries() (string, error) {
+	if dir := os.Getenv("PG_BIN"); dir != "" {
+		if _, err := os.Stat(filepath.Join(dir, "pg_ctl")); err != nil {
+			return "", fmt.Errorf("PG_BIN: %w", err)
+		}
+		return dir, nil
+	}
+	path, err := exec.LookPath("pg_ctl")
+	if err == nil {
+		return filepath.Dir(path), nil
+	}
+	matches, _ := filepath.Glob("/usr/lib/postgresql/*/bin/pg_ctl")
+	if len(matches) > 0 {
+		return filepath.Dir(matches[len(matches)-1]), nil
+	}
+	return "", errors.New("pg_ctl not found")
+}
+
+// cluster is a temporary Postgres server owned by the test binary.
+type cluster struct {
+	bin string
+	dir string
+	dsn string
+}
+
+func start(bin string) (*cluster, error) {
+	dir, err := os.MkdirTemp("", "pgtest-")
+	if err != nil {
+		return nil, fmt.Errorf("temp dir: %w", err)
+	}
+	c := &cluster{bin: bin, dir: dir}
+	data := filepath.Join(dir, "data")
+
+	out, err := exec.Command(filepath.Join(bin, "initdb"),
+		"-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync").CombinedOutput()
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("initdb: %w: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	opts := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off -c full_page_writes=off", port, dir)
+	out, err = exec.Command(filepath.Join(bin, "pg_ctl"),
+		"-D", data, "-o", opts, "-l", filepath.Join(dir, "postgres.log"),
+		"-t", strconv.Itoa(int(startTimeout.Seconds())), "-w", "start").CombinedOutput()
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("pg_ctl start: %w: %s", err, out)
+	}
+
+	c.dsn = fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port)
+	return c, nil
+}
+
+func (c *cluster) stop() {
+	_ = exec.Command(filepath.Join(c.bin, "pg_ctl"), "-D", filepath.Join(c.dir, "data"), "-m", "immediate", "-w", "stop").Run()
+	_ = os.RemoveAll(c.dir)
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("free port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func withSearchPath(dsn, schema string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("parse TEST_DSN: %w", err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func randomSuffix() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	defaultRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff     = 5 * time.Second
+)
+
+type metrics struct {
+	reads    atomic.Int64
+	writes   atomic.Int64
+	retries  atomic.Int64
+	timeouts atomic.Int64
+
+	replicaReads atomic.Int64
+}
+
+// Read runs an idempotent query under the read statement timeout. Serialization
+// failures and transient connection errors are retried with backoff.
+func (d *DB) Read(ctx context.Context, fn func(ctx context.Context) error) error {
+	d.metrics.reads.Add(1)
+	backoff := newBackoff(d.cfg.RetryBackoff)
+	for attempt := 0; ; attempt++ {
+		err := d.run(ctx, d.cfg.ReadTimeout, fn)
+		if err == nil || attempt >= d.cfg.ReadRetries || !IsRetryable(err) {
+			return err
+		}
+		if !sleep(ctx, backoff.next()) {
+			return err
+		}
+		d.metrics.retries.Add(1)
+	}
+}
+
+// Write runs a statement under the write statement timeout. Writes are never
+// retried because they may already have been applied.
+func (d *DB) Write(ctx context.Context, fn func(ctx context.Context) error) error {
+	d.metrics.writes.Add(1)
+	return d.run(ctx, d.cfg.WriteTimeout, fn)
+}
+
+func (d *DB) run(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err := fn(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		d.metrics.timeouts.Add(1)
+	}
+	return err
+}
+
+// IsRetryable reports whether err is a serialization failure, a deadlock or a
+// connection error after which repeating an idempotent query is safe.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", "40P01", "57P01", "57P02", "57P03":
+			return true
+		}
+		return strings.HasPrefix(pgErr.Code, "08")
+	}
+	if pgconn.SafeToRetry(err) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+type backoff struct {
+	delay time.Duration
+}
+
+func newBackoff(base time.Duration) *backoff {
+	if base <= 0 {
+		base = defaultRetryBackoff
+	}
+	return &backoff{delay: base}
+}
+
+// next returns the current delay with up to 20% jitter and doubles it for the
+// following call.
+func (b *backoff) next() time.Duration {
+	d := b.delay
+	b.delay = min(b.delay*2, maxRetryBackoff)
+	return d + time.Duration(rand.Int64N(int64(d)/5+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"prodcrud/pkg/logging"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const defaultReplicaCheckPeriod = 5 * time.Second
+
+// Querier is the subset of the pool API used by repositories, satisfied by
+// both the primary and replica pools.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type replica struct {
+	pool    *pgxpool.Pool
+	host    string
+	healthy atomic.Bool
+}
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so that replica reads go to the primary instead, giving
+// read-your-writes consistency right after a write.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func usePrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryKey{}).(bool)
+	return v
+}
+
+func (d *DB) connectReplicas(ctx context.Context) error {
+	for _, dsn := range d.cfg.ReplicaDSNs {
+		config, err := d.poolConfig(dsn)
+		if err != nil {
+			return fmt.Errorf("failed to parse replica dsn: %w", err)
+		}
+		pool, err := pgxpool.NewWithConfig(ctx, config)
+		if err != nil {
+			return fmt.Errorf("failed to create replica pool: %w", err)
+		}
+		r := &replica{pool: pool, host: config.ConnConfig.Host}
+		d.replicas = append(d.replicas, r)
+		d.checkReplica(ctx, r)
+	}
+	return nil
+}
+
+// ReadReplica runs fn like Read against a healthy replica picked in round-robin
+// order, falling back to the primary when none is available or ctx asks for it.
+// A replica failing with a connection error is ejected until the monitor sees
+// it healthy again, and the retry goes to the next one.
+func (d *DB) ReadReplica(ctx context.Context, fn func(ctx context.Context, q Querier) error) error {
+	return d.Read(ctx, func(ctx context.Context) error {
+		r := d.pick(ctx)
+		if r == nil {
+			return fn(ctx, d.Pool)
+		}
+		d.metrics.replicaReads.Add(1)
+		err := fn(ctx, r.pool)
+		if err != nil && IsRetryable(err) && r.healthy.CompareAndSwap(true, false) {
+			logging.For(ctx, logPackage).Warn("replica ejected", "host", r.host, "error", err)
+		}
+		return err
+	})
+}
+
+func (d *DB) pick(ctx context.Context) *replica {
+	if len(d.replicas) == 0 || usePrimary(ctx) {
+		return nil
+	}
+	start := d.next.Add(1)
+	for i := range uint64(len(d.replicas)) {
+		r := d.replicas[(start+i)%uint64(len(d.replicas))]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// MonitorReplicas returns a worker that periodically pings the replicas and
+// brings ejected ones back once they answer and are within the allowed lag.
+func (d *DB) MonitorReplicas() *ReplicaMonitor {
+	return &ReplicaMonitor{db: d}
+}
+
+type ReplicaMonitor struct {
+	db *DB
+}
+
+func (m *ReplicaMonitor) Run(ctx context.Context) error {
+	period := m.db.cfg.ReplicaCheckPeriod
+	if period <= 0 {
+		period = defaultReplicaCheckPeriod
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			for _, r := range m.db.replicas {
+				m.db.checkReplica(ctx, r)
+			}
+		}
+	}
+}
+
+func (d *DB) checkReplica(ctx context.Context, r *replica) {
+	timeout := d.cfg.ConnectTimeout
+	if timeout <= 0 {
+		timeout = defaultReplicaCheckPeriod
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// the last replayed transaction ages while the primary is idle, so a
+	// replica that has replayed all it received is not behind
+	var lag float64
+	err := r.pool.QueryRow(ctx, `
+	SELECT CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	            ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END::float8`).Scan(&lag)
+	healthy := err == nil
+	if healthy && d.cfg.ReplicaMaxLag > 0 && time.Duration(lag*float64(time.Second)) > d.cfg.ReplicaMaxLag {
+		err = fmt.Errorf("replication lag %.1fs exceeds %s", lag, d.cfg.ReplicaMaxLag)
+		healthy = false
+	}
+
+	if was := r.healthy.Swap(healthy); was != healthy {
+		if healthy {
+			logging.For(ctx, logPackage).Info("replica is healthy", "host", r.host)
+		} else {
+			logging.For(ctx, logPackage).Warn("replica ejected", "host", r.host, "error", err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDB(replicas int) *DB {
+	d := &DB{
+		Pool: &pgxpool.Pool{},
+		cfg:  Config{ReadRetries: 2, RetryBackoff: time.Millisecond},
+	}
+	for range replicas {
+		r := &replica{pool: &pgxpool.Pool{}}
+		r.healthy.Store(true)
+		d.replicas = append(d.replicas, r)
+	}
+	return d
+}
+
+func TestDB_ReadReplica(t *testing.T) {
+	t.Run("round robin", func(t *testing.T) {
+		d := newTestDB(2)
+		var used []Querier
+		for range 4 {
+			_ = d.ReadReplica(context.Background(), func(_ context.Context, q Querier) error {
+				used = append(used, q)
+				return nil
+			})
+		}
+		assert.Equal(t, []Querier{d.replicas[1].pool, d.replicas[0].pool, d.replicas[1].pool, d.replicas[0].pool}, used)
+		assert.Equal(t, int64(4), d.metrics.replicaReads.Load())
+	})
+	t.Run("skips unhealthy replicas", func(t *testing.T) {
+		d := newTestDB(2)
+		d.replicas[1].healthy.Store(false)
+		for range 3 {
+			_ = d.ReadReplica(context.Background(), func(_ context.Context, q Querier) error {
+				assert.Same(t, d.replicas[0].pool, q)
+				return nil
+			})
+		}
+	})
+	t.Run("falls back to primary", func(t *testing.T) {
+		d := newTestDB(1)
+		d.replicas[0].healthy.Store(false)
+		_ = d.ReadReplica(context.Background(), func(_ context.Context, q Querier) error {
+			assert.Same(t, d.Pool, q)
+			return nil
+		})
+	})
+	t.Run("read your writes", func(t *testing.T) {
+		d := newTestDB(2)
+		_ = d.ReadReplica(WithPrimary(context.Background()), func(_ context.Context, q Querier) error {
+			assert.Same(t, d.Pool, q)
+			return nil
+		})
+	})
+	t.Run("ejects failing replica and retries", func(t *testing.T) {
+		d := newTestDB(2)
+		var used []Querier
+		err := d.ReadReplica(context.Background(), func(_ context.Context, q Querier) error {
+			used = append(used, q)
+			if q == d.replicas[1].pool {
+				return &pgconn.PgError{Code: "08006"}
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []Querier{d.replicas[1].pool, d.replicas[0].pool}, used)
+		assert.False(t, d.replicas[1].healthy.Load())
+		assert.True(t, d.replicas[0].healthy.Load())
+	})
+}
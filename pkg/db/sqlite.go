@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // pure Go driver, registered as "sqlite"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+
+	sqliteScheme      = "sqlite://"
+	sqliteMemory      = ":memory:"
+	sqliteBusyTimeout = 5 * time.Second
+)
+
+// DriverOf reports the backend selected by the DSN scheme: postgres:// and
+// postgresql:// select Postgres, sqlite:// selects SQLite. It returns "" for
+// anything else.
+func DriverOf(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "postgres", "postgresql":
+		return DriverPostgres
+	case "sqlite":
+		return DriverSQLite
+	}
+	return ""
+}
+
+// SQLite is a database/sql handle on a SQLite file, for single node
+// deployments without Postgres. Writers wait for each other through the busy
+// timeout instead of failing with SQLITE_BUSY, transactions take the write
+// lock when they begin.
+type SQLite struct {
+	*sql.DB
+	cfg Config
+}
+
+// OpenSQLite opens the database named by a sqlite:// DSN, e.g.
+// sqlite:///var/lib/prodcrud/data.db or sqlite://:memory:. Only MaxConns and
+// the read/write timeouts of cfg are used.
+func OpenSQLite(ctx context.Context, cfg Config) (*SQLite, error) {
+	if DriverOf(cfg.DSN) != DriverSQLite {
+		return nil, fmt.Errorf("not a sqlite dsn: %q", cfg.DSN)
+	}
+	path, _, _ := strings.Cut(strings.TrimPrefix(cfg.DSN, sqliteScheme), "?")
+	if path == "" {
+		return nil, fmt.Errorf("missing database path in %q", cfg.DSN)
+	}
+
+	pragmas := url.Values{"_pragma": {
+		fmt.Sprintf("busy_timeout(%d)", sqliteBusyTimeout.Milliseconds()),
+		"foreign_keys(1)",
+	}}
+	// a deferred transaction that reads before it writes fails with
+	// SQLITE_BUSY on the upgrade instead of waiting out the busy timeout
+	pragmas.Set("_txlock", "immediate")
+	if path != sqliteMemory {
+		pragmas["_pragma"] = append(pragmas["_pragma"], "journal_mode(WAL)")
+	}
+	conn, err := sql.Open("sqlite", "file:"+path+"?"+pragmas.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+
+	switch {
+	case path == sqliteMemory:
+		// every connection would get its own empty database
+		conn.SetMaxOpenConns(1)
+		conn.SetConnMaxLifetime(0)
+	case cfg.MaxConns > 0:
+		conn.SetMaxOpenConns(int(cfg.MaxConns))
+	}
+
+	if err := conn.PingContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+	return &SQLite{DB: conn, cfg: cfg}, nil
+}
+
+// Read runs a query under the read timeout.
+func (s *SQLite) Read(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.run(ctx, s.cfg.ReadTimeout, fn)
+}
+
+// Write runs a statement under the write timeout.
+func (s *SQLite) Write(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.run(ctx, s.cfg.WriteTimeout, fn)
+}
+
+func (s *SQLite) run(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return fn(ctx)
+}
+
+// Close closes the database, it matches the lifecycle closer signature.
+func (s *SQLite) Close() {
+	_ = s.DB.Close()
+}
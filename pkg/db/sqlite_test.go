@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriverOf(t *testing.T) {
+	assert.Equal(t, DriverPostgres, DriverOf("postgres://user@localhost/db"))
+	assert.Equal(t, DriverPostgres, DriverOf("postgresql://user@localhost/db"))
+	assert.Equal(t, DriverSQLite, DriverOf("sqlite:///var/lib/data.db"))
+	assert.Equal(t, DriverSQLite, DriverOf("sqlite://:memory:"))
+	assert.Empty(t, DriverOf("mysql://localhost/db"))
+	assert.Empty(t, DriverOf("data.db"))
+}
+
+func TestOpenSQLite(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenSQLite(ctx, Config{DSN: "sqlite://" + filepath.Join(t.TempDir(), "data.db")})
+	require.NoError(t, err)
+	defer s.Close()
+
+	var mode string
+	require.NoError(t, s.Read(ctx, func(ctx context.Context) error {
+		return s.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&mode)
+	}))
+	assert.Equal(t, "wal", mode)
+
+	_, err = OpenSQLite(ctx, Config{DSN: "postgres://localhost/db"})
+	assert.Error(t, err)
+	_, err = OpenSQLite(ctx, Config{DSN: "sqlite://"})
+	assert.Error(t, err)
+}
+
+func TestSQLite_InTx(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenSQLite(ctx, Config{DSN: "sqlite://:memory:"})
+	require.NoError(t, err)
+	defer s.Close()
+	_, err = s.ExecContext(ctx, "CREATE TABLE t (n INTEGER)")
+	require.NoError(t, err)
+
+	count := func() (n int) {
+		require.NoError(t, s.Conn(ctx).QueryRowContext(ctx, "SELECT count(*) FROM t").Scan(&n))
+		return n
+	}
+	insert := func(ctx context.Context) error {
+		_, err := s.Conn(ctx).ExecContext(ctx, "INSERT INTO t VALUES (1)")
+		return err
+	}
+
+	require.NoError(t, s.InTx(ctx, func(ctx context.Context) error {
+		if err := insert(ctx); err != nil {
+			return err
+		}
+		// a nested call joins the transaction instead of waiting for the
+		// only connection
+		return s.InTx(ctx, insert)
+	}))
+	assert.Equal(t, 2, count())
+
+	failed := errors.New("failed")
+	err = s.InTx(ctx, func(ctx context.Context) error {
+		require.NoError(t, insert(ctx))
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, 2, count(), "the insert is rolled back")
+}
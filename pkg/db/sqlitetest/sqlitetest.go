@@ -0,0 +1,40 @@
+// Package sqlitetest provides throwaway SQLite databases for tests, with the
+// SQLite migrations applied. They are closed when the test ends.
+package sqlitetest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"prodcrud/migrations"
+	"prodcrud/pkg/db"
+	"prodcrud/pkg/migration"
+)
+
+// New opens an in-memory database, which runs on a single connection.
+func New(t testing.TB) *db.SQLite {
+	t.Helper()
+	return open(t, "sqlite://:memory:")
+}
+
+// NewFile opens a database in a file of the test's temporary directory, for
+// tests that need several connections.
+func NewFile(t testing.TB) *db.SQLite {
+	t.Helper()
+	return open(t, "sqlite://"+filepath.Join(t.TempDir(), "test.db"))
+}
+
+func open(t testing.TB, dsn string) *db.SQLite {
+	t.Helper()
+	ctx := context.Background()
+	conn, err := db.OpenSQLite(ctx, db.Config{DSN: dsn})
+	if err != nil {
+		t.Fatalf("sqlitetest: %v", err)
+	}
+	t.Cleanup(conn.Close)
+	if err := migration.NewSQLite(migrations.SQLite, conn.DB).Up(ctx); err != nil {
+		t.Fatalf("sqlitetest: %v", err)
+	}
+	return conn
+}
@@ -0,0 +1,57 @@
+package db
+
+import "time"
+
+// Stats is a snapshot of the pool and query counters.
+type Stats struct {
+	MaxConns             int32         `json:"max_conns"`
+	TotalConns           int32         `json:"total_conns"`
+	AcquiredConns        int32         `json:"acquired_conns"`
+	IdleConns            int32         `json:"idle_conns"`
+	ConstructingConns    int32         `json:"constructing_conns"`
+	AcquireCount         int64         `json:"acquire_count"`
+	AcquireDuration      time.Duration `json:"acquire_duration"`
+	EmptyAcquireCount    int64         `json:"empty_acquire_count"`
+	CanceledAcquireCount int64         `json:"canceled_acquire_count"`
+	NewConnsCount        int64         `json:"new_conns_count"`
+	LifetimeDestroyCount int64         `json:"max_lifetime_destroy_count"`
+	IdleDestroyCount     int64         `json:"max_idle_destroy_count"`
+	Reads                int64         `json:"reads"`
+	Writes               int64         `json:"writes"`
+	Retries              int64         `json:"retries"`
+	Timeouts             int64         `json:"timeouts"`
+	ReplicaReads         int64         `json:"replica_reads"`
+	Replicas             int           `json:"replicas"`
+	HealthyReplicas      int           `json:"healthy_replicas"`
+}
+
+func (d *DB) Stats() Stats {
+	s := d.Stat()
+	healthy := 0
+	for _, r := range d.replicas {
+		if r.healthy.Load() {
+			healthy++
+		}
+	}
+	return Stats{
+		MaxConns:             s.MaxConns(),
+		TotalConns:           s.TotalConns(),
+		AcquiredConns:        s.AcquiredConns(),
+		IdleConns:            s.IdleConns(),
+		ConstructingConns:    s.ConstructingConns(),
+		AcquireCount:         s.AcquireCount(),
+		AcquireDuration:      s.AcquireDuration(),
+		EmptyAcquireCount:    s.EmptyAcquireCount(),
+		CanceledAcquireCount: s.CanceledAcquireCount(),
+		NewConnsCount:        s.NewConnsCount(),
+		LifetimeDestroyCount: s.MaxLifetimeDestroyCount(),
+		IdleDestroyCount:     s.MaxIdleDestroyCount(),
+		Reads:                d.metrics.reads.Load(),
+		Writes:               d.metrics.writes.Load(),
+		Retries:              d.metrics.retries.Load(),
+		Timeouts:             d.metrics.timeouts.Load(),
+		ReplicaReads:         d.metrics.replicaReads.Load(),
+		Replicas:             len(d.replicas),
+		HealthyReplicas:      healthy,
+	}
+}
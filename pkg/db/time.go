@@ -0,0 +1,10 @@
+package db
+
+import "time"
+
+// Now is the current time in UTC truncated to microseconds, the resolution
+// Postgres stores, for the timestamps that the memory and SQLite
+// repositories set themselves.
+func Now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Transactor runs fn in a transaction shared by the repositories of a store:
+// they find it in the context fn receives, so their statements commit or roll
+// back together. A nested call joins the outer transaction.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type (
+	pgTxKey     struct{}
+	sqliteTxKey struct{}
+	memoryTxKey struct{}
+)
+
+// MemoryTx is the Transactor of the memory repositories, whose writes apply at
+// once: it runs one fn at a time, and if fn fails, undoes in reverse order
+// the writes the repositories recorded with OnRollback. Writes outside of InTx
+// are not held back by it.
+type MemoryTx struct {
+	mu sync.Mutex
+}
+
+// memoryTx is the undo log of a MemoryTx.InTx call.
+type memoryTx struct {
+	undo []func()
+}
+
+func (t *MemoryTx) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		return fn(ctx)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tx := &memoryTx{}
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, tx)); err != nil {
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+		return err
+	}
+	return nil
+}
+
+// OnRollback records how to undo a write of a memory repository, should the
+// MemoryTx.InTx call running in ctx fail. Outside of one, it does nothing.
+func OnRollback(ctx context.Context, undo func()) {
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		tx.undo = append(tx.undo, undo)
+	}
+}
+
+// Conn is what a repository queries on Postgres: the transaction of InTx, or
+// the primary pool outside of one. Begin opens a savepoint in a transaction.
+type Conn interface {
+	Querier
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// InTx runs fn in a transaction on the primary under the write timeout, and
+// commits if fn returns nil.
+func (d *DB) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(pgTxKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+	return d.Write(ctx, func(ctx context.Context) error {
+		return pgx.BeginFunc(ctx, d.Pool, func(tx pgx.Tx) error {
+			return fn(context.WithValue(ctx, pgTxKey{}, tx))
+		})
+	})
+}
+
+// Conn returns the transaction InTx put in ctx, or the primary pool.
+func (d *DB) Conn(ctx context.Context) Conn {
+	if tx, ok := ctx.Value(pgTxKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return d.Pool
+}
+
+// SQLConn is what a repository queries on SQLite: the transaction of InTx, or
+// the database outside of one.
+type SQLConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// InTx runs fn in a transaction under the write timeout, and commits if fn
+// returns nil. Inside fn every statement must go through Conn: with a single
+// connection, as for :memory:, another one would wait for the transaction.
+func (s *SQLite) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(sqliteTxKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+	return s.Write(ctx, func(ctx context.Context) error {
+		tx, err := s.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if err := fn(context.WithValue(ctx, sqliteTxKey{}, tx)); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// Conn returns the transaction InTx put in ctx, or the database.
+func (s *SQLite) Conn(ctx context.Context) SQLConn {
+	if tx, ok := ctx.Value(sqliteTxKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return s.DB
+}
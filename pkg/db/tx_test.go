@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryTx_InTx(t *testing.T) {
+	ctx := context.Background()
+	tx := &MemoryTx{}
+	var n int
+	add := func(ctx context.Context, delta int) {
+		n += delta
+		OnRollback(ctx, func() { n -= delta })
+	}
+
+	require.NoError(t, tx.InTx(ctx, func(ctx context.Context) error {
+		add(ctx, 1)
+		// a nested call joins the transaction instead of waiting for it
+		return tx.InTx(ctx, func(ctx context.Context) error {
+			add(ctx, 2)
+			return nil
+		})
+	}))
+	assert.Equal(t, 3, n)
+
+	failed := errors.New("failed")
+	err := tx.InTx(ctx, func(ctx context.Context) error {
+		add(ctx, 4)
+		return tx.InTx(ctx, func(ctx context.Context) error {
+			add(ctx, 8)
+			return failed
+		})
+	})
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, 3, n, "both writes are undone")
+
+	add(ctx, 1)
+	assert.Equal(t, 4, n, "a write outside of a transaction is kept")
+}
@@ -0,0 +1,179 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const DefaultShutdownTimeout = 15 * time.Second
+
+// Hook is a single component managed by the Manager. Start must return once the
+// component is running, Stop must release it before ctx expires. Either may be nil.
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// Worker is a background job that runs until its context is cancelled.
+type Worker interface {
+	Run(ctx context.Context) error
+}
+
+type Manager struct {
+	hooks   []Hook
+	signals []os.Signal
+	timeout time.Duration
+	errc    chan error
+}
+
+func New(timeout time.Duration) *Manager {
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	return &Manager{
+		signals: []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		timeout: timeout,
+		errc:    make(chan error, 1),
+	}
+}
+
+// Append registers hooks. They are started in the order given and stopped in reverse.
+func (m *Manager) Append(hooks ...Hook) {
+	m.hooks = append(m.hooks, hooks...)
+}
+
+// Server registers an HTTP server. The listener is opened during Start so bind
+// errors abort the startup, and Stop drains in-flight requests.
+func (m *Manager) Server(srv *http.Server) {
+	m.Append(Hook{
+		Name: "http server",
+		Start: func(ctx context.Context) error {
+			var lc net.ListenConfig
+			ln, err := lc.Listen(ctx, "tcp", srv.Addr)
+			if err != nil {
+				return fmt.Errorf("failed to listen on %s: %w", srv.Addr, err)
+			}
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					m.fail(fmt.Errorf("http server: %w", err))
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			if err := srv.Shutdown(ctx); err != nil {
+				return fmt.Errorf("failed to drain connections: %w", err)
+			}
+			return nil
+		},
+	})
+}
+
+// Worker registers a background worker. Its context is cancelled on shutdown and
+// Stop waits for Run to return. A worker returning an error triggers a shutdown.
+func (m *Manager) Worker(name string, w Worker) {
+	var (
+		cancel context.CancelFunc
+		done   chan struct{}
+	)
+	m.Append(Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			var runCtx context.Context
+			runCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+			done = make(chan struct{})
+			go func() {
+				defer close(done)
+				if err := w.Run(runCtx); err != nil && !errors.Is(err, context.Canceled) {
+					m.fail(fmt.Errorf("%s: %w", name, err))
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("worker did not stop: %w", ctx.Err())
+			}
+		},
+	})
+}
+
+// Closer registers a resource that only needs to be released on shutdown, such as a pool.
+func (m *Manager) Closer(name string, closeFn func()) {
+	m.Append(Hook{
+		Name: name,
+		Stop: func(context.Context) error {
+			closeFn()
+			return nil
+		},
+	})
+}
+
+// Run starts every hook and blocks until ctx is cancelled, a trapped signal arrives
+// or a component fails. Started hooks are then stopped in reverse order within the
+// shutdown timeout.
+func (m *Manager) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, m.signals...)
+	defer stop()
+
+	var (
+		runErr  error
+		started int
+	)
+	for _, h := range m.hooks {
+		if h.Start != nil {
+			if err := h.Start(ctx); err != nil {
+				runErr = fmt.Errorf("failed to start %s: %w", h.Name, err)
+				break
+			}
+		}
+		started++
+	}
+
+	if runErr == nil {
+		select {
+		case <-ctx.Done():
+		case runErr = <-m.errc:
+		}
+	}
+	stop()
+
+	return errors.Join(runErr, m.shutdown(context.WithoutCancel(ctx), started))
+}
+
+func (m *Manager) shutdown(ctx context.Context, started int) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	var errs []error
+	for i := started - 1; i >= 0; i-- {
+		h := m.hooks[i]
+		if h.Stop == nil {
+			continue
+		}
+		if err := h.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", h.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fail reports an asynchronous component failure. Only the first one is kept.
+func (m *Manager) fail(err error) {
+	select {
+	case m.errc <- err:
+	default:
+	}
+}
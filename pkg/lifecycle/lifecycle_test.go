@@ -0,0 +1,182 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) add(s string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, s)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+type workerFunc func(ctx context.Context) error
+
+func (f workerFunc) Run(ctx context.Context) error { return f(ctx) }
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+	return addr
+}
+
+func TestManager_StopOrder(t *testing.T) {
+	rec := &recorder{}
+	m := New(time.Second)
+	m.Closer("pool", func() { rec.add("close pool") })
+	for _, name := range []string{"first", "second"} {
+		m.Worker(name, workerFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			rec.add("stop " + name)
+			return ctx.Err()
+		}))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{"stop second", "stop first", "close pool"}, rec.get())
+}
+
+func TestManager_DrainsInFlightRequests(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	srv := &http.Server{
+		Addr: freeAddr(t),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			close(entered)
+			<-release
+			_, _ = io.WriteString(w, "done")
+		}),
+		ReadHeaderTimeout: time.Second,
+	}
+	m := New(2 * time.Second)
+	m.Server(srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Run(ctx) }()
+
+	respc := make(chan string, 1)
+	go func() {
+		var resp *http.Response
+		var err error
+		for range 50 {
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://"+srv.Addr, nil)
+			resp, err = http.DefaultClient.Do(req)
+			if err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			respc <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		respc <- string(body)
+	}()
+
+	<-entered
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	assert.Equal(t, "done", <-respc)
+	assert.NoError(t, <-done)
+}
+
+func TestManager_ShutdownDeadline(t *testing.T) {
+	m := New(50 * time.Millisecond)
+	m.Worker("stuck", workerFunc(func(context.Context) error {
+		select {}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := m.Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "failed to stop stuck")
+}
+
+func TestManager_StartFailureStopsStarted(t *testing.T) {
+	rec := &recorder{}
+	m := New(time.Second)
+	m.Closer("pool", func() { rec.add("close pool") })
+	m.Append(Hook{
+		Name:  "broken",
+		Start: func(context.Context) error { return errors.New("boom") },
+		Stop:  func(context.Context) error { rec.add("stop broken"); return nil },
+	})
+
+	err := m.Run(context.Background())
+	assert.EqualError(t, err, "failed to start broken: boom")
+	assert.Equal(t, []string{"close pool"}, rec.get())
+}
+
+func TestManager_WorkerFailureTriggersShutdown(t *testing.T) {
+	rec := &recorder{}
+	m := New(time.Second)
+	m.Closer("pool", func() { rec.add("close pool") })
+	m.Worker("failing", workerFunc(func(context.Context) error {
+		return errors.New("boom")
+	}))
+
+	err := m.Run(context.Background())
+	assert.EqualError(t, err, "failing: boom")
+	assert.Equal(t, []string{"close pool"}, rec.get())
+}
+
+func TestManager_TrapsSIGTERM(t *testing.T) {
+	rec := &recorder{}
+	m := New(time.Second)
+	m.Closer("pool", func() { rec.add("close pool") })
+	m.Append(Hook{
+		Name: "signal",
+		Start: func(context.Context) error {
+			return syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+		},
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- m.Run(context.Background()) }()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("manager did not stop on SIGTERM")
+	}
+	assert.Equal(t, []string{"close pool"}, rec.get())
+}
@@ -0,0 +1,178 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// PackageKey is the attribute that selects the per-package level, set by For.
+const PackageKey = "package"
+
+// DefaultPackage is the name under which the default level is reported.
+const DefaultPackage = "default"
+
+var sensitive = map[string]bool{
+	"password":      true,
+	"passwd":        true,
+	"secret":        true,
+	"token":         true,
+	"authorization": true,
+	"cookie":        true,
+	"dsn":           true,
+	"api_key":       true,
+}
+
+// Registry holds the JSON handler and the log levels, which can be changed at
+// runtime per package.
+type Registry struct {
+	mu     sync.RWMutex
+	def    *slog.LevelVar
+	levels map[string]*slog.LevelVar
+	out    slog.Handler
+}
+
+// New creates a registry writing JSON lines to w. levels overrides the default
+// level for individual packages.
+func New(w io.Writer, level slog.Level, levels map[string]slog.Level) *Registry {
+	r := &Registry{
+		def:    new(slog.LevelVar),
+		levels: make(map[string]*slog.LevelVar, len(levels)),
+	}
+	r.def.Set(level)
+	for pkg, l := range levels {
+		r.SetLevel(pkg, l)
+	}
+	r.out = slog.NewJSONHandler(w, &slog.HandlerOptions{
+		AddSource:   false,
+		Level:       slog.LevelDebug,
+		ReplaceAttr: redact,
+	})
+	return r
+}
+
+// Logger returns the root logger. Install it with slog.SetDefault so the log
+// package and FromContext fall back to it.
+func (r *Registry) Logger() *slog.Logger {
+	return slog.New(&handler{registry: r, next: r.out})
+}
+
+// SetLevel changes the level of pkg, or the default one for DefaultPackage.
+func (r *Registry) SetLevel(pkg string, level slog.Level) {
+	if pkg == "" || pkg == DefaultPackage {
+		r.def.Set(level)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if v, ok := r.levels[pkg]; ok {
+		v.Set(level)
+		return
+	}
+	v := new(slog.LevelVar)
+	v.Set(level)
+	r.levels[pkg] = v
+}
+
+// Levels reports the default level and every package override.
+func (r *Registry) Levels() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[string]string, len(r.levels)+1)
+	out[DefaultPackage] = r.def.Level().String()
+	for pkg, v := range r.levels {
+		out[pkg] = v.Level().String()
+	}
+	return out
+}
+
+func (r *Registry) level(pkg string) slog.Level {
+	if pkg != "" {
+		r.mu.RLock()
+		v, ok := r.levels[pkg]
+		r.mu.RUnlock()
+		if ok {
+			return v.Level()
+		}
+	}
+	return r.def.Level()
+}
+
+// ParseLevels parses "pkg=level" pairs such as repository/product=debug.
+func ParseLevels(pairs []string) (map[string]slog.Level, error) {
+	out := make(map[string]slog.Level, len(pairs))
+	for _, pair := range pairs {
+		pkg, lvl, ok := strings.Cut(pair, "=")
+		if !ok || pkg == "" {
+			return nil, fmt.Errorf("invalid package level %q, expected pkg=level", pair)
+		}
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(lvl)); err != nil {
+			return nil, fmt.Errorf("invalid level for %s: %w", pkg, err)
+		}
+		out[pkg] = l
+	}
+	return out, nil
+}
+
+// handler filters records by the level of the package recorded through
+// WithAttrs and forwards them to the JSON handler.
+type handler struct {
+	registry *Registry
+	next     slog.Handler
+	pkg      string
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.registry.level(h.pkg)
+}
+
+//nolint:gocritic //slog.Handler signature
+func (h *handler) Handle(ctx context.Context, rec slog.Record) error {
+	return h.next.Handle(ctx, rec) //nolint:wrapcheck //handler chain
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	pkg := h.pkg
+	for _, a := range attrs {
+		if a.Key == PackageKey {
+			pkg = a.Value.String()
+		}
+	}
+	return &handler{registry: h.registry, next: h.next.WithAttrs(attrs), pkg: pkg}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{registry: h.registry, next: h.next.WithGroup(name), pkg: h.pkg}
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if sensitive[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, "[REDACTED]")
+	}
+	return a
+}
+
+type loggerKey struct{}
+
+// WithLogger stores the request scoped logger in ctx.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the request scoped logger, or the default one.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// For returns the request scoped logger tagged with pkg, so its level can be
+// tuned separately.
+func For(ctx context.Context, pkg string) *slog.Logger {
+	return FromContext(ctx).With(PackageKey, pkg)
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestRegistry_PackageLevels(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, slog.LevelInfo, map[string]slog.Level{"repository/product": slog.LevelDebug})
+	ctx := WithLogger(context.Background(), r.Logger().With("request_id", "abc"))
+
+	For(ctx, "repository/product").Debug("visible")
+	For(ctx, "usecase/product").Debug("hidden")
+	For(ctx, "usecase/product").Info("info")
+
+	got := lines(t, &buf)
+	require.Len(t, got, 2)
+	assert.Equal(t, "visible", got[0]["msg"])
+	assert.Equal(t, "abc", got[0]["request_id"])
+	assert.Equal(t, "repository/product", got[0]["package"])
+	assert.Equal(t, "info", got[1]["msg"])
+}
+
+func TestRegistry_SetLevelAtRuntime(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, slog.LevelInfo, nil)
+	logger := r.Logger().With(PackageKey, "usecase/product")
+
+	logger.Debug("before")
+	r.SetLevel("usecase/product", slog.LevelDebug)
+	logger.Debug("after")
+	r.SetLevel(DefaultPackage, slog.LevelError)
+	r.Logger().Warn("default raised")
+
+	got := lines(t, &buf)
+	require.Len(t, got, 1)
+	assert.Equal(t, "after", got[0]["msg"])
+	assert.Equal(t, map[string]string{"default": "ERROR", "usecase/product": "DEBUG"}, r.Levels())
+}
+
+func TestRegistry_RedactsSensitiveFields(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, slog.LevelInfo, nil)
+
+	r.Logger().Info("login", "user", "bob", "password", "hunter2", "Authorization", "Bearer x")
+
+	got := lines(t, &buf)
+	require.Len(t, got, 1)
+	assert.Equal(t, "bob", got[0]["user"])
+	assert.Equal(t, "[REDACTED]", got[0]["password"])
+	assert.Equal(t, "[REDACTED]", got[0]["Authorization"])
+}
+
+func TestParseLevels(t *testing.T) {
+	levels, err := ParseLevels([]string{"repository/product=debug", "rest=warn"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]slog.Level{"repository/product": slog.LevelDebug, "rest": slog.LevelWarn}, levels)
+
+	_, err = ParseLevels([]string{"rest"})
+	assert.Error(t, err)
+	_, err = ParseLevels([]string{"rest=loud"})
+	assert.Error(t, err)
+}
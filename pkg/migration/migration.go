@@ -1,21 +1,238 @@
 package migration
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"prodcrud/pkg/db"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
 )
 
-func Migrate(file, dsn string) error {
-	m, err := migrate.New(file, dsn)
+// lockID is the key of the session advisory lock held while migrating, so
+// replicas booting together or an operator running the CLI do not race.
+const lockID int64 = 0x70726f6463727564 // "prodcrud"
+
+// DefaultLockTimeout is used when Migrator.LockTimeout is not set.
+const DefaultLockTimeout = time.Minute
+
+// Migrator applies the migrations of an fs.FS to a database.
+type Migrator struct {
+	fsys fs.FS
+	dsn  string
+	// sqlite is set for the SQLite backend, which is migrated through the
+	// application's own handle: a :memory: database exists only there.
+	sqlite *sql.DB
+	// LockTimeout bounds the wait for another migration to finish.
+	LockTimeout time.Duration
+}
+
+func New(fsys fs.FS, dsn string) *Migrator {
+	return &Migrator{fsys: fsys, dsn: dsn, LockTimeout: DefaultLockTimeout}
+}
+
+// NewSQLite returns a migrator for a SQLite database. SQLite is single node,
+// so no advisory lock is taken.
+func NewSQLite(fsys fs.FS, conn *sql.DB) *Migrator {
+	return &Migrator{fsys: fsys, sqlite: conn}
+}
+
+// Migrate applies every pending migration.
+func Migrate(fsys fs.FS, dsn string) error {
+	return New(fsys, dsn).Up(context.Background())
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.run(ctx, "migrate", func(mg *migrate.Migrate) error {
+		return mg.Up()
+	})
+}
+
+// Down rolls back the given number of migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+	return m.run(ctx, "roll back", func(mg *migrate.Migrate) error {
+		return mg.Steps(-steps)
+	})
+}
+
+// Goto migrates up or down to the given version.
+func (m *Migrator) Goto(ctx context.Context, version uint) error {
+	return m.run(ctx, "migrate to version", func(mg *migrate.Migrate) error {
+		return mg.Migrate(version)
+	})
+}
+
+// Force sets the version without running migrations, clearing the dirty flag
+// after a failed migration was fixed by hand. -1 means no version.
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	return m.run(ctx, "force version", func(mg *migrate.Migrate) error {
+		return mg.Force(version)
+	})
+}
+
+// Version reports the current schema version. ok is false when no migration
+// has been applied yet.
+func (m *Migrator) Version(ctx context.Context) (version uint, dirty, ok bool, err error) {
+	err = m.run(ctx, "read version", func(mg *migrate.Migrate) error {
+		var verr error
+		version, dirty, verr = mg.Version()
+		if errors.Is(verr, migrate.ErrNilVersion) {
+			return nil
+		}
+		ok = verr == nil
+		return verr
+	})
+	return version, dirty, ok, err
+}
+
+func (m *Migrator) run(ctx context.Context, op string, fn func(*migrate.Migrate) error) error {
+	src, err := iofs.New(m.fsys, ".")
 	if err != nil {
-		return fmt.Errorf("failed to create migration: %w", err)
+		return fmt.Errorf("failed to open migration source: %w", err)
+	}
+
+	var mg *migrate.Migrate
+	if m.sqlite != nil {
+		driver, err := sqlite.WithInstance(m.sqlite, &sqlite.Config{})
+		if err != nil {
+			return fmt.Errorf("failed to create migration: %w", err)
+		}
+		mg, err = migrate.NewWithInstance("iofs", src, db.DriverSQLite, driver)
+		if err != nil {
+			return fmt.Errorf("failed to create migration: %w", err)
+		}
+		// closing mg would close the application's handle as well
+		defer src.Close()
+	} else {
+		unlock, err := m.lock(ctx)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		mg, err = migrate.NewWithSourceInstance("iofs", src, m.dsn)
+		if err != nil {
+			return fmt.Errorf("failed to create migration: %w", err)
+		}
+		defer mg.Close()
 	}
-	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("failed to migrate: %w", err)
+
+	if err := fn(mg); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to %s: %w", op, err)
 	}
 	return nil
 }
+
+// lock takes the advisory lock on a dedicated connection and returns the
+// function releasing it.
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	timeout := m.LockTimeout
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, m.dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect for migration lock: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		_ = conn.Close(context.Background())
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("another migration is running, gave up after %s", timeout)
+		}
+		return nil, fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, _ = conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", lockID)
+		_ = conn.Close(ctx)
+	}, nil
+}
+
+// LatestVersion returns the highest migration version available in fsys.
+func LatestVersion(fsys fs.FS) (uint, error) {
+	src, err := iofs.New(fsys, ".")
+	if err != nil {
+		return 0, fmt.Errorf("failed to open migration source: %w", err)
+	}
+	defer src.Close()
+	return latest(src)
+}
+
+func latest(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migration source: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migration source: %w", err)
+		}
+		version = next
+	}
+}
+
+var (
+	validName = regexp.MustCompile(`^[a-z0-9_]+$`)
+	fileName  = regexp.MustCompile(`^(\d+)_.*\.(up|down)\.sql$`)
+)
+
+// Create writes an empty up/down pair numbered after the latest migration in
+// dir and returns the paths of the new files.
+func Create(dir, name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q, use lowercase letters, digits and underscores", name)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	var last uint64
+	for _, e := range entries {
+		match := fileName.FindStringSubmatch(e.Name())
+		if match == nil {
+			continue
+		}
+		if v, err := strconv.ParseUint(match[1], 10, 64); err == nil && v > last {
+			last = v
+		}
+	}
+
+	paths := make([]string, 0, 2)
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%06d_%s.%s.sql", last+1, name, direction))
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		_ = f.Close()
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,98 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"prodcrud/migrations"
+	"prodcrud/pkg/db"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000001_init.up.sql":     {Data: []byte("SELECT 1")},
+		"000001_init.down.sql":   {Data: []byte("SELECT 1")},
+		"000003_prices.up.sql":   {Data: []byte("SELECT 1")},
+		"000003_prices.down.sql": {Data: []byte("SELECT 1")},
+	}
+	version, err := LatestVersion(fsys)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), version)
+
+	_, err = LatestVersion(fstest.MapFS{})
+	assert.Error(t, err)
+}
+
+func TestLatestVersion_Embedded(t *testing.T) {
+	version, err := LatestVersion(migrations.FS)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, version, uint(1))
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "000007_init.up.sql"), nil, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), nil, 0o600))
+
+	paths, err := Create(dir, "Add_Prices")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "000008_add_prices.up.sql"),
+		filepath.Join(dir, "000008_add_prices.down.sql"),
+	}, paths)
+
+	_, err = Create(dir, "bad name;")
+	assert.Error(t, err)
+}
+
+// TestSQLite_StockCheck runs the migration loosening the products check in
+// place, which every pooled connection must see.
+func TestSQLite_StockCheck(t *testing.T) {
+	ctx := context.Background()
+	conn, err := db.OpenSQLite(ctx, db.Config{DSN: "sqlite://" + filepath.Join(t.TempDir(), "stock.db"), MaxConns: 4})
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+	m := NewSQLite(migrations.SQLite, conn.DB)
+	require.NoError(t, m.Goto(ctx, 10))
+	_, err = conn.Exec(`INSERT INTO products (name, price, quantity, description, created_at, updated_at)
+	VALUES ('pen', 100, 1, '', '2025-01-01', '2025-01-01')`)
+	require.NoError(t, err)
+	// keep connections busy so that the pool holds several with a parsed schema
+	held := make([]*sql.Conn, 3)
+	for i := range held {
+		held[i], err = conn.DB.Conn(ctx)
+		require.NoError(t, err)
+		_, err = held[i].ExecContext(ctx, `UPDATE products SET quantity = 1`)
+		require.NoError(t, err)
+	}
+	_, err = held[0].ExecContext(ctx, `UPDATE products SET quantity = 0`)
+	require.Error(t, err)
+	for _, c := range held {
+		require.NoError(t, c.Close())
+	}
+
+	require.NoError(t, m.Goto(ctx, 11))
+	for range 4 {
+		_, err = conn.Exec(`UPDATE products SET quantity = 0`)
+		require.NoError(t, err, "every connection sees the new check")
+		_, err = conn.Exec(`UPDATE products SET quantity = -1`)
+		require.Error(t, err)
+	}
+
+	assert.Error(t, m.Down(ctx, 1), "a product is out of stock")
+	_, dirty, _, err := m.Version(ctx)
+	require.NoError(t, err)
+	require.True(t, dirty)
+	require.NoError(t, m.Force(ctx, 11), "the failed down rolled back")
+	_, err = conn.Exec(`UPDATE products SET quantity = 1`)
+	require.NoError(t, err)
+	require.NoError(t, m.Down(ctx, 1))
+	_, err = conn.Exec(`UPDATE products SET quantity = 0`)
+	assert.Error(t, err)
+}
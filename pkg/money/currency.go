@@ -0,0 +1,45 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownCurrency is returned for codes missing from the ISO 4217 table below.
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// Currency is an ISO 4217 currency. Exponent is the number of digits after the
+// decimal point of its minor unit: 2 for USD cents, 0 for JPY.
+type Currency struct {
+	Code     string
+	Exponent int
+}
+
+var currencies = map[string]Currency{
+	"AED": {Code: "AED", Exponent: 2},
+	"BHD": {Code: "BHD", Exponent: 3},
+	"CHF": {Code: "CHF", Exponent: 2},
+	"CNY": {Code: "CNY", Exponent: 2},
+	"EUR": {Code: "EUR", Exponent: 2},
+	"GBP": {Code: "GBP", Exponent: 2},
+	"JPY": {Code: "JPY", Exponent: 0},
+	"KGS": {Code: "KGS", Exponent: 2},
+	"KRW": {Code: "KRW", Exponent: 0},
+	"KWD": {Code: "KWD", Exponent: 3},
+	"KZT": {Code: "KZT", Exponent: 2},
+	"RUB": {Code: "RUB", Exponent: 2},
+	"TJS": {Code: "TJS", Exponent: 2},
+	"TRY": {Code: "TRY", Exponent: 2},
+	"USD": {Code: "USD", Exponent: 2},
+	"UZS": {Code: "UZS", Exponent: 2},
+}
+
+// LookupCurrency returns the currency with the given code, case insensitive.
+func LookupCurrency(code string) (Currency, error) {
+	c, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w %q", ErrUnknownCurrency, code)
+	}
+	return c, nil
+}
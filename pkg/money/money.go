@@ -0,0 +1,178 @@
+// Package money holds exact monetary amounts: an integer count of minor units
+// in an ISO 4217 currency, so no value ever goes through a float.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+var (
+	// ErrCurrencyMismatch is returned when combining amounts in different currencies.
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrOverflow is returned when a result does not fit in int64 minor units.
+	ErrOverflow = errors.New("amount out of range")
+	// ErrInvalidAmount is returned for malformed decimal amounts or ones with
+	// more fraction digits than the currency has.
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+// Money is an amount in minor units of a currency: 1234 USD is $12.34.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// New returns amount minor units of currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Parse reads a decimal amount in major units, such as "12.34" or "-5", in
+// the given currency.
+func Parse(amount, currency string) (Money, error) {
+	c, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	s := strings.TrimSpace(amount)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !digits(whole) || !digits(frac) {
+		return Money{}, fmt.Errorf("%w %q", ErrInvalidAmount, amount)
+	}
+	if len(frac) > c.Exponent {
+		return Money{}, fmt.Errorf("%w %q: %s has %d decimal places", ErrInvalidAmount, amount, c.Code, c.Exponent)
+	}
+	frac += strings.Repeat("0", c.Exponent-len(frac))
+
+	n, ok := new(big.Int).SetString("0"+whole+frac, 10)
+	if !ok {
+		return Money{}, fmt.Errorf("%w %q", ErrInvalidAmount, amount)
+	}
+	if neg {
+		n.Neg(n)
+	}
+	if !n.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s %s", ErrOverflow, amount, c.Code)
+	}
+	return Money{Amount: n.Int64(), Currency: c.Code}, nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsZero reports whether m is the zero value, which PATCH style updates use
+// for "not set".
+func (m Money) IsZero() bool {
+	return m.Amount == 0 && m.Currency == ""
+}
+
+// IsPositive reports whether the amount is above zero.
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// Decimal formats the amount in major units with exactly the currency's
+// number of decimal places, e.g. "12.30" for 1230 USD.
+func (m Money) Decimal() string {
+	exp := 2
+	if c, err := LookupCurrency(m.Currency); err == nil {
+		exp = c.Exponent
+	}
+	n := new(big.Int).SetInt64(m.Amount)
+	neg := n.Sign() < 0
+	s := n.Abs(n).String()
+	if exp > 0 {
+		if len(s) <= exp {
+			s = strings.Repeat("0", exp-len(s)+1) + s
+		}
+		s = s[:len(s)-exp] + "." + s[len(s)-exp:]
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+// String formats m as "12.34 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// Add returns m + o, both in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Sub returns m - o, both in the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if o.Amount == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(Money{Amount: -o.Amount, Currency: o.Currency})
+}
+
+// Mul returns m multiplied by n, e.g. a unit price by a quantity.
+func (m Money) Mul(n int64) (Money, error) {
+	p := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(n))
+	if !p.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: p.Int64(), Currency: m.Currency}, nil
+}
+
+// JSON is the wire form of Money: the amount is a decimal string in major
+// units, so clients never parse it as a float.
+type JSON struct {
+	Amount   string `json:"amount" example:"12.34"`
+	Currency string `json:"currency" example:"USD"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	//nolint:wrapcheck //plain struct
+	return json.Marshal(JSON{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts the amount as a decimal string or a JSON number; the
+// number is read from its literal text, never as a float.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Amount   json.RawMessage `json:"amount"`
+		Currency string          `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("money must be an object with amount and currency: %w", err)
+	}
+	if raw.Currency == "" {
+		return errors.New("money: currency is required")
+	}
+	amount := strings.Trim(string(raw.Amount), `"`)
+	if amount == "" {
+		return errors.New("money: amount is required")
+	}
+	parsed, err := Parse(amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
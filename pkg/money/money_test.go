@@ -0,0 +1,166 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount, currency string
+		want             Money
+	}{
+		{"12.34", "USD", New(1234, "USD")},
+		{"12.3", "usd", New(1230, "USD")},
+		{"-0.05", "EUR", New(-5, "EUR")},
+		{"1500", "JPY", New(1500, "JPY")},
+		{"1.005", "KWD", New(1005, "KWD")},
+		{"92233720368547758.07", "USD", New(math.MaxInt64, "USD")},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.amount, tt.currency)
+		require.NoError(t, err, tt.amount)
+		assert.Equal(t, tt.want, got, tt.amount)
+	}
+
+	for _, bad := range []struct{ amount, currency string }{
+		{"1.234", "USD"}, {"1.5", "JPY"}, {"abc", "USD"}, {"", "USD"}, {"1e3", "USD"},
+		{"92233720368547758.08", "USD"}, {"1", "XXX"},
+	} {
+		_, err := Parse(bad.amount, bad.currency)
+		assert.Error(t, err, bad.amount+" "+bad.currency)
+	}
+}
+
+func TestMoney_Format(t *testing.T) {
+	assert.Equal(t, "12.34 USD", New(1234, "USD").String())
+	assert.Equal(t, "0.05", New(5, "USD").Decimal())
+	assert.Equal(t, "-0.05", New(-5, "USD").Decimal())
+	assert.Equal(t, "1500", New(1500, "JPY").Decimal())
+	assert.Equal(t, "0.001", New(1, "KWD").Decimal())
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	sum, err := New(150, "USD").Add(New(250, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, New(400, "USD"), sum)
+
+	_, err = New(1, "USD").Add(New(1, "EUR"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = New(math.MaxInt64, "USD").Add(New(1, "USD"))
+	assert.ErrorIs(t, err, ErrOverflow)
+
+	diff, err := New(100, "USD").Sub(New(250, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, New(-150, "USD"), diff)
+
+	total, err := New(1999, "USD").Mul(3)
+	require.NoError(t, err)
+	assert.Equal(t, New(5997, "USD"), total)
+	_, err = New(math.MaxInt64/2+1, "USD").Mul(2)
+	assert.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(New(1234500, "UZS"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"12345.00","currency":"UZS"}`, string(data))
+
+	var m Money
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":"19.99","currency":"USD"}`), &m))
+	assert.Equal(t, New(1999, "USD"), m)
+	require.NoError(t, json.Unmarshal([]byte(`{"amount":0.1,"currency":"EUR"}`), &m))
+	assert.Equal(t, New(10, "EUR"), m)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"1"}`), &m))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"1.001","currency":"USD"}`), &m))
+	assert.Error(t, json.Unmarshal([]byte(`1000`), &m))
+}
+
+func TestRate_Convert(t *testing.T) {
+	usdUzs := MustParseRate("12650.5")
+
+	got, err := usdUzs.Convert(New(1999, "USD"), "UZS")
+	require.NoError(t, err)
+	// 19.99 * 12650.5 = 252883.495 UZS, rounded half away from zero
+	assert.Equal(t, New(25288350, "UZS"), got)
+
+	back, err := usdUzs.Inverse().Convert(got, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, New(1999, "USD"), back)
+
+	yen, err := MustParseRate("151.37").Convert(New(1000, "USD"), "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, New(1514, "JPY"), yen, "exponent 2 to 0")
+
+	_, err = usdUzs.Convert(New(1, "USD"), "XXX")
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+}
+
+func TestMoney_Percent(t *testing.T) {
+	got, err := New(1999, "USD").Percent(MustParseRate("15"))
+	require.NoError(t, err)
+	// 15% of 19.99 = 2.9985, rounded half away from zero
+	assert.Equal(t, New(300, "USD"), got)
+
+	got, err = New(1000, "JPY").Percent(MustParseRate("12.5"))
+	require.NoError(t, err)
+	assert.Equal(t, New(125, "JPY"), got)
+
+	assert.Equal(t, 1, MustParseRate("100.5").Cmp(MustParseRate("100")))
+	assert.Equal(t, 0, MustParseRate("100.0").Cmp(MustParseRate("100")))
+}
+
+func TestMoney_Rounding(t *testing.T) {
+	// 7% of 0.50 = 0.035 and of -0.50 = -0.035: a half, to show each mode
+	for mode, want := range map[Rounding][2]int64{
+		RoundHalfUp:   {4, -4},
+		RoundHalfEven: {4, -4},
+		RoundDown:     {3, -3},
+		RoundUp:       {4, -4},
+	} {
+		for i, amount := range []int64{50, -50} {
+			got, err := New(amount, "USD").PercentRounded(MustParseRate("7"), mode)
+			require.NoError(t, err)
+			assert.Equal(t, want[i], got.Amount, "%s of %d", mode, amount)
+		}
+	}
+	// 5% of 0.50 = 0.025, where half even keeps the even 2
+	got, err := New(50, "USD").PercentRounded(MustParseRate("5"), RoundHalfEven)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), got.Amount)
+
+	// the tax in 119.00 at 19% is exactly 19.00
+	tax, err := New(11900, "EUR").IncludedPercent(MustParseRate("19"), RoundHalfUp)
+	require.NoError(t, err)
+	assert.Equal(t, New(1900, "EUR"), tax)
+	// and in 10.00 it is 1.5966..., which only up and half modes round to 1.60
+	tax, err = New(1000, "EUR").IncludedPercent(MustParseRate("19"), RoundDown)
+	require.NoError(t, err)
+	assert.Equal(t, New(159, "EUR"), tax)
+
+	mode, err := ParseRounding("")
+	require.NoError(t, err)
+	assert.Equal(t, RoundHalfUp, mode)
+	_, err = ParseRounding("bankers")
+	assert.ErrorIs(t, err, ErrInvalidRounding)
+}
+
+func TestParseRate(t *testing.T) {
+	r, err := ParseRate("0.0000790500")
+	require.NoError(t, err)
+	assert.Equal(t, "0.00007905", r.String())
+
+	for _, bad := range []string{"0", "-1", "1/3", "1e5", "0.00000000001", ""} {
+		_, err := ParseRate(bad)
+		assert.Error(t, err, bad)
+	}
+
+	var scanned Rate
+	require.NoError(t, scanned.Scan([]byte("12650.5000000000")))
+	assert.Equal(t, "12650.5", scanned.String())
+}
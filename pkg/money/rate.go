@@ -0,0 +1,176 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RateScale is the number of decimal places a rate is stored with.
+const RateScale = 10
+
+// ErrInvalidRate is returned for rates that are not positive decimals.
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// Rate is an exact positive decimal exchange rate: how many major units of
+// the quote currency one major unit of the base currency buys.
+type Rate struct {
+	r *big.Rat
+}
+
+// ParseRate reads a decimal such as "12650.5" with at most RateScale
+// fraction digits.
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || !digits(whole) || !digits(frac) || len(frac) > RateScale {
+		return Rate{}, fmt.Errorf("%w %q", ErrInvalidRate, s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("%w %q", ErrInvalidRate, s)
+	}
+	return Rate{r: r}, nil
+}
+
+// MustParseRate is ParseRate for constants in tests and defaults.
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// IsZero reports whether the rate is unset.
+func (r Rate) IsZero() bool {
+	return r.r == nil
+}
+
+// Inverse returns the rate of the opposite direction, rounded to RateScale.
+func (r Rate) Inverse() Rate {
+	inv := new(big.Rat).Inv(r.r)
+	// round through the decimal form so the inverse is storable as is
+	inv, _ = new(big.Rat).SetString(inv.FloatString(RateScale))
+	if inv.Sign() == 0 {
+		return r
+	}
+	return Rate{r: inv}
+}
+
+// String formats the rate without trailing zeros, e.g. "12650.5".
+func (r Rate) String() string {
+	if r.r == nil {
+		return ""
+	}
+	s := r.r.FloatString(RateScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Convert returns m in the currency to, using r as the price of one major
+// unit of m's currency in to. The result is rounded half away from zero to
+// the minor unit of to.
+func (r Rate) Convert(m Money, to string) (Money, error) {
+	from, err := LookupCurrency(m.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	target, err := LookupCurrency(to)
+	if err != nil {
+		return Money{}, err
+	}
+	if r.r == nil {
+		return Money{}, ErrInvalidRate
+	}
+
+	v := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), r.r)
+	shift := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(target.Exponent-from.Exponent))), nil))
+	if target.Exponent >= from.Exponent {
+		v.Mul(v, shift)
+	} else {
+		v.Quo(v, shift)
+	}
+	n := RoundHalfUp.round(v)
+	if !n.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: n.Int64(), Currency: target.Code}, nil
+}
+
+// Cmp compares r and o, returning -1, 0 or +1.
+func (r Rate) Cmp(o Rate) int {
+	return r.r.Cmp(o.r)
+}
+
+// Percent returns p percent of m, e.g. a discount, rounded half away from
+// zero to the minor unit.
+func (m Money) Percent(p Rate) (Money, error) {
+	return m.PercentRounded(p, RoundHalfUp)
+}
+
+// PercentRounded returns p percent of m, rounded to the minor unit with mode.
+func (m Money) PercentRounded(p Rate, mode Rounding) (Money, error) {
+	if p.r == nil {
+		return Money{}, ErrInvalidRate
+	}
+	return m.scale(new(big.Rat).Quo(p.r, big.NewRat(100, 1)), mode)
+}
+
+// IncludedPercent returns the part of m that a p percent surcharge added to
+// it, m * p / (100 + p), e.g. the tax in a tax inclusive price, rounded to
+// the minor unit with mode.
+func (m Money) IncludedPercent(p Rate, mode Rounding) (Money, error) {
+	if p.r == nil {
+		return Money{}, ErrInvalidRate
+	}
+	whole := new(big.Rat).Add(p.r, big.NewRat(100, 1))
+	return m.scale(new(big.Rat).Quo(p.r, whole), mode)
+}
+
+func (m Money) scale(f *big.Rat, mode Rounding) (Money, error) {
+	n := mode.round(new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), f))
+	if !n.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: n.Int64(), Currency: m.Currency}, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (r Rate) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Rate) UnmarshalText(text []byte) error {
+	parsed, err := ParseRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Value stores the rate as its decimal text, which both NUMERIC and TEXT
+// columns accept.
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+func (r *Rate) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return r.UnmarshalText([]byte(v))
+	case []byte:
+		return r.UnmarshalText(v)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidRate, src)
+	}
+}
@@ -0,0 +1,62 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Rounding is how a computed amount is brought to the minor unit. Every mode
+// is exact, so the same inputs always give the same cents.
+type Rounding string
+
+const (
+	// RoundHalfUp rounds to the nearest unit, halves away from zero.
+	RoundHalfUp Rounding = "half_up"
+	// RoundHalfEven rounds to the nearest unit, halves to the even one.
+	RoundHalfEven Rounding = "half_even"
+	// RoundDown truncates toward zero.
+	RoundDown Rounding = "down"
+	// RoundUp rounds away from zero.
+	RoundUp Rounding = "up"
+)
+
+// ErrInvalidRounding is returned for unknown rounding modes.
+var ErrInvalidRounding = errors.New("invalid rounding mode")
+
+// ParseRounding checks s names a rounding mode; empty means RoundHalfUp.
+func ParseRounding(s string) (Rounding, error) {
+	switch r := Rounding(s); r {
+	case "":
+		return RoundHalfUp, nil
+	case RoundHalfUp, RoundHalfEven, RoundDown, RoundUp:
+		return r, nil
+	default:
+		return "", fmt.Errorf("%w %q, want half_up, half_even, down or up", ErrInvalidRounding, s)
+	}
+}
+
+func (mode Rounding) round(v *big.Rat) *big.Int {
+	num := new(big.Int).Abs(v.Num())
+	q, rem := new(big.Int).QuoRem(num, v.Denom(), new(big.Int))
+	if rem.Sign() != 0 {
+		switch mode {
+		case RoundUp:
+			q.Add(q, big.NewInt(1))
+		case RoundDown:
+		default:
+			switch new(big.Int).Mul(rem, big.NewInt(2)).Cmp(v.Denom()) {
+			case 1:
+				q.Add(q, big.NewInt(1))
+			case 0:
+				if mode != RoundHalfEven || q.Bit(0) == 1 {
+					q.Add(q, big.NewInt(1))
+				}
+			}
+		}
+	}
+	if v.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q
+}
@@ -0,0 +1,17 @@
+package notify
+
+import (
+	"context"
+	"prodcrud/pkg/logging"
+)
+
+const logPackage = "notify"
+
+// Log writes messages to the logger of the context, at warn level so that
+// they stand out from the request logs.
+type Log struct{}
+
+func (Log) Notify(ctx context.Context, m Message) error {
+	logging.For(ctx, logPackage).Warn(m.Subject, "text", m.Text)
+	return nil
+}
@@ -0,0 +1,40 @@
+// Package notify sends alerts to people: to the application log, to a
+// webhook, or by email through an SMTP server.
+//
+// Delivery is at least once from the point of view of the caller, which
+// retries a message whose Notify failed: a Multi notifier sends it again to
+// the notifiers that succeeded the first time.
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+// Message is an alert. Subject and Text are written for people; Data is
+// posted as JSON to webhooks, for machines.
+type Message struct {
+	Subject string
+	Text    string
+	Data    any
+}
+
+// Notifier delivers messages.
+type Notifier interface {
+	// Notify delivers m; an error means it may not have been delivered.
+	Notify(ctx context.Context, m Message) error
+}
+
+// Multi delivers every message to each of its notifiers, and joins their
+// errors.
+type Multi []Notifier
+
+func (n Multi) Notify(ctx context.Context, m Message) error {
+	var errs []error
+	for _, notifier := range n {
+		if err := notifier.Notify(ctx, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
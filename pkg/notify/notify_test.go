@@ -0,0 +1,154 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"prodcrud/pkg/notify"
+	"prodcrud/pkg/notify/smtptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var message = notify.Message{
+	Subject: "2 products at or below their reorder point",
+	Text:    "pen: 3 in stock, reorder point 5\nстол: 0 in stock, reorder point 2\n",
+	Data:    map[string]int{"pen": 3},
+}
+
+type notifierFunc func(ctx context.Context, m notify.Message) error
+
+func (f notifierFunc) Notify(ctx context.Context, m notify.Message) error { return f(ctx, m) }
+
+func TestMulti(t *testing.T) {
+	var got []string
+	failed := errors.New("unreachable")
+	n := notify.Multi{
+		notifierFunc(func(_ context.Context, m notify.Message) error {
+			got = append(got, "first: "+m.Subject)
+			return failed
+		}),
+		notify.Log{},
+		notifierFunc(func(_ context.Context, m notify.Message) error {
+			got = append(got, "last: "+m.Subject)
+			return nil
+		}),
+	}
+	err := n.Notify(context.Background(), message)
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, []string{"first: " + message.Subject, "last: " + message.Subject}, got,
+		"a failure does not stop the other notifiers")
+	assert.NoError(t, notify.Multi{}.Notify(context.Background(), message))
+}
+
+func TestWebhook(t *testing.T) {
+	status := http.StatusNoContent
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	n, err := notify.NewWebhook(notify.WebhookConfig{URL: server.URL + "/alerts", Secret: "s3cret", Client: server.Client()})
+	require.NoError(t, err)
+	require.NoError(t, n.Notify(context.Background(), message))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, notify.Sign("s3cret", body), header.Get(notify.SignatureHeader))
+	var payload struct {
+		Subject string         `json:"subject"`
+		Text    string         `json:"text"`
+		Data    map[string]int `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, message.Subject, payload.Subject)
+	assert.Equal(t, message.Text, payload.Text)
+	assert.Equal(t, map[string]int{"pen": 3}, payload.Data)
+
+	status = http.StatusBadGateway
+	assert.EqualError(t, n.Notify(context.Background(), message), "webhook answered 502 Bad Gateway")
+
+	unsigned, err := notify.NewWebhook(notify.WebhookConfig{URL: server.URL, Client: server.Client()})
+	require.NoError(t, err)
+	status = http.StatusOK
+	require.NoError(t, unsigned.Notify(context.Background(), message))
+	assert.Empty(t, header.Get(notify.SignatureHeader))
+
+	for _, url := range []string{"", "localhost:8080", "ftp://example.com/alerts"} {
+		_, err := notify.NewWebhook(notify.WebhookConfig{URL: url})
+		assert.Error(t, err, url)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// the HMAC-SHA256 test vector of RFC 4231, test case 2
+	assert.Equal(t, "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		notify.Sign("Jefe", []byte("what do ya want for nothing?")))
+}
+
+func TestSMTP(t *testing.T) {
+	server := smtptest.New(t)
+	cfg := server.Config()
+	cfg.To = append(cfg.To, "Purchasing <buyers@example.com>")
+	n, err := notify.NewSMTP(cfg)
+	require.NoError(t, err)
+	require.NoError(t, n.Notify(context.Background(), message))
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, smtptest.From, messages[0].From)
+	assert.Equal(t, []string{smtptest.To, "buyers@example.com"}, messages[0].To)
+
+	email, err := mail.ReadMessage(strings.NewReader(messages[0].Data))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(email.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, message.Subject, subject)
+	assert.Equal(t, "<warehouse@example.com>, \"Purchasing\" <buyers@example.com>", email.Header.Get("To"))
+	assert.Equal(t, "text/plain; charset=utf-8", email.Header.Get("Content-Type"))
+	_, err = email.Header.Date()
+	assert.NoError(t, err)
+	text, err := io.ReadAll(quotedprintable.NewReader(email.Body))
+	require.NoError(t, err)
+	assert.Equal(t, message.Text, string(text))
+
+	require.NoError(t, n.Notify(context.Background(), notify.Message{Subject: "line\r\nBcc: x@example.com", Text: "."}))
+	email, err = mail.ReadMessage(strings.NewReader(server.Messages()[1].Data))
+	require.NoError(t, err)
+	assert.Equal(t, "line Bcc: x@example.com", email.Header.Get("Subject"), "a subject cannot inject headers")
+	assert.Empty(t, email.Header.Get("Bcc"))
+
+	server.Close()
+	assert.Error(t, n.Notify(context.Background(), message), "the server is down")
+}
+
+func TestNewSMTP(t *testing.T) {
+	valid := notify.SMTPConfig{Addr: "localhost:25", From: "alerts@example.com", To: []string{"a@example.com"}}
+	_, err := notify.NewSMTP(valid)
+	require.NoError(t, err)
+
+	for name, edit := range map[string]func(c *notify.SMTPConfig){
+		"no port":           func(c *notify.SMTPConfig) { c.Addr = "localhost" },
+		"no host":           func(c *notify.SMTPConfig) { c.Addr = ":25" },
+		"invalid sender":    func(c *notify.SMTPConfig) { c.From = "alerts" },
+		"no recipient":      func(c *notify.SMTPConfig) { c.To = nil },
+		"invalid recipient": func(c *notify.SMTPConfig) { c.To = []string{"a@example.com", "b"} },
+	} {
+		cfg := valid
+		edit(&cfg)
+		_, err := notify.NewSMTP(cfg)
+		assert.Error(t, err, name)
+	}
+}
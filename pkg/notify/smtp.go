@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPConfig addresses the mail server and the recipients of the alerts.
+type SMTPConfig struct {
+	// Addr is the host:port of the server.
+	Addr string
+	From string
+	To   []string
+	// Username and Password authenticate with PLAIN when Username is set,
+	// which is only done over TLS or to localhost.
+	Username string
+	Password string
+	// Timeout bounds a delivery from the dial to the QUIT, 10 seconds if zero.
+	Timeout time.Duration
+}
+
+// SMTP sends messages as plain text emails. It upgrades the connection with
+// STARTTLS when the server offers it.
+type SMTP struct {
+	cfg  SMTPConfig
+	host string
+	from *mail.Address
+	to   []*mail.Address
+	now  func() time.Time
+}
+
+// NewSMTP checks the configuration; it does not contact the server.
+func NewSMTP(cfg SMTPConfig) (*SMTP, error) {
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil || host == "" {
+		return nil, fmt.Errorf("smtp address %q must be host:port", cfg.Addr)
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("smtp sender %q is not an email address", cfg.From)
+	}
+	if len(cfg.To) == 0 {
+		return nil, errors.New("smtp needs at least one recipient")
+	}
+	to := make([]*mail.Address, len(cfg.To))
+	for i, addr := range cfg.To {
+		if to[i], err = mail.ParseAddress(addr); err != nil {
+			return nil, fmt.Errorf("smtp recipient %q is not an email address", addr)
+		}
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &SMTP{cfg: cfg, host: host, from: from, to: to, now: time.Now}, nil
+}
+
+func (s *SMTP) Notify(ctx context.Context, m Message) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	// closing the connection interrupts an exchange the context cancels
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("smtp: %w", err)
+	}
+	defer c.Close()
+	if err := s.send(c, m); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
+
+func (s *SMTP) send(c *smtp.Client, m Message) error {
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err //nolint:wrapcheck //wrapped by the caller
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.host)); err != nil {
+			return err //nolint:wrapcheck //wrapped by the caller
+		}
+	}
+	if err := c.Mail(s.from.Address); err != nil {
+		return err //nolint:wrapcheck //wrapped by the caller
+	}
+	for _, to := range s.to {
+		if err := c.Rcpt(to.Address); err != nil {
+			return err //nolint:wrapcheck //wrapped by the caller
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err //nolint:wrapcheck //wrapped by the caller
+	}
+	if _, err := w.Write(s.email(m)); err != nil {
+		return err //nolint:wrapcheck //wrapped by the caller
+	}
+	if err := w.Close(); err != nil {
+		return err //nolint:wrapcheck //wrapped by the caller
+	}
+	return c.Quit() //nolint:wrapcheck //wrapped by the caller
+}
+
+// email formats m as a quoted-printable UTF-8 text message.
+func (s *SMTP) email(m Message) []byte {
+	// a line break in the subject would start a header of its own
+	subject := strings.Join(strings.Fields(m.Subject), " ")
+	var b bytes.Buffer
+	to := make([]string, len(s.to))
+	for i, addr := range s.to {
+		to[i] = addr.String()
+	}
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", s.now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&b)
+	_, _ = qp.Write([]byte(m.Text))
+	_ = qp.Close()
+	return b.Bytes()
+}
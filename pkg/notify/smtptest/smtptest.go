@@ -0,0 +1,190 @@
+// Package smtptest serves a stand-in SMTP server for tests of code using
+// notify.SMTP.
+//
+// The server speaks the part of RFC 5321 net/smtp needs to send a message:
+// EHLO or HELO, MAIL, RCPT, DATA, RSET, NOOP and QUIT, without TLS or
+// authentication. It keeps every message it accepts.
+package smtptest
+
+import (
+	"net"
+	"net/textproto"
+	"prodcrud/pkg/notify"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The addresses of the configuration returned by Server.Config.
+const (
+	From = "alerts@example.com"
+	To   = "warehouse@example.com"
+)
+
+// Message is a message the server accepted.
+type Message struct {
+	From string
+	To   []string
+	// Data is the content after DATA, with the dots unstuffed and the lines
+	// ending in \n.
+	Data string
+}
+
+// Server is a running stand-in, closed when the test ends.
+type Server struct {
+	// Addr is the host:port the server listens on.
+	Addr string
+
+	ln       net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	messages []Message
+}
+
+// New starts a stand-in for the test, listening on the loopback interface.
+func New(t testing.TB) *Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("smtptest: %v", err)
+	}
+	s := &Server{Addr: ln.Addr().String(), ln: ln, conns: make(map[net.Conn]struct{})}
+	s.wg.Add(1)
+	go s.accept()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Config returns the configuration of a notifier sending from From to To
+// through the server.
+func (s *Server) Config() notify.SMTPConfig {
+	return notify.SMTPConfig{Addr: s.Addr, From: From, To: []string{To}}
+}
+
+// Messages lists the accepted messages in order.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Close stops listening, ends the open sessions and waits for them.
+func (s *Server) Close() {
+	_ = s.ln.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	c := textproto.NewConn(conn)
+	defer c.Close()
+
+	reply := func(code int, msg string) bool {
+		return c.PrintfLine("%d %s", code, msg) == nil
+	}
+	if !reply(220, "smtptest ready") {
+		return
+	}
+	var m Message
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		ok := true
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ok = c.PrintfLine("250-smtptest") == nil && reply(250, "8BITMIME")
+		case "HELO", "NOOP":
+			ok = reply(250, "ok")
+		case "RSET":
+			m = Message{}
+			ok = reply(250, "ok")
+		case "MAIL":
+			from, found := path(arg, "FROM:")
+			if !found {
+				ok = reply(501, "syntax: MAIL FROM:<address>")
+				break
+			}
+			m = Message{From: from}
+			ok = reply(250, "ok")
+		case "RCPT":
+			to, found := path(arg, "TO:")
+			switch {
+			case !found:
+				ok = reply(501, "syntax: RCPT TO:<address>")
+			case m.From == "":
+				ok = reply(503, "MAIL first")
+			default:
+				m.To = append(m.To, to)
+				ok = reply(250, "ok")
+			}
+		case "DATA":
+			if len(m.To) == 0 {
+				ok = reply(503, "RCPT first")
+				break
+			}
+			if !reply(354, "end data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, m)
+			s.mu.Unlock()
+			m = Message{}
+			ok = reply(250, "queued")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			ok = reply(502, "command not implemented")
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// path returns the address of a MAIL or RCPT argument such as
+// FROM:<a@example.com>, ignoring the parameters after it.
+func path(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	addr, _, _ := strings.Cut(strings.TrimSpace(arg[len(prefix):]), " ")
+	if !strings.HasPrefix(addr, "<") || !strings.HasSuffix(addr, ">") {
+		return "", false
+	}
+	return addr[1 : len(addr)-1], true
+}
@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SignatureHeader carries the signature of a webhook body: sha256= followed
+// by the hex HMAC-SHA256 of the body under the secret of the webhook.
+const SignatureHeader = "X-Signature-256"
+
+// WebhookConfig addresses an HTTP endpoint accepting alerts.
+type WebhookConfig struct {
+	URL string
+	// Secret signs the body in SignatureHeader, which is left out if empty.
+	Secret string
+	// Client sends the requests, a client with a 10 second timeout if nil.
+	Client *http.Client
+}
+
+// Payload is the JSON body posted for a message.
+type Payload struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// Webhook posts messages as a JSON Payload, and expects a 2xx answer.
+type Webhook struct {
+	cfg    WebhookConfig
+	client *http.Client
+}
+
+// NewWebhook checks the configuration; it does not contact the endpoint.
+func NewWebhook(cfg WebhookConfig) (*Webhook, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("webhook url %q must be an http or https URL", cfg.URL)
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Webhook{cfg: cfg, client: client}, nil
+}
+
+// Sign returns the value of SignatureHeader for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *Webhook) Notify(ctx context.Context, m Message) error {
+	body, err := json.Marshal(Payload{Subject: m.Subject, Text: m.Text, Data: m.Data})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.cfg.Secret, body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	// drained so that the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Package thumbnail reads uploaded images and scales them down in pure Go.
+//
+// Read sniffs the format from the content, as http.DetectContentType does,
+// and accepts JPEG, PNG and GIF, the first frame of an animated GIF.
+// Thumbnails of JPEG images are JPEG; the others are PNG, which keeps
+// their transparency.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// JPEGQuality is the quality of JPEG thumbnails.
+const JPEGQuality = 85
+
+var (
+	// ErrUnsupported is returned for content that is not an image of a
+	// supported format.
+	ErrUnsupported = errors.New("unsupported image format")
+	// ErrTooManyPixels is returned for an image larger than the pixel
+	// limit, checked before the image is decoded.
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+var decoders = map[string]func([]byte) (image.Image, error){
+	"image/jpeg": func(data []byte) (image.Image, error) { return jpeg.Decode(bytes.NewReader(data)) },
+	"image/png":  func(data []byte) (image.Image, error) { return png.Decode(bytes.NewReader(data)) },
+	"image/gif":  func(data []byte) (image.Image, error) { return gif.Decode(bytes.NewReader(data)) },
+}
+
+var configs = map[string]func([]byte) (image.Config, error){
+	"image/jpeg": func(data []byte) (image.Config, error) { return jpeg.DecodeConfig(bytes.NewReader(data)) },
+	"image/png":  func(data []byte) (image.Config, error) { return png.DecodeConfig(bytes.NewReader(data)) },
+	"image/gif":  func(data []byte) (image.Config, error) { return gif.DecodeConfig(bytes.NewReader(data)) },
+}
+
+// Image is a decoded upload.
+type Image struct {
+	ContentType string
+	Width       int
+	Height      int
+	image       image.Image
+}
+
+// Read sniffs and decodes data, refusing images of more than maxPixels.
+func Read(data []byte, maxPixels int) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	config, ok := configs[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, contentType)
+	}
+	cfg, err := config(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, fmt.Errorf("%w: the image is empty", ErrUnsupported)
+	}
+	if cfg.Width > maxPixels/cfg.Height {
+		return nil, fmt.Errorf("%w: %dx%d is over %d", ErrTooManyPixels, cfg.Width, cfg.Height, maxPixels)
+	}
+	img, err := decoders[contentType](data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+	return &Image{ContentType: contentType, Width: cfg.Width, Height: cfg.Height, image: img}, nil
+}
+
+// Thumbnail scales the image to fit a size by size square and encodes it,
+// returning the data and its content type. Smaller images keep their size.
+func (img *Image) Thumbnail(size int) ([]byte, string, error) {
+	thumb := Fit(img.image, size)
+	var buf bytes.Buffer
+	if img.ContentType == "image/jpeg" {
+		if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: JPEGQuality}); err != nil {
+			return nil, "", fmt.Errorf("encode thumbnail: %w", err)
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, thumb); err != nil {
+		return nil, "", fmt.Errorf("encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// Fit scales src down to fit a size by size square, keeping its aspect
+// ratio. Each pixel of the result averages the block of source pixels it
+// covers, which suits the large reductions of thumbnails. An image that
+// fits already is returned as is.
+func Fit(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if size <= 0 || sw <= size && sh <= size {
+		return src
+	}
+	dw, dh := size, size
+	if sw > sh {
+		dh = max(1, sh*size/sw)
+	} else {
+		dw = max(1, sw*size/sh)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		y0, y1 := b.Min.Y+y*sh/dh, b.Min.Y+(y+1)*sh/dh
+		for x := range dw {
+			x0, x1 := b.Min.X+x*sw/dw, b.Min.X+(x+1)*sw/dw
+			var r, g, bl, a uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+				}
+			}
+			n := uint64((x1 - x0) * (y1 - y0))
+			// RGBA returns premultiplied 16 bit channels, which average
+			// without darkening transparent edges.
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,74 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func checkerboard(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			if (x+y)%2 == 0 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Transparent)
+			}
+		}
+	}
+	return img
+}
+
+func TestFit(t *testing.T) {
+	src := checkerboard(400, 100)
+	thumb := Fit(src, 100)
+	assert.Equal(t, image.Rect(0, 0, 100, 25), thumb.Bounds())
+	// every block holds as many opaque white as transparent pixels
+	assert.Equal(t, color.RGBA{R: 127, G: 127, B: 127, A: 127}, thumb.At(10, 10))
+
+	assert.Equal(t, image.Rect(0, 0, 1, 100), Fit(checkerboard(2, 400), 100).Bounds())
+	assert.Same(t, src, Fit(src, 400), "an image that fits is not scaled")
+}
+
+func TestRead(t *testing.T) {
+	var pngData, jpegData, gifData bytes.Buffer
+	require.NoError(t, png.Encode(&pngData, checkerboard(300, 200)))
+	require.NoError(t, jpeg.Encode(&jpegData, checkerboard(300, 200), nil))
+	require.NoError(t, gif.Encode(&gifData, checkerboard(30, 20), nil))
+
+	for _, tc := range []struct {
+		data      []byte
+		want      string
+		thumbType string
+		thumbSize image.Rectangle
+	}{
+		{pngData.Bytes(), "image/png", "image/png", image.Rect(0, 0, 128, 85)},
+		{jpegData.Bytes(), "image/jpeg", "image/jpeg", image.Rect(0, 0, 128, 85)},
+		{gifData.Bytes(), "image/gif", "image/png", image.Rect(0, 0, 30, 20)},
+	} {
+		img, err := Read(tc.data, 1_000_000)
+		require.NoError(t, err, tc.want)
+		assert.Equal(t, tc.want, img.ContentType)
+		thumb, contentType, err := img.Thumbnail(128)
+		require.NoError(t, err, tc.want)
+		assert.Equal(t, tc.thumbType, contentType)
+		decoded, _, err := image.Decode(bytes.NewReader(thumb))
+		require.NoError(t, err, tc.want)
+		assert.Equal(t, tc.thumbSize, decoded.Bounds(), tc.want)
+	}
+
+	_, err := Read([]byte("%PDF-1.4 not an image"), 1_000_000)
+	assert.ErrorIs(t, err, ErrUnsupported)
+	_, err = Read(pngData.Bytes()[:100], 1_000_000)
+	assert.ErrorIs(t, err, ErrUnsupported, "a truncated image")
+	_, err = Read(pngData.Bytes(), 300*200-1)
+	assert.ErrorIs(t, err, ErrTooManyPixels)
+}